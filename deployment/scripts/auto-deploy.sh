@@ -11,6 +11,17 @@ BACKUP_DIR="${REPO_DIR}-backups"
 LOG_FILE="/var/log/lightning-deploy.log"
 MAX_BACKUPS=5
 
+# Canary mode (DEPLOY_CANARY=true, set by webhook-deployer --canary) smoke tests
+# the new API in mock mode on CANARY_PORT before any service is touched
+DEPLOY_CANARY="${DEPLOY_CANARY:-false}"
+CANARY_PORT="${CANARY_PORT:-18090}"
+
+# Databases the webhook deployer backed up before this deployment (--backup-db),
+# one <database>=<backup> per line. They are restored on rollback once the new
+# version has started, as its services may have migrated them.
+DEPLOY_DB_BACKUPS="${DEPLOY_DB_BACKUPS:-}"
+SERVICES_SWAPPED=false
+
 # Service names (adjust these to match your systemd service names)
 SERVICES=(
     "bitcoin-dashboard-api"
@@ -53,6 +64,10 @@ rollback() {
         sudo rm -rf "$REPO_DIR.rollback" || true
         sudo mv "$REPO_DIR" "$REPO_DIR.rollback" || true
         sudo mv "$BACKUP_DIR/previous" "$REPO_DIR" || true
+
+        if [ "$SERVICES_SWAPPED" = "true" ]; then
+            restore_databases
+        fi
         
         # Restart services
         for service in "${SERVICES[@]}"; do
@@ -74,6 +89,17 @@ rollback() {
     fi
 }
 
+# Restore the databases from DEPLOY_DB_BACKUPS. Services must be stopped.
+restore_databases() {
+    [ -n "$DEPLOY_DB_BACKUPS" ] || return 0
+    while IFS='=' read -r database backup; do
+        [ -n "$database" ] || continue
+        log "${YELLOW}💾 Restoring $database from $backup${NC}"
+        sudo rm -f "$database-wal" "$database-shm" || true
+        sudo cp "$backup" "$database" || log "${RED}❌ Failed to restore $database${NC}"
+    done <<< "$DEPLOY_DB_BACKUPS"
+}
+
 # Health check function
 health_check() {
     local service=$1
@@ -94,6 +120,32 @@ health_check() {
     return 1
 }
 
+# Canary: run the freshly built API in mock mode against a throwaway database
+# and smoke test it. Services keep running the old version if this fails.
+canary_check() {
+    local canary_dir
+    canary_dir=$(mktemp -d)
+
+    log "${BLUE}🐤 Starting canary API in mock mode on port $CANARY_PORT...${NC}"
+    ./bin/portfolio-api --mock --no-bitcoin --db "$canary_dir/canary.db" \
+        --host 127.0.0.1 --port "$CANARY_PORT" --admin-addr "" \
+        > "$canary_dir/canary.log" 2>&1 &
+    local canary_pid=$!
+
+    local result=0
+    ./bin/lnt smoke --url "http://127.0.0.1:$CANARY_PORT" --wait 30s > "$canary_dir/smoke.log" 2>&1 || result=1
+    tee -a "$LOG_FILE" < "$canary_dir/smoke.log"
+
+    kill "$canary_pid" 2>/dev/null || true
+    wait "$canary_pid" 2>/dev/null || true
+    if [ "$result" -ne 0 ]; then
+        log "${RED}📜 Canary API output:${NC}"
+        tail -n 20 "$canary_dir/canary.log" | tee -a "$LOG_FILE"
+    fi
+    rm -rf "$canary_dir"
+    return $result
+}
+
 # Create backup directory
 create_backup_dir() {
     sudo mkdir -p "$BACKUP_DIR"
@@ -142,9 +194,14 @@ main() {
     # Get current commit for comparison
     OLD_COMMIT=$(git rev-parse HEAD)
     
-    # Reset to latest main
-    log "${BLUE}🔄 Resetting to origin/main...${NC}"
-    git reset --hard origin/main || handle_error "git reset"
+    # Reset to the pushed commit, which the deployer may have verified, so a
+    # newer push that has not been checked yet is never deployed with it
+    TARGET="origin/main"
+    if [ -n "${DEPLOY_COMMIT:-}" ] && git cat-file -e "${DEPLOY_COMMIT}^{commit}" 2>/dev/null; then
+        TARGET="$DEPLOY_COMMIT"
+    fi
+    log "${BLUE}🔄 Resetting to ${TARGET}...${NC}"
+    git reset --hard "$TARGET" || handle_error "git reset"
     
     NEW_COMMIT=$(git rev-parse HEAD)
     
@@ -168,6 +225,11 @@ main() {
     # Build all components
     log "${BLUE}🔨 Building components...${NC}"
     make build || handle_error "building components"
+
+    if [ "$DEPLOY_CANARY" = "true" ]; then
+        canary_check || handle_error "canary smoke tests"
+        log "${GREEN}🐤 Canary passed, swapping services${NC}"
+    fi
     
     # Stop services gracefully
     log "${BLUE}⏹️  Stopping services...${NC}"
@@ -179,7 +241,8 @@ main() {
     # Small delay to ensure services are fully stopped
     sleep 2
     
-    # Start services
+    # Start services, which migrate the databases on the new version
+    SERVICES_SWAPPED=true
     log "${BLUE}▶️  Starting services...${NC}"
     for service in "${SERVICES[@]}"; do
         log "${BLUE}🚀 Starting $service${NC}"
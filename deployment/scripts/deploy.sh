@@ -42,12 +42,16 @@ echo ""
 echo "🔨 Building binaries..."
 cd "${PROJECT_ROOT}"
 
+# Embed the commit so /api/version does not need git at runtime
+VERSION_PKG="github.com/brewgator/lightning-node-tools/internal/version"
+LDFLAGS="-X ${VERSION_PKG}.Commit=$(git rev-parse --short HEAD 2>/dev/null || echo unknown) -X ${VERSION_PKG}.Version=$(git describe --tags --always --dirty 2>/dev/null || echo dev) -X ${VERSION_PKG}.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+
 for binary_info in "${BINARIES[@]}"; do
     binary_name=$(echo "${binary_info}" | cut -d: -f1)
     source_path=$(echo "${binary_info}" | cut -d: -f2)
 
     echo "  Building ${binary_name}..."
-    go build -o "${BIN_DIR}/${binary_name}" "./${source_path}"
+    go build -ldflags "${LDFLAGS}" -o "${BIN_DIR}/${binary_name}" "./${source_path}"
 
     if [ -f "${BIN_DIR}/${binary_name}" ]; then
         echo "    ✅ ${binary_name} built successfully"
@@ -4,4 +4,5 @@ exec $HOME/lightning-node-tools/bin/webhook-deployer \
   --port=9000 \
   --repo=$HOME/lightning-node-tools \
   --branch=main \
-  --script=./deployment/scripts/auto-deploy.sh
+  --script=./deployment/scripts/auto-deploy.sh \
+  --backup-db=data/portfolio.db
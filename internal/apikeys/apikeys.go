@@ -0,0 +1,189 @@
+// Package apikeys reads and edits the key file of the portfolio API. Each
+// line is "profile key [scope]": the profile the key serves (see
+// db.ProfilePath), the key itself and read or write, write when left out.
+// Blank lines and lines starting with # are skipped.
+package apikeys
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// MinKeyLength keeps guessable keys out of the key file
+const MinKeyLength = 16
+
+// Scopes a key may have. Read keys only read, write keys may also change
+// data.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// Key is one line of the key file
+type Key struct {
+	Profile string
+	Key     string
+	Scope   string
+}
+
+// CanWrite reports whether the key may change data
+func (k Key) CanWrite() bool {
+	return k.Scope == ScopeWrite
+}
+
+// Redacted is the start of the key, enough to tell keys apart in listings
+func (k Key) Redacted() string {
+	return k.Key[:8] + "…"
+}
+
+// Parse reads a key file. A profile may have several keys, a key only one
+// profile.
+func Parse(r io.Reader) ([]Key, error) {
+	var keys []Key
+	seen := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: want \"profile key [scope]\"", line)
+		}
+		key := Key{Profile: fields[0], Key: fields[1], Scope: ScopeWrite}
+		if len(fields) == 3 {
+			key.Scope = fields[2]
+		}
+		if err := key.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if other, ok := seen[key.Key]; ok {
+			return nil, fmt.Errorf("line %d: key is already used by profile %s", line, other)
+		}
+		seen[key.Key] = key.Profile
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
+
+// Load reads the key file at path, which must hold at least one key
+func Load(path string) ([]Key, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys, err := Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s has no API keys", path)
+	}
+	return keys, nil
+}
+
+// Generate returns a new random key
+func Generate() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Add generates a key for profile with scope and appends it to the key file
+// at path, creating the file readable by its owner only
+func Add(path, profile, scope string) (Key, error) {
+	secret, err := Generate()
+	if err != nil {
+		return Key{}, err
+	}
+	key := Key{Profile: profile, Key: secret, Scope: scope}
+	if err := key.validate(); err != nil {
+		return Key{}, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return Key{}, err
+	}
+	if _, err := fmt.Fprintf(file, "%s %s %s\n", key.Profile, key.Key, key.Scope); err != nil {
+		file.Close()
+		return Key{}, err
+	}
+	return key, file.Close()
+}
+
+// Remove deletes the one key starting with prefix from the key file at
+// path, keeping every other line as it is
+func Remove(path, prefix string) (Key, error) {
+	if len(prefix) < 8 {
+		return Key{}, fmt.Errorf("give at least the first 8 characters of the key")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	keys, err := Parse(strings.NewReader(string(content)))
+	if err != nil {
+		return Key{}, err
+	}
+
+	var matches []Key
+	for _, key := range keys {
+		if strings.HasPrefix(key.Key, prefix) {
+			matches = append(matches, key)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Key{}, fmt.Errorf("no key starts with %s", prefix)
+	case 1:
+	default:
+		return Key{}, fmt.Errorf("%d keys start with %s, give more of the key", len(matches), prefix)
+	}
+
+	var kept []string
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") && fields[1] == matches[0].Key {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	// Replace the file in one step so the API never reads half of it
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(kept, "")), 0600); err != nil {
+		return Key{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return Key{}, err
+	}
+	return matches[0], nil
+}
+
+func (k Key) validate() error {
+	if _, err := db.ProfilePath("", k.Profile); err != nil {
+		return err
+	}
+	if len(k.Key) < MinKeyLength {
+		return fmt.Errorf("key of profile %s is shorter than %d characters", k.Profile, MinKeyLength)
+	}
+	if k.Scope != ScopeRead && k.Scope != ScopeWrite {
+		return fmt.Errorf("invalid scope %q of profile %s, want %s or %s", k.Scope, k.Profile, ScopeRead, ScopeWrite)
+	}
+	return nil
+}
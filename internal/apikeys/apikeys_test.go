@@ -0,0 +1,53 @@
+package apikeys
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParse(t *testing.T) {
+	keys, err := Parse(strings.NewReader("# dashboard\ndefault dashboard-0123456789 read\nbusiness business-0123456789\n"))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(keys), 2)
+	testutils.AssertEqual(t, keys[0].CanWrite(), false)
+	testutils.AssertEqual(t, keys[0].Redacted(), "dashboar…")
+	// Keys without a scope keep the write access they always had
+	testutils.AssertEqual(t, keys[1].Scope, ScopeWrite)
+
+	_, err = Parse(strings.NewReader("default dashboard-0123456789 admin"))
+	testutils.AssertError(t, err, `line 1: invalid scope "admin"`)
+	_, err = Parse(strings.NewReader("default dashboard-0123456789 read extra"))
+	testutils.AssertError(t, err, "line 1: want")
+}
+
+func TestAddAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-keys")
+	testutils.AssertNoError(t, os.WriteFile(path, []byte("# keep me\ndefault dashboard-0123456789 read\n"), 0600))
+
+	added, err := Add(path, "business", ScopeWrite)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(added.Key), 48)
+	_, err = Add(path, "business", "admin")
+	testutils.AssertError(t, err, "invalid scope")
+
+	keys, err := Load(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(keys), 2)
+	testutils.AssertEqual(t, keys[1], added)
+
+	_, err = Remove(path, "dash")
+	testutils.AssertError(t, err, "at least the first 8 characters")
+	_, err = Remove(path, "notakey0")
+	testutils.AssertError(t, err, "no key starts with notakey0")
+
+	removed, err := Remove(path, "dashboard")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, removed.Profile, "default")
+	content, err := os.ReadFile(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, string(content), "# keep me\nbusiness "+added.Key+" write\n")
+}
@@ -0,0 +1,359 @@
+// Package archive moves forwarding events and channel snapshots older than a
+// retention period out of the database into gzipped CSV files, one per table
+// and month, e.g. data/portfolio-archive/forwarding_events/2024-01.csv.gz.
+// Later runs append to a month's file. The export endpoints read the files
+// back, so no history is lost while the live database stays small.
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// DefaultMonths is how many months of history stay in the database
+const DefaultMonths = 12
+
+// Tables archived, which name their directories
+const (
+	ForwardingEvents = "forwarding_events"
+	ChannelSnapshots = "channel_snapshots"
+)
+
+// monthLayout names the file of each month
+const monthLayout = "2006-01"
+
+var (
+	forwardingHeader = []string{"id", "timestamp", "channel_in_id", "channel_out_id", "amount_in", "amount_out", "fee", "fee_ppm"}
+	channelHeader    = []string{"id", "timestamp", "channel_id", "capacity", "local_balance", "remote_balance", "active", "peer_alias", "fee_ppm", "base_fee"}
+)
+
+// DirFor returns the archive directory of the database at dbPath, next to
+// it: data/portfolio-archive for data/portfolio.db
+func DirFor(dbPath string) string {
+	return strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + "-archive"
+}
+
+// Archive is a directory of archived rows
+type Archive struct {
+	dir string
+}
+
+// New returns the archive in dir, which is created on the first run
+func New(dir string) *Archive {
+	return &Archive{dir: dir}
+}
+
+// Result counts the rows a run archived
+type Result struct {
+	// ForwardsBefore is the day forwards were archived up to, earlier than
+	// asked when forwarding_daily is behind
+	ForwardsBefore   time.Time
+	ForwardingEvents int64
+	ChannelSnapshots int64
+}
+
+// Run archives the forwarding events and channel snapshots before before as
+// an archive collector run. Rows are deleted only once their month's file is
+// written and synced. Should a run stop between the two, the next one writes
+// the rows again, and reading skips the copies.
+func (a *Archive) Run(database *db.Database, before time.Time) (*Result, error) {
+	result := &Result{}
+	err := database.RecordCollectorRun(db.ArchiveCollector, func(run *db.CollectorRun) error {
+		forwards := a.writer(ForwardingEvents, forwardingHeader)
+		forwardsBefore, archived, err := database.ArchiveForwardingEvents(before, func(event *db.ForwardingEvent) error {
+			return forwards.write(event.Timestamp, forwardingRecord(event))
+		})
+		if closeErr := forwards.close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to archive forwarding events: %w", err)
+		}
+		result.ForwardsBefore = forwardsBefore
+		result.ForwardingEvents = archived
+		run.ItemsInserted += archived
+		if !forwardsBefore.IsZero() {
+			run.ResumePoint = forwardsBefore.Format(time.DateOnly)
+		}
+
+		channels := a.writer(ChannelSnapshots, channelHeader)
+		archived, err = database.ArchiveChannelSnapshots(before, func(snapshot *db.ChannelSnapshot) error {
+			return channels.write(snapshot.Timestamp, channelRecord(snapshot))
+		})
+		if closeErr := channels.close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to archive channel snapshots: %w", err)
+		}
+		result.ChannelSnapshots = archived
+		run.ItemsInserted += archived
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// monthWriter appends rows, oldest first, to the file of their month
+type monthWriter struct {
+	dir    string
+	header []string
+	month  string
+	file   *os.File
+	gz     *gzip.Writer
+	csv    *csv.Writer
+}
+
+func (a *Archive) writer(table string, header []string) *monthWriter {
+	return &monthWriter{dir: filepath.Join(a.dir, table), header: header}
+}
+
+func (w *monthWriter) write(timestamp time.Time, record []string) error {
+	if month := timestamp.UTC().Format(monthLayout); month != w.month {
+		if err := w.close(); err != nil {
+			return err
+		}
+		if err := w.open(month); err != nil {
+			return err
+		}
+	}
+	return w.csv.Write(record)
+}
+
+// open starts a gzip member at the end of the month's file; readers see the
+// members of a file as one stream
+func (w *monthWriter) open(month string) error {
+	if err := os.MkdirAll(w.dir, 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(w.dir, month+".csv.gz"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.month, w.file = month, file
+	w.gz = gzip.NewWriter(file)
+	w.csv = csv.NewWriter(w.gz)
+	if info.Size() == 0 {
+		return w.csv.Write(w.header)
+	}
+	return nil
+}
+
+// close finishes the current month's file and syncs it to disk
+func (w *monthWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	file := w.file
+	w.file, w.month = nil, ""
+	w.csv.Flush()
+	err := w.csv.Error()
+	if closeErr := w.gz.Close(); err == nil {
+		err = closeErr
+	}
+	if syncErr := file.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func forwardingRecord(e *db.ForwardingEvent) []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10), e.Timestamp.UTC().Format(time.RFC3339Nano), e.ChannelInID, e.ChannelOutID,
+		strconv.FormatInt(e.AmountIn, 10), strconv.FormatInt(e.AmountOut, 10),
+		strconv.FormatInt(e.Fee, 10), strconv.FormatInt(e.FeePPM, 10),
+	}
+}
+
+func channelRecord(s *db.ChannelSnapshot) []string {
+	return []string{
+		strconv.FormatInt(s.ID, 10), s.Timestamp.UTC().Format(time.RFC3339Nano), s.ChannelID,
+		strconv.FormatInt(s.Capacity, 10), strconv.FormatInt(s.LocalBalance, 10), strconv.FormatInt(s.RemoteBalance, 10),
+		strconv.FormatBool(s.Active), s.PeerAlias, strconv.FormatInt(s.FeePPM, 10), strconv.FormatInt(s.BaseFee, 10),
+	}
+}
+
+// EachForwardingEvent calls fn for every archived forwarding event within a
+// time range, oldest first. One month is read into memory at a time.
+func (a *Archive) EachForwardingEvent(from, to time.Time, fn func(*db.ForwardingEvent) error) error {
+	return a.each(ForwardingEvents, len(forwardingHeader), from, to, func(r *record) (archivedRow, error) {
+		e := &db.ForwardingEvent{
+			ID: r.int(0), Timestamp: r.time(1), ChannelInID: r.fields[2], ChannelOutID: r.fields[3],
+			AmountIn: r.int(4), AmountOut: r.int(5), Fee: r.int(6), FeePPM: r.int(7),
+		}
+		return archivedRow{id: e.ID, timestamp: e.Timestamp, emit: func() error { return fn(e) }}, r.err
+	})
+}
+
+// EachChannelSnapshot calls fn for every archived snapshot of channelID
+// within a time range, oldest first. One month is read into memory at a time.
+func (a *Archive) EachChannelSnapshot(channelID string, from, to time.Time, fn func(*db.ChannelSnapshot) error) error {
+	return a.each(ChannelSnapshots, len(channelHeader), from, to, func(r *record) (archivedRow, error) {
+		if r.fields[2] != channelID {
+			return archivedRow{}, nil
+		}
+		s := &db.ChannelSnapshot{
+			ID: r.int(0), Timestamp: r.time(1), ChannelID: r.fields[2],
+			Capacity: r.int(3), LocalBalance: r.int(4), RemoteBalance: r.int(5), Active: r.bool(6),
+			PeerAlias: r.fields[7], FeePPM: r.int(8), BaseFee: r.int(9),
+		}
+		return archivedRow{id: s.ID, timestamp: s.Timestamp, emit: func() error { return fn(s) }}, r.err
+	})
+}
+
+// record parses the fields of a CSV line, keeping the first error
+type record struct {
+	fields []string
+	err    error
+}
+
+func (r *record) int(i int) int64 {
+	value, err := strconv.ParseInt(r.fields[i], 10, 64)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("%s: %w", r.fields[i], err)
+	}
+	return value
+}
+
+func (r *record) bool(i int) bool {
+	value, err := strconv.ParseBool(r.fields[i])
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return value
+}
+
+func (r *record) time(i int) time.Time {
+	value, err := time.Parse(time.RFC3339Nano, r.fields[i])
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return value
+}
+
+// archivedRow is a parsed row waiting for its month to be sorted. Parsers
+// leave emit nil for rows the caller does not want.
+type archivedRow struct {
+	id        int64
+	timestamp time.Time
+	emit      func() error
+}
+
+// each reads the months of table overlapping from-to, sorts each month's
+// rows by time and passes those within the range to their emit, once per ID
+func (a *Archive) each(table string, fields int, from, to time.Time, parse func(*record) (archivedRow, error)) error {
+	months, err := a.months(table, from, to)
+	if err != nil {
+		return err
+	}
+	for _, month := range months {
+		rows, err := readMonth(month, fields, parse)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", month, err)
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			if !rows[i].timestamp.Equal(rows[j].timestamp) {
+				return rows[i].timestamp.Before(rows[j].timestamp)
+			}
+			return rows[i].id < rows[j].id
+		})
+		seen := make(map[int64]bool, len(rows))
+		for _, row := range rows {
+			if seen[row.id] || row.timestamp.Before(from) || row.timestamp.After(to) {
+				continue
+			}
+			seen[row.id] = true
+			if err := row.emit(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// months returns the files of table whose month overlaps from-to, oldest first
+func (a *Archive) months(table string, from, to time.Time) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(a.dir, table))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// ReadDir sorts by name, which for YYYY-MM is oldest first
+	var files []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".csv.gz")
+		if !ok {
+			continue
+		}
+		start, err := time.Parse(monthLayout, name)
+		if err != nil {
+			continue
+		}
+		if start.AddDate(0, 1, 0).Before(from) || start.After(to) {
+			continue
+		}
+		files = append(files, filepath.Join(a.dir, table, entry.Name()))
+	}
+	return files, nil
+}
+
+func readMonth(path string, fields int, parse func(*record) (archivedRow, error)) ([]archivedRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	reader := csv.NewReader(gz)
+	reader.FieldsPerRecord = fields
+	var rows []archivedRow
+	for line := 1; ; line++ {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 {
+			continue // header
+		}
+		row, err := parse(&record{fields: values})
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if row.emit != nil {
+			rows = append(rows, row)
+		}
+	}
+}
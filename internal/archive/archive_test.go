@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestRun(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	start := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	for day := 0; day < 60; day += 5 {
+		timestamp := start.AddDate(0, 0, day)
+		testutils.AssertNoError(t, database.InsertForwardingEvent(&db.ForwardingEvent{
+			Timestamp: timestamp, ChannelInID: "1", ChannelOutID: "2", AmountIn: 100010, AmountOut: 100000, Fee: 10,
+		}))
+		// Twice a day, so every other snapshot is a delta of the day's keyframe
+		for _, at := range []time.Time{timestamp, timestamp.Add(6 * time.Hour)} {
+			testutils.AssertNoError(t, database.InsertChannelSnapshots([]db.ChannelSnapshot{
+				{Timestamp: at, ChannelID: "2", Capacity: 1000000, LocalBalance: int64(day) * 1000, PeerAlias: "peer"},
+			}))
+		}
+	}
+	end := start.AddDate(0, 0, 60)
+	testutils.AssertNoError(t, database.MaterializeDailySummaries(end))
+	feesBefore, err := database.GetForwardingEventsFees(start.AddDate(0, 0, -1), end)
+	testutils.AssertNoError(t, err)
+
+	archive := New(filepath.Join(t.TempDir(), "archive"))
+	before := time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+	result, err := archive.Run(database, before)
+	testutils.AssertNoError(t, err)
+	// Jan 10 through Feb 14, every 5 days
+	testutils.AssertEqual(t, result.ForwardingEvents, int64(8))
+	testutils.AssertEqual(t, result.ForwardsBefore, before)
+	archivedBefore, err := database.ArchivedBefore()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, archivedBefore, before)
+
+	// Fee charts keep the archived days
+	feesAfter, err := database.GetForwardingEventsFees(start.AddDate(0, 0, -1), end)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(feesAfter), len(feesBefore))
+	for i := range feesBefore {
+		testutils.AssertEqual(t, feesAfter[i], feesBefore[i])
+	}
+
+	var forwards []db.ForwardingEvent
+	testutils.AssertNoError(t, archive.EachForwardingEvent(time.Time{}, end, func(e *db.ForwardingEvent) error {
+		forwards = append(forwards, *e)
+		return nil
+	}))
+	testutils.AssertEqual(t, len(forwards), 8)
+	testutils.AssertEqual(t, forwards[0].Timestamp.Equal(start), true)
+	testutils.AssertEqual(t, forwards[0].Fee, int64(10))
+
+	// The channel keeps its rows from the keyframe before the cut, so what is
+	// left still rebuilds
+	var archived []db.ChannelSnapshot
+	testutils.AssertNoError(t, archive.EachChannelSnapshot("2", time.Time{}, end, func(s *db.ChannelSnapshot) error {
+		archived = append(archived, *s)
+		return nil
+	}))
+	testutils.AssertEqual(t, int64(len(archived)), result.ChannelSnapshots)
+	last := archived[len(archived)-1]
+	testutils.AssertEqual(t, last.PeerAlias, "peer")
+	testutils.AssertEqual(t, last.Capacity, int64(1000000))
+	live, err := database.GetChannelSnapshots("2", time.Time{}, end)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(archived)+len(live), 24)
+	testutils.AssertEqual(t, live[0].Capacity, int64(1000000))
+
+	// A second run has nothing left to archive, and rows written twice by a
+	// run that stopped before deleting them are read once
+	result, err = archive.Run(database, before)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, result.ForwardingEvents, int64(0))
+	again := archive.writer(ForwardingEvents, forwardingHeader)
+	testutils.AssertNoError(t, again.write(forwards[0].Timestamp, forwardingRecord(&forwards[0])))
+	testutils.AssertNoError(t, again.close())
+	count := 0
+	testutils.AssertNoError(t, archive.EachForwardingEvent(time.Time{}, end, func(*db.ForwardingEvent) error {
+		count++
+		return nil
+	}))
+	testutils.AssertEqual(t, count, 8)
+
+	// Forwards not yet summarized stay in the database
+	result, err = archive.Run(database, end.AddDate(0, 1, 0))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, result.ForwardsBefore.Before(end), true)
+
+	// A late forward on an archived day does not replace the day's summary
+	testutils.AssertNoError(t, database.InsertForwardingEvent(&db.ForwardingEvent{
+		Timestamp: start.Add(time.Hour), ChannelInID: "1", ChannelOutID: "2", AmountIn: 50005, AmountOut: 50000, Fee: 5,
+	}))
+	feesAfter, err = database.GetForwardingEventsFees(start.AddDate(0, 0, -1), end)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, feesAfter[0], feesBefore[0])
+}
+
+func TestEachWithoutArchive(t *testing.T) {
+	archive := New(filepath.Join(t.TempDir(), "missing"))
+	testutils.AssertNoError(t, archive.EachForwardingEvent(time.Time{}, time.Now(), func(*db.ForwardingEvent) error {
+		t.Fatal("expected no forwards")
+		return nil
+	}))
+	testutils.AssertEqual(t, DirFor("data/portfolio.db"), "data/portfolio-archive")
+}
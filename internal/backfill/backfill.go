@@ -0,0 +1,113 @@
+// Package backfill rebuilds per-day address balance history from on-chain
+// transactions and compares it with what is already stored.
+package backfill
+
+import (
+	"sort"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// DailyBalances turns confirmed movements into one balance per UTC day from
+// from through to. Movements before from still count towards the opening
+// balance, so the full history should be passed in. Each row's timestamp is
+// the start of its day and its balance is the balance at the end of that day.
+// TxCount is the number of transactions confirmed that day, and BlockHeight
+// and BlockHash name the highest block of any movement up to the day's end.
+func DailyBalances(addressID int64, movements []bitcoin.BalanceMovement, from, to time.Time) []db.AddressBalance {
+	from = startOfDay(from)
+	to = startOfDay(to)
+	if to.Before(from) {
+		return nil
+	}
+
+	sorted := make([]bitcoin.BalanceMovement, len(movements))
+	copy(sorted, movements)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Blocktime < sorted[j].Blocktime
+	})
+
+	var balances []db.AddressBalance
+	var running int64
+	var block db.BlockRef
+	next := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1).Unix()
+		txids := make(map[string]bool)
+		for ; next < len(sorted) && sorted[next].Blocktime < end; next++ {
+			running += sorted[next].Amount
+			if sorted[next].Blocktime >= day.Unix() {
+				txids[sorted[next].TxID] = true
+			}
+			if sorted[next].Height >= block.Height {
+				block = db.BlockRef{Height: sorted[next].Height, Hash: sorted[next].BlockHash}
+			}
+		}
+		balances = append(balances, db.AddressBalance{
+			AddressID:   addressID,
+			Timestamp:   day,
+			Balance:     running,
+			TxCount:     int64(len(txids)),
+			BlockHeight: block.Height,
+			BlockHash:   block.Hash,
+		})
+	}
+	return balances
+}
+
+// FirstActivity returns the day of the earliest movement, or false if there
+// are none
+func FirstActivity(movements []bitcoin.BalanceMovement) (time.Time, bool) {
+	if len(movements) == 0 {
+		return time.Time{}, false
+	}
+	first := movements[0].Blocktime
+	for _, m := range movements[1:] {
+		if m.Blocktime < first {
+			first = m.Blocktime
+		}
+	}
+	return startOfDay(time.Unix(first, 0)), true
+}
+
+// Change is a day whose computed balance is missing from or differs from the
+// stored history
+type Change struct {
+	Date time.Time
+	// Old is the stored balance for the day, nil when there is none
+	Old *db.AddressBalance
+	New db.AddressBalance
+}
+
+// Diff compares computed daily balances with stored rows and returns the
+// days that would be added or changed by a merge, including days whose
+// balance is unchanged but now comes from a different block. When several
+// rows are stored for one day the latest is compared.
+func Diff(existing, computed []db.AddressBalance) []Change {
+	stored := make(map[time.Time]db.AddressBalance)
+	for _, balance := range existing {
+		day := startOfDay(balance.Timestamp)
+		if prev, ok := stored[day]; !ok || balance.Timestamp.After(prev.Timestamp) {
+			stored[day] = balance
+		}
+	}
+
+	var changes []Change
+	for _, balance := range computed {
+		day := startOfDay(balance.Timestamp)
+		old, ok := stored[day]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Date: day, New: balance})
+		case old.Balance != balance.Balance || old.TxCount != balance.TxCount || old.BlockHash != balance.BlockHash:
+			changes = append(changes, Change{Date: day, Old: &old, New: balance})
+		}
+	}
+	return changes
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
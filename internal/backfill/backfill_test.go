@@ -0,0 +1,72 @@
+package backfill
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestDailyBalances(t *testing.T) {
+	day := func(d, hour int) int64 {
+		return time.Date(2024, 1, d, hour, 0, 0, 0, time.UTC).Unix()
+	}
+	movements := []bitcoin.BalanceMovement{
+		{TxID: "c", Height: 400, BlockHash: "h400", Blocktime: day(4, 9), Amount: -30000},
+		{TxID: "a", Height: 100, BlockHash: "h100", Blocktime: day(1, 12), Amount: 100000},
+		{TxID: "b", Height: 300, BlockHash: "h300", Blocktime: day(3, 8), Amount: 50000},
+		{TxID: "b2", Height: 310, BlockHash: "h310", Blocktime: day(3, 20), Amount: 1000},
+	}
+
+	// Starting after the first movement still includes it in the balance
+	from := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 4, 23, 0, 0, 0, time.UTC)
+	balances := DailyBalances(7, movements, from, to)
+
+	testutils.AssertEqual(t, len(balances), 3)
+	testutils.AssertEqual(t, balances[0].Timestamp, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	testutils.AssertEqual(t, balances[0].Balance, int64(100000))
+	testutils.AssertEqual(t, balances[0].TxCount, int64(0))
+	testutils.AssertEqual(t, balances[0].BlockHash, "h100")
+	testutils.AssertEqual(t, balances[1].Balance, int64(151000))
+	testutils.AssertEqual(t, balances[1].TxCount, int64(2))
+	testutils.AssertEqual(t, balances[1].BlockHeight, int64(310))
+	testutils.AssertEqual(t, balances[1].BlockHash, "h310")
+	testutils.AssertEqual(t, balances[2].Balance, int64(121000))
+	testutils.AssertEqual(t, balances[2].AddressID, int64(7))
+
+	first, ok := FirstActivity(movements)
+	testutils.AssertEqual(t, ok, true)
+	testutils.AssertEqual(t, first, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestDiff(t *testing.T) {
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day2.AddDate(0, 0, 1)
+
+	existing := []db.AddressBalance{
+		{Timestamp: day1.Add(6 * time.Hour), Balance: 500},
+		{Timestamp: day2.Add(6 * time.Hour), Balance: 100},
+		// The latest row of the day is the one compared
+		{Timestamp: day2.Add(18 * time.Hour), Balance: 900},
+		{Timestamp: day3, Balance: 1000, BlockHash: "orphaned"},
+	}
+	computed := []db.AddressBalance{
+		{Timestamp: day1, Balance: 500},
+		{Timestamp: day2, Balance: 1000},
+		// Same balance, but the transaction was mined again in another block
+		{Timestamp: day3, Balance: 1000, BlockHash: "replacement"},
+		{Timestamp: day3.AddDate(0, 0, 1), Balance: 1000},
+	}
+
+	changes := Diff(existing, computed)
+	testutils.AssertEqual(t, len(changes), 3)
+	testutils.AssertEqual(t, changes[0].Date, day2)
+	testutils.AssertEqual(t, changes[0].Old.Balance, int64(900))
+	testutils.AssertEqual(t, changes[1].Date, day3)
+	testutils.AssertEqual(t, changes[1].New.BlockHash, "replacement")
+	testutils.AssertEqual(t, changes[2].Old == nil, true)
+}
@@ -0,0 +1,42 @@
+package backfill
+
+import (
+	"fmt"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// DefaultReorgDepth is how many blocks below the tip stored history is
+// checked against. Re-orgs deeper than this are not expected.
+const DefaultReorgDepth = 100
+
+// BlockHashes looks up blocks in the active chain, e.g. *bitcoin.Client
+type BlockHashes interface {
+	GetBlockHash(height int64) (string, error)
+}
+
+// FindReorg checks the blocks that stored address balances from the last
+// depth blocks were derived from against the active chain, whose tip is at
+// height tip. It returns the lowest stored block that is no longer in the
+// chain, or nil when every one still is.
+func FindReorg(database *db.Database, chain BlockHashes, tip int64, depth int64) (*db.BlockRef, error) {
+	blocks, err := database.GetAddressBalanceBlocks(tip - depth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored blocks: %w", err)
+	}
+
+	for _, block := range blocks {
+		// A chain that got shorter no longer has the block at all
+		if block.Height > tip {
+			return &block, nil
+		}
+		hash, err := chain.GetBlockHash(block.Height)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block hash at height %d: %w", block.Height, err)
+		}
+		if hash != block.Hash {
+			return &block, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,56 @@
+package backfill
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+// fakeChain maps heights to the active chain's block hashes
+type fakeChain map[int64]string
+
+func (c fakeChain) GetBlockHash(height int64) (string, error) {
+	hash, ok := c[height]
+	if !ok {
+		return "", fmt.Errorf("block height %d out of range", height)
+	}
+	return hash, nil
+}
+
+func TestFindReorg(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	address, err := database.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Test")
+	testutils.AssertNoError(t, err)
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	err = database.MergeAddressBalances(address.ID, []db.AddressBalance{
+		{Timestamp: day, Balance: 1000, BlockHeight: 10, BlockHash: "old"},
+		{Timestamp: day.AddDate(0, 0, 1), Balance: 2000, BlockHeight: 195, BlockHash: "a195"},
+		{Timestamp: day.AddDate(0, 0, 2), Balance: 3000, BlockHeight: 198, BlockHash: "a198"},
+	})
+	testutils.AssertNoError(t, err)
+
+	chain := fakeChain{195: "a195", 198: "a198", 199: "a199"}
+	reorg, err := FindReorg(database, chain, 199, 10)
+	testutils.AssertNoError(t, err)
+	if reorg != nil {
+		t.Fatalf("Expected no re-org, got %+v", reorg)
+	}
+
+	// Block 198 was replaced; block 10 is below the checked depth and never looked up
+	chain[198] = "b198"
+	reorg, err = FindReorg(database, chain, 199, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, *reorg, db.BlockRef{Height: 198, Hash: "a198"})
+
+	// A chain that got shorter lost the block too
+	reorg, err = FindReorg(database, fakeChain{195: "a195"}, 196, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, reorg.Height, int64(198))
+}
@@ -0,0 +1,164 @@
+package bitcoin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+)
+
+const (
+	// DefaultBalanceCacheTTL is how long address balances are cached by default
+	DefaultBalanceCacheTTL = 45 * time.Second
+	// hotEntryHits is how many reads of one cached balance make it hot
+	hotEntryHits = 3
+)
+
+// BalanceCache stores recent balance queries with TTL. Concurrent loads of
+// the same address are collapsed into one, and hot entries are refreshed in
+// the background shortly before they expire, so a dashboard reloading many
+// addresses at once does not hit Bitcoin Core for each of them.
+type BalanceCache struct {
+	entries  map[string]*CacheEntry
+	inflight map[string]*balanceLoad
+	mutex    sync.Mutex
+	ttl      time.Duration
+}
+
+// CacheEntry represents a cached balance result
+type CacheEntry struct {
+	Balance     int64
+	Confirmed   int64
+	Unconfirmed int64
+	TxCount     int64
+	Timestamp   time.Time
+	Address     string
+	// MinConfirmations is the threshold Confirmed was split at
+	MinConfirmations int64
+
+	// hits counts reads since the entry was loaded
+	hits int
+}
+
+// balanceLoad is a load in progress that callers can wait on
+type balanceLoad struct {
+	done  chan struct{}
+	entry *CacheEntry
+	err   error
+}
+
+// NewBalanceCache creates a cache whose entries expire after ttl
+func NewBalanceCache(ttl time.Duration) *BalanceCache {
+	return &BalanceCache{
+		entries:  make(map[string]*CacheEntry),
+		inflight: make(map[string]*balanceLoad),
+		ttl:      ttl,
+	}
+}
+
+// SetTTL changes the expiry of cached entries, including ones already stored
+func (c *BalanceCache) SetTTL(ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ttl = ttl
+}
+
+// Fetch returns the cached balance for address, or loads it with load when
+// it is missing or expired. Callers that arrive while a load is running wait
+// for it instead of starting their own. A hot entry read in the last quarter
+// of its TTL is refreshed in the background while the cached value is
+// returned. cached reports whether the result came from the cache. If ctx is
+// done before the load finishes Fetch returns ctx.Err(); the load continues
+// and its result is still cached.
+func (c *BalanceCache) Fetch(ctx context.Context, address string, load func() (*CacheEntry, error)) (entry *CacheEntry, cached bool, err error) {
+	c.mutex.Lock()
+	if entry, ok := c.entries[address]; ok {
+		age := time.Since(entry.Timestamp)
+		if age <= c.ttl {
+			entry.hits++
+			if entry.hits >= hotEntryHits && age >= c.ttl*3/4 {
+				c.startLoad(address, load)
+			}
+			c.mutex.Unlock()
+			metrics.CacheLookup("address_balance", true)
+			return entry, true, nil
+		}
+	}
+	call := c.startLoad(address, load)
+	c.mutex.Unlock()
+	metrics.CacheLookup("address_balance", false)
+
+	select {
+	case <-call.done:
+		return call.entry, false, call.err
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// startLoad runs load for address unless one is already running. The caller
+// must hold c.mutex.
+func (c *BalanceCache) startLoad(address string, load func() (*CacheEntry, error)) *balanceLoad {
+	if call, ok := c.inflight[address]; ok {
+		return call
+	}
+
+	call := &balanceLoad{done: make(chan struct{})}
+	c.inflight[address] = call
+	go func() {
+		call.entry, call.err = load()
+
+		c.mutex.Lock()
+		delete(c.inflight, address)
+		if call.err == nil {
+			// A refreshed entry stays hot so it keeps being refreshed while read
+			if old, ok := c.entries[address]; ok && old.hits >= hotEntryHits {
+				call.entry.hits = hotEntryHits
+			}
+			c.entries[address] = call.entry
+			c.deleteExpired()
+		}
+		c.mutex.Unlock()
+
+		close(call.done)
+	}()
+	return call
+}
+
+// Get retrieves a cached balance if still valid
+func (c *BalanceCache) Get(address string) *CacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, exists := c.entries[address]
+	if !exists {
+		return nil
+	}
+
+	// Check if entry has expired
+	if time.Since(entry.Timestamp) > c.ttl {
+		return nil
+	}
+
+	return entry
+}
+
+// Set stores a balance in cache
+func (c *BalanceCache) Set(address string, entry *CacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[address] = entry
+	c.deleteExpired()
+}
+
+// deleteExpired removes expired cache entries. The caller must hold c.mutex.
+func (c *BalanceCache) deleteExpired() {
+	now := time.Now()
+	for addr, entry := range c.entries {
+		if now.Sub(entry.Timestamp) > c.ttl {
+			delete(c.entries, addr)
+		}
+	}
+}
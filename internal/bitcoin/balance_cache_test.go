@@ -0,0 +1,118 @@
+package bitcoin
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestBalanceCacheFetchLoadsOnce(t *testing.T) {
+	cache := NewBalanceCache(time.Minute)
+
+	var loads int32
+	release := make(chan struct{})
+	load := func() (*CacheEntry, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return &CacheEntry{Address: "bc1qtest", Balance: 1000, Timestamp: time.Now()}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*CacheEntry, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			entry, _, err := cache.Fetch(context.Background(), "bc1qtest", load)
+			testutils.AssertNoError(t, err)
+			results[i] = entry
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	testutils.AssertEqual(t, atomic.LoadInt32(&loads), int32(1))
+	for _, entry := range results {
+		testutils.AssertEqual(t, entry.Balance, int64(1000))
+	}
+
+	_, cached, err := cache.Fetch(context.Background(), "bc1qtest", load)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, cached, true)
+	testutils.AssertEqual(t, atomic.LoadInt32(&loads), int32(1))
+}
+
+func TestBalanceCacheRefreshesHotEntries(t *testing.T) {
+	cache := NewBalanceCache(200 * time.Millisecond)
+
+	var loads int32
+	load := func() (*CacheEntry, error) {
+		n := atomic.AddInt32(&loads, 1)
+		return &CacheEntry{Address: "bc1qtest", Balance: int64(n), Timestamp: time.Now()}, nil
+	}
+
+	for i := 0; i < hotEntryHits; i++ {
+		_, _, err := cache.Fetch(context.Background(), "bc1qtest", load)
+		testutils.AssertNoError(t, err)
+	}
+
+	// Past the refresh point the cached value is served while a reload runs
+	time.Sleep(160 * time.Millisecond)
+	entry, cached, err := cache.Fetch(context.Background(), "bc1qtest", load)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, cached, true)
+	testutils.AssertEqual(t, entry.Balance, int64(1))
+
+	time.Sleep(50 * time.Millisecond)
+	refreshed := cache.Get("bc1qtest")
+	if refreshed == nil {
+		t.Fatal("expected refreshed entry in cache")
+	}
+	testutils.AssertEqual(t, refreshed.Balance, int64(2))
+}
+
+func TestBalanceCacheDoesNotCacheErrors(t *testing.T) {
+	cache := NewBalanceCache(time.Minute)
+
+	_, _, err := cache.Fetch(context.Background(), "bc1qtest", func() (*CacheEntry, error) {
+		return nil, errors.New("bitcoin core unavailable")
+	})
+	testutils.AssertError(t, err, "bitcoin core unavailable")
+
+	entry, cached, err := cache.Fetch(context.Background(), "bc1qtest", func() (*CacheEntry, error) {
+		return &CacheEntry{Address: "bc1qtest", Balance: 5, Timestamp: time.Now()}, nil
+	})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, cached, false)
+	testutils.AssertEqual(t, entry.Balance, int64(5))
+}
+
+func TestBalanceCacheFetchStopsWaitingOnCancel(t *testing.T) {
+	cache := NewBalanceCache(time.Minute)
+
+	release := make(chan struct{})
+	load := func() (*CacheEntry, error) {
+		<-release
+		return &CacheEntry{Address: "bc1qtest", Balance: 7, Timestamp: time.Now()}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err := cache.Fetch(ctx, "bc1qtest", load)
+	testutils.AssertError(t, err, "context deadline exceeded")
+
+	// The abandoned load still completes and fills the cache
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	entry, cached, err := cache.Fetch(context.Background(), "bc1qtest", load)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, cached, true)
+	testutils.AssertEqual(t, entry.Balance, int64(7))
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
 )
 
 // BalanceService handles periodic balance updates from Bitcoin Core
@@ -74,22 +75,24 @@ func (s *BalanceService) updateAllBalances() {
 	}
 
 	successCount := 0
+	activeCount := 0
 	for _, address := range addresses {
 		if !address.Active {
-			continue // Skip inactive addresses
+			continue // Skip paused addresses, their history is kept
 		}
+		activeCount++
 
 		balance, err := s.updateAddressBalance(address)
 		if err != nil {
-			log.Printf("Failed to update balance for %s: %v", address.Address, err)
+			log.Printf("Failed to update balance for %s: %v", redact.Address(address.Address), err)
 			continue
 		}
 
-		log.Printf("Updated balance for %s: %d satoshis", address.Address, balance)
+		log.Printf("Updated balance for %s: %s", redact.Address(address.Address), redact.Sats(balance))
 		successCount++
 	}
 
-	log.Printf("Successfully updated %d/%d addresses", successCount, len(addresses))
+	log.Printf("Successfully updated %d/%d active addresses", successCount, activeCount)
 }
 
 // updateAddressBalance updates the balance for a specific address
@@ -97,7 +100,7 @@ func (s *BalanceService) updateAddressBalance(address db.OnchainAddress) (int64,
 	// Get UTXOs to derive both balance and transaction count
 	utxos, err := s.client.GetAddressUTXOs(address.Address)
 	if err != nil {
-		log.Printf("Failed to get UTXOs for %s: %v", address.Address, err)
+		log.Printf("Failed to get UTXOs for %s: %v", redact.Address(address.Address), err)
 		return 0, err
 	}
 
@@ -127,8 +130,8 @@ func (s *BalanceService) updateAddressBalance(address db.OnchainAddress) (int64,
 		return 0, err
 	}
 
-	log.Printf("Inserted balance record for %s: %d satoshis (%d txs)",
-		address.Address, balance, txCount)
+	log.Printf("Inserted balance record for %s: %s (%d txs)",
+		redact.Address(address.Address), redact.Sats(balance), txCount)
 
 	return balance, nil
 }
@@ -161,7 +164,7 @@ func (s *BalanceService) GetAddressCurrentBalance(address string) (int64, error)
 }
 
 // ImportAndTrackAddress imports an address and starts tracking it
-func (s *BalanceService) ImportAndTrackAddress(address, label string) (*db.OnchainAddress, error) {
+func (s *BalanceService) ImportAndTrackAddress(address, label string, birthday Birthday) (*db.OnchainAddress, error) {
 	// First validate the address
 	validation, err := s.client.ValidateAddress(address)
 	if err != nil {
@@ -171,10 +174,10 @@ func (s *BalanceService) ImportAndTrackAddress(address, label string) (*db.Oncha
 		return nil, ErrInvalidAddress
 	}
 
-	// Import address to Bitcoin Core as watch-only
-	err = s.client.ImportAddress(address)
+	// Import address to Bitcoin Core as watch-only, rescanning from its birthday
+	err = s.client.ImportAddressFrom(address, birthday)
 	if err != nil {
-		log.Printf("Warning: Failed to import address %s: %v", address, err)
+		log.Printf("Warning: Failed to import address %s: %v", redact.Address(address), err)
 		// Continue anyway - address might already be imported
 	}
 
@@ -187,7 +190,7 @@ func (s *BalanceService) ImportAndTrackAddress(address, label string) (*db.Oncha
 	// Update balance immediately
 	_, err = s.updateAddressBalance(*dbAddress)
 	if err != nil {
-		log.Printf("Warning: Failed to update initial balance for %s: %v", address, err)
+		log.Printf("Warning: Failed to update initial balance for %s: %v", redact.Address(address), err)
 		// Don't fail the import due to balance update failure
 	}
 
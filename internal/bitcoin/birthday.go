@@ -0,0 +1,50 @@
+package bitcoin
+
+import (
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// Birthday is when a tracked address or wallet was created. Imports,
+// rescans and external history lookups skip everything before it, which
+// saves most of the work for recently created wallets. The zero Birthday
+// scans from genesis.
+type Birthday struct {
+	// Height is the first block that can contain the wallet's transactions
+	Height int64
+	// Time is the earliest block time, for when the height is not known
+	Time time.Time
+}
+
+// BirthdayOf returns the birthday recorded for a tracked address or xpub
+func BirthdayOf(addr db.OnchainAddress) Birthday {
+	birthday := Birthday{Height: addr.BirthHeight}
+	if addr.BirthDate != nil {
+		birthday.Time = *addr.BirthDate
+	}
+	return birthday
+}
+
+// IsZero reports whether no birthday is set
+func (b Birthday) IsZero() bool {
+	return b.Height == 0 && b.Time.IsZero()
+}
+
+// includes reports whether a block at height with blocktime is on or after
+// the birthday
+func (b Birthday) includes(height, blocktime int64) bool {
+	if height < b.Height {
+		return false
+	}
+	return b.Time.IsZero() || blocktime >= b.Time.Unix()
+}
+
+// after returns the birthday raised to at least height, e.g. to start at a
+// pruned node's prune height
+func (b Birthday) after(height int64) Birthday {
+	if height > b.Height {
+		b.Height = height
+	}
+	return b
+}
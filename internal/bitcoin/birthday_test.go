@@ -0,0 +1,76 @@
+package bitcoin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestBirthdayIncludes(t *testing.T) {
+	birthDate := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	testutils.AssertEqual(t, Birthday{}.includes(0, 0), true)
+	testutils.AssertEqual(t, Birthday{Height: 100}.includes(99, 0), false)
+	testutils.AssertEqual(t, Birthday{Height: 100}.includes(100, 0), true)
+	testutils.AssertEqual(t, Birthday{Time: birthDate}.includes(900000, birthDate.Unix()-1), false)
+	testutils.AssertEqual(t, Birthday{Time: birthDate}.includes(900000, birthDate.Unix()), true)
+
+	// Raising to the prune height only ever moves the birthday later
+	testutils.AssertEqual(t, Birthday{Height: 100}.after(50).Height, int64(100))
+	testutils.AssertEqual(t, Birthday{Height: 100}.after(150).Height, int64(150))
+
+	birthday := BirthdayOf(db.OnchainAddress{BirthHeight: 832000, BirthDate: &birthDate})
+	testutils.AssertEqual(t, birthday.Height, int64(832000))
+	testutils.AssertEqual(t, birthday.Time.Equal(birthDate), true)
+	testutils.AssertEqual(t, BirthdayOf(db.OnchainAddress{}).IsZero(), true)
+}
+
+func TestImportTimestamp(t *testing.T) {
+	birthDate := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	testutils.AssertEqual(t, importTimestamp(false, birthDate), `"now"`)
+	testutils.AssertEqual(t, importTimestamp(true, time.Time{}), "0")
+	testutils.AssertEqual(t, importTimestamp(true, birthDate), "1709251200")
+}
+
+func TestEsploraMovementsFromBirthday(t *testing.T) {
+	const watched = "bc1qwatched"
+	confirmed := func(height int64) mempool.Status {
+		return mempool.Status{Confirmed: true, BlockHeight: height, BlockTime: height * 600}
+	}
+
+	txs := []mempool.Transaction{
+		{
+			// Before the birthday, e.g. an earlier owner of a reused address
+			TxID:   "old",
+			Vout:   []mempool.Output{{ScriptPubKeyAddr: watched, Value: 70000}},
+			Status: confirmed(10),
+		},
+		{
+			TxID:   "a",
+			Vout:   []mempool.Output{{ScriptPubKeyAddr: watched, Value: 100000}},
+			Status: confirmed(50),
+		},
+		{
+			// Spends both; only the output from the birthday on is counted
+			TxID: "b",
+			Vin: []mempool.Input{
+				{TxID: "old", Vout: 0, PrevOut: &mempool.Output{ScriptPubKeyAddr: watched, Value: 70000}},
+				{TxID: "a", Vout: 0, PrevOut: &mempool.Output{ScriptPubKeyAddr: watched, Value: 100000}},
+			},
+			Vout:   []mempool.Output{{ScriptPubKeyAddr: "bc1qother", Value: 169000}},
+			Status: confirmed(80),
+		},
+	}
+
+	movements := esploraMovements(txs, map[string]bool{watched: true}, Birthday{Height: 40}, 100)
+
+	testutils.AssertEqual(t, len(movements), 2)
+	testutils.AssertEqual(t, movements[0].TxID, "a")
+	testutils.AssertEqual(t, movements[0].Amount, int64(100000))
+	testutils.AssertEqual(t, movements[1].TxID, "b")
+	testutils.AssertEqual(t, movements[1].Amount, int64(-100000))
+}
@@ -1,6 +1,7 @@
 package bitcoin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,6 +9,11 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/capture"
+	"github.com/brewgator/lightning-node-tools/internal/tracing"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
 )
 
 // Client represents a Bitcoin Core RPC client
@@ -17,7 +23,11 @@ type Client struct{}
 // This prevents command injection attacks by only allowing known-safe commands
 var allowedCommands = map[string]bool{
 	"getblockchaininfo": true,
+	"getblockhash":      true,
+	"getnetworkinfo":    true,
 	"getdescriptorinfo": true,
+	"deriveaddresses":   true,
+	"gettransaction":    true,
 	"importdescriptors": true,
 	"listunspent":       true,
 	"listtransactions":  true,
@@ -25,6 +35,8 @@ var allowedCommands = map[string]bool{
 	"getaddressinfo":    true,
 	"rescanblockchain":  true,
 	"getwalletinfo":     true,
+	"scantxoutset":      true,
+	"estimatesmartfee":  true,
 }
 
 // addressRegex matches valid Bitcoin addresses (common formats)
@@ -76,8 +88,7 @@ func NewClient() (*Client, error) {
 	// Test Bitcoin Core connectivity (without wallet)
 	// Note: This command doesn't involve user input and is safe to call directly
 	// getblockchaininfo is also in the allowlist for wallet-based operations
-	cmd := exec.Command("bitcoin-cli", "getblockchaininfo")
-	_, err := cmd.Output()
+	_, err := runner("getblockchaininfo")
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Bitcoin Core: %w", err)
 	}
@@ -97,6 +108,19 @@ func NewClient() (*Client, error) {
 	return &Client{}, nil
 }
 
+// runner executes bitcoin-cli; tests replace it with canned responses through SetRunner
+var runner = func(args ...string) ([]byte, error) {
+	return exec.Command("bitcoin-cli", args...).Output()
+}
+
+// SetRunner replaces how bitcoin-cli is run, e.g. with the canned responses
+// in internal/fixtures, and returns a function that restores the previous runner
+func SetRunner(run func(args ...string) ([]byte, error)) (restore func()) {
+	previous := runner
+	runner = run
+	return func() { runner = previous }
+}
+
 // RunBitcoinCLI executes bitcoin-cli commands and returns the output.
 //
 // Security: This function implements multiple security measures to prevent command injection:
@@ -104,9 +128,17 @@ func NewClient() (*Client, error) {
 //   - Input sanitization: String parameters are checked for shell metacharacters
 //   - Structured execution: Uses exec.Command with separate arguments (no shell interpretation)
 //
+// The response is kept when debug capture is enabled, see internal/capture.
+//
 // The current implementation validates addresses using ValidateAddress() before import,
 // uses type-safe numeric parameters, and restricts command execution to a known-safe subset.
 func RunBitcoinCLI(args ...string) ([]byte, error) {
+	return RunBitcoinCLIContext(context.Background(), args...)
+}
+
+// RunBitcoinCLIContext is RunBitcoinCLI traced within the span of ctx. The
+// span includes the wait for the RPC rate limiter.
+func RunBitcoinCLIContext(ctx context.Context, args ...string) (output []byte, err error) {
 	// Require at least one argument (the command name)
 	if len(args) == 0 {
 		return nil, fmt.Errorf("no command specified")
@@ -125,19 +157,27 @@ func RunBitcoinCLI(args ...string) ([]byte, error) {
 		}
 	}
 
+	_, span := tracing.StartClient(ctx, "bitcoin-cli "+command, tracing.String("rpc.system", "bitcoin-cli"))
+	defer func() { span.End(err) }()
+	rpcLimiter.wait()
+
 	// Add wallet parameter for our tracking wallet
 	fullArgs := []string{"-rpcwallet=tracker_watchonly"}
 	fullArgs = append(fullArgs, args...)
 
-	cmd := exec.Command("bitcoin-cli", fullArgs...)
-	output, err := cmd.Output()
+	output, err = runner(fullArgs...)
 	if err != nil {
 		// If there's an error, try to get stderr for more details
 		if exitError, ok := err.(*exec.ExitError); ok {
 			// Include stderr in the error message
-			return nil, fmt.Errorf("bitcoin-cli command failed: %v, stderr: %s", err, string(exitError.Stderr))
+			err = fmt.Errorf("bitcoin-cli command failed: %v, stderr: %s", err, string(exitError.Stderr))
+		} else {
+			err = fmt.Errorf("bitcoin-cli command failed: %v", err)
 		}
-		return nil, fmt.Errorf("bitcoin-cli command failed: %v", err)
+	}
+	capture.Save("bitcoin-cli", fullArgs, output, err)
+	if err != nil {
+		return nil, err
 	}
 	return output, nil
 }
@@ -157,40 +197,94 @@ func (c *Client) GetBlockchainInfo() (*BlockchainInfo, error) {
 	return &info, nil
 }
 
-// GetAddressBalance gets the current balance for a specific address by summing UTXOs
+// GetVersion returns the Bitcoin Core version as major.minor.patch from the
+// numeric version getnetworkinfo reports, so 0.21.1 (210100) is "21.1.0"
+// and 26.0 (260000) is "26.0.0", and the two compare correctly.
+func (c *Client) GetVersion() (string, error) {
+	output, err := RunBitcoinCLI("getnetworkinfo")
+	if err != nil {
+		return "", err
+	}
+
+	var info struct {
+		Version int64 `json:"version"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d.%d.%d", info.Version/10000, info.Version/100%100, info.Version%100), nil
+}
+
+// GetAddressBalance gets the current balance for a specific address by summing UTXOs,
+// including unconfirmed ones.
 // Note: This requires the address to be imported as watch-only
 func (c *Client) GetAddressBalance(address string) (int64, error) {
-	// Validate address format before processing
-	if err := sanitizeAddress(address); err != nil {
-		return 0, fmt.Errorf("invalid address format: %w", err)
+	split, err := c.GetAddressBalanceSplit(address, 1)
+	if err != nil {
+		return 0, err
 	}
+	return split.Total(), nil
+}
 
-	// Import address as watch-only if not already imported
-	err := c.ImportAddress(address)
-	if err != nil {
-		// Import might fail if address is already imported, which is fine
-		// We'll continue and try to get the balance anyway
+// GetAddressBalanceSplit gets the current balance for an address with UTXOs
+// that have at least minConf confirmations summed separately from the rest
+func (c *Client) GetAddressBalanceSplit(address string, minConf int64) (*AddressBalanceSplit, error) {
+	// Validate address format before processing
+	if err := sanitizeAddress(address); err != nil {
+		return nil, fmt.Errorf("invalid address format: %w", err)
 	}
 
-	// Get UTXOs to calculate actual spendable balance
+	// GetAddressUTXOs imports the address as watch-only if needed
 	utxos, err := c.GetAddressUTXOs(address)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	// Sum up UTXO values to get current balance (convert BTC to satoshis)
-	var balanceBTC float64
+	return splitUTXOs(utxos, minConf), nil
+}
+
+// splitUTXOs sums utxos into those with at least minConf confirmations and
+// the rest. A minConf below one is treated as one.
+func splitUTXOs(utxos []UTXO, minConf int64) *AddressBalanceSplit {
+	split := &AddressBalanceSplit{MinConfirmations: max(minConf, 1)}
 	for _, utxo := range utxos {
-		balanceBTC += utxo.Amount
+		amount := btcToSats(utxo.Amount)
+		if utxo.Confirmations >= split.MinConfirmations {
+			split.Confirmed += amount
+			split.UTXOCount++
+		} else {
+			split.Unconfirmed += amount
+			split.UnconfirmedUTXOCount++
+		}
 	}
-
-	// Convert BTC to satoshis
-	balanceSatoshis := int64(balanceBTC * 100000000)
-	return balanceSatoshis, nil
+	return split
 }
 
 // ImportAddress imports an address as watch-only using descriptors
 func (c *Client) ImportAddress(address string) error {
+	return c.ImportAddressFrom(address, Birthday{})
+}
+
+// ImportAddressFrom imports an address as watch-only and rescans from its
+// birthday: from the birthday height when known, otherwise from its time,
+// or from genesis when no birthday is set
+func (c *Client) ImportAddressFrom(address string, birthday Birthday) error {
+	if birthday.Height == 0 {
+		return c.ImportAddressWithRescan(address, true, birthday.Time)
+	}
+	if err := c.ImportAddressWithRescan(address, false, time.Time{}); err != nil {
+		return err
+	}
+	return c.RescanBlockchain(birthday.Height)
+}
+
+// ImportAddressWithRescan imports an address as watch-only. With rescan the
+// wallet scans from blocks mined at since, or from genesis when since is
+// zero; without it the address is only watched from now on, which pruned
+// nodes need since they cannot rescan old blocks. Callers then rescan from
+// the prune height themselves.
+func (c *Client) ImportAddressWithRescan(address string, rescan bool, since time.Time) error {
 	// Validate address format before processing
 	if err := sanitizeAddress(address); err != nil {
 		return fmt.Errorf("invalid address format: %w", err)
@@ -202,12 +296,25 @@ func (c *Client) ImportAddress(address string) error {
 		return fmt.Errorf("failed to get descriptor info: %w", err)
 	}
 
-	// Import using descriptors with full history (timestamp: 0 forces rescan from genesis)
-	descriptorJSON := fmt.Sprintf(`[{"desc":"%s","timestamp":0,"watchonly":true}]`, descriptorInfo.Descriptor)
+	// Import using descriptors (timestamp: 0 forces rescan from genesis)
+	descriptorJSON := fmt.Sprintf(`[{"desc":"%s","timestamp":%s,"watchonly":true}]`,
+		descriptorInfo.Descriptor, importTimestamp(rescan, since))
 	_, err = RunBitcoinCLI("importdescriptors", descriptorJSON)
 	return err
 }
 
+// importTimestamp is the importdescriptors timestamp: since, or genesis when
+// since is zero, if the wallet should rescan, otherwise now
+func importTimestamp(rescan bool, since time.Time) string {
+	if !rescan {
+		return `"now"`
+	}
+	if since.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(since.Unix(), 10)
+}
+
 // GetDescriptorInfo gets descriptor information for an address
 func (c *Client) GetDescriptorInfo(address string) (*DescriptorInfo, error) {
 	// Validate address format before processing
@@ -215,12 +322,20 @@ func (c *Client) GetDescriptorInfo(address string) (*DescriptorInfo, error) {
 		return nil, fmt.Errorf("invalid address format: %w", err)
 	}
 
+	return c.getDescriptorInfo(fmt.Sprintf("addr(%s)", address))
+}
+
+// getDescriptorInfo analyses a descriptor and returns it with its checksum
+func (c *Client) getDescriptorInfo(descriptor string) (*DescriptorInfo, error) {
+	if err := sanitizeString(descriptor); err != nil {
+		return nil, fmt.Errorf("invalid descriptor: %w", err)
+	}
+
 	// Use bitcoin-cli without wallet for getdescriptorinfo (it's a non-wallet command)
-	// Security: The address is sanitized above, and we use exec.Command with separate
+	// Security: The descriptor is sanitized above, and we use exec.Command with separate
 	// arguments (no shell interpretation) to prevent command injection
-	descriptorArg := fmt.Sprintf("addr(%s)", address)
-	cmd := exec.Command("bitcoin-cli", "getdescriptorinfo", descriptorArg)
-	output, err := cmd.Output()
+	rpcLimiter.wait()
+	output, err := runner("getdescriptorinfo", descriptor)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("bitcoin-cli getdescriptorinfo failed: %v, stderr: %s", err, string(exitError.Stderr))
@@ -236,6 +351,134 @@ func (c *Client) GetDescriptorInfo(address string) (*DescriptorInfo, error) {
 	return &info, nil
 }
 
+// XPubDescriptors returns the receive and change descriptors for an extended
+// public key. SLIP-132 keys (ypub, zpub, ...) are converted to xpub form and
+// scriptType, if empty, is taken from the key prefix.
+func XPubDescriptors(xpub, scriptType string) ([]string, error) {
+	normalized, impliedType, err := utils.NormalizeXPub(xpub)
+	if err != nil {
+		return nil, err
+	}
+	if scriptType == "" {
+		scriptType = impliedType
+	}
+
+	var descriptors []string
+	for _, chain := range []int{0, 1} {
+		key := fmt.Sprintf("%s/%d/*", normalized, chain)
+		switch scriptType {
+		case utils.ScriptTypeP2PKH, utils.ScriptTypeP2WPKH:
+			descriptors = append(descriptors, fmt.Sprintf("%s(%s)", scriptType, key))
+		case utils.ScriptTypeP2SHP2WPKH:
+			descriptors = append(descriptors, fmt.Sprintf("sh(wpkh(%s))", key))
+		default:
+			return nil, fmt.Errorf("unsupported script type %q", scriptType)
+		}
+	}
+	return descriptors, nil
+}
+
+// ImportRangedDescriptor imports a ranged descriptor as watch-only, covering
+// indexes 0 through end-1. rescan and since work as in ImportAddressWithRescan.
+func (c *Client) ImportRangedDescriptor(descriptor string, end int, rescan bool, since time.Time) error {
+	info, err := c.getDescriptorInfo(descriptor)
+	if err != nil {
+		return fmt.Errorf("failed to get descriptor info: %w", err)
+	}
+
+	descriptorJSON := fmt.Sprintf(`[{"desc":"%s","range":[0,%d],"timestamp":%s,"watchonly":true}]`,
+		info.Descriptor, end-1, importTimestamp(rescan, since))
+	_, err = RunBitcoinCLI("importdescriptors", descriptorJSON)
+	return err
+}
+
+// DeriveAddresses returns the addresses of a ranged descriptor for indexes 0
+// through end-1
+func (c *Client) DeriveAddresses(descriptor string, end int) ([]string, error) {
+	info, err := c.getDescriptorInfo(descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get descriptor info: %w", err)
+	}
+
+	output, err := RunBitcoinCLI("deriveaddresses", info.Descriptor, fmt.Sprintf("[0,%d]", end-1))
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	if err := json.Unmarshal(output, &addresses); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// ScanDescriptors returns the unspent outputs of ranged descriptors for
+// indexes 0 through end-1 by scanning the UTXO set. Nothing is imported into
+// the tracking wallet, so only confirmed outputs are found, and a scan can
+// take minutes on mainnet.
+func (c *Client) ScanDescriptors(descriptors []string, end int) ([]ScannedUTXO, error) {
+	type scanObject struct {
+		Desc  string `json:"desc"`
+		Range int    `json:"range"`
+	}
+	var objects []scanObject
+	for _, descriptor := range descriptors {
+		if err := sanitizeString(descriptor); err != nil {
+			return nil, fmt.Errorf("invalid descriptor: %w", err)
+		}
+		objects = append(objects, scanObject{Desc: descriptor, Range: end - 1})
+	}
+	objectsJSON, err := json.Marshal(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := RunBitcoinCLI("scantxoutset", "start", string(objectsJSON))
+	if err != nil {
+		return nil, err
+	}
+	var scan UTXOSetScan
+	if err := json.Unmarshal(output, &scan); err != nil {
+		return nil, err
+	}
+	if !scan.Success {
+		return nil, fmt.Errorf("UTXO set scan was aborted")
+	}
+	return scan.Unspents, nil
+}
+
+// EstimateFeeRate returns the fee rate in sat/vB for confirmation within
+// target blocks, from estimatesmartfee
+func (c *Client) EstimateFeeRate(target int) (float64, error) {
+	output, err := RunBitcoinCLI("estimatesmartfee", strconv.Itoa(target))
+	if err != nil {
+		return 0, err
+	}
+	var estimate FeeEstimate
+	if err := json.Unmarshal(output, &estimate); err != nil {
+		return 0, err
+	}
+	if estimate.FeeRate <= 0 {
+		return 0, fmt.Errorf("no fee estimate for %d blocks: %s", target, strings.Join(estimate.Errors, "; "))
+	}
+	// BTC/kvB to sat/vB
+	return estimate.FeeRate * 100000000 / 1000, nil
+}
+
+// GetWalletTransaction returns a wallet transaction with its decoded inputs
+func (c *Client) GetWalletTransaction(txid string) (*WalletTransaction, error) {
+	output, err := RunBitcoinCLI("gettransaction", txid, "true", "true")
+	if err != nil {
+		return nil, err
+	}
+
+	var tx WalletTransaction
+	if err := json.Unmarshal(output, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
 // GetAddressUTXOs gets unspent transaction outputs for an address
 func (c *Client) GetAddressUTXOs(address string) ([]UTXO, error) {
 	// Validate address format before processing
@@ -333,6 +576,15 @@ func (c *Client) GetAddressInfo(address string) (*AddressInfo, error) {
 	return &info, nil
 }
 
+// GetBlockHash returns the hash of the active chain's block at height
+func (c *Client) GetBlockHash(height int64) (string, error) {
+	output, err := RunBitcoinCLI("getblockhash", strconv.FormatInt(height, 10))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // RescanBlockchain rescans the blockchain for transactions affecting watched addresses
 // This is useful after importing new addresses
 func (c *Client) RescanBlockchain(startHeight int64) error {
@@ -355,8 +607,7 @@ func SetupTrackingWallet() error {
 	log.Printf("📝 Wallet not loaded, checking if it exists on disk...")
 
 	// Try to create the wallet - this will fail if it already exists, which is fine
-	cmd := exec.Command("bitcoin-cli", "createwallet", "tracker_watchonly", "false", "false", "", "false", "true", "false")
-	output, err := cmd.Output()
+	output, err := runner("createwallet", "tracker_watchonly", "false", "false", "", "false", "true", "false")
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			stderr := string(exitError.Stderr)
@@ -381,16 +632,14 @@ func LoadTrackingWallet() error {
 	log.Println("🔄 Attempting to load tracking wallet...")
 
 	// First check if it's already loaded by testing wallet info
-	cmd := exec.Command("bitcoin-cli", "-rpcwallet=tracker_watchonly", "getwalletinfo")
-	_, err := cmd.Output()
+	_, err := runner("-rpcwallet=tracker_watchonly", "getwalletinfo")
 	if err == nil {
 		log.Println("✅ Tracking wallet already loaded and accessible")
 		return nil
 	}
 
 	// Try to load the wallet
-	cmd = exec.Command("bitcoin-cli", "loadwallet", "tracker_watchonly")
-	output, err := cmd.Output()
+	output, err := runner("loadwallet", "tracker_watchonly")
 	if err != nil {
 		// Check if it's already loaded
 		if exitError, ok := err.(*exec.ExitError); ok {
@@ -411,8 +660,7 @@ func LoadTrackingWallet() error {
 // walletExists checks if a wallet with the given name exists
 func walletExists(walletName string) (bool, error) {
 	// List all wallets
-	cmd := exec.Command("bitcoin-cli", "listwallets")
-	output, err := cmd.Output()
+	output, err := runner("listwallets")
 	if err != nil {
 		return false, fmt.Errorf("failed to list wallets: %w", err)
 	}
@@ -0,0 +1,75 @@
+package bitcoin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// testZPub is the BIP84 test vector account key
+const testZPub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+
+func TestXPubDescriptors(t *testing.T) {
+	descriptors, err := XPubDescriptors(testZPub, "")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(descriptors), 2)
+	testutils.AssertEqual(t, descriptors[0], "wpkh(xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V/0/*)")
+
+	descriptors, err = XPubDescriptors(testZPub, utils.ScriptTypeP2SHP2WPKH)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, strings.HasPrefix(descriptors[1], "sh(wpkh("), true)
+
+	_, err = XPubDescriptors(testZPub, "tr")
+	testutils.AssertError(t, err, "unsupported script type")
+}
+
+// FuzzXPubDescriptors checks that descriptors built from user-supplied keys
+// and script types are well formed and safe to pass to bitcoin-cli
+func FuzzXPubDescriptors(f *testing.F) {
+	f.Add(testZPub, "")
+	f.Add(testZPub, utils.ScriptTypeP2PKH)
+	f.Add(testZPub, utils.ScriptTypeP2SHP2WPKH)
+	f.Add(testZPub, "wpkh);rm -rf /")
+	f.Add("xpub", "wpkh")
+
+	f.Fuzz(func(t *testing.T, xpub, scriptType string) {
+		descriptors, err := XPubDescriptors(xpub, scriptType)
+		if err != nil {
+			return
+		}
+		if len(descriptors) != 2 {
+			t.Fatalf("XPubDescriptors(%q, %q) returned %d descriptors", xpub, scriptType, len(descriptors))
+		}
+		for chain, descriptor := range descriptors {
+			if err := sanitizeString(descriptor); err != nil {
+				t.Errorf("descriptor %q is not safe for bitcoin-cli: %v", descriptor, err)
+			}
+			if strings.Count(descriptor, "(") != strings.Count(descriptor, ")") {
+				t.Errorf("descriptor %q has unbalanced parentheses", descriptor)
+			}
+			if want := []string{"/0/*)", "/1/*)"}[chain]; !strings.Contains(descriptor, want) {
+				t.Errorf("descriptor %q does not derive chain %d", descriptor, chain)
+			}
+		}
+	})
+}
+
+// FuzzSanitizeAddress checks that addresses passed to bitcoin-cli never carry
+// shell or descriptor syntax
+func FuzzSanitizeAddress(f *testing.F) {
+	f.Add("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh")
+	f.Add("3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy")
+	f.Add("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh\n")
+	f.Add("addr(bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh)")
+
+	f.Fuzz(func(t *testing.T, address string) {
+		if sanitizeAddress(address) != nil {
+			return
+		}
+		if strings.ContainsAny(address, "\x00;|&$`\n\r<>(){}[]\"' ") {
+			t.Errorf("sanitizeAddress accepted %q", address)
+		}
+	})
+}
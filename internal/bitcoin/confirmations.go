@@ -0,0 +1,69 @@
+package bitcoin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// ConfirmationPolicy maps address classes to the number of confirmations
+// their UTXOs need before they count as confirmed. Funds with fewer
+// confirmations are reported as unconfirmed, so a cold storage deposit can
+// stay pending until it is buried deep enough to be considered final.
+type ConfirmationPolicy map[string]int64
+
+// DefaultConfirmationPolicy counts hot wallet funds as confirmed after one
+// confirmation and cold storage after six
+func DefaultConfirmationPolicy() ConfirmationPolicy {
+	return ConfirmationPolicy{
+		db.AddressClassHot:  1,
+		db.AddressClassCold: 6,
+	}
+}
+
+// MinConfirmations returns the confirmations required for class. Unknown
+// classes use the hot wallet threshold, and the result is never below one.
+func (p ConfirmationPolicy) MinConfirmations(class string) int64 {
+	minConf, ok := p[class]
+	if !ok {
+		minConf = p[db.AddressClassHot]
+	}
+	return max(minConf, 1)
+}
+
+// String formats the policy as accepted by ParseConfirmationPolicy
+func (p ConfirmationPolicy) String() string {
+	var entries []string
+	for _, class := range db.AddressClasses {
+		entries = append(entries, fmt.Sprintf("%s:%d", class, p.MinConfirmations(class)))
+	}
+	return strings.Join(entries, ",")
+}
+
+// ParseConfirmationPolicy parses comma-separated class:confirmations pairs,
+// e.g. "hot:1,cold:6". Classes that are not listed keep their default.
+func ParseConfirmationPolicy(value string) (ConfirmationPolicy, error) {
+	policy := DefaultConfirmationPolicy()
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		class, count, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid entry %q: expected class:confirmations", entry)
+		}
+		if !db.IsValidAddressClass(class) {
+			return nil, fmt.Errorf("unknown address class %q, expected one of: %s", class, strings.Join(db.AddressClasses, ", "))
+		}
+		minConf, err := strconv.ParseInt(count, 10, 64)
+		if err != nil || minConf < 1 {
+			return nil, fmt.Errorf("invalid confirmations for %s: %q must be a positive integer", class, count)
+		}
+		policy[class] = minConf
+	}
+	return policy, nil
+}
@@ -0,0 +1,48 @@
+package bitcoin
+
+import (
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseConfirmationPolicy(t *testing.T) {
+	policy, err := ParseConfirmationPolicy("cold:12")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, policy.MinConfirmations("cold"), int64(12))
+	testutils.AssertEqual(t, policy.MinConfirmations("hot"), int64(1))
+	// Addresses stored before classes existed count as hot
+	testutils.AssertEqual(t, policy.MinConfirmations(""), int64(1))
+	testutils.AssertEqual(t, policy.String(), "hot:1,cold:12")
+
+	defaults, err := ParseConfirmationPolicy("")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, defaults.String(), DefaultConfirmationPolicy().String())
+
+	for _, value := range []string{"cold", "warm:3", "cold:0", "cold:many"} {
+		if _, err := ParseConfirmationPolicy(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
+
+func TestSplitUTXOs(t *testing.T) {
+	utxos := []UTXO{
+		{Amount: 0.001, Confirmations: 0},
+		{Amount: 0.002, Confirmations: 1},
+		{Amount: 0.004, Confirmations: 6},
+	}
+
+	split := splitUTXOs(utxos, 1)
+	testutils.AssertEqual(t, split.Confirmed, int64(600000))
+	testutils.AssertEqual(t, split.Unconfirmed, int64(100000))
+
+	// A 1-conf deposit to cold storage is still pending at six confirmations
+	split = splitUTXOs(utxos, 6)
+	testutils.AssertEqual(t, split.Confirmed, int64(400000))
+	testutils.AssertEqual(t, split.Unconfirmed, int64(300000))
+	testutils.AssertEqual(t, split.UnconfirmedUTXOCount, int64(2))
+	testutils.AssertEqual(t, split.Total(), int64(700000))
+
+	testutils.AssertEqual(t, splitUTXOs(utxos, 0).MinConfirmations, int64(1))
+}
@@ -0,0 +1,127 @@
+package bitcoin
+
+import (
+	"log"
+
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+)
+
+// externalGapLimit is how many consecutive unused addresses end an xpub
+// chain when querying the external backend, which is too slow to ask about
+// every derived address
+const externalGapLimit = 20
+
+// pruneState reports whether Bitcoin Core is pruned and the lowest block it
+// still stores. It is checked once per scanner; if the check fails the node
+// is treated as unpruned.
+func (ts *TransactionScanner) pruneState() (bool, int64) {
+	ts.pruneOnce.Do(func() {
+		info, err := ts.client.GetBlockchainInfo()
+		if err != nil {
+			log.Printf("⚠️  Could not check whether Bitcoin Core is pruned: %v", err)
+			return
+		}
+		ts.pruned, ts.pruneHeight = info.Pruned, info.PruneHeight
+		if !ts.pruned {
+			return
+		}
+		if ts.fallback != nil {
+			log.Printf("✂️  Bitcoin Core is pruned below block %d, using the external backend for older history", ts.pruneHeight)
+		} else {
+			log.Printf("⚠️  Bitcoin Core is pruned below block %d and no external backend is set, older history may be missing", ts.pruneHeight)
+		}
+	})
+	return ts.pruned, ts.pruneHeight
+}
+
+// externalMovements fetches the confirmed history of address chains from the
+// external backend and returns the movements of outputs created from the
+// birthday up to pruneHeight. A chain with more than one address stops after
+// externalGapLimit consecutive unused addresses.
+func (ts *TransactionScanner) externalMovements(chains [][]string, birthday Birthday, pruneHeight int64) ([]BalanceMovement, error) {
+	watched := make(map[string]bool)
+	txs := make(map[string]mempool.Transaction)
+
+	for _, chain := range chains {
+		unused := 0
+		for _, address := range chain {
+			watched[address] = true
+
+			history, err := ts.fallback.GetAddressChainTransactionsSince(address, birthday.Height)
+			if err != nil {
+				return nil, err
+			}
+			if len(history) == 0 {
+				unused++
+				if len(chain) > 1 && unused >= externalGapLimit {
+					break
+				}
+				continue
+			}
+			unused = 0
+			for _, tx := range history {
+				txs[tx.TxID] = tx
+			}
+		}
+	}
+
+	list := make([]mempool.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		list = append(list, tx)
+	}
+	return esploraMovements(list, watched, birthday, pruneHeight), nil
+}
+
+// esploraMovements converts Esplora transactions into movements for the
+// watched addresses, counting only outputs created from the birthday up to
+// pruneHeight: their credits, and their debits whenever they are spent.
+// Outputs from pruneHeight on are left to Bitcoin Core.
+func esploraMovements(txs []mempool.Transaction, watched map[string]bool, birthday Birthday, pruneHeight int64) []BalanceMovement {
+	counted := func(status mempool.Status) bool {
+		return status.BlockHeight < pruneHeight && birthday.includes(status.BlockHeight, status.BlockTime)
+	}
+	statuses := make(map[string]mempool.Status, len(txs))
+	for _, tx := range txs {
+		if tx.Status.Confirmed {
+			statuses[tx.TxID] = tx.Status
+		}
+	}
+
+	var movements []BalanceMovement
+	for _, tx := range txs {
+		if !tx.Status.Confirmed {
+			continue
+		}
+
+		var amount int64
+		if counted(tx.Status) {
+			for _, out := range tx.Vout {
+				if watched[out.ScriptPubKeyAddr] {
+					amount += out.Value
+				}
+			}
+		}
+		for _, in := range tx.Vin {
+			if in.PrevOut == nil || !watched[in.PrevOut.ScriptPubKeyAddr] {
+				continue
+			}
+			if status, ok := statuses[in.TxID]; ok && counted(status) {
+				amount -= in.PrevOut.Value
+			}
+		}
+
+		if amount != 0 {
+			movements = append(movements, BalanceMovement{
+				TxID:      tx.TxID,
+				Height:    tx.Status.BlockHeight,
+				BlockHash: tx.Status.BlockHash,
+				Blocktime: tx.Status.BlockTime,
+				Amount:    amount,
+				Source:    SourceEsplora,
+			})
+		}
+	}
+
+	sortMovements(movements)
+	return movements
+}
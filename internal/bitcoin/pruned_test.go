@@ -0,0 +1,62 @@
+package bitcoin
+
+import (
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestEsploraMovements(t *testing.T) {
+	const watched = "bc1qwatched"
+	confirmed := func(height int64) mempool.Status {
+		return mempool.Status{Confirmed: true, BlockHeight: height, BlockTime: height * 600}
+	}
+
+	txs := []mempool.Transaction{
+		{
+			// Received before the prune height
+			TxID:   "a",
+			Vout:   []mempool.Output{{ScriptPubKeyAddr: watched, Value: 100000}},
+			Status: confirmed(50),
+		},
+		{
+			// Spends the pre-prune output after the prune height, with change back
+			TxID: "b",
+			Vin: []mempool.Input{{
+				TxID:    "a",
+				Vout:    0,
+				PrevOut: &mempool.Output{ScriptPubKeyAddr: watched, Value: 100000},
+			}},
+			Vout: []mempool.Output{
+				{ScriptPubKeyAddr: watched, Value: 60000},
+				{ScriptPubKeyAddr: "bc1qother", Value: 39000},
+			},
+			Status: confirmed(150),
+		},
+		{
+			// Spends the post-prune change, which Bitcoin Core accounts for
+			TxID: "c",
+			Vin: []mempool.Input{{
+				TxID:    "b",
+				Vout:    0,
+				PrevOut: &mempool.Output{ScriptPubKeyAddr: watched, Value: 60000},
+			}},
+			Status: confirmed(200),
+		},
+		{
+			TxID: "unconfirmed",
+			Vout: []mempool.Output{{ScriptPubKeyAddr: watched, Value: 5000}},
+		},
+	}
+
+	movements := esploraMovements(txs, map[string]bool{watched: true}, Birthday{}, 100)
+
+	testutils.AssertEqual(t, len(movements), 2)
+	testutils.AssertEqual(t, movements[0].TxID, "a")
+	testutils.AssertEqual(t, movements[0].Amount, int64(100000))
+	testutils.AssertEqual(t, movements[0].Source, SourceEsplora)
+	testutils.AssertEqual(t, movements[1].TxID, "b")
+	testutils.AssertEqual(t, movements[1].Amount, int64(-100000))
+	testutils.AssertEqual(t, movements[1].Height, int64(150))
+}
@@ -0,0 +1,48 @@
+package bitcoin
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces out calls so they start no more often than once per
+// interval. A zero interval disables limiting.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the caller may make its next call
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	if l.interval <= 0 {
+		l.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// rpcLimiter limits every bitcoin-cli call made by this package
+var rpcLimiter = &rateLimiter{}
+
+// SetRPCRateLimit limits bitcoin-cli calls to perSecond across all
+// goroutines, so parallel scans do not overwhelm Bitcoin Core. Zero or a
+// negative value removes the limit.
+func SetRPCRateLimit(perSecond float64) {
+	rpcLimiter.mu.Lock()
+	defer rpcLimiter.mu.Unlock()
+	if perSecond <= 0 {
+		rpcLimiter.interval = 0
+		return
+	}
+	rpcLimiter.interval = time.Duration(float64(time.Second) / perSecond)
+}
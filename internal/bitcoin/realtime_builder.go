@@ -0,0 +1,96 @@
+package bitcoin
+
+import (
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+)
+
+// RealtimeServiceBuilder assembles a RealtimeBalanceService from whichever
+// backends are available. Bitcoin Core is required; LND and the history
+// fallback are optional and simply leave their features out when unset.
+type RealtimeServiceBuilder struct {
+	database  *db.Database
+	client    *Client
+	lndClient *lnd.Client
+	fallback  *mempool.Client
+	cacheTTL  time.Duration
+	policy    ConfirmationPolicy
+}
+
+// NewRealtimeServiceBuilder starts a builder for a service backed by database
+func NewRealtimeServiceBuilder(database *db.Database) *RealtimeServiceBuilder {
+	return &RealtimeServiceBuilder{
+		database: database,
+		cacheTTL: DefaultBalanceCacheTTL,
+	}
+}
+
+// WithBitcoin sets the Bitcoin Core client. A nil client is ignored.
+func (b *RealtimeServiceBuilder) WithBitcoin(client *Client) *RealtimeServiceBuilder {
+	if client != nil {
+		b.client = client
+	}
+	return b
+}
+
+// WithLightning adds Lightning balances to portfolio history. A nil client is
+// ignored, leaving history onchain only.
+func (b *RealtimeServiceBuilder) WithLightning(lndClient *lnd.Client) *RealtimeServiceBuilder {
+	if lndClient != nil {
+		b.lndClient = lndClient
+	}
+	return b
+}
+
+// WithHistoryFallback sets the Esplora or mempool.space backend used for
+// address history below a pruned node's prune height. A nil client is ignored.
+func (b *RealtimeServiceBuilder) WithHistoryFallback(fallback *mempool.Client) *RealtimeServiceBuilder {
+	if fallback != nil {
+		b.fallback = fallback
+	}
+	return b
+}
+
+// WithCacheTTL sets how long address balances are cached. Non-positive values
+// keep the default.
+func (b *RealtimeServiceBuilder) WithCacheTTL(ttl time.Duration) *RealtimeServiceBuilder {
+	if ttl > 0 {
+		b.cacheTTL = ttl
+	}
+	return b
+}
+
+// WithConfirmationPolicy sets how many confirmations each address class needs
+// before its funds count as confirmed. A nil policy keeps the default.
+func (b *RealtimeServiceBuilder) WithConfirmationPolicy(policy ConfirmationPolicy) *RealtimeServiceBuilder {
+	if policy != nil {
+		b.policy = policy
+	}
+	return b
+}
+
+// HasLightning reports whether the built service will include Lightning data
+func (b *RealtimeServiceBuilder) HasLightning() bool {
+	return b.lndClient != nil
+}
+
+// Build creates the service. It returns ErrNodeNotConnected when no Bitcoin
+// Core client was provided, since every real-time query depends on it.
+func (b *RealtimeServiceBuilder) Build() (*RealtimeBalanceService, error) {
+	if b.client == nil {
+		return nil, ErrNodeNotConnected
+	}
+
+	service := NewRealtimeBalanceService(b.client, b.database, b.lndClient)
+	if b.fallback != nil {
+		service.SetHistoryFallback(b.fallback)
+	}
+	if b.policy != nil {
+		service.SetConfirmationPolicy(b.policy)
+	}
+	service.SetCacheTTL(b.cacheTTL)
+	return service, nil
+}
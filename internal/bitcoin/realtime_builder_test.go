@@ -0,0 +1,53 @@
+package bitcoin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestRealtimeServiceBuilderRequiresBitcoin(t *testing.T) {
+	service, err := NewRealtimeServiceBuilder(nil).WithLightning(&lnd.Client{}).Build()
+	if !errors.Is(err, ErrNodeNotConnected) {
+		t.Fatalf("expected ErrNodeNotConnected, got %v", err)
+	}
+	if service != nil {
+		t.Fatal("expected no service without Bitcoin Core")
+	}
+}
+
+func TestRealtimeServiceBuilderWiresLightning(t *testing.T) {
+	builder := NewRealtimeServiceBuilder(nil).WithBitcoin(&Client{}).WithLightning(&lnd.Client{})
+	testutils.AssertEqual(t, builder.HasLightning(), true)
+
+	service, err := builder.Build()
+	testutils.AssertNoError(t, err)
+	if service.lndClient == nil || service.lightningScanner == nil {
+		t.Fatal("expected Lightning history to be wired")
+	}
+}
+
+func TestRealtimeServiceBuilderWithoutLightning(t *testing.T) {
+	builder := NewRealtimeServiceBuilder(nil).
+		WithBitcoin(&Client{}).
+		WithLightning(nil).
+		WithHistoryFallback(mempool.NewClient("https://mempool.example/api")).
+		WithCacheTTL(2 * time.Minute).
+		WithConfirmationPolicy(ConfirmationPolicy{"cold": 3})
+	testutils.AssertEqual(t, builder.HasLightning(), false)
+
+	service, err := builder.Build()
+	testutils.AssertNoError(t, err)
+	if service.lightningScanner != nil {
+		t.Fatal("expected no Lightning scanner without LND")
+	}
+	if service.txScanner.fallback == nil {
+		t.Fatal("expected history fallback to be wired")
+	}
+	testutils.AssertEqual(t, service.cache.ttl, 2*time.Minute)
+	testutils.AssertEqual(t, service.confirmations.MinConfirmations("cold"), int64(3))
+}
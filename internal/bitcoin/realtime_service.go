@@ -1,13 +1,19 @@
 package bitcoin
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/brewgator/lightning-node-tools/internal/db"
 	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/tracing"
 )
 
 // RealtimeBalanceService provides real-time balance calculations from Bitcoin Core and LND
@@ -18,30 +24,20 @@ type RealtimeBalanceService struct {
 	txScanner        *TransactionScanner
 	lndClient        *lnd.Client
 	lightningScanner *lnd.LightningHistoryScanner
-}
-
-// BalanceCache stores recent balance queries with TTL
-type BalanceCache struct {
-	entries map[string]*CacheEntry
-	mutex   sync.RWMutex
-	ttl     time.Duration
-}
-
-// CacheEntry represents a cached balance result
-type CacheEntry struct {
-	Balance   int64
-	TxCount   int64
-	Timestamp time.Time
-	Address   string
+	confirmations    ConfirmationPolicy
 }
 
 // AddressBalanceResult contains real-time balance information
 type AddressBalanceResult struct {
-	Address     string    `json:"address"`
-	Balance     int64     `json:"balance"`
-	TxCount     int64     `json:"tx_count"`
-	LastUpdated time.Time `json:"last_updated"`
-	Source      string    `json:"source"` // "cache" or "bitcoin-core"
+	Address     string `json:"address"`
+	Balance     int64  `json:"balance"`     // Confirmed plus unconfirmed
+	Confirmed   int64  `json:"confirmed"`   // UTXOs with at least MinConfirmations confirmations
+	Unconfirmed int64  `json:"unconfirmed"` // UTXOs with fewer
+	TxCount     int64  `json:"tx_count"`
+	// MinConfirmations is the threshold for the address's class
+	MinConfirmations int64     `json:"min_confirmations"`
+	LastUpdated      time.Time `json:"last_updated"`
+	Source           string    `json:"source"` // "cache" or "bitcoin-core"
 }
 
 // PortfolioSnapshot represents a real-time portfolio snapshot
@@ -52,43 +48,62 @@ type PortfolioSnapshot struct {
 	OnchainConfirmed   int64     `json:"onchain_confirmed"`
 	OnchainUnconfirmed int64     `json:"onchain_unconfirmed"`
 	TrackedAddresses   int64     `json:"tracked_addresses"`
+	TrackedConfirmed   int64     `json:"tracked_confirmed"`
+	TrackedUnconfirmed int64     `json:"tracked_unconfirmed"`
 	ColdStorage        int64     `json:"cold_storage"`
+	Liquid             int64     `json:"liquid"`             // L-BTC, confirmed plus unconfirmed
+	LiquidUnconfirmed  int64     `json:"liquid_unconfirmed"` // Included in Liquid
+	Ecash              int64     `json:"ecash"`              // Fedimint and Cashu, held by the mints
 	TotalPortfolio     int64     `json:"total_portfolio"`
 	TotalLiquid        int64     `json:"total_liquid"`
+	// TotalConfirmed is the total portfolio less funds that do not yet have
+	// the confirmations their address class requires
+	TotalConfirmed int64 `json:"total_confirmed"`
+	// Tracked addresses left out of the totals because their query failed or timed out
+	TrackedFailed   []string `json:"tracked_failed,omitempty"`
+	TrackedTimedOut []string `json:"tracked_timed_out,omitempty"`
+}
+
+// Pending returns the on-chain funds that are not confirmed yet
+func (p PortfolioSnapshot) Pending() int64 {
+	return p.OnchainUnconfirmed + p.TrackedUnconfirmed + p.LiquidUnconfirmed
 }
 
 // NewRealtimeBalanceService creates a new real-time balance service
 func NewRealtimeBalanceService(client *Client, database *db.Database, lndClient *lnd.Client) *RealtimeBalanceService {
-	cache := &BalanceCache{
-		entries: make(map[string]*CacheEntry),
-		ttl:     45 * time.Second, // 45 second cache TTL for good performance
-	}
-
 	var lightningScanner *lnd.LightningHistoryScanner
 	if lndClient != nil {
-		lightningScanner = lnd.NewLightningHistoryScanner(lndClient)
+		lightningScanner = lnd.NewLightningHistoryScannerWithStore(lndClient, database)
 	}
 
 	return &RealtimeBalanceService{
 		client:           client,
 		database:         database,
-		cache:            cache,
+		cache:            NewBalanceCache(DefaultBalanceCacheTTL),
 		txScanner:        NewTransactionScanner(client),
 		lndClient:        lndClient,
 		lightningScanner: lightningScanner,
+		confirmations:    DefaultConfirmationPolicy(),
 	}
 }
 
+// SetConfirmationPolicy sets how many confirmations each address class needs
+// before its funds count as confirmed in balances and portfolio totals
+func (s *RealtimeBalanceService) SetConfirmationPolicy(policy ConfirmationPolicy) {
+	s.confirmations = policy
+}
+
 // GetCurrentPortfolio calculates the current portfolio in real-time
-func (s *RealtimeBalanceService) GetCurrentPortfolio() (*PortfolioSnapshot, error) {
+func (s *RealtimeBalanceService) GetCurrentPortfolio(ctx context.Context) (*PortfolioSnapshot, error) {
 	log.Println("🔄 Calculating real-time portfolio...")
 
 	// Get tracked addresses and calculate their balances
-	trackedTotal, err := s.GetTrackedAddressesBalance()
+	tracked, err := s.GetTrackedAddressesBalance(ctx)
 	if err != nil {
 		log.Printf("⚠️  Warning: Failed to get tracked addresses balance: %v", err)
-		trackedTotal = 0
+		tracked = &TrackedAddressesBalance{}
 	}
+	trackedTotal := tracked.Total()
 
 	// Get cold storage total from database (this remains manual entry)
 	coldTotal, err := s.getColdStorageTotal()
@@ -97,8 +112,23 @@ func (s *RealtimeBalanceService) GetCurrentPortfolio() (*PortfolioSnapshot, erro
 		coldTotal = 0
 	}
 
+	// L-BTC comes from the latest Liquid collector snapshot
+	var liquidTotal, liquidUnconfirmed int64
+	if liquid, err := s.database.GetLatestLiquidBalance(); err != nil {
+		log.Printf("⚠️  Warning: Failed to get Liquid balance: %v", err)
+	} else if liquid != nil {
+		liquidTotal, liquidUnconfirmed = liquid.Confirmed+liquid.Unconfirmed, liquid.Unconfirmed
+	}
+
+	// Ecash is the sum of the latest balance per federation and mint
+	ecashTotal, err := s.database.GetEcashBalanceAt(time.Now())
+	if err != nil {
+		log.Printf("⚠️  Warning: Failed to get ecash balance: %v", err)
+		ecashTotal = 0
+	}
+
 	// Calculate totals
-	totalLiquid := trackedTotal // Only tracked addresses are liquid in this new model
+	totalLiquid := trackedTotal + liquidTotal + ecashTotal // Only tracked addresses, L-BTC and ecash are liquid in this new model
 	totalPortfolio := totalLiquid + coldTotal
 
 	snapshot := &PortfolioSnapshot{
@@ -108,140 +138,242 @@ func (s *RealtimeBalanceService) GetCurrentPortfolio() (*PortfolioSnapshot, erro
 		OnchainConfirmed:   0, // These are now part of tracked addresses
 		OnchainUnconfirmed: 0,
 		TrackedAddresses:   trackedTotal,
+		TrackedConfirmed:   tracked.Confirmed,
+		TrackedUnconfirmed: tracked.Unconfirmed,
 		ColdStorage:        coldTotal,
+		Liquid:             liquidTotal,
+		LiquidUnconfirmed:  liquidUnconfirmed,
+		Ecash:              ecashTotal,
 		TotalPortfolio:     totalPortfolio,
 		TotalLiquid:        totalLiquid,
+		TotalConfirmed:     totalPortfolio - tracked.Unconfirmed - liquidUnconfirmed,
+		TrackedFailed:      tracked.Failed,
+		TrackedTimedOut:    tracked.TimedOut,
 	}
 
-	log.Printf("✅ Real-time portfolio calculated: %d sats total (%d tracked, %d cold)",
-		totalPortfolio, trackedTotal, coldTotal)
+	log.Printf("✅ Real-time portfolio calculated: %s total (%s tracked, %s cold, %s liquid, %s ecash)",
+		redact.Sats(totalPortfolio), redact.Amount(trackedTotal), redact.Amount(coldTotal),
+		redact.Amount(liquidTotal), redact.Amount(ecashTotal))
 
 	return snapshot, nil
 }
 
-// GetTrackedAddressesBalance calculates total balance of all tracked addresses
-func (s *RealtimeBalanceService) GetTrackedAddressesBalance() (int64, error) {
+// Limits for fetching tracked address balances
+const (
+	// trackedBalanceWorkers caps concurrent balance queries against Bitcoin Core
+	trackedBalanceWorkers = 8
+	// trackedBalanceTimeout bounds the whole fetch; slower addresses are reported as timed out
+	trackedBalanceTimeout = 10 * time.Second
+)
+
+// TrackedAddressesBalance is the combined balance of the active tracked addresses
+type TrackedAddressesBalance struct {
+	Confirmed   int64 `json:"confirmed"`
+	Unconfirmed int64 `json:"unconfirmed"`
+	// Active is the number of active addresses; Succeeded is how many were summed
+	Active    int `json:"active"`
+	Succeeded int `json:"succeeded"`
+	// Failed lists addresses whose query returned an error; TimedOut lists
+	// addresses that did not finish before the deadline or cancellation
+	Failed   []string `json:"failed,omitempty"`
+	TimedOut []string `json:"timed_out,omitempty"`
+	// Balances holds the result of each address that was summed
+	Balances []AddressBalanceResult `json:"-"`
+}
+
+// Total returns the confirmed and unconfirmed balance combined
+func (b TrackedAddressesBalance) Total() int64 {
+	return b.Confirmed + b.Unconfirmed
+}
+
+// Partial reports whether some active addresses are missing from the totals
+func (b TrackedAddressesBalance) Partial() bool {
+	return b.Succeeded < b.Active
+}
+
+// GetTrackedAddressesBalance calculates total balance of all tracked addresses.
+// At most trackedBalanceWorkers addresses are queried at once. Addresses that
+// fail or do not finish before trackedBalanceTimeout or ctx cancellation are
+// left out of the totals and listed in the result.
+func (s *RealtimeBalanceService) GetTrackedAddressesBalance(ctx context.Context) (*TrackedAddressesBalance, error) {
 	// Get all active tracked addresses
 	addresses, err := s.database.GetOnchainAddresses()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get tracked addresses: %w", err)
+		return nil, fmt.Errorf("failed to get tracked addresses: %w", err)
 	}
 
-	if len(addresses) == 0 {
-		return 0, nil
+	var active []string
+	classes := make(map[string]string)
+	for _, addr := range addresses {
+		if addr.Active {
+			active = append(active, addr.Address)
+			classes[addr.Address] = addr.Class
+		}
 	}
 
-	var totalBalance int64
-	successCount := 0
+	ctx, cancel := context.WithTimeout(ctx, trackedBalanceTimeout)
+	defer cancel()
 
-	// Process addresses concurrently for better performance
-	results := make(chan *AddressBalanceResult, len(addresses))
-	errors := make(chan error, len(addresses))
+	fetch := func(ctx context.Context, address string) (*AddressBalanceResult, error) {
+		return s.GetAddressBalanceContext(ctx, address, classes[address])
+	}
+	total := sumAddressBalances(ctx, active, trackedBalanceWorkers, fetch)
+	if len(active) > 0 {
+		log.Printf("✅ Processed %d/%d active addresses, total: %s (%s unconfirmed)",
+			total.Succeeded, total.Active, redact.Sats(total.Total()), redact.Amount(total.Unconfirmed))
+	}
+	return total, nil
+}
 
-	// Launch goroutines for each active address
-	activeCount := 0
-	for _, addr := range addresses {
-		if !addr.Active {
-			continue
-		}
-		activeCount++
-		go func(address db.OnchainAddress) {
-			result, err := s.GetAddressBalance(address.Address)
-			if err != nil {
-				errors <- fmt.Errorf("address %s: %w", address.Address, err)
+// sumAddressBalances fetches addresses with at most workers queries in flight
+// and sums the results. Workers waiting for a slot or a result give up as soon
+// as ctx is done, so the call returns promptly after a timeout.
+func sumAddressBalances(ctx context.Context, addresses []string, workers int,
+	fetch func(context.Context, string) (*AddressBalanceResult, error)) *TrackedAddressesBalance {
+
+	type outcome struct {
+		result *AddressBalanceResult
+		err    error
+	}
+	outcomes := make([]outcome, len(addresses))
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				outcomes[i].err = ctx.Err()
 				return
 			}
-			results <- result
-		}(addr)
-	}
-
-	// Collect results
-	for i := 0; i < activeCount; i++ {
-		select {
-		case result := <-results:
-			totalBalance += result.Balance
-			successCount++
-			log.Printf("📊 %s: %d sats [%s]",
-				truncateAddress(result.Address), result.Balance, result.Source)
-		case err := <-errors:
-			log.Printf("❌ %v", err)
-		case <-time.After(10 * time.Second):
-			log.Printf("⏰ Timeout waiting for address balance")
+			// A freed slot and cancellation can be ready at once
+			if err := ctx.Err(); err != nil {
+				outcomes[i].err = err
+				return
+			}
+			outcomes[i].result, outcomes[i].err = fetch(ctx, address)
+		}(i, address)
+	}
+	wg.Wait()
+
+	total := &TrackedAddressesBalance{Active: len(addresses)}
+	for i, o := range outcomes {
+		switch {
+		case o.err == nil:
+			total.Confirmed += o.result.Confirmed
+			total.Unconfirmed += o.result.Unconfirmed
+			total.Succeeded++
+			total.Balances = append(total.Balances, *o.result)
+			log.Printf("📊 %s: %s (%s unconfirmed) [%s]",
+				redact.Address(o.result.Address), redact.Sats(o.result.Balance), redact.Amount(o.result.Unconfirmed), o.result.Source)
+		case errors.Is(o.err, context.DeadlineExceeded) || errors.Is(o.err, context.Canceled):
+			total.TimedOut = append(total.TimedOut, addresses[i])
+			log.Printf("⏰ Timed out waiting for balance of %s", redact.Address(addresses[i]))
+		default:
+			total.Failed = append(total.Failed, addresses[i])
+			log.Printf("❌ address %s: %v", addresses[i], o.err)
 		}
 	}
+	return total
+}
 
-	log.Printf("✅ Processed %d/%d active addresses, total: %d sats",
-		successCount, activeCount, totalBalance)
-
-	return totalBalance, nil
+// GetAddressBalance gets balance for a single address with caching. class
+// decides how many confirmations count as confirmed.
+func (s *RealtimeBalanceService) GetAddressBalance(address, class string) (*AddressBalanceResult, error) {
+	return s.GetAddressBalanceContext(context.Background(), address, class)
 }
 
-// GetAddressBalance gets balance for a single address with caching
-func (s *RealtimeBalanceService) GetAddressBalance(address string) (*AddressBalanceResult, error) {
-	// Check cache first
-	if cached := s.cache.Get(address); cached != nil {
-		return &AddressBalanceResult{
-			Address:     cached.Address,
-			Balance:     cached.Balance,
-			TxCount:     cached.TxCount,
-			LastUpdated: cached.Timestamp,
-			Source:      "cache",
-		}, nil
-	}
+// GetAddressBalanceContext is GetAddressBalance but stops waiting when ctx is
+// done. The Bitcoin Core query itself keeps running and fills the cache.
+func (s *RealtimeBalanceService) GetAddressBalanceContext(ctx context.Context, address, class string) (*AddressBalanceResult, error) {
+	minConf := s.confirmations.MinConfirmations(class)
 
-	// Query Bitcoin Core directly
-	balance, err := s.client.GetAddressBalance(address)
+	// Concurrent requests for the same address share one Bitcoin Core query.
+	// The key includes the threshold so a class change is not served stale.
+	key := fmt.Sprintf("%s:%d", address, minConf)
+	entry, cached, err := s.cache.Fetch(ctx, key, func() (*CacheEntry, error) {
+		split, err := s.client.GetAddressBalanceSplit(address, minConf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get balance from Bitcoin Core: %w", err)
+		}
+		return &CacheEntry{
+			Balance:     split.Total(),
+			Confirmed:   split.Confirmed,
+			Unconfirmed: split.Unconfirmed,
+			// UTXO count as transaction count approximation
+			TxCount:          split.UTXOCount + split.UnconfirmedUTXOCount,
+			MinConfirmations: split.MinConfirmations,
+			Timestamp:        time.Now(),
+			Address:          address,
+		}, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get balance from Bitcoin Core: %w", err)
+		return nil, err
 	}
 
-	// Get UTXO count as transaction count approximation
-	utxos, err := s.client.GetAddressUTXOs(address)
-	if err != nil {
-		log.Printf("⚠️  Failed to get UTXO count for %s: %v", address, err)
-		// Continue with balance only
+	source := "bitcoin-core"
+	if cached {
+		source = "cache"
 	}
-
-	txCount := int64(len(utxos))
-	timestamp := time.Now()
-
-	// Cache the result
-	s.cache.Set(address, &CacheEntry{
-		Balance:   balance,
-		TxCount:   txCount,
-		Timestamp: timestamp,
-		Address:   address,
-	})
-
 	return &AddressBalanceResult{
-		Address:     address,
-		Balance:     balance,
-		TxCount:     txCount,
-		LastUpdated: timestamp,
-		Source:      "bitcoin-core",
+		Address:          entry.Address,
+		Balance:          entry.Balance,
+		Confirmed:        entry.Confirmed,
+		Unconfirmed:      entry.Unconfirmed,
+		TxCount:          entry.TxCount,
+		MinConfirmations: entry.MinConfirmations,
+		LastUpdated:      entry.Timestamp,
+		Source:           source,
 	}, nil
 }
 
-// GetAddressHistory generates real-time transaction history for an address
-func (s *RealtimeBalanceService) GetAddressHistory(address string, from, to time.Time) ([]AddressBalanceResult, error) {
-	// Import address to ensure we have transaction data
-	err := s.client.ImportAddress(address)
+// SetCacheTTL changes how long address balances are cached
+func (s *RealtimeBalanceService) SetCacheTTL(ttl time.Duration) {
+	s.cache.SetTTL(ttl)
+}
+
+// WarmCache loads the balances of all active tracked addresses so the first
+// dashboard load is served from the cache
+func (s *RealtimeBalanceService) WarmCache() {
+	start := time.Now()
+	tracked, err := s.GetTrackedAddressesBalance(context.Background())
 	if err != nil {
-		log.Printf("⚠️  Import warning for %s: %v", address, err)
+		log.Printf("⚠️  Failed to warm balance cache: %v", err)
+		return
 	}
+	log.Printf("🔥 Warmed balance cache for %d/%d addresses in %v",
+		tracked.Succeeded, tracked.Active, time.Since(start).Round(time.Millisecond))
+}
 
-	// Use transaction scanner to get historical balance points
-	return s.txScanner.GetBalanceHistory(address, from, to)
+// GetAddressHistory generates real-time transaction history for an address,
+// scanning from its birthday on
+func (s *RealtimeBalanceService) GetAddressHistory(address string, birthday Birthday, from, to time.Time) ([]AddressBalanceResult, error) {
+	// The scanner imports the address, rescanning only what a pruned node still has
+	return s.txScanner.GetBalanceHistory(address, birthday, from, to)
 }
 
-// GetPortfolioHistory generates real-time portfolio history based on actual transaction dates
-func (s *RealtimeBalanceService) GetPortfolioHistory(from, to time.Time) ([]PortfolioSnapshot, error) {
+// SetHistoryFallback makes address history use an Esplora or mempool.space
+// backend for blocks a pruned node no longer stores
+func (s *RealtimeBalanceService) SetHistoryFallback(fallback *mempool.Client) {
+	s.txScanner = NewTransactionScannerWithFallback(s.client, fallback)
+}
+
+// GetPortfolioHistory generates real-time portfolio history based on actual
+// transaction dates. Its steps are traced within the span of ctx.
+func (s *RealtimeBalanceService) GetPortfolioHistory(ctx context.Context, from, to time.Time) ([]PortfolioSnapshot, error) {
+	database := s.database.WithContext(ctx)
 	log.Printf("📈 Generating Lightning + Bitcoin transaction-based portfolio history from %v to %v", from.Format("2006-01-02"), to.Format("2006-01-02"))
 
 	// Get Lightning transaction history if available
 	var lightningHistory []lnd.LightningBalancePoint
 	if s.lightningScanner != nil {
+		_, span := tracing.Start(ctx, "lightning history")
 		history, err := s.lightningScanner.GetLightningHistory(from, to)
+		span.End(err)
 		if err != nil {
 			log.Printf("⚠️  Warning: Failed to get Lightning history: %v", err)
 		} else {
@@ -251,7 +383,7 @@ func (s *RealtimeBalanceService) GetPortfolioHistory(from, to time.Time) ([]Port
 	}
 
 	// Get all addresses to analyze
-	addresses, err := s.database.GetOnchainAddresses()
+	addresses, err := database.GetOnchainAddresses()
 	if err != nil {
 		return nil, err
 	}
@@ -264,7 +396,7 @@ func (s *RealtimeBalanceService) GetPortfolioHistory(from, to time.Time) ([]Port
 			continue
 		}
 
-		transactions, err := s.txScanner.GetAddressTransactions(addr.Address)
+		transactions, err := s.txScanner.GetAddressTransactionsContext(ctx, addr.Address)
 		if err != nil {
 			log.Printf("⚠️  Failed to get transactions for %s: %v", addr.Address, err)
 			continue
@@ -321,12 +453,61 @@ func (s *RealtimeBalanceService) GetPortfolioHistory(from, to time.Time) ([]Port
 	// Generate enhanced snapshots using Lightning data as primary source
 	var snapshots []PortfolioSnapshot
 	for _, date := range sortedDates {
-		snapshot := s.getPortfolioSnapshotWithLightningData(date, lightningHistory)
+		snapshot, err := s.getPortfolioSnapshotWithLightningData(ctx, date, lightningHistory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build snapshot for %s: %w", date.Format(time.DateOnly), err)
+		}
 		snapshots = append(snapshots, snapshot)
 	}
 
 	log.Printf("✅ Generated %d portfolio snapshots based on transaction history", len(snapshots))
-	return snapshots, nil
+	return s.mergeStoredSnapshots(database, snapshots, from, to), nil
+}
+
+// mergeStoredSnapshots overlays snapshots stored in the database (for example
+// imported from CSV) onto generated history. A stored snapshot replaces the
+// generated one for the same day, so imported data covers periods that cannot
+// be reconstructed from the node.
+func (s *RealtimeBalanceService) mergeStoredSnapshots(database *db.Database, generated []PortfolioSnapshot, from, to time.Time) []PortfolioSnapshot {
+	stored, err := database.GetBalanceSnapshots(from, to)
+	if err != nil {
+		log.Printf("⚠️  Warning: Failed to load stored balance snapshots: %v", err)
+		return generated
+	}
+	if len(stored) == 0 {
+		return generated
+	}
+
+	storedDays := make(map[string]bool, len(stored))
+	merged := make([]PortfolioSnapshot, 0, len(generated)+len(stored))
+	for _, snapshot := range stored {
+		storedDays[snapshot.Timestamp.Format("2006-01-02")] = true
+		merged = append(merged, PortfolioSnapshot{
+			Timestamp:          snapshot.Timestamp,
+			LightningLocal:     snapshot.LightningLocal,
+			LightningRemote:    snapshot.LightningRemote,
+			OnchainConfirmed:   snapshot.OnchainConfirmed,
+			OnchainUnconfirmed: snapshot.OnchainUnconfirmed,
+			TrackedAddresses:   snapshot.TrackedAddresses,
+			ColdStorage:        snapshot.ColdStorage,
+			TotalPortfolio:     snapshot.TotalPortfolio,
+			TotalLiquid:        snapshot.TotalLiquid,
+			TotalConfirmed:     snapshot.TotalPortfolio - snapshot.OnchainUnconfirmed,
+		})
+	}
+
+	for _, snapshot := range generated {
+		if !storedDays[snapshot.Timestamp.Format("2006-01-02")] {
+			merged = append(merged, snapshot)
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	log.Printf("📊 Merged %d stored snapshots into portfolio history", len(stored))
+	return merged
 }
 
 // getPortfolioSnapshotForDate calculates portfolio value for a specific date using historical transactions
@@ -344,7 +525,7 @@ func (s *RealtimeBalanceService) getPortfolioSnapshotForDate(date time.Time) (*P
 			continue
 		}
 
-		balance, err := s.getHistoricalBalanceForDate(addr.Address, date)
+		balance, err := s.getHistoricalBalanceForDate(context.Background(), addr.Address, date)
 		if err != nil {
 			log.Printf("⚠️  Failed to get historical balance for %s on %v: %v", addr.Address, date.Format("2006-01-02"), err)
 			continue
@@ -352,23 +533,29 @@ func (s *RealtimeBalanceService) getPortfolioSnapshotForDate(date time.Time) (*P
 		trackedTotal += balance
 	}
 
-	// For cold storage, we only have manual entries, so use current values
-	// TODO: Could implement historical cold storage tracking if needed
-	coldTotal, _ := s.getColdStorageTotal()
+	// Cold storage history is kept continuous by daily snapshots
+	coldTotal, liquidTotal, ecashTotal, err := componentTotalsAt(s.database, date)
+	if err != nil {
+		return nil, err
+	}
+	totalLiquid := trackedTotal + liquidTotal + ecashTotal
 
 	return &PortfolioSnapshot{
 		Timestamp:        date,
 		TrackedAddresses: trackedTotal,
 		ColdStorage:      coldTotal,
-		TotalPortfolio:   trackedTotal + coldTotal,
-		TotalLiquid:      trackedTotal,
+		Liquid:           liquidTotal,
+		Ecash:            ecashTotal,
+		TotalPortfolio:   totalLiquid + coldTotal,
+		TotalLiquid:      totalLiquid,
+		TotalConfirmed:   totalLiquid + coldTotal,
 	}, nil
 }
 
 // getHistoricalBalanceForDate calculates an address balance as of a specific date
-func (s *RealtimeBalanceService) getHistoricalBalanceForDate(address string, targetDate time.Time) (int64, error) {
+func (s *RealtimeBalanceService) getHistoricalBalanceForDate(ctx context.Context, address string, targetDate time.Time) (int64, error) {
 	// Get all transactions for this address
-	transactions, err := s.txScanner.GetAddressTransactions(address)
+	transactions, err := s.txScanner.GetAddressTransactionsContext(ctx, address)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get transactions: %w", err)
 	}
@@ -410,7 +597,11 @@ func (s *RealtimeBalanceService) getHistoricalBalanceForDate(address string, tar
 }
 
 // getPortfolioSnapshotWithLightningData creates a portfolio snapshot prioritizing Lightning wallet data
-func (s *RealtimeBalanceService) getPortfolioSnapshotWithLightningData(date time.Time, lightningHistory []lnd.LightningBalancePoint) PortfolioSnapshot {
+func (s *RealtimeBalanceService) getPortfolioSnapshotWithLightningData(ctx context.Context, date time.Time, lightningHistory []lnd.LightningBalancePoint) (PortfolioSnapshot, error) {
+	ctx, span := tracing.Start(ctx, "portfolio snapshot", tracing.String("date", date.Format(time.DateOnly)))
+	defer span.End(nil)
+	database := s.database.WithContext(ctx)
+
 	// Find the Lightning balance point closest to this date
 	var lightningLocal, lightningRemote, onchainConfirmed int64
 
@@ -433,23 +624,27 @@ func (s *RealtimeBalanceService) getPortfolioSnapshotWithLightningData(date time
 
 	// Get tracked addresses balance for this date (secondary)
 	trackedTotal := int64(0)
-	addresses, err := s.database.GetOnchainAddresses()
-	if err == nil {
-		for _, addr := range addresses {
-			if !addr.Active {
-				continue
-			}
-			if balance, err := s.getHistoricalBalanceForDate(addr.Address, date); err == nil {
-				trackedTotal += balance
-			}
+	addresses, err := database.GetOnchainAddresses()
+	if err != nil {
+		return PortfolioSnapshot{}, fmt.Errorf("failed to get tracked addresses: %w", err)
+	}
+	for _, addr := range addresses {
+		if !addr.Active {
+			continue
+		}
+		if balance, err := s.getHistoricalBalanceForDate(ctx, addr.Address, date); err == nil {
+			trackedTotal += balance
 		}
 	}
 
-	// Get cold storage total (current values, as these are manual entries)
-	coldTotal, _ := s.getColdStorageTotal()
+	// Get cold storage total as of this date from balance history
+	coldTotal, liquidTotal, ecashTotal, err := componentTotalsAt(database, date)
+	if err != nil {
+		return PortfolioSnapshot{}, err
+	}
 
 	// Calculate totals with Lightning as primary focus
-	totalLiquid := lightningLocal + onchainConfirmed + trackedTotal
+	totalLiquid := lightningLocal + onchainConfirmed + trackedTotal + liquidTotal + ecashTotal
 	totalPortfolio := totalLiquid + lightningRemote + coldTotal
 
 	return PortfolioSnapshot{
@@ -460,9 +655,27 @@ func (s *RealtimeBalanceService) getPortfolioSnapshotWithLightningData(date time
 		OnchainUnconfirmed: 0,                // Would need to track unconfirmed separately
 		TrackedAddresses:   trackedTotal,     // Additional tracked addresses
 		ColdStorage:        coldTotal,        // Cold storage (manual entries)
+		Liquid:             liquidTotal,      // L-BTC from the Liquid collector
+		Ecash:              ecashTotal,       // Fedimint and Cashu from the ecash collector
 		TotalPortfolio:     totalPortfolio,   // Everything combined
 		TotalLiquid:        totalLiquid,      // Spendable (local + on-chain + tracked)
+		TotalConfirmed:     totalPortfolio,   // History only has confirmed transactions
+	}, nil
+}
+
+// componentTotalsAt returns the cold storage, Liquid and ecash totals as of
+// date, which are kept in the database rather than read from the node
+func componentTotalsAt(database *db.Database, date time.Time) (cold, liquid, ecash int64, err error) {
+	if cold, err = database.GetColdStorageTotalAt(date); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get cold storage total: %w", err)
 	}
+	if liquid, err = database.GetLiquidBalanceAt(date); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get Liquid balance: %w", err)
+	}
+	if ecash, err = database.GetEcashBalanceAt(date); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get ecash balance: %w", err)
+	}
+	return cold, liquid, ecash, nil
 }
 
 // getColdStorageTotal gets total cold storage balance from database
@@ -481,55 +694,3 @@ func (s *RealtimeBalanceService) getColdStorageTotal() (int64, error) {
 
 	return total, nil
 }
-
-// Cache methods
-
-// Get retrieves a cached balance if still valid
-func (c *BalanceCache) Get(address string) *CacheEntry {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-
-	entry, exists := c.entries[address]
-	if !exists {
-		return nil
-	}
-
-	// Check if entry has expired
-	if time.Since(entry.Timestamp) > c.ttl {
-		return nil
-	}
-
-	return entry
-}
-
-// Set stores a balance in cache
-func (c *BalanceCache) Set(address string, entry *CacheEntry) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	c.entries[address] = entry
-
-	// Clean up expired entries periodically
-	go c.cleanupExpired()
-}
-
-// cleanupExpired removes expired cache entries
-func (c *BalanceCache) cleanupExpired() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	now := time.Now()
-	for addr, entry := range c.entries {
-		if now.Sub(entry.Timestamp) > c.ttl {
-			delete(c.entries, addr)
-		}
-	}
-}
-
-// Helper function to truncate addresses for logging
-func truncateAddress(address string) string {
-	if len(address) <= 16 {
-		return address
-	}
-	return fmt.Sprintf("%s...%s", address[:8], address[len(address)-8:])
-}
@@ -0,0 +1,59 @@
+package bitcoin_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/fixtures"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+// BenchmarkGetPortfolioHistory measures generating portfolio history for the
+// fixture node, answering bitcoin-cli and lncli from fixtures so only the
+// scanning and the database reads are timed
+func BenchmarkGetPortfolioHistory(b *testing.B) {
+	b.Cleanup(lnd.SetRunner(fixtures.LNDRunner))
+	b.Cleanup(bitcoin.SetRunner(fixtures.BitcoinRunner))
+
+	// History generation logs every step
+	logOutput := log.Writer()
+	b.Cleanup(func() { log.SetOutput(logOutput) })
+	log.SetOutput(io.Discard)
+
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(b))
+	testutils.AssertNoError(b, err)
+	b.Cleanup(func() { database.Close() })
+	testutils.AssertNoError(b, fixtures.Seed(database))
+
+	bitcoinClient, err := bitcoin.NewClient()
+	testutils.AssertNoError(b, err)
+	lndClient, err := lnd.NewClient()
+	testutils.AssertNoError(b, err)
+	service, err := bitcoin.NewRealtimeServiceBuilder(database).
+		WithBitcoin(bitcoinClient).
+		WithLightning(lndClient).
+		Build()
+	testutils.AssertNoError(b, err)
+
+	for _, r := range []struct {
+		name   string
+		months int
+	}{
+		{"month", 1},
+		{"year", 12},
+	} {
+		b.Run(r.name, func(b *testing.B) {
+			from := fixtures.Now.AddDate(0, -r.months, 0)
+			for b.Loop() {
+				if _, err := service.GetPortfolioHistory(context.Background(), from, fixtures.Now); err != nil {
+					b.Fatalf("GetPortfolioHistory: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package bitcoin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestSumAddressBalancesBoundsConcurrency(t *testing.T) {
+	addresses := []string{"bc1qa", "bc1qb", "bc1qc", "bc1qd", "bc1qe", "bc1qf"}
+
+	var running, peak int32
+	fetch := func(ctx context.Context, address string) (*AddressBalanceResult, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return &AddressBalanceResult{Address: address, Balance: 150, Confirmed: 100, Unconfirmed: 50}, nil
+	}
+
+	total := sumAddressBalances(context.Background(), addresses, 2, fetch)
+
+	testutils.AssertEqual(t, atomic.LoadInt32(&peak), int32(2))
+	testutils.AssertEqual(t, total.Succeeded, 6)
+	testutils.AssertEqual(t, total.Confirmed, int64(600))
+	testutils.AssertEqual(t, total.Unconfirmed, int64(300))
+	testutils.AssertEqual(t, total.Partial(), false)
+}
+
+func TestSumAddressBalancesReportsPartialResults(t *testing.T) {
+	addresses := []string{"bc1qfast", "bc1qbroken", "bc1qslow"}
+
+	fetch := func(ctx context.Context, address string) (*AddressBalanceResult, error) {
+		switch address {
+		case "bc1qbroken":
+			return nil, errors.New("rpc error")
+		case "bc1qslow":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &AddressBalanceResult{Address: address, Balance: 1000, Confirmed: 1000}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	total := sumAddressBalances(ctx, addresses, 3, fetch)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected sum to return after the deadline, took %v", elapsed)
+	}
+
+	testutils.AssertEqual(t, total.Active, 3)
+	testutils.AssertEqual(t, total.Succeeded, 1)
+	testutils.AssertEqual(t, total.Total(), int64(1000))
+	testutils.AssertEqual(t, total.Partial(), true)
+	testutils.AssertEqual(t, strings.Join(total.Failed, ","), "bc1qbroken")
+	testutils.AssertEqual(t, strings.Join(total.TimedOut, ","), "bc1qslow")
+	testutils.AssertEqual(t, len(total.Balances), 1)
+	testutils.AssertEqual(t, total.Balances[0].Address, "bc1qfast")
+}
+
+func TestSumAddressBalancesSkipsQueuedAddressesAfterCancel(t *testing.T) {
+	addresses := []string{"bc1qa", "bc1qb", "bc1qc"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	fetch := func(ctx context.Context, address string) (*AddressBalanceResult, error) {
+		atomic.AddInt32(&calls, 1)
+		cancel()
+		return nil, ctx.Err()
+	}
+
+	total := sumAddressBalances(ctx, addresses, 1, fetch)
+
+	testutils.AssertEqual(t, atomic.LoadInt32(&calls), int32(1))
+	testutils.AssertEqual(t, total.Succeeded, 0)
+	testutils.AssertEqual(t, len(total.TimedOut), 3)
+}
+
+func TestComponentTotalsAtReportsDatabaseErrors(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	_, err = database.InsertColdStorageEntry("Vault", 1000000, "")
+	testutils.AssertNoError(t, err)
+
+	cold, _, _, err := componentTotalsAt(database, time.Now())
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, cold, int64(1000000))
+
+	// A failing database must not turn into a zero balance in history
+	testutils.AssertNoError(t, database.Close())
+	_, _, _, err = componentTotalsAt(database, time.Now())
+	testutils.AssertError(t, err, "failed to get cold storage total")
+}
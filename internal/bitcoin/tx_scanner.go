@@ -1,16 +1,34 @@
 package bitcoin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
 )
 
 // TransactionScanner scans Bitcoin Core for transaction history
 type TransactionScanner struct {
 	client *Client
+	// fallback supplies history Bitcoin Core no longer has on pruned nodes
+	fallback *mempool.Client
+
+	// importMu serializes imports, since Bitcoin Core rejects an import
+	// while another one is rescanning the wallet
+	importMu sync.Mutex
+
+	pruneOnce   sync.Once
+	pruned      bool
+	pruneHeight int64
 }
 
 // TransactionSummary represents aggregated transaction data
@@ -30,49 +48,50 @@ func NewTransactionScanner(client *Client) *TransactionScanner {
 	}
 }
 
-// GetBalanceHistory scans transaction history and generates balance snapshots
-func (ts *TransactionScanner) GetBalanceHistory(address string, from, to time.Time) ([]AddressBalanceResult, error) {
-	log.Printf("📈 Scanning transaction history for %s from %v to %v",
-		truncateAddress(address), from.Format("2006-01-02"), to.Format("2006-01-02"))
-
-	// Ensure address is imported for transaction scanning
-	err := ts.client.ImportAddress(address)
-	if err != nil {
-		log.Printf("⚠️  Import warning for %s: %v", address, err)
+// NewTransactionScannerWithFallback creates a transaction scanner that fetches
+// history from an Esplora or mempool.space backend when the node is pruned
+func NewTransactionScannerWithFallback(client *Client, fallback *mempool.Client) *TransactionScanner {
+	return &TransactionScanner{
+		client:   client,
+		fallback: fallback,
 	}
+}
 
-	// Get all transactions for this address
-	transactions, err := ts.GetAddressTransactions(address)
+// GetBalanceHistory scans transaction history from the address's birthday
+// and generates daily balance snapshots. Each snapshot's Source names the
+// backends that supplied the history, e.g. "bitcoin-core" or
+// "bitcoin-core+esplora" on a pruned node, with "-partial" appended when
+// pre-prune history is unavailable.
+func (ts *TransactionScanner) GetBalanceHistory(address string, birthday Birthday, from, to time.Time) ([]AddressBalanceResult, error) {
+	log.Printf("📈 Scanning transaction history for %s from %v to %v",
+		redact.Address(address), from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	history, err := ts.GetAddressMovements(address, birthday)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transactions: %w", err)
 	}
 
-	// Filter transactions within date range and sort by time
-	filteredTxs := ts.filterTransactionsByDate(transactions, from, to)
-	sort.Slice(filteredTxs, func(i, j int) bool {
-		return filteredTxs[i].Time < filteredTxs[j].Time
-	})
-
-	log.Printf("📊 Found %d transactions for %s in date range", len(filteredTxs), truncateAddress(address))
-
-	// Generate daily balance snapshots
-	snapshots := ts.generateDailySnapshots(filteredTxs, address, from, to)
+	source := strings.Join(history.Sources, "+")
+	if !history.Complete {
+		source += "-partial"
+	}
+	log.Printf("📊 Found %d transactions for %s from %s", len(history.Movements), redact.Address(address), source)
 
-	return snapshots, nil
+	return dailySnapshots(history.Movements, address, source, from, to), nil
 }
 
 // GetAddressTransactions gets all transactions for an address from Bitcoin Core
 func (ts *TransactionScanner) GetAddressTransactions(address string) ([]AddressTransaction, error) {
-	// Use listtransactions to get all wallet transactions
+	return ts.GetAddressTransactionsContext(context.Background(), address)
+}
+
+// GetAddressTransactionsContext is GetAddressTransactions traced within the
+// span of ctx
+func (ts *TransactionScanner) GetAddressTransactionsContext(ctx context.Context, address string) ([]AddressTransaction, error) {
 	// Note: This requires the address to be imported as watch-only
-	output, err := RunBitcoinCLI("listtransactions", "*", "10000", "0", "true")
+	allTxs, err := ts.listAllTransactions(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list transactions: %w", err)
-	}
-
-	var allTxs []AddressTransaction
-	if err := json.Unmarshal(output, &allTxs); err != nil {
-		return nil, fmt.Errorf("failed to parse transactions: %w", err)
+		return nil, err
 	}
 
 	// Filter for this specific address
@@ -83,10 +102,249 @@ func (ts *TransactionScanner) GetAddressTransactions(address string) ([]AddressT
 		}
 	}
 
-	log.Printf("🔍 Found %d transactions for address %s", len(addressTxs), truncateAddress(address))
+	log.Printf("🔍 Found %d transactions for address %s", len(addressTxs), redact.Address(address))
 	return addressTxs, nil
 }
 
+// listTransactionsPageSize is how many wallet entries are fetched per listtransactions call
+const listTransactionsPageSize = 1000
+
+// listAllTransactions pages through every transaction in the tracking wallet
+func (ts *TransactionScanner) listAllTransactions(ctx context.Context) ([]AddressTransaction, error) {
+	var allTxs []AddressTransaction
+	for skip := 0; ; skip += listTransactionsPageSize {
+		output, err := RunBitcoinCLIContext(ctx, "listtransactions", "*",
+			strconv.Itoa(listTransactionsPageSize), strconv.Itoa(skip), "true")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list transactions: %w", err)
+		}
+
+		var page []AddressTransaction
+		if err := json.Unmarshal(output, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse transactions: %w", err)
+		}
+		allTxs = append(allTxs, page...)
+
+		if len(page) < listTransactionsPageSize {
+			return allTxs, nil
+		}
+	}
+}
+
+// GetAddressMovements returns every confirmed credit and debit for a single
+// address from its birthday on
+func (ts *TransactionScanner) GetAddressMovements(address string, birthday Birthday) (*MovementHistory, error) {
+	rescanHeight := ts.rescanHeight(birthday)
+
+	ts.importMu.Lock()
+	err := ts.client.ImportAddressWithRescan(address, rescanHeight == 0, birthday.Time)
+	if err == nil && rescanHeight > 0 {
+		err = ts.client.RescanBlockchain(rescanHeight)
+	}
+	ts.importMu.Unlock()
+	if err != nil {
+		log.Printf("⚠️  Import warning for %s: %v", redact.Address(address), err)
+	}
+
+	return ts.collectHistory([][]string{{address}}, birthday)
+}
+
+// GetXPubMovements returns every confirmed credit and debit for the first
+// depth receive and change addresses of an extended public key from its
+// birthday on
+func (ts *TransactionScanner) GetXPubMovements(xpub, scriptType string, depth int, birthday Birthday) (*MovementHistory, error) {
+	descriptors, err := XPubDescriptors(xpub, scriptType)
+	if err != nil {
+		return nil, err
+	}
+	rescanHeight := ts.rescanHeight(birthday)
+
+	var chains [][]string
+	for _, descriptor := range descriptors {
+		ts.importMu.Lock()
+		err := ts.client.ImportRangedDescriptor(descriptor, depth, rescanHeight == 0, birthday.Time)
+		ts.importMu.Unlock()
+		if err != nil {
+			log.Printf("⚠️  Import warning for %s: %v", redact.Address(xpub), err)
+		}
+		derived, err := ts.client.DeriveAddresses(descriptor, depth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive addresses: %w", err)
+		}
+		chains = append(chains, derived)
+	}
+
+	if rescanHeight > 0 {
+		ts.importMu.Lock()
+		err := ts.client.RescanBlockchain(rescanHeight)
+		ts.importMu.Unlock()
+		if err != nil {
+			log.Printf("⚠️  Rescan warning for %s: %v", redact.Address(xpub), err)
+		}
+	}
+
+	return ts.collectHistory(chains, birthday)
+}
+
+// rescanHeight returns the block to rescan from after importing without a
+// rescan: the later of the birthday height and, on a pruned node, the prune
+// height. It is 0 when the import itself should rescan, from the birthday
+// time or genesis.
+func (ts *TransactionScanner) rescanHeight(birthday Birthday) int64 {
+	pruned, pruneHeight := ts.pruneState()
+	if pruned {
+		return birthday.after(pruneHeight).Height
+	}
+	return birthday.Height
+}
+
+// collectHistory gathers movements from the birthday on for address chains
+// from Bitcoin Core and, on a pruned node, from the external backend. Each
+// output is attributed to exactly one source by the height it was created
+// at: Bitcoin Core covers outputs from the prune height on and the external
+// backend covers older ones, including when they are spent later. A wallet
+// born at or after the prune height needs no external history at all.
+func (ts *TransactionScanner) collectHistory(chains [][]string, birthday Birthday) (*MovementHistory, error) {
+	var addresses []string
+	for _, chain := range chains {
+		addresses = append(addresses, chain...)
+	}
+
+	pruned, pruneHeight := ts.pruneState()
+	history := &MovementHistory{Pruned: pruned, Complete: true}
+	if !pruned || birthday.Height >= pruneHeight {
+		movements, err := ts.getMovements(addresses, birthday)
+		if err != nil {
+			return nil, err
+		}
+		history.Movements = movements
+		history.Sources = []string{SourceBitcoinCore}
+		if pruned {
+			history.PruneHeight = pruneHeight
+		}
+		return history, nil
+	}
+
+	history.PruneHeight = pruneHeight
+	since := birthday
+	if ts.fallback != nil {
+		external, err := ts.externalMovements(chains, birthday, pruneHeight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch pre-prune history: %w", err)
+		}
+		history.Movements = external
+		history.Sources = append(history.Sources, SourceEsplora)
+		since = birthday.after(pruneHeight)
+	} else {
+		// Keep whatever the wallet saw before pruning, but it may be partial
+		history.Complete = false
+	}
+
+	movements, err := ts.getMovements(addresses, since)
+	if err != nil {
+		return nil, err
+	}
+	history.Movements = append(history.Movements, movements...)
+	history.Sources = append([]string{SourceBitcoinCore}, history.Sources...)
+
+	sortMovements(history.Movements)
+	return history, nil
+}
+
+// GetMovements returns the confirmed credits and debits for a set of
+// addresses, oldest first. Credits come from the wallet's receive entries.
+// Debits are found by decoding the inputs of the wallet's send transactions,
+// so fees and change are accounted for without relying on the send entries'
+// destination addresses.
+func (ts *TransactionScanner) GetMovements(addresses []string) ([]BalanceMovement, error) {
+	return ts.getMovements(addresses, Birthday{})
+}
+
+// getMovements is GetMovements restricted to outputs created on or after
+// since, along with their spends
+func (ts *TransactionScanner) getMovements(addresses []string, since Birthday) ([]BalanceMovement, error) {
+	watched := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		watched[address] = true
+	}
+
+	allTxs, err := ts.listAllTransactions(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	credits := make(map[outpoint]int64)
+	spendTxs := make(map[string]bool)
+	var movements []BalanceMovement
+
+	for _, tx := range allTxs {
+		// Unconfirmed and conflicted transactions have no block time
+		if tx.Blocktime == 0 || !since.includes(tx.Blockheight, tx.Blocktime) {
+			continue
+		}
+		switch tx.Category {
+		case "receive", "generate":
+			if watched[tx.Address] {
+				amount := btcToSats(tx.Amount)
+				credits[outpoint{tx.TxID, tx.Vout}] = amount
+				movements = append(movements, BalanceMovement{
+					TxID:      tx.TxID,
+					Height:    tx.Blockheight,
+					BlockHash: tx.Blockhash,
+					Blocktime: tx.Blocktime,
+					Amount:    amount,
+					Source:    SourceBitcoinCore,
+				})
+			}
+		case "send":
+			spendTxs[tx.TxID] = true
+		}
+	}
+
+	for txid := range spendTxs {
+		walletTx, err := ts.client.GetWalletTransaction(txid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s: %w", txid, err)
+		}
+
+		var spent int64
+		for _, vin := range walletTx.Decoded.Vin {
+			spent += credits[outpoint{vin.TxID, vin.Vout}]
+		}
+		if spent > 0 {
+			movements = append(movements, BalanceMovement{
+				TxID:      txid,
+				Height:    walletTx.Blockheight,
+				BlockHash: walletTx.Blockhash,
+				Blocktime: walletTx.Blocktime,
+				Amount:    -spent,
+				Source:    SourceBitcoinCore,
+			})
+		}
+	}
+
+	sortMovements(movements)
+	return movements, nil
+}
+
+// outpoint identifies a transaction output
+type outpoint struct {
+	txid string
+	vout int
+}
+
+func sortMovements(movements []BalanceMovement) {
+	sort.SliceStable(movements, func(i, j int) bool {
+		return movements[i].Blocktime < movements[j].Blocktime
+	})
+}
+
+// btcToSats converts a bitcoin-cli BTC amount to satoshis, rounding away
+// floating point error
+func btcToSats(amount float64) int64 {
+	return int64(math.Round(amount * 100000000))
+}
+
 // GetTransactionSummary generates daily transaction summaries for an address
 func (ts *TransactionScanner) GetTransactionSummary(address string, from, to time.Time) ([]TransactionSummary, error) {
 	transactions, err := ts.GetAddressTransactions(address)
@@ -158,61 +416,30 @@ func (ts *TransactionScanner) filterTransactionsByDate(transactions []AddressTra
 	return filtered
 }
 
-// generateDailySnapshots creates daily balance snapshots from transaction data
-func (ts *TransactionScanner) generateDailySnapshots(transactions []AddressTransaction, address string, from, to time.Time) []AddressBalanceResult {
+// dailySnapshots creates one snapshot per day from from through to. The
+// balance is summed forward from the address's first movement, so movements
+// before from set the opening balance.
+func dailySnapshots(movements []BalanceMovement, address, source string, from, to time.Time) []AddressBalanceResult {
 	var snapshots []AddressBalanceResult
-
-	// Get current balance as our ending point
-	currentBalance := int64(0)
-	if len(transactions) > 0 {
-		// Try to get the actual current balance from Bitcoin Core
-		balance, err := ts.client.GetAddressBalance(address)
-		if err != nil {
-			log.Printf("⚠️  Could not get current balance for %s: %v", address, err)
-		} else {
-			currentBalance = balance
-		}
-	}
-
-	// Group transactions by date
-	dailyTxs := make(map[string][]AddressTransaction)
-	for _, tx := range transactions {
-		date := time.Unix(tx.Blocktime, 0).Format("2006-01-02")
-		dailyTxs[date] = append(dailyTxs[date], tx)
-	}
-
-	// Work backwards from current balance to calculate historical balances
-	runningBalance := currentBalance
-
-	// Generate snapshots for each day (work backwards for accuracy)
-	current := to
-	for current.After(from) || current.Equal(from) {
-		dateKey := current.Format("2006-01-02")
-		dayTxs := dailyTxs[dateKey]
-
-		// Calculate net change for this day
-		var netChange int64
-		for _, tx := range dayTxs {
-			amountSats := int64(tx.Amount * 100000000)
-			netChange += amountSats
+	var balance int64
+	next := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1).Unix()
+		var txCount int64
+		for ; next < len(movements) && movements[next].Blocktime < end; next++ {
+			balance += movements[next].Amount
+			if movements[next].Blocktime >= day.Unix() {
+				txCount++
+			}
 		}
-
-		// Create snapshot for this day
-		snapshot := AddressBalanceResult{
+		snapshots = append(snapshots, AddressBalanceResult{
 			Address:     address,
-			Balance:     runningBalance,
-			TxCount:     int64(len(dayTxs)),
-			LastUpdated: current,
-			Source:      "transaction-scan",
-		}
-
-		snapshots = append([]AddressBalanceResult{snapshot}, snapshots...) // Prepend to maintain chronological order
-
-		// Move to previous day and adjust balance
-		runningBalance -= netChange
-		current = current.AddDate(0, 0, -1)
+			Balance:     balance,
+			TxCount:     txCount,
+			LastUpdated: day,
+			Source:      source,
+		})
 	}
-
 	return snapshots
 }
 
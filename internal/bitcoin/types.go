@@ -15,6 +15,7 @@ type BlockchainInfo struct {
 	ChainWork            string  `json:"chainwork"`
 	SizeOnDisk           int64   `json:"size_on_disk"`
 	Pruned               bool    `json:"pruned"`
+	PruneHeight          int64   `json:"pruneheight,omitempty"` // Lowest block still stored, when pruned
 	Warnings             string  `json:"warnings"`
 }
 
@@ -34,6 +35,20 @@ type UTXO struct {
 	Safe          bool    `json:"safe"`
 }
 
+// AddressBalanceSplit is an address balance split by confirmation status
+type AddressBalanceSplit struct {
+	Confirmed            int64 `json:"confirmed"`   // Satoshis in UTXOs with at least MinConfirmations confirmations
+	Unconfirmed          int64 `json:"unconfirmed"` // Satoshis in UTXOs with fewer
+	UTXOCount            int64 `json:"utxo_count"`
+	UnconfirmedUTXOCount int64 `json:"unconfirmed_utxo_count"`
+	MinConfirmations     int64 `json:"min_confirmations"`
+}
+
+// Total returns the confirmed and unconfirmed balance combined
+func (b AddressBalanceSplit) Total() int64 {
+	return b.Confirmed + b.Unconfirmed
+}
+
 // AddressTransaction represents a transaction involving an address
 type AddressTransaction struct {
 	Account         string   `json:"account,omitempty"`
@@ -46,6 +61,7 @@ type AddressTransaction struct {
 	Confirmations   int64    `json:"confirmations"`
 	Blockhash       string   `json:"blockhash,omitempty"`
 	Blockindex      int      `json:"blockindex,omitempty"`
+	Blockheight     int64    `json:"blockheight,omitempty"`
 	Blocktime       int64    `json:"blocktime,omitempty"`
 	TxID            string   `json:"txid"`
 	WalletConflicts []string `json:"walletconflicts"`
@@ -57,6 +73,49 @@ type AddressTransaction struct {
 	Abandoned       bool     `json:"abandoned,omitempty"`
 }
 
+// WalletTransaction represents the result of gettransaction with verbose decoding
+type WalletTransaction struct {
+	TxID        string `json:"txid"`
+	Blockhash   string `json:"blockhash,omitempty"`
+	Blocktime   int64  `json:"blocktime,omitempty"`
+	Blockheight int64  `json:"blockheight,omitempty"`
+	Decoded     struct {
+		Vin []struct {
+			TxID string `json:"txid"`
+			Vout int    `json:"vout"`
+		} `json:"vin"`
+	} `json:"decoded"`
+}
+
+// Sources of transaction history
+const (
+	SourceBitcoinCore = "bitcoin-core"
+	SourceEsplora     = "esplora"
+)
+
+// BalanceMovement is a confirmed credit to or debit from a set of addresses
+type BalanceMovement struct {
+	TxID      string `json:"txid"`
+	Height    int64  `json:"height"`
+	BlockHash string `json:"blockhash"`
+	Blocktime int64  `json:"blocktime"`
+	Amount    int64  `json:"amount"` // Satoshis, negative when coins are spent
+	Source    string `json:"source"` // SourceBitcoinCore or SourceEsplora
+}
+
+// MovementHistory is the transaction history of an address or xpub along
+// with where it came from
+type MovementHistory struct {
+	Movements []BalanceMovement `json:"movements"`
+	// Sources lists the backends that supplied movements
+	Sources []string `json:"sources"`
+	// Pruned is set when Bitcoin Core only holds blocks from PruneHeight on
+	Pruned      bool  `json:"pruned"`
+	PruneHeight int64 `json:"prune_height,omitempty"`
+	// Complete is false when history below PruneHeight could not be fetched
+	Complete bool `json:"complete"`
+}
+
 // AddressValidation represents the result of validateaddress
 type AddressValidation struct {
 	IsValid        bool   `json:"isvalid"`
@@ -100,6 +159,33 @@ type BalanceUpdate struct {
 	BlockHeight   int64     `json:"block_height"`
 }
 
+// UTXOSetScan is the result of scantxoutset start
+type UTXOSetScan struct {
+	Success  bool          `json:"success"`
+	Height   int64         `json:"height"`
+	Unspents []ScannedUTXO `json:"unspents"`
+}
+
+// ScannedUTXO is an unspent output found by scantxoutset
+type ScannedUTXO struct {
+	TxID         string `json:"txid"`
+	Vout         uint32 `json:"vout"`
+	ScriptPubKey string `json:"scriptPubKey"`
+	// Desc describes the output with the full origin path of each key,
+	// which ends in the address index
+	Desc   string  `json:"desc"`
+	Amount float64 `json:"amount"`
+	Height int64   `json:"height"`
+}
+
+// FeeEstimate is the result of estimatesmartfee. FeeRate is in BTC/kvB and
+// missing when Bitcoin Core has too little data, which Errors explains.
+type FeeEstimate struct {
+	FeeRate float64  `json:"feerate"`
+	Errors  []string `json:"errors"`
+	Blocks  int      `json:"blocks"`
+}
+
 // DescriptorInfo represents the result of getdescriptorinfo
 type DescriptorInfo struct {
 	Descriptor     string `json:"descriptor"`
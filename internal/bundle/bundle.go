@@ -0,0 +1,201 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// FormatVersion is the current configuration bundle format
+const FormatVersion = 1
+
+// Bundle is a portable description of everything a node tracks, without balances.
+// It is used to migrate a setup to a new server or share it.
+type Bundle struct {
+	Version         int                    `json:"version"`
+	ExportedAt      time.Time              `json:"exported_at"`
+	Addresses       []AddressConfig        `json:"addresses"`
+	OfflineAccounts []OfflineAccountConfig `json:"offline_accounts"`
+}
+
+// AddressConfig is a tracked on-chain address or xpub
+type AddressConfig struct {
+	Address     string     `json:"address"`
+	Label       string     `json:"label"`
+	Active      bool       `json:"active"`
+	BirthHeight int64      `json:"birth_height,omitempty"`
+	BirthDate   *time.Time `json:"birth_date,omitempty"`
+	Class       string     `json:"class,omitempty"`
+}
+
+// OfflineAccountConfig is a cold storage account without its balance
+type OfflineAccountConfig struct {
+	Name        string `json:"name"`
+	Notes       string `json:"notes"`
+	DisplayUnit string `json:"display_unit"`
+	db.ColdStorageMetadata
+}
+
+// ImportResult summarizes what an import changed
+type ImportResult struct {
+	AddressesAdded         int      `json:"addresses_added"`
+	AddressesSkipped       int      `json:"addresses_skipped"`
+	OfflineAccountsAdded   int      `json:"offline_accounts_added"`
+	OfflineAccountsSkipped int      `json:"offline_accounts_skipped"`
+	Warnings               []string `json:"warnings,omitempty"`
+}
+
+// Export builds a bundle from the tracked entities in database
+func Export(database *db.Database) (*Bundle, error) {
+	addresses, err := database.GetOnchainAddresses()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked addresses: %w", err)
+	}
+
+	entries, err := database.GetColdStorageEntries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offline accounts: %w", err)
+	}
+
+	b := &Bundle{
+		Version:         FormatVersion,
+		ExportedAt:      time.Now().UTC(),
+		Addresses:       make([]AddressConfig, 0, len(addresses)),
+		OfflineAccounts: make([]OfflineAccountConfig, 0, len(entries)),
+	}
+
+	for _, addr := range addresses {
+		b.Addresses = append(b.Addresses, AddressConfig{
+			Address:     addr.Address,
+			Label:       addr.Label,
+			Active:      addr.Active,
+			BirthHeight: addr.BirthHeight,
+			BirthDate:   addr.BirthDate,
+			Class:       addr.Class,
+		})
+	}
+
+	for _, entry := range entries {
+		b.OfflineAccounts = append(b.OfflineAccounts, OfflineAccountConfig{
+			Name:                entry.Name,
+			Notes:               entry.Notes,
+			DisplayUnit:         entry.DisplayUnit,
+			ColdStorageMetadata: entry.ColdStorageMetadata,
+		})
+	}
+
+	return b, nil
+}
+
+// Write encodes the bundle as indented JSON
+func (b *Bundle) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// Read decodes and validates a bundle
+func Read(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	if b.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d, expected %d", b.Version, FormatVersion)
+	}
+
+	for _, addr := range b.Addresses {
+		if !utils.ValidateBitcoinAddress(addr.Address) && !utils.ValidateXPub(addr.Address) {
+			return nil, fmt.Errorf("invalid address or xpub in bundle: %q", addr.Address)
+		}
+		if addr.BirthHeight < 0 {
+			return nil, fmt.Errorf("negative birth height for %q in bundle", addr.Address)
+		}
+		if addr.Class != "" && !db.IsValidAddressClass(addr.Class) {
+			return nil, fmt.Errorf("unknown class %q for %q in bundle", addr.Class, addr.Address)
+		}
+	}
+
+	for _, account := range b.OfflineAccounts {
+		if account.Name == "" {
+			return nil, fmt.Errorf("offline account without a name in bundle")
+		}
+		if !db.IsValidCustodyType(account.CustodyType) {
+			return nil, fmt.Errorf("invalid custody type %q for offline account %q", account.CustodyType, account.Name)
+		}
+	}
+
+	return &b, nil
+}
+
+// Import adds the bundle's entities to database. Entities that already exist
+// (same address or account name) are left untouched. New offline accounts start
+// with a zero balance, since bundles never carry balances.
+func Import(database *db.Database, b *Bundle) (*ImportResult, error) {
+	result := &ImportResult{}
+
+	for _, cfg := range b.Addresses {
+		addr, err := database.InsertOnchainAddress(cfg.Address, cfg.Label)
+		if err != nil {
+			if isUniqueViolation(err) {
+				result.AddressesSkipped++
+				continue
+			}
+			return result, fmt.Errorf("failed to add address %s: %w", cfg.Address, err)
+		}
+		if !cfg.Active {
+			if _, err := database.UpdateOnchainAddress(addr.ID, cfg.Label, false); err != nil {
+				return result, fmt.Errorf("failed to pause address %s: %w", cfg.Address, err)
+			}
+		}
+		if cfg.BirthHeight > 0 || cfg.BirthDate != nil {
+			if _, err := database.SetOnchainAddressBirthday(addr.ID, cfg.BirthHeight, cfg.BirthDate); err != nil {
+				return result, fmt.Errorf("failed to set birthday of address %s: %w", cfg.Address, err)
+			}
+		}
+		if cfg.Class != "" && cfg.Class != addr.Class {
+			if _, err := database.SetOnchainAddressClass(addr.ID, cfg.Class); err != nil {
+				return result, fmt.Errorf("failed to set class of address %s: %w", cfg.Address, err)
+			}
+		}
+		result.AddressesAdded++
+	}
+
+	for _, cfg := range b.OfflineAccounts {
+		entry, err := database.InsertColdStorageEntry(cfg.Name, 0, cfg.Notes)
+		if err != nil {
+			if isUniqueViolation(err) {
+				result.OfflineAccountsSkipped++
+				continue
+			}
+			return result, fmt.Errorf("failed to add offline account %s: %w", cfg.Name, err)
+		}
+		if cfg.ColdStorageMetadata != (db.ColdStorageMetadata{}) {
+			if _, err := database.UpdateColdStorageMetadata(entry.ID, cfg.ColdStorageMetadata); err != nil {
+				return result, fmt.Errorf("failed to set metadata for %s: %w", cfg.Name, err)
+			}
+		}
+		if unit, err := utils.NormalizeUnit(cfg.DisplayUnit); err == nil && unit != utils.UnitSats {
+			if err := database.UpdateColdStorageDisplayUnit(entry.ID, unit); err != nil {
+				return result, fmt.Errorf("failed to set display unit for %s: %w", cfg.Name, err)
+			}
+		}
+		result.OfflineAccountsAdded++
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("offline account %q was created with a zero balance, verify and update it", cfg.Name))
+	}
+
+	return result, nil
+}
+
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
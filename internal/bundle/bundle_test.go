@@ -0,0 +1,103 @@
+package bundle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func createTestDB(t *testing.T) *db.Database {
+	t.Helper()
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	return database
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := createTestDB(t)
+	defer source.Close()
+
+	savings, err := source.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Savings")
+	testutils.AssertNoError(t, err)
+	_, err = source.SetOnchainAddressBirthday(savings.ID, 832000, nil)
+	testutils.AssertNoError(t, err)
+	_, err = source.SetOnchainAddressClass(savings.ID, db.AddressClassCold)
+	testutils.AssertNoError(t, err)
+	paused, err := source.InsertOnchainAddress("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "Genesis")
+	testutils.AssertNoError(t, err)
+	_, err = source.UpdateOnchainAddress(paused.ID, paused.Label, false)
+	testutils.AssertNoError(t, err)
+
+	entry, err := source.InsertColdStorageEntry("Vault", 5000000, "steel plate")
+	testutils.AssertNoError(t, err)
+	_, err = source.UpdateColdStorageMetadata(entry.ID, db.ColdStorageMetadata{CustodyType: db.CustodyTypeHardware})
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, source.UpdateColdStorageDisplayUnit(entry.ID, "BTC"))
+
+	exported, err := Export(source)
+	testutils.AssertNoError(t, err)
+
+	var buf bytes.Buffer
+	testutils.AssertNoError(t, exported.Write(&buf))
+
+	// Balances are never part of a bundle
+	if strings.Contains(buf.String(), "5000000") {
+		t.Error("Bundle should not contain balances")
+	}
+
+	b, err := Read(&buf)
+	testutils.AssertNoError(t, err)
+
+	target := createTestDB(t)
+	defer target.Close()
+
+	result, err := Import(target, b)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, result.AddressesAdded, 2)
+	testutils.AssertEqual(t, result.OfflineAccountsAdded, 1)
+
+	addresses, err := target.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(addresses), 2)
+	testutils.AssertEqual(t, addresses[0].BirthHeight, int64(832000))
+	testutils.AssertEqual(t, addresses[0].Class, db.AddressClassCold)
+	testutils.AssertEqual(t, addresses[1].Active, false)
+	testutils.AssertEqual(t, addresses[1].Class, db.AddressClassHot)
+
+	entries, err := target.GetColdStorageEntries()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, entries[0].Balance, int64(0))
+	testutils.AssertEqual(t, entries[0].CustodyType, db.CustodyTypeHardware)
+	testutils.AssertEqual(t, entries[0].DisplayUnit, "BTC")
+
+	// Importing again skips everything
+	result, err = Import(target, b)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, result.AddressesSkipped, 2)
+	testutils.AssertEqual(t, result.OfflineAccountsSkipped, 1)
+}
+
+func TestReadRejectsInvalidBundles(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"wrong version", `{"version": 99}`},
+		{"unknown field", `{"version": 1, "balances": []}`},
+		{"bad address", `{"version": 1, "addresses": [{"address": "not-an-address"}]}`},
+		{"unnamed account", `{"version": 1, "offline_accounts": [{"name": ""}]}`},
+		{"bad custody type", `{"version": 1, "offline_accounts": [{"name": "x", "custody_type": "exchange"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.input))
+			testutils.AssertError(t, err, tt.name)
+		})
+	}
+}
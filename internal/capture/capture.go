@@ -0,0 +1,75 @@
+// Package capture optionally keeps the raw responses of lncli and
+// bitcoin-cli, tagged with the collector run that asked for them, so numbers
+// that look wrong can be traced back to exactly what the node returned.
+// Capture is off until a Store is installed with Enable.
+package capture
+
+import (
+	"sync"
+	"time"
+)
+
+// Record is one node command and its raw response
+type Record struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"` // lncli or bitcoin-cli
+	Args    []string  `json:"args"`
+	// Collector and RunID identify the collector run that was in progress,
+	// see SetRun; both are empty outside a run
+	Collector string `json:"collector,omitempty"`
+	RunID     int64  `json:"run_id,omitempty"`
+	Output    string `json:"output"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Store persists records. Implementations must be safe for concurrent use.
+type Store interface {
+	Save(record Record) error
+}
+
+var (
+	mu        sync.Mutex
+	store     Store
+	collector string
+	runID     int64
+)
+
+// Enable installs the store records are saved to; nil turns capture off
+func Enable(s Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	store = s
+}
+
+// SetRun tags the records that follow with a collector run until the next
+// call; an empty collector clears the tag
+func SetRun(name string, id int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	collector, runID = name, id
+}
+
+// Save records a command's response when capture is enabled. A record that
+// cannot be stored is dropped: debugging must not break collection.
+func Save(command string, args []string, output []byte, err error) {
+	mu.Lock()
+	s := store
+	record := Record{
+		Time:      time.Now().UTC(),
+		Command:   command,
+		Args:      args,
+		Collector: collector,
+		RunID:     runID,
+		Output:    string(output),
+	}
+	mu.Unlock()
+
+	if s == nil {
+		return
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	// Errors are deliberately ignored, see above
+	_ = s.Save(record)
+}
@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestSaveTagsRun(t *testing.T) {
+	dir := t.TempDir()
+	testutils.AssertNoError(t, EnableDir(dir, 1))
+	defer Enable(nil)
+
+	SetRun("forwarding", 42)
+	Save("lncli", []string{"fwdinghistory", "--start_time", "1700000000"}, []byte(`{"forwarding_events": []}`), nil)
+	SetRun("", 0)
+	Save("lncli", []string{"getinfo"}, nil, errors.New("lncli command failed: exit status 1"))
+
+	records, err := List(dir, Filter{}, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(records), 2)
+	testutils.AssertEqual(t, records[0].Args[0], "getinfo")
+	testutils.AssertEqual(t, records[0].RunID, int64(0))
+	testutils.AssertEqual(t, records[0].Error, "lncli command failed: exit status 1")
+	testutils.AssertEqual(t, records[1].Collector, "forwarding")
+	testutils.AssertEqual(t, records[1].Output, `{"forwarding_events": []}`)
+
+	records, err = List(dir, Filter{Collector: "forwarding", RunID: 42}, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(records), 1)
+
+	// Nothing is saved once capture is off
+	Enable(nil)
+	Save("lncli", []string{"getinfo"}, []byte("{}"), nil)
+	records, err = List(dir, Filter{}, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(records), 2)
+}
+
+func TestRingStoreEvictsOldest(t *testing.T) {
+	dir := t.TempDir()
+	output := strings.Repeat("x", 400)
+
+	store, err := NewRingStore(dir, 2000)
+	testutils.AssertNoError(t, err)
+	for i := 0; i < 10; i++ {
+		testutils.AssertNoError(t, store.Save(Record{Command: "bitcoin-cli", Args: []string{"listunspent"}, RunID: int64(i + 1), Output: output}))
+	}
+
+	entries, err := os.ReadDir(dir)
+	testutils.AssertNoError(t, err)
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		testutils.AssertNoError(t, err)
+		size += info.Size()
+	}
+	if size > 2000 {
+		t.Errorf("expected at most 2000 bytes kept, got %d", size)
+	}
+
+	records, err := List(dir, Filter{}, 100)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, records[0].RunID, int64(10))
+	testutils.AssertEqual(t, len(records) < 10, true)
+
+	// Reopening picks up the existing files and keeps enforcing the cap
+	store, err = NewRingStore(dir, 1000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, store.Save(Record{Command: "bitcoin-cli", Args: []string{"listunspent"}, RunID: 11, Output: output}))
+	records, err = List(dir, Filter{}, 100)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, records[0].RunID, int64(11))
+	testutils.AssertEqual(t, len(records) <= 2, true)
+}
@@ -0,0 +1,157 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxBytes caps a RingStore at 50 MB
+const DefaultMaxBytes = 50 << 20
+
+// RingStore keeps records as one JSON file each in a directory, deleting
+// the oldest files once their total size exceeds a cap
+type RingStore struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	files []ringFile // Oldest first
+	size  int64
+	seq   int
+}
+
+type ringFile struct {
+	name string
+	size int64
+}
+
+// NewRingStore opens or creates a ring buffer in dir capped at maxBytes
+func NewRingStore(dir string, maxBytes int64) (*RingStore, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("capture size cap must be positive")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+
+	names, err := recordFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &RingStore{dir: dir, maxBytes: maxBytes}
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		s.files = append(s.files, ringFile{name: name, size: info.Size()})
+		s.size += info.Size()
+	}
+	return s, nil
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Save writes record and evicts the oldest records over the size cap
+func (s *RingStore) Save(record Record) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Names sort chronologically; seq orders records within the same instant
+	s.seq++
+	subcommand := ""
+	if len(record.Args) > 0 {
+		subcommand = unsafeNameChars.ReplaceAllString(record.Args[0], "")
+	}
+	name := fmt.Sprintf("%s-%06d-%s-%s.json",
+		record.Time.UTC().Format("20060102T150405.000000000"), s.seq%1000000, record.Command, subcommand)
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0600); err != nil {
+		return err
+	}
+	s.files = append(s.files, ringFile{name: name, size: int64(len(data))})
+	s.size += int64(len(data))
+
+	// Keep the newest record even when it alone is over the cap
+	for s.size > s.maxBytes && len(s.files) > 1 {
+		oldest := s.files[0]
+		if err := os.Remove(filepath.Join(s.dir, oldest.name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.files = s.files[1:]
+		s.size -= oldest.size
+	}
+	return nil
+}
+
+// recordFiles returns the record file names in dir, oldest first
+func recordFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Filter selects records when listing a capture directory. Zero fields match
+// every record.
+type Filter struct {
+	Collector string
+	RunID     int64
+}
+
+// List returns up to limit records from a RingStore directory, newest first
+func List(dir string, filter Filter, limit int) ([]Record, error) {
+	names, err := recordFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []Record{}
+	for i := len(names) - 1; i >= 0 && len(records) < limit; i-- {
+		data, err := os.ReadFile(filepath.Join(dir, names[i]))
+		if err != nil {
+			// Evicted since the directory was read
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", names[i], err)
+		}
+		if filter.Collector != "" && record.Collector != filter.Collector {
+			continue
+		}
+		if filter.RunID != 0 && record.RunID != filter.RunID {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// EnableDir turns capture on with a RingStore in dir capped at maxMB megabytes
+func EnableDir(dir string, maxMB int64) error {
+	store, err := NewRingStore(dir, maxMB<<20)
+	if err != nil {
+		return err
+	}
+	Enable(store)
+	return nil
+}
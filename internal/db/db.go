@@ -1,23 +1,58 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/brewgator/lightning-node-tools/internal/capture"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/tracing"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 var (
 	// ErrNotFound indicates that a requested resource was not found
 	ErrNotFound = errors.New("resource not found")
+	// ErrStatementExists indicates that the month has already been closed
+	ErrStatementExists = errors.New("statement already exists")
+	// ErrQuarantined indicates that a record had an impossible timestamp and
+	// was moved to quarantined_records instead of being inserted
+	ErrQuarantined = errors.New("record quarantined")
+	// ErrModeMismatch indicates that a database is mostly used in the other
+	// mode, mock or real, than the one it was opened in
+	ErrModeMismatch = errors.New("database mode mismatch")
+	// ErrNotSats indicates that an amount in another unit, such as fiat
+	// cents, was about to be used as sats
+	ErrNotSats = errors.New("amount is not in sats")
+	// ErrStatusChanged indicates that a record was no longer in the status
+	// a transition expected
+	ErrStatusChanged = errors.New("status changed")
 )
 
 type Database struct {
 	conn     *sql.DB
 	mockMode bool
+
+	// Set when the database is a dry run's in-memory copy, which lives as
+	// long as scratch is open
+	scratch  *sql.Conn
+	baseline map[string]tableMark
+
+	// ctx is the context queries run in, set by WithContext
+	ctx context.Context
 }
 
 // NewDatabase creates a new database connection and initializes tables
@@ -44,6 +79,30 @@ func NewDatabaseWithMockMode(dbPath string, mockMode bool) (*Database, error) {
 	return db, nil
 }
 
+// DefaultProfile is the portfolio kept in the database file given by --db.
+// Every other profile is a separate portfolio in a database file of its own
+// next to it, so profiles never share a row.
+const DefaultProfile = "default"
+
+// ProfileFlagUsage describes the flag services use to pick a profile
+const ProfileFlagUsage = "Portfolio profile, e.g. business; profiles other than \"default\" are kept in their own database file next to --db"
+
+var profileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ProfilePath returns the database file of profile for the database at
+// dbPath: dbPath itself for DefaultProfile, otherwise the profile name is
+// appended to the file name, e.g. data/portfolio-business.db.
+func ProfilePath(dbPath, profile string) (string, error) {
+	if profile == DefaultProfile {
+		return dbPath, nil
+	}
+	if !profileName.MatchString(profile) {
+		return "", fmt.Errorf("invalid profile %q: use up to 32 lowercase letters, digits, - and _", profile)
+	}
+	ext := filepath.Ext(dbPath)
+	return strings.TrimSuffix(dbPath, ext) + "-" + profile + ext, nil
+}
+
 // IsMockMode returns true if the database is running in mock mode
 func (db *Database) IsMockMode() bool {
 	return db.mockMode
@@ -67,9 +126,64 @@ func (db *Database) GetTableName(baseName string) string {
 
 // Close closes the database connection
 func (db *Database) Close() error {
+	if db.scratch != nil {
+		db.scratch.Close()
+	}
 	return db.conn.Close()
 }
 
+// WithContext returns a copy of the database whose queries run in ctx, so
+// they are traced within its span. The copy shares the connection; close
+// the original.
+func (db *Database) WithContext(ctx context.Context) *Database {
+	traced := *db
+	traced.ctx = ctx
+	return &traced
+}
+
+func (db *Database) context() context.Context {
+	if db.ctx == nil {
+		return context.Background()
+	}
+	return db.ctx
+}
+
+// startQuery begins the span of a query, named by its first keyword as in
+// "sqlite SELECT"
+func (db *Database) startQuery(query string) (context.Context, *tracing.Span) {
+	ctx := db.context()
+	if tracing.FromContext(ctx) == nil {
+		return ctx, nil
+	}
+	statement := strings.Join(strings.Fields(query), " ")
+	operation, _, _ := strings.Cut(statement, " ")
+	return tracing.StartClient(ctx, "sqlite "+strings.ToUpper(operation),
+		tracing.String("db.system", "sqlite"), tracing.String("db.statement", statement))
+}
+
+// query runs a query; its span covers the time to the first row
+func (db *Database) query(query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := db.startQuery(query)
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	span.End(err)
+	return rows, err
+}
+
+// queryRow runs a query for one row; its span does not cover Scan
+func (db *Database) queryRow(query string, args ...interface{}) *sql.Row {
+	ctx, span := db.startQuery(query)
+	row := db.conn.QueryRowContext(ctx, query, args...)
+	span.End(row.Err())
+	return row
+}
+
+func (db *Database) exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := db.startQuery(query)
+	result, err := db.conn.ExecContext(ctx, query, args...)
+	span.End(err)
+	return result, err
+}
+
 // initTables creates all required tables
 func (db *Database) initTables() error {
 	queries := []string{
@@ -100,11 +214,11 @@ func (db *Database) initTables() error {
 			active BOOLEAN NOT NULL,
 			peer_alias TEXT,
 			fee_ppm INTEGER,
-			base_fee INTEGER
+			base_fee INTEGER,
+			changed INTEGER
 		);`,
 
 		`CREATE INDEX IF NOT EXISTS idx_channel_snapshots_timestamp ON channel_snapshots(timestamp);`,
-		`CREATE INDEX IF NOT EXISTS idx_channel_snapshots_channel_id ON channel_snapshots(channel_id);`,
 
 		`CREATE TABLE IF NOT EXISTS forwarding_events (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -113,16 +227,42 @@ func (db *Database) initTables() error {
 			channel_out_id TEXT NOT NULL,
 			amount_in INTEGER NOT NULL,
 			amount_out INTEGER NOT NULL,
-			fee INTEGER NOT NULL
+			fee INTEGER NOT NULL,
+			fee_ppm INTEGER NOT NULL DEFAULT 0
 		);`,
 
 		`CREATE INDEX IF NOT EXISTS idx_forwarding_events_timestamp ON forwarding_events(timestamp);`,
 
+		`CREATE TABLE IF NOT EXISTS forwarding_daily (
+			date TEXT PRIMARY KEY,
+			total_fee INTEGER NOT NULL,
+			forward_count INTEGER NOT NULL
+		);`,
+
+		// Forwards that failed, seen on LND's HTLC event stream
+		`CREATE TABLE IF NOT EXISTS htlc_failures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			channel_in_id TEXT NOT NULL,
+			channel_out_id TEXT NOT NULL,
+			amount_in INTEGER NOT NULL,
+			amount_out INTEGER NOT NULL,
+			fee INTEGER NOT NULL,
+			outcome TEXT NOT NULL,
+			failure TEXT NOT NULL DEFAULT ''
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_htlc_failures_timestamp ON htlc_failures(timestamp);`,
+
 		`CREATE TABLE IF NOT EXISTS onchain_addresses (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			address TEXT UNIQUE NOT NULL,
 			label TEXT,
-			active BOOLEAN NOT NULL DEFAULT 1
+			active BOOLEAN NOT NULL DEFAULT 1,
+			birth_height INTEGER NOT NULL DEFAULT 0,
+			birth_date DATETIME,
+			class TEXT NOT NULL DEFAULT 'hot',
+			deleted_at DATETIME
 		);`,
 
 		`CREATE TABLE IF NOT EXISTS address_balances (
@@ -131,6 +271,8 @@ func (db *Database) initTables() error {
 			timestamp DATETIME NOT NULL,
 			balance INTEGER NOT NULL,
 			tx_count INTEGER NOT NULL,
+			block_height INTEGER NOT NULL DEFAULT 0,
+			block_hash TEXT NOT NULL DEFAULT '',
 			FOREIGN KEY(address_id) REFERENCES onchain_addresses(id)
 		);`,
 
@@ -141,7 +283,13 @@ func (db *Database) initTables() error {
 			name TEXT UNIQUE NOT NULL,
 			balance INTEGER NOT NULL,
 			last_updated DATETIME NOT NULL,
-			notes TEXT
+			notes TEXT,
+			custody_type TEXT NOT NULL DEFAULT '',
+			device_model TEXT NOT NULL DEFAULT '',
+			location_hint TEXT NOT NULL DEFAULT '',
+			derivation_ref TEXT NOT NULL DEFAULT '',
+			display_unit TEXT NOT NULL DEFAULT 'sats',
+			deleted_at DATETIME
 		);`,
 
 		// Mock data tables (identical structure with _mock suffix)
@@ -171,11 +319,11 @@ func (db *Database) initTables() error {
 			active BOOLEAN NOT NULL,
 			peer_alias TEXT,
 			fee_ppm INTEGER,
-			base_fee INTEGER
+			base_fee INTEGER,
+			changed INTEGER
 		);`,
 
 		`CREATE INDEX IF NOT EXISTS idx_channel_snapshots_mock_timestamp ON channel_snapshots_mock(timestamp);`,
-		`CREATE INDEX IF NOT EXISTS idx_channel_snapshots_mock_channel_id ON channel_snapshots_mock(channel_id);`,
 
 		`CREATE TABLE IF NOT EXISTS forwarding_events_mock (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -184,16 +332,41 @@ func (db *Database) initTables() error {
 			channel_out_id TEXT NOT NULL,
 			amount_in INTEGER NOT NULL,
 			amount_out INTEGER NOT NULL,
-			fee INTEGER NOT NULL
+			fee INTEGER NOT NULL,
+			fee_ppm INTEGER NOT NULL DEFAULT 0
 		);`,
 
 		`CREATE INDEX IF NOT EXISTS idx_forwarding_events_mock_timestamp ON forwarding_events_mock(timestamp);`,
 
+		`CREATE TABLE IF NOT EXISTS forwarding_daily_mock (
+			date TEXT PRIMARY KEY,
+			total_fee INTEGER NOT NULL,
+			forward_count INTEGER NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS htlc_failures_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			channel_in_id TEXT NOT NULL,
+			channel_out_id TEXT NOT NULL,
+			amount_in INTEGER NOT NULL,
+			amount_out INTEGER NOT NULL,
+			fee INTEGER NOT NULL,
+			outcome TEXT NOT NULL,
+			failure TEXT NOT NULL DEFAULT ''
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_htlc_failures_mock_timestamp ON htlc_failures_mock(timestamp);`,
+
 		`CREATE TABLE IF NOT EXISTS onchain_addresses_mock (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			address TEXT UNIQUE NOT NULL,
 			label TEXT,
-			active BOOLEAN NOT NULL DEFAULT 1
+			active BOOLEAN NOT NULL DEFAULT 1,
+			birth_height INTEGER NOT NULL DEFAULT 0,
+			birth_date DATETIME,
+			class TEXT NOT NULL DEFAULT 'hot',
+			deleted_at DATETIME
 		);`,
 
 		`CREATE TABLE IF NOT EXISTS address_balances_mock (
@@ -202,6 +375,8 @@ func (db *Database) initTables() error {
 			timestamp DATETIME NOT NULL,
 			balance INTEGER NOT NULL,
 			tx_count INTEGER NOT NULL,
+			block_height INTEGER NOT NULL DEFAULT 0,
+			block_hash TEXT NOT NULL DEFAULT '',
 			FOREIGN KEY(address_id) REFERENCES onchain_addresses_mock(id)
 		);`,
 
@@ -212,7 +387,13 @@ func (db *Database) initTables() error {
 			name TEXT UNIQUE NOT NULL,
 			balance INTEGER NOT NULL,
 			last_updated DATETIME NOT NULL,
-			notes TEXT
+			notes TEXT,
+			custody_type TEXT NOT NULL DEFAULT '',
+			device_model TEXT NOT NULL DEFAULT '',
+			location_hint TEXT NOT NULL DEFAULT '',
+			derivation_ref TEXT NOT NULL DEFAULT '',
+			display_unit TEXT NOT NULL DEFAULT 'sats',
+			deleted_at DATETIME
 		);`,
 
 		// Cold storage balance history tables
@@ -228,7 +409,6 @@ func (db *Database) initTables() error {
 		);`,
 
 		`CREATE INDEX IF NOT EXISTS idx_cold_storage_history_timestamp ON cold_storage_history(timestamp);`,
-		`CREATE INDEX IF NOT EXISTS idx_cold_storage_history_account_id ON cold_storage_history(account_id);`,
 
 		`CREATE TABLE IF NOT EXISTS cold_storage_history_mock (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -242,13 +422,13 @@ func (db *Database) initTables() error {
 		);`,
 
 		`CREATE INDEX IF NOT EXISTS idx_cold_storage_history_mock_timestamp ON cold_storage_history_mock(timestamp);`,
-		`CREATE INDEX IF NOT EXISTS idx_cold_storage_history_mock_account_id ON cold_storage_history_mock(account_id);`,
 
 		// Strike balance tracking tables
 		`CREATE TABLE IF NOT EXISTS strike_balance_snapshots (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			timestamp DATETIME NOT NULL,
 			currency TEXT NOT NULL,
+			unit TEXT NOT NULL DEFAULT '',
 			available INTEGER NOT NULL,
 			total INTEGER NOT NULL,
 			pending INTEGER NOT NULL,
@@ -263,6 +443,7 @@ func (db *Database) initTables() error {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			timestamp DATETIME NOT NULL,
 			currency TEXT NOT NULL,
+			unit TEXT NOT NULL DEFAULT '',
 			available INTEGER NOT NULL,
 			total INTEGER NOT NULL,
 			pending INTEGER NOT NULL,
@@ -271,21 +452,658 @@ func (db *Database) initTables() error {
 
 		`CREATE INDEX IF NOT EXISTS idx_strike_balance_mock_timestamp ON strike_balance_snapshots_mock(timestamp);`,
 		`CREATE INDEX IF NOT EXISTS idx_strike_balance_mock_currency ON strike_balance_snapshots_mock(currency);`,
+
+		// Liquid (L-BTC) balance snapshots
+		`CREATE TABLE IF NOT EXISTS liquid_balance_snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			confirmed INTEGER NOT NULL,
+			unconfirmed INTEGER NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_liquid_balance_timestamp ON liquid_balance_snapshots(timestamp);`,
+
+		`CREATE TABLE IF NOT EXISTS liquid_balance_snapshots_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			confirmed INTEGER NOT NULL,
+			unconfirmed INTEGER NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_liquid_balance_mock_timestamp ON liquid_balance_snapshots_mock(timestamp);`,
+
+		// Ecash balance snapshots, one row per federation or mint
+		`CREATE TABLE IF NOT EXISTS ecash_balance_snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			protocol TEXT NOT NULL,
+			mint TEXT NOT NULL,
+			name TEXT NOT NULL,
+			amount INTEGER NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_ecash_balance_timestamp ON ecash_balance_snapshots(timestamp);`,
+		`CREATE INDEX IF NOT EXISTS idx_ecash_balance_mint ON ecash_balance_snapshots(protocol, mint, timestamp);`,
+
+		`CREATE TABLE IF NOT EXISTS ecash_balance_snapshots_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			protocol TEXT NOT NULL,
+			mint TEXT NOT NULL,
+			name TEXT NOT NULL,
+			amount INTEGER NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_ecash_balance_mock_timestamp ON ecash_balance_snapshots_mock(timestamp);`,
+		`CREATE INDEX IF NOT EXISTS idx_ecash_balance_mock_mint ON ecash_balance_snapshots_mock(protocol, mint, timestamp);`,
+
+		// Collector run log
+		`CREATE TABLE IF NOT EXISTS collector_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			collector TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME,
+			status TEXT NOT NULL,
+			items_inserted INTEGER NOT NULL DEFAULT 0,
+			errors INTEGER NOT NULL DEFAULT 0,
+			error_message TEXT NOT NULL DEFAULT '',
+			resume_point TEXT NOT NULL DEFAULT ''
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_collector_runs_collector ON collector_runs(collector, started_at);`,
+
+		`CREATE TABLE IF NOT EXISTS collector_runs_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			collector TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME,
+			status TEXT NOT NULL,
+			items_inserted INTEGER NOT NULL DEFAULT 0,
+			errors INTEGER NOT NULL DEFAULT 0,
+			error_message TEXT NOT NULL DEFAULT '',
+			resume_point TEXT NOT NULL DEFAULT ''
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_collector_runs_mock_collector ON collector_runs_mock(collector, started_at);`,
+
+		// Node versions, one row each time a node is seen on a new version
+		`CREATE TABLE IF NOT EXISTS node_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			node TEXT NOT NULL,
+			version TEXT NOT NULL,
+			first_seen DATETIME NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_node_versions_node ON node_versions(node, first_seen);`,
+
+		`CREATE TABLE IF NOT EXISTS node_versions_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			node TEXT NOT NULL,
+			version TEXT NOT NULL,
+			first_seen DATETIME NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_node_versions_mock_node ON node_versions_mock(node, first_seen);`,
+
+		// Records rejected for an impossible timestamp, kept for inspection
+		`CREATE TABLE IF NOT EXISTS quarantined_records (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source TEXT NOT NULL,
+			record_timestamp DATETIME NOT NULL,
+			reason TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			quarantined_at DATETIME NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_quarantined_records_quarantined_at ON quarantined_records(quarantined_at);`,
+
+		`CREATE TABLE IF NOT EXISTS quarantined_records_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source TEXT NOT NULL,
+			record_timestamp DATETIME NOT NULL,
+			reason TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			quarantined_at DATETIME NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_quarantined_records_mock_quarantined_at ON quarantined_records_mock(quarantined_at);`,
+
+		// Lightning balance points derived from LND history, one per event
+		`CREATE TABLE IF NOT EXISTS lightning_balance_points (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_key TEXT NOT NULL UNIQUE,
+			timestamp DATETIME NOT NULL,
+			event_type TEXT NOT NULL,
+			amount INTEGER NOT NULL DEFAULT 0,
+			onchain_balance INTEGER NOT NULL DEFAULT 0,
+			lightning_local INTEGER NOT NULL DEFAULT 0,
+			lightning_remote INTEGER NOT NULL DEFAULT 0
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_lightning_balance_points_timestamp ON lightning_balance_points(timestamp);`,
+
+		`CREATE TABLE IF NOT EXISTS lightning_balance_points_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_key TEXT NOT NULL UNIQUE,
+			timestamp DATETIME NOT NULL,
+			event_type TEXT NOT NULL,
+			amount INTEGER NOT NULL DEFAULT 0,
+			onchain_balance INTEGER NOT NULL DEFAULT 0,
+			lightning_local INTEGER NOT NULL DEFAULT 0,
+			lightning_remote INTEGER NOT NULL DEFAULT 0
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_lightning_balance_points_mock_timestamp ON lightning_balance_points_mock(timestamp);`,
+
+		// Mission control pair history snapshots; times are unix seconds, 0 when unset
+		`CREATE TABLE IF NOT EXISTS mission_control_pairs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			node_from TEXT NOT NULL,
+			node_to TEXT NOT NULL,
+			success_time INTEGER NOT NULL DEFAULT 0,
+			success_amt_msat INTEGER NOT NULL DEFAULT 0,
+			fail_time INTEGER NOT NULL DEFAULT 0,
+			fail_amt_msat INTEGER NOT NULL DEFAULT 0
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_mission_control_pairs_timestamp ON mission_control_pairs(timestamp);`,
+
+		`CREATE TABLE IF NOT EXISTS mission_control_pairs_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			node_from TEXT NOT NULL,
+			node_to TEXT NOT NULL,
+			success_time INTEGER NOT NULL DEFAULT 0,
+			success_amt_msat INTEGER NOT NULL DEFAULT 0,
+			fail_time INTEGER NOT NULL DEFAULT 0,
+			fail_amt_msat INTEGER NOT NULL DEFAULT 0
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_mission_control_pairs_mock_timestamp ON mission_control_pairs_mock(timestamp);`,
+
+		// Per-peer policy shared by the channel acceptor and other tools
+		`CREATE TABLE IF NOT EXISTS peer_policies (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			pubkey TEXT NOT NULL UNIQUE,
+			blocklisted BOOLEAN NOT NULL DEFAULT 0,
+			preferred BOOLEAN NOT NULL DEFAULT 0,
+			notes TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS peer_policies_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			pubkey TEXT NOT NULL UNIQUE,
+			blocklisted BOOLEAN NOT NULL DEFAULT 0,
+			preferred BOOLEAN NOT NULL DEFAULT 0,
+			notes TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL
+		);`,
+
+		// Private notes about a peer: how to reach them and what was agreed
+		`CREATE TABLE IF NOT EXISTS peer_notes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			pubkey TEXT NOT NULL UNIQUE,
+			contact TEXT NOT NULL DEFAULT '',
+			agreement TEXT NOT NULL DEFAULT '',
+			agreed_on TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS peer_notes_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			pubkey TEXT NOT NULL UNIQUE,
+			contact TEXT NOT NULL DEFAULT '',
+			agreement TEXT NOT NULL DEFAULT '',
+			agreed_on TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL
+		);`,
+
+		// The peer of each channel, as channel snapshots only carry its alias
+		`CREATE TABLE IF NOT EXISTS channel_peers (
+			channel_id TEXT PRIMARY KEY,
+			pubkey TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS channel_peers_mock (
+			channel_id TEXT PRIMARY KEY,
+			pubkey TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		);`,
+
+		// The operator's own names for channels, shown instead of peer aliases
+		`CREATE TABLE IF NOT EXISTS channel_names (
+			channel_id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS channel_names_mock (
+			channel_id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		);`,
+
+		// Channels bought or sold as leases (Lightning Pool, liquidity ads, LSPs)
+		`CREATE TABLE IF NOT EXISTS channel_leases (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			channel_id TEXT NOT NULL UNIQUE,
+			side TEXT NOT NULL,
+			provider TEXT NOT NULL DEFAULT '',
+			premium INTEGER NOT NULL DEFAULT 0,
+			duration_blocks INTEGER NOT NULL DEFAULT 0,
+			expiry_height INTEGER NOT NULL DEFAULT 0,
+			started_at DATETIME,
+			notes TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS channel_leases_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			channel_id TEXT NOT NULL UNIQUE,
+			side TEXT NOT NULL,
+			provider TEXT NOT NULL DEFAULT '',
+			premium INTEGER NOT NULL DEFAULT 0,
+			duration_blocks INTEGER NOT NULL DEFAULT 0,
+			expiry_height INTEGER NOT NULL DEFAULT 0,
+			started_at DATETIME,
+			notes TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL
+		);`,
+
+		// Probe payments sent to measure payment reliability
+		`CREATE TABLE IF NOT EXISTS probe_results (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			destination TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			success BOOLEAN NOT NULL,
+			failure_reason TEXT NOT NULL DEFAULT '',
+			latency_ms INTEGER NOT NULL DEFAULT 0,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			hops INTEGER NOT NULL DEFAULT 0,
+			fee_msat INTEGER NOT NULL DEFAULT 0
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_probe_results_timestamp ON probe_results(timestamp);`,
+
+		`CREATE TABLE IF NOT EXISTS probe_results_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			destination TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			success BOOLEAN NOT NULL,
+			failure_reason TEXT NOT NULL DEFAULT '',
+			latency_ms INTEGER NOT NULL DEFAULT 0,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			hops INTEGER NOT NULL DEFAULT 0,
+			fee_msat INTEGER NOT NULL DEFAULT 0
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_probe_results_mock_timestamp ON probe_results_mock(timestamp);`,
+
+		// Deposits into and withdrawals from the portfolio, excluded from performance
+		`CREATE TABLE IF NOT EXISTS portfolio_transfers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			amount INTEGER NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_portfolio_transfers_timestamp ON portfolio_transfers(timestamp);`,
+
+		`CREATE TABLE IF NOT EXISTS portfolio_transfers_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			amount INTEGER NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_portfolio_transfers_mock_timestamp ON portfolio_transfers_mock(timestamp);`,
+
+		// Free-text notes on the portfolio timeline, drawn as chart markers
+		`CREATE TABLE IF NOT EXISTS annotations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			text TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_annotations_timestamp ON annotations(timestamp);`,
+
+		`CREATE TABLE IF NOT EXISTS annotations_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			text TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_annotations_mock_timestamp ON annotations_mock(timestamp);`,
+
+		// Withdrawals from Strike proposed and executed by the balance collector
+		`CREATE TABLE IF NOT EXISTS strike_withdrawals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			amount INTEGER NOT NULL,
+			destination_type TEXT NOT NULL,
+			destination TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			quote_id TEXT NOT NULL DEFAULT '',
+			payment_id TEXT NOT NULL DEFAULT '',
+			fee INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT ''
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_strike_withdrawals_status ON strike_withdrawals(status);`,
+
+		`CREATE TABLE IF NOT EXISTS strike_withdrawals_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			amount INTEGER NOT NULL,
+			destination_type TEXT NOT NULL,
+			destination TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			quote_id TEXT NOT NULL DEFAULT '',
+			payment_id TEXT NOT NULL DEFAULT '',
+			fee INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT ''
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_strike_withdrawals_mock_status ON strike_withdrawals_mock(status);`,
+
+		// Channel requests submitted by other nodes through the public API
+		`CREATE TABLE IF NOT EXISTS channel_requests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			pubkey TEXT NOT NULL,
+			host TEXT NOT NULL,
+			capacity INTEGER NOT NULL,
+			contact TEXT NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			fee_rate INTEGER NOT NULL DEFAULT 0,
+			funding_txid TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT ''
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_channel_requests_status ON channel_requests(status);`,
+
+		`CREATE TABLE IF NOT EXISTS channel_requests_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			pubkey TEXT NOT NULL,
+			host TEXT NOT NULL,
+			capacity INTEGER NOT NULL,
+			contact TEXT NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			fee_rate INTEGER NOT NULL DEFAULT 0,
+			funding_txid TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT ''
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_channel_requests_mock_status ON channel_requests_mock(status);`,
+
+		// Month-end statements; triggers reject any change once a month is closed
+		`CREATE TABLE IF NOT EXISTS statements (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			month TEXT NOT NULL UNIQUE,
+			period_start DATETIME NOT NULL,
+			period_end DATETIME NOT NULL,
+			lightning_local INTEGER NOT NULL,
+			lightning_remote INTEGER NOT NULL,
+			onchain_balance INTEGER NOT NULL,
+			tracked_addresses INTEGER NOT NULL,
+			cold_storage INTEGER NOT NULL,
+			liquid INTEGER NOT NULL DEFAULT 0,
+			ecash INTEGER NOT NULL DEFAULT 0,
+			total_portfolio INTEGER NOT NULL,
+			forwarding_fees INTEGER NOT NULL,
+			forward_count INTEGER NOT NULL,
+			forward_volume INTEGER NOT NULL,
+			deposits INTEGER NOT NULL,
+			withdrawals INTEGER NOT NULL,
+			closed_at DATETIME NOT NULL
+		);`,
+
+		`CREATE TRIGGER IF NOT EXISTS statements_no_update BEFORE UPDATE ON statements
+		BEGIN SELECT RAISE(ABORT, 'statements are immutable'); END;`,
+
+		`CREATE TRIGGER IF NOT EXISTS statements_no_delete BEFORE DELETE ON statements
+		BEGIN SELECT RAISE(ABORT, 'statements are immutable'); END;`,
+
+		`CREATE TABLE IF NOT EXISTS statements_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			month TEXT NOT NULL UNIQUE,
+			period_start DATETIME NOT NULL,
+			period_end DATETIME NOT NULL,
+			lightning_local INTEGER NOT NULL,
+			lightning_remote INTEGER NOT NULL,
+			onchain_balance INTEGER NOT NULL,
+			tracked_addresses INTEGER NOT NULL,
+			cold_storage INTEGER NOT NULL,
+			liquid INTEGER NOT NULL DEFAULT 0,
+			ecash INTEGER NOT NULL DEFAULT 0,
+			total_portfolio INTEGER NOT NULL,
+			forwarding_fees INTEGER NOT NULL,
+			forward_count INTEGER NOT NULL,
+			forward_volume INTEGER NOT NULL,
+			deposits INTEGER NOT NULL,
+			withdrawals INTEGER NOT NULL,
+			closed_at DATETIME NOT NULL
+		);`,
+
+		`CREATE TRIGGER IF NOT EXISTS statements_mock_no_update BEFORE UPDATE ON statements_mock
+		BEGIN SELECT RAISE(ABORT, 'statements are immutable'); END;`,
+
+		`CREATE TRIGGER IF NOT EXISTS statements_mock_no_delete BEFORE DELETE ON statements_mock
+		BEGIN SELECT RAISE(ABORT, 'statements are immutable'); END;`,
+
+		// BTC prices as published by the price sources, one row per currency
+		`CREATE TABLE IF NOT EXISTS btc_prices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			currency TEXT NOT NULL,
+			price REAL NOT NULL,
+			source TEXT NOT NULL DEFAULT '',
+			UNIQUE(currency, timestamp)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS btc_prices_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			currency TEXT NOT NULL,
+			price REAL NOT NULL,
+			source TEXT NOT NULL DEFAULT '',
+			UNIQUE(currency, timestamp)
+		);`,
+
+		// Multisig wallets imported from a coordinator or device export
+		`CREATE TABLE IF NOT EXISTS multisig_wallets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			descriptor TEXT NOT NULL,
+			format TEXT NOT NULL,
+			next_address_index INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS multisig_wallets_mock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			descriptor TEXT NOT NULL,
+			format TEXT NOT NULL,
+			next_address_index INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		);`,
 	}
 
 	for _, query := range queries {
-		if _, err := db.conn.Exec(query); err != nil {
+		if _, err := db.exec(query); err != nil {
 			return fmt.Errorf("failed to execute query: %w", err)
 		}
 	}
 
+	return db.migrateTables()
+}
+
+// migrateTables adds columns introduced after a table was first created.
+// CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so databases
+// created by older versions are upgraded here.
+func (db *Database) migrateTables() error {
+	migrations := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"onchain_addresses", "deleted_at", "DATETIME"},
+		{"onchain_addresses_mock", "deleted_at", "DATETIME"},
+		{"onchain_addresses", "birth_height", "INTEGER NOT NULL DEFAULT 0"},
+		{"onchain_addresses_mock", "birth_height", "INTEGER NOT NULL DEFAULT 0"},
+		{"onchain_addresses", "birth_date", "DATETIME"},
+		{"onchain_addresses_mock", "birth_date", "DATETIME"},
+		{"onchain_addresses", "class", "TEXT NOT NULL DEFAULT 'hot'"},
+		{"onchain_addresses_mock", "class", "TEXT NOT NULL DEFAULT 'hot'"},
+		{"statements", "liquid", "INTEGER NOT NULL DEFAULT 0"},
+		{"statements_mock", "liquid", "INTEGER NOT NULL DEFAULT 0"},
+		{"statements", "ecash", "INTEGER NOT NULL DEFAULT 0"},
+		{"statements_mock", "ecash", "INTEGER NOT NULL DEFAULT 0"},
+		{"address_balances", "block_height", "INTEGER NOT NULL DEFAULT 0"},
+		{"address_balances_mock", "block_height", "INTEGER NOT NULL DEFAULT 0"},
+		{"address_balances", "block_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"address_balances_mock", "block_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries", "deleted_at", "DATETIME"},
+		{"cold_storage_entries_mock", "deleted_at", "DATETIME"},
+		{"cold_storage_entries", "custody_type", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries_mock", "custody_type", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries", "device_model", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries_mock", "device_model", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries", "location_hint", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries_mock", "location_hint", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries", "derivation_ref", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries_mock", "derivation_ref", "TEXT NOT NULL DEFAULT ''"},
+		{"cold_storage_entries", "display_unit", "TEXT NOT NULL DEFAULT 'sats'"},
+		{"cold_storage_entries_mock", "display_unit", "TEXT NOT NULL DEFAULT 'sats'"},
+		{"forwarding_events", "fee_ppm", "INTEGER NOT NULL DEFAULT 0"},
+		{"forwarding_events_mock", "fee_ppm", "INTEGER NOT NULL DEFAULT 0"},
+		{"forwarding_events", "streamed", "INTEGER NOT NULL DEFAULT 0"},
+		{"forwarding_events_mock", "streamed", "INTEGER NOT NULL DEFAULT 0"},
+		{"channel_snapshots", "changed", "INTEGER"},
+		{"channel_snapshots_mock", "changed", "INTEGER"},
+		{"strike_balance_snapshots", "unit", "TEXT NOT NULL DEFAULT ''"},
+		{"strike_balance_snapshots_mock", "unit", "TEXT NOT NULL DEFAULT ''"},
+	}
+
+	for _, m := range migrations {
+		if err := db.addColumnIfMissing(m.table, m.column, m.definition); err != nil {
+			return fmt.Errorf("failed to migrate %s.%s: %w", m.table, m.column, err)
+		}
+	}
+
+	// Indexes found missing by EXPLAIN QUERY PLAN on the per-channel, per-address
+	// and per-account queries. Composite indexes replace the single-column
+	// indexes they start with.
+	indexes := []struct {
+		name    string
+		table   string
+		columns string
+	}{
+		{"idx_forwarding_events_channel_in", "forwarding_events", "channel_in_id"},
+		{"idx_forwarding_events_mock_channel_in", "forwarding_events_mock", "channel_in_id"},
+		{"idx_forwarding_events_channel_out", "forwarding_events", "channel_out_id, timestamp"},
+		{"idx_forwarding_events_mock_channel_out", "forwarding_events_mock", "channel_out_id, timestamp"},
+		{"idx_address_balances_address", "address_balances", "address_id, timestamp"},
+		{"idx_address_balances_mock_address", "address_balances_mock", "address_id, timestamp"},
+		{"idx_cold_storage_history_account", "cold_storage_history", "account_id, timestamp"},
+		{"idx_cold_storage_history_mock_account", "cold_storage_history_mock", "account_id, timestamp"},
+		{"idx_channel_snapshots_channel", "channel_snapshots", "channel_id, timestamp"},
+		{"idx_channel_snapshots_mock_channel", "channel_snapshots_mock", "channel_id, timestamp"},
+	}
+	for _, index := range indexes {
+		if _, err := db.exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s(%s)`, index.name, index.table, index.columns)); err != nil {
+			return fmt.Errorf("failed to create index %s: %w", index.name, err)
+		}
+	}
+	for _, index := range []string{
+		"idx_cold_storage_history_account_id", "idx_cold_storage_history_mock_account_id",
+		"idx_channel_snapshots_channel_id", "idx_channel_snapshots_mock_channel_id",
+	} {
+		if _, err := db.exec(fmt.Sprintf(`DROP INDEX IF EXISTS %s`, index)); err != nil {
+			return fmt.Errorf("failed to drop index %s: %w", index, err)
+		}
+	}
+
+	// Derive the fee rate of forwards stored before fee_ppm existed, rounded
+	// like ForwardFeePPM
+	for _, table := range []string{"forwarding_events", "forwarding_events_mock"} {
+		if _, err := db.exec(fmt.Sprintf(`
+			UPDATE %s SET fee_ppm = (fee * 1000000 + amount_out / 2) / amount_out
+			WHERE fee_ppm = 0 AND fee > 0 AND amount_out > 0
+		`, table)); err != nil {
+			return fmt.Errorf("failed to backfill %s.fee_ppm: %w", table, err)
+		}
+	}
+
+	// Strike balances stored before the unit column were sats for BTC and
+	// cents for everything else, as StrikeUnit says
+	for _, table := range []string{"strike_balance_snapshots", "strike_balance_snapshots_mock"} {
+		if _, err := db.exec(fmt.Sprintf(`
+			UPDATE %s SET unit = CASE WHEN currency = 'BTC' THEN 'sats' ELSE 'cents' END
+			WHERE unit = ''
+		`, table)); err != nil {
+			return fmt.Errorf("failed to backfill %s.unit: %w", table, err)
+		}
+	}
+
 	return nil
 }
 
+// addColumnIfMissing adds a column to a table unless it already exists.
+// SECURITY NOTE: all arguments must be hardcoded string literals, never user input.
+func (db *Database) addColumnIfMissing(table, column, definition string) error {
+	rows, err := db.query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
 // InsertBalanceSnapshot inserts a new balance snapshot.
 // If a snapshot with the same timestamp already exists, it will be replaced due to the use of INSERT OR REPLACE.
+// A snapshot with an impossible timestamp is quarantined and ErrQuarantined returned.
 func (db *Database) InsertBalanceSnapshot(snapshot *BalanceSnapshot) error {
 	tableName := db.getTableName("balance_snapshots")
+	if err := db.checkTimestamp(db.conn, "balance_snapshots", snapshot.Timestamp, EarliestBitcoinTime, snapshot); err != nil {
+		return err
+	}
 	query := fmt.Sprintf(`
 		INSERT OR REPLACE INTO %s
 		(timestamp, lightning_local, lightning_remote, onchain_confirmed, onchain_unconfirmed,
@@ -293,7 +1111,7 @@ func (db *Database) InsertBalanceSnapshot(snapshot *BalanceSnapshot) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, tableName)
 
-	_, err := db.conn.Exec(query,
+	_, err := db.exec(query,
 		snapshot.Timestamp,
 		snapshot.LightningLocal,
 		snapshot.LightningRemote,
@@ -308,18 +1126,73 @@ func (db *Database) InsertBalanceSnapshot(snapshot *BalanceSnapshot) error {
 	return err
 }
 
-// GetBalanceSnapshots retrieves balance snapshots within a time range
-func (db *Database) GetBalanceSnapshots(from, to time.Time) ([]BalanceSnapshot, error) {
+// InsertBalanceSnapshots inserts a batch of balance snapshots in a single transaction.
+// Snapshots with an existing timestamp are replaced, matching InsertBalanceSnapshot.
+// Either all snapshots are written or none are, except that snapshots with an
+// impossible timestamp are quarantined in the same transaction and skipped.
+func (db *Database) InsertBalanceSnapshots(snapshots []BalanceSnapshot) error {
 	tableName := db.getTableName("balance_snapshots")
 	query := fmt.Sprintf(`
-		SELECT id, timestamp, lightning_local, lightning_remote, onchain_confirmed,
-		       onchain_unconfirmed, tracked_addresses, cold_storage, total_portfolio, total_liquid
-		FROM %s
+		INSERT OR REPLACE INTO %s
+		(timestamp, lightning_local, lightning_remote, onchain_confirmed, onchain_unconfirmed,
+		 tracked_addresses, cold_storage, total_portfolio, total_liquid)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, snapshot := range snapshots {
+		err := db.checkTimestamp(tx, "balance_snapshots", snapshot.Timestamp, EarliestBitcoinTime, snapshot)
+		if errors.Is(err, ErrQuarantined) {
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		_, err = stmt.Exec(
+			snapshot.Timestamp,
+			snapshot.LightningLocal,
+			snapshot.LightningRemote,
+			snapshot.OnchainConfirmed,
+			snapshot.OnchainUnconfirmed,
+			snapshot.TrackedAddresses,
+			snapshot.ColdStorage,
+			snapshot.TotalPortfolio,
+			snapshot.TotalLiquid,
+		)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert snapshot at %s: %w", snapshot.Timestamp.Format(time.RFC3339), err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBalanceSnapshots retrieves balance snapshots within a time range
+func (db *Database) GetBalanceSnapshots(from, to time.Time) ([]BalanceSnapshot, error) {
+	tableName := db.getTableName("balance_snapshots")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, lightning_local, lightning_remote, onchain_confirmed,
+		       onchain_unconfirmed, tracked_addresses, cold_storage, total_portfolio, total_liquid
+		FROM %s
 		WHERE timestamp BETWEEN ? AND ?
 		ORDER BY timestamp ASC
 	`, tableName)
 
-	rows, err := db.conn.Query(query, from, to)
+	rows, err := db.query(query, from, to)
 	if err != nil {
 		return nil, err
 	}
@@ -354,7 +1227,7 @@ func (db *Database) GetLatestBalanceSnapshot() (*BalanceSnapshot, error) {
 	`, tableName)
 
 	var s BalanceSnapshot
-	err := db.conn.QueryRow(query).Scan(
+	err := db.queryRow(query).Scan(
 		&s.ID, &s.Timestamp, &s.LightningLocal, &s.LightningRemote,
 		&s.OnchainConfirmed, &s.OnchainUnconfirmed, &s.TrackedAddresses,
 		&s.ColdStorage, &s.TotalPortfolio, &s.TotalLiquid,
@@ -370,588 +1243,4986 @@ func (db *Database) GetLatestBalanceSnapshot() (*BalanceSnapshot, error) {
 	return &s, nil
 }
 
-// GetForwardingEventsFees retrieves forwarding fee data aggregated by day within a time range
-func (db *Database) GetForwardingEventsFees(from, to time.Time) ([]DailyFeeData, error) {
-	tableName := db.getTableName("forwarding_events")
+// GetBalanceSnapshotAt returns the latest balance snapshot at or before
+// date, or nil if there is none
+func (db *Database) GetBalanceSnapshotAt(date time.Time) (*BalanceSnapshot, error) {
+	tableName := db.getTableName("balance_snapshots")
 	query := fmt.Sprintf(`
-		SELECT
-			DATE(timestamp) as date,
-			SUM(fee) as total_fee,
-			COUNT(*) as forward_count
+		SELECT id, timestamp, lightning_local, lightning_remote, onchain_confirmed,
+		       onchain_unconfirmed, tracked_addresses, cold_storage, total_portfolio, total_liquid
 		FROM %s
-		WHERE timestamp BETWEEN ? AND ?
-		GROUP BY DATE(timestamp)
-		ORDER BY date ASC
+		WHERE timestamp <= ?
+		ORDER BY timestamp DESC
+		LIMIT 1
 	`, tableName)
 
-	rows, err := db.conn.Query(query, from, to)
+	var s BalanceSnapshot
+	err := db.queryRow(query, date).Scan(
+		&s.ID, &s.Timestamp, &s.LightningLocal, &s.LightningRemote,
+		&s.OnchainConfirmed, &s.OnchainUnconfirmed, &s.TrackedAddresses,
+		&s.ColdStorage, &s.TotalPortfolio, &s.TotalLiquid,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var feeData []DailyFeeData
-	for rows.Next() {
-		var d DailyFeeData
-		err := rows.Scan(&d.Date, &d.TotalFee, &d.ForwardCount)
-		if err != nil {
-			return nil, err
-		}
-		feeData = append(feeData, d)
+	return &s, nil
+}
+
+// ChannelKeyframeInterval is how often a channel's snapshot is stored in
+// full. In between, a snapshot stores only the fields that changed since the
+// channel's previous one, and reads rebuild it from the last keyframe.
+const ChannelKeyframeInterval = 24 * time.Hour
+
+// Bits of channel_snapshots.changed marking the fields a delta row stores.
+// Rows with changed NULL are keyframes, as are all rows stored before delta
+// encoding. Unchanged fields are stored as 0 or NULL, which SQLite keeps in
+// the row header alone.
+const (
+	changedCapacity = 1 << iota
+	changedLocalBalance
+	changedRemoteBalance
+	changedActive
+	changedPeerAlias
+	changedFeePPM
+	changedBaseFee
+)
+
+// storedChannelSnapshot is a channel_snapshots row as stored
+type storedChannelSnapshot struct {
+	ChannelSnapshot
+	changed sql.NullInt64
+}
+
+// channelSnapshotChanges returns the bits of the fields next changes from prev
+func channelSnapshotChanges(prev, next ChannelSnapshot) int64 {
+	var changed int64
+	if next.Capacity != prev.Capacity {
+		changed |= changedCapacity
+	}
+	if next.LocalBalance != prev.LocalBalance {
+		changed |= changedLocalBalance
+	}
+	if next.RemoteBalance != prev.RemoteBalance {
+		changed |= changedRemoteBalance
 	}
+	if next.Active != prev.Active {
+		changed |= changedActive
+	}
+	if next.PeerAlias != prev.PeerAlias {
+		changed |= changedPeerAlias
+	}
+	if next.FeePPM != prev.FeePPM {
+		changed |= changedFeePPM
+	}
+	if next.BaseFee != prev.BaseFee {
+		changed |= changedBaseFee
+	}
+	return changed
+}
 
-	return feeData, rows.Err()
+// apply returns prev updated with the fields the row stores
+func (row storedChannelSnapshot) apply(prev ChannelSnapshot) ChannelSnapshot {
+	if !row.changed.Valid {
+		return row.ChannelSnapshot
+	}
+	snapshot := prev
+	snapshot.ID = row.ID
+	snapshot.Timestamp = row.Timestamp
+	changed := row.changed.Int64
+	if changed&changedCapacity != 0 {
+		snapshot.Capacity = row.Capacity
+	}
+	if changed&changedLocalBalance != 0 {
+		snapshot.LocalBalance = row.LocalBalance
+	}
+	if changed&changedRemoteBalance != 0 {
+		snapshot.RemoteBalance = row.RemoteBalance
+	}
+	if changed&changedActive != 0 {
+		snapshot.Active = row.Active
+	}
+	if changed&changedPeerAlias != 0 {
+		snapshot.PeerAlias = row.PeerAlias
+	}
+	if changed&changedFeePPM != 0 {
+		snapshot.FeePPM = row.FeePPM
+	}
+	if changed&changedBaseFee != 0 {
+		snapshot.BaseFee = row.BaseFee
+	}
+	return snapshot
 }
 
-// InsertForwardingEvent inserts a new forwarding event
-func (db *Database) InsertForwardingEvent(event *ForwardingEvent) error {
-	tableName := db.getTableName("forwarding_events")
-	query := fmt.Sprintf(`
-		INSERT INTO %s
-		(timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, tableName)
+// channelSnapshotValues returns the column values storing snapshot: every
+// field for a keyframe (changed is nil), only the changed fields otherwise
+func channelSnapshotValues(snapshot ChannelSnapshot, changed *int64) []interface{} {
+	stored := func(bit int64, value interface{}, unchanged interface{}) interface{} {
+		if changed == nil || *changed&bit != 0 {
+			return value
+		}
+		return unchanged
+	}
+	var changedValue interface{}
+	if changed != nil {
+		changedValue = *changed
+	}
+	return []interface{}{
+		stored(changedCapacity, snapshot.Capacity, 0),
+		stored(changedLocalBalance, snapshot.LocalBalance, 0),
+		stored(changedRemoteBalance, snapshot.RemoteBalance, 0),
+		stored(changedActive, snapshot.Active, false),
+		stored(changedPeerAlias, snapshot.PeerAlias, nil),
+		stored(changedFeePPM, snapshot.FeePPM, nil),
+		stored(changedBaseFee, snapshot.BaseFee, nil),
+		changedValue,
+	}
+}
 
-	_, err := db.conn.Exec(query,
-		event.Timestamp,
-		event.ChannelInID,
-		event.ChannelOutID,
-		event.AmountIn,
-		event.AmountOut,
-		event.Fee,
-	)
+// InsertChannelSnapshots stores one snapshot per channel in a single transaction.
+// Snapshots with an impossible timestamp are quarantined and skipped.
+func (db *Database) InsertChannelSnapshots(snapshots []ChannelSnapshot) error {
+	tableName := db.getTableName("channel_snapshots")
 
-	return err
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		err := db.checkTimestamp(tx, "channel_snapshots", snapshot.Timestamp, EarliestLightningTime, snapshot)
+		if errors.Is(err, ErrQuarantined) {
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := insertChannelSnapshot(tx, tableName, snapshot); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert snapshot for channel %s: %w", snapshot.ChannelID, err)
+		}
+	}
+
+	return tx.Commit()
 }
 
-// InsertForwardingEventIgnoreDuplicate inserts a new forwarding event, ignoring duplicates
-func (db *Database) InsertForwardingEventIgnoreDuplicate(event *ForwardingEvent) error {
-	tableName := db.getTableName("forwarding_events")
+// insertChannelSnapshot stores snapshot as the changes since the channel's
+// previous snapshot, or in full when there is none or the last keyframe is
+// ChannelKeyframeInterval old. A snapshot older than the channel's latest is
+// stored in full, and the snapshot after it rewritten in full so the deltas
+// that follow still apply.
+func insertChannelSnapshot(tx *sql.Tx, tableName string, snapshot ChannelSnapshot) error {
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT id, timestamp, channel_id, capacity, local_balance, remote_balance, active,
+		       peer_alias, fee_ppm, base_fee, changed
+		FROM %s
+		WHERE channel_id = ? AND timestamp >= COALESCE(
+			(SELECT MAX(timestamp) FROM %s WHERE channel_id = ? AND changed IS NULL AND timestamp <= ?), ?)
+		ORDER BY timestamp ASC, id ASC
+	`, tableName, tableName), snapshot.ChannelID, snapshot.ChannelID, snapshot.Timestamp.UTC(), snapshot.Timestamp.UTC())
+	if err != nil {
+		return err
+	}
+	chain, err := scanChannelSnapshotRows(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
 
-	// Check if event already exists (same timestamp, channel_in_id, channel_out_id)
-	checkQuery := fmt.Sprintf(`
-		SELECT id FROM %s
-		WHERE timestamp = ? AND channel_in_id = ? AND channel_out_id = ?
-		LIMIT 1
-	`, tableName)
+	// Split the rebuilt chain at the new snapshot
+	var prev *ChannelSnapshot
+	var keyframeTime time.Time
+	var next *ChannelSnapshot
+	var nextIsKeyframe bool
+	var state ChannelSnapshot
+	for _, row := range chain {
+		state = row.apply(state)
+		rebuilt := state
+		if state.Timestamp.After(snapshot.Timestamp) {
+			next, nextIsKeyframe = &rebuilt, !row.changed.Valid
+			break
+		}
+		if !row.changed.Valid {
+			keyframeTime = state.Timestamp
+		}
+		prev = &rebuilt
+	}
 
-	var existingID int64
-	err := db.conn.QueryRow(checkQuery, event.Timestamp, event.ChannelInID, event.ChannelOutID).Scan(&existingID)
-	if err == nil {
-		// Event already exists, ignore
-		return nil
+	var changed *int64
+	if prev != nil && next == nil && snapshot.Timestamp.Sub(keyframeTime) < ChannelKeyframeInterval {
+		delta := channelSnapshotChanges(*prev, snapshot)
+		changed = &delta
 	}
 
-	// Insert the new event
-	insertQuery := fmt.Sprintf(`
+	values := append([]interface{}{snapshot.Timestamp.UTC(), snapshot.ChannelID}, channelSnapshotValues(snapshot, changed)...)
+	if _, err := tx.Exec(fmt.Sprintf(`
 		INSERT INTO %s
-		(timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, tableName)
-
-	_, err = db.conn.Exec(insertQuery,
-		event.Timestamp,
-		event.ChannelInID,
-		event.ChannelOutID,
-		event.AmountIn,
-		event.AmountOut,
-		event.Fee,
-	)
+		(timestamp, channel_id, capacity, local_balance, remote_balance, active, peer_alias, fee_ppm, base_fee, changed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, tableName), values...); err != nil {
+		return err
+	}
 
+	if next == nil || nextIsKeyframe {
+		return nil
+	}
+	values = append(channelSnapshotValues(*next, nil), next.ID)
+	_, err = tx.Exec(fmt.Sprintf(`
+		UPDATE %s
+		SET capacity = ?, local_balance = ?, remote_balance = ?, active = ?, peer_alias = ?,
+		    fee_ppm = ?, base_fee = ?, changed = ?
+		WHERE id = ?
+	`, tableName), values...)
 	return err
 }
 
-// GetOnchainAddresses retrieves all tracked onchain addresses
-func (db *Database) GetOnchainAddresses() ([]OnchainAddress, error) {
-	tableName := db.getTableName("onchain_addresses")
+// GetChannelSnapshots returns the snapshots of one channel between from and
+// to, oldest first
+func (db *Database) GetChannelSnapshots(channelID string, from, to time.Time) ([]ChannelSnapshot, error) {
+	tableName := db.getTableName("channel_snapshots")
+	// Start at the keyframe the first snapshot in range is rebuilt from
 	query := fmt.Sprintf(`
-		SELECT id, address, label, active
+		SELECT id, timestamp, channel_id, capacity, local_balance, remote_balance, active,
+		       peer_alias, fee_ppm, base_fee, changed
 		FROM %s
-		ORDER BY id ASC
-	`, tableName)
+		WHERE channel_id = ? AND timestamp <= ? AND timestamp >= COALESCE(
+			(SELECT MAX(timestamp) FROM %s WHERE channel_id = ? AND changed IS NULL AND timestamp <= ?), ?)
+		ORDER BY timestamp ASC, id ASC
+	`, tableName, tableName)
 
-	rows, err := db.conn.Query(query)
+	rows, err := db.query(query, channelID, to.UTC(), channelID, from.UTC(), from.UTC())
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var addresses []OnchainAddress
+	stored, err := scanChannelSnapshotRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []ChannelSnapshot
+	for _, snapshot := range rebuildChannelSnapshots(stored) {
+		if !snapshot.Timestamp.Before(from) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+func scanChannelSnapshotRows(rows *sql.Rows) ([]storedChannelSnapshot, error) {
+	var snapshots []storedChannelSnapshot
 	for rows.Next() {
-		var addr OnchainAddress
-		err := rows.Scan(&addr.ID, &addr.Address, &addr.Label, &addr.Active)
+		snapshot, err := scanStoredChannelSnapshot(rows)
 		if err != nil {
 			return nil, err
 		}
-		addresses = append(addresses, addr)
+		snapshots = append(snapshots, snapshot)
 	}
 
-	return addresses, rows.Err()
+	return snapshots, rows.Err()
 }
 
-// GetOnchainAddressByID retrieves a specific onchain address by ID
-func (db *Database) GetOnchainAddressByID(id int64) (*OnchainAddress, error) {
-	tableName := db.getTableName("onchain_addresses")
-	query := fmt.Sprintf(`
-		SELECT id, address, label, active
-		FROM %s
-		WHERE id = ?
-	`, tableName)
-
-	var addr OnchainAddress
-	err := db.conn.QueryRow(query, id).Scan(&addr.ID, &addr.Address, &addr.Label, &addr.Active)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
+// scanStoredChannelSnapshot reads the current row of a channel_snapshots query
+func scanStoredChannelSnapshot(rows *sql.Rows) (storedChannelSnapshot, error) {
+	var snapshot storedChannelSnapshot
+	var peerAlias sql.NullString
+	var feePPM, baseFee sql.NullInt64
+	if err := rows.Scan(&snapshot.ID, &snapshot.Timestamp, &snapshot.ChannelID, &snapshot.Capacity,
+		&snapshot.LocalBalance, &snapshot.RemoteBalance, &snapshot.Active,
+		&peerAlias, &feePPM, &baseFee, &snapshot.changed); err != nil {
+		return storedChannelSnapshot{}, err
 	}
+	snapshot.PeerAlias = peerAlias.String
+	snapshot.FeePPM = feePPM.Int64
+	snapshot.BaseFee = baseFee.Int64
+	return snapshot, nil
+}
 
-	return &addr, nil
+// rebuildChannelSnapshots applies each channel's rows in order, which must be
+// oldest first per channel starting at a keyframe
+func rebuildChannelSnapshots(rows []storedChannelSnapshot) []ChannelSnapshot {
+	latest := make(map[string]ChannelSnapshot)
+	snapshots := make([]ChannelSnapshot, 0, len(rows))
+	for _, row := range rows {
+		snapshot := row.apply(latest[row.ChannelID])
+		latest[row.ChannelID] = snapshot
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
 }
 
-// InsertOnchainAddress adds a new tracked onchain address
-func (db *Database) InsertOnchainAddress(address, label string) (*OnchainAddress, error) {
-	tableName := db.getTableName("onchain_addresses")
+// GetLatestChannelSnapshots returns the snapshots from the most recent
+// collection, one per channel that was open at the time
+func (db *Database) GetLatestChannelSnapshots() ([]ChannelSnapshot, error) {
+	tableName := db.getTableName("channel_snapshots")
+	// Every row of those channels since the keyframe each is rebuilt from
 	query := fmt.Sprintf(`
-		INSERT INTO %s (address, label, active)
-		VALUES (?, ?, 1)
-	`, tableName)
-
-	result, err := db.conn.Exec(query, address, label)
+		WITH latest AS (SELECT MAX(timestamp) AS timestamp FROM %s)
+		SELECT s.id, s.timestamp, s.channel_id, s.capacity, s.local_balance, s.remote_balance, s.active,
+		       s.peer_alias, s.fee_ppm, s.base_fee, s.changed
+		FROM %s s, latest
+		WHERE s.channel_id IN (SELECT channel_id FROM %s WHERE timestamp = latest.timestamp)
+		  AND s.timestamp <= latest.timestamp
+		  AND s.timestamp >= (
+			SELECT MAX(k.timestamp) FROM %s k
+			WHERE k.channel_id = s.channel_id AND k.changed IS NULL AND k.timestamp <= latest.timestamp)
+		ORDER BY s.channel_id ASC, s.timestamp ASC, s.id ASC
+	`, tableName, tableName, tableName, tableName)
+
+	rows, err := db.query(query)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	id, err := result.LastInsertId()
+	stored, err := scanChannelSnapshotRows(rows)
 	if err != nil {
 		return nil, err
 	}
 
-	return &OnchainAddress{
-		ID:      id,
-		Address: address,
-		Label:   label,
-		Active:  true,
-	}, nil
+	rebuilt := rebuildChannelSnapshots(stored)
+	var latest time.Time
+	for _, snapshot := range rebuilt {
+		if snapshot.Timestamp.After(latest) {
+			latest = snapshot.Timestamp
+		}
+	}
+	var snapshots []ChannelSnapshot
+	for _, snapshot := range rebuilt {
+		if snapshot.Timestamp.Equal(latest) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
 }
 
-// DeleteOnchainAddress removes a tracked onchain address
-func (db *Database) DeleteOnchainAddress(id int64) error {
-	tableName := db.getTableName("onchain_addresses")
-	query := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, tableName)
+// dailySummaryCollector is the collector_runs name of the job that
+// materializes daily summaries. Its resume point is the last day stored.
+const dailySummaryCollector = "daily-summary"
 
-	result, err := db.conn.Exec(query, id)
-	if err != nil {
-		return err
-	}
+// dayLayout formats the UTC dates daily summaries are keyed by
+const dayLayout = "2006-01-02"
 
-	rowsAffected, err := result.RowsAffected()
+// utcDay returns the start of t's UTC day
+func utcDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// GetForwardingEventsFees retrieves forwarding fee data aggregated by day within a time range.
+// Whole days already materialized by MaterializeDailySummaries are read from
+// forwarding_daily; only the partial days at either end and days not yet
+// materialized scan forwarding_events.
+func (db *Database) GetForwardingEventsFees(from, to time.Time) ([]DailyFeeData, error) {
+	through, err := db.dailySummariesThrough()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	firstDay := utcDay(from)
+	if firstDay.Before(from) {
+		firstDay = firstDay.AddDate(0, 0, 1)
+	}
+	lastDay := utcDay(to).AddDate(0, 0, -1)
+	if through.Before(lastDay) {
+		lastDay = through
+	}
+	if through.IsZero() || lastDay.Before(firstDay) {
+		return db.aggregateForwardingDays("timestamp BETWEEN ? AND ?", from, to)
 	}
 
-	return nil
-}
-
-// GetAddressBalanceHistory retrieves balance history for a specific address
-func (db *Database) GetAddressBalanceHistory(address string, from, to time.Time) ([]AddressBalance, error) {
-	tableName := db.getTableName("address_balances")
-	addrTableName := db.getTableName("onchain_addresses")
-
-	query := fmt.Sprintf(`
-		SELECT ab.id, ab.address_id, ab.timestamp, ab.balance, ab.tx_count
-		FROM %s ab
-		JOIN %s oa ON ab.address_id = oa.id
-		WHERE oa.address = ? AND ab.timestamp BETWEEN ? AND ?
-		ORDER BY ab.timestamp ASC
-	`, tableName, addrTableName)
+	// Day boundaries are checked with DATE(), which converts the zone each
+	// forward was stored in to UTC. The timestamp bounds a day beyond them
+	// only let the timestamp index narrow the scan.
+	feeData, err := db.aggregateForwardingDays("timestamp >= ? AND timestamp < ? AND DATE(timestamp) < ?",
+		from, firstDay.AddDate(0, 0, 1), firstDay.Format(dayLayout))
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := db.conn.Query(query, address, from, to)
+	tableName := db.getTableName("forwarding_daily")
+	rows, err := db.query(fmt.Sprintf(`
+		SELECT date, total_fee, forward_count
+		FROM %s
+		WHERE date BETWEEN ? AND ?
+		ORDER BY date ASC
+	`, tableName), firstDay.Format(dayLayout), lastDay.Format(dayLayout))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-
-	var balances []AddressBalance
 	for rows.Next() {
-		var balance AddressBalance
-		err := rows.Scan(
-			&balance.ID, &balance.AddressID, &balance.Timestamp,
-			&balance.Balance, &balance.TxCount,
-		)
-		if err != nil {
+		var d DailyFeeData
+		if err := rows.Scan(&d.Date, &d.TotalFee, &d.ForwardCount); err != nil {
 			return nil, err
 		}
-		balances = append(balances, balance)
+		feeData = append(feeData, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return balances, rows.Err()
-}
-
-// InsertAddressBalance adds a new balance record for an address
-func (db *Database) InsertAddressBalance(balance *AddressBalance) error {
-	tableName := db.getTableName("address_balances")
-	query := fmt.Sprintf(`
-		INSERT OR REPLACE INTO %s
-		(address_id, timestamp, balance, tx_count)
-		VALUES (?, ?, ?, ?)
-	`, tableName)
-
-	_, err := db.conn.Exec(query,
-		balance.AddressID,
-		balance.Timestamp,
-		balance.Balance,
-		balance.TxCount,
-	)
-
-	return err
+	tail, err := db.aggregateForwardingDays("timestamp > ? AND timestamp <= ? AND DATE(timestamp) > ?",
+		lastDay, to, lastDay.Format(dayLayout))
+	if err != nil {
+		return nil, err
+	}
+	return append(feeData, tail...), nil
 }
 
-// GetColdStorageEntries retrieves all cold storage entries
-func (db *Database) GetColdStorageEntries() ([]ColdStorageEntry, error) {
-	tableName := db.getTableName("cold_storage_entries")
+// aggregateForwardingDays totals the forwarding events matching where by
+// UTC day. SECURITY NOTE: where must be a hardcoded string literal.
+func (db *Database) aggregateForwardingDays(where string, args ...interface{}) ([]DailyFeeData, error) {
+	tableName := db.getTableName("forwarding_events")
 	query := fmt.Sprintf(`
-		SELECT id, name, balance, last_updated, notes
+		SELECT
+			DATE(timestamp) as date,
+			SUM(fee) as total_fee,
+			COUNT(*) as forward_count
 		FROM %s
-		ORDER BY id ASC
-	`, tableName)
+		WHERE %s
+		GROUP BY DATE(timestamp)
+		ORDER BY date ASC
+	`, tableName, where)
 
-	rows, err := db.conn.Query(query)
+	rows, err := db.query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var entries []ColdStorageEntry
+	var feeData []DailyFeeData
 	for rows.Next() {
-		var entry ColdStorageEntry
-		err := rows.Scan(&entry.ID, &entry.Name, &entry.Balance, &entry.LastUpdated, &entry.Notes)
+		var d DailyFeeData
+		err := rows.Scan(&d.Date, &d.TotalFee, &d.ForwardCount)
 		if err != nil {
 			return nil, err
 		}
-		entries = append(entries, entry)
+		feeData = append(feeData, d)
 	}
 
-	return entries, rows.Err()
+	return feeData, rows.Err()
 }
 
-// GetColdStorageEntryByID retrieves a specific cold storage entry by ID
-func (db *Database) GetColdStorageEntryByID(id int64) (*ColdStorageEntry, error) {
-	tableName := db.getTableName("cold_storage_entries")
-	query := fmt.Sprintf(`
-		SELECT id, name, balance, last_updated, notes
-		FROM %s
-		WHERE id = ?
-	`, tableName)
+// MaterializeDailySummaries stores the forwarding totals of every UTC day
+// after the last one stored through the day before now, so chart queries
+// over long ranges read a row per day instead of every forward. The first
+// run covers all history; later runs only add the days since. Run nightly.
+func (db *Database) MaterializeDailySummaries(now time.Time) error {
+	through := utcDay(now).AddDate(0, 0, -1)
+	last, err := db.dailySummariesThrough()
+	if err != nil {
+		return err
+	}
+	if !last.IsZero() && !last.Before(through) {
+		return nil
+	}
 
-	var entry ColdStorageEntry
-	err := db.conn.QueryRow(query, id).Scan(&entry.ID, &entry.Name, &entry.Balance, &entry.LastUpdated, &entry.Notes)
-	if err == sql.ErrNoRows {
-		return nil, nil
+	return db.RecordCollectorRun(dailySummaryCollector, func(run *CollectorRun) error {
+		from := time.Time{}
+		if !last.IsZero() {
+			from = last.AddDate(0, 0, 1)
+		}
+		days, err := db.materializeForwardingDays(from, through)
+		if err != nil {
+			return fmt.Errorf("failed to materialize forwarding days: %w", err)
+		}
+		run.ItemsInserted = days
+		run.ResumePoint = through.Format(dayLayout)
+		return nil
+	})
+}
+
+// dailySummariesThrough returns the last UTC day materialized, or the zero
+// time if none is
+func (db *Database) dailySummariesThrough() (time.Time, error) {
+	resumePoint, err := db.GetLastResumePoint(dailySummaryCollector)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last daily summary: %w", err)
+	}
+	if resumePoint == "" {
+		return time.Time{}, nil
 	}
+	day, err := time.Parse(dayLayout, resumePoint)
 	if err != nil {
-		return nil, err
+		return time.Time{}, fmt.Errorf("invalid daily summary resume point %q: %w", resumePoint, err)
 	}
-
-	return &entry, nil
+	return day, nil
 }
 
-// InsertColdStorageEntry adds a new cold storage entry
-func (db *Database) InsertColdStorageEntry(name string, balance int64, notes string) (*ColdStorageEntry, error) {
-	tableName := db.getTableName("cold_storage_entries")
-	query := fmt.Sprintf(`
-		INSERT INTO %s (name, balance, last_updated, notes)
-		VALUES (?, ?, ?, ?)
-	`, tableName)
+// materializeForwardingDays replaces the summaries of the UTC days from
+// through through and returns how many days had forwards
+func (db *Database) materializeForwardingDays(from, through time.Time) (int64, error) {
+	tableName := db.getTableName("forwarding_daily")
+	eventsTable := db.getTableName("forwarding_events")
 
-	now := time.Now()
-	result, err := db.conn.Exec(query, name, balance, now, notes)
+	// The forwards of archived days are gone, their summaries are all that is left
+	archivedBefore, err := db.ArchivedBefore()
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if from.Before(archivedBefore) {
+		from = archivedBefore
+	}
+	if from.After(through) {
+		return 0, nil
 	}
 
-	id, err := result.LastInsertId()
+	tx, err := db.conn.Begin()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	return &ColdStorageEntry{
-		ID:          id,
-		Name:        name,
-		Balance:     balance,
-		LastUpdated: now,
-		Notes:       notes,
-	}, nil
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE date BETWEEN ? AND ?`, tableName),
+		from.Format(dayLayout), through.Format(dayLayout)); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(fmt.Sprintf(`
+		INSERT INTO %s (date, total_fee, forward_count)
+		SELECT DATE(timestamp), SUM(fee), COUNT(*)
+		FROM %s
+		WHERE timestamp >= ? AND timestamp < ? AND DATE(timestamp) BETWEEN ? AND ?
+		GROUP BY DATE(timestamp)
+	`, tableName, eventsTable), from.AddDate(0, 0, -1), through.AddDate(0, 0, 2),
+		from.Format(dayLayout), through.Format(dayLayout))
+	if err != nil {
+		return 0, err
+	}
+	days, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return days, tx.Commit()
 }
 
-// UpdateColdStorageEntry updates an existing cold storage entry and records balance history
-func (db *Database) UpdateColdStorageEntry(id int64, name string, balance int64, notes string) (*ColdStorageEntry, error) {
-	// Get current entry to track previous balance
-	current, err := db.GetColdStorageEntryByID(id)
-	if err != nil {
-		return nil, err
+// refreshDailySummary re-materializes the day of a forward inserted after
+// that day was summarized, such as one found by a catch-up
+func (db *Database) refreshDailySummary(timestamp time.Time) error {
+	day := utcDay(timestamp)
+	if !day.Before(utcDay(time.Now())) {
+		return nil
 	}
-	if current == nil {
-		return nil, sql.ErrNoRows
+	through, err := db.dailySummariesThrough()
+	if err != nil || through.Before(day) {
+		return err
 	}
+	_, err = db.materializeForwardingDays(day, day)
+	return err
+}
 
-	tableName := db.getTableName("cold_storage_entries")
+// EachForwardingEvent calls fn for every forwarding event within a time range,
+// oldest first, reading one row at a time so that exporting years of forwards
+// does not hold them all in memory. An error from fn stops the iteration and
+// is returned.
+func (db *Database) EachForwardingEvent(from, to time.Time, fn func(*ForwardingEvent) error) error {
+	tableName := db.getTableName("forwarding_events")
 	query := fmt.Sprintf(`
-		UPDATE %s
-		SET name = ?, balance = ?, last_updated = ?, notes = ?
-		WHERE id = ?
+		SELECT id, timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, fee_ppm
+		FROM %s
+		WHERE timestamp BETWEEN ? AND ?
+		ORDER BY timestamp ASC, id ASC
 	`, tableName)
 
-	now := time.Now()
-	result, err := db.conn.Exec(query, name, balance, now, notes, id)
-	if err != nil {
-		return nil, err
-	}
-
-	rowsAffected, err := result.RowsAffected()
+	rows, err := db.query(query, from, to)
 	if err != nil {
-		return nil, err
-	}
-
-	if rowsAffected == 0 {
-		return nil, sql.ErrNoRows
+		return err
 	}
+	defer rows.Close()
 
-	// Record balance history if balance changed
-	if current.Balance != balance {
-		historyEntry := &ColdStorageBalanceHistory{
-			AccountID:       id,
-			Timestamp:       now,
-			Balance:         balance,
-			PreviousBalance: current.Balance,
-			IsVerified:      true, // Assume verified when manually updated
-			Notes:           notes,
+	var event ForwardingEvent
+	for rows.Next() {
+		if err := rows.Scan(&event.ID, &event.Timestamp, &event.ChannelInID, &event.ChannelOutID,
+			&event.AmountIn, &event.AmountOut, &event.Fee, &event.FeePPM); err != nil {
+			return err
 		}
-
-		if err := db.InsertColdStorageHistory(historyEntry); err != nil {
-			// Log error but don't fail the update
-			log.Printf("Warning: failed to record balance history: %v\n", err)
+		if err := fn(&event); err != nil {
+			return err
 		}
 	}
 
-	return &ColdStorageEntry{
-		ID:          id,
-		Name:        name,
-		Balance:     balance,
-		LastUpdated: now,
-		Notes:       notes,
-	}, nil
+	return rows.Err()
 }
 
-// DeleteColdStorageEntry removes a cold storage entry
-func (db *Database) DeleteColdStorageEntry(id int64) error {
-	tableName := db.getTableName("cold_storage_entries")
-	query := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, tableName)
-
-	result, err := db.conn.Exec(query, id)
+// ArchiveCollector is the collector_runs name of the job that moves old
+// forwarding events and channel snapshots out of the database, see
+// internal/archive. Its resume point is the first day whose forwards are
+// still stored.
+const ArchiveCollector = "archive"
+
+// ArchivedBefore returns the UTC day before which forwarding events were
+// archived, or the zero time if none were
+func (db *Database) ArchivedBefore() (time.Time, error) {
+	resumePoint, err := db.GetLastResumePoint(ArchiveCollector)
+	if err != nil || resumePoint == "" {
+		return time.Time{}, err
+	}
+	day, err := time.Parse(dayLayout, resumePoint)
 	if err != nil {
-		return err
+		return time.Time{}, fmt.Errorf("invalid archive resume point %q: %w", resumePoint, err)
 	}
+	return day, nil
+}
 
-	rowsAffected, err := result.RowsAffected()
+// ArchiveForwardingEvents calls write for every forwarding event before the
+// UTC day of before, oldest first, and then deletes them. Only days already
+// summarized in forwarding_daily are archived, so fee charts keep them; the
+// returned day is the one archiving actually stopped at. An error from write
+// stops the archive before anything is deleted.
+func (db *Database) ArchiveForwardingEvents(before time.Time, write func(*ForwardingEvent) error) (time.Time, int64, error) {
+	before = utcDay(before)
+	through, err := db.dailySummariesThrough()
 	if err != nil {
-		return err
+		return time.Time{}, 0, err
 	}
-
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	if through.IsZero() {
+		return time.Time{}, 0, nil
+	}
+	if next := through.AddDate(0, 0, 1); next.Before(before) {
+		before = next
 	}
 
-	return nil
-}
-
-// InsertColdStorageHistory records a balance change in cold storage history
-func (db *Database) InsertColdStorageHistory(history *ColdStorageBalanceHistory) error {
-	tableName := db.getTableName("cold_storage_history")
+	tableName := db.getTableName("forwarding_events")
 	query := fmt.Sprintf(`
-		INSERT INTO %s (account_id, timestamp, balance, previous_balance, is_verified, notes)
-		VALUES (?, ?, ?, ?, ?, ?)
+		SELECT id, timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, fee_ppm
+		FROM %s
+		WHERE timestamp < ?
+		ORDER BY timestamp ASC, id ASC
 	`, tableName)
+	rows, err := db.query(query, before)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	defer rows.Close()
 
-	_, err := db.conn.Exec(query,
-		history.AccountID,
-		history.Timestamp,
-		history.Balance,
-		history.PreviousBalance,
-		history.IsVerified,
-		history.Notes,
-	)
+	var lastID int64
+	var event ForwardingEvent
+	for rows.Next() {
+		if err := rows.Scan(&event.ID, &event.Timestamp, &event.ChannelInID, &event.ChannelOutID,
+			&event.AmountIn, &event.AmountOut, &event.Fee, &event.FeePPM); err != nil {
+			return time.Time{}, 0, err
+		}
+		if err := write(&event); err != nil {
+			return time.Time{}, 0, err
+		}
+		lastID = max(lastID, event.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return time.Time{}, 0, err
+	}
+	rows.Close()
 
-	return err
+	// Forwards stored meanwhile have later IDs and wait for the next run
+	result, err := db.exec(fmt.Sprintf(`DELETE FROM %s WHERE timestamp < ? AND id <= ?`, tableName), before, lastID)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return before, deleted, err
 }
 
-// GetColdStorageHistory retrieves balance history for a specific account
-func (db *Database) GetColdStorageHistory(accountID int64, from, to time.Time) ([]ColdStorageBalanceHistory, error) {
-	tableName := db.getTableName("cold_storage_history")
+// ArchiveChannelSnapshots calls write for every channel snapshot before
+// before, rebuilt in full and oldest first, and then deletes the rows. Each
+// channel keeps its rows from its last keyframe before before onward, so the
+// snapshots left can still be rebuilt. An error from write stops the archive
+// before anything is deleted.
+func (db *Database) ArchiveChannelSnapshots(before time.Time, write func(*ChannelSnapshot) error) (int64, error) {
+	tableName := db.getTableName("channel_snapshots")
+	archived := fmt.Sprintf(`
+		timestamp < (SELECT MAX(k.timestamp) FROM %[1]s k
+			WHERE k.channel_id = %[1]s.channel_id AND k.changed IS NULL AND k.timestamp <= ?)
+	`, tableName)
 	query := fmt.Sprintf(`
-		SELECT id, account_id, timestamp, balance, previous_balance, is_verified, notes
+		SELECT id, timestamp, channel_id, capacity, local_balance, remote_balance, active,
+		       peer_alias, fee_ppm, base_fee, changed
 		FROM %s
-		WHERE account_id = ? AND timestamp BETWEEN ? AND ?
-		ORDER BY timestamp ASC
-	`, tableName)
-
-	rows, err := db.conn.Query(query, accountID, from, to)
+		WHERE %s
+		ORDER BY timestamp ASC, id ASC
+	`, tableName, archived)
+	rows, err := db.query(query, before)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer rows.Close()
 
-	var history []ColdStorageBalanceHistory
+	// Every channel's first row archived is a keyframe: either its first
+	// snapshot or the keyframe the previous archive stopped at
+	latest := make(map[string]ChannelSnapshot)
+	var lastID int64
 	for rows.Next() {
-		var entry ColdStorageBalanceHistory
-		err := rows.Scan(
-			&entry.ID, &entry.AccountID, &entry.Timestamp,
-			&entry.Balance, &entry.PreviousBalance, &entry.IsVerified, &entry.Notes,
-		)
+		row, err := scanStoredChannelSnapshot(rows)
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
-		history = append(history, entry)
+		snapshot := row.apply(latest[row.ChannelID])
+		latest[row.ChannelID] = snapshot
+		if err := write(&snapshot); err != nil {
+			return 0, err
+		}
+		lastID = max(lastID, snapshot.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
 	}
+	rows.Close()
 
-	return history, rows.Err()
+	result, err := db.exec(fmt.Sprintf(`DELETE FROM %s WHERE %s AND id <= ?`, tableName, archived), before, lastID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-// GetColdStorageEntriesWithWarnings retrieves all cold storage entries with warning status
-func (db *Database) GetColdStorageEntriesWithWarnings() ([]map[string]interface{}, error) {
-	tableName := db.getTableName("cold_storage_entries")
+// Vacuum rebuilds the database file, giving the space of deleted rows back
+// to the filesystem. SQLite otherwise keeps it for new rows.
+func (db *Database) Vacuum() error {
+	_, err := db.exec(`VACUUM`)
+	return err
+}
+
+// LastForwardingEventID returns the ID of the newest stored forwarding
+// event, or 0 when there are none
+func (db *Database) LastForwardingEventID() (int64, error) {
+	tableName := db.getTableName("forwarding_events")
+	var id int64
+	err := db.queryRow(fmt.Sprintf(`SELECT COALESCE(MAX(id), 0) FROM %s`, tableName)).Scan(&id)
+	return id, err
+}
+
+// GetForwardingEventsAfter returns up to limit forwarding events stored
+// after the event with ID afterID, in the order they were stored. Events
+// arrive in batches from the collector, so following the ID rather than the
+// timestamp also finds late events with older timestamps.
+func (db *Database) GetForwardingEventsAfter(afterID int64, limit int) ([]ForwardingEvent, error) {
+	tableName := db.getTableName("forwarding_events")
 	query := fmt.Sprintf(`
-		SELECT id, name, balance, last_updated, notes,
-		       (julianday('now') - julianday(last_updated)) as days_since_update
+		SELECT id, timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, fee_ppm
 		FROM %s
+		WHERE id > ?
 		ORDER BY id ASC
+		LIMIT ?
 	`, tableName)
 
-	rows, err := db.conn.Query(query)
+	rows, err := db.query(query, afterID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var entries []map[string]interface{}
+	events := []ForwardingEvent{}
 	for rows.Next() {
-		var id int64
-		var name, notes string
-		var balance int64
-		var lastUpdated time.Time
-		var daysSinceUpdate float64
-
-		err := rows.Scan(&id, &name, &balance, &lastUpdated, &notes, &daysSinceUpdate)
-		if err != nil {
+		var event ForwardingEvent
+		if err := rows.Scan(&event.ID, &event.Timestamp, &event.ChannelInID, &event.ChannelOutID,
+			&event.AmountIn, &event.AmountOut, &event.Fee, &event.FeePPM); err != nil {
 			return nil, err
 		}
-
-		entry := map[string]interface{}{
-			"id":                int64(id),
-			"name":              name,
-			"balance":           balance,
-			"last_updated":      lastUpdated,
-			"notes":             notes,
-			"days_since_update": int(daysSinceUpdate),
-			"needs_warning":     daysSinceUpdate > 90,
-		}
-
-		entries = append(entries, entry)
+		events = append(events, event)
 	}
 
-	return entries, rows.Err()
+	return events, rows.Err()
 }
 
-// InsertStrikeBalanceSnapshot stores a Strike balance snapshot
-func (db *Database) InsertStrikeBalanceSnapshot(snapshot *StrikeBalanceSnapshot) error {
-	tableName := db.getTableName("strike_balance_snapshots")
+// GetForwardingStats summarizes forwarding events within a time range.
+// Empty ranges return zero counts with no largest forward or busiest channel.
+func (db *Database) GetForwardingStats(from, to time.Time) (*ForwardingStats, error) {
+	tableName := db.getTableName("forwarding_events")
+	stats := &ForwardingStats{}
 
-	query := fmt.Sprintf(`
-		INSERT INTO %s (timestamp, currency, available, total, pending, reserved)
-		VALUES (?, ?, ?, ?, ?, ?)
+	totalsQuery := fmt.Sprintf(`
+		SELECT COUNT(*), COALESCE(SUM(amount_out), 0), COALESCE(SUM(fee), 0)
+		FROM %s
+		WHERE timestamp BETWEEN ? AND ?
 	`, tableName)
+	if err := db.queryRow(totalsQuery, from, to).Scan(&stats.ForwardCount, &stats.TotalVolume, &stats.TotalFees); err != nil {
+		return nil, err
+	}
+	if stats.ForwardCount == 0 {
+		return stats, nil
+	}
 
-	_, err := db.conn.Exec(query,
-		snapshot.Timestamp,
-		snapshot.Currency,
-		snapshot.Available,
-		snapshot.Total,
-		snapshot.Pending,
-		snapshot.Reserved,
-	)
+	stats.MeanForwardSize = stats.TotalVolume / stats.ForwardCount
+	if stats.TotalVolume > 0 {
+		ppm := float64(stats.TotalFees) * 1_000_000 / float64(stats.TotalVolume)
+		stats.EffectiveFeePPM = math.Round(ppm*100) / 100
+	}
 
-	return err
+	// Median: the middle value, or the mean of the two middle values
+	medianQuery := fmt.Sprintf(`
+		SELECT AVG(amount_out) FROM (
+			SELECT amount_out FROM %s
+			WHERE timestamp BETWEEN ? AND ?
+			ORDER BY amount_out
+			LIMIT 2 - (? %% 2) OFFSET (? - 1) / 2
+		)
+	`, tableName)
+	var median float64
+	if err := db.queryRow(medianQuery, from, to, stats.ForwardCount, stats.ForwardCount).Scan(&median); err != nil {
+		return nil, err
+	}
+	stats.MedianForwardSize = int64(math.Round(median))
+
+	largestQuery := fmt.Sprintf(`
+		SELECT id, timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, fee_ppm
+		FROM %s
+		WHERE timestamp BETWEEN ? AND ?
+		ORDER BY amount_out DESC, timestamp ASC
+		LIMIT 1
+	`, tableName)
+	var largest ForwardingEvent
+	if err := db.queryRow(largestQuery, from, to).Scan(
+		&largest.ID, &largest.Timestamp, &largest.ChannelInID, &largest.ChannelOutID,
+		&largest.AmountIn, &largest.AmountOut, &largest.Fee, &largest.FeePPM,
+	); err != nil {
+		return nil, err
+	}
+	stats.LargestForward = &largest
+
+	busiestQuery := fmt.Sprintf(`
+		SELECT channel_id, COUNT(*) AS forwards, SUM(amount) AS volume FROM (
+			SELECT channel_in_id AS channel_id, amount_in AS amount FROM %[1]s
+			WHERE timestamp BETWEEN ? AND ?
+			UNION ALL
+			SELECT channel_out_id, amount_out FROM %[1]s
+			WHERE timestamp BETWEEN ? AND ?
+		)
+		GROUP BY channel_id
+		ORDER BY forwards DESC, volume DESC, channel_id ASC
+		LIMIT 1
+	`, tableName)
+	var busiest ChannelForwardStats
+	if err := db.queryRow(busiestQuery, from, to, from, to).Scan(&busiest.ChannelID, &busiest.ForwardCount, &busiest.Volume); err != nil {
+		return nil, err
+	}
+	stats.BusiestChannel = &busiest
+
+	return stats, nil
 }
 
-// GetLatestStrikeBalance gets the most recent Strike balance for a currency
-func (db *Database) GetLatestStrikeBalance(currency string) (*StrikeBalanceSnapshot, error) {
-	tableName := db.getTableName("strike_balance_snapshots")
+// GetTopFeeChannels returns the channels that earned the most fees within a
+// time range, highest first. Fees are credited to the outgoing channel.
+func (db *Database) GetTopFeeChannels(from, to time.Time, limit int) ([]ChannelFeeStats, error) {
+	tableName := db.getTableName("forwarding_events")
+	query := fmt.Sprintf(`
+		SELECT channel_out_id, COUNT(*), COALESCE(SUM(amount_out), 0), COALESCE(SUM(fee), 0)
+		FROM %s
+		WHERE timestamp BETWEEN ? AND ?
+		GROUP BY channel_out_id
+		ORDER BY SUM(fee) DESC, COUNT(*) DESC, channel_out_id ASC
+		LIMIT ?
+	`, tableName)
+
+	rows, err := db.query(query, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []ChannelFeeStats
+	for rows.Next() {
+		var c ChannelFeeStats
+		if err := rows.Scan(&c.ChannelID, &c.ForwardCount, &c.Volume, &c.Fees); err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+
+	return channels, rows.Err()
+}
+
+// GetFeesByChannel returns the forwards in and out of every channel that
+// forwarded within a time range, highest earning first
+func (db *Database) GetFeesByChannel(from, to time.Time) ([]ChannelFees, error) {
+	tableName := db.getTableName("forwarding_events")
+	snapshotsTable := db.getTableName("channel_snapshots")
+	namesTable := db.getTableName("channel_names")
+	// Delta snapshots store NULL for an unchanged alias, keyframes always store it
+	query := fmt.Sprintf(`
+		SELECT f.channel_id,
+		       COALESCE((SELECT s.peer_alias FROM %[2]s s
+		                 WHERE s.channel_id = f.channel_id AND s.peer_alias IS NOT NULL
+		                 ORDER BY s.timestamp DESC, s.id DESC LIMIT 1), ''),
+		       COALESCE((SELECT n.name FROM %[3]s n WHERE n.channel_id = f.channel_id), ''),
+		       SUM(f.out_count), SUM(f.out_volume), SUM(f.out_fees),
+		       SUM(f.in_count), SUM(f.in_volume), SUM(f.in_fees)
+		FROM (
+			SELECT channel_out_id AS channel_id, 1 AS out_count, amount_out AS out_volume, fee AS out_fees,
+			       0 AS in_count, 0 AS in_volume, 0 AS in_fees
+			FROM %[1]s WHERE timestamp BETWEEN ? AND ?
+			UNION ALL
+			SELECT channel_in_id, 0, 0, 0, 1, amount_in, fee
+			FROM %[1]s WHERE timestamp BETWEEN ? AND ?
+		) f
+		GROUP BY f.channel_id
+		ORDER BY SUM(f.out_fees) DESC, SUM(f.in_fees) DESC, f.channel_id ASC
+	`, tableName, snapshotsTable, namesTable)
+
+	rows, err := db.query(query, from, to, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := []ChannelFees{}
+	for rows.Next() {
+		var c ChannelFees
+		if err := rows.Scan(&c.ChannelID, &c.PeerAlias, &c.Name, &c.OutForwardCount, &c.OutVolume, &c.OutFees,
+			&c.InForwardCount, &c.InVolume, &c.InFees); err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+
+	return channels, rows.Err()
+}
 
+// GetChannelFeeStats returns the forwards out of channelID within a time
+// range. Fees are credited to the outgoing channel.
+func (db *Database) GetChannelFeeStats(channelID string, from, to time.Time) (*ChannelFeeStats, error) {
+	tableName := db.getTableName("forwarding_events")
 	query := fmt.Sprintf(`
-		SELECT id, timestamp, currency, available, total, pending, reserved
+		SELECT COUNT(*), COALESCE(SUM(amount_out), 0), COALESCE(SUM(fee), 0)
 		FROM %s
-		WHERE currency = ?
+		WHERE channel_out_id = ? AND timestamp BETWEEN ? AND ?
+	`, tableName)
+
+	stats := &ChannelFeeStats{ChannelID: channelID}
+	err := db.queryRow(query, channelID, from, to).Scan(&stats.ForwardCount, &stats.Volume, &stats.Fees)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetChannelLastForward returns the time of the latest forward in or out of
+// channelID at or before to, or nil if there is none
+func (db *Database) GetChannelLastForward(channelID string, to time.Time) (*time.Time, error) {
+	tableName := db.getTableName("forwarding_events")
+	query := fmt.Sprintf(`
+		SELECT timestamp FROM %s
+		WHERE (channel_in_id = ? OR channel_out_id = ?) AND timestamp <= ?
 		ORDER BY timestamp DESC
 		LIMIT 1
 	`, tableName)
 
-	var snapshot StrikeBalanceSnapshot
-	err := db.conn.QueryRow(query, currency).Scan(
-		&snapshot.ID,
-		&snapshot.Timestamp,
-		&snapshot.Currency,
-		&snapshot.Available,
-		&snapshot.Total,
-		&snapshot.Pending,
-		&snapshot.Reserved,
+	var last time.Time
+	err := db.queryRow(query, channelID, channelID, to).Scan(&last)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &last, nil
+}
+
+// GetFeePPMHistograms returns the fee rate histogram of all forwards within a
+// time range and one per outgoing channel, highest earning first. Fees are
+// credited to the outgoing channel.
+func (db *Database) GetFeePPMHistograms(from, to time.Time) (*FeePPMHistogram, []FeePPMHistogram, error) {
+	tableName := db.getTableName("forwarding_events")
+	query := fmt.Sprintf(`
+		SELECT channel_out_id, fee_ppm, COUNT(*), SUM(amount_out), SUM(fee)
+		FROM %s
+		WHERE timestamp BETWEEN ? AND ?
+		GROUP BY channel_out_id, fee_ppm
+		ORDER BY fee_ppm ASC
+	`, tableName)
+
+	rows, err := db.query(query, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	// Rows arrive in fee rate order, so each histogram's rates are sorted
+	overall := newFeePPMTally()
+	byChannel := make(map[string]*feePPMTally)
+	for rows.Next() {
+		var channelID string
+		var rate feePPMRate
+		if err := rows.Scan(&channelID, &rate.ppm, &rate.forwards, &rate.volume, &rate.fees); err != nil {
+			return nil, nil, err
+		}
+		tally, ok := byChannel[channelID]
+		if !ok {
+			tally = newFeePPMTally()
+			byChannel[channelID] = tally
+		}
+		overall.add(rate)
+		tally.add(rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	channels := make([]FeePPMHistogram, 0, len(byChannel))
+	for channelID, tally := range byChannel {
+		histogram := tally.histogram()
+		histogram.ChannelID = channelID
+		channels = append(channels, histogram)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].Fees != channels[j].Fees {
+			return channels[i].Fees > channels[j].Fees
+		}
+		return channels[i].ChannelID < channels[j].ChannelID
+	})
+
+	histogram := overall.histogram()
+	return &histogram, channels, nil
+}
+
+// feePPMRate totals the forwards that earned one fee rate
+type feePPMRate struct {
+	ppm, forwards, volume, fees int64
+}
+
+// feePPMTally accumulates fee rates, added in ascending order, into a histogram
+type feePPMTally struct {
+	rates   []feePPMRate
+	buckets []FeePPMBucket
+}
+
+func newFeePPMTally() *feePPMTally {
+	buckets := make([]FeePPMBucket, len(FeePPMBucketBounds))
+	for i, bound := range FeePPMBucketBounds {
+		buckets[i].MinPPM = bound
+		if i+1 < len(FeePPMBucketBounds) {
+			upper := FeePPMBucketBounds[i+1]
+			buckets[i].MaxPPM = &upper
+		}
+	}
+	return &feePPMTally{buckets: buckets}
+}
+
+func (t *feePPMTally) add(rate feePPMRate) {
+	t.rates = append(t.rates, rate)
+	i := sort.Search(len(t.buckets), func(i int) bool { return t.buckets[i].MinPPM > rate.ppm }) - 1
+	if i < 0 {
+		i = 0
+	}
+	t.buckets[i].ForwardCount += rate.forwards
+	t.buckets[i].Volume += rate.volume
+	t.buckets[i].Fees += rate.fees
+}
+
+func (t *feePPMTally) histogram() FeePPMHistogram {
+	histogram := FeePPMHistogram{Buckets: t.buckets}
+	for _, bucket := range t.buckets {
+		histogram.ForwardCount += bucket.ForwardCount
+		histogram.Volume += bucket.Volume
+		histogram.Fees += bucket.Fees
+	}
+
+	// The median is the rate of the middle forward, the lower one of an even count
+	middle := (histogram.ForwardCount + 1) / 2
+	var seen int64
+	for _, rate := range t.rates {
+		seen += rate.forwards
+		if seen >= middle {
+			histogram.MedianPPM = rate.ppm
+			break
+		}
+	}
+	return histogram
+}
+
+// InsertForwardingEvent inserts a new forwarding event. An event with an
+// impossible timestamp is quarantined and ErrQuarantined returned.
+func (db *Database) InsertForwardingEvent(event *ForwardingEvent) error {
+	tableName := db.getTableName("forwarding_events")
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+		(timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, fee_ppm)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	if err := db.checkTimestamp(db.conn, "forwarding_events", event.Timestamp, EarliestLightningTime, event); err != nil {
+		return err
+	}
+
+	event.FeePPM = ForwardFeePPM(event.Fee, event.AmountOut)
+	_, err := db.exec(query,
+		event.Timestamp,
+		event.ChannelInID,
+		event.ChannelOutID,
+		event.AmountIn,
+		event.AmountOut,
+		event.Fee,
+		event.FeePPM,
 	)
+	if err != nil {
+		return err
+	}
+
+	return db.refreshDailySummary(event.Timestamp)
+}
+
+// InsertForwardingEventIgnoreDuplicate inserts a new forwarding event, ignoring duplicates
+func (db *Database) InsertForwardingEventIgnoreDuplicate(event *ForwardingEvent) error {
+	_, err := db.InsertForwardingEventIfNew(event)
+	return err
+}
+
+// InsertForwardingEventIfNew inserts a forwarding event unless one with the same
+// timestamp and channels already exists. It reports whether a row was inserted.
+// Like InsertForwardingEvent it quarantines events with impossible timestamps.
+func (db *Database) InsertForwardingEventIfNew(event *ForwardingEvent) (bool, error) {
+	tableName := db.getTableName("forwarding_events")
+
+	if err := db.checkTimestamp(db.conn, "forwarding_events", event.Timestamp, EarliestLightningTime, event); err != nil {
+		return false, err
+	}
+
+	// Check if event already exists (same timestamp, channel_in_id, channel_out_id)
+	checkQuery := fmt.Sprintf(`
+		SELECT id FROM %s
+		WHERE timestamp = ? AND channel_in_id = ? AND channel_out_id = ?
+		LIMIT 1
+	`, tableName)
+
+	var existingID int64
+	err := db.queryRow(checkQuery, event.Timestamp, event.ChannelInID, event.ChannelOutID).Scan(&existingID)
+	if err == nil {
+		// Event already exists, ignore
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	// The forwarding history replaces a forward the HTLC event stream
+	// stored first, as LND's own record of it
+	streamed, err := db.nearbyForward(event, true)
+	if err != nil {
+		return false, err
+	}
+	if streamed != nil {
+		updateQuery := fmt.Sprintf(`
+			UPDATE %s
+			SET timestamp = ?, amount_in = ?, amount_out = ?, fee = ?, fee_ppm = ?, streamed = 0
+			WHERE id = ?
+		`, tableName)
+		event.FeePPM = ForwardFeePPM(event.Fee, event.AmountOut)
+		if _, err := db.exec(updateQuery, event.Timestamp, event.AmountIn, event.AmountOut, event.Fee,
+			event.FeePPM, streamed.ID); err != nil {
+			return false, err
+		}
+		if err := db.refreshDailySummary(streamed.Timestamp); err != nil {
+			return false, err
+		}
+		return false, db.refreshDailySummary(event.Timestamp)
+	}
+
+	if err := db.insertForward(event, false); err != nil {
+		return false, err
+	}
+	return true, db.refreshDailySummary(event.Timestamp)
+}
 
+// StreamedForwardWindow is how far apart the HTLC event stream's and the
+// forwarding history's times of one forward may be. The stream sees the
+// settle, the history records when the forward was resolved, and the two
+// usually differ by well under a second.
+const StreamedForwardWindow = time.Minute
+
+// InsertStreamedForwardingEvent stores a forward seen settling on LND's HTLC
+// event stream, unless the forwarding history already has it. The row is
+// replaced by the history's once a collection finds it. It reports whether
+// a row was inserted.
+func (db *Database) InsertStreamedForwardingEvent(event *ForwardingEvent) (bool, error) {
+	if err := db.checkTimestamp(db.conn, "forwarding_events", event.Timestamp, EarliestLightningTime, event); err != nil {
+		return false, err
+	}
+
+	existing, err := db.nearbyForward(event, false)
+	if err != nil || existing != nil {
+		return false, err
+	}
+
+	if err := db.insertForward(event, true); err != nil {
+		return false, err
+	}
+	return true, db.refreshDailySummary(event.Timestamp)
+}
+
+// nearbyForward returns a forward through the same channels, earning the
+// same fee, within StreamedForwardWindow of event, or nil if there is none.
+// With onlyStreamed it only considers forwards not yet found in the
+// forwarding history. Amounts are left out as the history's are rounded
+// differently.
+func (db *Database) nearbyForward(event *ForwardingEvent, onlyStreamed bool) (*ForwardingEvent, error) {
+	tableName := db.getTableName("forwarding_events")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp FROM %s
+		WHERE channel_in_id = ? AND channel_out_id = ? AND fee = ?
+		  AND timestamp BETWEEN ? AND ?
+		  AND (streamed = 1 OR ? = 0)
+		ORDER BY id ASC
+		LIMIT 1
+	`, tableName)
+
+	var found ForwardingEvent
+	err := db.queryRow(query, event.ChannelInID, event.ChannelOutID, event.Fee,
+		event.Timestamp.Add(-StreamedForwardWindow), event.Timestamp.Add(StreamedForwardWindow),
+		onlyStreamed).Scan(&found.ID, &found.Timestamp)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	return &found, nil
+}
 
-	return &snapshot, nil
+// insertForward stores event, marked as streamed if it came from the HTLC
+// event stream rather than the forwarding history
+func (db *Database) insertForward(event *ForwardingEvent, streamed bool) error {
+	tableName := db.getTableName("forwarding_events")
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+		(timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, fee_ppm, streamed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	event.FeePPM = ForwardFeePPM(event.Fee, event.AmountOut)
+	result, err := db.exec(query,
+		event.Timestamp,
+		event.ChannelInID,
+		event.ChannelOutID,
+		event.AmountIn,
+		event.AmountOut,
+		event.Fee,
+		event.FeePPM,
+		streamed,
+	)
+	if err != nil {
+		return err
+	}
+	event.ID, err = result.LastInsertId()
+	return err
 }
 
-// GetStrikeBalanceHistory retrieves historical Strike balance snapshots
-func (db *Database) GetStrikeBalanceHistory(currency string, from, to time.Time) ([]*StrikeBalanceSnapshot, error) {
-	tableName := db.getTableName("strike_balance_snapshots")
+// InsertHTLCFailure stores a forward that failed on the HTLC event stream
+func (db *Database) InsertHTLCFailure(failure *HTLCFailure) error {
+	tableName := db.getTableName("htlc_failures")
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+		(timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, outcome, failure)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	if err := db.checkTimestamp(db.conn, "htlc_failures", failure.Timestamp, EarliestLightningTime, failure); err != nil {
+		return err
+	}
+
+	result, err := db.exec(query,
+		failure.Timestamp,
+		failure.ChannelInID,
+		failure.ChannelOutID,
+		failure.AmountIn,
+		failure.AmountOut,
+		failure.Fee,
+		failure.Outcome,
+		failure.Failure,
+	)
+	if err != nil {
+		return err
+	}
+	failure.ID, err = result.LastInsertId()
+	return err
+}
 
+// GetHTLCFailures returns the failed forwards within a time range, newest first
+func (db *Database) GetHTLCFailures(from, to time.Time) ([]HTLCFailure, error) {
+	tableName := db.getTableName("htlc_failures")
 	query := fmt.Sprintf(`
-		SELECT id, timestamp, currency, available, total, pending, reserved
+		SELECT id, timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, outcome, failure
 		FROM %s
-		WHERE currency = ? AND timestamp >= ? AND timestamp <= ?
-		ORDER BY timestamp ASC
+		WHERE timestamp BETWEEN ? AND ?
+		ORDER BY timestamp DESC, id DESC
 	`, tableName)
 
-	rows, err := db.conn.Query(query, currency, from, to)
+	rows, err := db.query(query, from, to)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var snapshots []*StrikeBalanceSnapshot
+	failures := []HTLCFailure{}
 	for rows.Next() {
-		var snapshot StrikeBalanceSnapshot
-		err := rows.Scan(
-			&snapshot.ID,
-			&snapshot.Timestamp,
-			&snapshot.Currency,
-			&snapshot.Available,
-			&snapshot.Total,
-			&snapshot.Pending,
-			&snapshot.Reserved,
-		)
-		if err != nil {
+		var f HTLCFailure
+		if err := rows.Scan(&f.ID, &f.Timestamp, &f.ChannelInID, &f.ChannelOutID, &f.AmountIn,
+			&f.AmountOut, &f.Fee, &f.Outcome, &f.Failure); err != nil {
 			return nil, err
 		}
-		snapshots = append(snapshots, &snapshot)
+		failures = append(failures, f)
 	}
 
-	return snapshots, rows.Err()
+	return failures, rows.Err()
+}
+
+// GetOnchainAddresses retrieves all tracked onchain addresses
+func (db *Database) GetOnchainAddresses() ([]OnchainAddress, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		SELECT id, address, label, active, birth_height, birth_date, class
+		FROM %s
+		WHERE deleted_at IS NULL
+		ORDER BY id ASC
+	`, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []OnchainAddress
+	for rows.Next() {
+		var addr OnchainAddress
+		err := rows.Scan(&addr.ID, &addr.Address, &addr.Label, &addr.Active, &addr.BirthHeight, &addr.BirthDate, &addr.Class)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, addr)
+	}
+
+	return addresses, rows.Err()
+}
+
+// GetOnchainAddressByID retrieves a specific onchain address by ID
+func (db *Database) GetOnchainAddressByID(id int64) (*OnchainAddress, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		SELECT id, address, label, active, birth_height, birth_date, class
+		FROM %s
+		WHERE id = ? AND deleted_at IS NULL
+	`, tableName)
+
+	var addr OnchainAddress
+	err := db.queryRow(query, id).Scan(&addr.ID, &addr.Address, &addr.Label, &addr.Active, &addr.BirthHeight, &addr.BirthDate, &addr.Class)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &addr, nil
+}
+
+// InsertOnchainAddress adds a new tracked onchain address
+func (db *Database) InsertOnchainAddress(address, label string) (*OnchainAddress, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (address, label, active)
+		VALUES (?, ?, 1)
+	`, tableName)
+
+	result, err := db.exec(query, address, label)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &OnchainAddress{
+		ID:      id,
+		Address: address,
+		Label:   label,
+		Active:  true,
+		Class:   AddressClassHot,
+	}, nil
+}
+
+// UpdateOnchainAddress updates the label and active flag of a tracked onchain address.
+// Inactive addresses keep their balance history but are skipped by collectors.
+func (db *Database) UpdateOnchainAddress(id int64, label string, active bool) (*OnchainAddress, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET label = ?, active = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, tableName)
+
+	result, err := db.exec(query, label, active, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return db.GetOnchainAddressByID(id)
+}
+
+// SetOnchainAddressBirthday sets the block height and date before which a
+// tracked address or xpub cannot have transactions. History scans start
+// there; a zero height and nil date mean the history is scanned from genesis.
+func (db *Database) SetOnchainAddressBirthday(id, height int64, date *time.Time) (*OnchainAddress, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET birth_height = ?, birth_date = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, tableName)
+
+	result, err := db.exec(query, height, date, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return db.GetOnchainAddressByID(id)
+}
+
+// SetOnchainAddressClass sets the class of a tracked address or xpub, which
+// decides how many confirmations its funds need to count as confirmed
+func (db *Database) SetOnchainAddressClass(id int64, class string) (*OnchainAddress, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET class = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, tableName)
+
+	result, err := db.exec(query, class, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return db.GetOnchainAddressByID(id)
+}
+
+// DeleteOnchainAddress soft-deletes a tracked onchain address.
+// The row and its balance history are kept so the address can be restored.
+func (db *Database) DeleteOnchainAddress(id int64) error {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, tableName)
+
+	result, err := db.exec(query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RestoreOnchainAddress undoes a soft delete of a tracked onchain address
+func (db *Database) RestoreOnchainAddress(id int64) (*OnchainAddress, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, tableName)
+
+	result, err := db.exec(query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return db.GetOnchainAddressByID(id)
+}
+
+// GetDeletedOnchainAddresses retrieves soft-deleted onchain addresses, most recently deleted first
+func (db *Database) GetDeletedOnchainAddresses() ([]OnchainAddress, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		SELECT id, address, label, active, birth_height, birth_date, class, deleted_at
+		FROM %s
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []OnchainAddress
+	for rows.Next() {
+		var addr OnchainAddress
+		var deletedAt time.Time
+		err := rows.Scan(&addr.ID, &addr.Address, &addr.Label, &addr.Active, &addr.BirthHeight, &addr.BirthDate, &addr.Class, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		addr.DeletedAt = &deletedAt
+		addresses = append(addresses, addr)
+	}
+
+	return addresses, rows.Err()
+}
+
+// GetDeletedOnchainAddressID returns the ID of the soft-deleted row tracking
+// address, or 0 when it is not deleted. The row still holds the address, so
+// tracking it again means restoring that row.
+func (db *Database) GetDeletedOnchainAddressID(address string) (int64, error) {
+	tableName := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE address = ? AND deleted_at IS NOT NULL`, tableName)
+
+	var id int64
+	err := db.queryRow(query, address).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// GetAddressBalanceHistory retrieves balance history for a specific address
+func (db *Database) GetAddressBalanceHistory(address string, from, to time.Time) ([]AddressBalance, error) {
+	tableName := db.getTableName("address_balances")
+	addrTableName := db.getTableName("onchain_addresses")
+
+	query := fmt.Sprintf(`
+		SELECT ab.id, ab.address_id, ab.timestamp, ab.balance, ab.tx_count, ab.block_height, ab.block_hash
+		FROM %s ab
+		JOIN %s oa ON ab.address_id = oa.id
+		WHERE oa.address = ? AND ab.timestamp BETWEEN ? AND ?
+		ORDER BY ab.timestamp ASC
+	`, tableName, addrTableName)
+
+	rows, err := db.query(query, address, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []AddressBalance
+	for rows.Next() {
+		var balance AddressBalance
+		err := rows.Scan(
+			&balance.ID, &balance.AddressID, &balance.Timestamp,
+			&balance.Balance, &balance.TxCount, &balance.BlockHeight, &balance.BlockHash,
+		)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, rows.Err()
+}
+
+// InsertAddressBalance adds a new balance record for an address
+func (db *Database) InsertAddressBalance(balance *AddressBalance) error {
+	tableName := db.getTableName("address_balances")
+	query := fmt.Sprintf(`
+		INSERT OR REPLACE INTO %s
+		(address_id, timestamp, balance, tx_count, block_height, block_hash)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	_, err := db.exec(query,
+		balance.AddressID,
+		balance.Timestamp,
+		balance.Balance,
+		balance.TxCount,
+		balance.BlockHeight,
+		balance.BlockHash,
+	)
+
+	return err
+}
+
+// MergeAddressBalances writes one balance per day for an address. Each
+// balance replaces any rows already stored for the same address on the same
+// UTC day, so repeated backfills do not accumulate duplicates. All rows are
+// written in one transaction.
+func (db *Database) MergeAddressBalances(addressID int64, balances []AddressBalance) error {
+	tableName := db.getTableName("address_balances")
+	deleteQuery := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE address_id = ? AND timestamp >= ? AND timestamp < ?
+	`, tableName)
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO %s (address_id, timestamp, balance, tx_count, block_height, block_hash)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, balance := range balances {
+		day := balance.Timestamp.UTC().Truncate(24 * time.Hour)
+		if _, err := tx.Exec(deleteQuery, addressID, day, day.AddDate(0, 0, 1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to clear balances for %s: %w", day.Format("2006-01-02"), err)
+		}
+		if _, err := tx.Exec(insertQuery, addressID, balance.Timestamp.UTC(), balance.Balance, balance.TxCount,
+			balance.BlockHeight, balance.BlockHash); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert balance for %s: %w", day.Format("2006-01-02"), err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAddressBalanceBlocks returns the distinct blocks at or above minHeight
+// that stored address balances were derived from, lowest first. Rows stored
+// without a block hash are skipped.
+func (db *Database) GetAddressBalanceBlocks(minHeight int64) ([]BlockRef, error) {
+	tableName := db.getTableName("address_balances")
+	query := fmt.Sprintf(`
+		SELECT DISTINCT block_height, block_hash
+		FROM %s
+		WHERE block_height >= ? AND block_hash != ''
+		ORDER BY block_height ASC
+	`, tableName)
+
+	rows, err := db.query(query, minHeight)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []BlockRef
+	for rows.Next() {
+		var block BlockRef
+		if err := rows.Scan(&block.Height, &block.Hash); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
+
+// InvalidateAddressBalances deletes every stored address balance derived
+// from a block at or above height, e.g. after those blocks were re-orged
+// out. It returns the earliest deleted day of each affected address so its
+// history can be rebuilt from there.
+func (db *Database) InvalidateAddressBalances(height int64) (map[int64]time.Time, error) {
+	tableName := db.getTableName("address_balances")
+	selectQuery := fmt.Sprintf(`
+		SELECT address_id, timestamp
+		FROM %s
+		WHERE block_height >= ?
+		ORDER BY timestamp ASC
+	`, tableName)
+	deleteQuery := fmt.Sprintf(`DELETE FROM %s WHERE block_height >= ?`, tableName)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(selectQuery, height)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	affected := make(map[int64]time.Time)
+	for rows.Next() {
+		var addressID int64
+		var timestamp time.Time
+		if err := rows.Scan(&addressID, &timestamp); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		if _, ok := affected[addressID]; !ok {
+			affected[addressID] = timestamp
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if _, err := tx.Exec(deleteQuery, height); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return affected, nil
+}
+
+// GetColdStorageEntries retrieves all cold storage entries
+func (db *Database) GetColdStorageEntries() ([]ColdStorageEntry, error) {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`
+		SELECT id, name, balance, last_updated, notes, custody_type, device_model, location_hint, derivation_ref, display_unit
+		FROM %s
+		WHERE deleted_at IS NULL
+		ORDER BY id ASC
+	`, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ColdStorageEntry
+	for rows.Next() {
+		var entry ColdStorageEntry
+		err := rows.Scan(
+			&entry.ID, &entry.Name, &entry.Balance, &entry.LastUpdated, &entry.Notes,
+			&entry.CustodyType, &entry.DeviceModel, &entry.LocationHint, &entry.DerivationRef, &entry.DisplayUnit,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetColdStorageEntryByID retrieves a specific cold storage entry by ID
+func (db *Database) GetColdStorageEntryByID(id int64) (*ColdStorageEntry, error) {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`
+		SELECT id, name, balance, last_updated, notes, custody_type, device_model, location_hint, derivation_ref, display_unit
+		FROM %s
+		WHERE id = ? AND deleted_at IS NULL
+	`, tableName)
+
+	var entry ColdStorageEntry
+	err := db.queryRow(query, id).Scan(
+		&entry.ID, &entry.Name, &entry.Balance, &entry.LastUpdated, &entry.Notes,
+		&entry.CustodyType, &entry.DeviceModel, &entry.LocationHint, &entry.DerivationRef, &entry.DisplayUnit,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// InsertColdStorageEntry adds a new cold storage entry
+func (db *Database) InsertColdStorageEntry(name string, balance int64, notes string) (*ColdStorageEntry, error) {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (name, balance, last_updated, notes)
+		VALUES (?, ?, ?, ?)
+	`, tableName)
+
+	now := time.Now()
+	result, err := db.exec(query, name, balance, now, notes)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ColdStorageEntry{
+		ID:          id,
+		Name:        name,
+		Balance:     balance,
+		LastUpdated: now,
+		Notes:       notes,
+		DisplayUnit: "sats",
+	}, nil
+}
+
+// UpdateColdStorageEntry updates an existing cold storage entry and records balance history
+func (db *Database) UpdateColdStorageEntry(id int64, name string, balance int64, notes string) (*ColdStorageEntry, error) {
+	// Get current entry to track previous balance
+	current, err := db.GetColdStorageEntryByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET name = ?, balance = ?, last_updated = ?, notes = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, tableName)
+
+	now := time.Now()
+	result, err := db.exec(query, name, balance, now, notes, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	// Record balance history if balance changed
+	if current.Balance != balance {
+		historyEntry := &ColdStorageBalanceHistory{
+			AccountID:       id,
+			Timestamp:       now,
+			Balance:         balance,
+			PreviousBalance: current.Balance,
+			IsVerified:      true, // Assume verified when manually updated
+			Notes:           notes,
+		}
+
+		if err := db.InsertColdStorageHistory(historyEntry); err != nil {
+			// Log error but don't fail the update
+			log.Printf("Warning: failed to record balance history: %v\n", err)
+		}
+	}
+
+	return &ColdStorageEntry{
+		ID:                  id,
+		Name:                name,
+		Balance:             balance,
+		LastUpdated:         now,
+		Notes:               notes,
+		ColdStorageMetadata: current.ColdStorageMetadata,
+		DisplayUnit:         current.DisplayUnit,
+	}, nil
+}
+
+// UpdateColdStorageMetadata replaces the custody metadata of a cold storage entry.
+// Metadata changes do not touch the balance or last_updated timestamp.
+func (db *Database) UpdateColdStorageMetadata(id int64, metadata ColdStorageMetadata) (*ColdStorageEntry, error) {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET custody_type = ?, device_model = ?, location_hint = ?, derivation_ref = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, tableName)
+
+	result, err := db.exec(query,
+		metadata.CustodyType,
+		metadata.DeviceModel,
+		metadata.LocationHint,
+		metadata.DerivationRef,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return db.GetColdStorageEntryByID(id)
+}
+
+// UpdateColdStorageDisplayUnit sets the unit ("sats" or "BTC") an entry is documented in.
+// Balances are always stored in sats; the unit is only a display hint.
+func (db *Database) UpdateColdStorageDisplayUnit(id int64, unit string) error {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`UPDATE %s SET display_unit = ? WHERE id = ? AND deleted_at IS NULL`, tableName)
+
+	result, err := db.exec(query, unit, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteColdStorageEntry soft-deletes a cold storage entry.
+// The entry and its balance history are kept so it can be restored.
+func (db *Database) DeleteColdStorageEntry(id int64) error {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, tableName)
+
+	result, err := db.exec(query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RestoreColdStorageEntry undoes a soft delete of a cold storage entry
+func (db *Database) RestoreColdStorageEntry(id int64) (*ColdStorageEntry, error) {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`UPDATE %s SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, tableName)
+
+	result, err := db.exec(query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return db.GetColdStorageEntryByID(id)
+}
+
+// GetDeletedColdStorageEntries retrieves soft-deleted cold storage entries, most recently deleted first
+func (db *Database) GetDeletedColdStorageEntries() ([]ColdStorageEntry, error) {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`
+		SELECT id, name, balance, last_updated, notes, custody_type, device_model, location_hint, derivation_ref, display_unit, deleted_at
+		FROM %s
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ColdStorageEntry
+	for rows.Next() {
+		var entry ColdStorageEntry
+		var deletedAt time.Time
+		err := rows.Scan(
+			&entry.ID, &entry.Name, &entry.Balance, &entry.LastUpdated, &entry.Notes,
+			&entry.CustodyType, &entry.DeviceModel, &entry.LocationHint, &entry.DerivationRef, &entry.DisplayUnit,
+			&deletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entry.DeletedAt = &deletedAt
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetDeletedColdStorageEntryID returns the ID of the soft-deleted cold
+// storage entry named name, or 0 when there is none. The entry keeps its
+// name, so it has to be restored rather than added again.
+func (db *Database) GetDeletedColdStorageEntryID(name string) (int64, error) {
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE name = ? AND deleted_at IS NOT NULL`, tableName)
+
+	var id int64
+	err := db.queryRow(query, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// InsertColdStorageHistory records a balance change in cold storage history
+func (db *Database) InsertColdStorageHistory(history *ColdStorageBalanceHistory) error {
+	tableName := db.getTableName("cold_storage_history")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (account_id, timestamp, balance, previous_balance, is_verified, notes)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	_, err := db.exec(query,
+		history.AccountID,
+		history.Timestamp,
+		history.Balance,
+		history.PreviousBalance,
+		history.IsVerified,
+		history.Notes,
+	)
+
+	return err
+}
+
+// SnapshotColdStorageBalances records each active account's last-known balance into
+// cold storage history for the day containing date. Accounts that already have a
+// history entry that day are skipped, so running it more than once a day is harmless.
+// Returns the number of snapshots written.
+func (db *Database) SnapshotColdStorageBalances(date time.Time) (int64, error) {
+	entriesTable := db.getTableName("cold_storage_entries")
+	historyTable := db.getTableName("cold_storage_history")
+
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (account_id, timestamp, balance, previous_balance, is_verified, notes)
+		SELECT e.id, ?, e.balance, e.balance, 0, 'Daily snapshot'
+		FROM %s e
+		WHERE e.deleted_at IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM %s h
+			WHERE h.account_id = e.id AND h.timestamp >= ? AND h.timestamp < ?
+		  )
+	`, historyTable, entriesTable, historyTable)
+
+	result, err := db.exec(query, date, dayStart, dayEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetColdStorageTotalAt returns the combined cold storage balance as of date.
+// Each account contributes its latest history balance at or before date. Accounts
+// whose history starts after date contribute the balance before their first change,
+// and accounts without any history contribute their current balance. Accounts
+// deleted after date are counted, as they still held their balance then.
+func (db *Database) GetColdStorageTotalAt(date time.Time) (int64, error) {
+	entriesTable := db.getTableName("cold_storage_entries")
+	historyTable := db.getTableName("cold_storage_history")
+	query := fmt.Sprintf(`
+		SELECT COALESCE(SUM(COALESCE(
+			(SELECT h.balance FROM %[2]s h
+			 WHERE h.account_id = e.id AND h.timestamp <= ?
+			 ORDER BY h.timestamp DESC LIMIT 1),
+			(SELECT h.previous_balance FROM %[2]s h
+			 WHERE h.account_id = e.id
+			 ORDER BY h.timestamp ASC LIMIT 1),
+			e.balance
+		)), 0)
+		FROM %[1]s e
+		WHERE e.deleted_at IS NULL OR e.deleted_at > ?
+	`, entriesTable, historyTable)
+
+	var total int64
+	err := db.queryRow(query, date, date).Scan(&total)
+	return total, err
+}
+
+// GetColdStorageBalancesAt returns the balance of every cold storage account
+// as of date, by name. They add up to GetColdStorageTotalAt, accounts deleted
+// after date included.
+func (db *Database) GetColdStorageBalancesAt(date time.Time) ([]ColdStorageBalanceAt, error) {
+	entriesTable := db.getTableName("cold_storage_entries")
+	historyTable := db.getTableName("cold_storage_history")
+	query := fmt.Sprintf(`
+		SELECT e.id, e.name, COALESCE(
+			(SELECT h.balance FROM %[2]s h
+			 WHERE h.account_id = e.id AND h.timestamp <= ?
+			 ORDER BY h.timestamp DESC LIMIT 1),
+			(SELECT h.previous_balance FROM %[2]s h
+			 WHERE h.account_id = e.id
+			 ORDER BY h.timestamp ASC LIMIT 1),
+			e.balance
+		)
+		FROM %[1]s e
+		WHERE e.deleted_at IS NULL OR e.deleted_at > ?
+		ORDER BY e.name ASC
+	`, entriesTable, historyTable)
+
+	rows, err := db.query(query, date, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []ColdStorageBalanceAt
+	for rows.Next() {
+		var balance ColdStorageBalanceAt
+		if err := rows.Scan(&balance.AccountID, &balance.Name, &balance.Balance); err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, rows.Err()
+}
+
+// GetColdStorageHistory retrieves balance history for a specific account
+func (db *Database) GetColdStorageHistory(accountID int64, from, to time.Time) ([]ColdStorageBalanceHistory, error) {
+	tableName := db.getTableName("cold_storage_history")
+	query := fmt.Sprintf(`
+		SELECT id, account_id, timestamp, balance, previous_balance, is_verified, notes
+		FROM %s
+		WHERE account_id = ? AND timestamp BETWEEN ? AND ?
+		ORDER BY timestamp ASC
+	`, tableName)
+
+	rows, err := db.query(query, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []ColdStorageBalanceHistory
+	for rows.Next() {
+		var entry ColdStorageBalanceHistory
+		err := rows.Scan(
+			&entry.ID, &entry.AccountID, &entry.Timestamp,
+			&entry.Balance, &entry.PreviousBalance, &entry.IsVerified, &entry.Notes,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+
+	return history, rows.Err()
+}
+
+// coldStorageSortOrders maps API sort keys to ORDER BY clauses
+var coldStorageSortOrders = map[string]string{
+	"":             "id ASC",
+	"name":         "name COLLATE NOCASE ASC",
+	"balance":      "balance DESC, id ASC",
+	"last_updated": "last_updated DESC, id ASC",
+	"custody_type": "custody_type ASC, name COLLATE NOCASE ASC",
+}
+
+// ColdStorageSorts lists the supported cold storage sort keys
+var ColdStorageSorts = []string{"name", "balance", "last_updated", "custody_type"}
+
+// IsValidColdStorageSort reports whether sortBy is a supported cold storage sort key
+func IsValidColdStorageSort(sortBy string) bool {
+	_, ok := coldStorageSortOrders[sortBy]
+	return ok
+}
+
+// ColdStorageFilter narrows and orders the cold storage entries returned by
+// GetColdStorageEntriesFiltered. Empty fields apply no filter.
+type ColdStorageFilter struct {
+	CustodyType string
+	SortBy      string // "", "name", "balance", "last_updated" or "custody_type"
+}
+
+// GetColdStorageEntriesWithWarnings retrieves all cold storage entries with warning status
+func (db *Database) GetColdStorageEntriesWithWarnings() ([]map[string]interface{}, error) {
+	return db.GetColdStorageEntriesFiltered(ColdStorageFilter{})
+}
+
+// GetColdStorageEntriesFiltered retrieves cold storage entries with warning status,
+// filtered by custody type and ordered by the requested sort key
+func (db *Database) GetColdStorageEntriesFiltered(filter ColdStorageFilter) ([]map[string]interface{}, error) {
+	orderBy, ok := coldStorageSortOrders[filter.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort key: %q", filter.SortBy)
+	}
+
+	tableName := db.getTableName("cold_storage_entries")
+	query := fmt.Sprintf(`
+		SELECT id, name, balance, last_updated, notes,
+		       custody_type, device_model, location_hint, derivation_ref, display_unit,
+		       (julianday('now') - julianday(last_updated)) as days_since_update
+		FROM %s
+		WHERE deleted_at IS NULL AND (? = '' OR custody_type = ?)
+		ORDER BY %s
+	`, tableName, orderBy)
+
+	rows, err := db.query(query, filter.CustodyType, filter.CustodyType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []map[string]interface{}
+	for rows.Next() {
+		var id int64
+		var name, notes string
+		var balance int64
+		var lastUpdated time.Time
+		var metadata ColdStorageMetadata
+		var displayUnit string
+		var daysSinceUpdate float64
+
+		err := rows.Scan(
+			&id, &name, &balance, &lastUpdated, &notes,
+			&metadata.CustodyType, &metadata.DeviceModel, &metadata.LocationHint, &metadata.DerivationRef,
+			&displayUnit, &daysSinceUpdate,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := map[string]interface{}{
+			"id":                int64(id),
+			"name":              name,
+			"balance":           balance,
+			"last_updated":      lastUpdated,
+			"notes":             notes,
+			"custody_type":      metadata.CustodyType,
+			"device_model":      metadata.DeviceModel,
+			"location_hint":     metadata.LocationHint,
+			"derivation_ref":    metadata.DerivationRef,
+			"display_unit":      displayUnit,
+			"days_since_update": int(daysSinceUpdate),
+			"needs_warning":     daysSinceUpdate > 90,
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// InsertStrikeBalanceSnapshot stores a Strike balance snapshot. An empty
+// Unit is set from the currency; one that does not match it is refused.
+func (db *Database) InsertStrikeBalanceSnapshot(snapshot *StrikeBalanceSnapshot) error {
+	tableName := db.getTableName("strike_balance_snapshots")
+
+	unit := StrikeUnit(snapshot.Currency)
+	if snapshot.Unit == "" {
+		snapshot.Unit = unit
+	} else if snapshot.Unit != unit {
+		return fmt.Errorf("Strike %s amounts are in %s, not %s", snapshot.Currency, unit, snapshot.Unit)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (timestamp, currency, unit, available, total, pending, reserved)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	_, err := db.exec(query,
+		snapshot.Timestamp,
+		snapshot.Currency,
+		snapshot.Unit,
+		snapshot.Available,
+		snapshot.Total,
+		snapshot.Pending,
+		snapshot.Reserved,
+	)
+
+	return err
+}
+
+// GetLatestStrikeBalance gets the most recent Strike balance for a currency
+func (db *Database) GetLatestStrikeBalance(currency string) (*StrikeBalanceSnapshot, error) {
+	tableName := db.getTableName("strike_balance_snapshots")
+
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, currency, unit, available, total, pending, reserved
+		FROM %s
+		WHERE currency = ?
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`, tableName)
+
+	var snapshot StrikeBalanceSnapshot
+	err := db.queryRow(query, currency).Scan(
+		&snapshot.ID,
+		&snapshot.Timestamp,
+		&snapshot.Currency,
+		&snapshot.Unit,
+		&snapshot.Available,
+		&snapshot.Total,
+		&snapshot.Pending,
+		&snapshot.Reserved,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// GetLatestStrikeBalances gets the most recent Strike balance of every
+// currency, ordered by currency
+func (db *Database) GetLatestStrikeBalances() ([]*StrikeBalanceSnapshot, error) {
+	tableName := db.getTableName("strike_balance_snapshots")
+
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, currency, unit, available, total, pending, reserved
+		FROM %s s
+		WHERE timestamp = (SELECT MAX(timestamp) FROM %s WHERE currency = s.currency)
+		GROUP BY currency
+		ORDER BY currency
+	`, tableName, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*StrikeBalanceSnapshot
+	for rows.Next() {
+		var snapshot StrikeBalanceSnapshot
+		if err := rows.Scan(&snapshot.ID, &snapshot.Timestamp, &snapshot.Currency, &snapshot.Unit,
+			&snapshot.Available, &snapshot.Total, &snapshot.Pending, &snapshot.Reserved); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// GetStrikeBalancesAt gets the latest Strike balance at or before date of
+// every currency, ordered by currency
+func (db *Database) GetStrikeBalancesAt(date time.Time) ([]*StrikeBalanceSnapshot, error) {
+	tableName := db.getTableName("strike_balance_snapshots")
+
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, currency, unit, available, total, pending, reserved
+		FROM %[1]s s
+		WHERE s.id = (
+			SELECT l.id FROM %[1]s l
+			WHERE l.currency = s.currency AND l.timestamp <= ?
+			ORDER BY l.timestamp DESC, l.id DESC
+			LIMIT 1
+		)
+		ORDER BY currency
+	`, tableName)
+
+	rows, err := db.query(query, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*StrikeBalanceSnapshot
+	for rows.Next() {
+		var snapshot StrikeBalanceSnapshot
+		if err := rows.Scan(&snapshot.ID, &snapshot.Timestamp, &snapshot.Currency, &snapshot.Unit,
+			&snapshot.Available, &snapshot.Total, &snapshot.Pending, &snapshot.Reserved); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// GetStrikeBalanceHistory retrieves historical Strike balance snapshots
+func (db *Database) GetStrikeBalanceHistory(currency string, from, to time.Time) ([]*StrikeBalanceSnapshot, error) {
+	tableName := db.getTableName("strike_balance_snapshots")
+
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, currency, unit, available, total, pending, reserved
+		FROM %s
+		WHERE currency = ? AND timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC
+	`, tableName)
+
+	rows, err := db.query(query, currency, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*StrikeBalanceSnapshot
+	for rows.Next() {
+		var snapshot StrikeBalanceSnapshot
+		err := rows.Scan(
+			&snapshot.ID,
+			&snapshot.Timestamp,
+			&snapshot.Currency,
+			&snapshot.Unit,
+			&snapshot.Available,
+			&snapshot.Total,
+			&snapshot.Pending,
+			&snapshot.Reserved,
+		)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// InsertLiquidBalanceSnapshot stores an L-BTC balance snapshot
+func (db *Database) InsertLiquidBalanceSnapshot(snapshot *LiquidBalanceSnapshot) error {
+	tableName := db.getTableName("liquid_balance_snapshots")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (timestamp, confirmed, unconfirmed)
+		VALUES (?, ?, ?)
+	`, tableName)
+
+	result, err := db.exec(query, snapshot.Timestamp, snapshot.Confirmed, snapshot.Unconfirmed)
+	if err != nil {
+		return err
+	}
+	snapshot.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetLatestLiquidBalance returns the most recent L-BTC balance snapshot, or
+// nil if none has been collected
+func (db *Database) GetLatestLiquidBalance() (*LiquidBalanceSnapshot, error) {
+	tableName := db.getTableName("liquid_balance_snapshots")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, confirmed, unconfirmed
+		FROM %s
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`, tableName)
+
+	var snapshot LiquidBalanceSnapshot
+	err := db.queryRow(query).Scan(&snapshot.ID, &snapshot.Timestamp, &snapshot.Confirmed, &snapshot.Unconfirmed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// GetLiquidBalanceSnapshotAt returns the latest L-BTC balance snapshot at or
+// before date, or nil if there is none
+func (db *Database) GetLiquidBalanceSnapshotAt(date time.Time) (*LiquidBalanceSnapshot, error) {
+	tableName := db.getTableName("liquid_balance_snapshots")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, confirmed, unconfirmed
+		FROM %s
+		WHERE timestamp <= ?
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`, tableName)
+
+	var snapshot LiquidBalanceSnapshot
+	err := db.queryRow(query, date).Scan(&snapshot.ID, &snapshot.Timestamp, &snapshot.Confirmed, &snapshot.Unconfirmed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// GetLiquidBalanceAt returns the total L-BTC balance of the latest snapshot at
+// or before date, or 0 if there is none
+func (db *Database) GetLiquidBalanceAt(date time.Time) (int64, error) {
+	snapshot, err := db.GetLiquidBalanceSnapshotAt(date)
+	if err != nil || snapshot == nil {
+		return 0, err
+	}
+	return snapshot.Confirmed + snapshot.Unconfirmed, nil
+}
+
+// GetLiquidBalanceHistory retrieves L-BTC balance snapshots within a time range
+func (db *Database) GetLiquidBalanceHistory(from, to time.Time) ([]LiquidBalanceSnapshot, error) {
+	tableName := db.getTableName("liquid_balance_snapshots")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, confirmed, unconfirmed
+		FROM %s
+		WHERE timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC
+	`, tableName)
+
+	rows, err := db.query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []LiquidBalanceSnapshot
+	for rows.Next() {
+		var snapshot LiquidBalanceSnapshot
+		if err := rows.Scan(&snapshot.ID, &snapshot.Timestamp, &snapshot.Confirmed, &snapshot.Unconfirmed); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// InsertEcashBalanceSnapshot stores the balance held with one federation or mint
+func (db *Database) InsertEcashBalanceSnapshot(snapshot *EcashBalanceSnapshot) error {
+	tableName := db.getTableName("ecash_balance_snapshots")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (timestamp, protocol, mint, name, amount)
+		VALUES (?, ?, ?, ?, ?)
+	`, tableName)
+
+	result, err := db.exec(query, snapshot.Timestamp, snapshot.Protocol, snapshot.Mint, snapshot.Name, snapshot.Amount)
+	if err != nil {
+		return err
+	}
+	snapshot.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetEcashBalancesAt returns the latest snapshot at or before date for each
+// federation and mint, ordered by name. Mints whose wallet was emptied keep
+// appearing with their last (zero) balance.
+func (db *Database) GetEcashBalancesAt(date time.Time) ([]EcashBalanceSnapshot, error) {
+	tableName := db.getTableName("ecash_balance_snapshots")
+	query := fmt.Sprintf(`
+		SELECT s.id, s.timestamp, s.protocol, s.mint, s.name, s.amount
+		FROM %[1]s s
+		WHERE s.id = (
+			SELECT l.id FROM %[1]s l
+			WHERE l.protocol = s.protocol AND l.mint = s.mint AND l.timestamp <= ?
+			ORDER BY l.timestamp DESC, l.id DESC
+			LIMIT 1
+		)
+		ORDER BY s.name, s.mint
+	`, tableName)
+
+	rows, err := db.query(query, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []EcashBalanceSnapshot
+	for rows.Next() {
+		var snapshot EcashBalanceSnapshot
+		if err := rows.Scan(&snapshot.ID, &snapshot.Timestamp, &snapshot.Protocol, &snapshot.Mint, &snapshot.Name, &snapshot.Amount); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// GetLatestEcashBalances returns the most recent snapshot for each
+// federation and mint
+func (db *Database) GetLatestEcashBalances() ([]EcashBalanceSnapshot, error) {
+	return db.GetEcashBalancesAt(time.Now())
+}
+
+// GetEcashBalanceAt returns the ecash held across all federations and mints
+// at date, or 0 if nothing was collected by then
+func (db *Database) GetEcashBalanceAt(date time.Time) (int64, error) {
+	snapshots, err := db.GetEcashBalancesAt(date)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, snapshot := range snapshots {
+		total += snapshot.Amount
+	}
+	return total, nil
+}
+
+// GetEcashBalanceHistory retrieves ecash balance snapshots of all federations
+// and mints within a time range
+func (db *Database) GetEcashBalanceHistory(from, to time.Time) ([]EcashBalanceSnapshot, error) {
+	tableName := db.getTableName("ecash_balance_snapshots")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, protocol, mint, name, amount
+		FROM %s
+		WHERE timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC, id ASC
+	`, tableName)
+
+	rows, err := db.query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []EcashBalanceSnapshot
+	for rows.Next() {
+		var snapshot EcashBalanceSnapshot
+		if err := rows.Scan(&snapshot.ID, &snapshot.Timestamp, &snapshot.Protocol, &snapshot.Mint, &snapshot.Name, &snapshot.Amount); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// InsertLightningBalancePoints stores derived Lightning balance points in one
+// transaction. Points whose event key is already stored are skipped, so the
+// balances recorded for an event never change. Returns how many were added.
+func (db *Database) InsertLightningBalancePoints(points []LightningBalancePoint) (int64, error) {
+	tableName := db.getTableName("lightning_balance_points")
+	query := fmt.Sprintf(`
+		INSERT OR IGNORE INTO %s
+		(event_key, timestamp, event_type, amount, onchain_balance, lightning_local, lightning_remote)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	var inserted int64
+	for _, point := range points {
+		result, err := tx.Exec(query, point.EventKey, point.Timestamp.UTC(), point.EventType, point.Amount,
+			point.OnchainBalance, point.LightningLocal, point.LightningRemote)
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to insert Lightning balance point %s: %w", point.EventKey, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		inserted += affected
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// GetLightningBalancePoints returns the stored Lightning balance points
+// between from and to, oldest first
+func (db *Database) GetLightningBalancePoints(from, to time.Time) ([]LightningBalancePoint, error) {
+	tableName := db.getTableName("lightning_balance_points")
+	query := fmt.Sprintf(`
+		SELECT id, event_key, timestamp, event_type, amount, onchain_balance, lightning_local, lightning_remote
+		FROM %s
+		WHERE timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC, id ASC
+	`, tableName)
+
+	rows, err := db.query(query, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []LightningBalancePoint
+	for rows.Next() {
+		var point LightningBalancePoint
+		if err := rows.Scan(&point.ID, &point.EventKey, &point.Timestamp, &point.EventType, &point.Amount,
+			&point.OnchainBalance, &point.LightningLocal, &point.LightningRemote); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// GetLightningBalanceAt returns the latest stored Lightning balance point at
+// or before date, or ErrNotFound if there is none
+func (db *Database) GetLightningBalanceAt(date time.Time) (*LightningBalancePoint, error) {
+	tableName := db.getTableName("lightning_balance_points")
+	query := fmt.Sprintf(`
+		SELECT id, event_key, timestamp, event_type, amount, onchain_balance, lightning_local, lightning_remote
+		FROM %s
+		WHERE timestamp <= ?
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1
+	`, tableName)
+
+	var point LightningBalancePoint
+	err := db.queryRow(query, date.UTC()).Scan(&point.ID, &point.EventKey, &point.Timestamp, &point.EventType,
+		&point.Amount, &point.OnchainBalance, &point.LightningLocal, &point.LightningRemote)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &point, nil
+}
+
+// InsertBTCPrices stores BTC prices in one transaction. A price already
+// stored for its currency and time is skipped. Returns how many were added.
+func (db *Database) InsertBTCPrices(prices []BTCPrice) (int64, error) {
+	tableName := db.getTableName("btc_prices")
+	query := fmt.Sprintf(`
+		INSERT OR IGNORE INTO %s (timestamp, currency, price, source)
+		VALUES (?, ?, ?, ?)
+	`, tableName)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	var inserted int64
+	for _, price := range prices {
+		result, err := tx.Exec(query, price.Timestamp.UTC(), price.Currency, price.Price, price.Source)
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to insert %s price: %w", price.Currency, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		inserted += affected
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// GetBTCPriceAt returns the latest stored price of currency at or before
+// date, or nil if there is none
+func (db *Database) GetBTCPriceAt(currency string, date time.Time) (*BTCPrice, error) {
+	tableName := db.getTableName("btc_prices")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, currency, price, source
+		FROM %s
+		WHERE currency = ? AND timestamp <= ?
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`, tableName)
+
+	var price BTCPrice
+	err := db.queryRow(query, currency, date.UTC()).Scan(&price.ID, &price.Timestamp, &price.Currency, &price.Price, &price.Source)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+// GetBTCPriceHistory returns the stored prices of currency between from and
+// to, oldest first
+func (db *Database) GetBTCPriceHistory(currency string, from, to time.Time) ([]BTCPrice, error) {
+	tableName := db.getTableName("btc_prices")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, currency, price, source
+		FROM %s
+		WHERE currency = ? AND timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC
+	`, tableName)
+
+	rows, err := db.query(query, currency, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []BTCPrice
+	for rows.Next() {
+		var price BTCPrice
+		if err := rows.Scan(&price.ID, &price.Timestamp, &price.Currency, &price.Price, &price.Source); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+
+	return prices, rows.Err()
+}
+
+// InsertMissionControlSnapshot stores one mission control snapshot, giving
+// every pair the snapshot timestamp
+func (db *Database) InsertMissionControlSnapshot(timestamp time.Time, pairs []MissionControlPair) error {
+	tableName := db.getTableName("mission_control_pairs")
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+		(timestamp, node_from, node_to, success_time, success_amt_msat, fail_time, fail_amt_msat)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		_, err := tx.Exec(query, timestamp.UTC(), pair.NodeFrom, pair.NodeTo,
+			pair.SuccessTime, pair.SuccessAmtMsat, pair.FailTime, pair.FailAmtMsat)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert mission control pair %s->%s: %w", pair.NodeFrom, pair.NodeTo, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLatestMissionControlPairs returns the pairs of the most recent mission
+// control snapshot, optionally only those from or to node
+func (db *Database) GetLatestMissionControlPairs(node string) ([]MissionControlPair, error) {
+	tableName := db.getTableName("mission_control_pairs")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, node_from, node_to, success_time, success_amt_msat, fail_time, fail_amt_msat
+		FROM %s
+		WHERE timestamp = (SELECT MAX(timestamp) FROM %s)
+		  AND (? = '' OR node_from = ? OR node_to = ?)
+		ORDER BY node_from ASC, node_to ASC
+	`, tableName, tableName)
+
+	rows, err := db.query(query, node, node, node)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs []MissionControlPair
+	for rows.Next() {
+		var pair MissionControlPair
+		if err := rows.Scan(&pair.ID, &pair.Timestamp, &pair.NodeFrom, &pair.NodeTo,
+			&pair.SuccessTime, &pair.SuccessAmtMsat, &pair.FailTime, &pair.FailAmtMsat); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, rows.Err()
+}
+
+// DeleteMissionControlSnapshotsBefore removes mission control snapshots taken
+// before cutoff and returns how many pairs were deleted
+func (db *Database) DeleteMissionControlSnapshotsBefore(cutoff time.Time) (int64, error) {
+	tableName := db.getTableName("mission_control_pairs")
+	query := fmt.Sprintf(`DELETE FROM %s WHERE timestamp < ?`, tableName)
+
+	result, err := db.exec(query, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetPeerPolicies returns every peer policy, ordered by pubkey
+func (db *Database) GetPeerPolicies() ([]PeerPolicy, error) {
+	tableName := db.getTableName("peer_policies")
+	query := fmt.Sprintf(`
+		SELECT id, pubkey, blocklisted, preferred, notes, updated_at
+		FROM %s
+		ORDER BY pubkey ASC
+	`, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []PeerPolicy
+	for rows.Next() {
+		var policy PeerPolicy
+		if err := rows.Scan(&policy.ID, &policy.Pubkey, &policy.Blocklisted, &policy.Preferred,
+			&policy.Notes, &policy.UpdatedAt); err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// GetPeerPolicy returns the policy for pubkey, or nil if it has none
+func (db *Database) GetPeerPolicy(pubkey string) (*PeerPolicy, error) {
+	tableName := db.getTableName("peer_policies")
+	query := fmt.Sprintf(`
+		SELECT id, pubkey, blocklisted, preferred, notes, updated_at
+		FROM %s
+		WHERE pubkey = ?
+	`, tableName)
+
+	var policy PeerPolicy
+	err := db.queryRow(query, pubkey).Scan(&policy.ID, &policy.Pubkey, &policy.Blocklisted,
+		&policy.Preferred, &policy.Notes, &policy.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SetPeerPolicy creates or replaces the policy for policy.Pubkey
+func (db *Database) SetPeerPolicy(policy PeerPolicy) (*PeerPolicy, error) {
+	tableName := db.getTableName("peer_policies")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (pubkey, blocklisted, preferred, notes, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(pubkey) DO UPDATE SET
+			blocklisted = excluded.blocklisted,
+			preferred = excluded.preferred,
+			notes = excluded.notes,
+			updated_at = excluded.updated_at
+	`, tableName)
+
+	_, err := db.exec(query, policy.Pubkey, policy.Blocklisted, policy.Preferred, policy.Notes, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return db.GetPeerPolicy(policy.Pubkey)
+}
+
+// DeletePeerPolicy removes the policy for pubkey, returning sql.ErrNoRows if it has none
+func (db *Database) DeletePeerPolicy(pubkey string) error {
+	tableName := db.getTableName("peer_policies")
+	query := fmt.Sprintf(`DELETE FROM %s WHERE pubkey = ?`, tableName)
+
+	result, err := db.exec(query, pubkey)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetPeerNotes returns every peer's notes, ordered by pubkey
+func (db *Database) GetPeerNotes() ([]PeerNote, error) {
+	tableName := db.getTableName("peer_notes")
+	query := fmt.Sprintf(`
+		SELECT id, pubkey, contact, agreement, agreed_on, notes, updated_at
+		FROM %s
+		ORDER BY pubkey ASC
+	`, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []PeerNote
+	for rows.Next() {
+		var note PeerNote
+		if err := rows.Scan(&note.ID, &note.Pubkey, &note.Contact, &note.Agreement, &note.AgreedOn,
+			&note.Notes, &note.UpdatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// GetPeerNote returns the notes about pubkey, or nil if there are none
+func (db *Database) GetPeerNote(pubkey string) (*PeerNote, error) {
+	tableName := db.getTableName("peer_notes")
+	query := fmt.Sprintf(`
+		SELECT id, pubkey, contact, agreement, agreed_on, notes, updated_at
+		FROM %s
+		WHERE pubkey = ?
+	`, tableName)
+
+	var note PeerNote
+	err := db.queryRow(query, pubkey).Scan(&note.ID, &note.Pubkey, &note.Contact, &note.Agreement,
+		&note.AgreedOn, &note.Notes, &note.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// SetPeerNote creates or replaces the notes about note.Pubkey
+func (db *Database) SetPeerNote(note PeerNote) (*PeerNote, error) {
+	tableName := db.getTableName("peer_notes")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (pubkey, contact, agreement, agreed_on, notes, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(pubkey) DO UPDATE SET
+			contact = excluded.contact,
+			agreement = excluded.agreement,
+			agreed_on = excluded.agreed_on,
+			notes = excluded.notes,
+			updated_at = excluded.updated_at
+	`, tableName)
+
+	_, err := db.exec(query, note.Pubkey, note.Contact, note.Agreement, note.AgreedOn, note.Notes, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return db.GetPeerNote(note.Pubkey)
+}
+
+// DeletePeerNote removes the notes about pubkey, returning sql.ErrNoRows if there are none
+func (db *Database) DeletePeerNote(pubkey string) error {
+	tableName := db.getTableName("peer_notes")
+	query := fmt.Sprintf(`DELETE FROM %s WHERE pubkey = ?`, tableName)
+
+	result, err := db.exec(query, pubkey)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetChannelPeers records the peer pubkey of each channel ID in peers
+func (db *Database) SetChannelPeers(peers map[string]string) error {
+	tableName := db.getTableName("channel_peers")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (channel_id, pubkey, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET
+			pubkey = excluded.pubkey,
+			updated_at = excluded.updated_at
+	`, tableName)
+
+	now := time.Now().UTC()
+	for channelID, pubkey := range peers {
+		if _, err := db.exec(query, channelID, pubkey, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetChannelPeer returns the peer pubkey of channelID, or "" if it was never recorded
+func (db *Database) GetChannelPeer(channelID string) (string, error) {
+	tableName := db.getTableName("channel_peers")
+	query := fmt.Sprintf(`SELECT pubkey FROM %s WHERE channel_id = ?`, tableName)
+
+	var pubkey string
+	err := db.queryRow(query, channelID).Scan(&pubkey)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return pubkey, err
+}
+
+// GetChannelNames returns every channel name, ordered by channel ID
+func (db *Database) GetChannelNames() ([]ChannelName, error) {
+	tableName := db.getTableName("channel_names")
+	query := fmt.Sprintf(`
+		SELECT channel_id, name, updated_at
+		FROM %s
+		ORDER BY channel_id ASC
+	`, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []ChannelName
+	for rows.Next() {
+		var name ChannelName
+		if err := rows.Scan(&name.ChannelID, &name.Name, &name.UpdatedAt); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// GetChannelNameMap returns the name of each named channel by channel ID
+func (db *Database) GetChannelNameMap() (map[string]string, error) {
+	names, err := db.GetChannelNames()
+	if err != nil {
+		return nil, err
+	}
+	byChannel := make(map[string]string, len(names))
+	for _, name := range names {
+		byChannel[name.ChannelID] = name.Name
+	}
+	return byChannel, nil
+}
+
+// GetChannelName returns the name of channelID, or nil if it has none
+func (db *Database) GetChannelName(channelID string) (*ChannelName, error) {
+	tableName := db.getTableName("channel_names")
+	query := fmt.Sprintf(`SELECT channel_id, name, updated_at FROM %s WHERE channel_id = ?`, tableName)
+
+	var name ChannelName
+	err := db.queryRow(query, channelID).Scan(&name.ChannelID, &name.Name, &name.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &name, nil
+}
+
+// SetChannelName names channelID, replacing its previous name
+func (db *Database) SetChannelName(channelID, name string) (*ChannelName, error) {
+	tableName := db.getTableName("channel_names")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (channel_id, name, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET
+			name = excluded.name,
+			updated_at = excluded.updated_at
+	`, tableName)
+
+	if _, err := db.exec(query, channelID, name, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return db.GetChannelName(channelID)
+}
+
+// DeleteChannelName removes the name of channelID, returning sql.ErrNoRows if it has none
+func (db *Database) DeleteChannelName(channelID string) error {
+	tableName := db.getTableName("channel_names")
+	query := fmt.Sprintf(`DELETE FROM %s WHERE channel_id = ?`, tableName)
+
+	result, err := db.exec(query, channelID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ReadChannelNames returns the channel names stored in the database at
+// path, for tools that talk to LND rather than the database. There are no
+// names when no database was created there yet.
+func ReadChannelNames(path string) (map[string]string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	database, err := NewDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	defer database.Close()
+	return database.GetChannelNameMap()
+}
+
+// EnvPath returns the database selected by the LNT_DB and LNT_PROFILE
+// environment variables, as lnt sets them for the tools it runs, or
+// fallback when LNT_DB is not set
+func EnvPath(fallback string) (string, error) {
+	path := os.Getenv("LNT_DB")
+	if path == "" {
+		path = fallback
+	}
+	profile := os.Getenv("LNT_PROFILE")
+	if profile == "" {
+		return path, nil
+	}
+	return ProfilePath(path, profile)
+}
+
+// channelLeaseColumns is the column list scanned by scanChannelLease
+const channelLeaseColumns = `id, channel_id, side, provider, premium, duration_blocks, expiry_height,
+	started_at, notes, updated_at`
+
+func scanChannelLease(row interface{ Scan(...interface{}) error }) (*ChannelLease, error) {
+	var lease ChannelLease
+	var startedAt sql.NullTime
+	err := row.Scan(&lease.ID, &lease.ChannelID, &lease.Side, &lease.Provider, &lease.Premium,
+		&lease.DurationBlocks, &lease.ExpiryHeight, &startedAt, &lease.Notes, &lease.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if startedAt.Valid {
+		lease.StartedAt = &startedAt.Time
+	}
+	return &lease, nil
+}
+
+// GetChannelLeases returns every recorded lease, soonest expiry first
+func (db *Database) GetChannelLeases() ([]ChannelLease, error) {
+	tableName := db.getTableName("channel_leases")
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		ORDER BY expiry_height ASC, channel_id ASC
+	`, channelLeaseColumns, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []ChannelLease
+	for rows.Next() {
+		lease, err := scanChannelLease(rows)
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, *lease)
+	}
+
+	return leases, rows.Err()
+}
+
+// GetChannelLease returns the lease of channelID, or nil if it has none
+func (db *Database) GetChannelLease(channelID string) (*ChannelLease, error) {
+	tableName := db.getTableName("channel_leases")
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE channel_id = ?`, channelLeaseColumns, tableName)
+
+	lease, err := scanChannelLease(db.queryRow(query, channelID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return lease, err
+}
+
+// SetChannelLease creates or replaces the lease of lease.ChannelID
+func (db *Database) SetChannelLease(lease ChannelLease) (*ChannelLease, error) {
+	tableName := db.getTableName("channel_leases")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (channel_id, side, provider, premium, duration_blocks, expiry_height,
+		                started_at, notes, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET
+			side = excluded.side,
+			provider = excluded.provider,
+			premium = excluded.premium,
+			duration_blocks = excluded.duration_blocks,
+			expiry_height = excluded.expiry_height,
+			started_at = excluded.started_at,
+			notes = excluded.notes,
+			updated_at = excluded.updated_at
+	`, tableName)
+
+	_, err := db.exec(query, lease.ChannelID, lease.Side, lease.Provider, lease.Premium,
+		lease.DurationBlocks, lease.ExpiryHeight, lease.StartedAt, lease.Notes, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return db.GetChannelLease(lease.ChannelID)
+}
+
+// RecordDetectedChannelLease stores a lease found on a channel by the
+// collector, unless the channel already has one. Leases entered by hand keep
+// their terms. Returns whether a lease was added.
+func (db *Database) RecordDetectedChannelLease(lease ChannelLease) (bool, error) {
+	tableName := db.getTableName("channel_leases")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (channel_id, side, provider, premium, duration_blocks, expiry_height,
+		                started_at, notes, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(channel_id) DO NOTHING
+	`, tableName)
+
+	result, err := db.exec(query, lease.ChannelID, lease.Side, lease.Provider, lease.Premium,
+		lease.DurationBlocks, lease.ExpiryHeight, lease.StartedAt, lease.Notes, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	return added > 0, err
+}
+
+// DeleteChannelLease removes the lease of channelID, returning sql.ErrNoRows if it has none
+func (db *Database) DeleteChannelLease(channelID string) error {
+	tableName := db.getTableName("channel_leases")
+	query := fmt.Sprintf(`DELETE FROM %s WHERE channel_id = ?`, tableName)
+
+	result, err := db.exec(query, channelID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// InsertProbeResult stores the outcome of one probe payment and sets its ID
+func (db *Database) InsertProbeResult(result *ProbeResult) error {
+	tableName := db.getTableName("probe_results")
+	query := fmt.Sprintf(`
+		INSERT INTO %s
+		(timestamp, destination, amount, success, failure_reason, latency_ms, attempts, hops, fee_msat)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	res, err := db.exec(query, result.Timestamp, result.Destination, result.Amount, result.Success,
+		result.FailureReason, result.LatencyMs, result.Attempts, result.Hops, result.FeeMsat)
+	if err != nil {
+		return err
+	}
+	result.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetProbeResults retrieves the probes sent within a time range, oldest first
+func (db *Database) GetProbeResults(from, to time.Time) ([]ProbeResult, error) {
+	tableName := db.getTableName("probe_results")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, destination, amount, success, failure_reason, latency_ms, attempts, hops, fee_msat
+		FROM %s
+		WHERE timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC, id ASC
+	`, tableName)
+
+	rows, err := db.query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []ProbeResult
+	for rows.Next() {
+		var r ProbeResult
+		if err := rows.Scan(&r.ID, &r.Timestamp, &r.Destination, &r.Amount, &r.Success,
+			&r.FailureReason, &r.LatencyMs, &r.Attempts, &r.Hops, &r.FeeMsat); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+// GetProbeStats summarises the probes sent within a time range, overall and
+// per destination. Destinations are ordered by pubkey.
+func (db *Database) GetProbeStats(from, to time.Time) (*ProbeStats, []ProbeStats, error) {
+	results, err := db.GetProbeResults(from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	overall := &probeTally{}
+	byDestination := make(map[string]*probeTally)
+	for _, result := range results {
+		tally, ok := byDestination[result.Destination]
+		if !ok {
+			tally = &probeTally{}
+			byDestination[result.Destination] = tally
+		}
+		overall.add(result)
+		tally.add(result)
+	}
+
+	destinations := make([]ProbeStats, 0, len(byDestination))
+	for destination, tally := range byDestination {
+		stats := tally.stats()
+		stats.Destination = destination
+		destinations = append(destinations, stats)
+	}
+	sort.Slice(destinations, func(i, j int) bool {
+		return destinations[i].Destination < destinations[j].Destination
+	})
+
+	stats := overall.stats()
+	return &stats, destinations, nil
+}
+
+// probeTally accumulates probe results into ProbeStats
+type probeTally struct {
+	probes, successes, latency int64
+	last                       *time.Time
+}
+
+func (t *probeTally) add(result ProbeResult) {
+	t.probes++
+	if result.Success {
+		t.successes++
+		t.latency += result.LatencyMs
+	}
+	if t.last == nil || result.Timestamp.After(*t.last) {
+		timestamp := result.Timestamp
+		t.last = &timestamp
+	}
+}
+
+func (t *probeTally) stats() ProbeStats {
+	stats := ProbeStats{Probes: t.probes, Successes: t.successes, LastProbe: t.last}
+	if t.probes > 0 {
+		stats.SuccessRate = float64(t.successes) / float64(t.probes)
+	}
+	if t.successes > 0 {
+		stats.AvgLatencyMs = t.latency / t.successes
+	}
+	return stats
+}
+
+// InsertPortfolioTransfer records a deposit (positive amount) or withdrawal
+// (negative amount) and sets its ID
+func (db *Database) InsertPortfolioTransfer(transfer *PortfolioTransfer) error {
+	tableName := db.getTableName("portfolio_transfers")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (timestamp, amount, notes, created_at)
+		VALUES (?, ?, ?, ?)
+	`, tableName)
+
+	transfer.CreatedAt = time.Now().UTC()
+	result, err := db.exec(query, transfer.Timestamp, transfer.Amount, transfer.Notes, transfer.CreatedAt)
+	if err != nil {
+		return err
+	}
+	transfer.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetPortfolioTransfers returns the transfers between from and to, oldest first
+func (db *Database) GetPortfolioTransfers(from, to time.Time) ([]PortfolioTransfer, error) {
+	tableName := db.getTableName("portfolio_transfers")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, amount, notes, created_at
+		FROM %s
+		WHERE timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC, id ASC
+	`, tableName)
+
+	rows, err := db.query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []PortfolioTransfer
+	for rows.Next() {
+		var transfer PortfolioTransfer
+		if err := rows.Scan(&transfer.ID, &transfer.Timestamp, &transfer.Amount, &transfer.Notes,
+			&transfer.CreatedAt); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, rows.Err()
+}
+
+// DeletePortfolioTransfer removes a transfer, returning sql.ErrNoRows if it does not exist
+func (db *Database) DeletePortfolioTransfer(id int64) error {
+	tableName := db.getTableName("portfolio_transfers")
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, tableName)
+
+	result, err := db.exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// InsertAnnotation stores an annotation and sets its ID
+func (db *Database) InsertAnnotation(annotation *Annotation) error {
+	tableName := db.getTableName("annotations")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (timestamp, text, created_at)
+		VALUES (?, ?, ?)
+	`, tableName)
+
+	annotation.CreatedAt = time.Now().UTC()
+	result, err := db.exec(query, annotation.Timestamp, annotation.Text, annotation.CreatedAt)
+	if err != nil {
+		return err
+	}
+	annotation.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetAnnotations returns the annotations between from and to, oldest first
+func (db *Database) GetAnnotations(from, to time.Time) ([]Annotation, error) {
+	tableName := db.getTableName("annotations")
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, text, created_at
+		FROM %s
+		WHERE timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC, id ASC
+	`, tableName)
+
+	rows, err := db.query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var annotations []Annotation
+	for rows.Next() {
+		var annotation Annotation
+		if err := rows.Scan(&annotation.ID, &annotation.Timestamp, &annotation.Text, &annotation.CreatedAt); err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, annotation)
+	}
+
+	return annotations, rows.Err()
+}
+
+// DeleteAnnotation removes an annotation, returning sql.ErrNoRows if it does not exist
+func (db *Database) DeleteAnnotation(id int64) error {
+	tableName := db.getTableName("annotations")
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, tableName)
+
+	result, err := db.exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// strikeWithdrawalColumns are the strike_withdrawals columns in the order
+// scanStrikeWithdrawal reads them
+const strikeWithdrawalColumns = `id, created_at, updated_at, amount, destination_type, destination,
+	status, quote_id, payment_id, fee, error`
+
+func scanStrikeWithdrawal(row interface{ Scan(...interface{}) error }) (*StrikeWithdrawal, error) {
+	var w StrikeWithdrawal
+	err := row.Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt, &w.Amount, &w.DestinationType, &w.Destination,
+		&w.Status, &w.QuoteID, &w.PaymentID, &w.Fee, &w.Error)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// InsertStrikeWithdrawal stores a proposed withdrawal and sets its ID and
+// timestamps
+func (db *Database) InsertStrikeWithdrawal(withdrawal *StrikeWithdrawal) error {
+	tableName := db.getTableName("strike_withdrawals")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (created_at, updated_at, amount, destination_type, destination, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	withdrawal.CreatedAt = time.Now().UTC()
+	withdrawal.UpdatedAt = withdrawal.CreatedAt
+	result, err := db.exec(query, withdrawal.CreatedAt, withdrawal.UpdatedAt, withdrawal.Amount,
+		withdrawal.DestinationType, withdrawal.Destination, withdrawal.Status)
+	if err != nil {
+		return err
+	}
+	withdrawal.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetStrikeWithdrawal returns a withdrawal, or sql.ErrNoRows if it does not exist
+func (db *Database) GetStrikeWithdrawal(id int64) (*StrikeWithdrawal, error) {
+	tableName := db.getTableName("strike_withdrawals")
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, strikeWithdrawalColumns, tableName)
+	return scanStrikeWithdrawal(db.queryRow(query, id))
+}
+
+// GetStrikeWithdrawals returns the withdrawals in any of statuses, or all
+// of them without statuses, newest first
+func (db *Database) GetStrikeWithdrawals(statuses ...string) ([]StrikeWithdrawal, error) {
+	tableName := db.getTableName("strike_withdrawals")
+	query := fmt.Sprintf(`SELECT %s FROM %s`, strikeWithdrawalColumns, tableName)
+	args := make([]interface{}, 0, len(statuses))
+	if len(statuses) > 0 {
+		query += ` WHERE status IN (?` + strings.Repeat(`, ?`, len(statuses)-1) + `)`
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	withdrawals := []StrikeWithdrawal{}
+	for rows.Next() {
+		withdrawal, err := scanStrikeWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, *withdrawal)
+	}
+
+	return withdrawals, rows.Err()
+}
+
+// TransitionStrikeWithdrawal moves a withdrawal from status from to to,
+// returning sql.ErrNoRows if it does not exist and ErrStatusChanged if it
+// is no longer in from, e.g. because it was approved twice
+func (db *Database) TransitionStrikeWithdrawal(id int64, from, to string) error {
+	tableName := db.getTableName("strike_withdrawals")
+	query := fmt.Sprintf(`UPDATE %s SET status = ?, updated_at = ? WHERE id = ? AND status = ?`, tableName)
+
+	result, err := db.exec(query, to, time.Now().UTC(), id, from)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := db.GetStrikeWithdrawal(id); err != nil {
+			return err
+		}
+		return ErrStatusChanged
+	}
+	return nil
+}
+
+// UpdateStrikeWithdrawal records the outcome of executing a withdrawal: its
+// status, Strike quote and payment IDs, fee and error
+func (db *Database) UpdateStrikeWithdrawal(withdrawal *StrikeWithdrawal) error {
+	tableName := db.getTableName("strike_withdrawals")
+	query := fmt.Sprintf(`
+		UPDATE %s SET status = ?, destination = ?, quote_id = ?, payment_id = ?, fee = ?, error = ?, updated_at = ?
+		WHERE id = ?
+	`, tableName)
+
+	withdrawal.UpdatedAt = time.Now().UTC()
+	_, err := db.exec(query, withdrawal.Status, withdrawal.Destination, withdrawal.QuoteID,
+		withdrawal.PaymentID, withdrawal.Fee, withdrawal.Error, withdrawal.UpdatedAt, withdrawal.ID)
+	return err
+}
+
+// ResolveStrikeWithdrawal records the outcome, as found on Strike, of a
+// withdrawal left executing: executed with its payment ID, or failed with
+// the reason. It returns sql.ErrNoRows if the withdrawal does not exist and
+// ErrStatusChanged if it is not executing.
+func (db *Database) ResolveStrikeWithdrawal(id int64, status, paymentID, reason string) error {
+	tableName := db.getTableName("strike_withdrawals")
+	query := fmt.Sprintf(`
+		UPDATE %s SET status = ?, payment_id = ?, error = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, tableName)
+
+	result, err := db.exec(query, status, paymentID, reason, time.Now().UTC(), id, WithdrawalExecuting)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := db.GetStrikeWithdrawal(id); err != nil {
+			return err
+		}
+		return ErrStatusChanged
+	}
+	return nil
+}
+
+// channelRequestColumns are the channel_requests columns in the order
+// scanChannelRequest reads them
+const channelRequestColumns = `id, created_at, updated_at, pubkey, host, capacity, contact, message,
+	status, fee_rate, funding_txid, error`
+
+func scanChannelRequest(row interface{ Scan(...interface{}) error }) (*ChannelRequest, error) {
+	var c ChannelRequest
+	err := row.Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt, &c.Pubkey, &c.Host, &c.Capacity, &c.Contact, &c.Message,
+		&c.Status, &c.FeeRate, &c.FundingTxid, &c.Error)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// InsertChannelRequest stores a submitted channel request and sets its ID
+// and timestamps
+func (db *Database) InsertChannelRequest(request *ChannelRequest) error {
+	tableName := db.getTableName("channel_requests")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (created_at, updated_at, pubkey, host, capacity, contact, message, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	request.CreatedAt = time.Now().UTC()
+	request.UpdatedAt = request.CreatedAt
+	result, err := db.exec(query, request.CreatedAt, request.UpdatedAt, request.Pubkey, request.Host,
+		request.Capacity, request.Contact, request.Message, request.Status)
+	if err != nil {
+		return err
+	}
+	request.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetChannelRequest returns a channel request, or sql.ErrNoRows if it does
+// not exist
+func (db *Database) GetChannelRequest(id int64) (*ChannelRequest, error) {
+	tableName := db.getTableName("channel_requests")
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, channelRequestColumns, tableName)
+	return scanChannelRequest(db.queryRow(query, id))
+}
+
+// GetChannelRequests returns the channel requests in any of statuses, or
+// all of them without statuses, newest first
+func (db *Database) GetChannelRequests(statuses ...string) ([]ChannelRequest, error) {
+	tableName := db.getTableName("channel_requests")
+	query := fmt.Sprintf(`SELECT %s FROM %s`, channelRequestColumns, tableName)
+	args := make([]interface{}, 0, len(statuses))
+	if len(statuses) > 0 {
+		query += ` WHERE status IN (?` + strings.Repeat(`, ?`, len(statuses)-1) + `)`
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []ChannelRequest{}
+	for rows.Next() {
+		request, err := scanChannelRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+
+	return requests, rows.Err()
+}
+
+// TransitionChannelRequest moves a channel request from status from to to,
+// returning sql.ErrNoRows if it does not exist and ErrStatusChanged if it
+// is no longer in from
+func (db *Database) TransitionChannelRequest(id int64, from, to string) error {
+	tableName := db.getTableName("channel_requests")
+	query := fmt.Sprintf(`UPDATE %s SET status = ?, updated_at = ? WHERE id = ? AND status = ?`, tableName)
+
+	result, err := db.exec(query, to, time.Now().UTC(), id, from)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := db.GetChannelRequest(id); err != nil {
+			return err
+		}
+		return ErrStatusChanged
+	}
+	return nil
+}
+
+// UpdateChannelRequest records the outcome of opening a requested channel:
+// its status, fee rate, funding transaction and error
+func (db *Database) UpdateChannelRequest(request *ChannelRequest) error {
+	tableName := db.getTableName("channel_requests")
+	query := fmt.Sprintf(`
+		UPDATE %s SET status = ?, fee_rate = ?, funding_txid = ?, error = ?, updated_at = ?
+		WHERE id = ?
+	`, tableName)
+
+	request.UpdatedAt = time.Now().UTC()
+	_, err := db.exec(query, request.Status, request.FeeRate, request.FundingTxid, request.Error,
+		request.UpdatedAt, request.ID)
+	return err
+}
+
+// multisigWalletColumns are the multisig_wallets columns in the order
+// scanMultisigWallet reads them
+const multisigWalletColumns = `id, name, descriptor, format, next_address_index, created_at`
+
+// scanMultisigWallet reads a multisig_wallets row
+func scanMultisigWallet(row interface{ Scan(...interface{}) error }) (*MultisigWallet, error) {
+	var wallet MultisigWallet
+	if err := row.Scan(&wallet.ID, &wallet.Name, &wallet.Descriptor, &wallet.Format, &wallet.NextAddressIndex, &wallet.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// InsertMultisigWallet stores an imported multisig wallet and sets its ID.
+// Names are unique.
+func (db *Database) InsertMultisigWallet(wallet *MultisigWallet) error {
+	tableName := db.getTableName("multisig_wallets")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (name, descriptor, format, created_at)
+		VALUES (?, ?, ?, ?)
+	`, tableName)
+
+	wallet.CreatedAt = time.Now().UTC()
+	result, err := db.exec(query, wallet.Name, wallet.Descriptor, wallet.Format, wallet.CreatedAt)
+	if err != nil {
+		return err
+	}
+	wallet.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetMultisigWallet returns a multisig wallet, or sql.ErrNoRows if it does not exist
+func (db *Database) GetMultisigWallet(id int64) (*MultisigWallet, error) {
+	tableName := db.getTableName("multisig_wallets")
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, multisigWalletColumns, tableName)
+	return scanMultisigWallet(db.queryRow(query, id))
+}
+
+// GetMultisigWallets returns every multisig wallet by name
+func (db *Database) GetMultisigWallets() ([]MultisigWallet, error) {
+	tableName := db.getTableName("multisig_wallets")
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY name ASC`, multisigWalletColumns, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wallets []MultisigWallet
+	for rows.Next() {
+		wallet, err := scanMultisigWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, *wallet)
+	}
+	return wallets, rows.Err()
+}
+
+// AdvanceMultisigWalletIndex moves a multisig wallet's next receive
+// address index forward to next. It never moves it back, so it reports
+// whether the index changed.
+func (db *Database) AdvanceMultisigWalletIndex(id int64, next uint32) (bool, error) {
+	tableName := db.getTableName("multisig_wallets")
+	query := fmt.Sprintf(`UPDATE %s SET next_address_index = ? WHERE id = ? AND next_address_index < ?`, tableName)
+
+	result, err := db.exec(query, next, id, next)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetTrackedAddressTotalAt sums the last recorded balance at or before date
+// of every tracked address that has not been deleted
+func (db *Database) GetTrackedAddressTotalAt(date time.Time) (int64, error) {
+	balanceTable := db.getTableName("address_balances")
+	addrTable := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		SELECT COALESCE(SUM((
+			SELECT ab.balance FROM %s ab
+			WHERE ab.address_id = oa.id AND ab.timestamp <= ?
+			ORDER BY ab.timestamp DESC
+			LIMIT 1
+		)), 0)
+		FROM %s oa
+		WHERE oa.deleted_at IS NULL
+	`, balanceTable, addrTable)
+
+	var total int64
+	err := db.queryRow(query, date).Scan(&total)
+	return total, err
+}
+
+// GetAddressBalancesAt returns the last balance recorded at or before date of
+// every tracked address, ordered by address ID. Addresses deleted after date
+// are included; addresses without a balance by then are not.
+func (db *Database) GetAddressBalancesAt(date time.Time) ([]AddressBalanceAt, error) {
+	balanceTable := db.getTableName("address_balances")
+	addrTable := db.getTableName("onchain_addresses")
+	query := fmt.Sprintf(`
+		SELECT oa.id, oa.address, oa.label, oa.active, oa.birth_height, oa.birth_date, oa.class,
+		       ab.balance, ab.timestamp
+		FROM %[2]s oa
+		JOIN %[1]s ab ON ab.id = (
+			SELECT l.id FROM %[1]s l
+			WHERE l.address_id = oa.id AND l.timestamp <= ?
+			ORDER BY l.timestamp DESC, l.id DESC
+			LIMIT 1
+		)
+		WHERE oa.deleted_at IS NULL OR oa.deleted_at > ?
+		ORDER BY oa.id ASC
+	`, balanceTable, addrTable)
+
+	rows, err := db.query(query, date, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []AddressBalanceAt
+	for rows.Next() {
+		var b AddressBalanceAt
+		if err := rows.Scan(&b.ID, &b.Address, &b.Label, &b.Active, &b.BirthHeight, &b.BirthDate, &b.Class,
+			&b.Balance, &b.RecordedAt); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+
+	return balances, rows.Err()
+}
+
+// InsertStatement stores a closed month's statement and sets its ID. It
+// returns ErrStatementExists if the month is already closed.
+func (db *Database) InsertStatement(statement *Statement) error {
+	tableName := db.getTableName("statements")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (month, period_start, period_end, lightning_local, lightning_remote,
+		                onchain_balance, tracked_addresses, cold_storage, liquid, ecash, total_portfolio,
+		                forwarding_fees, forward_count, forward_volume, deposits, withdrawals, closed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, tableName)
+
+	existing, err := db.GetStatement(statement.Month)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrStatementExists
+	}
+
+	statement.ClosedAt = time.Now().UTC()
+	result, err := db.exec(query,
+		statement.Month, statement.PeriodStart, statement.PeriodEnd,
+		statement.LightningLocal, statement.LightningRemote, statement.OnchainBalance,
+		statement.TrackedAddresses, statement.ColdStorage, statement.Liquid, statement.Ecash, statement.TotalPortfolio,
+		statement.ForwardingFees, statement.ForwardCount, statement.ForwardVolume,
+		statement.Deposits, statement.Withdrawals, statement.ClosedAt,
+	)
+	if err != nil {
+		return err
+	}
+	statement.ID, err = result.LastInsertId()
+	return err
+}
+
+// statementColumns is the column list scanned by scanStatement
+const statementColumns = `id, month, period_start, period_end, lightning_local, lightning_remote,
+	onchain_balance, tracked_addresses, cold_storage, liquid, ecash, total_portfolio,
+	forwarding_fees, forward_count, forward_volume, deposits, withdrawals, closed_at`
+
+func scanStatement(row interface{ Scan(...interface{}) error }) (*Statement, error) {
+	var s Statement
+	err := row.Scan(&s.ID, &s.Month, &s.PeriodStart, &s.PeriodEnd, &s.LightningLocal, &s.LightningRemote,
+		&s.OnchainBalance, &s.TrackedAddresses, &s.ColdStorage, &s.Liquid, &s.Ecash, &s.TotalPortfolio,
+		&s.ForwardingFees, &s.ForwardCount, &s.ForwardVolume, &s.Deposits, &s.Withdrawals, &s.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetStatement returns the statement for month ("YYYY-MM"), or nil if it is not closed
+func (db *Database) GetStatement(month string) (*Statement, error) {
+	tableName := db.getTableName("statements")
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE month = ?`, statementColumns, tableName)
+
+	statement, err := scanStatement(db.queryRow(query, month))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return statement, err
+}
+
+// GetStatements returns every closed statement, newest month first
+func (db *Database) GetStatements() ([]Statement, error) {
+	tableName := db.getTableName("statements")
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY month DESC`, statementColumns, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []Statement
+	for rows.Next() {
+		statement, err := scanStatement(rows)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, *statement)
+	}
+
+	return statements, rows.Err()
+}
+
+// StartCollectorRun records the start of a collector run
+func (db *Database) StartCollectorRun(collector string) (*CollectorRun, error) {
+	tableName := db.getTableName("collector_runs")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (collector, started_at, status)
+		VALUES (?, ?, ?)
+	`, tableName)
+
+	run := &CollectorRun{
+		Collector: collector,
+		StartedAt: time.Now(),
+		Status:    CollectorRunRunning,
+	}
+	result, err := db.exec(query, run.Collector, run.StartedAt, run.Status)
+	if err != nil {
+		return nil, err
+	}
+	run.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// UpdateCollectorRunProgress saves the counters and resume point of a run that
+// is still in progress, so an interrupted run can be resumed
+func (db *Database) UpdateCollectorRunProgress(run *CollectorRun) error {
+	tableName := db.getTableName("collector_runs")
+	query := fmt.Sprintf(`
+		UPDATE %s SET items_inserted = ?, errors = ?, resume_point = ?
+		WHERE id = ?
+	`, tableName)
+
+	_, err := db.exec(query, run.ItemsInserted, run.Errors, run.ResumePoint, run.ID)
+	return err
+}
+
+// FinishCollectorRun records the outcome of a run. runErr fails the run;
+// otherwise it is partial when item-level errors were counted.
+func (db *Database) FinishCollectorRun(run *CollectorRun, runErr error) error {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = runStatus(run, runErr)
+	if runErr != nil {
+		run.ErrorMessage = runErr.Error()
+	}
+
+	tableName := db.getTableName("collector_runs")
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET finished_at = ?, status = ?, items_inserted = ?, errors = ?, error_message = ?, resume_point = ?
+		WHERE id = ?
+	`, tableName)
+
+	_, err := db.exec(query, run.FinishedAt, run.Status, run.ItemsInserted, run.Errors,
+		run.ErrorMessage, run.ResumePoint, run.ID)
+	return err
+}
+
+func runStatus(run *CollectorRun, runErr error) string {
+	switch {
+	case runErr != nil:
+		return CollectorRunFailed
+	case run.Errors > 0:
+		return CollectorRunPartial
+	default:
+		return CollectorRunSuccess
+	}
+}
+
+// Collector runs as Prometheus metrics, served by the collectors' /metrics
+var (
+	collectorRuns = metrics.Default.NewCounter("collector_runs_total",
+		"Collector runs, by collector and status (success, partial or failed)", "collector", "status")
+	collectorRunDuration = metrics.Default.NewHistogram("collector_run_duration_seconds",
+		"Collector run durations, by collector", metrics.JobBuckets, "collector")
+	collectorItems = metrics.Default.NewCounter("collector_items_inserted_total",
+		"Items inserted by collector runs, by collector", "collector")
+	collectorErrors = metrics.Default.NewCounter("collector_errors_total",
+		"Errors collector runs skipped past, by collector", "collector")
+)
+
+// RecordCollectorRun runs collect inside a recorded collector run. collect
+// updates the run's counters and resume point as it goes. Failing to record
+// the run is logged and does not stop the collection.
+func (db *Database) RecordCollectorRun(collector string, collect func(run *CollectorRun) error) error {
+	run, err := db.StartCollectorRun(collector)
+	if err != nil {
+		log.Printf("Warning: failed to record start of %s run: %v", collector, err)
+		run = &CollectorRun{Collector: collector, StartedAt: time.Now()}
+		collectErr := collect(run)
+		observeCollectorRun(run, collectErr)
+		return collectErr
+	}
+
+	// Tag captured node responses with the run, see internal/capture
+	capture.SetRun(collector, run.ID)
+	collectErr := collect(run)
+	capture.SetRun("", 0)
+	if err := db.FinishCollectorRun(run, collectErr); err != nil {
+		log.Printf("Warning: failed to record end of %s run: %v", collector, err)
+	}
+	observeCollectorRun(run, collectErr)
+	return collectErr
+}
+
+func observeCollectorRun(run *CollectorRun, runErr error) {
+	collectorRuns.Inc(run.Collector, runStatus(run, runErr))
+	collectorRunDuration.Observe(time.Since(run.StartedAt), run.Collector)
+	collectorItems.Add(float64(run.ItemsInserted), run.Collector)
+	collectorErrors.Add(float64(run.Errors), run.Collector)
+}
+
+// GetCollectorRuns returns the most recent runs, newest first. An empty
+// collector returns runs for every collector.
+func (db *Database) GetCollectorRuns(collector string, limit int) ([]CollectorRun, error) {
+	tableName := db.getTableName("collector_runs")
+	query := fmt.Sprintf(`
+		SELECT id, collector, started_at, finished_at, status, items_inserted, errors, error_message, resume_point
+		FROM %s
+		WHERE ? = '' OR collector = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?
+	`, tableName)
+
+	rows, err := db.query(query, collector, collector, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []CollectorRun{}
+	for rows.Next() {
+		run, err := scanCollectorRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+
+	return runs, rows.Err()
+}
+
+// Recent collector runs CheckMode compares. The other mode must have at least
+// modeCheckMinRuns runs and more than modeCheckRatio times as many as the
+// mode the database was opened in.
+const (
+	ModeCheckWindow  = 7 * 24 * time.Hour
+	modeCheckMinRuns = 3
+	modeCheckRatio   = 4
+)
+
+// ModeCheckFlagUsage describes the flag collectors use to skip CheckMode
+const ModeCheckFlagUsage = "Start even if most recent collector runs on the database were in the other mode (mock or real)"
+
+// CheckMode returns an error wrapping ErrModeMismatch when most collector
+// runs within ModeCheckWindow before now were recorded in the other mode than
+// the database was opened in. A real collector started on a test database,
+// or a mock one on the node's database, usually means --mock was forgotten
+// or added by mistake.
+func (db *Database) CheckMode(now time.Time) error {
+	since := now.Add(-ModeCheckWindow)
+	count := func(table string) (int64, error) {
+		var runs int64
+		err := db.queryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE started_at >= ?`, table), since).Scan(&runs)
+		return runs, err
+	}
+
+	realRuns, err := count("collector_runs")
+	if err != nil {
+		return fmt.Errorf("failed to count collector runs: %w", err)
+	}
+	mockRuns, err := count("collector_runs_mock")
+	if err != nil {
+		return fmt.Errorf("failed to count mock collector runs: %w", err)
+	}
+
+	own, other, otherMode := realRuns, mockRuns, "mock"
+	if db.mockMode {
+		own, other, otherMode = mockRuns, realRuns, "real"
+	}
+	if other < modeCheckMinRuns || other <= own*modeCheckRatio {
+		return nil
+	}
+	return fmt.Errorf("%w: %d of the %d collector runs in the last %d days were in %s mode",
+		ErrModeMismatch, other, own+other, int(ModeCheckWindow.Hours()/24), otherMode)
+}
+
+// DryRunFlagUsage describes the flag collectors use to open the database with
+// OpenDryRun
+const DryRunFlagUsage = "Collect once into an in-memory copy of the database and log what would be inserted, leaving the database untouched"
+
+// tableMark is how far a table went when a dry run started
+type tableMark struct {
+	rows     int64
+	maxRowID int64
+}
+
+// dryRuns numbers the in-memory databases of dry runs
+var dryRuns atomic.Int64
+
+// OpenDryRun opens an in-memory copy of the database at dbPath, so a
+// collector can run unchanged without writing to the real database;
+// DryRunChanges reports what it wrote instead. A missing database is not
+// created, the run starts from empty tables.
+func OpenDryRun(dbPath string, mockMode bool) (*Database, error) {
+	conn, err := sql.Open("sqlite3", fmt.Sprintf("file:lnt-dry-run-%d?mode=memory&cache=shared", dryRuns.Add(1)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// A shared in-memory database is dropped with its last connection
+	scratch, err := conn.Conn(context.Background())
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db := &Database{conn: conn, mockMode: mockMode, scratch: scratch}
+	if _, err := os.Stat(dbPath); err == nil {
+		if err := copyDatabase(dbPath, scratch); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if err := db.initTables(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize tables: %w", err)
+	}
+	if db.baseline, err = db.tableMarks(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read table sizes: %w", err)
+	}
+	return db, nil
+}
+
+// copyDatabase copies the database at path into dst with SQLite's online
+// backup, opening it read-only
+func copyDatabase(path string, dst *sql.Conn) error {
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+	src, err := conn.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer src.Close()
+
+	err = dst.Raw(func(dstConn interface{}) error {
+		return src.Raw(func(srcConn interface{}) error {
+			backup, err := dstConn.(*sqlite3.SQLiteConn).Backup("main", srcConn.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+	return nil
+}
+
+// BackupDir returns the directory backups of the database at dbPath are kept
+// in, next to it: data/portfolio-backups for data/portfolio.db
+func BackupDir(dbPath string) string {
+	return strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + "-backups"
+}
+
+// SnapshotPath returns where the read-only snapshot of the database at dbPath
+// is written by default, next to it: data/portfolio-snapshot.db for
+// data/portfolio.db
+func SnapshotPath(dbPath string) string {
+	return strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + "-snapshot.db"
+}
+
+// Snapshot replaces dst with a consistent, read-only copy of the database at
+// path, for analytics tools such as Metabase or Grafana to query without
+// touching the live file. The copy is written next to dst and renamed over
+// it, so readers see either the previous snapshot or the new one.
+func Snapshot(path, dst string) error {
+	tmp := dst + ".tmp"
+	// A copy left behind by an interrupted snapshot would make VACUUM INTO fail
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := Backup(path, tmp); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0444); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// preMigrationPrefix starts the names of backups taken before a new version
+// migrates the database
+const preMigrationPrefix = "pre-migration-"
+
+// Backup writes a copy of the database at path to the new file dst with
+// VACUUM INTO, which is consistent while services keep writing
+func Backup(path, dst string) error {
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`VACUUM INTO ?`, dst); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return nil
+}
+
+// BackupBeforeMigration backs up the database at dbPath into BackupDir as
+// pre-migration-<time>[-<label>].db, e.g. labelled with the commit being
+// deployed, and deletes all but the keep most recent such backups. A
+// database that does not exist yet has nothing to lose and returns "".
+func BackupBeforeMigration(dbPath, label string, keep int, now time.Time) (string, error) {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return "", nil
+	}
+	dir := BackupDir(dbPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := preMigrationPrefix + now.UTC().Format("20060102-150405")
+	if label != "" {
+		name += "-" + label
+	}
+	dst := filepath.Join(dir, name+".db")
+	if err := Backup(dbPath, dst); err != nil {
+		return "", err
+	}
+	if err := pruneBackups(dir, keep); err != nil {
+		return dst, err
+	}
+	return dst, nil
+}
+
+// pruneBackups deletes all but the keep most recent pre-migration backups in
+// dir. Their names start with the time they were taken, so they sort by it.
+func pruneBackups(dir string, keep int) error {
+	backups, err := filepath.Glob(filepath.Join(dir, preMigrationPrefix+"*.db"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(backups)
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("failed to delete old backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// tableMarks returns the row count and highest rowid of every table
+func (db *Database) tableMarks() (map[string]tableMark, error) {
+	rows, err := db.query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	marks := make(map[string]tableMark, len(tables))
+	for _, table := range tables {
+		var mark tableMark
+		query := fmt.Sprintf(`SELECT COUNT(*), COALESCE(MAX(rowid), 0) FROM "%s"`, table)
+		if err := db.queryRow(query).Scan(&mark.rows, &mark.maxRowID); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		marks[table] = mark
+	}
+	return marks, nil
+}
+
+// DryRunChanges returns the tables a dry run opened with OpenDryRun has
+// inserted rows into or deleted rows from so far, sorted by name. Rows
+// replaced by an upsert count as both. Rows updated in place are not
+// reported.
+func (db *Database) DryRunChanges() ([]DryRunChange, error) {
+	if db.scratch == nil {
+		return nil, errors.New("database was not opened for a dry run")
+	}
+	marks, err := db.tableMarks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table sizes: %w", err)
+	}
+
+	var changes []DryRunChange
+	for table, mark := range marks {
+		before := db.baseline[table]
+		change := DryRunChange{Table: table}
+
+		err := db.queryRow(fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE rowid > ?`, table), before.maxRowID).Scan(&change.Inserted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count rows inserted into %s: %w", table, err)
+		}
+		change.Deleted = before.rows + change.Inserted - mark.rows
+		if change.Inserted == 0 && change.Deleted == 0 {
+			continue
+		}
+
+		if change.Inserted > 0 {
+			rows, err := db.query(fmt.Sprintf(`SELECT * FROM "%s" WHERE rowid > ? ORDER BY rowid LIMIT ?`, table),
+				before.maxRowID, DryRunSampleSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read rows inserted into %s: %w", table, err)
+			}
+			change.Samples, err = scanRecords(rows)
+			rows.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read rows inserted into %s: %w", table, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Table < changes[j].Table })
+	return changes, nil
+}
+
+// LogDryRun logs what a dry run opened with OpenDryRun would have written:
+// row counts per table and, with samples, the first rows inserted. Samples
+// hold amounts and addresses, so collectors only pass true when logging is
+// verbose.
+func (db *Database) LogDryRun(samples bool) {
+	changes, err := db.DryRunChanges()
+	if err != nil {
+		log.Printf("❌ Failed to list dry run changes: %v", err)
+		return
+	}
+	if len(changes) == 0 {
+		log.Println("🧪 Dry run: nothing would have been written to the database")
+		return
+	}
+
+	log.Println("🧪 Dry run left the database untouched; it would have written:")
+	for _, change := range changes {
+		line := fmt.Sprintf("   %s: %d rows inserted", change.Table, change.Inserted)
+		if change.Deleted > 0 {
+			line += fmt.Sprintf(", %d deleted", change.Deleted)
+		}
+		log.Println(line)
+		if !samples {
+			continue
+		}
+		for _, sample := range change.Samples {
+			row, err := json.Marshal(sample)
+			if err != nil {
+				log.Printf("      (unprintable row: %v)", err)
+				continue
+			}
+			log.Printf("      %s", row)
+		}
+	}
+	if !samples {
+		log.Println("   (sample rows are hidden unless logging is verbose)")
+	}
+}
+
+// GetLastResumePoint returns the resume point of the most recent run of a
+// collector that recorded one, or "" if there is none. Failed and interrupted
+// runs count, since their resume point only covers completed work.
+func (db *Database) GetLastResumePoint(collector string) (string, error) {
+	tableName := db.getTableName("collector_runs")
+	query := fmt.Sprintf(`
+		SELECT resume_point FROM %s
+		WHERE collector = ? AND resume_point != ''
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1
+	`, tableName)
+
+	var resumePoint string
+	err := db.queryRow(query, collector).Scan(&resumePoint)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return resumePoint, err
+}
+
+func scanCollectorRun(row interface{ Scan(...interface{}) error }) (*CollectorRun, error) {
+	var run CollectorRun
+	var finishedAt sql.NullTime
+	err := row.Scan(&run.ID, &run.Collector, &run.StartedAt, &finishedAt, &run.Status,
+		&run.ItemsInserted, &run.Errors, &run.ErrorMessage, &run.ResumePoint)
+	if err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return &run, nil
+}
+
+// CheckTimestamp reports why timestamp cannot belong to a real record: it is
+// more than MaxClockSkew in the future, or before earliest
+func CheckTimestamp(timestamp, earliest time.Time) error {
+	if timestamp.Before(earliest) {
+		return fmt.Errorf("timestamp %s is before %s", timestamp.UTC().Format(time.RFC3339), earliest.Format("2006-01-02"))
+	}
+	if limit := time.Now().Add(MaxClockSkew); timestamp.After(limit) {
+		return fmt.Errorf("timestamp %s is in the future", timestamp.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// checkTimestamp quarantines record when its timestamp fails CheckTimestamp,
+// returning an error wrapping ErrQuarantined, so a misparsed node response
+// is kept for inspection instead of landing in the charts
+func (db *Database) checkTimestamp(exec execer, source string, timestamp, earliest time.Time, record interface{}) error {
+	reason := CheckTimestamp(timestamp, earliest)
+	if reason == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode quarantined record: %w", err)
+	}
+
+	tableName := db.getTableName("quarantined_records")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (source, record_timestamp, reason, payload, quarantined_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, tableName)
+	if _, err := exec.Exec(query, source, timestamp, reason.Error(), string(payload), time.Now()); err != nil {
+		return fmt.Errorf("failed to quarantine record: %w", err)
+	}
+
+	log.Printf("Warning: quarantined %s record: %v", source, reason)
+	return fmt.Errorf("%w: %v", ErrQuarantined, reason)
+}
+
+// GetQuarantinedRecords returns the most recently quarantined records,
+// newest first. An empty source returns records for every source.
+func (db *Database) GetQuarantinedRecords(source string, limit int) ([]QuarantinedRecord, error) {
+	tableName := db.getTableName("quarantined_records")
+	query := fmt.Sprintf(`
+		SELECT id, source, record_timestamp, reason, payload, quarantined_at
+		FROM %s
+		WHERE ? = '' OR source = ?
+		ORDER BY quarantined_at DESC, id DESC
+		LIMIT ?
+	`, tableName)
+
+	rows, err := db.query(query, source, source, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []QuarantinedRecord{}
+	for rows.Next() {
+		var r QuarantinedRecord
+		if err := rows.Scan(&r.ID, &r.Source, &r.RecordTimestamp, &r.Reason, &r.Payload, &r.QuarantinedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// GetQuarantineCounts returns how many records each source has had
+// quarantined, keyed by source
+func (db *Database) GetQuarantineCounts() (map[string]int64, error) {
+	tableName := db.getTableName("quarantined_records")
+	query := fmt.Sprintf(`SELECT source, COUNT(*) FROM %s GROUP BY source`, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var source string
+		var count int64
+		if err := rows.Scan(&source, &count); err != nil {
+			return nil, err
+		}
+		counts[source] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// RecordNodeVersion stores the version a node runs unless it is the one
+// already recorded last, and reports whether it was new
+func (db *Database) RecordNodeVersion(node, version string) (bool, error) {
+	tableName := db.getTableName("node_versions")
+
+	var current string
+	err := db.queryRow(fmt.Sprintf(`
+		SELECT version FROM %s WHERE node = ?
+		ORDER BY first_seen DESC, id DESC
+		LIMIT 1
+	`, tableName), node).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if err == nil && current == version {
+		return false, nil
+	}
+
+	_, err = db.exec(fmt.Sprintf(`
+		INSERT INTO %s (node, version, first_seen) VALUES (?, ?, ?)
+	`, tableName), node, version, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetNodeVersions returns the latest recorded version of each node, ordered
+// by node
+func (db *Database) GetNodeVersions() ([]NodeVersion, error) {
+	tableName := db.getTableName("node_versions")
+	query := fmt.Sprintf(`
+		SELECT v.id, v.node, v.version, v.first_seen
+		FROM %s v
+		WHERE v.id = (
+			SELECT id FROM %s
+			WHERE node = v.node
+			ORDER BY first_seen DESC, id DESC
+			LIMIT 1
+		)
+		ORDER BY v.node
+	`, tableName, tableName)
+
+	rows, err := db.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []NodeVersion{}
+	for rows.Next() {
+		var v NodeVersion
+		if err := rows.Scan(&v.ID, &v.Node, &v.Version, &v.FirstSeen); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// integrityCheck finds rows of table matching where. %s in where is
+// replaced by the table named ref, for checks against another table.
+type integrityCheck struct {
+	name        string
+	table       string
+	ref         string
+	where       string
+	description string
+}
+
+// integrityChecks are the consistency rules CheckIntegrity applies.
+// Accounts and addresses that were deleted keep their history so they can be
+// restored; only rows whose account no longer exists at all are orphaned.
+var integrityChecks = []integrityCheck{
+	{
+		name: "orphaned-address-balances", table: "address_balances", ref: "onchain_addresses",
+		where:       "address_id NOT IN (SELECT id FROM %s)",
+		description: "address balances for an address that does not exist",
+	},
+	{
+		name: "orphaned-cold-storage-history", table: "cold_storage_history", ref: "cold_storage_entries",
+		where:       "account_id NOT IN (SELECT id FROM %s)",
+		description: "cold storage history for an account that does not exist",
+	},
+	{
+		name: "negative-address-balances", table: "address_balances",
+		where:       "balance < 0",
+		description: "negative address balances",
+	},
+	{
+		name: "negative-cold-storage-history", table: "cold_storage_history",
+		where:       "balance < 0 OR previous_balance < 0",
+		description: "negative cold storage balances",
+	},
+	{
+		name: "negative-balance-snapshots", table: "balance_snapshots",
+		where:       "lightning_local < 0 OR lightning_remote < 0 OR onchain_confirmed < 0 OR onchain_unconfirmed < 0 OR tracked_addresses < 0 OR cold_storage < 0",
+		description: "portfolio snapshots with a negative balance",
+	},
+	{
+		name: "forward-amounts", table: "forwarding_events",
+		where:       "amount_out > amount_in",
+		description: "forwards that sent out more than they received",
+	},
+	{
+		// Amounts are rounded down from msat, so they may be a sat apart
+		name: "forward-fees", table: "forwarding_events",
+		where:       "amount_out <= amount_in AND (fee < 0 OR ABS(amount_in - amount_out - fee) > 1)",
+		description: "forwards whose fee is not the difference between the amounts",
+	},
+}
+
+// findIntegrityCheck returns the check called name
+func findIntegrityCheck(name string) (integrityCheck, bool) {
+	for _, check := range integrityChecks {
+		if check.name == name {
+			return check, true
+		}
+	}
+	return integrityCheck{}, false
+}
+
+// integrityCondition returns the check's WHERE clause for the current tables
+func (db *Database) integrityCondition(check integrityCheck) string {
+	if check.ref == "" {
+		return check.where
+	}
+	return fmt.Sprintf(check.where, db.getTableName(check.ref))
+}
+
+// CheckIntegrity runs every integrity check and returns the ones that found
+// rows, in the order they are defined
+func (db *Database) CheckIntegrity() ([]IntegrityIssue, error) {
+	issues := []IntegrityIssue{}
+	for _, check := range integrityChecks {
+		tableName := db.getTableName(check.table)
+		rows, err := db.query(fmt.Sprintf(`SELECT id FROM %s WHERE %s ORDER BY id ASC`,
+			tableName, db.integrityCondition(check)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %s check: %w", check.name, err)
+		}
+
+		issue := IntegrityIssue{Check: check.name, Table: check.table, Description: check.description, SampleIDs: []int64{}}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if len(issue.SampleIDs) < IntegritySampleSize {
+				issue.SampleIDs = append(issue.SampleIDs, id)
+			}
+			issue.Count++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if issue.Count > 0 {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// RepairIntegrityIssue moves the rows found by the named check into
+// quarantined_records, where they stay for inspection, and returns how many
+// were moved. Daily summaries are rebuilt when forwards were moved.
+func (db *Database) RepairIntegrityIssue(name string) (int64, error) {
+	check, ok := findIntegrityCheck(name)
+	if !ok {
+		return 0, fmt.Errorf("unknown integrity check %q", name)
+	}
+	tableName := db.getTableName(check.table)
+	condition := db.integrityCondition(check)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(fmt.Sprintf(`SELECT * FROM %s WHERE %s ORDER BY id ASC`, tableName, condition))
+	if err != nil {
+		return 0, err
+	}
+	records, err := scanRecords(rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	quarantineTable := db.getTableName("quarantined_records")
+	insert := fmt.Sprintf(`
+		INSERT INTO %s (source, record_timestamp, reason, payload, quarantined_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, quarantineTable)
+	now := time.Now()
+	for _, record := range records {
+		payload, err := json.Marshal(record)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode quarantined record: %w", err)
+		}
+		timestamp, ok := record["timestamp"].(time.Time)
+		if !ok {
+			timestamp = now
+		}
+		if _, err := tx.Exec(insert, check.table, timestamp, "integrity check: "+check.description, string(payload), now); err != nil {
+			return 0, fmt.Errorf("failed to quarantine record: %w", err)
+		}
+	}
+
+	result, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE %s`, tableName, condition))
+	if err != nil {
+		return 0, err
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if check.table == "forwarding_events" && moved > 0 {
+		through, err := db.dailySummariesThrough()
+		if err != nil {
+			return moved, err
+		}
+		if !through.IsZero() {
+			if _, err := db.materializeForwardingDays(time.Time{}, through); err != nil {
+				return moved, fmt.Errorf("failed to rebuild daily summaries: %w", err)
+			}
+		}
+	}
+	return moved, nil
+}
+
+// scanRecords reads every row into a map of column name to value
+func scanRecords(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			record[column] = values[i]
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
 }
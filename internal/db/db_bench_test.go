@@ -0,0 +1,175 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+// Benchmarks for the queries the collectors and the API run most often. A
+// busy routing node records tens of thousands of forwards a year, so the
+// read benchmarks seed a year of them. Run with make bench.
+
+// benchForwardsPerYear is roughly a busy routing node's yearly forward count
+const benchForwardsPerYear = 50000
+
+func createBenchDB(b *testing.B) *Database {
+	b.Helper()
+	db, err := NewDatabase(testutils.CreateTestDBPath(b))
+	testutils.AssertNoError(b, err)
+	b.Cleanup(func() { db.Close() })
+	return db
+}
+
+// benchForwardingEvents returns count events spread evenly over the year
+// before now, cycling through a handful of channels
+func benchForwardingEvents(count int, now time.Time) []ForwardingEvent {
+	start := now.AddDate(-1, 0, 0)
+	step := now.Sub(start) / time.Duration(count)
+	events := make([]ForwardingEvent, count)
+	for i := range events {
+		amount := int64(10000 + i%90000)
+		events[i] = ForwardingEvent{
+			Timestamp:    start.Add(time.Duration(i) * step).Truncate(time.Second),
+			ChannelInID:  fmt.Sprintf("90623837121580%04d", i%7),
+			ChannelOutID: fmt.Sprintf("90711798041819%04d", i%11),
+			AmountIn:     amount + amount/1000 + 1,
+			AmountOut:    amount,
+			Fee:          amount/1000 + 1,
+		}
+	}
+	return events
+}
+
+// seedBenchForwards inserts events in a single transaction, which is far
+// quicker than InsertForwardingEvent for setting up large tables
+func seedBenchForwards(b *testing.B, db *Database, events []ForwardingEvent) {
+	b.Helper()
+	tx, err := db.conn.Begin()
+	testutils.AssertNoError(b, err)
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO forwarding_events
+		(timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee, fee_ppm)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	testutils.AssertNoError(b, err)
+	defer stmt.Close()
+
+	for _, e := range events {
+		_, err := stmt.Exec(e.Timestamp, e.ChannelInID, e.ChannelOutID, e.AmountIn, e.AmountOut, e.Fee, ForwardFeePPM(e.Fee, e.AmountOut))
+		testutils.AssertNoError(b, err)
+	}
+	testutils.AssertNoError(b, tx.Commit())
+}
+
+// BenchmarkInsertForwardingEventBatch measures a collector run storing a
+// batch of new forwards, one op being one batch
+func BenchmarkInsertForwardingEventBatch(b *testing.B) {
+	for _, size := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("events=%d", size), func(b *testing.B) {
+			db := createBenchDB(b)
+			now := time.Now()
+			b.ReportMetric(float64(size), "events/op")
+
+			for i := 0; b.Loop(); i++ {
+				// Shift each batch so every insert is a new row
+				events := benchForwardingEvents(size, now.Add(-time.Duration(i)*time.Second))
+				for j := range events {
+					if err := db.InsertForwardingEvent(&events[j]); err != nil {
+						b.Fatalf("InsertForwardingEvent: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkInsertForwardingEventIfNewBatch measures the collector's
+// deduplicating insert when half of each batch was stored on an earlier run
+func BenchmarkInsertForwardingEventIfNewBatch(b *testing.B) {
+	const size = 1000
+	db := createBenchDB(b)
+	now := time.Now()
+	seedBenchForwards(b, db, benchForwardingEvents(size/2, now))
+	b.ReportMetric(size, "events/op")
+
+	for i := 0; b.Loop(); i++ {
+		events := benchForwardingEvents(size/2, now)
+		events = append(events, benchForwardingEvents(size/2, now.Add(-time.Duration(i+1)*time.Second))...)
+		for j := range events {
+			if _, err := db.InsertForwardingEventIfNew(&events[j]); err != nil {
+				b.Fatalf("InsertForwardingEventIfNew: %v", err)
+			}
+		}
+	}
+}
+
+// BenchmarkGetForwardingEventsFees measures the daily fee aggregation behind
+// the fees chart over the ranges the dashboard offers
+func BenchmarkGetForwardingEventsFees(b *testing.B) {
+	db := createBenchDB(b)
+	now := time.Now()
+	seedBenchForwards(b, db, benchForwardingEvents(benchForwardsPerYear, now))
+
+	for _, r := range []struct {
+		name string
+		from time.Time
+	}{
+		{"week", now.AddDate(0, 0, -7)},
+		{"quarter", now.AddDate(0, -3, 0)},
+		{"year", now.AddDate(-1, 0, 0)},
+	} {
+		b.Run(r.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := db.GetForwardingEventsFees(r.from, now); err != nil {
+					b.Fatalf("GetForwardingEventsFees: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetForwardingStats measures the summary over a year of forwards
+func BenchmarkGetForwardingStats(b *testing.B) {
+	db := createBenchDB(b)
+	now := time.Now()
+	seedBenchForwards(b, db, benchForwardingEvents(benchForwardsPerYear, now))
+
+	for b.Loop() {
+		if _, err := db.GetForwardingStats(now.AddDate(-1, 0, 0), now); err != nil {
+			b.Fatalf("GetForwardingStats: %v", err)
+		}
+	}
+}
+
+// BenchmarkGetBalanceSnapshots measures reading a year of the collector's
+// snapshots, which history generation merges into every response
+func BenchmarkGetBalanceSnapshots(b *testing.B) {
+	db := createBenchDB(b)
+	now := time.Now().Truncate(time.Hour)
+
+	// One snapshot every 15 minutes, as the collector takes them
+	var snapshots []BalanceSnapshot
+	for ts := now.AddDate(-1, 0, 0); ts.Before(now); ts = ts.Add(15 * time.Minute) {
+		snapshots = append(snapshots, BalanceSnapshot{
+			Timestamp:        ts,
+			LightningLocal:   1500000,
+			LightningRemote:  2000000,
+			OnchainConfirmed: 800000,
+			TotalPortfolio:   2300000,
+			TotalLiquid:      2300000,
+		})
+	}
+	testutils.AssertNoError(b, db.InsertBalanceSnapshots(snapshots))
+	b.ReportMetric(float64(len(snapshots)), "rows/op")
+
+	for b.Loop() {
+		if _, err := db.GetBalanceSnapshots(now.AddDate(-1, 0, 0), now); err != nil {
+			b.Fatalf("GetBalanceSnapshots: %v", err)
+		}
+	}
+}
@@ -2,10 +2,19 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
 	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/brewgator/lightning-node-tools/internal/tracing"
 )
 
 func createTestDB(t *testing.T) *Database {
@@ -208,6 +217,83 @@ func TestInsertAndGetForwardingEvents(t *testing.T) {
 	testutils.AssertEqual(t, dayData.ForwardCount, int64(1))
 }
 
+func TestEachForwardingEvent(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().Truncate(time.Second)
+	for i := 3; i > 0; i-- {
+		testutils.AssertNoError(t, db.InsertForwardingEvent(&ForwardingEvent{
+			Timestamp:    now.Add(-time.Duration(i) * time.Hour),
+			ChannelInID:  "123456789:1:0",
+			ChannelOutID: "987654321:1:0",
+			AmountIn:     100000 + int64(i),
+			AmountOut:    100000,
+			Fee:          int64(i),
+		}))
+	}
+
+	var fees []int64
+	err := db.EachForwardingEvent(now.Add(-24*time.Hour), now, func(event *ForwardingEvent) error {
+		fees = append(fees, event.Fee)
+		return nil
+	})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(fees), 3)
+	testutils.AssertEqual(t, fees[0], int64(3))
+	testutils.AssertEqual(t, fees[2], int64(1))
+
+	// An error from the callback stops the iteration
+	stop := errors.New("stop")
+	calls := 0
+	err = db.EachForwardingEvent(now.Add(-24*time.Hour), now, func(*ForwardingEvent) error {
+		calls++
+		return stop
+	})
+	testutils.AssertEqual(t, errors.Is(err, stop), true)
+	testutils.AssertEqual(t, calls, 1)
+}
+
+func TestGetForwardingEventsAfter(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	last, err := db.LastForwardingEventID()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, last, int64(0))
+
+	now := time.Now().Truncate(time.Second)
+	for i := 1; i <= 3; i++ {
+		testutils.AssertNoError(t, db.InsertForwardingEvent(&ForwardingEvent{
+			// Each event is stored after one that happened later
+			Timestamp:    now.Add(-time.Duration(i) * time.Hour),
+			ChannelInID:  "123456789:1:0",
+			ChannelOutID: "987654321:1:0",
+			AmountIn:     100000 + int64(i),
+			AmountOut:    100000,
+			Fee:          int64(i),
+		}))
+	}
+
+	events, err := db.GetForwardingEventsAfter(last, 2)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(events), 2)
+	testutils.AssertEqual(t, events[0].Fee, int64(1))
+
+	events, err = db.GetForwardingEventsAfter(events[1].ID, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(events), 1)
+	testutils.AssertEqual(t, events[0].Fee, int64(3))
+
+	last, err = db.LastForwardingEventID()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, last, events[0].ID)
+
+	events, err = db.GetForwardingEventsAfter(last, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(events), 0)
+}
+
 func TestInsertForwardingEventIgnoreDuplicate(t *testing.T) {
 	db := createTestDB(t)
 	defer db.Close()
@@ -509,6 +595,87 @@ func TestGetOnchainAddresses(t *testing.T) {
 	}
 }
 
+func TestUpdateOnchainAddress(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	address, err := db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Original")
+	testutils.AssertNoError(t, err)
+
+	// Pause tracking and relabel
+	updated, err := db.UpdateOnchainAddress(address.ID, "Renamed", false)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, updated.Label, "Renamed")
+	testutils.AssertEqual(t, updated.Active, false)
+
+	retrieved, err := db.GetOnchainAddressByID(address.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, retrieved.Label, "Renamed")
+	testutils.AssertEqual(t, retrieved.Active, false)
+
+	// Updating a non-existent address returns sql.ErrNoRows
+	_, err = db.UpdateOnchainAddress(99999, "Missing", true)
+	if err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows when updating non-existent address, got %v", err)
+	}
+}
+
+func TestSetOnchainAddressBirthday(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	address, err := db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Recent wallet")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, address.BirthHeight, int64(0))
+
+	birthDate := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	updated, err := db.SetOnchainAddressBirthday(address.ID, 832000, &birthDate)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, updated.BirthHeight, int64(832000))
+	if updated.BirthDate == nil || !updated.BirthDate.Equal(birthDate) {
+		t.Errorf("Expected birth date %v, got %v", birthDate, updated.BirthDate)
+	}
+
+	addresses, err := db.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, addresses[0].BirthHeight, int64(832000))
+
+	// Clearing the birthday scans from genesis again
+	cleared, err := db.SetOnchainAddressBirthday(address.ID, 0, nil)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, cleared.BirthHeight, int64(0))
+	if cleared.BirthDate != nil {
+		t.Errorf("Expected no birth date, got %v", cleared.BirthDate)
+	}
+
+	_, err = db.SetOnchainAddressBirthday(99999, 1, nil)
+	if err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows for non-existent address, got %v", err)
+	}
+}
+
+func TestSetOnchainAddressClass(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	address, err := db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Vault")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, address.Class, AddressClassHot)
+
+	updated, err := db.SetOnchainAddressClass(address.ID, AddressClassCold)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, updated.Class, AddressClassCold)
+
+	addresses, err := db.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, addresses[0].Class, AddressClassCold)
+
+	_, err = db.SetOnchainAddressClass(99999, AddressClassCold)
+	if err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows for non-existent address, got %v", err)
+	}
+}
+
 func TestDeleteOnchainAddress(t *testing.T) {
 	db := createTestDB(t)
 	defer db.Close()
@@ -529,6 +696,82 @@ func TestDeleteOnchainAddress(t *testing.T) {
 	}
 }
 
+func TestRestoreOnchainAddress(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	address, err := db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Restorable")
+	testutils.AssertNoError(t, err)
+
+	err = db.InsertAddressBalance(&AddressBalance{
+		AddressID: address.ID,
+		Timestamp: time.Now().Add(-time.Hour),
+		Balance:   50000,
+		TxCount:   1,
+	})
+	testutils.AssertNoError(t, err)
+
+	err = db.DeleteOnchainAddress(address.ID)
+	testutils.AssertNoError(t, err)
+
+	// Soft-deleted addresses are hidden from the active list
+	addresses, err := db.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(addresses), 0)
+
+	deleted, err := db.GetDeletedOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(deleted), 1)
+	if deleted[0].DeletedAt == nil {
+		t.Error("Deleted address should have deleted_at set")
+	}
+
+	// Deleting twice is reported as not found
+	err = db.DeleteOnchainAddress(address.ID)
+	if err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows when deleting an already deleted address, got %v", err)
+	}
+
+	restored, err := db.RestoreOnchainAddress(address.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, restored.Address, address.Address)
+
+	// Balance history survives the delete/restore cycle
+	history, err := db.GetAddressBalanceHistory(address.Address, time.Now().Add(-24*time.Hour), time.Now())
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(history), 1)
+
+	// Restoring an address that is not deleted fails
+	_, err = db.RestoreOnchainAddress(address.ID)
+	if err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows when restoring an active address, got %v", err)
+	}
+}
+
+func TestRestoreColdStorageEntry(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	entry, err := db.InsertColdStorageEntry("Vault", 1000000, "")
+	testutils.AssertNoError(t, err)
+
+	err = db.DeleteColdStorageEntry(entry.ID)
+	testutils.AssertNoError(t, err)
+
+	// Soft-deleted entries no longer count towards totals
+	entries, err := db.GetColdStorageEntriesWithWarnings()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(entries), 0)
+
+	deleted, err := db.GetDeletedColdStorageEntries()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(deleted), 1)
+
+	restored, err := db.RestoreColdStorageEntry(entry.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, restored.Balance, int64(1000000))
+}
+
 func TestDeleteNonExistentOnchainAddress(t *testing.T) {
 	db := createTestDB(t)
 	defer db.Close()
@@ -617,6 +860,95 @@ func TestInsertAddressBalanceMultiple(t *testing.T) {
 	}
 }
 
+func TestMergeAddressBalances(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	address, err := db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Test")
+	testutils.AssertNoError(t, err)
+
+	day1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	// Two collector rows on day 1 are replaced by the merged daily row
+	for _, hour := range []int{6, 18} {
+		err = db.InsertAddressBalance(&AddressBalance{
+			AddressID: address.ID,
+			Timestamp: day1.Add(time.Duration(hour) * time.Hour),
+			Balance:   1000,
+			TxCount:   1,
+		})
+		testutils.AssertNoError(t, err)
+	}
+
+	err = db.MergeAddressBalances(address.ID, []AddressBalance{
+		{AddressID: address.ID, Timestamp: day1, Balance: 5000, TxCount: 1},
+		{AddressID: address.ID, Timestamp: day2, Balance: 3000, TxCount: 2},
+	})
+	testutils.AssertNoError(t, err)
+
+	// Merging again is idempotent
+	err = db.MergeAddressBalances(address.ID, []AddressBalance{
+		{AddressID: address.ID, Timestamp: day2, Balance: 3000, TxCount: 2},
+	})
+	testutils.AssertNoError(t, err)
+
+	balances, err := db.GetAddressBalanceHistory(address.Address, day1, day2.AddDate(0, 0, 1))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 2)
+	testutils.AssertEqual(t, balances[0].Balance, int64(5000))
+	testutils.AssertEqual(t, balances[1].Balance, int64(3000))
+	testutils.AssertEqual(t, balances[1].TxCount, int64(2))
+}
+
+func TestInvalidateAddressBalances(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	first, err := db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "First")
+	testutils.AssertNoError(t, err)
+	second, err := db.InsertOnchainAddress("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "Second")
+	testutils.AssertNoError(t, err)
+
+	day1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	err = db.MergeAddressBalances(first.ID, []AddressBalance{
+		{Timestamp: day1, Balance: 5000, TxCount: 1, BlockHeight: 100, BlockHash: "aa"},
+		{Timestamp: day2, Balance: 8000, TxCount: 1, BlockHeight: 105, BlockHash: "bb"},
+		// A quiet day still depends on the last block with activity
+		{Timestamp: day3, Balance: 8000, TxCount: 0, BlockHeight: 105, BlockHash: "bb"},
+	})
+	testutils.AssertNoError(t, err)
+	err = db.MergeAddressBalances(second.ID, []AddressBalance{
+		{Timestamp: day1, Balance: 1000, TxCount: 1, BlockHeight: 90, BlockHash: "cc"},
+		// Stored before block hashes were tracked
+		{Timestamp: day2, Balance: 1000, TxCount: 0},
+	})
+	testutils.AssertNoError(t, err)
+
+	blocks, err := db.GetAddressBalanceBlocks(95)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(blocks), 2)
+	testutils.AssertEqual(t, blocks[0], BlockRef{Height: 100, Hash: "aa"})
+	testutils.AssertEqual(t, blocks[1], BlockRef{Height: 105, Hash: "bb"})
+
+	affected, err := db.InvalidateAddressBalances(105)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(affected), 1)
+	testutils.AssertEqual(t, affected[first.ID].Equal(day2), true)
+
+	balances, err := db.GetAddressBalanceHistory(first.Address, day1, day3.AddDate(0, 0, 1))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 1)
+	testutils.AssertEqual(t, balances[0].BlockHash, "aa")
+
+	balances, err = db.GetAddressBalanceHistory(second.Address, day1, day3.AddDate(0, 0, 1))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 2)
+}
+
 func TestGetAddressBalanceHistoryTimeRange(t *testing.T) {
 	db := createTestDB(t)
 	defer db.Close()
@@ -680,3 +1012,1904 @@ func TestGetAddressBalanceHistoryEmptyResult(t *testing.T) {
 		t.Errorf("Expected 0 balance records for non-existent address, got %d", len(balances))
 	}
 }
+
+func TestColdStorageMetadataAndFilter(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	hw, err := db.InsertColdStorageEntry("Coldcard", 3000000, "")
+	testutils.AssertNoError(t, err)
+	paper, err := db.InsertColdStorageEntry("Paper wallet", 1000000, "")
+	testutils.AssertNoError(t, err)
+
+	updated, err := db.UpdateColdStorageMetadata(hw.ID, ColdStorageMetadata{
+		CustodyType:   CustodyTypeHardware,
+		DeviceModel:   "Coldcard Mk4",
+		LocationHint:  "home safe",
+		DerivationRef: "m/84'/0'/0'",
+	})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, updated.DeviceModel, "Coldcard Mk4")
+	testutils.AssertEqual(t, updated.Balance, int64(3000000))
+
+	_, err = db.UpdateColdStorageMetadata(paper.ID, ColdStorageMetadata{CustodyType: CustodyTypePaper})
+	testutils.AssertNoError(t, err)
+
+	// Balance updates keep existing metadata
+	balanceUpdated, err := db.UpdateColdStorageEntry(hw.ID, hw.Name, 3500000, "")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, balanceUpdated.CustodyType, CustodyTypeHardware)
+
+	hardware, err := db.GetColdStorageEntriesFiltered(ColdStorageFilter{CustodyType: CustodyTypeHardware})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(hardware), 1)
+	testutils.AssertEqual(t, hardware[0]["location_hint"], "home safe")
+
+	byBalance, err := db.GetColdStorageEntriesFiltered(ColdStorageFilter{SortBy: "balance"})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(byBalance), 2)
+	testutils.AssertEqual(t, byBalance[0]["name"], "Coldcard")
+
+	_, err = db.GetColdStorageEntriesFiltered(ColdStorageFilter{SortBy: "id; DROP TABLE x"})
+	testutils.AssertError(t, err, "unsupported sort key")
+
+	_, err = db.UpdateColdStorageMetadata(99999, ColdStorageMetadata{})
+	if err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows when updating metadata of non-existent entry, got %v", err)
+	}
+}
+
+func TestSnapshotColdStorageBalances(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	vault, err := db.InsertColdStorageEntry("Vault", 1000000, "")
+	testutils.AssertNoError(t, err)
+	deleted, err := db.InsertColdStorageEntry("Old", 500000, "")
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, db.DeleteColdStorageEntry(deleted.ID))
+
+	now := time.Now()
+	written, err := db.SnapshotColdStorageBalances(now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, written, int64(1))
+
+	// A second run on the same day is a no-op
+	written, err = db.SnapshotColdStorageBalances(now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, written, int64(0))
+
+	history, err := db.GetColdStorageHistory(vault.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(history), 1)
+	testutils.AssertEqual(t, history[0].Balance, int64(1000000))
+	testutils.AssertEqual(t, history[0].IsVerified, false)
+}
+
+func TestLiquidBalanceSnapshots(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	latest, err := db.GetLatestLiquidBalance()
+	testutils.AssertNoError(t, err)
+	if latest != nil {
+		t.Fatalf("Expected no Liquid balance before the first snapshot, got %+v", latest)
+	}
+
+	now := time.Now()
+	for _, snapshot := range []LiquidBalanceSnapshot{
+		{Timestamp: now.AddDate(0, 0, -10), Confirmed: 1000000},
+		{Timestamp: now.AddDate(0, 0, -5), Confirmed: 1500000, Unconfirmed: 20000},
+	} {
+		snapshot := snapshot
+		testutils.AssertNoError(t, db.InsertLiquidBalanceSnapshot(&snapshot))
+	}
+
+	latest, err = db.GetLatestLiquidBalance()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, latest.Confirmed, int64(1500000))
+	testutils.AssertEqual(t, latest.Unconfirmed, int64(20000))
+
+	for _, tt := range []struct {
+		date     time.Time
+		expected int64
+	}{
+		{now.AddDate(0, 0, -20), 0},
+		{now.AddDate(0, 0, -7), 1000000},
+		{now, 1520000},
+	} {
+		total, err := db.GetLiquidBalanceAt(tt.date)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, total, tt.expected)
+	}
+
+	history, err := db.GetLiquidBalanceHistory(now.AddDate(0, 0, -7), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(history), 1)
+	testutils.AssertEqual(t, history[0].Confirmed, int64(1500000))
+}
+
+func TestEcashBalanceSnapshots(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	for _, snapshot := range []EcashBalanceSnapshot{
+		{Timestamp: now.AddDate(0, 0, -10), Protocol: "fedimint", Mint: "fed1", Name: "Fedi", Amount: 50000},
+		{Timestamp: now.AddDate(0, 0, -8), Protocol: "cashu", Mint: "https://mint.example", Name: "mint.example", Amount: 2000},
+		{Timestamp: now.AddDate(0, 0, -3), Protocol: "fedimint", Mint: "fed1", Name: "Fedi", Amount: 40000},
+	} {
+		snapshot := snapshot
+		testutils.AssertNoError(t, db.InsertEcashBalanceSnapshot(&snapshot))
+	}
+
+	latest, err := db.GetLatestEcashBalances()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(latest), 2)
+	testutils.AssertEqual(t, latest[0].Name, "Fedi")
+	testutils.AssertEqual(t, latest[0].Amount, int64(40000))
+	testutils.AssertEqual(t, latest[1].Amount, int64(2000))
+
+	for _, tt := range []struct {
+		date     time.Time
+		expected int64
+	}{
+		{now.AddDate(0, 0, -20), 0},
+		{now.AddDate(0, 0, -9), 50000},
+		{now.AddDate(0, 0, -5), 52000},
+		{now, 42000},
+	} {
+		total, err := db.GetEcashBalanceAt(tt.date)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, total, tt.expected)
+	}
+
+	history, err := db.GetEcashBalanceHistory(now.AddDate(0, 0, -9), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(history), 2)
+	testutils.AssertEqual(t, history[0].Protocol, "cashu")
+}
+
+func TestGetColdStorageTotalAt(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	vault, err := db.InsertColdStorageEntry("Vault", 1000000, "")
+	testutils.AssertNoError(t, err)
+	_, err = db.InsertColdStorageEntry("No history", 200000, "")
+	testutils.AssertNoError(t, err)
+
+	now := time.Now()
+	for _, h := range []ColdStorageBalanceHistory{
+		{AccountID: vault.ID, Timestamp: now.AddDate(0, 0, -10), Balance: 1000000, PreviousBalance: 400000},
+		{AccountID: vault.ID, Timestamp: now.AddDate(0, 0, -5), Balance: 1500000, PreviousBalance: 1000000},
+	} {
+		h := h
+		testutils.AssertNoError(t, db.InsertColdStorageHistory(&h))
+	}
+
+	tests := []struct {
+		name     string
+		date     time.Time
+		expected int64
+	}{
+		{"before history uses first previous balance", now.AddDate(0, 0, -20), 400000 + 200000},
+		{"between changes", now.AddDate(0, 0, -7), 1000000 + 200000},
+		{"after latest change", now, 1500000 + 200000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, err := db.GetColdStorageTotalAt(tt.date)
+			testutils.AssertNoError(t, err)
+			testutils.AssertEqual(t, total, tt.expected)
+		})
+	}
+}
+
+func TestGetColdStorageBalancesAt(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	vault, err := db.InsertColdStorageEntry("Vault", 1500000, "")
+	testutils.AssertNoError(t, err)
+	sold, err := db.InsertColdStorageEntry("Sold", 200000, "")
+	testutils.AssertNoError(t, err)
+
+	now := time.Now()
+	testutils.AssertNoError(t, db.InsertColdStorageHistory(&ColdStorageBalanceHistory{
+		AccountID: vault.ID, Timestamp: now.AddDate(0, 0, -5), Balance: 1500000, PreviousBalance: 1000000,
+	}))
+	testutils.AssertNoError(t, db.DeleteColdStorageEntry(sold.ID))
+
+	// The deleted account still held its balance a week ago
+	balances, err := db.GetColdStorageBalancesAt(now.AddDate(0, 0, -7))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 2)
+	testutils.AssertEqual(t, balances[0].Name, "Sold")
+	testutils.AssertEqual(t, balances[0].Balance, int64(200000))
+	testutils.AssertEqual(t, balances[1].AccountID, vault.ID)
+	testutils.AssertEqual(t, balances[1].Balance, int64(1000000))
+
+	balances, err = db.GetColdStorageBalancesAt(now.Add(time.Minute))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 1)
+	testutils.AssertEqual(t, balances[0].Balance, int64(1500000))
+}
+
+func TestColdStorageAtCountsLaterDeletions(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	_, err := db.InsertColdStorageEntry("Vault", 1500000, "")
+	testutils.AssertNoError(t, err)
+	sold, err := db.InsertColdStorageEntry("Sold", 200000, "")
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, db.DeleteColdStorageEntry(sold.ID))
+
+	// History and /portfolio/at agree before and after the deletion
+	now := time.Now()
+	for _, tc := range []struct {
+		date time.Time
+		want int64
+	}{
+		{now.AddDate(0, 0, -1), 1500000 + 200000},
+		{now.Add(time.Minute), 1500000},
+	} {
+		total, err := db.GetColdStorageTotalAt(tc.date)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, total, tc.want)
+
+		balances, err := db.GetColdStorageBalancesAt(tc.date)
+		testutils.AssertNoError(t, err)
+		var sum int64
+		for _, balance := range balances {
+			sum += balance.Balance
+		}
+		testutils.AssertEqual(t, sum, total)
+	}
+}
+
+func TestMigrateLegacySchema(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+
+	// Create tables the way older versions did, without deleted_at
+	conn, err := sql.Open("sqlite3", dbPath)
+	testutils.AssertNoError(t, err)
+	_, err = conn.Exec(`CREATE TABLE onchain_addresses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		address TEXT UNIQUE NOT NULL,
+		label TEXT,
+		active BOOLEAN NOT NULL DEFAULT 1
+	)`)
+	testutils.AssertNoError(t, err)
+	_, err = conn.Exec(`INSERT INTO onchain_addresses (address, label) VALUES ('bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh', 'Legacy')`)
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, conn.Close())
+
+	db, err := NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer db.Close()
+
+	addresses, err := db.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(addresses), 1)
+
+	testutils.AssertNoError(t, db.DeleteOnchainAddress(addresses[0].ID))
+}
+
+func TestMigrateIndexes(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+
+	// Older versions indexed cold storage history by account alone
+	conn, err := sql.Open("sqlite3", dbPath)
+	testutils.AssertNoError(t, err)
+	_, err = conn.Exec(`CREATE TABLE cold_storage_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_id INTEGER NOT NULL,
+		timestamp DATETIME NOT NULL,
+		balance INTEGER NOT NULL,
+		previous_balance INTEGER NOT NULL DEFAULT 0,
+		is_verified BOOLEAN NOT NULL DEFAULT 1,
+		notes TEXT
+	)`)
+	testutils.AssertNoError(t, err)
+	_, err = conn.Exec(`CREATE INDEX idx_cold_storage_history_account_id ON cold_storage_history(account_id)`)
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, conn.Close())
+
+	db, err := NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer db.Close()
+
+	indexExists := func(name string) bool {
+		var count int
+		err := db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?`, name).Scan(&count)
+		testutils.AssertNoError(t, err)
+		return count == 1
+	}
+	testutils.AssertEqual(t, indexExists("idx_cold_storage_history_account_id"), false)
+	for _, name := range []string{
+		"idx_forwarding_events_channel_in",
+		"idx_forwarding_events_channel_out",
+		"idx_address_balances_address",
+		"idx_cold_storage_history_account",
+		"idx_cold_storage_history_mock_account",
+	} {
+		testutils.AssertEqual(t, indexExists(name), true)
+	}
+
+	// The per-address balance lookup is answered from the composite index
+	rows, err := db.conn.Query(`EXPLAIN QUERY PLAN
+		SELECT balance FROM address_balances
+		WHERE address_id = ? AND timestamp >= ? AND timestamp < ?`, 1, time.Now(), time.Now())
+	testutils.AssertNoError(t, err)
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		testutils.AssertNoError(t, rows.Scan(&id, &parent, &notUsed, &detail))
+		plan = append(plan, detail)
+	}
+	testutils.AssertNoError(t, rows.Err())
+	if !strings.Contains(strings.Join(plan, "\n"), "idx_address_balances_address") {
+		t.Errorf("address balance lookup does not use idx_address_balances_address: %v", plan)
+	}
+}
+
+func TestGetForwardingStats(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	events := []*ForwardingEvent{
+		{Timestamp: base, ChannelInID: "a", ChannelOutID: "b", AmountIn: 100100, AmountOut: 100000, Fee: 100},
+		{Timestamp: base.Add(time.Minute), ChannelInID: "a", ChannelOutID: "c", AmountIn: 20020, AmountOut: 20000, Fee: 20},
+		{Timestamp: base.Add(2 * time.Minute), ChannelInID: "c", ChannelOutID: "a", AmountIn: 10010, AmountOut: 10000, Fee: 10},
+		{Timestamp: base.Add(3 * time.Minute), ChannelInID: "b", ChannelOutID: "c", AmountIn: 30030, AmountOut: 30000, Fee: 30},
+	}
+	for _, event := range events {
+		testutils.AssertNoError(t, db.InsertForwardingEvent(event))
+	}
+
+	stats, err := db.GetForwardingStats(base.Add(-time.Hour), time.Now())
+	testutils.AssertNoError(t, err)
+
+	testutils.AssertEqual(t, stats.ForwardCount, int64(4))
+	testutils.AssertEqual(t, stats.TotalVolume, int64(160000))
+	testutils.AssertEqual(t, stats.TotalFees, int64(160))
+	testutils.AssertEqual(t, stats.MeanForwardSize, int64(40000))
+	// Even count: mean of 20000 and 30000
+	testutils.AssertEqual(t, stats.MedianForwardSize, int64(25000))
+	testutils.AssertEqual(t, stats.EffectiveFeePPM, float64(1000))
+	testutils.AssertEqual(t, stats.LargestForward.AmountOut, int64(100000))
+	// "a" and "c" both appear in three forwards; "a" moved more volume
+	testutils.AssertEqual(t, stats.BusiestChannel.ChannelID, "a")
+	testutils.AssertEqual(t, stats.BusiestChannel.ForwardCount, int64(3))
+
+	// Odd count takes the middle value
+	stats, err = db.GetForwardingStats(base.Add(-time.Hour), base.Add(150*time.Second))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stats.MedianForwardSize, int64(20000))
+
+	// Fees are credited to the outgoing channel: b 100, c 20+30, a 10
+	top, err := db.GetTopFeeChannels(base.Add(-time.Hour), time.Now(), 2)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(top), 2)
+	testutils.AssertEqual(t, top[0].ChannelID, "b")
+	testutils.AssertEqual(t, top[1].ChannelID, "c")
+	testutils.AssertEqual(t, top[1].Fees, int64(50))
+	testutils.AssertEqual(t, top[1].ForwardCount, int64(2))
+}
+
+func TestFeePPMHistograms(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	events := []*ForwardingEvent{
+		// Low-fee flood through "a": 1 ppm, 1 ppm and 5 ppm
+		{Timestamp: base, ChannelInID: "x", ChannelOutID: "a", AmountIn: 1000001, AmountOut: 1000000, Fee: 1},
+		{Timestamp: base.Add(time.Minute), ChannelInID: "x", ChannelOutID: "a", AmountIn: 2000002, AmountOut: 2000000, Fee: 2},
+		{Timestamp: base.Add(2 * time.Minute), ChannelInID: "x", ChannelOutID: "a", AmountIn: 200001, AmountOut: 200000, Fee: 1},
+		// High-fee niche through "b": 1500 ppm and 3000 ppm
+		{Timestamp: base.Add(3 * time.Minute), ChannelInID: "x", ChannelOutID: "b", AmountIn: 20030, AmountOut: 20000, Fee: 30},
+		{Timestamp: base.Add(4 * time.Minute), ChannelInID: "x", ChannelOutID: "b", AmountIn: 10030, AmountOut: 10000, Fee: 30},
+	}
+	for _, event := range events {
+		testutils.AssertNoError(t, db.InsertForwardingEvent(event))
+	}
+	testutils.AssertEqual(t, events[2].FeePPM, int64(5))
+
+	overall, channels, err := db.GetFeePPMHistograms(base.Add(-time.Hour), time.Now())
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, overall.ForwardCount, int64(5))
+	testutils.AssertEqual(t, overall.MedianPPM, int64(5))
+	testutils.AssertEqual(t, len(overall.Buckets), len(FeePPMBucketBounds))
+	testutils.AssertEqual(t, overall.Buckets[0].ForwardCount, int64(3))
+	testutils.AssertEqual(t, overall.Buckets[0].Volume, int64(3200000))
+
+	testutils.AssertEqual(t, len(channels), 2)
+	testutils.AssertEqual(t, channels[0].ChannelID, "b")
+	testutils.AssertEqual(t, channels[0].MedianPPM, int64(1500))
+	testutils.AssertEqual(t, channels[0].Buckets[6].ForwardCount, int64(1))
+	last := channels[0].Buckets[len(channels[0].Buckets)-1]
+	testutils.AssertEqual(t, last.ForwardCount, int64(1))
+	if last.MaxPPM != nil {
+		t.Errorf("expected the last bucket to be open-ended, got max %d", *last.MaxPPM)
+	}
+	testutils.AssertEqual(t, channels[1].MedianPPM, int64(1))
+}
+
+func TestFeePPMBackfill(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+
+	// Forwards stored before fee_ppm existed
+	conn, err := sql.Open("sqlite3", dbPath)
+	testutils.AssertNoError(t, err)
+	_, err = conn.Exec(`CREATE TABLE forwarding_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL,
+		channel_in_id TEXT NOT NULL,
+		channel_out_id TEXT NOT NULL,
+		amount_in INTEGER NOT NULL,
+		amount_out INTEGER NOT NULL,
+		fee INTEGER NOT NULL
+	)`)
+	testutils.AssertNoError(t, err)
+	_, err = conn.Exec(`INSERT INTO forwarding_events (timestamp, channel_in_id, channel_out_id, amount_in, amount_out, fee)
+		VALUES (?, 'x', 'a', 300100, 300000, 100)`, time.Now().Add(-time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, conn.Close())
+
+	db, err := NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer db.Close()
+
+	stats, err := db.GetForwardingStats(time.Now().Add(-2*time.Hour), time.Now())
+	testutils.AssertNoError(t, err)
+	// 333.33 ppm rounds to 333
+	testutils.AssertEqual(t, stats.LargestForward.FeePPM, int64(333))
+}
+
+func TestGetForwardingStatsEmpty(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	stats, err := db.GetForwardingStats(time.Now().Add(-24*time.Hour), time.Now())
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stats.ForwardCount, int64(0))
+	if stats.LargestForward != nil || stats.BusiestChannel != nil {
+		t.Error("Expected no largest forward or busiest channel for an empty range")
+	}
+}
+
+func TestCollectorRuns(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	// Successful run with progress
+	err := db.RecordCollectorRun("forwarding", func(run *CollectorRun) error {
+		run.ItemsInserted = 5
+		run.ResumePoint = "1700000000"
+		return nil
+	})
+	testutils.AssertNoError(t, err)
+
+	// Partial run: item-level errors are counted but the run completes
+	err = db.RecordCollectorRun("forwarding", func(run *CollectorRun) error {
+		run.ItemsInserted = 2
+		run.Errors = 1
+		run.ResumePoint = "1700000600"
+		return nil
+	})
+	testutils.AssertNoError(t, err)
+
+	// Failed run without a resume point
+	err = db.RecordCollectorRun("forwarding", func(run *CollectorRun) error {
+		return errors.New("lnd unavailable")
+	})
+	testutils.AssertError(t, err, "failed run should return its error")
+
+	err = db.RecordCollectorRun("strike-balance", func(run *CollectorRun) error {
+		run.ItemsInserted = 1
+		return nil
+	})
+	testutils.AssertNoError(t, err)
+
+	runs, err := db.GetCollectorRuns("forwarding", 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(runs), 3)
+
+	// Newest first
+	testutils.AssertEqual(t, runs[0].Status, CollectorRunFailed)
+	testutils.AssertEqual(t, runs[0].ErrorMessage, "lnd unavailable")
+	testutils.AssertEqual(t, runs[1].Status, CollectorRunPartial)
+	testutils.AssertEqual(t, runs[1].Errors, int64(1))
+	testutils.AssertEqual(t, runs[2].Status, CollectorRunSuccess)
+	testutils.AssertEqual(t, runs[2].ItemsInserted, int64(5))
+	if runs[2].FinishedAt == nil {
+		t.Error("Expected finished run to have finished_at")
+	}
+
+	all, err := db.GetCollectorRuns("", 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(all), 4)
+
+	// The failed run recorded no resume point, so the partial run's is used
+	resumePoint, err := db.GetLastResumePoint("forwarding")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, resumePoint, "1700000600")
+
+	resumePoint, err = db.GetLastResumePoint("cold-storage-snapshot")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, resumePoint, "")
+
+	// Runs are counted for the collectors' /metrics
+	err = db.RecordCollectorRun("metrics-test", func(run *CollectorRun) error {
+		run.ItemsInserted = 3
+		run.Errors = 1
+		return nil
+	})
+	testutils.AssertNoError(t, err)
+	var b strings.Builder
+	testutils.AssertNoError(t, metrics.Default.WritePrometheus(&b, "test"))
+	for _, line := range []string{
+		`test_collector_runs_total{collector="metrics-test",status="partial"} 1`,
+		`test_collector_items_inserted_total{collector="metrics-test"} 3`,
+		`test_collector_errors_total{collector="metrics-test"} 1`,
+		`test_collector_run_duration_seconds_count{collector="metrics-test"} 1`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestUpdateCollectorRunProgress(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	// An interrupted run keeps the progress saved before it stopped
+	run, err := db.StartCollectorRun("forwarding-catchup")
+	testutils.AssertNoError(t, err)
+	run.ItemsInserted = 40
+	run.ResumePoint = "1700000000"
+	testutils.AssertNoError(t, db.UpdateCollectorRunProgress(run))
+
+	runs, err := db.GetCollectorRuns("forwarding-catchup", 1)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, runs[0].Status, CollectorRunRunning)
+	testutils.AssertEqual(t, runs[0].ItemsInserted, int64(40))
+
+	resumePoint, err := db.GetLastResumePoint("forwarding-catchup")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, resumePoint, "1700000000")
+}
+
+func TestInsertForwardingEventIfNew(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	event := &ForwardingEvent{
+		Timestamp:    time.Now().Truncate(time.Second),
+		ChannelInID:  "123456789:1:0",
+		ChannelOutID: "987654321:1:0",
+		AmountIn:     100000,
+		AmountOut:    99800,
+		Fee:          200,
+	}
+
+	inserted, err := db.InsertForwardingEventIfNew(event)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, true)
+
+	inserted, err = db.InsertForwardingEventIfNew(event)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, false)
+}
+
+func TestInsertStreamedForwardingEvent(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	settled := time.Now().Add(-time.Hour).Truncate(time.Second)
+	streamed := &ForwardingEvent{
+		Timestamp:    settled.Add(300 * time.Millisecond),
+		ChannelInID:  "123456789:1:0",
+		ChannelOutID: "987654321:1:0",
+		AmountIn:     100200,
+		AmountOut:    100000,
+		Fee:          200,
+	}
+	inserted, err := db.InsertStreamedForwardingEvent(streamed)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, true)
+
+	// The stream reconnecting and seeing it again changes nothing
+	again := *streamed
+	inserted, err = db.InsertStreamedForwardingEvent(&again)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, false)
+
+	// The forwarding history's record replaces the streamed one
+	polled := &ForwardingEvent{
+		Timestamp:    settled,
+		ChannelInID:  "123456789:1:0",
+		ChannelOutID: "987654321:1:0",
+		AmountIn:     100,
+		AmountOut:    100,
+		Fee:          200,
+	}
+	inserted, err = db.InsertForwardingEventIfNew(polled)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, false)
+
+	// A forward of the same fee through other channels is its own
+	other := *polled
+	other.ChannelOutID = "555555555:1:0"
+	inserted, err = db.InsertForwardingEventIfNew(&other)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, true)
+
+	var events []ForwardingEvent
+	err = db.EachForwardingEvent(settled.Add(-time.Hour), settled.Add(time.Hour), func(event *ForwardingEvent) error {
+		events = append(events, *event)
+		return nil
+	})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(events), 2)
+	testutils.AssertEqual(t, events[0].ID, streamed.ID)
+	testutils.AssertEqual(t, events[0].Timestamp.Equal(settled), true)
+	testutils.AssertEqual(t, events[0].AmountIn, int64(100))
+
+	// Once replaced, the stream no longer matches it against the history
+	// but the row still keeps the forward from being stored twice
+	inserted, err = db.InsertStreamedForwardingEvent(&again)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, false)
+}
+
+func TestHTLCFailures(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	failures := []*HTLCFailure{
+		{Timestamp: now.Add(-2 * time.Hour), ChannelInID: "111:1:0", ChannelOutID: "222:1:0",
+			AmountIn: 50010, AmountOut: 50000, Fee: 10, Outcome: "failed"},
+		{Timestamp: now.Add(-time.Hour), ChannelInID: "111:1:0", ChannelOutID: "333:1:0",
+			AmountIn: 2000020, AmountOut: 2000000, Fee: 20, Outcome: "link_failed", Failure: "INSUFFICIENT_BALANCE"},
+	}
+	for _, failure := range failures {
+		testutils.AssertNoError(t, db.InsertHTLCFailure(failure))
+	}
+
+	got, err := db.GetHTLCFailures(now.Add(-3*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(got), 2)
+	testutils.AssertEqual(t, got[0].ID, failures[1].ID)
+	testutils.AssertEqual(t, got[0].Failure, "INSUFFICIENT_BALANCE")
+	testutils.AssertEqual(t, got[1].Outcome, "failed")
+
+	got, err = db.GetHTLCFailures(now.Add(-90*time.Minute), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(got), 1)
+
+	// Failures are kept apart from forwards
+	stats, err := db.GetForwardingStats(now.Add(-3*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stats.ForwardCount, int64(0))
+}
+
+func TestLightningBalancePoints(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	day1 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	points := []LightningBalancePoint{
+		{EventKey: "lightning_in:aa", Timestamp: day1, EventType: "lightning_in", Amount: 1000,
+			OnchainBalance: 50000, LightningLocal: 21000, LightningRemote: 79000},
+		{EventKey: "lightning_out:bb", Timestamp: day2, EventType: "lightning_out", Amount: -500,
+			OnchainBalance: 50000, LightningLocal: 20500, LightningRemote: 79500},
+	}
+
+	inserted, err := db.InsertLightningBalancePoints(points)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, int64(2))
+
+	// Stored events keep their balances when seen again
+	points[0].LightningLocal = 99999
+	inserted, err = db.InsertLightningBalancePoints(points)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, int64(0))
+
+	stored, err := db.GetLightningBalancePoints(day1, day2)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(stored), 2)
+	testutils.AssertEqual(t, stored[0].EventKey, "lightning_in:aa")
+	testutils.AssertEqual(t, stored[0].LightningLocal, int64(21000))
+	testutils.AssertEqual(t, stored[1].Amount, int64(-500))
+
+	at, err := db.GetLightningBalanceAt(day2.Add(-time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, at.EventKey, "lightning_in:aa")
+
+	_, err = db.GetLightningBalanceAt(day1.Add(-time.Hour))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before the first point, got %v", err)
+	}
+}
+
+func TestMissionControlSnapshots(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	older := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	latest := older.Add(time.Hour)
+
+	testutils.AssertNoError(t, db.InsertMissionControlSnapshot(older, []MissionControlPair{
+		{NodeFrom: "aa", NodeTo: "bb", FailTime: 1714560000, FailAmtMsat: 5000},
+	}))
+	testutils.AssertNoError(t, db.InsertMissionControlSnapshot(latest, []MissionControlPair{
+		{NodeFrom: "bb", NodeTo: "cc", SuccessTime: 1714563600, SuccessAmtMsat: 9000},
+		{NodeFrom: "aa", NodeTo: "bb", SuccessTime: 1714563000, SuccessAmtMsat: 1000},
+	}))
+
+	pairs, err := db.GetLatestMissionControlPairs("")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(pairs), 2)
+	testutils.AssertEqual(t, pairs[0].NodeFrom, "aa")
+	testutils.AssertEqual(t, pairs[0].SuccessAmtMsat, int64(1000))
+	testutils.AssertEqual(t, pairs[0].Timestamp.Equal(latest), true)
+
+	pairs, err = db.GetLatestMissionControlPairs("cc")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(pairs), 1)
+	testutils.AssertEqual(t, pairs[0].NodeFrom, "bb")
+
+	deleted, err := db.DeleteMissionControlSnapshotsBefore(latest)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, deleted, int64(1))
+}
+
+func TestPeerPolicies(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	policy, err := db.GetPeerPolicy("02aa")
+	testutils.AssertNoError(t, err)
+	if policy != nil {
+		t.Fatalf("expected no policy, got %+v", policy)
+	}
+
+	policy, err = db.SetPeerPolicy(PeerPolicy{Pubkey: "02aa", Blocklisted: true, Notes: "force closes"})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, policy.Blocklisted, true)
+	testutils.AssertEqual(t, policy.Notes, "force closes")
+
+	// Setting again replaces the policy
+	_, err = db.SetPeerPolicy(PeerPolicy{Pubkey: "02aa", Preferred: true})
+	testutils.AssertNoError(t, err)
+	_, err = db.SetPeerPolicy(PeerPolicy{Pubkey: "03bb", Preferred: true})
+	testutils.AssertNoError(t, err)
+
+	policies, err := db.GetPeerPolicies()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(policies), 2)
+	testutils.AssertEqual(t, policies[0].Pubkey, "02aa")
+	testutils.AssertEqual(t, policies[0].Blocklisted, false)
+	testutils.AssertEqual(t, policies[0].Preferred, true)
+	testutils.AssertEqual(t, policies[0].Notes, "")
+
+	testutils.AssertNoError(t, db.DeletePeerPolicy("02aa"))
+	if err := db.DeletePeerPolicy("02aa"); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows deleting a missing policy, got %v", err)
+	}
+}
+
+func TestPeerNotes(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	note, err := db.GetPeerNote("02aa")
+	testutils.AssertNoError(t, err)
+	if note != nil {
+		t.Fatalf("expected no note, got %+v", note)
+	}
+
+	note, err = db.SetPeerNote(PeerNote{Pubkey: "02aa", Contact: "@alice on Telegram",
+		Agreement: "we both keep 1k ppm", AgreedOn: "2024-03-01"})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, note.Contact, "@alice on Telegram")
+	testutils.AssertEqual(t, note.AgreedOn, "2024-03-01")
+
+	// Setting again replaces the note
+	_, err = db.SetPeerNote(PeerNote{Pubkey: "02aa", Notes: "slow to respond"})
+	testutils.AssertNoError(t, err)
+	_, err = db.SetPeerNote(PeerNote{Pubkey: "03bb", Contact: "bob@example.com"})
+	testutils.AssertNoError(t, err)
+
+	notes, err := db.GetPeerNotes()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(notes), 2)
+	testutils.AssertEqual(t, notes[0].Pubkey, "02aa")
+	testutils.AssertEqual(t, notes[0].Agreement, "")
+	testutils.AssertEqual(t, notes[0].Notes, "slow to respond")
+
+	testutils.AssertNoError(t, db.DeletePeerNote("02aa"))
+	if err := db.DeletePeerNote("02aa"); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows deleting a missing note, got %v", err)
+	}
+}
+
+func TestChannelPeers(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	pubkey, err := db.GetChannelPeer("100")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, pubkey, "")
+
+	testutils.AssertNoError(t, db.SetChannelPeers(map[string]string{"100": "02aa", "200": "03bb"}))
+	testutils.AssertNoError(t, db.SetChannelPeers(map[string]string{"100": "02cc"}))
+
+	pubkey, err = db.GetChannelPeer("100")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, pubkey, "02cc")
+	pubkey, err = db.GetChannelPeer("200")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, pubkey, "03bb")
+}
+
+func TestChannelNames(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	name, err := db.GetChannelName("100")
+	testutils.AssertNoError(t, err)
+	if name != nil {
+		t.Fatalf("expected no name, got %+v", name)
+	}
+
+	name, err = db.SetChannelName("100", "LOOP main")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, name.Name, "LOOP main")
+
+	// Naming again replaces the name
+	_, err = db.SetChannelName("100", "LOOP out")
+	testutils.AssertNoError(t, err)
+	_, err = db.SetChannelName("200", "Kraken drain")
+	testutils.AssertNoError(t, err)
+
+	names, err := db.GetChannelNameMap()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(names), 2)
+	testutils.AssertEqual(t, names["100"], "LOOP out")
+	testutils.AssertEqual(t, names["200"], "Kraken drain")
+
+	testutils.AssertNoError(t, db.DeleteChannelName("100"))
+	if err := db.DeleteChannelName("100"); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows deleting a missing name, got %v", err)
+	}
+}
+
+func TestReadChannelNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.db")
+
+	// No database is created just to read names
+	names, err := ReadChannelNames(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(names), 0)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no database at %s, got %v", path, err)
+	}
+
+	db, err := NewDatabase(path)
+	testutils.AssertNoError(t, err)
+	_, err = db.SetChannelName("100", "LOOP main")
+	testutils.AssertNoError(t, err)
+	db.Close()
+
+	names, err = ReadChannelNames(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, names["100"], "LOOP main")
+}
+
+func TestBackupBeforeMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.db")
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	// Nothing to back up before the first start
+	backup, err := BackupBeforeMigration(path, "abc1234", 2, now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, backup, "")
+
+	db, err := NewDatabase(path)
+	testutils.AssertNoError(t, err)
+	defer db.Close()
+	_, err = db.SetChannelName("100", "LOOP main")
+	testutils.AssertNoError(t, err)
+
+	var backups []string
+	for i := 0; i < 3; i++ {
+		backup, err := BackupBeforeMigration(path, "abc1234", 2, now.Add(time.Duration(i)*time.Hour))
+		testutils.AssertNoError(t, err)
+		backups = append(backups, backup)
+	}
+	testutils.AssertEqual(t, backups[0], filepath.Join(BackupDir(path), "pre-migration-20261018-120000-abc1234.db"))
+
+	// Only the two most recent are kept, and they hold the data
+	if _, err := os.Stat(backups[0]); !os.IsNotExist(err) {
+		t.Errorf("expected the oldest backup to be deleted, got %v", err)
+	}
+	names, err := ReadChannelNames(backups[2])
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, names["100"], "LOOP main")
+	kept, err := filepath.Glob(filepath.Join(BackupDir(path), "*.db"))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(kept), 2)
+}
+
+func TestSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.db")
+	db, err := NewDatabase(path)
+	testutils.AssertNoError(t, err)
+	defer db.Close()
+	_, err = db.SetChannelName("100", "LOOP main")
+	testutils.AssertNoError(t, err)
+
+	snapshot := SnapshotPath(path)
+	testutils.AssertEqual(t, filepath.Base(snapshot), "portfolio-snapshot.db")
+	testutils.AssertNoError(t, Snapshot(path, snapshot))
+
+	// Later changes show up in the next snapshot, which replaces the last
+	_, err = db.SetChannelName("200", "WoS drain")
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, Snapshot(path, snapshot))
+
+	names, err := ReadChannelNames(snapshot)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(names), 2)
+	info, err := os.Stat(snapshot)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, info.Mode().Perm(), os.FileMode(0444))
+	if _, err := os.Stat(snapshot + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected no temporary copy, got %v", err)
+	}
+}
+
+func TestChannelLeases(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	lease, err := db.GetChannelLease("100")
+	testutils.AssertNoError(t, err)
+	if lease != nil {
+		t.Fatalf("expected no lease, got %+v", lease)
+	}
+
+	started := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	lease, err = db.SetChannelLease(ChannelLease{
+		ChannelID: "100", Side: LeaseSideBought, Provider: "pool", Premium: 5000,
+		DurationBlocks: 4032, ExpiryHeight: 840000, StartedAt: &started,
+	})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, lease.Premium, int64(5000))
+	testutils.AssertEqual(t, lease.StartedAt.Equal(started), true)
+
+	// A detected lease does not replace the terms entered by hand
+	added, err := db.RecordDetectedChannelLease(ChannelLease{ChannelID: "100", Side: LeaseSideSold, ExpiryHeight: 1})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, added, false)
+	added, err = db.RecordDetectedChannelLease(ChannelLease{ChannelID: "200", Side: LeaseSideSold, Provider: "pool", ExpiryHeight: 830000})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, added, true)
+
+	leases, err := db.GetChannelLeases()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(leases), 2)
+	testutils.AssertEqual(t, leases[0].ChannelID, "200")
+	if leases[0].StartedAt != nil {
+		t.Errorf("expected no start date for a detected lease, got %v", leases[0].StartedAt)
+	}
+	testutils.AssertEqual(t, leases[1].Side, LeaseSideBought)
+
+	testutils.AssertNoError(t, db.DeleteChannelLease("200"))
+	if err := db.DeleteChannelLease("200"); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows deleting a missing lease, got %v", err)
+	}
+}
+
+func TestGetChannelFeeStats(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	for _, event := range []ForwardingEvent{
+		{Timestamp: now.Add(-48 * time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 100010, AmountOut: 100000, Fee: 10},
+		{Timestamp: now.Add(-2 * time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 50020, AmountOut: 50000, Fee: 20},
+		{Timestamp: now.Add(-time.Hour), ChannelInID: "100", ChannelOutID: "1", AmountIn: 10040, AmountOut: 10000, Fee: 40},
+	} {
+		event := event
+		testutils.AssertNoError(t, db.InsertForwardingEvent(&event))
+	}
+
+	stats, err := db.GetChannelFeeStats("100", now.Add(-24*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stats.ForwardCount, int64(1))
+	testutils.AssertEqual(t, stats.Fees, int64(20))
+
+	stats, err = db.GetChannelFeeStats("100", time.Time{}, now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stats.Fees, int64(30))
+	testutils.AssertEqual(t, stats.Volume, int64(150000))
+}
+
+func TestWithContextTracesQueries(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	type span struct {
+		Name         string `json:"name"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+	}
+	var spans []span
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		testutils.AssertNoError(t, json.NewDecoder(r.Body).Decode(&request))
+		spans = append(spans, request.ResourceSpans[0].ScopeSpans[0].Spans...)
+	}))
+	defer collector.Close()
+	tracer, err := tracing.New(collector.URL, "test", 1)
+	testutils.AssertNoError(t, err)
+
+	tracer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := db.WithContext(r.Context()).GetOnchainAddresses()
+		testutils.AssertNoError(t, err)
+		// The original stays untraced
+		_, err = db.GetOnchainAddresses()
+		testutils.AssertNoError(t, err)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	tracer.Close()
+
+	testutils.AssertEqual(t, len(spans), 2)
+	testutils.AssertEqual(t, spans[0].Name, "sqlite SELECT")
+	testutils.AssertEqual(t, spans[0].ParentSpanID, spans[1].SpanID)
+}
+
+func TestGetFeesByChannel(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	testutils.AssertNoError(t, db.InsertChannelSnapshots([]ChannelSnapshot{
+		{Timestamp: now.Add(-2 * time.Hour), ChannelID: "100", Capacity: 1000000, PeerAlias: "old alias"},
+		{Timestamp: now.Add(-2 * time.Hour), ChannelID: "1", Capacity: 1000000, PeerAlias: "inbound"},
+	}))
+	// A delta row that keeps the balance change only
+	testutils.AssertNoError(t, db.InsertChannelSnapshots([]ChannelSnapshot{
+		{Timestamp: now.Add(-time.Hour), ChannelID: "100", Capacity: 1000000, LocalBalance: 5, PeerAlias: "new alias"},
+		{Timestamp: now.Add(-time.Hour), ChannelID: "1", Capacity: 1000000, LocalBalance: 5, PeerAlias: "inbound"},
+	}))
+	for _, event := range []ForwardingEvent{
+		{Timestamp: now.Add(-48 * time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 100010, AmountOut: 100000, Fee: 10},
+		{Timestamp: now.Add(-2 * time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 50020, AmountOut: 50000, Fee: 20},
+		{Timestamp: now.Add(-time.Hour), ChannelInID: "100", ChannelOutID: "200", AmountIn: 10040, AmountOut: 10000, Fee: 40},
+	} {
+		event := event
+		testutils.AssertNoError(t, db.InsertForwardingEvent(&event))
+	}
+	_, err := db.SetChannelName("200", "Kraken drain")
+	testutils.AssertNoError(t, err)
+
+	channels, err := db.GetFeesByChannel(now.Add(-24*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(channels), 3)
+	// 200 earned the most, 100 earned and also brought in a forward
+	testutils.AssertEqual(t, channels[0], ChannelFees{ChannelID: "200", Name: "Kraken drain", OutForwardCount: 1, OutVolume: 10000, OutFees: 40})
+	testutils.AssertEqual(t, channels[1], ChannelFees{ChannelID: "100", PeerAlias: "new alias",
+		OutForwardCount: 1, OutVolume: 50000, OutFees: 20, InForwardCount: 1, InVolume: 10040, InFees: 40})
+	// An alias unchanged since the keyframe is read from it
+	testutils.AssertEqual(t, channels[2], ChannelFees{ChannelID: "1", PeerAlias: "inbound", InForwardCount: 1, InVolume: 50020, InFees: 20})
+
+	channels, err = db.GetFeesByChannel(now.Add(-time.Minute), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(channels), 0)
+}
+
+func TestGetChannelLastForward(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, event := range []ForwardingEvent{
+		{Timestamp: now.Add(-48 * time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 100010, AmountOut: 100000, Fee: 10},
+		{Timestamp: now.Add(-time.Hour), ChannelInID: "100", ChannelOutID: "1", AmountIn: 10040, AmountOut: 10000, Fee: 40},
+	} {
+		event := event
+		testutils.AssertNoError(t, db.InsertForwardingEvent(&event))
+	}
+
+	// Forwards into the channel count as well as those out of it
+	last, err := db.GetChannelLastForward("100", now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, last.Equal(now.Add(-time.Hour)), true)
+
+	last, err = db.GetChannelLastForward("100", now.Add(-24*time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, last.Equal(now.Add(-48*time.Hour)), true)
+
+	last, err = db.GetChannelLastForward("200", now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, last == nil, true)
+}
+
+func TestProbeStats(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, result := range []ProbeResult{
+		{Timestamp: now.Add(-48 * time.Hour), Destination: "02aa", Amount: 1000, Success: false, FailureReason: "FAILURE_REASON_NO_ROUTE"},
+		{Timestamp: now.Add(-3 * time.Hour), Destination: "02aa", Amount: 1000, Success: true, LatencyMs: 800, Hops: 3},
+		{Timestamp: now.Add(-2 * time.Hour), Destination: "02aa", Amount: 1000, Success: true, LatencyMs: 1200, Hops: 2},
+		{Timestamp: now.Add(-time.Hour), Destination: "03bb", Amount: 1000, Success: false, FailureReason: "FAILURE_REASON_TIMEOUT"},
+	} {
+		result := result
+		testutils.AssertNoError(t, db.InsertProbeResult(&result))
+	}
+
+	results, err := db.GetProbeResults(now.Add(-24*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(results), 3)
+	testutils.AssertEqual(t, results[2].FailureReason, "FAILURE_REASON_TIMEOUT")
+
+	overall, destinations, err := db.GetProbeStats(now.Add(-24*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, overall.Probes, int64(3))
+	testutils.AssertEqual(t, overall.Successes, int64(2))
+	testutils.AssertEqual(t, overall.AvgLatencyMs, int64(1000))
+	testutils.AssertEqual(t, overall.LastProbe.Equal(now.Add(-time.Hour)), true)
+	testutils.AssertEqual(t, len(destinations), 2)
+	testutils.AssertEqual(t, destinations[0].Destination, "02aa")
+	testutils.AssertEqual(t, destinations[0].SuccessRate, 1.0)
+	testutils.AssertEqual(t, destinations[1].SuccessRate, 0.0)
+	testutils.AssertEqual(t, destinations[1].AvgLatencyMs, int64(0))
+
+	overall, destinations, err = db.GetProbeStats(now.Add(time.Hour), now.Add(2*time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, overall.Probes, int64(0))
+	testutils.AssertEqual(t, len(destinations), 0)
+}
+
+func TestTimestampQuarantine(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// A misparsed unix timestamp and one from next year are quarantined
+	for _, timestamp := range []time.Time{time.Unix(0, 0), now.Add(365 * 24 * time.Hour)} {
+		inserted, err := db.InsertForwardingEventIfNew(&ForwardingEvent{
+			Timestamp: timestamp, ChannelInID: "111:1:0", ChannelOutID: "222:1:0", AmountIn: 1000, AmountOut: 999, Fee: 1,
+		})
+		testutils.AssertEqual(t, inserted, false)
+		testutils.AssertEqual(t, errors.Is(err, ErrQuarantined), true)
+	}
+
+	// A node clock slightly ahead is tolerated
+	inserted, err := db.InsertForwardingEventIfNew(&ForwardingEvent{
+		Timestamp: now.Add(time.Minute), ChannelInID: "111:1:0", ChannelOutID: "222:1:0", AmountIn: 1000, AmountOut: 999, Fee: 1,
+	})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, true)
+
+	// Bad rows in a batch are skipped and the rest written
+	err = db.InsertBalanceSnapshots([]BalanceSnapshot{
+		{Timestamp: now.Add(-time.Hour), TotalPortfolio: 1000},
+		{Timestamp: time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC), TotalPortfolio: 2000},
+	})
+	testutils.AssertNoError(t, err)
+	snapshots, err := db.GetBalanceSnapshots(time.Unix(0, 0), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(snapshots), 1)
+
+	counts, err := db.GetQuarantineCounts()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, counts["forwarding_events"], int64(2))
+	testutils.AssertEqual(t, counts["balance_snapshots"], int64(1))
+
+	records, err := db.GetQuarantinedRecords("forwarding_events", 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(records), 2)
+	testutils.AssertEqual(t, strings.Contains(records[0].Reason, "in the future"), true)
+	testutils.AssertEqual(t, strings.Contains(records[1].Reason, "before 2018-01-01"), true)
+	testutils.AssertEqual(t, strings.Contains(records[1].Payload, `"channel_in_id":"111:1:0"`), true)
+}
+
+func TestNodeVersions(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	for _, tt := range []struct {
+		node, version string
+		changed       bool
+	}{
+		{"lnd", "0.17.0-beta", true},
+		{"lnd", "0.17.0-beta", false},
+		{"bitcoin_core", "26.0.0", true},
+		{"lnd", "0.18.3-beta", true},
+	} {
+		changed, err := db.RecordNodeVersion(tt.node, tt.version)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, changed, tt.changed)
+	}
+
+	versions, err := db.GetNodeVersions()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(versions), 2)
+	testutils.AssertEqual(t, versions[0].Node, "bitcoin_core")
+	testutils.AssertEqual(t, versions[1].Version, "0.18.3-beta")
+}
+
+func TestPortfolioTransfers(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	deposit := &PortfolioTransfer{Timestamp: now.Add(-48 * time.Hour), Amount: 1000000, Notes: "DCA buy"}
+	testutils.AssertNoError(t, db.InsertPortfolioTransfer(deposit))
+	if deposit.ID == 0 {
+		t.Fatal("expected the transfer ID to be set")
+	}
+	testutils.AssertNoError(t, db.InsertPortfolioTransfer(&PortfolioTransfer{Timestamp: now, Amount: -250000}))
+
+	transfers, err := db.GetPortfolioTransfers(now.Add(-72*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(transfers), 2)
+	testutils.AssertEqual(t, transfers[0].Amount, int64(1000000))
+	testutils.AssertEqual(t, transfers[0].Notes, "DCA buy")
+	testutils.AssertEqual(t, transfers[1].Amount, int64(-250000))
+
+	transfers, err = db.GetPortfolioTransfers(now.Add(-24*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(transfers), 1)
+
+	testutils.AssertNoError(t, db.DeletePortfolioTransfer(deposit.ID))
+	if err := db.DeletePortfolioTransfer(deposit.ID); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows deleting a missing transfer, got %v", err)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	bought := &Annotation{Timestamp: now.Add(-48 * time.Hour), Text: "bought hardware wallet"}
+	testutils.AssertNoError(t, db.InsertAnnotation(bought))
+	if bought.ID == 0 {
+		t.Fatal("expected the annotation ID to be set")
+	}
+	testutils.AssertNoError(t, db.InsertAnnotation(&Annotation{Timestamp: now, Text: "opened 3 channels"}))
+
+	annotations, err := db.GetAnnotations(now.Add(-72*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(annotations), 2)
+	testutils.AssertEqual(t, annotations[0].Text, "bought hardware wallet")
+	testutils.AssertEqual(t, annotations[1].Text, "opened 3 channels")
+
+	annotations, err = db.GetAnnotations(now.Add(-24*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(annotations), 1)
+
+	testutils.AssertNoError(t, db.DeleteAnnotation(bought.ID))
+	if err := db.DeleteAnnotation(bought.ID); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows deleting a missing annotation, got %v", err)
+	}
+}
+
+func TestMultisigWallets(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	vault := &MultisigWallet{Name: "Vault", Descriptor: "wsh(sortedmulti(2,...))#abcdefgh", Format: "Caravan wallet config"}
+	testutils.AssertNoError(t, db.InsertMultisigWallet(vault))
+	if vault.ID == 0 {
+		t.Fatal("expected the wallet ID to be set")
+	}
+	testutils.AssertNoError(t, db.InsertMultisigWallet(&MultisigWallet{Name: "Inheritance", Descriptor: "wsh(multi(1,...))", Format: "descriptor"}))
+
+	// Names are unique
+	testutils.AssertError(t, db.InsertMultisigWallet(&MultisigWallet{Name: "Vault", Descriptor: "x", Format: "descriptor"}), "duplicate name")
+
+	wallets, err := db.GetMultisigWallets()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(wallets), 2)
+	testutils.AssertEqual(t, wallets[0].Name, "Inheritance")
+
+	stored, err := db.GetMultisigWallet(vault.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stored.Descriptor, vault.Descriptor)
+	testutils.AssertEqual(t, stored.Format, vault.Format)
+	testutils.AssertEqual(t, stored.NextAddressIndex, uint32(0))
+	if _, err := db.GetMultisigWallet(vault.ID + 100); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows for a missing wallet, got %v", err)
+	}
+
+	// The next address index only moves forward
+	advanced, err := db.AdvanceMultisigWalletIndex(vault.ID, 5)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, advanced, true)
+	advanced, err = db.AdvanceMultisigWalletIndex(vault.ID, 3)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, advanced, false)
+	stored, err = db.GetMultisigWallet(vault.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stored.NextAddressIndex, uint32(5))
+}
+
+func TestStatementsAreImmutable(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	statement := &Statement{
+		Month:          "2024-05",
+		PeriodStart:    start,
+		PeriodEnd:      start.AddDate(0, 1, 0).Add(-time.Second),
+		ColdStorage:    5000000,
+		TotalPortfolio: 5000000,
+		ForwardingFees: 1200,
+	}
+	testutils.AssertNoError(t, db.InsertStatement(statement))
+
+	err := db.InsertStatement(&Statement{Month: "2024-05", PeriodStart: start, PeriodEnd: start})
+	if err != ErrStatementExists {
+		t.Fatalf("expected ErrStatementExists closing a month twice, got %v", err)
+	}
+
+	stored, err := db.GetStatement("2024-05")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stored.ColdStorage, int64(5000000))
+	testutils.AssertEqual(t, stored.ForwardingFees, int64(1200))
+
+	missing, err := db.GetStatement("2024-06")
+	testutils.AssertNoError(t, err)
+	if missing != nil {
+		t.Fatalf("expected no statement, got %+v", missing)
+	}
+
+	_, err = db.conn.Exec(`UPDATE statements SET total_portfolio = 0`)
+	testutils.AssertError(t, err, "statements are immutable")
+	_, err = db.conn.Exec(`DELETE FROM statements`)
+	testutils.AssertError(t, err, "statements are immutable")
+
+	statements, err := db.GetStatements()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(statements), 1)
+}
+
+func TestGetTrackedAddressTotalAt(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first, err := db.InsertOnchainAddress("bc1qfirst", "first")
+	testutils.AssertNoError(t, err)
+	second, err := db.InsertOnchainAddress("bc1qsecond", "second")
+	testutils.AssertNoError(t, err)
+
+	testutils.AssertNoError(t, db.InsertAddressBalance(&AddressBalance{AddressID: first.ID, Timestamp: now.Add(-72 * time.Hour), Balance: 1000}))
+	testutils.AssertNoError(t, db.InsertAddressBalance(&AddressBalance{AddressID: first.ID, Timestamp: now, Balance: 3000}))
+	testutils.AssertNoError(t, db.InsertAddressBalance(&AddressBalance{AddressID: second.ID, Timestamp: now.Add(-48 * time.Hour), Balance: 500}))
+
+	total, err := db.GetTrackedAddressTotalAt(now.Add(-24 * time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, total, int64(1500))
+
+	total, err = db.GetTrackedAddressTotalAt(now.Add(-96 * time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, total, int64(0))
+}
+
+func TestGetAddressBalancesAt(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first, err := db.InsertOnchainAddress("bc1qfirst", "first")
+	testutils.AssertNoError(t, err)
+	second, err := db.InsertOnchainAddress("bc1qsecond", "second")
+	testutils.AssertNoError(t, err)
+	late, err := db.InsertOnchainAddress("bc1qlate", "late")
+	testutils.AssertNoError(t, err)
+
+	testutils.AssertNoError(t, db.InsertAddressBalance(&AddressBalance{AddressID: first.ID, Timestamp: now.Add(-72 * time.Hour), Balance: 1000}))
+	testutils.AssertNoError(t, db.InsertAddressBalance(&AddressBalance{AddressID: first.ID, Timestamp: now.Add(-time.Hour), Balance: 3000}))
+	testutils.AssertNoError(t, db.InsertAddressBalance(&AddressBalance{AddressID: second.ID, Timestamp: now.Add(-48 * time.Hour), Balance: 500}))
+	testutils.AssertNoError(t, db.InsertAddressBalance(&AddressBalance{AddressID: late.ID, Timestamp: now.Add(-time.Hour), Balance: 700}))
+	testutils.AssertNoError(t, db.DeleteOnchainAddress(second.ID))
+
+	// Only addresses with a balance by then, including the since deleted one
+	balances, err := db.GetAddressBalancesAt(now.Add(-24 * time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 2)
+	testutils.AssertEqual(t, balances[0].Address, "bc1qfirst")
+	testutils.AssertEqual(t, balances[0].Balance, int64(1000))
+	testutils.AssertEqual(t, balances[0].RecordedAt.Equal(now.Add(-72*time.Hour)), true)
+	testutils.AssertEqual(t, balances[1].Label, "second")
+	testutils.AssertEqual(t, balances[1].Balance, int64(500))
+
+	balances, err = db.GetAddressBalancesAt(now.Add(time.Minute))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 2)
+	testutils.AssertEqual(t, balances[0].Balance, int64(3000))
+	testutils.AssertEqual(t, balances[1].Address, "bc1qlate")
+}
+
+func TestGetStrikeBalancesAt(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, snapshot := range []StrikeBalanceSnapshot{
+		{Timestamp: now.Add(-48 * time.Hour), Currency: "BTC", Unit: StrikeUnitSats, Total: 100000},
+		{Timestamp: now.Add(-time.Hour), Currency: "BTC", Unit: StrikeUnitSats, Total: 150000},
+		{Timestamp: now.Add(-time.Hour), Currency: "USD", Unit: StrikeUnitCents, Total: 2500},
+	} {
+		snapshot := snapshot
+		testutils.AssertNoError(t, db.InsertStrikeBalanceSnapshot(&snapshot))
+	}
+
+	balances, err := db.GetStrikeBalancesAt(now.Add(-24 * time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 1)
+	testutils.AssertEqual(t, balances[0].Total, int64(100000))
+
+	balances, err = db.GetStrikeBalancesAt(now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 2)
+	testutils.AssertEqual(t, balances[0].Total, int64(150000))
+	testutils.AssertEqual(t, balances[1].Currency, "USD")
+}
+
+func TestChannelSnapshotDeltas(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	start := time.Now().UTC().Truncate(time.Second).Add(-48 * time.Hour)
+	snapshot := func(offset time.Duration, local int64) ChannelSnapshot {
+		return ChannelSnapshot{
+			Timestamp: start.Add(offset), ChannelID: "100", Capacity: 1000000,
+			LocalBalance: local, RemoteBalance: 1000000 - local, Active: true,
+			PeerAlias: "peer", FeePPM: 250, BaseFee: 1000,
+		}
+	}
+	changedColumns := func() []sql.NullInt64 {
+		rows, err := db.conn.Query(`SELECT changed FROM channel_snapshots ORDER BY timestamp, id`)
+		testutils.AssertNoError(t, err)
+		defer rows.Close()
+		var changed []sql.NullInt64
+		for rows.Next() {
+			var c sql.NullInt64
+			testutils.AssertNoError(t, rows.Scan(&c))
+			changed = append(changed, c)
+		}
+		return changed
+	}
+
+	testutils.AssertNoError(t, db.InsertChannelSnapshots([]ChannelSnapshot{
+		snapshot(0, 600000),
+		snapshot(10*time.Minute, 550000),
+		snapshot(20*time.Minute, 550000),
+	}))
+
+	// A keyframe, then only what changed
+	changed := changedColumns()
+	testutils.AssertEqual(t, len(changed), 3)
+	testutils.AssertEqual(t, changed[0].Valid, false)
+	testutils.AssertEqual(t, changed[1].Int64, int64(changedLocalBalance|changedRemoteBalance))
+	testutils.AssertEqual(t, changed[2].Int64, int64(0))
+
+	snapshots, err := db.GetChannelSnapshots("100", start.Add(15*time.Minute), start.Add(time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(snapshots), 1)
+	want := snapshot(20*time.Minute, 550000)
+	want.ID = snapshots[0].ID
+	testutils.AssertEqual(t, snapshots[0].Timestamp.Equal(want.Timestamp), true)
+	snapshots[0].Timestamp = want.Timestamp
+	testutils.AssertEqual(t, snapshots[0], want)
+
+	// A snapshot older than the latest is stored in full, as is the one after it
+	testutils.AssertNoError(t, db.InsertChannelSnapshots([]ChannelSnapshot{snapshot(15*time.Minute, 500000)}))
+	changed = changedColumns()
+	testutils.AssertEqual(t, changed[2].Valid, false)
+	testutils.AssertEqual(t, changed[3].Valid, false)
+
+	snapshots, err = db.GetChannelSnapshots("100", start, start.Add(time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(snapshots), 4)
+	for i, local := range []int64{600000, 550000, 500000, 550000} {
+		testutils.AssertEqual(t, snapshots[i].LocalBalance, local)
+		testutils.AssertEqual(t, snapshots[i].PeerAlias, "peer")
+	}
+
+	// Keyframes recur so reads never rebuild more than an interval of rows
+	testutils.AssertNoError(t, db.InsertChannelSnapshots([]ChannelSnapshot{
+		snapshot(ChannelKeyframeInterval+20*time.Minute, 500000),
+		snapshot(ChannelKeyframeInterval+25*time.Minute, 500000),
+	}))
+	changed = changedColumns()
+	testutils.AssertEqual(t, changed[4].Valid, false)
+	testutils.AssertEqual(t, changed[5].Int64, int64(0))
+
+	latest, err := db.GetLatestChannelSnapshots()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(latest), 1)
+	testutils.AssertEqual(t, latest[0].LocalBalance, int64(500000))
+	testutils.AssertEqual(t, latest[0].FeePPM, int64(250))
+	testutils.AssertEqual(t, latest[0].Active, true)
+}
+
+func TestDailySummaries(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC()
+	today := utcDay(now)
+	for _, event := range []ForwardingEvent{
+		{Timestamp: today.AddDate(0, 0, -3).Add(10 * time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 100010, AmountOut: 100000, Fee: 10},
+		{Timestamp: today.AddDate(0, 0, -3).Add(12 * time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 100020, AmountOut: 100000, Fee: 20},
+		{Timestamp: today.AddDate(0, 0, -2).Add(5 * time.Hour), ChannelInID: "100", ChannelOutID: "1", AmountIn: 50005, AmountOut: 50000, Fee: 5},
+		{Timestamp: today.Add(time.Minute), ChannelInID: "100", ChannelOutID: "1", AmountIn: 10001, AmountOut: 10000, Fee: 1},
+	} {
+		event := event
+		testutils.AssertNoError(t, db.InsertForwardingEvent(&event))
+	}
+
+	from := today.AddDate(0, 0, -3).Add(11 * time.Hour)
+	live, err := db.GetForwardingEventsFees(from, now)
+	testutils.AssertNoError(t, err)
+
+	testutils.AssertNoError(t, db.MaterializeDailySummaries(now))
+	var days int
+	testutils.AssertNoError(t, db.conn.QueryRow(`SELECT COUNT(*) FROM forwarding_daily`).Scan(&days))
+	testutils.AssertEqual(t, days, 2)
+
+	// Summaries serve whole days; the partial first day and today are still scanned
+	summarized, err := db.GetForwardingEventsFees(from, now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(summarized), 3)
+	testutils.AssertEqual(t, len(summarized), len(live))
+	for i := range live {
+		testutils.AssertEqual(t, summarized[i], live[i])
+	}
+	testutils.AssertEqual(t, summarized[0].TotalFee, int64(20))
+
+	// Nothing new to materialize until the next day
+	testutils.AssertNoError(t, db.MaterializeDailySummaries(now))
+	runs, err := db.GetCollectorRuns(dailySummaryCollector, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(runs), 1)
+
+	// A forward found later updates its day's summary
+	late := ForwardingEvent{Timestamp: today.AddDate(0, 0, -2).Add(6 * time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 70007, AmountOut: 70000, Fee: 7}
+	inserted, err := db.InsertForwardingEventIfNew(&late)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, true)
+
+	summarized, err = db.GetForwardingEventsFees(from, now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, summarized[1], DailyFeeData{Date: today.AddDate(0, 0, -2).Format(dayLayout), TotalFee: 12, ForwardCount: 2})
+}
+
+func TestIntegrityChecks(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	issues, err := db.CheckIntegrity()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(issues), 0)
+
+	address, err := db.InsertOnchainAddress("bc1qintegrity", "test")
+	testutils.AssertNoError(t, err)
+	now := time.Now()
+	for _, balance := range []AddressBalance{
+		{AddressID: address.ID, Timestamp: now, Balance: 1000},
+		{AddressID: address.ID, Timestamp: now, Balance: -5},
+		{AddressID: 999, Timestamp: now, Balance: 2000},
+	} {
+		balance := balance
+		testutils.AssertNoError(t, db.InsertAddressBalance(&balance))
+	}
+	for _, event := range []ForwardingEvent{
+		{Timestamp: now, ChannelInID: "1", ChannelOutID: "2", AmountIn: 100010, AmountOut: 100000, Fee: 10},
+		{Timestamp: now, ChannelInID: "1", ChannelOutID: "3", AmountIn: 100000, AmountOut: 100500, Fee: 0},
+		{Timestamp: now, ChannelInID: "1", ChannelOutID: "4", AmountIn: 100010, AmountOut: 100000, Fee: 500},
+	} {
+		event := event
+		testutils.AssertNoError(t, db.InsertForwardingEvent(&event))
+	}
+
+	issues, err = db.CheckIntegrity()
+	testutils.AssertNoError(t, err)
+	found := make(map[string]IntegrityIssue)
+	for _, issue := range issues {
+		found[issue.Check] = issue
+	}
+	testutils.AssertEqual(t, len(found), 4)
+	testutils.AssertEqual(t, found["orphaned-address-balances"].Count, int64(1))
+	testutils.AssertEqual(t, found["negative-address-balances"].Count, int64(1))
+	testutils.AssertEqual(t, found["forward-amounts"].Count, int64(1))
+	testutils.AssertEqual(t, found["forward-fees"].Count, int64(1))
+
+	// Deleted addresses keep their history in case they are restored
+	testutils.AssertNoError(t, db.DeleteOnchainAddress(address.ID))
+	issues, err = db.CheckIntegrity()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(issues), 4)
+
+	// Repairs move the rows into quarantine
+	moved, err := db.RepairIntegrityIssue("orphaned-address-balances")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, moved, int64(1))
+	records, err := db.GetQuarantinedRecords("address_balances", 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(records), 1)
+	testutils.AssertEqual(t, strings.Contains(records[0].Payload, `"address_id":999`), true)
+	testutils.AssertEqual(t, records[0].RecordTimestamp.Equal(now), true)
+
+	issues, err = db.CheckIntegrity()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(issues), 3)
+
+	_, err = db.RepairIntegrityIssue("no-such-check")
+	testutils.AssertError(t, err, "unknown integrity check")
+}
+
+func TestCheckMode(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+	realDB, err := NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer realDB.Close()
+	mockDB, err := NewDatabaseWithMockMode(dbPath, true)
+	testutils.AssertNoError(t, err)
+	defer mockDB.Close()
+
+	now := time.Now()
+	testutils.AssertNoError(t, realDB.CheckMode(now))
+
+	record := func(database *Database, runs int) {
+		for i := 0; i < runs; i++ {
+			testutils.AssertNoError(t, database.RecordCollectorRun("test", func(*CollectorRun) error { return nil }))
+		}
+	}
+
+	// A few mock runs on the node's database are fine
+	record(realDB, 1)
+	record(mockDB, 3)
+	testutils.AssertNoError(t, realDB.CheckMode(now))
+	testutils.AssertNoError(t, mockDB.CheckMode(now))
+
+	// A database used almost only in mock mode refuses real collectors
+	record(mockDB, 3)
+	err = realDB.CheckMode(now)
+	testutils.AssertEqual(t, errors.Is(err, ErrModeMismatch), true)
+	testutils.AssertError(t, err, "6 of the 7 collector runs in the last 7 days were in mock mode")
+	testutils.AssertNoError(t, mockDB.CheckMode(now))
+
+	// Old runs no longer count
+	testutils.AssertNoError(t, realDB.CheckMode(now.Add(ModeCheckWindow+time.Hour)))
+}
+
+func TestDryRun(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+	database, err := NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	seedTestData(t, database)
+	before, err := database.GetBalanceSnapshots(time.Time{}, time.Now())
+	testutils.AssertNoError(t, err)
+	database.Close()
+
+	dryRun, err := OpenDryRun(dbPath, false)
+	testutils.AssertNoError(t, err)
+	copied, err := dryRun.GetBalanceSnapshots(time.Time{}, time.Now())
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(copied), len(before))
+
+	changes, err := dryRun.DryRunChanges()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(changes), 0)
+
+	for i := 0; i < DryRunSampleSize+1; i++ {
+		snapshot := &BalanceSnapshot{Timestamp: time.Now().Add(-time.Duration(i) * time.Minute), LightningLocal: 4200}
+		testutils.AssertNoError(t, dryRun.InsertBalanceSnapshot(snapshot))
+	}
+	changes, err = dryRun.DryRunChanges()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(changes), 1)
+	testutils.AssertEqual(t, changes[0].Table, "balance_snapshots")
+	testutils.AssertEqual(t, changes[0].Inserted, int64(DryRunSampleSize+1))
+	testutils.AssertEqual(t, changes[0].Deleted, int64(0))
+	testutils.AssertEqual(t, len(changes[0].Samples), DryRunSampleSize)
+	testutils.AssertEqual(t, changes[0].Samples[0]["lightning_local"], int64(4200))
+	dryRun.LogDryRun(true)
+	dryRun.Close()
+
+	// Nothing reached the real database
+	database, err = NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+	after, err := database.GetBalanceSnapshots(time.Time{}, time.Now())
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(after), len(before))
+	_, err = database.DryRunChanges()
+	testutils.AssertError(t, err, "not opened for a dry run")
+
+	// A dry run against a database that does not exist yet leaves it missing
+	missing := dbPath + ".missing"
+	dryRun, err = OpenDryRun(missing, true)
+	testutils.AssertNoError(t, err)
+	dryRun.Close()
+	_, err = os.Stat(missing)
+	testutils.AssertEqual(t, os.IsNotExist(err), true)
+}
+
+func TestProfilePath(t *testing.T) {
+	path, err := ProfilePath("data/portfolio.db", DefaultProfile)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, path, "data/portfolio.db")
+
+	path, err = ProfilePath("data/portfolio.db", "business")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, path, "data/portfolio-business.db")
+
+	path, err = ProfilePath("/var/lib/lnt/portfolio", "llc_2")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, path, "/var/lib/lnt/portfolio-llc_2")
+
+	for _, profile := range []string{"", "Business", "../other", "a b", "-x", strings.Repeat("a", 33)} {
+		_, err := ProfilePath("data/portfolio.db", profile)
+		testutils.AssertError(t, err, "invalid profile")
+	}
+}
+
+func TestStrikeBalanceUnits(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+
+	// Older versions stored sats and cents alike, without a unit
+	conn, err := sql.Open("sqlite3", dbPath)
+	testutils.AssertNoError(t, err)
+	_, err = conn.Exec(`CREATE TABLE strike_balance_snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL,
+		currency TEXT NOT NULL,
+		available INTEGER NOT NULL,
+		total INTEGER NOT NULL,
+		pending INTEGER NOT NULL,
+		reserved INTEGER NOT NULL
+	)`)
+	testutils.AssertNoError(t, err)
+	old := time.Now().Add(-time.Hour)
+	_, err = conn.Exec(`INSERT INTO strike_balance_snapshots (timestamp, currency, available, total, pending, reserved)
+		VALUES (?, 'BTC', 100000, 100000, 0, 0), (?, 'USD', 5000, 5000, 0, 0)`, old, old)
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, conn.Close())
+
+	db, err := NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer db.Close()
+
+	testutils.AssertNoError(t, db.InsertStrikeBalanceSnapshot(&StrikeBalanceSnapshot{Timestamp: time.Now(), Currency: "USD", Total: 7500}))
+	err = db.InsertStrikeBalanceSnapshot(&StrikeBalanceSnapshot{Timestamp: time.Now(), Currency: "EUR", Unit: StrikeUnitSats, Total: 1})
+	testutils.AssertError(t, err, "are in cents")
+
+	balances, err := db.GetLatestStrikeBalances()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 2)
+	testutils.AssertEqual(t, balances[0].Unit, StrikeUnitSats)
+	testutils.AssertEqual(t, balances[1].Unit, StrikeUnitCents)
+	testutils.AssertEqual(t, balances[1].Total, int64(7500))
+
+	total, _, err := balances[0].Sats()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, total, int64(100000))
+	_, _, err = balances[1].Sats()
+	testutils.AssertEqual(t, errors.Is(err, ErrNotSats), true)
+}
+
+func TestStrikeWithdrawals(t *testing.T) {
+	db, err := NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer db.Close()
+
+	withdrawal := &StrikeWithdrawal{Amount: 500000, DestinationType: WithdrawalLightning, Status: WithdrawalPendingApproval}
+	testutils.AssertNoError(t, db.InsertStrikeWithdrawal(withdrawal))
+
+	open, err := db.GetStrikeWithdrawals(WithdrawalPendingApproval, WithdrawalApproved)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(open), 1)
+
+	testutils.AssertNoError(t, db.TransitionStrikeWithdrawal(withdrawal.ID, WithdrawalPendingApproval, WithdrawalApproved))
+	err = db.TransitionStrikeWithdrawal(withdrawal.ID, WithdrawalPendingApproval, WithdrawalApproved)
+	testutils.AssertEqual(t, errors.Is(err, ErrStatusChanged), true)
+	err = db.TransitionStrikeWithdrawal(withdrawal.ID+1, WithdrawalPendingApproval, WithdrawalApproved)
+	testutils.AssertEqual(t, err, sql.ErrNoRows)
+
+	withdrawal.Status = WithdrawalExecuted
+	withdrawal.Destination = "lnbc5m1..."
+	withdrawal.PaymentID = "payment-1"
+	withdrawal.Fee = 12
+	testutils.AssertNoError(t, db.UpdateStrikeWithdrawal(withdrawal))
+
+	stored, err := db.GetStrikeWithdrawal(withdrawal.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stored.Status, WithdrawalExecuted)
+	testutils.AssertEqual(t, stored.PaymentID, "payment-1")
+	testutils.AssertEqual(t, stored.Fee, int64(12))
+
+	open, err = db.GetStrikeWithdrawals(WithdrawalPendingApproval, WithdrawalApproved)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(open), 0)
+	all, err := db.GetStrikeWithdrawals()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(all), 1)
+
+	// Only a withdrawal left executing can be resolved
+	err = db.ResolveStrikeWithdrawal(withdrawal.ID, WithdrawalFailed, "", "never paid")
+	testutils.AssertEqual(t, errors.Is(err, ErrStatusChanged), true)
+	interrupted := &StrikeWithdrawal{Amount: 400000, DestinationType: WithdrawalLightning, Status: WithdrawalExecuting}
+	testutils.AssertNoError(t, db.InsertStrikeWithdrawal(interrupted))
+	testutils.AssertNoError(t, db.ResolveStrikeWithdrawal(interrupted.ID, WithdrawalExecuted, "payment-2", ""))
+	stored, err = db.GetStrikeWithdrawal(interrupted.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stored.Status, WithdrawalExecuted)
+	testutils.AssertEqual(t, stored.PaymentID, "payment-2")
+}
+
+func TestBTCPrices(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	prices := []BTCPrice{
+		{Timestamp: now.Add(-48 * time.Hour), Currency: "USD", Price: 40000, Source: "mempool"},
+		{Timestamp: now.Add(-48 * time.Hour), Currency: "EUR", Price: 37000, Source: "mempool"},
+		{Timestamp: now.Add(-24 * time.Hour), Currency: "USD", Price: 42000, Source: "mempool"},
+		{Timestamp: now.Add(-time.Hour), Currency: "USD", Price: 43000, Source: "coinbase"},
+	}
+	inserted, err := db.InsertBTCPrices(prices)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, int64(4))
+
+	// A price already stored for its time is kept
+	inserted, err = db.InsertBTCPrices([]BTCPrice{{Timestamp: now.Add(-time.Hour), Currency: "USD", Price: 1, Source: "mempool"}})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, inserted, int64(0))
+
+	price, err := db.GetBTCPriceAt("USD", now.Add(-2*time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, price.Price, 42000.0)
+	testutils.AssertEqual(t, price.Timestamp.Equal(now.Add(-24*time.Hour)), true)
+
+	price, err = db.GetBTCPriceAt("USD", now.Add(-72*time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, price == nil, true)
+
+	history, err := db.GetBTCPriceHistory("USD", now.Add(-30*time.Hour), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(history), 2)
+	testutils.AssertEqual(t, history[0].Price, 42000.0)
+	testutils.AssertEqual(t, history[1].Source, "coinbase")
+}
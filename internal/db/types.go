@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -51,6 +52,52 @@ type ForwardingEvent struct {
 	AmountIn     int64     `json:"amount_in" db:"amount_in"`
 	AmountOut    int64     `json:"amount_out" db:"amount_out"`
 	Fee          int64     `json:"fee" db:"fee"`
+	// FeePPM is the fee rate earned, derived from Fee and AmountOut on insert
+	FeePPM int64 `json:"fee_ppm" db:"fee_ppm"`
+}
+
+// ForwardFeePPM returns the fee rate of a forward in parts per million of
+// the outgoing amount, rounded to the nearest ppm
+func ForwardFeePPM(fee, amountOut int64) int64 {
+	if amountOut <= 0 {
+		return 0
+	}
+	return (fee*1_000_000 + amountOut/2) / amountOut
+}
+
+// HTLCFailure is a forward through the node that failed, seen on LND's HTLC
+// event stream. Outcome is lnd.ForwardFailed when a later hop failed it and
+// lnd.ForwardLinkFailed when this node refused it, in which case Failure
+// says why, e.g. INSUFFICIENT_BALANCE. Fee is what it would have earned.
+type HTLCFailure struct {
+	ID           int64     `json:"id" db:"id"`
+	Timestamp    time.Time `json:"timestamp" db:"timestamp"`
+	ChannelInID  string    `json:"channel_in_id" db:"channel_in_id"`
+	ChannelOutID string    `json:"channel_out_id" db:"channel_out_id"`
+	AmountIn     int64     `json:"amount_in" db:"amount_in"`
+	AmountOut    int64     `json:"amount_out" db:"amount_out"`
+	Fee          int64     `json:"fee" db:"fee"`
+	Outcome      string    `json:"outcome" db:"outcome"`
+	Failure      string    `json:"failure" db:"failure"`
+}
+
+// Classes of tracked addresses. Each class can require a different number
+// of confirmations before its funds count as confirmed.
+const (
+	AddressClassHot  = "hot"
+	AddressClassCold = "cold"
+)
+
+// AddressClasses lists the valid address classes
+var AddressClasses = []string{AddressClassHot, AddressClassCold}
+
+// IsValidAddressClass reports whether class is one of the known address classes
+func IsValidAddressClass(class string) bool {
+	switch class {
+	case AddressClassHot, AddressClassCold:
+		return true
+	}
+	return false
 }
 
 // OnchainAddress represents a tracked Bitcoin address
@@ -59,6 +106,17 @@ type OnchainAddress struct {
 	Address string `json:"address" db:"address"`
 	Label   string `json:"label" db:"label"`
 	Active  bool   `json:"active" db:"active"`
+
+	// BirthHeight and BirthDate mark when the address or wallet was created.
+	// History scans skip everything before them; zero means unknown.
+	BirthHeight int64      `json:"birth_height,omitempty" db:"birth_height"`
+	BirthDate   *time.Time `json:"birth_date,omitempty" db:"birth_date"`
+
+	// Class is AddressClassHot or AddressClassCold
+	Class string `json:"class" db:"class"`
+
+	// DeletedAt is set when the address has been soft-deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 // AddressBalance represents the balance of a tracked address at a point in time
@@ -68,6 +126,25 @@ type AddressBalance struct {
 	Timestamp time.Time `json:"timestamp" db:"timestamp"`
 	Balance   int64     `json:"balance" db:"balance"`
 	TxCount   int64     `json:"tx_count" db:"tx_count"`
+
+	// BlockHeight and BlockHash identify the latest block the balance was
+	// derived from, so balances from re-orged blocks can be found
+	BlockHeight int64  `json:"block_height,omitempty" db:"block_height"`
+	BlockHash   string `json:"block_hash,omitempty" db:"block_hash"`
+}
+
+// AddressBalanceAt is the last balance of a tracked address recorded at or
+// before a past moment
+type AddressBalanceAt struct {
+	OnchainAddress
+	Balance    int64     `json:"balance"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// BlockRef identifies a block by height and hash
+type BlockRef struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
 }
 
 // ColdStorageEntry represents manually tracked cold storage
@@ -77,6 +154,42 @@ type ColdStorageEntry struct {
 	Balance     int64     `json:"balance" db:"balance"`
 	LastUpdated time.Time `json:"last_updated" db:"last_updated"`
 	Notes       string    `json:"notes" db:"notes"`
+
+	ColdStorageMetadata
+
+	// DisplayUnit is the unit the entry is documented in ("sats" or "BTC"); Balance is always sats
+	DisplayUnit string `json:"display_unit" db:"display_unit"`
+
+	// DeletedAt is set when the entry has been soft-deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// Custody types for cold storage entries
+const (
+	CustodyTypeHardware      = "hardware"
+	CustodyTypePaper         = "paper"
+	CustodyTypeCollaborative = "collaborative"
+)
+
+// CustodyTypes lists the known custody types
+var CustodyTypes = []string{CustodyTypeHardware, CustodyTypePaper, CustodyTypeCollaborative}
+
+// IsValidCustodyType reports whether custodyType is empty or one of the known custody types
+func IsValidCustodyType(custodyType string) bool {
+	switch custodyType {
+	case "", CustodyTypeHardware, CustodyTypePaper, CustodyTypeCollaborative:
+		return true
+	}
+	return false
+}
+
+// ColdStorageMetadata describes how and where a cold storage entry is held.
+// LocationHint and DerivationRef are reminders only and must never contain secrets.
+type ColdStorageMetadata struct {
+	CustodyType   string `json:"custody_type" db:"custody_type"`     // "hardware", "paper" or "collaborative"
+	DeviceModel   string `json:"device_model" db:"device_model"`     // e.g. "Coldcard Mk4"
+	LocationHint  string `json:"location_hint" db:"location_hint"`   // e.g. "safe deposit box"
+	DerivationRef string `json:"derivation_ref" db:"derivation_ref"` // e.g. "m/84'/0'/0'" or a fingerprint
 }
 
 // DailyFeeData represents aggregated fee data for a specific day
@@ -86,6 +199,80 @@ type DailyFeeData struct {
 	ForwardCount int64  `json:"forward_count" db:"forward_count"`
 }
 
+// ForwardingStats summarizes forwarding activity over a time range.
+// Forward sizes and volume use the outgoing amount.
+type ForwardingStats struct {
+	ForwardCount      int64                `json:"forward_count"`
+	TotalVolume       int64                `json:"total_volume"`
+	TotalFees         int64                `json:"total_fees"`
+	MeanForwardSize   int64                `json:"mean_forward_size"`
+	MedianForwardSize int64                `json:"median_forward_size"`
+	EffectiveFeePPM   float64              `json:"effective_fee_ppm"`
+	LargestForward    *ForwardingEvent     `json:"largest_forward,omitempty"`
+	BusiestChannel    *ChannelForwardStats `json:"busiest_channel,omitempty"`
+}
+
+// ChannelForwardStats counts the forwards a channel took part in, either as
+// the incoming or the outgoing side
+type ChannelForwardStats struct {
+	ChannelID    string `json:"channel_id"`
+	ForwardCount int64  `json:"forward_count"`
+	Volume       int64  `json:"volume"`
+}
+
+// ChannelFeeStats totals the forwards a channel carried out as the outgoing
+// side and the fees they earned
+type ChannelFeeStats struct {
+	ChannelID    string `json:"channel_id"`
+	ForwardCount int64  `json:"forward_count"`
+	Volume       int64  `json:"volume"`
+	Fees         int64  `json:"fees"`
+}
+
+// ChannelFees is the routing of one channel within a time range, as the
+// channel forwards came in through and as the one they went out through.
+// Fees are earned by the outgoing channel; InFees are the fees forwards
+// entering through the channel paid elsewhere.
+type ChannelFees struct {
+	ChannelID string `json:"channel_id"`
+	// PeerAlias is the alias of the latest channel snapshot, empty if the
+	// channel was never snapshotted
+	PeerAlias string `json:"peer_alias"`
+	// Name is the operator's name for the channel, empty if it has none
+	Name            string `json:"name,omitempty"`
+	OutForwardCount int64  `json:"out_forward_count"`
+	OutVolume       int64  `json:"out_volume"`
+	OutFees         int64  `json:"out_fees"`
+	InForwardCount  int64  `json:"in_forward_count"`
+	InVolume        int64  `json:"in_volume"`
+	InFees          int64  `json:"in_fees"`
+}
+
+// FeePPMBucketBounds are the lower bounds of the fee rate histogram buckets.
+// Each bucket runs up to the next bound; the last one is open-ended.
+var FeePPMBucketBounds = []int64{0, 10, 50, 100, 250, 500, 1000, 2500}
+
+// FeePPMBucket counts the forwards that earned a fee rate from MinPPM up to
+// but excluding MaxPPM
+type FeePPMBucket struct {
+	MinPPM       int64  `json:"min_ppm"`
+	MaxPPM       *int64 `json:"max_ppm"` // nil for the open-ended last bucket
+	ForwardCount int64  `json:"forward_count"`
+	Volume       int64  `json:"volume"`
+	Fees         int64  `json:"fees"`
+}
+
+// FeePPMHistogram spreads the forwards out of one channel, or of all
+// channels when ChannelID is empty, over the FeePPMBucketBounds buckets
+type FeePPMHistogram struct {
+	ChannelID    string         `json:"channel_id,omitempty"`
+	ForwardCount int64          `json:"forward_count"`
+	Volume       int64          `json:"volume"`
+	Fees         int64          `json:"fees"`
+	MedianPPM    int64          `json:"median_ppm"`
+	Buckets      []FeePPMBucket `json:"buckets"`
+}
+
 // ColdStorageBalanceHistory represents balance changes for cold storage accounts over time
 type ColdStorageBalanceHistory struct {
 	ID              int64     `json:"id" db:"id"`
@@ -97,13 +284,421 @@ type ColdStorageBalanceHistory struct {
 	Notes           string    `json:"notes" db:"notes"`
 }
 
+// ColdStorageBalanceAt is the balance of a cold storage account as of a past
+// moment
+type ColdStorageBalanceAt struct {
+	AccountID int64  `json:"account_id"`
+	Name      string `json:"name"`
+	Balance   int64  `json:"balance"`
+}
+
+// Units of Strike balance amounts
+const (
+	StrikeUnitSats  = "sats"
+	StrikeUnitCents = "cents"
+)
+
+// StrikeUnit returns the unit Strike amounts in currency are stored in:
+// sats for BTC, cents for fiat
+func StrikeUnit(currency string) string {
+	if currency == "BTC" {
+		return StrikeUnitSats
+	}
+	return StrikeUnitCents
+}
+
 // StrikeBalanceSnapshot represents Strike account balance at a point in time
 type StrikeBalanceSnapshot struct {
 	ID        int64     `json:"id" db:"id"`
 	Timestamp time.Time `json:"timestamp" db:"timestamp"`
-	Currency  string    `json:"currency" db:"currency"`   // "BTC", "USD", etc.
-	Available int64     `json:"available" db:"available"` // Sats for BTC, cents for fiat
-	Total     int64     `json:"total" db:"total"`
-	Pending   int64     `json:"pending" db:"pending"`
-	Reserved  int64     `json:"reserved" db:"reserved"`
+	Currency  string    `json:"currency" db:"currency"` // "BTC", "USD", etc.
+	// Unit is what the amounts count, StrikeUnitSats or StrikeUnitCents.
+	// Only sats amounts may be added to other balances; see Sats.
+	Unit      string `json:"unit" db:"unit"`
+	Available int64  `json:"available" db:"available"`
+	Total     int64  `json:"total" db:"total"`
+	Pending   int64  `json:"pending" db:"pending"`
+	Reserved  int64  `json:"reserved" db:"reserved"`
+}
+
+// Sats returns the total and pending balance when they are in sats, and
+// ErrNotSats for fiat balances, which have to be converted at a BTC price
+// before they can be added to anything
+func (s *StrikeBalanceSnapshot) Sats() (total, pending int64, err error) {
+	if s.Unit != StrikeUnitSats {
+		return 0, 0, fmt.Errorf("%w: Strike %s balance is in %s", ErrNotSats, s.Currency, s.Unit)
+	}
+	return s.Total, s.Pending, nil
+}
+
+// LiquidBalanceSnapshot is the L-BTC balance of an Elements wallet at a point in time
+type LiquidBalanceSnapshot struct {
+	ID          int64     `json:"id" db:"id"`
+	Timestamp   time.Time `json:"timestamp" db:"timestamp"`
+	Confirmed   int64     `json:"confirmed" db:"confirmed"`     // Sats
+	Unconfirmed int64     `json:"unconfirmed" db:"unconfirmed"` // Sats, incoming and immature peg-ins
+}
+
+// EcashBalanceSnapshot is the ecash held with one Fedimint federation or
+// Cashu mint at a point in time
+type EcashBalanceSnapshot struct {
+	ID        int64     `json:"id" db:"id"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	Protocol  string    `json:"protocol" db:"protocol"` // fedimint or cashu
+	Mint      string    `json:"mint" db:"mint"`         // Federation ID or mint URL
+	Name      string    `json:"name" db:"name"`
+	Amount    int64     `json:"amount" db:"amount"` // Sats
+}
+
+// LightningBalancePoint is the LND wallet and channel balance right after one
+// on-chain transaction, settled invoice or payment. EventKey identifies the
+// event, e.g. "lightning_in:<payment hash>".
+type LightningBalancePoint struct {
+	ID              int64     `json:"id" db:"id"`
+	EventKey        string    `json:"event_key" db:"event_key"`
+	Timestamp       time.Time `json:"timestamp" db:"timestamp"`
+	EventType       string    `json:"event_type" db:"event_type"` // "onchain", "lightning_in", "lightning_out", "balance"
+	Amount          int64     `json:"amount" db:"amount"`
+	OnchainBalance  int64     `json:"onchain_balance" db:"onchain_balance"`
+	LightningLocal  int64     `json:"lightning_local" db:"lightning_local"`
+	LightningRemote int64     `json:"lightning_remote" db:"lightning_remote"`
+}
+
+// BTCPrice is the price of one BTC in a fiat currency at the time a price
+// source published it. Source names where it came from, e.g. "mempool".
+type BTCPrice struct {
+	ID        int64     `json:"id" db:"id"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	Currency  string    `json:"currency" db:"currency"`
+	Price     float64   `json:"price" db:"price"`
+	Source    string    `json:"source" db:"source"`
+}
+
+// MissionControlPair is LND's mission control result for one directed node
+// pair in a snapshot. Times are unix seconds and zero when there was no such result.
+type MissionControlPair struct {
+	ID             int64     `json:"id" db:"id"`
+	Timestamp      time.Time `json:"timestamp" db:"timestamp"`
+	NodeFrom       string    `json:"node_from" db:"node_from"`
+	NodeTo         string    `json:"node_to" db:"node_to"`
+	SuccessTime    int64     `json:"success_time" db:"success_time"`
+	SuccessAmtMsat int64     `json:"success_amt_msat" db:"success_amt_msat"`
+	FailTime       int64     `json:"fail_time" db:"fail_time"`
+	FailAmtMsat    int64     `json:"fail_amt_msat" db:"fail_amt_msat"`
+}
+
+// PeerPolicy records how the tools treat one Lightning peer. Blocklisted
+// peers are avoided (e.g. their channel opens are rejected); preferred peers
+// are favoured (e.g. they skip the channel acceptor's size rules).
+type PeerPolicy struct {
+	ID          int64     `json:"id" db:"id"`
+	Pubkey      string    `json:"pubkey" db:"pubkey"`
+	Blocklisted bool      `json:"blocklisted" db:"blocklisted"`
+	Preferred   bool      `json:"preferred" db:"preferred"`
+	Notes       string    `json:"notes" db:"notes"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PeerNote holds private context about one Lightning peer: how to reach
+// the operator and what was agreed with them, e.g. "we both keep 1k ppm".
+// AgreedOn is the date of the agreement as YYYY-MM-DD, or empty.
+type PeerNote struct {
+	ID        int64     `json:"id" db:"id"`
+	Pubkey    string    `json:"pubkey" db:"pubkey"`
+	Contact   string    `json:"contact" db:"contact"`
+	Agreement string    `json:"agreement" db:"agreement"`
+	AgreedOn  string    `json:"agreed_on" db:"agreed_on"`
+	Notes     string    `json:"notes" db:"notes"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ChannelName is the operator's own name for a channel, e.g. "LOOP main",
+// shown instead of the peer's alias so channels to one peer can be told apart
+type ChannelName struct {
+	ChannelID string    `json:"channel_id" db:"channel_id"`
+	Name      string    `json:"name" db:"name"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MaxChannelNameLength caps a channel name, in characters, so it fits
+// where aliases are shown
+const MaxChannelNameLength = 32
+
+// Channel lease sides
+const (
+	// LeaseSideBought is inbound liquidity we paid a premium for
+	LeaseSideBought = "bought"
+	// LeaseSideSold is a channel we opened to a buyer for a premium
+	LeaseSideSold = "sold"
+)
+
+// LeaseSides lists the valid lease sides
+var LeaseSides = []string{LeaseSideBought, LeaseSideSold}
+
+// IsValidLeaseSide reports whether side is a known lease side
+func IsValidLeaseSide(side string) bool {
+	switch side {
+	case LeaseSideBought, LeaseSideSold:
+		return true
+	}
+	return false
+}
+
+// ChannelLease records the terms a channel was bought or sold under, e.g.
+// through Lightning Pool or a liquidity ad
+type ChannelLease struct {
+	ID        int64  `json:"id" db:"id"`
+	ChannelID string `json:"channel_id" db:"channel_id"`
+	Side      string `json:"side" db:"side"`
+	Provider  string `json:"provider" db:"provider"` // e.g. pool, liquidity-ad, an LSP name
+	// Premium is the sats paid for a bought lease or earned for a sold one
+	Premium        int64 `json:"premium" db:"premium"`
+	DurationBlocks int64 `json:"duration_blocks" db:"duration_blocks"`
+	// ExpiryHeight is the block at which the lease ends and the channel may be closed
+	ExpiryHeight int64 `json:"expiry_height" db:"expiry_height"`
+	// StartedAt is when the lease began; routing fees are counted from it
+	StartedAt *time.Time `json:"started_at,omitempty" db:"started_at"`
+	Notes     string     `json:"notes" db:"notes"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ProbeResult is one probe payment sent to measure payment reliability.
+// Success means the payment reached Destination; probes never settle.
+type ProbeResult struct {
+	ID            int64     `json:"id" db:"id"`
+	Timestamp     time.Time `json:"timestamp" db:"timestamp"`
+	Destination   string    `json:"destination" db:"destination"`
+	Amount        int64     `json:"amount" db:"amount"` // Sats
+	Success       bool      `json:"success" db:"success"`
+	FailureReason string    `json:"failure_reason,omitempty" db:"failure_reason"`
+	LatencyMs     int64     `json:"latency_ms" db:"latency_ms"`
+	Attempts      int64     `json:"attempts" db:"attempts"`
+	Hops          int64     `json:"hops" db:"hops"`
+	FeeMsat       int64     `json:"fee_msat" db:"fee_msat"` // Fee the route would have cost
+}
+
+// ProbeStats summarises the probes sent to one destination, or to all of
+// them when Destination is empty
+type ProbeStats struct {
+	Destination string  `json:"destination,omitempty"`
+	Probes      int64   `json:"probes"`
+	Successes   int64   `json:"successes"`
+	SuccessRate float64 `json:"success_rate"` // Ratio 0-1, 0 without probes
+	// AvgLatencyMs averages successful probes only
+	AvgLatencyMs int64      `json:"avg_latency_ms"`
+	LastProbe    *time.Time `json:"last_probe,omitempty"`
+}
+
+// PortfolioTransfer is money moved into (positive Amount) or out of
+// (negative Amount) the portfolio, e.g. buying BTC or spending it. Transfers
+// are stripped out of performance so stacking is not counted as growth.
+type PortfolioTransfer struct {
+	ID        int64     `json:"id" db:"id"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	Amount    int64     `json:"amount" db:"amount"`
+	Notes     string    `json:"notes" db:"notes"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Annotation is a note on the portfolio timeline, e.g. "bought a hardware
+// wallet" or "opened 3 channels", shown as a marker on history charts
+type Annotation struct {
+	ID        int64     `json:"id" db:"id"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	Text      string    `json:"text" db:"text"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
+
+// Strike withdrawal statuses. Manual approval starts withdrawals in
+// pending_approval; automatic ones start approved.
+const (
+	WithdrawalPendingApproval = "pending_approval"
+	WithdrawalApproved        = "approved"
+	WithdrawalRejected        = "rejected"
+	// WithdrawalExecuting is set before a withdrawal is paid; one left in it
+	// was interrupted and has to be checked on Strike
+	WithdrawalExecuting = "executing"
+	WithdrawalExecuted  = "executed"
+	WithdrawalFailed    = "failed"
+)
+
+// Strike withdrawal destinations
+const (
+	WithdrawalOnchain   = "onchain"
+	WithdrawalLightning = "lightning"
+)
+
+// Channel request statuses. Requests start pending; approving one moves it
+// to opening while the channel is funded.
+const (
+	ChannelRequestPending  = "pending"
+	ChannelRequestRejected = "rejected"
+	ChannelRequestOpening  = "opening"
+	ChannelRequestOpened   = "opened"
+	ChannelRequestFailed   = "failed"
+)
+
+// ChannelRequest is a request from a node for a channel from ours,
+// submitted through the public API and opened once approved
+type ChannelRequest struct {
+	ID        int64     `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Pubkey    string    `json:"pubkey" db:"pubkey"`
+	Host      string    `json:"host" db:"host"`         // host:port the node is reached at
+	Capacity  int64     `json:"capacity" db:"capacity"` // Sats
+	Contact   string    `json:"contact" db:"contact"`
+	Message   string    `json:"message,omitempty" db:"message"`
+	Status    string    `json:"status" db:"status"`
+	FeeRate   int64     `json:"fee_rate,omitempty" db:"fee_rate"` // sat/vB the channel was opened at
+	// FundingTxid is the channel's funding transaction once it is opened
+	FundingTxid string `json:"funding_txid,omitempty" db:"funding_txid"`
+	Error       string `json:"error,omitempty" db:"error"`
+}
+
+// StrikeWithdrawal is a withdrawal of BTC from Strike proposed by the
+// balance collector once the balance passed its threshold, and the log of
+// its execution
+type StrikeWithdrawal struct {
+	ID        int64     `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Amount    int64     `json:"amount" db:"amount"` // Sats
+	// DestinationType is WithdrawalOnchain or WithdrawalLightning
+	DestinationType string `json:"destination_type" db:"destination_type"`
+	// Destination is the tracked address, or for Lightning the invoice,
+	// which is only created when the withdrawal is executed
+	Destination string `json:"destination" db:"destination"`
+	Status      string `json:"status" db:"status"`
+	QuoteID     string `json:"quote_id,omitempty" db:"quote_id"`
+	PaymentID   string `json:"payment_id,omitempty" db:"payment_id"`
+	Fee         int64  `json:"fee" db:"fee"` // Sats charged by Strike
+	Error       string `json:"error,omitempty" db:"error"`
+}
+
+// Statement freezes one month's figures at month end. Balances are as of
+// PeriodEnd; fees, forwards and transfers cover PeriodStart to PeriodEnd.
+// Statements cannot be changed or deleted once stored.
+type Statement struct {
+	ID          int64     `json:"id" db:"id"`
+	Month       string    `json:"month" db:"month"` // "YYYY-MM"
+	PeriodStart time.Time `json:"period_start" db:"period_start"`
+	PeriodEnd   time.Time `json:"period_end" db:"period_end"`
+
+	// Balances by component
+	LightningLocal   int64 `json:"lightning_local" db:"lightning_local"`
+	LightningRemote  int64 `json:"lightning_remote" db:"lightning_remote"` // Not part of the total
+	OnchainBalance   int64 `json:"onchain_balance" db:"onchain_balance"`
+	TrackedAddresses int64 `json:"tracked_addresses" db:"tracked_addresses"`
+	ColdStorage      int64 `json:"cold_storage" db:"cold_storage"`
+	Liquid           int64 `json:"liquid" db:"liquid"` // L-BTC
+	Ecash            int64 `json:"ecash" db:"ecash"`   // Fedimint and Cashu
+	TotalPortfolio   int64 `json:"total_portfolio" db:"total_portfolio"`
+
+	// Income
+	ForwardingFees int64 `json:"forwarding_fees" db:"forwarding_fees"`
+	ForwardCount   int64 `json:"forward_count" db:"forward_count"`
+	ForwardVolume  int64 `json:"forward_volume" db:"forward_volume"`
+
+	// Transfers in and out of the portfolio
+	Deposits    int64 `json:"deposits" db:"deposits"`
+	Withdrawals int64 `json:"withdrawals" db:"withdrawals"`
+
+	ClosedAt time.Time `json:"closed_at" db:"closed_at"`
+}
+
+// Collector run statuses
+const (
+	CollectorRunRunning = "running"
+	CollectorRunSuccess = "success"
+	CollectorRunPartial = "partial"
+	CollectorRunFailed  = "failed"
+)
+
+// CollectorRun is one execution of a collector, recorded in collector_runs.
+// ResumePoint is collector-specific, e.g. the last collected unix timestamp.
+type CollectorRun struct {
+	ID            int64      `json:"id" db:"id"`
+	Collector     string     `json:"collector" db:"collector"`
+	StartedAt     time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	Status        string     `json:"status" db:"status"`
+	ItemsInserted int64      `json:"items_inserted" db:"items_inserted"`
+	Errors        int64      `json:"errors" db:"errors"`
+	ErrorMessage  string     `json:"error_message,omitempty" db:"error_message"`
+	ResumePoint   string     `json:"resume_point,omitempty" db:"resume_point"`
+}
+
+// MaxClockSkew is how far ahead of this machine's clock a record may be
+// before its timestamp is treated as bad rather than a clock running fast
+const MaxClockSkew = 10 * time.Minute
+
+// Records timestamped before these cannot be real and were misparsed
+var (
+	EarliestBitcoinTime   = time.Date(2009, 1, 3, 0, 0, 0, 0, time.UTC) // Genesis block
+	EarliestLightningTime = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC) // Lightning on mainnet
+)
+
+// QuarantineSources are the tables whose records are checked for impossible
+// timestamps before insertion
+var QuarantineSources = []string{"balance_snapshots", "channel_snapshots", "forwarding_events"}
+
+// QuarantinedRecord is a record rejected for an impossible timestamp, or
+// moved aside by RepairIntegrityIssue. Payload is the record as JSON, as it
+// would have been inserted into Source.
+type QuarantinedRecord struct {
+	ID              int64     `json:"id" db:"id"`
+	Source          string    `json:"source" db:"source"`
+	RecordTimestamp time.Time `json:"record_timestamp" db:"record_timestamp"`
+	Reason          string    `json:"reason" db:"reason"`
+	Payload         string    `json:"payload" db:"payload"`
+	QuarantinedAt   time.Time `json:"quarantined_at" db:"quarantined_at"`
+}
+
+// IntegrityIssue is one kind of inconsistent row found by CheckIntegrity
+type IntegrityIssue struct {
+	Check       string  `json:"check"` // name passed to RepairIntegrityIssue
+	Table       string  `json:"table"`
+	Description string  `json:"description"`
+	Count       int64   `json:"count"`
+	SampleIDs   []int64 `json:"sample_ids"` // up to IntegritySampleSize row IDs
+}
+
+// IntegritySampleSize is how many row IDs an IntegrityIssue lists
+const IntegritySampleSize = 5
+
+// DryRunChange is what a dry run wrote to one table
+type DryRunChange struct {
+	Table    string                   `json:"table"`
+	Inserted int64                    `json:"inserted"`
+	Deleted  int64                    `json:"deleted"`
+	Samples  []map[string]interface{} `json:"samples"` // the first DryRunSampleSize rows inserted
+}
+
+// DryRunSampleSize is how many inserted rows a DryRunChange includes
+const DryRunSampleSize = 3
+
+// NodeVersion is a version a node was first seen running at FirstSeen
+type NodeVersion struct {
+	ID        int64     `json:"id" db:"id"`
+	Node      string    `json:"node" db:"node"` // "lnd" or "bitcoin_core"
+	Version   string    `json:"version" db:"version"`
+	FirstSeen time.Time `json:"first_seen" db:"first_seen"`
+}
+
+// MultisigWallet is a multisig wallet imported from a coordinator or device
+// export, kept as a descriptor with its <receive;change> steps
+type MultisigWallet struct {
+	ID         int64  `json:"id" db:"id"`
+	Name       string `json:"name" db:"name"`
+	Descriptor string `json:"descriptor" db:"descriptor"`
+	Format     string `json:"format" db:"format"` // the export it was read from
+	// NextAddressIndex is the first receive address that has not received
+	// funds yet, the next one to hand out
+	NextAddressIndex uint32    `json:"next_address_index" db:"next_address_index"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+// MaxMultisigWalletNameLength caps the name of an imported multisig wallet,
+// in characters
+const MaxMultisigWalletNameLength = 64
@@ -0,0 +1,94 @@
+package ecash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCashuURL is where the nutshell wallet API listens by default
+const DefaultCashuURL = "http://127.0.0.1:4448"
+
+// CashuClient reads per-mint balances from a nutshell Cashu wallet API
+type CashuClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewCashuClient creates a client for the wallet API at baseURL
+func NewCashuClient(baseURL string) *CashuClient {
+	return &CashuClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// GetBalances returns the sat balance held with each mint in the wallet
+func (c *CashuClient) GetBalances() ([]Balance, error) {
+	resp, err := c.client.Get(c.baseURL + "/balance")
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cashu wallet API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	balances, err := parseCashuBalance(body)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now()
+	for i := range balances {
+		balances[i].Timestamp = timestamp
+	}
+	return balances, nil
+}
+
+// parseCashuBalance reads the per-mint balances of a /balance response,
+// sorted by mint URL. Proofs reserved for tokens that were sent but not yet
+// claimed are not counted, and mints in units other than sats are skipped.
+func parseCashuBalance(body []byte) ([]Balance, error) {
+	var response struct {
+		Mints map[string]struct {
+			Available int64  `json:"available"`
+			Unit      string `json:"unit"`
+		} `json:"mints"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse cashu balance: %w", err)
+	}
+
+	var balances []Balance
+	for mintURL, mint := range response.Mints {
+		if mint.Unit != "" && mint.Unit != "sat" {
+			continue
+		}
+		name := mintURL
+		if parsed, err := url.Parse(mintURL); err == nil && parsed.Host != "" {
+			name = parsed.Host
+		}
+		balances = append(balances, Balance{
+			Protocol: ProtocolCashu,
+			Mint:     mintURL,
+			Name:     name,
+			Amount:   mint.Available,
+		})
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Mint < balances[j].Mint
+	})
+	return balances, nil
+}
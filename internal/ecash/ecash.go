@@ -0,0 +1,30 @@
+// Package ecash reads ecash balances held with Fedimint federations and
+// Cashu mints.
+//
+// Ecash is custodial: the federation or mint holds the bitcoin and the
+// wallet holds bearer notes. Each federation or mint is therefore tracked
+// as its own balance, so the exposure to a single custodian stays visible.
+package ecash
+
+import "time"
+
+// Supported ecash protocols
+const (
+	ProtocolFedimint = "fedimint"
+	ProtocolCashu    = "cashu"
+)
+
+// Balance is the ecash held with one federation or mint, in sats
+type Balance struct {
+	Timestamp time.Time `json:"timestamp"`
+	Protocol  string    `json:"protocol"`
+	// Mint is the federation ID for Fedimint and the mint URL for Cashu
+	Mint   string `json:"mint"`
+	Name   string `json:"name"`
+	Amount int64  `json:"amount"`
+}
+
+// Source is an ecash wallet that can report its balance per mint
+type Source interface {
+	GetBalances() ([]Balance, error)
+}
@@ -0,0 +1,60 @@
+package ecash
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseFedimintInfo(t *testing.T) {
+	balance, err := parseFedimintInfo([]byte(`{
+		"federation_id": "15db8cb4f1ec8e484d73b889372bec94812580f929e8148b7437d359af422cd3",
+		"network": "bitcoin",
+		"meta": {"federation_name": "Bitcoin Principles"},
+		"total_amount_msat": 12345678,
+		"total_num_notes": 9
+	}`))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, balance.Protocol, ProtocolFedimint)
+	testutils.AssertEqual(t, balance.Name, "Bitcoin Principles")
+	// Millisats below a whole sat are dropped
+	testutils.AssertEqual(t, balance.Amount, int64(12345))
+
+	// Federations without a name fall back to their ID
+	balance, err = parseFedimintInfo([]byte(`{"federation_id": "abc", "total_amount_msat": 0}`))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, balance.Name, "abc")
+
+	_, err = parseFedimintInfo([]byte(`{"total_amount_msat": 1000}`))
+	testutils.AssertError(t, err, "did not report a federation ID")
+}
+
+func TestCashuClientGetBalances(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/balance" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+			"balance": 2600,
+			"mints": {
+				"https://mint.minibits.cash/Bitcoin": {"available": 2000, "balance": 2100, "unit": "sat"},
+				"https://8333.space:3338": {"available": 500, "balance": 500},
+				"https://stablenut.umint.cash": {"available": 1200, "balance": 1200, "unit": "usd"}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	balances, err := NewCashuClient(server.URL + "/").GetBalances()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(balances), 2)
+	testutils.AssertEqual(t, balances[0].Mint, "https://8333.space:3338")
+	testutils.AssertEqual(t, balances[0].Name, "8333.space:3338")
+	testutils.AssertEqual(t, balances[0].Amount, int64(500))
+	// Reserved proofs of unclaimed sent tokens are not counted
+	testutils.AssertEqual(t, balances[1].Amount, int64(2000))
+	testutils.AssertEqual(t, balances[1].Protocol, ProtocolCashu)
+}
@@ -0,0 +1,78 @@
+package ecash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// FedimintClient reads a Fedimint client's balance with fedimint-cli. A
+// fedimint-cli data directory holds one federation, so each federation
+// needs its own client.
+type FedimintClient struct {
+	dataDir string
+}
+
+// NewFedimintClient creates a client for the fedimint-cli data directory
+// and checks that it has joined a federation
+func NewFedimintClient(dataDir string) (*FedimintClient, error) {
+	if dataDir == "" {
+		return nil, fmt.Errorf("fedimint data directory is required")
+	}
+	if strings.ContainsAny(dataDir, "\x00;|&$`\n\r") {
+		return nil, fmt.Errorf("fedimint data directory contains invalid characters")
+	}
+
+	c := &FedimintClient{dataDir: dataDir}
+	if _, err := c.GetBalances(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Fedimint client in %s: %w", dataDir, err)
+	}
+	return c, nil
+}
+
+// GetBalances returns the balance held with the client's federation
+func (c *FedimintClient) GetBalances() ([]Balance, error) {
+	output, err := exec.Command("fedimint-cli", "--data-dir", c.dataDir, "info").Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("fedimint-cli info failed: %v, stderr: %s", err, string(exitError.Stderr))
+		}
+		return nil, fmt.Errorf("fedimint-cli info failed: %w", err)
+	}
+
+	balance, err := parseFedimintInfo(output)
+	if err != nil {
+		return nil, err
+	}
+	balance.Timestamp = time.Now()
+	return []Balance{*balance}, nil
+}
+
+// parseFedimintInfo reads the federation and total note value from the
+// output of fedimint-cli info
+func parseFedimintInfo(output []byte) (*Balance, error) {
+	var info struct {
+		FederationID    string                 `json:"federation_id"`
+		Meta            map[string]interface{} `json:"meta"`
+		TotalAmountMsat int64                  `json:"total_amount_msat"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse fedimint-cli info: %w", err)
+	}
+	if info.FederationID == "" {
+		return nil, fmt.Errorf("fedimint-cli info did not report a federation ID")
+	}
+
+	name, _ := info.Meta["federation_name"].(string)
+	if name == "" {
+		name = info.FederationID
+	}
+	return &Balance{
+		Protocol: ProtocolFedimint,
+		Mint:     info.FederationID,
+		Name:     name,
+		Amount:   info.TotalAmountMsat / 1000,
+	}, nil
+}
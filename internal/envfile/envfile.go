@@ -0,0 +1,65 @@
+// Package envfile loads the KEY=value settings of a .env file into the
+// environment, so secrets such as STRIKE_API_KEY need not be on the command
+// line. Variables already set in the environment win over the file.
+package envfile
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Load sets the variables of the .env file at path that the environment
+// does not set yet. A missing file is not an error, .env files are optional.
+func Load(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), "\"")
+
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+		}
+	}
+	return scanner.Err()
+}
+
+// ProjectPath returns the path of the checkout the running binary was built
+// into, as bin/<tool>, e.g. ProjectPath(".env") for the project's .env
+func ProjectPath(name string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(filepath.Dir(exe)), name), nil
+}
+
+// LoadProject loads the project's .env, then the one in the working
+// directory, which is handy while developing
+func LoadProject() error {
+	if path, err := ProjectPath(".env"); err == nil {
+		if err := Load(path); err != nil {
+			return err
+		}
+	}
+	return Load(".env")
+}
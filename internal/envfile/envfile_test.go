@@ -0,0 +1,24 @@
+package envfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# Strike\nENVFILE_TEST_KEY=\"abc=def\"\n\nENVFILE_TEST_SET=file\nnot a setting\n"
+	testutils.AssertNoError(t, os.WriteFile(path, []byte(content), 0600))
+	t.Setenv("ENVFILE_TEST_SET", "environment")
+	t.Setenv("ENVFILE_TEST_KEY", "")
+
+	testutils.AssertNoError(t, Load(path))
+	testutils.AssertEqual(t, os.Getenv("ENVFILE_TEST_KEY"), "abc=def")
+	// The environment wins over the file
+	testutils.AssertEqual(t, os.Getenv("ENVFILE_TEST_SET"), "environment")
+
+	testutils.AssertNoError(t, Load(filepath.Join(t.TempDir(), "missing")))
+}
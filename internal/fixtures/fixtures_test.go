@@ -0,0 +1,136 @@
+package fixtures
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/multisig"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestLNDFixturesParse(t *testing.T) {
+	defer lnd.SetRunner(LNDRunner)()
+
+	client, err := lnd.NewClient()
+	testutils.AssertNoError(t, err)
+
+	channels, err := lnd.GetChannels()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(channels), 2)
+	testutils.AssertEqual(t, channels[0].ChanID, ChannelACINQ)
+	testutils.AssertEqual(t, channels[1].ChanID, ChannelWoS)
+
+	balances, err := client.GetChannelBalances()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, balances.LocalBalance, int64(1500000))
+
+	wallet, err := client.GetWalletBalance()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, wallet.ConfirmedBalance, int64(800000))
+
+	version, err := lnd.GetVersion()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, version, "0.17.4-beta commit=v0.17.4-beta")
+
+	state, err := lnd.GetWalletState()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, state, lnd.WalletStateServerActive)
+
+	pairs, err := lnd.QueryMissionControl()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(pairs), 2)
+
+	_, err = lnd.RunLNCLI("openchannel")
+	testutils.AssertError(t, err, `no lnd fixture for "openchannel"`)
+}
+
+func TestBitcoinFixturesParse(t *testing.T) {
+	defer bitcoin.SetRunner(BitcoinRunner)()
+
+	client, err := bitcoin.NewClient()
+	testutils.AssertNoError(t, err)
+
+	version, err := client.GetVersion()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, version, "26.0.0")
+
+	split, err := client.GetAddressBalanceSplit(TrackedAddress, 1)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, split.Confirmed, int64(1500000))
+	testutils.AssertEqual(t, split.Unconfirmed, int64(50000))
+
+	transactions, err := bitcoin.NewTransactionScanner(client).GetAddressTransactions(TrackedAddress)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(transactions), 2)
+
+	wallet, err := multisig.Parse(MultisigDescriptor)
+	testutils.AssertNoError(t, err)
+	chains, err := wallet.ChainDescriptors()
+	testutils.AssertNoError(t, err)
+	unspents, err := client.ScanDescriptors(chains, 1000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(unspents), 2)
+	testutils.AssertEqual(t, unspents[1].Vout, uint32(1))
+
+	feeRate, err := client.EstimateFeeRate(6)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, feeRate, 12.0)
+}
+
+func TestFixtureTimestampsPrecedeNow(t *testing.T) {
+	defer lnd.SetRunner(LNDRunner)()
+	client := &lnd.Client{}
+
+	invoices, err := client.GetInvoices()
+	testutils.AssertNoError(t, err)
+	payments, err := client.GetPayments()
+	testutils.AssertNoError(t, err)
+	transactions, err := client.GetTransactions()
+	testutils.AssertNoError(t, err)
+
+	var stamps []string
+	for _, invoice := range invoices {
+		stamps = append(stamps, invoice.CreationDate)
+	}
+	for _, payment := range payments {
+		stamps = append(stamps, payment.CreationDate)
+	}
+	for _, tx := range transactions {
+		stamps = append(stamps, tx.TimeStamp)
+	}
+
+	monthStart := Now.AddDate(0, -1, 0)
+	for _, stamp := range stamps {
+		unix, err := strconv.ParseInt(stamp, 10, 64)
+		testutils.AssertNoError(t, err)
+		if ts := time.Unix(unix, 0); ts.Before(monthStart) || ts.After(Now) {
+			t.Errorf("fixture timestamp %s (%v) is not in the month before Now", stamp, ts.UTC())
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	testutils.AssertNoError(t, Seed(database))
+
+	stats, err := database.GetForwardingStats(Now.AddDate(0, -1, 0), Now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stats.ForwardCount, int64(5))
+	testutils.AssertEqual(t, stats.TotalFees, int64(1012))
+
+	addresses, err := database.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(addresses), 1)
+	testutils.AssertEqual(t, addresses[0].Address, TrackedAddress)
+
+	counts, err := database.GetQuarantineCounts()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(counts), 0)
+}
@@ -0,0 +1,321 @@
+package fixtures
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/ecash"
+	"github.com/brewgator/lightning-node-tools/internal/multisig"
+)
+
+// Remote node pubkeys of the channels in lnd/listchannels.json
+const (
+	peerACINQ = "03864ef025fde8fb587d989186ce6a4a186895ee44a926bfc370e2c366597a3f8f"
+	peerWoS   = "035e4ff418fc8b5554c5d9eea66396c227bd429a3251c8cbc711002ba215bfc226"
+)
+
+// day returns midnight UTC of the given January 2024 day plus offset
+func day(d int, offset time.Duration) time.Time {
+	return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC).Add(offset)
+}
+
+// Seed fills database with what the collectors would have stored for the
+// fixture node during January 2024. Only rows the database timestamps itself,
+// such as created_at or deleted_at, depend on the wall clock.
+func Seed(database *db.Database) error {
+	steps := []struct {
+		name string
+		seed func(*db.Database) error
+	}{
+		{"balance snapshots", seedBalances},
+		{"channel snapshots", seedChannels},
+		{"forwarding events", seedForwards},
+		{"HTLC failures", seedHTLCFailures},
+		{"mission control", seedMissionControl},
+		{"probe results", seedProbes},
+		{"peer policies", seedPeerPolicies},
+		{"peer notes", seedPeerNotes},
+		{"channel names", seedChannelNames},
+		{"channel requests", seedChannelRequests},
+		{"channel leases", seedLeases},
+		{"portfolio transfers", seedTransfers},
+		{"annotations", seedAnnotations},
+		{"on-chain addresses", seedAddresses},
+		{"cold storage", seedColdStorage},
+		{"multisig wallets", seedMultisigWallets},
+		{"custodial balances", seedCustodial},
+		{"statements", seedStatements},
+	}
+	for _, step := range steps {
+		if err := step.seed(database); err != nil {
+			return fmt.Errorf("failed to seed %s: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+func seedBalances(database *db.Database) error {
+	snapshots := []db.BalanceSnapshot{
+		{Timestamp: day(1, 0), LightningLocal: 0, LightningRemote: 0, OnchainConfirmed: 0, TrackedAddresses: 0, ColdStorage: 5000000, TotalPortfolio: 5000000, TotalLiquid: 0},
+		{Timestamp: day(11, 0), LightningLocal: 1000000, LightningRemote: 996810, OnchainConfirmed: 996810, TrackedAddresses: 2000000, ColdStorage: 5000000, TotalPortfolio: 9996810, TotalLiquid: 3996810},
+		{Timestamp: day(21, 0), LightningLocal: 1500000, LightningRemote: 1493060, OnchainConfirmed: 800000, OnchainUnconfirmed: 50000, TrackedAddresses: 1550000, ColdStorage: 5000000, TotalPortfolio: 8900000, TotalLiquid: 3900000},
+	}
+	return database.InsertBalanceSnapshots(snapshots)
+}
+
+func seedChannels(database *db.Database) error {
+	var snapshots []db.ChannelSnapshot
+	for _, d := range []int{10, 20, 30} {
+		shift := int64(d-10) * 10000
+		snapshots = append(snapshots,
+			db.ChannelSnapshot{Timestamp: day(d, 12*time.Hour), ChannelID: ChannelACINQ, Capacity: 2000000,
+				LocalBalance: 1000000 + shift, RemoteBalance: 996530 - shift, Active: true, PeerAlias: "ACINQ", FeePPM: 250, BaseFee: 1000},
+			db.ChannelSnapshot{Timestamp: day(d, 12*time.Hour), ChannelID: ChannelWoS, Capacity: 1000000,
+				LocalBalance: 500000 - shift, RemoteBalance: 496530 + shift, Active: d != 20, PeerAlias: "WalletOfSatoshi.com", FeePPM: 500},
+		)
+	}
+	if err := database.InsertChannelSnapshots(snapshots); err != nil {
+		return err
+	}
+	return database.SetChannelPeers(map[string]string{ChannelACINQ: peerACINQ, ChannelWoS: peerWoS})
+}
+
+func seedForwards(database *db.Database) error {
+	events := []db.ForwardingEvent{
+		{Timestamp: day(12, 3*time.Hour), ChannelInID: ChannelWoS, ChannelOutID: ChannelACINQ, AmountIn: 100250, AmountOut: 100000, Fee: 250},
+		{Timestamp: day(15, 9*time.Hour), ChannelInID: ChannelWoS, ChannelOutID: ChannelACINQ, AmountIn: 500126, AmountOut: 500000, Fee: 126},
+		{Timestamp: day(18, 17*time.Hour), ChannelInID: ChannelACINQ, ChannelOutID: ChannelWoS, AmountIn: 20010, AmountOut: 20000, Fee: 10},
+		{Timestamp: day(24, 22*time.Hour), ChannelInID: ChannelACINQ, ChannelOutID: ChannelWoS, AmountIn: 1250625, AmountOut: 1250000, Fee: 625},
+		{Timestamp: day(29, 6*time.Hour), ChannelInID: ChannelWoS, ChannelOutID: ChannelACINQ, AmountIn: 5001, AmountOut: 5000, Fee: 1},
+	}
+	for i := range events {
+		if err := database.InsertForwardingEvent(&events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func seedHTLCFailures(database *db.Database) error {
+	failures := []db.HTLCFailure{
+		{Timestamp: day(16, 4*time.Hour), ChannelInID: ChannelWoS, ChannelOutID: ChannelACINQ, AmountIn: 2000500,
+			AmountOut: 2000000, Fee: 500, Outcome: "link_failed", Failure: "INSUFFICIENT_BALANCE"},
+		{Timestamp: day(22, 13*time.Hour), ChannelInID: ChannelACINQ, ChannelOutID: ChannelWoS, AmountIn: 30015,
+			AmountOut: 30000, Fee: 15, Outcome: "failed"},
+	}
+	for i := range failures {
+		if err := database.InsertHTLCFailure(&failures[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func seedMissionControl(database *db.Database) error {
+	return database.InsertMissionControlSnapshot(day(30, 0), []db.MissionControlPair{
+		{NodeFrom: peerACINQ, NodeTo: peerWoS, SuccessTime: day(20, 12*time.Hour).Unix(), SuccessAmtMsat: 100000000,
+			FailTime: day(23, 8*time.Hour).Unix(), FailAmtMsat: 800000000},
+		{NodeFrom: peerWoS, NodeTo: peerACINQ, SuccessTime: day(25, 0).Unix(), SuccessAmtMsat: 250000000},
+	})
+}
+
+func seedProbes(database *db.Database) error {
+	probes := []db.ProbeResult{
+		{Timestamp: day(28, 0), Destination: peerACINQ, Amount: 1000, Success: true, LatencyMs: 1200, Attempts: 1, Hops: 1, FeeMsat: 1000},
+		{Timestamp: day(28, 30*time.Minute), Destination: peerWoS, Amount: 1000, Success: false, FailureReason: "FAILURE_REASON_NO_ROUTE", LatencyMs: 4100, Attempts: 3, Hops: 2},
+		{Timestamp: day(29, 0), Destination: peerACINQ, Amount: 1000, Success: true, LatencyMs: 800, Attempts: 1, Hops: 1, FeeMsat: 1000},
+		{Timestamp: day(29, 30*time.Minute), Destination: peerWoS, Amount: 1000, Success: true, LatencyMs: 2600, Attempts: 2, Hops: 2, FeeMsat: 2000},
+	}
+	for i := range probes {
+		if err := database.InsertProbeResult(&probes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func seedPeerPolicies(database *db.Database) error {
+	if _, err := database.SetPeerPolicy(db.PeerPolicy{Pubkey: peerACINQ, Preferred: true, Notes: "reliable outbound"}); err != nil {
+		return err
+	}
+	_, err := database.SetPeerPolicy(db.PeerPolicy{Pubkey: peerWoS, Notes: "watch depletion"})
+	return err
+}
+
+func seedPeerNotes(database *db.Database) error {
+	_, err := database.SetPeerNote(db.PeerNote{Pubkey: peerACINQ, Contact: "@acinq on Telegram",
+		Agreement: "we both keep 250 ppm", AgreedOn: "2024-01-05"})
+	return err
+}
+
+func seedChannelNames(database *db.Database) error {
+	_, err := database.SetChannelName(ChannelWoS, "WoS drain")
+	return err
+}
+
+func seedChannelRequests(database *db.Database) error {
+	return database.InsertChannelRequest(&db.ChannelRequest{
+		Pubkey:   "03" + strings.Repeat("5a", 32),
+		Host:     "node.example.com:9735",
+		Capacity: 2000000,
+		Contact:  "@example on Telegram",
+		Message:  "routing node in Lisbon",
+		Status:   db.ChannelRequestPending,
+	})
+}
+
+func seedLeases(database *db.Database) error {
+	startedAt := day(10, 12*time.Hour)
+	_, err := database.SetChannelLease(db.ChannelLease{
+		ChannelID:      ChannelWoS,
+		Side:           db.LeaseSideBought,
+		Provider:       "pool",
+		Premium:        2500,
+		DurationBlocks: 4032,
+		ExpiryHeight:   828300,
+		StartedAt:      &startedAt,
+		Notes:          "inbound for the shop",
+	})
+	return err
+}
+
+func seedTransfers(database *db.Database) error {
+	transfers := []db.PortfolioTransfer{
+		{Timestamp: day(5, 12*time.Hour), Amount: 3000000, Notes: "funded node wallet"},
+		{Timestamp: day(26, 0), Amount: -250000, Notes: "paid rent"},
+	}
+	for i := range transfers {
+		if err := database.InsertPortfolioTransfer(&transfers[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func seedAnnotations(database *db.Database) error {
+	annotations := []db.Annotation{
+		{Timestamp: day(5, 12*time.Hour), Text: "bought hardware wallet"},
+		{Timestamp: day(10, 18*time.Hour), Text: "opened 2 channels"},
+	}
+	for i := range annotations {
+		if err := database.InsertAnnotation(&annotations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func seedAddresses(database *db.Database) error {
+	address, err := database.InsertOnchainAddress(TrackedAddress, "savings")
+	if err != nil {
+		return err
+	}
+	birthDate := day(9, 0)
+	if _, err := database.SetOnchainAddressBirthday(address.ID, 823900, &birthDate); err != nil {
+		return err
+	}
+	balances := []db.AddressBalance{
+		{AddressID: address.ID, Timestamp: day(10, 12*time.Hour), Balance: 2000000, TxCount: 1, BlockHeight: 824000,
+			BlockHash: "00000000000000000003e3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708"},
+		{AddressID: address.ID, Timestamp: day(20, 12*time.Hour), Balance: 1500000, TxCount: 2, BlockHeight: 824600,
+			BlockHash: "00000000000000000004f4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f70819"},
+	}
+	for i := range balances {
+		if err := database.InsertAddressBalance(&balances[i]); err != nil {
+			return err
+		}
+	}
+
+	retired, err := database.InsertOnchainAddress("bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", "old deposit address")
+	if err != nil {
+		return err
+	}
+	return database.DeleteOnchainAddress(retired.ID)
+}
+
+func seedMultisigWallets(database *db.Database) error {
+	return database.InsertMultisigWallet(&db.MultisigWallet{Name: "Vault", Descriptor: MultisigDescriptor, Format: multisig.FormatDescriptor})
+}
+
+func seedColdStorage(database *db.Database) error {
+	entry, err := database.InsertColdStorageEntry("Coldcard", 5000000, "multisig key 1")
+	if err != nil {
+		return err
+	}
+	if _, err := database.UpdateColdStorageMetadata(entry.ID, db.ColdStorageMetadata{
+		CustodyType:   db.CustodyTypeHardware,
+		DeviceModel:   "Coldcard Mk4",
+		LocationHint:  "home safe",
+		DerivationRef: "m/48'/0'/0'/2'",
+	}); err != nil {
+		return err
+	}
+	history := []db.ColdStorageBalanceHistory{
+		{AccountID: entry.ID, Timestamp: day(1, 0), Balance: 4000000, Notes: "initial"},
+		{AccountID: entry.ID, Timestamp: day(15, 0), Balance: 5000000, PreviousBalance: 4000000, IsVerified: true, Notes: "stacked"},
+	}
+	for i := range history {
+		if err := database.InsertColdStorageHistory(&history[i]); err != nil {
+			return err
+		}
+	}
+
+	lost, err := database.InsertColdStorageEntry("Paper wallet", 100000, "")
+	if err != nil {
+		return err
+	}
+	return database.DeleteColdStorageEntry(lost.ID)
+}
+
+// seedCustodial stores the Strike, Liquid and ecash collectors' snapshots
+func seedCustodial(database *db.Database) error {
+	for _, snapshot := range []db.StrikeBalanceSnapshot{
+		{Timestamp: day(15, 0), Currency: "BTC", Available: 200000, Total: 200000},
+		{Timestamp: day(30, 0), Currency: "BTC", Available: 180000, Total: 190000, Pending: 10000},
+		{Timestamp: day(30, 0), Currency: "USD", Available: 12550, Total: 12550},
+	} {
+		if err := database.InsertStrikeBalanceSnapshot(&snapshot); err != nil {
+			return err
+		}
+	}
+
+	for _, snapshot := range []db.LiquidBalanceSnapshot{
+		{Timestamp: day(15, 0), Confirmed: 300000},
+		{Timestamp: day(30, 0), Confirmed: 300000, Unconfirmed: 25000},
+	} {
+		if err := database.InsertLiquidBalanceSnapshot(&snapshot); err != nil {
+			return err
+		}
+	}
+
+	for _, snapshot := range []db.EcashBalanceSnapshot{
+		{Timestamp: day(15, 0), Protocol: ecash.ProtocolFedimint, Mint: "fed11qgqzc2nhwden5te0vejkg6tdd9h8gepwvejkg6tdd9h8garhduhx6at5d9h8jmn9wshxxmmd9uqqzgxg6s3evnr6m9zdxr6hxkdkukexpcs3mn7mj3g5pc5dfh63l4tj6g9zk4er", Name: "Fixture Federation", Amount: 20000},
+		{Timestamp: day(20, 0), Protocol: ecash.ProtocolCashu, Mint: "https://mint.example.com", Name: "Example Mint", Amount: 5000},
+		{Timestamp: day(30, 0), Protocol: ecash.ProtocolFedimint, Mint: "fed11qgqzc2nhwden5te0vejkg6tdd9h8gepwvejkg6tdd9h8garhduhx6at5d9h8jmn9wshxxmmd9uqqzgxg6s3evnr6m9zdxr6hxkdkukexpcs3mn7mj3g5pc5dfh63l4tj6g9zk4er", Name: "Fixture Federation", Amount: 15000},
+	} {
+		if err := database.InsertEcashBalanceSnapshot(&snapshot); err != nil {
+			return err
+		}
+	}
+
+	return database.InsertStrikeWithdrawal(&db.StrikeWithdrawal{
+		Amount:          150000,
+		DestinationType: db.WithdrawalOnchain,
+		Destination:     "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh",
+		Status:          db.WithdrawalPendingApproval,
+	})
+}
+
+func seedStatements(database *db.Database) error {
+	return database.InsertStatement(&db.Statement{
+		Month:            "2023-12",
+		PeriodStart:      time.Date(2023, time.December, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:        time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		TrackedAddresses: 0,
+		ColdStorage:      4000000,
+		TotalPortfolio:   4000000,
+		Deposits:         4000000,
+	})
+}
@@ -0,0 +1,178 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// MaxCSVRows limits how many snapshots a single import may contain
+const MaxCSVRows = 100000
+
+// componentColumns maps CSV header names to the snapshot field they populate.
+// Header names match the JSON names used by the portfolio API.
+var componentColumns = map[string]func(*db.BalanceSnapshot) *int64{
+	"lightning_local":     func(s *db.BalanceSnapshot) *int64 { return &s.LightningLocal },
+	"lightning_remote":    func(s *db.BalanceSnapshot) *int64 { return &s.LightningRemote },
+	"onchain_confirmed":   func(s *db.BalanceSnapshot) *int64 { return &s.OnchainConfirmed },
+	"onchain_unconfirmed": func(s *db.BalanceSnapshot) *int64 { return &s.OnchainUnconfirmed },
+	"tracked_addresses":   func(s *db.BalanceSnapshot) *int64 { return &s.TrackedAddresses },
+	"cold_storage":        func(s *db.BalanceSnapshot) *int64 { return &s.ColdStorage },
+	"total_portfolio":     func(s *db.BalanceSnapshot) *int64 { return &s.TotalPortfolio },
+	"total_liquid":        func(s *db.BalanceSnapshot) *int64 { return &s.TotalLiquid },
+}
+
+// timestampLayouts are the accepted timestamp formats, tried in order
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseBalanceSnapshotsCSV reads balance snapshots from CSV.
+//
+// The first row is a header with a "timestamp" (or "date") column and one or
+// more component columns: lightning_local, lightning_remote, onchain_confirmed,
+// onchain_unconfirmed, tracked_addresses, cold_storage. total_portfolio and
+// total_liquid are optional and calculated when absent. Amounts are parsed in
+// unit ("sats" or "BTC"); empty cells count as zero. Snapshots are returned in
+// timestamp order.
+func ParseBalanceSnapshotsCSV(r io.Reader, unit string) ([]db.BalanceSnapshot, error) {
+	if _, err := utils.NormalizeUnit(unit); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	timestampCol := -1
+	columns := make([]string, len(header))
+	hasComponent := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[i] = name
+		switch {
+		case name == "timestamp" || name == "date":
+			if timestampCol >= 0 {
+				return nil, errors.New("CSV header has more than one timestamp column")
+			}
+			timestampCol = i
+		case componentColumns[name] != nil:
+			hasComponent = true
+		default:
+			return nil, fmt.Errorf("unknown CSV column %q", header[i])
+		}
+	}
+	if timestampCol < 0 {
+		return nil, errors.New("CSV header must include a timestamp column")
+	}
+	if !hasComponent {
+		return nil, errors.New("CSV header must include at least one balance column")
+	}
+	_, hasTotalPortfolio := indexOf(columns, "total_portfolio")
+	_, hasTotalLiquid := indexOf(columns, "total_liquid")
+
+	var snapshots []db.BalanceSnapshot
+	seen := make(map[int64]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(snapshots) >= MaxCSVRows {
+			return nil, fmt.Errorf("CSV has more than %d rows", MaxCSVRows)
+		}
+
+		var snapshot db.BalanceSnapshot
+		snapshot.Timestamp, err = parseTimestamp(record[timestampCol])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if previous, ok := seen[snapshot.Timestamp.Unix()]; ok {
+			return nil, fmt.Errorf("line %d: duplicate timestamp, already used on line %d", line, previous)
+		}
+		seen[snapshot.Timestamp.Unix()] = line
+
+		for i, value := range record {
+			if i == timestampCol || strings.TrimSpace(value) == "" {
+				continue
+			}
+			amount, err := utils.ParseAmountToSats(value, unit)
+			if err != nil {
+				return nil, fmt.Errorf("line %d, column %s: %w", line, columns[i], err)
+			}
+			*componentColumns[columns[i]](&snapshot) = amount
+		}
+
+		if !hasTotalLiquid {
+			snapshot.TotalLiquid = snapshot.LightningLocal + snapshot.OnchainConfirmed + snapshot.TrackedAddresses
+		}
+		if !hasTotalPortfolio {
+			snapshot.TotalPortfolio = snapshot.TotalLiquid + snapshot.LightningRemote + snapshot.ColdStorage
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if len(snapshots) == 0 {
+		return nil, errors.New("CSV has no data rows")
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Timestamp.Before(snapshots[j].Timestamp)
+	})
+
+	return snapshots, nil
+}
+
+// parseTimestamp accepts RFC3339, common date/time layouts or unix seconds
+func parseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("timestamp is required")
+	}
+
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+func indexOf(values []string, target string) (int, bool) {
+	for i, v := range values {
+		if v == target {
+			return i, true
+		}
+	}
+	return -1, false
+}
@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseBalanceSnapshotsCSV(t *testing.T) {
+	input := `date,lightning_local,onchain_confirmed,cold_storage
+# exported from spreadsheet
+2023-02-01,200000,100000,
+2023-01-01,100000,50000,1000000
+`
+	snapshots, err := ParseBalanceSnapshotsCSV(strings.NewReader(input), "sats")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(snapshots), 2)
+
+	// Sorted by timestamp
+	testutils.AssertEqual(t, snapshots[0].Timestamp.Format("2006-01-02"), "2023-01-01")
+	testutils.AssertEqual(t, snapshots[0].ColdStorage, int64(1000000))
+	testutils.AssertEqual(t, snapshots[0].TotalLiquid, int64(150000))
+	testutils.AssertEqual(t, snapshots[0].TotalPortfolio, int64(1150000))
+
+	// Empty cells are zero
+	testutils.AssertEqual(t, snapshots[1].ColdStorage, int64(0))
+}
+
+func TestParseBalanceSnapshotsCSVBTCUnit(t *testing.T) {
+	input := "timestamp,cold_storage,total_portfolio\n2023-01-01T00:00:00Z,0.5,0.75\n"
+
+	snapshots, err := ParseBalanceSnapshotsCSV(strings.NewReader(input), "BTC")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, snapshots[0].ColdStorage, int64(50000000))
+	// Explicit totals are kept as provided
+	testutils.AssertEqual(t, snapshots[0].TotalPortfolio, int64(75000000))
+
+	// A sign after the decimal point is not read as part of the fraction
+	for _, amount := range []string{"1.-5", "1.+5", "+1.5"} {
+		_, err := ParseBalanceSnapshotsCSV(strings.NewReader("timestamp,cold_storage\n2023-01-01,"+amount+"\n"), "BTC")
+		testutils.AssertError(t, err, amount)
+	}
+}
+
+func TestParseBalanceSnapshotsCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"no timestamp column", "lightning_local\n100\n"},
+		{"no balance column", "timestamp\n2023-01-01\n"},
+		{"unknown column", "timestamp,lightning\n2023-01-01,100\n"},
+		{"header only", "timestamp,cold_storage\n"},
+		{"bad timestamp", "timestamp,cold_storage\nyesterday,100\n"},
+		{"bad amount", "timestamp,cold_storage\n2023-01-01,lots\n"},
+		{"duplicate timestamp", "timestamp,cold_storage\n2023-01-01,100\n2023-01-01,200\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBalanceSnapshotsCSV(strings.NewReader(tt.input), "sats")
+			testutils.AssertError(t, err, tt.name)
+		})
+	}
+}
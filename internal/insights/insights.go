@@ -0,0 +1,269 @@
+// Package insights turns the stored analytics into short observations
+// about a period, such as which channel earned most of the routing fees or
+// whose inbound liquidity drained, for the dashboard and reports.
+package insights
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// Kinds of insight
+const (
+	KindPortfolioChange = "portfolio_change"
+	KindFeeChange       = "fee_change"
+	KindForwardChange   = "forward_change"
+	KindFeeShare        = "fee_share"
+	KindInboundDrop     = "inbound_drop"
+)
+
+// Thresholds below which a change is not worth mentioning
+const (
+	// activityChange is the relative change in fees or forwards against
+	// the previous period of the same length
+	activityChange = 0.25
+	// feeShare is the share of routing fees one channel has to earn
+	feeShare = 0.25
+	// inboundDrop is the relative drop in a channel's remote balance, and
+	// minInboundShare how much of the capacity it must have started at
+	inboundDrop     = 0.5
+	minInboundShare = 0.1
+)
+
+// Insight is one observation about a period
+type Insight struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	// ChannelID is set for insights about one channel
+	ChannelID string `json:"channel_id,omitempty"`
+	// Change is the relative change, or for fee_share the share, e.g. 0.4
+	Change float64 `json:"change"`
+}
+
+// Generate returns the insights for the period from to to: the portfolio's
+// gain, routing fees and forwards against the period before, the channel
+// that earned the largest share of fees and channels whose inbound
+// liquidity dropped. Only changes past the thresholds above are reported.
+func Generate(database *db.Database, from, to time.Time) ([]Insight, error) {
+	insights := []Insight{}
+
+	portfolio, err := portfolioInsight(database, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if portfolio != nil {
+		insights = append(insights, *portfolio)
+	}
+
+	activity, err := activityInsights(database, from, to)
+	if err != nil {
+		return nil, err
+	}
+	insights = append(insights, activity...)
+
+	snapshots, err := database.GetLatestChannelSnapshots()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channels: %w", err)
+	}
+	names, err := database.GetChannelNameMap()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel names: %w", err)
+	}
+	for _, snapshot := range snapshots {
+		if names[snapshot.ChannelID] == "" {
+			names[snapshot.ChannelID] = channelName(snapshot)
+		}
+	}
+
+	share, err := feeShareInsight(database, from, to, names)
+	if err != nil {
+		return nil, err
+	}
+	if share != nil {
+		insights = append(insights, *share)
+	}
+
+	drops, err := inboundInsights(database, snapshots, names, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(insights, drops...), nil
+}
+
+// portfolioInsight reports the change in portfolio value less transfers
+// between the first and last balance snapshot of the period
+func portfolioInsight(database *db.Database, from, to time.Time) (*Insight, error) {
+	snapshots, err := database.GetBalanceSnapshots(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance snapshots: %w", err)
+	}
+	if len(snapshots) < 2 {
+		return nil, nil
+	}
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+
+	transfers, err := database.GetPortfolioTransfers(first.Timestamp, last.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+	var net int64
+	for _, transfer := range transfers {
+		net += transfer.Amount
+	}
+	gain := last.TotalPortfolio - first.TotalPortfolio - net
+	if gain == 0 || first.TotalPortfolio <= 0 {
+		return nil, nil
+	}
+
+	change := float64(gain) / float64(first.TotalPortfolio)
+	verb := "gained"
+	if gain < 0 {
+		verb = "lost"
+	}
+	message := fmt.Sprintf("Portfolio %s %s (%s)", verb, utils.FormatSats(abs(gain)), percent(math.Abs(change)))
+	if net != 0 {
+		message += " excluding transfers"
+	}
+	return &Insight{Kind: KindPortfolioChange, Message: message, Change: change}, nil
+}
+
+// activityInsights compares routing fees and forwards with the period of
+// the same length before from
+func activityInsights(database *db.Database, from, to time.Time) ([]Insight, error) {
+	current, err := database.GetForwardingStats(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forwarding stats: %w", err)
+	}
+	previous, err := database.GetForwardingStats(from.Add(-to.Sub(from)), from.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous forwarding stats: %w", err)
+	}
+
+	var insights []Insight
+	if change, ok := relativeChange(previous.TotalFees, current.TotalFees); ok {
+		insights = append(insights, Insight{
+			Kind:    KindFeeChange,
+			Message: fmt.Sprintf("Routing fees %s %s to %s", direction(change), percent(math.Abs(change)), utils.FormatSats(current.TotalFees)),
+			Change:  change,
+		})
+	}
+	if change, ok := relativeChange(previous.ForwardCount, current.ForwardCount); ok {
+		insights = append(insights, Insight{
+			Kind:    KindForwardChange,
+			Message: fmt.Sprintf("Forwards %s %s to %d", direction(change), percent(math.Abs(change)), current.ForwardCount),
+			Change:  change,
+		})
+	}
+	return insights, nil
+}
+
+// feeShareInsight reports the channel that earned the most routing fees
+// when its share reaches feeShare
+func feeShareInsight(database *db.Database, from, to time.Time, names map[string]string) (*Insight, error) {
+	stats, err := database.GetForwardingStats(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forwarding stats: %w", err)
+	}
+	if stats.TotalFees <= 0 {
+		return nil, nil
+	}
+	top, err := database.GetTopFeeChannels(from, to, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top channels: %w", err)
+	}
+	if len(top) == 0 {
+		return nil, nil
+	}
+
+	share := float64(top[0].Fees) / float64(stats.TotalFees)
+	if share < feeShare {
+		return nil, nil
+	}
+	name := names[top[0].ChannelID]
+	if name == "" {
+		name = top[0].ChannelID
+	}
+	return &Insight{
+		Kind:      KindFeeShare,
+		Message:   fmt.Sprintf("Channel %s earned %s of routing fees", name, percent(share)),
+		ChannelID: top[0].ChannelID,
+		Change:    share,
+	}, nil
+}
+
+// inboundInsights reports the open channels whose remote balance dropped
+// by inboundDrop or more from their first snapshot of the period to their
+// last, largest drop first
+func inboundInsights(database *db.Database, channels []db.ChannelSnapshot, names map[string]string, from, to time.Time) ([]Insight, error) {
+	var insights []Insight
+	for _, channel := range channels {
+		history, err := database.GetChannelSnapshots(channel.ChannelID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get history of channel %s: %w", channel.ChannelID, err)
+		}
+		if len(history) < 2 {
+			continue
+		}
+
+		start, end := history[0], history[len(history)-1]
+		if start.RemoteBalance <= 0 || float64(start.RemoteBalance) < minInboundShare*float64(start.Capacity) {
+			continue
+		}
+
+		change := float64(end.RemoteBalance-start.RemoteBalance) / float64(start.RemoteBalance)
+		if change > -inboundDrop {
+			continue
+		}
+		insights = append(insights, Insight{
+			Kind: KindInboundDrop,
+			Message: fmt.Sprintf("Inbound from %s dropped %s to %s", names[channel.ChannelID], percent(-change),
+				utils.FormatSats(end.RemoteBalance)),
+			ChannelID: channel.ChannelID,
+			Change:    change,
+		})
+	}
+
+	sort.SliceStable(insights, func(i, j int) bool { return insights[i].Change < insights[j].Change })
+	return insights, nil
+}
+
+// relativeChange returns the change from previous to current when both
+// periods saw activity and it reaches activityChange
+func relativeChange(previous, current int64) (float64, bool) {
+	if previous <= 0 {
+		return 0, false
+	}
+	change := float64(current-previous) / float64(previous)
+	return change, math.Abs(change) >= activityChange
+}
+
+func channelName(snapshot db.ChannelSnapshot) string {
+	if snapshot.PeerAlias != "" {
+		return snapshot.PeerAlias
+	}
+	return snapshot.ChannelID
+}
+
+func direction(change float64) string {
+	if change < 0 {
+		return "fell"
+	}
+	return "rose"
+}
+
+// percent formats a fraction as a whole percentage, e.g. 0.404 as "40%"
+func percent(fraction float64) string {
+	return fmt.Sprintf("%.0f%%", fraction*100)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
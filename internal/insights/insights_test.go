@@ -0,0 +1,88 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestGenerate(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	from, to := start.AddDate(0, 0, 7), start.AddDate(0, 0, 14)
+	at := func(days int) time.Time { return start.AddDate(0, 0, days).Add(time.Hour) }
+
+	testutils.AssertNoError(t, database.InsertBalanceSnapshots([]db.BalanceSnapshot{
+		{Timestamp: at(7), TotalPortfolio: 1000000},
+		{Timestamp: at(13), TotalPortfolio: 1600000},
+	}))
+	testutils.AssertNoError(t, database.InsertPortfolioTransfer(&db.PortfolioTransfer{Timestamp: at(10), Amount: 500000}))
+
+	// The week before earned 100 sats in two forwards; this week 400 in two,
+	// 300 of them through ACINQ
+	for _, event := range []db.ForwardingEvent{
+		{Timestamp: at(1), ChannelInID: "1", ChannelOutID: "2", AmountOut: 100000, Fee: 50},
+		{Timestamp: at(2), ChannelInID: "1", ChannelOutID: "2", AmountOut: 100000, Fee: 50},
+		{Timestamp: at(8), ChannelInID: "2", ChannelOutID: "1", AmountOut: 300000, Fee: 300},
+		{Timestamp: at(9), ChannelInID: "1", ChannelOutID: "2", AmountOut: 100000, Fee: 100},
+	} {
+		testutils.AssertNoError(t, database.InsertForwardingEvent(&event))
+	}
+
+	// ACINQ's inbound drains from 800k to 240k; the other channel's barely moves
+	for _, day := range []struct {
+		days           int
+		acinq, another int64
+	}{{8, 800000, 500000}, {10, 500000, 450000}, {13, 240000, 400000}} {
+		testutils.AssertNoError(t, database.InsertChannelSnapshots([]db.ChannelSnapshot{
+			{Timestamp: at(day.days), ChannelID: "1", Capacity: 1000000, RemoteBalance: day.acinq, LocalBalance: 1000000 - day.acinq, Active: true, PeerAlias: "ACINQ"},
+			{Timestamp: at(day.days), ChannelID: "2", Capacity: 1000000, RemoteBalance: day.another, LocalBalance: 1000000 - day.another, Active: true},
+		}))
+	}
+
+	insights, err := Generate(database, from, to)
+	testutils.AssertNoError(t, err)
+
+	kinds := make(map[string]Insight)
+	for _, insight := range insights {
+		kinds[insight.Kind] = insight
+	}
+	testutils.AssertEqual(t, len(insights), 4)
+	testutils.AssertEqual(t, kinds[KindPortfolioChange].Message, "Portfolio gained 100.0K sats (10%) excluding transfers")
+	testutils.AssertEqual(t, kinds[KindFeeChange].Message, "Routing fees rose 300% to 400 sats")
+	testutils.AssertEqual(t, kinds[KindFeeShare].Message, "Channel ACINQ earned 75% of routing fees")
+	testutils.AssertEqual(t, kinds[KindFeeShare].ChannelID, "1")
+	testutils.AssertEqual(t, kinds[KindInboundDrop].Message, "Inbound from ACINQ dropped 70% to 240.0K sats")
+
+	// Forwards held steady at two, so there is nothing to say about them
+	if _, ok := kinds[KindForwardChange]; ok {
+		t.Error("expected no forward_change insight for an unchanged forward count")
+	}
+
+	// A channel's own name is used instead of its peer's alias
+	_, err = database.SetChannelName("1", "ACINQ main")
+	testutils.AssertNoError(t, err)
+	insights, err = Generate(database, from, to)
+	testutils.AssertNoError(t, err)
+	for _, insight := range insights {
+		kinds[insight.Kind] = insight
+	}
+	testutils.AssertEqual(t, kinds[KindFeeShare].Message, "Channel ACINQ main earned 75% of routing fees")
+	testutils.AssertEqual(t, kinds[KindInboundDrop].Message, "Inbound from ACINQ main dropped 70% to 240.0K sats")
+}
+
+func TestGenerateWithoutData(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	now := time.Now()
+	insights, err := Generate(database, now.AddDate(0, 0, -7), now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(insights), 0)
+}
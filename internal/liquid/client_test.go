@@ -0,0 +1,39 @@
+package liquid
+
+import (
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseBalances(t *testing.T) {
+	output := []byte(`{
+		"mine": {
+			"trusted": {"bitcoin": 0.015, "ce091c998b83c78bb71a632313ba3760f1763d9cfcffae02258ffa9865a37bd2": 100},
+			"untrusted_pending": {"bitcoin": 0.0002},
+			"immature": {"bitcoin": 0}
+		},
+		"watchonly": {
+			"trusted": {"bitcoin": 0.001},
+			"untrusted_pending": {},
+			"immature": {"bitcoin": 0.00000001}
+		}
+	}`)
+
+	balance, err := parseBalances(output)
+	testutils.AssertNoError(t, err)
+	// Other assets such as USDt are not L-BTC and are left out
+	testutils.AssertEqual(t, balance.Confirmed, int64(1600000))
+	testutils.AssertEqual(t, balance.Unconfirmed, int64(20001))
+	testutils.AssertEqual(t, balance.Total(), int64(1620001))
+}
+
+func TestParseBalancesWithoutWatchOnly(t *testing.T) {
+	balance, err := parseBalances([]byte(`{"mine": {"trusted": {"bitcoin": 0.5}}}`))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, balance.Confirmed, int64(50000000))
+	testutils.AssertEqual(t, balance.Unconfirmed, int64(0))
+
+	_, err = parseBalances([]byte(`not json`))
+	testutils.AssertError(t, err, "failed to parse getbalances")
+}
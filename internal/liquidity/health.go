@@ -0,0 +1,66 @@
+package liquidity
+
+import (
+	"math"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// HealthIdleDays is how long since its last forward a channel takes to lose
+// all of its activity points
+const HealthIdleDays = 30
+
+// HealthTargetPPM is the 30-day fee income, in ppm of capacity, that earns
+// a channel all of its earnings points
+const HealthTargetPPM = 500
+
+// Most points each part of the health score gives
+const (
+	healthOnlinePoints   = 15
+	healthBalancePoints  = 25
+	healthActivityPoints = 30
+	healthEarningsPoints = 30
+)
+
+// Health scores a channel from 0 to 100 as the sum of its parts, so a low
+// score shows what is wrong with the channel
+type Health struct {
+	Score int `json:"score"`
+	// Online is 15 when the channel is active
+	Online int `json:"online"`
+	// Balance is up to 25, the most at half the capacity on either side
+	Balance int `json:"balance"`
+	// Activity is up to 30, the most right after a forward and none once
+	// HealthIdleDays pass without one
+	Activity int `json:"activity"`
+	// Earnings is up to 30, the most once the last 30 days' fees reach
+	// HealthTargetPPM of the capacity
+	Earnings int `json:"earnings"`
+}
+
+// HealthOf scores channel as of its snapshot time, given the fees it earned
+// as the outgoing side over the 30 days before and its last forward in
+// either direction, nil if it never forwarded
+func HealthOf(channel db.ChannelSnapshot, fees30d db.ChannelFeeStats, lastForward *time.Time) Health {
+	var health Health
+	if channel.Active {
+		health.Online = healthOnlinePoints
+	}
+
+	ratio := LocalRatio(channel.LocalBalance, channel.Capacity)
+	health.Balance = int(math.Round(healthBalancePoints * (1 - math.Abs(ratio-0.5)*2)))
+
+	if lastForward != nil {
+		idle := channel.Timestamp.Sub(*lastForward).Hours() / 24
+		health.Activity = int(math.Round(healthActivityPoints * math.Max(0, math.Min(1, 1-idle/HealthIdleDays))))
+	}
+
+	if channel.Capacity > 0 {
+		ppm := float64(fees30d.Fees) * 1_000_000 / float64(channel.Capacity)
+		health.Earnings = int(math.Round(healthEarningsPoints * math.Min(1, ppm/HealthTargetPPM)))
+	}
+
+	health.Score = health.Online + health.Balance + health.Activity + health.Earnings
+	return health
+}
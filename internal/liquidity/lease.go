@@ -0,0 +1,69 @@
+package liquidity
+
+import (
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// BlocksPerDay is the expected number of blocks mined in a day
+const BlocksPerDay = 144
+
+// blockInterval is the expected time between blocks
+const blockInterval = 10 * time.Minute
+
+// LeaseMetrics are the expiry countdown of a channel lease and how its
+// routing income compares to the premium
+type LeaseMetrics struct {
+	// BlocksRemaining and EstimatedExpiry are omitted when the chain height
+	// or the expiry height is unknown
+	BlocksRemaining *int64     `json:"blocks_remaining,omitempty"`
+	EstimatedExpiry *time.Time `json:"estimated_expiry,omitempty"`
+	Expired         bool       `json:"expired"`
+	// FeesEarned is routing income of forwards out of the channel since the lease started
+	FeesEarned   int64 `json:"fees_earned"`
+	ForwardCount int64 `json:"forward_count"`
+	// NetReturn is the premium plus fees for a sold lease, and the fees less
+	// the premium paid for a bought one
+	NetReturn int64 `json:"net_return"`
+	// PremiumCovered is fees earned as a fraction of the premium; a bought
+	// lease has paid for itself at 1. Omitted when no premium is recorded.
+	PremiumCovered *float64 `json:"premium_covered,omitempty"`
+	// PremiumYield is the premium as an annualised percentage of capacity
+	PremiumYield *float64 `json:"premium_yield,omitempty"`
+}
+
+// Lease computes the metrics of a lease on a channel of the given capacity
+// that has earned fees. height is the current block height, or 0 if unknown.
+func Lease(lease db.ChannelLease, capacity int64, fees db.ChannelFeeStats, height int64, now time.Time) LeaseMetrics {
+	metrics := LeaseMetrics{
+		FeesEarned:   fees.Fees,
+		ForwardCount: fees.ForwardCount,
+	}
+
+	if height > 0 && lease.ExpiryHeight > 0 {
+		remaining := max(lease.ExpiryHeight-height, 0)
+		expiry := now.Add(time.Duration(remaining) * blockInterval)
+		metrics.BlocksRemaining = &remaining
+		metrics.EstimatedExpiry = &expiry
+		metrics.Expired = remaining == 0
+	}
+
+	if lease.Side == db.LeaseSideSold {
+		metrics.NetReturn = lease.Premium + fees.Fees
+	} else {
+		metrics.NetReturn = fees.Fees - lease.Premium
+	}
+
+	if lease.Premium > 0 {
+		covered := float64(fees.Fees) / float64(lease.Premium)
+		metrics.PremiumCovered = &covered
+
+		if capacity > 0 && lease.DurationBlocks > 0 {
+			yield := float64(lease.Premium) / float64(capacity) * (365 * BlocksPerDay / float64(lease.DurationBlocks)) * 100
+			metrics.PremiumYield = &yield
+		}
+	}
+
+	return metrics
+}
@@ -0,0 +1,136 @@
+// Package liquidity classifies channels by how much of their capacity is on
+// the local side, so depleted and saturated channels can be flagged.
+package liquidity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Channel states
+const (
+	StateBalanced  = "balanced"
+	StateDepleted  = "depleted"  // Local ratio below the low threshold
+	StateSaturated = "saturated" // Local ratio above the high threshold
+)
+
+// Default thresholds
+const (
+	DefaultLowRatio   = 0.05
+	DefaultHighRatio  = 0.95
+	DefaultHysteresis = 0.02
+)
+
+// Thresholds are local balance ratios (0-1) at which a channel becomes
+// depleted or saturated. A flagged channel only returns to balanced once it
+// is Hysteresis past the threshold, so a ratio hovering around it does not
+// flap between states.
+type Thresholds struct {
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
+	Hysteresis float64 `json:"hysteresis"`
+}
+
+// DefaultThresholds returns the 5% / 95% thresholds with 2% hysteresis
+func DefaultThresholds() Thresholds {
+	return Thresholds{Low: DefaultLowRatio, High: DefaultHighRatio, Hysteresis: DefaultHysteresis}
+}
+
+// Validate checks that the thresholds are ordered ratios between 0 and 1
+func (t Thresholds) Validate() error {
+	if t.Low < 0 || t.High > 1 || t.Low >= t.High {
+		return fmt.Errorf("thresholds must satisfy 0 <= low < high <= 1, got low=%g high=%g", t.Low, t.High)
+	}
+	if t.Hysteresis < 0 || t.Low+t.Hysteresis > t.High-t.Hysteresis {
+		return fmt.Errorf("hysteresis %g is too wide for low=%g high=%g", t.Hysteresis, t.Low, t.High)
+	}
+	return nil
+}
+
+// Config holds the default thresholds and per-channel overrides
+type Config struct {
+	Default   Thresholds
+	Overrides map[string]Thresholds
+}
+
+// NewConfig returns a config using the default thresholds for every channel
+func NewConfig() Config {
+	return Config{Default: DefaultThresholds(), Overrides: map[string]Thresholds{}}
+}
+
+// For returns the thresholds that apply to a channel
+func (c Config) For(channelID string) Thresholds {
+	if t, ok := c.Overrides[channelID]; ok {
+		return t
+	}
+	return c.Default
+}
+
+// ParseOverrides parses per-channel thresholds written as
+// "chan_id:low:high" entries separated by commas, e.g.
+// "812345678901234567:0.1:0.9". Overrides use the default hysteresis.
+func ParseOverrides(value string, hysteresis float64) (map[string]Thresholds, error) {
+	overrides := make(map[string]Thresholds)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// Short channel IDs may contain colons, so take the ratios from the end
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid override %q: expected chan_id:low:high", entry)
+		}
+		channelID := strings.Join(parts[:len(parts)-2], ":")
+		low, err := strconv.ParseFloat(parts[len(parts)-2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid low ratio in override %q: %w", entry, err)
+		}
+		high, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid high ratio in override %q: %w", entry, err)
+		}
+
+		t := Thresholds{Low: low, High: high, Hysteresis: hysteresis}
+		if err := t.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid override for channel %s: %w", channelID, err)
+		}
+		overrides[channelID] = t
+	}
+	return overrides, nil
+}
+
+// LocalRatio returns the share of capacity on the local side, or 0 for an
+// empty channel
+func LocalRatio(local, capacity int64) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+	return float64(local) / float64(capacity)
+}
+
+// Classify returns the state of a channel with the given local ratio. prev is
+// the channel's previous state ("" if unknown) and applies the hysteresis.
+func Classify(ratio float64, t Thresholds, prev string) string {
+	switch prev {
+	case StateDepleted:
+		if ratio < t.Low+t.Hysteresis {
+			return StateDepleted
+		}
+	case StateSaturated:
+		if ratio > t.High-t.Hysteresis {
+			return StateSaturated
+		}
+	}
+
+	switch {
+	case ratio < t.Low:
+		return StateDepleted
+	case ratio > t.High:
+		return StateSaturated
+	default:
+		return StateBalanced
+	}
+}
@@ -0,0 +1,137 @@
+package liquidity
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestClassify(t *testing.T) {
+	th := DefaultThresholds()
+
+	tests := []struct {
+		name  string
+		ratio float64
+		prev  string
+		want  string
+	}{
+		{"balanced", 0.5, "", StateBalanced},
+		{"depleted", 0.04, "", StateDepleted},
+		{"saturated", 0.96, "", StateSaturated},
+		{"at low threshold", 0.05, "", StateBalanced},
+		{"depleted within hysteresis", 0.06, StateDepleted, StateDepleted},
+		{"depleted recovered", 0.08, StateDepleted, StateBalanced},
+		{"saturated within hysteresis", 0.94, StateSaturated, StateSaturated},
+		{"saturated recovered", 0.92, StateSaturated, StateBalanced},
+		{"depleted flips to saturated", 0.99, StateDepleted, StateSaturated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutils.AssertEqual(t, Classify(tt.ratio, th, tt.prev), tt.want)
+		})
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	overrides, err := ParseOverrides("812345678901234567:0.1:0.9, 700000:1:0:0.2:0.8", DefaultHysteresis)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(overrides), 2)
+	testutils.AssertEqual(t, overrides["812345678901234567"].Low, 0.1)
+	testutils.AssertEqual(t, overrides["700000:1:0"].High, 0.8)
+
+	config := NewConfig()
+	config.Overrides = overrides
+	testutils.AssertEqual(t, config.For("700000:1:0").Low, 0.2)
+	testutils.AssertEqual(t, config.For("other").Low, DefaultLowRatio)
+
+	_, err = ParseOverrides("123:0.9:0.1", DefaultHysteresis)
+	testutils.AssertError(t, err, "low < high")
+
+	_, err = ParseOverrides("123:0.1", DefaultHysteresis)
+	testutils.AssertError(t, err, "expected chan_id:low:high")
+}
+
+func TestLease(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	fees := db.ChannelFeeStats{ChannelID: "1", ForwardCount: 12, Fees: 2500}
+
+	// 1M sats of inbound bought for 10,000 sats over about 3 months
+	bought := db.ChannelLease{ChannelID: "1", Side: db.LeaseSideBought, Premium: 10000, DurationBlocks: 12960, ExpiryHeight: 850000}
+	metrics := Lease(bought, 1000000, fees, 849856, now)
+	testutils.AssertEqual(t, *metrics.BlocksRemaining, int64(144))
+	testutils.AssertEqual(t, *metrics.EstimatedExpiry, now.Add(24*time.Hour))
+	testutils.AssertEqual(t, metrics.Expired, false)
+	testutils.AssertEqual(t, metrics.NetReturn, int64(-7500))
+	testutils.AssertEqual(t, *metrics.PremiumCovered, 0.25)
+	if yield := *metrics.PremiumYield; math.Abs(yield-4.0555) > 0.001 {
+		t.Errorf("Expected a premium yield of about 4.06%%, got %f", yield)
+	}
+
+	sold := bought
+	sold.Side = db.LeaseSideSold
+	metrics = Lease(sold, 1000000, fees, 851000, now)
+	testutils.AssertEqual(t, *metrics.BlocksRemaining, int64(0))
+	testutils.AssertEqual(t, metrics.Expired, true)
+	testutils.AssertEqual(t, metrics.NetReturn, int64(12500))
+
+	// Without a chain height or premium there is no countdown or coverage
+	metrics = Lease(db.ChannelLease{Side: db.LeaseSideBought, ExpiryHeight: 850000}, 1000000, fees, 0, now)
+	if metrics.BlocksRemaining != nil || metrics.PremiumCovered != nil || metrics.PremiumYield != nil {
+		t.Errorf("Expected no countdown or premium metrics, got %+v", metrics)
+	}
+}
+
+func TestRefillFor(t *testing.T) {
+	fees := db.ChannelFeeStats{ChannelID: "1", Fees: 3000}
+
+	// 300k sats to refill, earning 100 sats a day: 333 ppm a day
+	refill := RefillFor(200000, 1000000, fees, 30)
+	testutils.AssertEqual(t, refill.Amount, int64(300000))
+	testutils.AssertEqual(t, refill.FeesPerDay, 100.0)
+	if math.Abs(refill.Priority-333.333) > 0.001 {
+		t.Errorf("Expected a priority of about 333 ppm, got %f", refill.Priority)
+	}
+
+	// The same income over a smaller refill ranks higher
+	smaller := RefillFor(400000, 1000000, fees, 30)
+	if smaller.Priority <= refill.Priority {
+		t.Errorf("Expected a smaller refill to rank higher, got %f <= %f", smaller.Priority, refill.Priority)
+	}
+
+	idle := RefillFor(0, 1000000, db.ChannelFeeStats{}, 30)
+	testutils.AssertEqual(t, idle.Priority, 0.0)
+
+	if refill := RefillFor(600000, 1000000, fees, 30); refill != nil {
+		t.Errorf("Expected no refill for a channel above half local balance, got %+v", refill)
+	}
+}
+
+func TestHealthOf(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	channel := db.ChannelSnapshot{Timestamp: now, Capacity: 1000000, LocalBalance: 500000, Active: true}
+	yesterday := now.AddDate(0, 0, -1)
+
+	// Balanced, active, forwarding daily and earning the target
+	health := HealthOf(channel, db.ChannelFeeStats{Fees: 500}, &now)
+	testutils.AssertEqual(t, health, Health{Score: 100, Online: 15, Balance: 25, Activity: 30, Earnings: 30})
+
+	// Idle for 15 of HealthIdleDays, earning a fifth of the target
+	fortnight := now.AddDate(0, 0, -15)
+	health = HealthOf(channel, db.ChannelFeeStats{Fees: 100}, &fortnight)
+	testutils.AssertEqual(t, health.Activity, 15)
+	testutils.AssertEqual(t, health.Earnings, 6)
+
+	// Offline, all on one side and never forwarded
+	channel.Active = false
+	channel.LocalBalance = 0
+	health = HealthOf(channel, db.ChannelFeeStats{}, nil)
+	testutils.AssertEqual(t, health, Health{})
+
+	// A quarter out of balance loses half the balance points
+	channel.LocalBalance = 750000
+	testutils.AssertEqual(t, HealthOf(channel, db.ChannelFeeStats{}, &yesterday).Balance, 13)
+}
@@ -0,0 +1,36 @@
+package liquidity
+
+import (
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// RefillLookbackDays is the routing history a refill's priority is based on
+const RefillLookbackDays = 30
+
+// Refill is the local balance a depleted channel needs to get back to half
+// its capacity, weighted by how quickly the channel turns outbound
+// liquidity into fees
+type Refill struct {
+	Amount int64 `json:"amount"`
+	// FeesPerDay is the channel's routing income per day over the lookback
+	FeesPerDay float64 `json:"fees_per_day"`
+	// Priority is FeesPerDay per million sats refilled: the daily return of
+	// the refill in ppm. Channels that earned nothing have priority 0.
+	Priority float64 `json:"priority"`
+}
+
+// RefillFor returns the refill of a channel that earned fees as the
+// outgoing side over days, or nil when it holds at least half its capacity
+func RefillFor(local, capacity int64, fees db.ChannelFeeStats, days float64) *Refill {
+	amount := capacity/2 - local
+	if amount <= 0 {
+		return nil
+	}
+
+	refill := &Refill{Amount: amount}
+	if days > 0 {
+		refill.FeesPerDay = float64(fees.Fees) / days
+		refill.Priority = refill.FeesPerDay * 1_000_000 / float64(amount)
+	}
+	return refill
+}
@@ -0,0 +1,105 @@
+// Package listen opens the sockets the HTTP services serve on and shuts them
+// down cleanly. Besides host:port, services accept unix:/path so a reverse
+// proxy on the same machine can reach them without any TCP port being open.
+//
+// Unix sockets are created with SocketMode, which lets the service user and
+// its group connect: add the proxy's user to the service's group rather than
+// opening the socket to everyone. The socket is removed again on shutdown.
+package listen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// UnixPrefix marks an address as a unix socket path, e.g. unix:/run/api.sock
+const UnixPrefix = "unix:"
+
+// SocketMode limits unix sockets to the service user and its group
+const SocketMode = 0660
+
+// ShutdownTimeout is how long in-flight requests get to finish on shutdown
+const ShutdownTimeout = 10 * time.Second
+
+// Endpoint is a handler served on a listener
+type Endpoint struct {
+	Listener net.Listener
+	Handler  http.Handler
+}
+
+// Listen opens addr, which is either host:port or unix:/path
+func Listen(addr string) (net.Listener, error) {
+	if path, ok := IsUnix(addr); ok {
+		return Unix(path, SocketMode)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// IsUnix returns the socket path of a unix:/path address
+func IsUnix(addr string) (string, bool) {
+	return strings.CutPrefix(addr, UnixPrefix)
+}
+
+// Unix listens on a unix socket at path with the given permissions. A socket
+// left behind by a crashed run is replaced, but any other file at path is an
+// error rather than being deleted.
+func Unix(path string, mode fs.FileMode) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("empty unix socket path")
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// Serve serves every endpoint until ctx is done or one of them fails, then
+// shuts them all down, letting in-flight requests finish for up to
+// ShutdownTimeout. Closing a unix listener removes its socket. Serve returns
+// nil after a shutdown through ctx.
+func Serve(ctx context.Context, endpoints ...Endpoint) error {
+	servers := make([]*http.Server, len(endpoints))
+	errs := make(chan error, len(endpoints))
+	for i, endpoint := range endpoints {
+		servers[i] = &http.Server{Handler: endpoint.Handler}
+		go func(server *http.Server, listener net.Listener) {
+			errs <- server.Serve(listener)
+		}(servers[i], endpoint.Listener)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil && err == nil {
+			err = fmt.Errorf("shutdown: %w", shutdownErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package listen
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+
+	listener, err := Listen(UnixPrefix + path)
+	testutils.AssertNoError(t, err)
+	info, err := os.Stat(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, info.Mode().Perm(), os.FileMode(SocketMode))
+
+	// A socket left behind by a crashed run does not block the next start
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	listener, err = Listen(UnixPrefix + path)
+	testutils.AssertNoError(t, err)
+	listener.Close()
+
+	// Other files are never removed
+	regular := filepath.Join(t.TempDir(), "api.sock")
+	testutils.AssertNoError(t, os.WriteFile(regular, nil, 0600))
+	_, err = Listen(UnixPrefix + regular)
+	testutils.AssertError(t, err, "is not a socket")
+
+	_, err = Listen(UnixPrefix)
+	testutils.AssertError(t, err, "empty unix socket path")
+}
+
+func TestServeRemovesSocketOnShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := Listen(UnixPrefix + path)
+	testutils.AssertNoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, Endpoint{Listener: listener, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "ok")
+		})})
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://api/health")
+	testutils.AssertNoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	testutils.AssertEqual(t, string(body), "ok")
+
+	cancel()
+	select {
+	case err := <-done:
+		testutils.AssertNoError(t, err)
+	case <-time.After(ShutdownTimeout):
+		t.Fatal("Serve did not return after shutdown")
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket still exists after shutdown: %v", err)
+	}
+}
+
+func TestServeReturnsListenerError(t *testing.T) {
+	listener, err := Listen("127.0.0.1:0")
+	testutils.AssertNoError(t, err)
+	listener.Close()
+
+	err = Serve(context.Background(), Endpoint{Listener: listener, Handler: http.NotFoundHandler()})
+	testutils.AssertError(t, err, "use of closed network connection")
+}
@@ -0,0 +1,96 @@
+package lnd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// channelAcceptorPath is the REST endpoint of LND's ChannelAcceptor stream
+const channelAcceptorPath = "/v1/channels/acceptor?method=POST"
+
+// channelFlagAnnounce is the open_channel flag bit set for public channels
+const channelFlagAnnounce = 1
+
+// ChannelAcceptRequest is an inbound channel open awaiting a decision.
+// Byte fields are base64 in LND's JSON.
+type ChannelAcceptRequest struct {
+	NodePubkey    []byte `json:"node_pubkey"`
+	PendingChanID []byte `json:"pending_chan_id"`
+	FundingAmt    string `json:"funding_amt"`
+	PushAmt       string `json:"push_amt"`
+	ChannelFlags  uint32 `json:"channel_flags"`
+	WantsZeroConf bool   `json:"wants_zero_conf"`
+}
+
+// Pubkey returns the hex pubkey of the node opening the channel
+func (r ChannelAcceptRequest) Pubkey() string {
+	return hex.EncodeToString(r.NodePubkey)
+}
+
+// FundingSat returns the channel size in sats
+func (r ChannelAcceptRequest) FundingSat() int64 {
+	amount, _ := strconv.ParseInt(r.FundingAmt, 10, 64)
+	return amount
+}
+
+// PushSat returns the amount pushed to us on open in sats
+func (r ChannelAcceptRequest) PushSat() int64 {
+	amount, _ := strconv.ParseInt(r.PushAmt, 10, 64)
+	return amount
+}
+
+// Private reports whether the channel will not be announced
+func (r ChannelAcceptRequest) Private() bool {
+	return r.ChannelFlags&channelFlagAnnounce == 0
+}
+
+// ChannelAcceptResponse answers a ChannelAcceptRequest. Error is sent to the
+// peer when the channel is rejected.
+type ChannelAcceptResponse struct {
+	Accept        bool   `json:"accept"`
+	PendingChanID []byte `json:"pending_chan_id"`
+	Error         string `json:"error,omitempty"`
+}
+
+// channelAcceptStreamMessage is how the REST proxy wraps stream messages
+type channelAcceptStreamMessage struct {
+	Result *ChannelAcceptRequest `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// RunChannelAcceptor registers as LND's channel acceptor and answers every
+// inbound channel open with decide until ctx is cancelled or the stream
+// fails. While no acceptor is connected LND accepts channels by its own rules.
+func RunChannelAcceptor(ctx context.Context, cfg RESTConfig, decide func(ChannelAcceptRequest) ChannelAcceptResponse) error {
+	stream, err := dialRESTStream(ctx, cfg, channelAcceptorPath)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	for {
+		var message channelAcceptStreamMessage
+		if err := stream.ReadJSON(&message); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read channel accept request: %w", err)
+		}
+		if message.Error != nil {
+			return fmt.Errorf("channel acceptor stream error: %s", message.Error.Message)
+		}
+		if message.Result == nil {
+			continue
+		}
+
+		response := decide(*message.Result)
+		response.PendingChanID = message.Result.PendingChanID
+		if err := stream.WriteJSON(response); err != nil {
+			return fmt.Errorf("failed to send channel accept response: %w", err)
+		}
+	}
+}
@@ -5,19 +5,64 @@ import (
 	"fmt"
 	"os/exec"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/capture"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+)
+
+// runner executes lncli; tests replace it with canned responses through SetRunner
+var runner = func(args ...string) ([]byte, error) {
+	return exec.Command("lncli", args...).Output()
+}
+
+// SetRunner replaces how lncli is run, e.g. with the canned responses in
+// internal/fixtures, and returns a function that restores the previous runner
+func SetRunner(run func(args ...string) ([]byte, error)) (restore func()) {
+	previous := runner
+	runner = run
+	return func() { runner = previous }
+}
+
+// lncli calls as Prometheus metrics, by command, e.g. listchannels
+var (
+	callDuration = metrics.Default.NewHistogram("lnd_call_duration_seconds",
+		"lncli call durations, by command", metrics.Buckets, "command")
+	callErrors = metrics.Default.NewCounter("lnd_call_errors_total",
+		"lncli calls that failed, by command", "command")
 )
 
-// RunLNCLI executes lncli commands and returns the output
+// commandName returns the lncli command of args, skipping global flags
+func commandName(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return "none"
+}
+
+// RunLNCLI executes lncli commands and returns the output. The response is
+// kept when debug capture is enabled, see internal/capture.
 func RunLNCLI(args ...string) ([]byte, error) {
-	cmd := exec.Command("lncli", args...)
-	output, err := cmd.Output()
+	start := time.Now()
+	output, err := runner(args...)
+	command := commandName(args)
+	callDuration.Observe(time.Since(start), command)
 	if err != nil {
+		callErrors.Inc(command)
 		// If there's an error, try to get stderr for more details
 		if exitError, ok := err.(*exec.ExitError); ok {
 			// Include stderr in the error message
-			return nil, fmt.Errorf("lncli command failed: %v, stderr: %s", err, string(exitError.Stderr))
+			err = fmt.Errorf("lncli command failed: %v, stderr: %s", err, string(exitError.Stderr))
+		} else {
+			err = fmt.Errorf("lncli command failed: %v", err)
 		}
-		return nil, fmt.Errorf("lncli command failed: %v", err)
+	}
+	capture.Save("lncli", args, output, err)
+	if err != nil {
+		return nil, err
 	}
 	return output, nil
 }
@@ -230,6 +275,40 @@ func GetNodePubkey() (string, error) {
 	return response.IdentityPubkey, nil
 }
 
+// GetVersion returns the version LND reports, e.g. "0.17.0-beta commit=v0.17.0-beta"
+func GetVersion() (string, error) {
+	output, err := RunLNCLI("getinfo")
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return "", err
+	}
+
+	return response.Version, nil
+}
+
+// GetBlockHeight retrieves the block height our node is synced to
+func GetBlockHeight() (int64, error) {
+	output, err := RunLNCLI("getinfo")
+	if err != nil {
+		return 0, err
+	}
+
+	var response struct {
+		BlockHeight int64 `json:"block_height"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return 0, err
+	}
+
+	return response.BlockHeight, nil
+}
+
 // GetChannelInfo retrieves detailed channel information
 func GetChannelInfo(chanID string) (*ChannelEdge, error) {
 	output, err := RunLNCLI("getchaninfo", chanID)
@@ -288,6 +367,113 @@ func OpenChannel(peerPubkey string, localAmt int64, satPerVbyte int64) (*OpenCha
 	return &response, nil
 }
 
+// PeerPubkey returns the pubkey of a peer address in the form pubkey@host:port
+// or just pubkey
+func PeerPubkey(peerAddress string) string {
+	pubkey, _, _ := strings.Cut(peerAddress, "@")
+	return pubkey
+}
+
+// ConnectAndOpenChannel connects to a peer unless it is connected already,
+// checks the connection and opens a channel of localAmt sats to it
+func ConnectAndOpenChannel(peerAddress string, localAmt int64, satPerVbyte int64) (*OpenChannelResponse, error) {
+	pubkey := PeerPubkey(peerAddress)
+	connected := func() (bool, error) {
+		peers, err := ListPeers()
+		if err != nil {
+			return false, fmt.Errorf("failed to list peers: %w", err)
+		}
+		for _, peer := range peers {
+			if peer.PubKey == pubkey {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	ok, err := connected()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := ConnectPeer(peerAddress); err != nil {
+			return nil, fmt.Errorf("failed to connect to peer: %w", err)
+		}
+		if ok, err = connected(); err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("peer connection verification failed")
+		}
+	}
+
+	response, err := OpenChannel(pubkey, localAmt, satPerVbyte)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	return response, nil
+}
+
+// AddInvoice creates an invoice for amount sats and returns its payment request
+func AddInvoice(amount int64, memo string) (string, error) {
+	output, err := RunLNCLI("addinvoice", "--amt", fmt.Sprintf("%d", amount), "--memo", memo)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		PaymentRequest string `json:"payment_request"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return "", err
+	}
+	if response.PaymentRequest == "" {
+		return "", fmt.Errorf("lncli addinvoice returned no payment request")
+	}
+
+	return response.PaymentRequest, nil
+}
+
+// SignMessage signs message with the node's identity key, returning LND's
+// zbase32 signature, which verifymessage checks against the node's pubkey
+func SignMessage(message string) (string, error) {
+	output, err := RunLNCLI("signmessage", "--msg", message)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return "", err
+	}
+	if response.Signature == "" {
+		return "", fmt.Errorf("lncli signmessage returned no signature")
+	}
+
+	return response.Signature, nil
+}
+
+// VerifyMessage checks a SignMessage signature, returning the pubkey of the
+// node that made it. LND only reports nodes in its graph as valid.
+func VerifyMessage(message, signature string) (pubkey string, valid bool, err error) {
+	output, err := RunLNCLI("verifymessage", "--msg", message, "--sig", signature)
+	if err != nil {
+		return "", false, err
+	}
+
+	var response struct {
+		Valid  bool   `json:"valid"`
+		Pubkey string `json:"pubkey"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return "", false, err
+	}
+
+	return response.Pubkey, response.Valid, nil
+}
+
 // GetForwardingHistory retrieves forwarding history for a time range
 func (c *Client) GetForwardingHistory(startTime, endTime string) (*ForwardingHistory, error) {
 	args := []string{"fwdinghistory", "--start_time", startTime}
@@ -5,11 +5,25 @@ import (
 	"log"
 	"strconv"
 	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+)
+
+const (
+	// lightningHistoryCollector names the collector runs that sync Lightning
+	// balance points; the resume point is the unix time of the last sync
+	lightningHistoryCollector = "lightning-history"
+	// lightningHistoryMaxAge is how stale stored points may be before a
+	// history request rescans LND for newer events
+	lightningHistoryMaxAge = 5 * time.Minute
 )
 
 // LightningHistoryScanner scans Lightning and on-chain transactions for historical data
 type LightningHistoryScanner struct {
 	client *Client
+	// database stores derived balance points; nil rescans LND on every request
+	database *db.Database
 }
 
 // LightningBalancePoint represents a balance at a specific point in time
@@ -29,35 +43,151 @@ func NewLightningHistoryScanner(client *Client) *LightningHistoryScanner {
 	}
 }
 
+// NewLightningHistoryScannerWithStore creates a scanner that persists derived
+// balance points in database and serves history from there
+func NewLightningHistoryScannerWithStore(client *Client, database *db.Database) *LightningHistoryScanner {
+	return &LightningHistoryScanner{
+		client:   client,
+		database: database,
+	}
+}
+
 // GetLightningHistory generates historical Lightning + on-chain balance progression
 func (s *LightningHistoryScanner) GetLightningHistory(from, to time.Time) ([]LightningBalancePoint, error) {
+	if s.database != nil {
+		return s.getStoredHistory(from, to)
+	}
+	return s.scanHistory(from, to)
+}
+
+// scanHistory rebuilds the balance progression from LND without storing it
+func (s *LightningHistoryScanner) scanHistory(from, to time.Time) ([]LightningBalancePoint, error) {
 	log.Printf("⚡ Scanning Lightning transaction history from %v to %v",
 		from.Format("2006-01-02"), to.Format("2006-01-02"))
 
 	// Get current balances as our endpoint
-	currentOnchain := int64(0)
-	currentLocal := int64(0)
-	currentRemote := int64(0)
+	currentOnchain, currentLocal, currentRemote, err := s.currentBalances()
+	if err != nil {
+		log.Printf("⚠️  Warning: %v", err)
+	}
+
+	allEvents := s.collectEvents(from.Unix(), to.Unix())
+	fromUnix := from.Unix()
+
+	log.Printf("📊 Found %d Lightning transactions in date range", len(allEvents))
+
+	// Build balance progression by working backwards from current balance
+	var balancePoints []LightningBalancePoint
+
+	// Start with current balances
+	onchainBalance := currentOnchain
+	lightningLocal := currentLocal
+	lightningRemote := currentRemote
+
+	// Add current state
+	balancePoints = append(balancePoints, LightningBalancePoint{
+		Timestamp:       to,
+		OnchainBalance:  onchainBalance,
+		LightningLocal:  lightningLocal,
+		LightningRemote: lightningRemote,
+		TransactionType: "current",
+	})
+
+	// Work backwards through transactions
+	for i := len(allEvents) - 1; i >= 0; i-- {
+		event := allEvents[i]
+		eventTime := time.Unix(event.timestamp, 0)
+
+		// Apply reverse transaction to get balance before this transaction
+		switch event.txType {
+		case "onchain":
+			onchainBalance -= event.amount // Reverse on-chain transaction
+		case "lightning_in":
+			lightningLocal -= event.amount // Reverse incoming Lightning
+		case "lightning_out":
+			lightningLocal -= event.amount // Reverse outgoing Lightning (amount is already negative)
+		}
 
-	// Get current wallet balance
-	if walletBalance, err := s.client.GetWalletBalance(); err == nil {
-		currentOnchain = walletBalance.ConfirmedBalance
+		point := LightningBalancePoint{
+			Timestamp:         eventTime,
+			OnchainBalance:    onchainBalance,
+			LightningLocal:    lightningLocal,
+			LightningRemote:   lightningRemote,
+			TransactionType:   event.txType,
+			TransactionAmount: event.amount,
+		}
+		balancePoints = append([]LightningBalancePoint{point}, balancePoints...)
+		log.Printf("📊 Added balance point: %s, on-chain: %s, local: %s, remote: %s",
+			eventTime.Format("2006-01-02"), redact.Amount(onchainBalance), redact.Amount(lightningLocal), redact.Amount(lightningRemote))
+	}
+
+	// If we have no transactions, create a simple progression using current balance
+	if len(allEvents) == 0 {
+		log.Println("📊 No Lightning transactions found, creating simple balance progression")
+
+		// Create balance points showing current balance maintained over time
+		balancePoints = []LightningBalancePoint{
+			{
+				Timestamp:       from,
+				OnchainBalance:  currentOnchain,
+				LightningLocal:  currentLocal,
+				LightningRemote: currentRemote,
+				TransactionType: "start",
+			},
+			{
+				Timestamp:       to,
+				OnchainBalance:  currentOnchain,
+				LightningLocal:  currentLocal,
+				LightningRemote: currentRemote,
+				TransactionType: "current",
+			},
+		}
 	} else {
-		log.Printf("⚠️  Warning: Could not get wallet balance: %v", err)
+		// Add starting point if no transactions at start date
+		if allEvents[0].timestamp > fromUnix {
+			balancePoints = append([]LightningBalancePoint{{
+				Timestamp:       from,
+				OnchainBalance:  onchainBalance,
+				LightningLocal:  lightningLocal,
+				LightningRemote: lightningRemote,
+				TransactionType: "start",
+			}}, balancePoints...)
+		}
 	}
 
-	// Get current channel balances
-	if channelBalance, err := s.client.GetChannelBalances(); err == nil {
-		currentLocal = channelBalance.LocalBalance
-		currentRemote = channelBalance.RemoteBalance
+	log.Printf("✅ Generated %d Lightning balance points", len(balancePoints))
+	for i, point := range balancePoints {
+		log.Printf("  Point %d: %s - on-chain: %d, local: %d, remote: %d",
+			i+1, point.Timestamp.Format("2006-01-02"),
+			point.OnchainBalance, point.LightningLocal, point.LightningRemote)
+	}
+	return balancePoints, nil
+}
+
+// currentBalances returns LND's confirmed wallet balance and local and remote
+// channel balances. Balances that could not be read are zero and reported in err.
+func (s *LightningHistoryScanner) currentBalances() (onchain, local, remote int64, err error) {
+	walletBalance, walletErr := s.client.GetWalletBalance()
+	if walletErr == nil {
+		onchain = walletBalance.ConfirmedBalance
 	} else {
-		log.Printf("⚠️  Warning: Could not get channel balance: %v", err)
+		err = fmt.Errorf("could not get wallet balance: %w", walletErr)
+	}
+
+	channelBalance, channelErr := s.client.GetChannelBalances()
+	if channelErr == nil {
+		local = channelBalance.LocalBalance
+		remote = channelBalance.RemoteBalance
+	} else if err == nil {
+		err = fmt.Errorf("could not get channel balance: %w", channelErr)
 	}
+	return onchain, local, remote, err
+}
 
-	// Collect all transactions within date range
+// collectEvents returns on-chain transactions, settled invoices and succeeded
+// payments with timestamps between fromUnix and toUnix, oldest first
+func (s *LightningHistoryScanner) collectEvents(fromUnix, toUnix int64) []transactionEvent {
 	var allEvents []transactionEvent
-	fromUnix := from.Unix()
-	toUnix := to.Unix()
 
 	// Get on-chain transactions
 	onchainTxs, err := s.client.GetTransactions()
@@ -75,8 +205,8 @@ func (s *LightningHistoryScanner) GetLightningHistory(from, to time.Time) ([]Lig
 						amount:    amount,
 						txHash:    tx.TxHash,
 					})
-					log.Printf("📈 On-chain tx: %s, amount: %d, date: %s",
-						tx.TxHash[:8], amount, time.Unix(timestamp, 0).Format("2006-01-02"))
+					log.Printf("📈 On-chain tx: %s, amount: %s, date: %s",
+						tx.TxHash[:8], redact.Amount(amount), time.Unix(timestamp, 0).Format("2006-01-02"))
 				}
 			} else {
 				log.Printf("⚠️  Could not parse timestamp for tx %s: %s", tx.TxHash, tx.TimeStamp)
@@ -103,8 +233,8 @@ func (s *LightningHistoryScanner) GetLightningHistory(from, to time.Time) ([]Lig
 							amount:    amount,
 							txHash:    invoice.RHash,
 						})
-						log.Printf("⚡ Lightning receive: %d sats, date: %s",
-							amount, time.Unix(timestamp, 0).Format("2006-01-02"))
+						log.Printf("⚡ Lightning receive: %s, date: %s",
+							redact.Sats(amount), time.Unix(timestamp, 0).Format("2006-01-02"))
 					}
 				} else {
 					log.Printf("⚠️  Could not parse settle date for invoice: %s", invoice.SettleDate)
@@ -133,8 +263,8 @@ func (s *LightningHistoryScanner) GetLightningHistory(from, to time.Time) ([]Lig
 							amount:    -amount, // Negative for outgoing
 							txHash:    payment.PaymentHash,
 						})
-						log.Printf("⚡ Lightning send: -%d sats, date: %s",
-							amount, time.Unix(timestamp, 0).Format("2006-01-02"))
+						log.Printf("⚡ Lightning send: %s, date: %s",
+							redact.Sats(-amount), time.Unix(timestamp, 0).Format("2006-01-02"))
 					}
 				} else {
 					log.Printf("⚠️  Could not parse creation date for payment: %s", payment.CreationDate)
@@ -153,94 +283,7 @@ func (s *LightningHistoryScanner) GetLightningHistory(from, to time.Time) ([]Lig
 		}
 	}
 
-	log.Printf("📊 Found %d Lightning transactions in date range", len(allEvents))
-
-	// Build balance progression by working backwards from current balance
-	var balancePoints []LightningBalancePoint
-
-	// Start with current balances
-	onchainBalance := currentOnchain
-	lightningLocal := currentLocal
-	lightningRemote := currentRemote
-
-	// Add current state
-	balancePoints = append(balancePoints, LightningBalancePoint{
-		Timestamp:       to,
-		OnchainBalance:  onchainBalance,
-		LightningLocal:  lightningLocal,
-		LightningRemote: lightningRemote,
-		TransactionType: "current",
-	})
-
-	// Work backwards through transactions
-	for i := len(allEvents) - 1; i >= 0; i-- {
-		event := allEvents[i]
-		eventTime := time.Unix(event.timestamp, 0)
-
-		// Apply reverse transaction to get balance before this transaction
-		switch event.txType {
-		case "onchain":
-			onchainBalance -= event.amount // Reverse on-chain transaction
-		case "lightning_in":
-			lightningLocal -= event.amount // Reverse incoming Lightning
-		case "lightning_out":
-			lightningLocal -= event.amount // Reverse outgoing Lightning (amount is already negative)
-		}
-
-		point := LightningBalancePoint{
-			Timestamp:         eventTime,
-			OnchainBalance:    onchainBalance,
-			LightningLocal:    lightningLocal,
-			LightningRemote:   lightningRemote,
-			TransactionType:   event.txType,
-			TransactionAmount: event.amount,
-		}
-		balancePoints = append([]LightningBalancePoint{point}, balancePoints...)
-		log.Printf("📊 Added balance point: %s, on-chain: %d, local: %d, remote: %d",
-			eventTime.Format("2006-01-02"), onchainBalance, lightningLocal, lightningRemote)
-	}
-
-	// If we have no transactions, create a simple progression using current balance
-	if len(allEvents) == 0 {
-		log.Println("📊 No Lightning transactions found, creating simple balance progression")
-
-		// Create balance points showing current balance maintained over time
-		balancePoints = []LightningBalancePoint{
-			{
-				Timestamp:       from,
-				OnchainBalance:  currentOnchain,
-				LightningLocal:  currentLocal,
-				LightningRemote: currentRemote,
-				TransactionType: "start",
-			},
-			{
-				Timestamp:       to,
-				OnchainBalance:  currentOnchain,
-				LightningLocal:  currentLocal,
-				LightningRemote: currentRemote,
-				TransactionType: "current",
-			},
-		}
-	} else {
-		// Add starting point if no transactions at start date
-		if allEvents[0].timestamp > fromUnix {
-			balancePoints = append([]LightningBalancePoint{{
-				Timestamp:       from,
-				OnchainBalance:  onchainBalance,
-				LightningLocal:  lightningLocal,
-				LightningRemote: lightningRemote,
-				TransactionType: "start",
-			}}, balancePoints...)
-		}
-	}
-
-	log.Printf("✅ Generated %d Lightning balance points", len(balancePoints))
-	for i, point := range balancePoints {
-		log.Printf("  Point %d: %s - on-chain: %d, local: %d, remote: %d",
-			i+1, point.Timestamp.Format("2006-01-02"),
-			point.OnchainBalance, point.LightningLocal, point.LightningRemote)
-	}
-	return balancePoints, nil
+	return allEvents
 }
 
 // transactionEvent represents a transaction event for sorting
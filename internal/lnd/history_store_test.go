@@ -0,0 +1,36 @@
+package lnd
+
+import "testing"
+
+func TestDerivePoints(t *testing.T) {
+	events := []transactionEvent{
+		{timestamp: 1714560000, txType: "onchain", amount: 100000, txHash: "tx1"},
+		{timestamp: 1714646400, txType: "lightning_in", amount: 2000, txHash: "inv1"},
+		{timestamp: 1714732800, txType: "lightning_out", amount: -500, txHash: "pay1"},
+	}
+
+	points := derivePoints(events, 100000, 21500, 78500)
+	if len(points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(points))
+	}
+
+	// Each point holds the balance right after its event
+	want := []struct {
+		key            string
+		onchain, local int64
+	}{
+		{"onchain:tx1", 100000, 20000},
+		{"lightning_in:inv1", 100000, 22000},
+		{"lightning_out:pay1", 100000, 21500},
+	}
+	for i, w := range want {
+		p := points[i]
+		if p.EventKey != w.key || p.OnchainBalance != w.onchain || p.LightningLocal != w.local {
+			t.Errorf("point %d: got %s onchain=%d local=%d, want %s onchain=%d local=%d",
+				i, p.EventKey, p.OnchainBalance, p.LightningLocal, w.key, w.onchain, w.local)
+		}
+		if p.LightningRemote != 78500 {
+			t.Errorf("point %d: remote balance changed to %d", i, p.LightningRemote)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package lnd
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// REST endpoint of LND's SubscribeHtlcEvents stream
+const htlcEventsPath = "/v2/router/htlcevents?method=GET"
+
+// Outcomes of a forward through the node
+const (
+	// ForwardSettled is a forward that was paid
+	ForwardSettled = "settled"
+	// ForwardFailed is a forward the next hop or one after it failed
+	ForwardFailed = "failed"
+	// ForwardLinkFailed is an HTLC this node refused to forward, e.g. for
+	// lack of outbound liquidity or a fee below its policy
+	ForwardLinkFailed = "link_failed"
+)
+
+// ForwardEvent is a forward through the node that settled or failed
+type ForwardEvent struct {
+	Outcome    string
+	Time       time.Time
+	ChanIDIn   string
+	ChanIDOut  string
+	AmtInMsat  int64
+	AmtOutMsat int64
+	// Failure says why a link failed, e.g. INSUFFICIENT_BALANCE; empty
+	// otherwise, as LND does not report why later hops failed
+	Failure string
+}
+
+// FeeMsat returns what the forward earned, or would have
+func (e ForwardEvent) FeeMsat() int64 {
+	return e.AmtInMsat - e.AmtOutMsat
+}
+
+// htlcInfo is the amounts of a forwarded HTLC
+type htlcInfo struct {
+	IncomingAmtMsat string `json:"incoming_amt_msat"`
+	OutgoingAmtMsat string `json:"outgoing_amt_msat"`
+}
+
+// htlcEvent is an update of SubscribeHtlcEvents. Exactly one of the event
+// fields is set.
+type htlcEvent struct {
+	IncomingChannelID string `json:"incoming_channel_id"`
+	OutgoingChannelID string `json:"outgoing_channel_id"`
+	IncomingHtlcID    string `json:"incoming_htlc_id"`
+	TimestampNs       string `json:"timestamp_ns"`
+	EventType         string `json:"event_type"`
+
+	ForwardEvent *struct {
+		Info htlcInfo `json:"info"`
+	} `json:"forward_event"`
+	ForwardFailEvent *struct{} `json:"forward_fail_event"`
+	SettleEvent      *struct{} `json:"settle_event"`
+	LinkFailEvent    *struct {
+		Info          htlcInfo `json:"info"`
+		WireFailure   string   `json:"wire_failure"`
+		FailureDetail string   `json:"failure_detail"`
+	} `json:"link_fail_event"`
+}
+
+// circuitKey identifies a forward by its incoming HTLC
+type circuitKey struct {
+	channel string
+	htlc    string
+}
+
+// forwardTracker pairs the settle and fail events of forwards, which carry
+// no amounts, with the forward events that started them
+type forwardTracker struct {
+	inflight map[circuitKey]htlcInfo
+}
+
+func newForwardTracker() *forwardTracker {
+	return &forwardTracker{inflight: make(map[circuitKey]htlcInfo)}
+}
+
+// event returns the forward that update completed, if any. Payments the node
+// sends or receives itself are left out.
+func (t *forwardTracker) event(update htlcEvent) (ForwardEvent, bool) {
+	if update.EventType != "FORWARD" {
+		return ForwardEvent{}, false
+	}
+	key := circuitKey{channel: update.IncomingChannelID, htlc: update.IncomingHtlcID}
+
+	var info htlcInfo
+	event := ForwardEvent{ChanIDIn: update.IncomingChannelID, ChanIDOut: update.OutgoingChannelID}
+	switch {
+	case update.ForwardEvent != nil:
+		t.inflight[key] = update.ForwardEvent.Info
+		return ForwardEvent{}, false
+	case update.SettleEvent != nil, update.ForwardFailEvent != nil:
+		// Forwards started before the subscription are unknown; the
+		// forwarding history still has the settled ones
+		var ok bool
+		if info, ok = t.inflight[key]; !ok {
+			return ForwardEvent{}, false
+		}
+		delete(t.inflight, key)
+		event.Outcome = ForwardSettled
+		if update.ForwardFailEvent != nil {
+			event.Outcome = ForwardFailed
+		}
+	case update.LinkFailEvent != nil:
+		info = update.LinkFailEvent.Info
+		event.Outcome = ForwardLinkFailed
+		event.Failure = update.LinkFailEvent.FailureDetail
+		if event.Failure == "" || event.Failure == "NO_DETAIL" {
+			event.Failure = update.LinkFailEvent.WireFailure
+		}
+	default:
+		return ForwardEvent{}, false
+	}
+
+	nanos, _ := strconv.ParseInt(update.TimestampNs, 10, 64)
+	event.Time = time.Unix(0, nanos)
+	event.AmtInMsat, _ = strconv.ParseInt(info.IncomingAmtMsat, 10, 64)
+	event.AmtOutMsat, _ = strconv.ParseInt(info.OutgoingAmtMsat, 10, 64)
+	return event, true
+}
+
+// SubscribeForwardEvents calls handle for every forward that settles or fails
+// until ctx is cancelled or the stream fails. Forwards are only reported
+// when they complete while subscribed; handle runs on the stream's goroutine
+// and must not block for long.
+func SubscribeForwardEvents(ctx context.Context, cfg RESTConfig, handle func(ForwardEvent)) error {
+	tracker := newForwardTracker()
+	return subscribe(ctx, cfg, htlcEventsPath, struct{}{}, tracker.event, handle)
+}
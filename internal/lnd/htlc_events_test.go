@@ -0,0 +1,53 @@
+package lnd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestForwardTracker(t *testing.T) {
+	tracker := newForwardTracker()
+	update := func(data string) (ForwardEvent, bool) {
+		t.Helper()
+		var message streamMessage[htlcEvent]
+		testutils.AssertNoError(t, json.Unmarshal([]byte(data), &message))
+		return tracker.event(*message.Result)
+	}
+
+	// The forward event carries the amounts, its settlement completes it
+	_, ok := update(`{"result":{"incoming_channel_id":"100","outgoing_channel_id":"200","incoming_htlc_id":"7","timestamp_ns":"1760000000000000000","event_type":"FORWARD","forward_event":{"info":{"incoming_amt_msat":"100250000","outgoing_amt_msat":"100000000"}}}}`)
+	testutils.AssertEqual(t, ok, false)
+	event, ok := update(`{"result":{"incoming_channel_id":"100","outgoing_channel_id":"200","incoming_htlc_id":"7","timestamp_ns":"1760000001500000000","event_type":"FORWARD","settle_event":{}}}`)
+	testutils.AssertEqual(t, ok, true)
+	testutils.AssertEqual(t, event.Outcome, ForwardSettled)
+	testutils.AssertEqual(t, event.ChanIDIn, "100")
+	testutils.AssertEqual(t, event.ChanIDOut, "200")
+	testutils.AssertEqual(t, event.AmtOutMsat, int64(100000000))
+	testutils.AssertEqual(t, event.FeeMsat(), int64(250000))
+	testutils.AssertEqual(t, event.Time.Equal(time.Unix(1760000001, 500000000)), true)
+	testutils.AssertEqual(t, len(tracker.inflight), 0)
+
+	// A later hop failing the forward
+	update(`{"result":{"incoming_channel_id":"100","outgoing_channel_id":"300","incoming_htlc_id":"8","timestamp_ns":"1760000002000000000","event_type":"FORWARD","forward_event":{"info":{"incoming_amt_msat":"50050000","outgoing_amt_msat":"50000000"}}}}`)
+	event, ok = update(`{"result":{"incoming_channel_id":"100","outgoing_channel_id":"300","incoming_htlc_id":"8","timestamp_ns":"1760000003000000000","event_type":"FORWARD","forward_fail_event":{}}}`)
+	testutils.AssertEqual(t, ok, true)
+	testutils.AssertEqual(t, event.Outcome, ForwardFailed)
+	testutils.AssertEqual(t, event.AmtInMsat, int64(50050000))
+
+	// This node refusing to forward
+	event, ok = update(`{"result":{"incoming_channel_id":"100","outgoing_channel_id":"200","incoming_htlc_id":"9","timestamp_ns":"1760000004000000000","event_type":"FORWARD","link_fail_event":{"info":{"incoming_amt_msat":"900100000","outgoing_amt_msat":"900000000"},"wire_failure":"TEMPORARY_CHANNEL_FAILURE","failure_detail":"INSUFFICIENT_BALANCE"}}}`)
+	testutils.AssertEqual(t, ok, true)
+	testutils.AssertEqual(t, event.Outcome, ForwardLinkFailed)
+	testutils.AssertEqual(t, event.Failure, "INSUFFICIENT_BALANCE")
+	testutils.AssertEqual(t, event.AmtOutMsat, int64(900000000))
+
+	// Settlements of forwards started before subscribing, and the node's
+	// own payments, are left to the forwarding history
+	_, ok = update(`{"result":{"incoming_channel_id":"100","incoming_htlc_id":"1","event_type":"FORWARD","settle_event":{}}}`)
+	testutils.AssertEqual(t, ok, false)
+	_, ok = update(`{"result":{"outgoing_channel_id":"200","event_type":"SEND","link_fail_event":{"info":{"outgoing_amt_msat":"1000"},"failure_detail":"INSUFFICIENT_BALANCE"}}}`)
+	testutils.AssertEqual(t, ok, false)
+}
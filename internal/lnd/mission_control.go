@@ -0,0 +1,105 @@
+package lnd
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Mission control estimator parameters, matching LND's apriori defaults
+const (
+	// aprioriHopProbability is assumed for pairs without a relevant result
+	aprioriHopProbability = 0.6
+	// previousSuccessProbability is assumed for amounts that succeeded before
+	previousSuccessProbability = 0.95
+	// failurePenaltyHalfLife is how long it takes a failure to recover half
+	// of the apriori probability
+	failurePenaltyHalfLife = time.Hour
+)
+
+// MissionControlPair is mission control's last result for one directed node
+// pair, as returned by querymc
+type MissionControlPair struct {
+	NodeFrom string                `json:"node_from"`
+	NodeTo   string                `json:"node_to"`
+	History  MissionControlHistory `json:"history"`
+}
+
+// MissionControlHistory holds the raw querymc results for a pair. Times are
+// unix seconds and "0" when there was no such result.
+type MissionControlHistory struct {
+	FailTime       string `json:"fail_time"`
+	FailAmtSat     string `json:"fail_amt_sat"`
+	FailAmtMsat    string `json:"fail_amt_msat"`
+	SuccessTime    string `json:"success_time"`
+	SuccessAmtSat  string `json:"success_amt_sat"`
+	SuccessAmtMsat string `json:"success_amt_msat"`
+}
+
+// MissionControlResponse represents the response from querymc
+type MissionControlResponse struct {
+	Pairs []MissionControlPair `json:"pairs"`
+}
+
+// PairHistory is a parsed mission control result. Zero times mean there was
+// no such result.
+type PairHistory struct {
+	SuccessTime    int64 `json:"success_time"`
+	SuccessAmtMsat int64 `json:"success_amt_msat"`
+	FailTime       int64 `json:"fail_time"`
+	FailAmtMsat    int64 `json:"fail_amt_msat"`
+}
+
+// Parse converts the querymc strings to a PairHistory; unparseable values are zero
+func (h MissionControlHistory) Parse() PairHistory {
+	var parsed PairHistory
+	parsed.SuccessTime, _ = strconv.ParseInt(h.SuccessTime, 10, 64)
+	parsed.SuccessAmtMsat, _ = strconv.ParseInt(h.SuccessAmtMsat, 10, 64)
+	parsed.FailTime, _ = strconv.ParseInt(h.FailTime, 10, 64)
+	parsed.FailAmtMsat, _ = strconv.ParseInt(h.FailAmtMsat, 10, 64)
+	return parsed
+}
+
+// SuccessProbability estimates the chance that forwarding amtMsat over the
+// pair succeeds, following LND's apriori estimator: amounts at or above the
+// last failure are penalized and recover towards the apriori probability with
+// a one hour half-life, amounts at or below the last success are likely to
+// succeed, and anything else gets the apriori probability.
+func (h PairHistory) SuccessProbability(amtMsat int64, now time.Time) float64 {
+	if h.FailTime > 0 && amtMsat >= h.FailAmtMsat {
+		age := now.Sub(time.Unix(h.FailTime, 0))
+		if age < 0 {
+			age = 0
+		}
+		recovered := 1 - math.Pow(2, -age.Hours()/failurePenaltyHalfLife.Hours())
+		return aprioriHopProbability * recovered
+	}
+
+	if h.SuccessTime > 0 && amtMsat <= h.SuccessAmtMsat {
+		return previousSuccessProbability
+	}
+
+	return aprioriHopProbability
+}
+
+// QueryMissionControl returns LND's mission control pair history
+func QueryMissionControl() ([]MissionControlPair, error) {
+	output, err := RunLNCLI("querymc")
+	if err != nil {
+		return nil, err
+	}
+
+	var response MissionControlResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Pairs, nil
+}
+
+// ResetMissionControl clears LND's mission control history
+func ResetMissionControl() error {
+	_, err := RunLNCLI("resetmc")
+	return err
+}
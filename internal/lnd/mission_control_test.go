@@ -0,0 +1,54 @@
+package lnd
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestMissionControlHistoryParse(t *testing.T) {
+	history := MissionControlHistory{
+		FailTime:       "1700000000",
+		FailAmtMsat:    "250000000",
+		SuccessTime:    "1699990000",
+		SuccessAmtMsat: "100000000",
+	}
+
+	parsed := history.Parse()
+	testutils.AssertEqual(t, parsed.FailTime, int64(1700000000))
+	testutils.AssertEqual(t, parsed.FailAmtMsat, int64(250000000))
+	testutils.AssertEqual(t, parsed.SuccessTime, int64(1699990000))
+	testutils.AssertEqual(t, parsed.SuccessAmtMsat, int64(100000000))
+}
+
+func TestPairSuccessProbability(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	history := PairHistory{
+		SuccessTime:    now.Add(-2 * time.Hour).Unix(),
+		SuccessAmtMsat: 100_000_000,
+		FailTime:       now.Add(-time.Hour).Unix(),
+		FailAmtMsat:    500_000_000,
+	}
+
+	tests := []struct {
+		name    string
+		history PairHistory
+		amtMsat int64
+		want    float64
+	}{
+		{"no history", PairHistory{}, 100_000_000, aprioriHopProbability},
+		{"below last success", history, 50_000_000, previousSuccessProbability},
+		{"between success and failure", history, 200_000_000, aprioriHopProbability},
+		{"failure one half-life ago", history, 500_000_000, aprioriHopProbability / 2},
+		{"fresh failure", PairHistory{FailTime: now.Unix(), FailAmtMsat: 1000}, 2000, 0},
+	}
+
+	for _, tt := range tests {
+		got := tt.history.SuccessProbability(tt.amtMsat, now)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: got %f, want %f", tt.name, got, tt.want)
+		}
+	}
+}
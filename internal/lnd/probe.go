@@ -0,0 +1,112 @@
+package lnd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// probeReachedReason is the failure reason of a payment that reached its
+// destination but was rejected because nobody holds its preimage
+const probeReachedReason = "FAILURE_REASON_INCORRECT_PAYMENT_DETAILS"
+
+// ProbeResult is the outcome of one probe payment
+type ProbeResult struct {
+	// Reached is true when the payment got to the destination
+	Reached bool
+	// FailureReason is LND's failure reason when the destination was not reached
+	FailureReason string
+	Attempts      int
+	// Hops and FeeMsat describe the last route tried
+	Hops    int
+	FeeMsat int64
+	Latency time.Duration
+}
+
+// SendProbe sends a payment of amountSat to dest with a random payment hash.
+// Since no one knows the preimage the destination rejects it, so no funds
+// move, but getting that rejection shows a working route. feeLimitSat
+// bounds the routes tried, as a real payment of this size would.
+func SendProbe(dest string, amountSat, feeLimitSat int64, timeout time.Duration) (*ProbeResult, error) {
+	hash := make([]byte, 32)
+	if _, err := rand.Read(hash); err != nil {
+		return nil, fmt.Errorf("failed to generate payment hash: %w", err)
+	}
+
+	started := time.Now()
+	cmd := exec.Command("lncli", "sendpayment",
+		"--dest", dest,
+		"--amt", strconv.FormatInt(amountSat, 10),
+		"--payment_hash", hex.EncodeToString(hash),
+		"--final_cltv_delta", "40",
+		"--fee_limit", strconv.FormatInt(feeLimitSat, 10),
+		"--timeout", timeout.String(),
+		"--force", "--json",
+	)
+	// lncli exits non-zero for failed payments but still prints the payment
+	output, err := cmd.Output()
+	elapsed := time.Since(started)
+
+	result, parseErr := parseProbePayment(output)
+	if parseErr != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("lncli sendpayment failed: %v, stderr: %s", err, string(exitError.Stderr))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("lncli sendpayment failed: %w", err)
+		}
+		return nil, parseErr
+	}
+	if result.Latency == 0 {
+		result.Latency = elapsed
+	}
+	return result, nil
+}
+
+// parseProbePayment reads the final payment printed by lncli sendpayment --json
+func parseProbePayment(output []byte) (*ProbeResult, error) {
+	var payment struct {
+		Status         string `json:"status"`
+		FailureReason  string `json:"failure_reason"`
+		CreationTimeNs string `json:"creation_time_ns"`
+		Htlcs          []struct {
+			ResolveTimeNs string `json:"resolve_time_ns"`
+			Route         struct {
+				TotalFeesMsat string            `json:"total_fees_msat"`
+				Hops          []json.RawMessage `json:"hops"`
+			} `json:"route"`
+		} `json:"htlcs"`
+	}
+	if err := json.Unmarshal(output, &payment); err != nil {
+		return nil, fmt.Errorf("failed to parse sendpayment output: %w", err)
+	}
+	if payment.Status == "" {
+		return nil, fmt.Errorf("sendpayment output has no payment status")
+	}
+
+	result := &ProbeResult{
+		Reached:       payment.FailureReason == probeReachedReason,
+		FailureReason: payment.FailureReason,
+		Attempts:      len(payment.Htlcs),
+	}
+	if result.Reached {
+		result.FailureReason = ""
+	}
+
+	if len(payment.Htlcs) > 0 {
+		last := payment.Htlcs[len(payment.Htlcs)-1]
+		result.Hops = len(last.Route.Hops)
+		result.FeeMsat, _ = strconv.ParseInt(last.Route.TotalFeesMsat, 10, 64)
+
+		created, _ := strconv.ParseInt(payment.CreationTimeNs, 10, 64)
+		resolved, _ := strconv.ParseInt(last.ResolveTimeNs, 10, 64)
+		if created > 0 && resolved > created {
+			result.Latency = time.Duration(resolved - created)
+		}
+	}
+	return result, nil
+}
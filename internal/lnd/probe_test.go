@@ -0,0 +1,38 @@
+package lnd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseProbePayment(t *testing.T) {
+	reached := []byte(`{
+		"status": "FAILED",
+		"failure_reason": "FAILURE_REASON_INCORRECT_PAYMENT_DETAILS",
+		"creation_time_ns": "1700000000000000000",
+		"htlcs": [
+			{"status": "FAILED", "resolve_time_ns": "1700000000400000000",
+			 "route": {"total_fees_msat": "3000", "hops": [{}, {}, {}, {}]}},
+			{"status": "FAILED", "resolve_time_ns": "1700000001250000000",
+			 "route": {"total_fees_msat": "1021", "hops": [{}, {}]}}
+		]
+	}`)
+	result, err := parseProbePayment(reached)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, result.Reached, true)
+	testutils.AssertEqual(t, result.FailureReason, "")
+	testutils.AssertEqual(t, result.Attempts, 2)
+	testutils.AssertEqual(t, result.Hops, 2)
+	testutils.AssertEqual(t, result.FeeMsat, int64(1021))
+	testutils.AssertEqual(t, result.Latency, 1250*time.Millisecond)
+
+	result, err = parseProbePayment([]byte(`{"status": "FAILED", "failure_reason": "FAILURE_REASON_NO_ROUTE", "htlcs": []}`))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, result.Reached, false)
+	testutils.AssertEqual(t, result.FailureReason, "FAILURE_REASON_NO_ROUTE")
+
+	_, err = parseProbePayment([]byte(`[lncli] rpc error: code = Unknown desc = invalid vertex length`))
+	testutils.AssertError(t, err, "failed to parse sendpayment output")
+}
@@ -0,0 +1,129 @@
+package lnd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// REST endpoints of LND's SubscribeInvoices and TrackPayments streams
+const (
+	invoiceSubscriptionPath = "/v1/invoices/subscribe?method=GET"
+	paymentSubscriptionPath = "/v2/router/payments?method=GET"
+)
+
+// Balance event types
+const (
+	BalanceEventInvoice = "invoice"
+	BalanceEventPayment = "payment"
+)
+
+// BalanceEvent is money moving through the node: a settled invoice or a
+// payment that succeeded
+type BalanceEvent struct {
+	Type        string
+	PaymentHash string // hex
+	AmountSat   int64  // received, or sent including fees
+}
+
+// subscribedInvoice is the part of an invoice update needed to spot
+// settlements. Byte fields are base64 in LND's JSON.
+type subscribedInvoice struct {
+	RHash      []byte `json:"r_hash"`
+	AmtPaidSat string `json:"amt_paid_sat"`
+	State      string `json:"state"`
+}
+
+// trackedPayment is the part of a payment update needed to spot completions
+type trackedPayment struct {
+	PaymentHash string `json:"payment_hash"`
+	ValueSat    string `json:"value_sat"`
+	FeeSat      string `json:"fee_sat"`
+	Status      string `json:"status"`
+}
+
+// streamMessage is how the REST proxy wraps server stream messages
+type streamMessage[T any] struct {
+	Result *T `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// balanceEvent returns the event for a settled invoice
+func (i subscribedInvoice) balanceEvent() (BalanceEvent, bool) {
+	if i.State != "SETTLED" {
+		return BalanceEvent{}, false
+	}
+	amount, _ := strconv.ParseInt(i.AmtPaidSat, 10, 64)
+	return BalanceEvent{Type: BalanceEventInvoice, PaymentHash: hex.EncodeToString(i.RHash), AmountSat: amount}, true
+}
+
+// balanceEvent returns the event for a payment that succeeded
+func (p trackedPayment) balanceEvent() (BalanceEvent, bool) {
+	if p.Status != "SUCCEEDED" {
+		return BalanceEvent{}, false
+	}
+	value, _ := strconv.ParseInt(p.ValueSat, 10, 64)
+	fee, _ := strconv.ParseInt(p.FeeSat, 10, 64)
+	return BalanceEvent{Type: BalanceEventPayment, PaymentHash: p.PaymentHash, AmountSat: value + fee}, true
+}
+
+// SubscribeBalanceEvents calls handle for every invoice settled and payment
+// completed until ctx is cancelled or either stream fails. Only events that
+// happen while subscribed are delivered; handle runs on the streams'
+// goroutines and must not block for long.
+func SubscribeBalanceEvents(ctx context.Context, cfg RESTConfig, handle func(BalanceEvent)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- subscribe(ctx, cfg, invoiceSubscriptionPath, struct{}{}, subscribedInvoice.balanceEvent, handle)
+	}()
+	go func() {
+		request := map[string]bool{"no_inflight_updates": true}
+		errs <- subscribe(ctx, cfg, paymentSubscriptionPath, request, trackedPayment.balanceEvent, handle)
+	}()
+
+	// The first stream to end takes the other one down with it
+	err := <-errs
+	cancel()
+	<-errs
+	return err
+}
+
+// subscribe opens the stream at path, sends its request and passes every
+// update that event accepts to handle
+func subscribe[T, E any](ctx context.Context, cfg RESTConfig, path string, request interface{},
+	event func(T) (E, bool), handle func(E)) error {
+	stream, err := dialRESTStream(ctx, cfg, path)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if err := stream.WriteJSON(request); err != nil {
+		return fmt.Errorf("failed to send subscription request: %w", err)
+	}
+
+	for {
+		var message streamMessage[T]
+		if err := stream.ReadJSON(&message); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read %s update: %w", path, err)
+		}
+		if message.Error != nil {
+			return fmt.Errorf("%s stream error: %s", path, message.Error.Message)
+		}
+		if message.Result == nil {
+			continue
+		}
+		if e, ok := event(*message.Result); ok {
+			handle(e)
+		}
+	}
+}
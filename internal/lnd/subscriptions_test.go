@@ -0,0 +1,42 @@
+package lnd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestInvoiceBalanceEvent(t *testing.T) {
+	var message streamMessage[subscribedInvoice]
+	data := `{"result":{"r_hash":"AqE=","amt_paid_sat":"21000","state":"SETTLED"}}`
+	testutils.AssertNoError(t, json.Unmarshal([]byte(data), &message))
+
+	event, ok := message.Result.balanceEvent()
+	testutils.AssertEqual(t, ok, true)
+	testutils.AssertEqual(t, event.Type, BalanceEventInvoice)
+	testutils.AssertEqual(t, event.PaymentHash, "02a1")
+	testutils.AssertEqual(t, event.AmountSat, int64(21000))
+
+	// Newly added invoices move no money
+	_, ok = subscribedInvoice{State: "OPEN"}.balanceEvent()
+	testutils.AssertEqual(t, ok, false)
+}
+
+func TestPaymentBalanceEvent(t *testing.T) {
+	var message streamMessage[trackedPayment]
+	data := `{"result":{"payment_hash":"02a1","value_sat":"50000","fee_sat":"12","status":"SUCCEEDED"}}`
+	testutils.AssertNoError(t, json.Unmarshal([]byte(data), &message))
+
+	event, ok := message.Result.balanceEvent()
+	testutils.AssertEqual(t, ok, true)
+	testutils.AssertEqual(t, event.Type, BalanceEventPayment)
+	testutils.AssertEqual(t, event.AmountSat, int64(50012))
+
+	_, ok = trackedPayment{Status: "FAILED"}.balanceEvent()
+	testutils.AssertEqual(t, ok, false)
+
+	var failed streamMessage[trackedPayment]
+	testutils.AssertNoError(t, json.Unmarshal([]byte(`{"error":{"message":"permission denied"}}`), &failed))
+	testutils.AssertEqual(t, failed.Error.Message, "permission denied")
+}
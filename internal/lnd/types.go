@@ -9,7 +9,18 @@ type Channel struct {
 	RemoteBalance string `json:"remote_balance"`
 	Active        bool   `json:"active"`
 	Private       bool   `json:"private"`
-}
+	// LocalChanReserveSat is the part of the local balance we cannot spend
+	LocalChanReserveSat string `json:"local_chan_reserve_sat"`
+	// Initiator is true when we opened the channel
+	Initiator      bool   `json:"initiator"`
+	CommitmentType string `json:"commitment_type"`
+	// ThawHeight is the lease expiry height of a script-enforced lease channel
+	ThawHeight int64 `json:"thaw_height"`
+}
+
+// CommitmentTypeScriptEnforcedLease marks channels opened through Lightning
+// Pool, which cannot be closed cooperatively by the seller before ThawHeight
+const CommitmentTypeScriptEnforcedLease = "SCRIPT_ENFORCED_LEASE"
 
 // NodeInfo represents basic node information
 type NodeInfo struct {
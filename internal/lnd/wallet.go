@@ -0,0 +1,87 @@
+package lnd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Wallet states reported by lncli state
+const (
+	WalletStateNonExisting    = "NON_EXISTING"
+	WalletStateLocked         = "LOCKED"
+	WalletStateUnlocked       = "UNLOCKED"
+	WalletStateRPCActive      = "RPC_ACTIVE"
+	WalletStateServerActive   = "SERVER_ACTIVE"
+	WalletStateWaitingToStart = "WAITING_TO_START"
+)
+
+// GetWalletState returns LND's wallet state, one of the WalletState constants.
+// Unlike other lncli commands, state answers while the wallet is locked.
+func GetWalletState() (string, error) {
+	output, err := RunLNCLI("state")
+	if err != nil {
+		return "", err
+	}
+	return parseWalletState(output)
+}
+
+// parseWalletState reads the output of lncli state
+func parseWalletState(output []byte) (string, error) {
+	var response struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return "", fmt.Errorf("failed to parse state output: %w", err)
+	}
+	if response.State == "" {
+		return "", fmt.Errorf("state output has no state")
+	}
+	return response.State, nil
+}
+
+// CheckPasswordFile refuses a wallet password file that anyone but its owner
+// could read, or that belongs to another user
+func CheckPasswordFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read password file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("password file %s is not a regular file", path)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("password file %s has mode %04o, it must not be accessible by group or others (chmod 600)", path, perm)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("password file %s is owned by uid %d, not the current user", path, stat.Uid)
+	}
+	return nil
+}
+
+// UnlockWallet unlocks LND's wallet with the password in passwordFile, which
+// must pass CheckPasswordFile. The password is passed on stdin so it never
+// appears in the process list.
+func UnlockWallet(passwordFile string) error {
+	if err := CheckPasswordFile(passwordFile); err != nil {
+		return err
+	}
+	password, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return fmt.Errorf("failed to read password file: %w", err)
+	}
+	password = bytes.TrimRight(password, "\r\n")
+	if len(password) == 0 {
+		return fmt.Errorf("password file %s is empty", passwordFile)
+	}
+
+	cmd := exec.Command("lncli", "unlock", "--stdin")
+	cmd.Stdin = bytes.NewReader(append(password, '\n'))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("lncli unlock failed: %v, output: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package lnd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseWalletState(t *testing.T) {
+	state, err := parseWalletState([]byte(`{"state": "LOCKED"}`))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, state, WalletStateLocked)
+
+	_, err = parseWalletState([]byte(`{}`))
+	testutils.AssertError(t, err, "no state")
+
+	_, err = parseWalletState([]byte(`[lncli] rpc error`))
+	testutils.AssertError(t, err, "failed to parse state output")
+}
+
+func TestCheckPasswordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet-password")
+	testutils.AssertError(t, CheckPasswordFile(path), "failed to read password file")
+
+	testutils.AssertNoError(t, os.WriteFile(path, []byte("hunter2\n"), 0600))
+	testutils.AssertNoError(t, CheckPasswordFile(path))
+
+	testutils.AssertNoError(t, os.Chmod(path, 0640))
+	testutils.AssertError(t, CheckPasswordFile(path), "chmod 600")
+
+	testutils.AssertError(t, CheckPasswordFile(filepath.Dir(path)), "not a regular file")
+}
@@ -0,0 +1,295 @@
+package lnd
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LND's REST proxy serves bidirectional gRPC streams, such as the channel
+// acceptor, over WebSockets. This is a minimal RFC 6455 client for them:
+// text frames carrying one JSON message each, with ping/pong and close handling.
+
+// websocketGUID is appended to the handshake key to compute Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// maxFrameSize bounds the payload of one message read from LND
+const maxFrameSize = 4 << 20
+
+// ErrStreamClosed is returned when LND closes a REST stream
+var ErrStreamClosed = errors.New("stream closed by LND")
+
+// RESTConfig locates LND's REST endpoint and the credentials to use it
+type RESTConfig struct {
+	Host         string // host:port of the REST listener
+	TLSCertPath  string
+	MacaroonPath string
+}
+
+// DefaultRESTConfig returns the REST settings of a default mainnet LND install
+func DefaultRESTConfig() RESTConfig {
+	lndDir := ".lnd"
+	if home, err := os.UserHomeDir(); err == nil {
+		lndDir = filepath.Join(home, ".lnd")
+	}
+	return RESTConfig{
+		Host:         "localhost:8080",
+		TLSCertPath:  filepath.Join(lndDir, "tls.cert"),
+		MacaroonPath: filepath.Join(lndDir, "data", "chain", "bitcoin", "mainnet", "admin.macaroon"),
+	}
+}
+
+// restStream is a WebSocket connection to one LND REST streaming endpoint
+type restStream struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// writeMutex serializes frames written by callers and pong replies
+	writeMutex sync.Mutex
+}
+
+// dialRESTStream opens a WebSocket to path on LND's REST proxy, e.g.
+// /v1/channels/acceptor?method=POST. The connection is closed when ctx is
+// cancelled.
+func dialRESTStream(ctx context.Context, cfg RESTConfig, path string) (*restStream, error) {
+	cert, err := os.ReadFile(cfg.TLSCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(cert) {
+		return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCertPath)
+	}
+
+	macaroon, err := os.ReadFile(cfg.MacaroonPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read macaroon: %w", err)
+	}
+
+	host, _, err := net.SplitHostPort(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REST host %q: %w", cfg.Host, err)
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{RootCAs: pool, ServerName: host}}
+	conn, err := dialer.DialContext(ctx, "tcp", cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LND REST at %s: %w", cfg.Host, err)
+	}
+
+	stream, err := handshake(conn, cfg.Host, path, hex.EncodeToString(macaroon))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	return stream, nil
+}
+
+// handshake upgrades conn to a WebSocket. LND's proxy takes the HTTP method
+// of the underlying REST call as a query parameter, so path includes it.
+func handshake(conn net.Conn, host, path, macaroonHex string) (*restStream, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req, err := http.NewRequest("GET", "https://"+host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Grpc-Metadata-Macaroon", macaroonHex)
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send WebSocket handshake: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WebSocket handshake: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("WebSocket handshake rejected: %s %s", resp.Status, body)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, fmt.Errorf("WebSocket handshake returned an invalid accept key")
+	}
+
+	return &restStream{conn: conn, reader: reader}, nil
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value expected for key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ReadJSON reads the next message and decodes it into v, answering pings on the way
+func (s *restStream) ReadJSON(v interface{}) error {
+	var message []byte
+	for {
+		fin, opcode, payload, err := readFrame(s.reader)
+		if err != nil {
+			return err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := s.writeFrame(opPong, payload); err != nil {
+				return err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			s.writeFrame(opClose, nil)
+			return ErrStreamClosed
+		case opText, opBinary, opContinuation:
+			message = append(message, payload...)
+			if len(message) > maxFrameSize {
+				return fmt.Errorf("message exceeds %d bytes", maxFrameSize)
+			}
+		default:
+			return fmt.Errorf("unexpected WebSocket opcode %d", opcode)
+		}
+
+		if fin {
+			return json.Unmarshal(message, v)
+		}
+	}
+}
+
+// WriteJSON sends v as one text message
+func (s *restStream) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.writeFrame(opText, data)
+}
+
+// Close sends a close frame and closes the connection
+func (s *restStream) Close() error {
+	s.writeFrame(opClose, nil)
+	return s.conn.Close()
+}
+
+func (s *restStream) writeFrame(opcode byte, payload []byte) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return writeFrame(s.conn, opcode, payload)
+}
+
+// writeFrame writes a single final frame. Client frames must be masked.
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, 0x80|byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 0x80|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	header = append(header, mask...)
+
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+
+	if _, err := w.Write(append(header, masked...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readFrame reads one frame, unmasking its payload if needed
+func readFrame(r io.Reader) (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxFrameSize {
+		return false, 0, nil, fmt.Errorf("frame of %d bytes exceeds %d", length, maxFrameSize)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
@@ -0,0 +1,63 @@
+package lnd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	testutils.AssertEqual(t, acceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 10, 200, 70000} {
+		payload := bytes.Repeat([]byte("x"), size)
+
+		var buf bytes.Buffer
+		testutils.AssertNoError(t, writeFrame(&buf, opText, payload))
+
+		fin, opcode, got, err := readFrame(&buf)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, fin, true)
+		testutils.AssertEqual(t, opcode, byte(opText))
+		testutils.AssertEqual(t, string(got), string(payload))
+	}
+}
+
+func TestRESTStreamAnswersPings(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	stream := &restStream{conn: client, reader: bufio.NewReader(client)}
+
+	go func() {
+		// Unmasked server frames: a ping, then a message split in two fragments
+		server.Write([]byte{0x80 | opPing, 2, 'h', 'i'})
+		readFrame(server) // the pong
+		first, second := `{"result`, `":{"funding_amt":"5"}}`
+		server.Write(append([]byte{opText, byte(len(first))}, first...))
+		server.Write(append([]byte{0x80 | opContinuation, byte(len(second))}, second...))
+	}()
+
+	var message channelAcceptStreamMessage
+	testutils.AssertNoError(t, stream.ReadJSON(&message))
+	testutils.AssertEqual(t, message.Result.FundingSat(), int64(5))
+}
+
+func TestChannelAcceptRequestDecoding(t *testing.T) {
+	data := `{"node_pubkey":"AqE=","pending_chan_id":"AAE=","funding_amt":"250000","channel_flags":0}`
+
+	var request ChannelAcceptRequest
+	testutils.AssertNoError(t, json.NewDecoder(strings.NewReader(data)).Decode(&request))
+	testutils.AssertEqual(t, request.Pubkey(), "02a1")
+	testutils.AssertEqual(t, request.FundingSat(), int64(250000))
+	testutils.AssertEqual(t, request.Private(), true)
+}
@@ -0,0 +1,92 @@
+// Package lock keeps two copies of a daemon from running at once, e.g. a
+// systemd restart racing a manual launch, which would otherwise collect and
+// insert the same data twice.
+//
+// Locks are advisory flock(2) locks on a file next to the database. The
+// kernel drops them when the process exits, even after a crash, so a stale
+// lock file never blocks the next start.
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrLocked is returned when another process holds the lock
+var ErrLocked = errors.New("another instance is already running")
+
+// Lock is a held single-instance lock
+type Lock struct {
+	file *os.File
+}
+
+// PathFor returns the lock file of the named daemon using the database at
+// dbPath. Mock runs use their own lock, as they write to separate tables.
+func PathFor(dbPath, name string, mockMode bool) string {
+	if mockMode {
+		name += "-mock"
+	}
+	return filepath.Join(filepath.Dir(dbPath), name+".lock")
+}
+
+// Acquire takes the lock at path without waiting and records our PID in it.
+// If another process holds it, the error wraps ErrLocked and names its PID.
+func Acquire(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			if pid := readPID(file); pid != "" {
+				return nil, fmt.Errorf("%w (pid %s, lock %s)", ErrLocked, pid, path)
+			}
+			return nil, fmt.Errorf("%w (lock %s)", ErrLocked, path)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &Lock{file: file}, nil
+}
+
+// AcquireUnlessDryRun takes the named daemon's lock, refusing to run
+// alongside another instance, which would insert the same data twice. A dry
+// run writes nothing, so it may run next to one: it takes no lock and gets a
+// nil *Lock, which is safe to Release.
+func AcquireUnlessDryRun(dbPath, name string, mockMode, dryRun bool) (*Lock, error) {
+	if dryRun {
+		return nil, nil
+	}
+	return Acquire(PathFor(dbPath, name, mockMode))
+}
+
+// Release gives up the lock; releasing a nil *Lock does nothing. The lock
+// file is left in place: removing it could let one process lock a new file
+// while another has the old one open and is about to lock it.
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	if err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	return l.file.Close()
+}
+
+// readPID returns the PID recorded in a lock file, or "" if there is none
+func readPID(file *os.File) string {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	return strings.TrimSpace(string(buf[:n]))
+}
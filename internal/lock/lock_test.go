@@ -0,0 +1,62 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestAcquire(t *testing.T) {
+	path := PathFor(filepath.Join(t.TempDir(), "portfolio.db"), "forwarding-collector", false)
+	testutils.AssertEqual(t, filepath.Base(path), "forwarding-collector.lock")
+
+	first, err := Acquire(path)
+	testutils.AssertNoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, strings.TrimSpace(string(contents)), strconv.Itoa(os.Getpid()))
+
+	// A second open file description cannot take the lock while it is held
+	_, err = Acquire(path)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	testutils.AssertError(t, err, "pid "+strconv.Itoa(os.Getpid()))
+
+	// Mock runs lock separately
+	mock, err := Acquire(PathFor(path, "forwarding-collector", true))
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, mock.Release())
+
+	testutils.AssertNoError(t, first.Release())
+	second, err := Acquire(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, second.Release())
+}
+
+func TestAcquireUnlessDryRun(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "portfolio.db")
+
+	held, err := AcquireUnlessDryRun(dbPath, "monthly-close", false, false)
+	testutils.AssertNoError(t, err)
+
+	// A dry run may run next to the real one
+	dry, err := AcquireUnlessDryRun(dbPath, "monthly-close", false, true)
+	testutils.AssertNoError(t, err)
+	if dry != nil {
+		t.Fatal("expected no lock for a dry run")
+	}
+	testutils.AssertNoError(t, dry.Release())
+
+	_, err = AcquireUnlessDryRun(dbPath, "monthly-close", false, false)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	testutils.AssertNoError(t, held.Release())
+}
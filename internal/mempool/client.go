@@ -113,6 +113,68 @@ func (c *Client) GetAddressTransactions(address string, after string) ([]Transac
 	return transactions, nil
 }
 
+// ChainTransactionsPageSize is the number of confirmed transactions Esplora
+// returns per page of address history
+const ChainTransactionsPageSize = 25
+
+// GetAddressChainTransactions gets one page of confirmed transactions for an
+// address, newest first. Pass the last txid of the previous page as lastSeen
+// to fetch the next page; a page shorter than ChainTransactionsPageSize is the
+// last. Works with both mempool.space and plain Esplora servers.
+func (c *Client) GetAddressChainTransactions(address string, lastSeen string) ([]Transaction, error) {
+	if err := c.limiter.Wait(); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/address/%s/txs/chain", c.baseURL, address)
+	if lastSeen != "" {
+		url += "/" + lastSeen
+	}
+
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch address transactions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("mempool API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var transactions []Transaction
+	if err := json.NewDecoder(resp.Body).Decode(&transactions); err != nil {
+		return nil, fmt.Errorf("failed to decode transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// GetAllAddressChainTransactions pages through the full confirmed history of an address
+func (c *Client) GetAllAddressChainTransactions(address string) ([]Transaction, error) {
+	return c.GetAddressChainTransactionsSince(address, 0)
+}
+
+// GetAddressChainTransactionsSince pages through the confirmed history of an
+// address back to minHeight. Since pages are newest first, paging stops at
+// the first page reaching below minHeight; that page may still include
+// older transactions.
+func (c *Client) GetAddressChainTransactionsSince(address string, minHeight int64) ([]Transaction, error) {
+	var all []Transaction
+	lastSeen := ""
+	for {
+		page, err := c.GetAddressChainTransactions(address, lastSeen)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < ChainTransactionsPageSize || page[len(page)-1].Status.BlockHeight < minHeight {
+			return all, nil
+		}
+		lastSeen = page[len(page)-1].TxID
+	}
+}
+
 // GetChainTips gets current blockchain information
 func (c *Client) GetChainTips() (*ChainTips, error) {
 	if err := c.limiter.Wait(); err != nil {
@@ -147,6 +209,43 @@ func (c *Client) GetChainTips() (*ChainTips, error) {
 	}, nil
 }
 
+// GetPrices gets the current BTC price in each currency mempool.space tracks
+func (c *Client) GetPrices() (*Prices, error) {
+	if err := c.limiter.Wait(); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/v1/prices", c.baseURL)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("mempool API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	// The response is {"time": <unix>, "USD": <price>, "EUR": <price>, ...}
+	var raw map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode prices: %w", err)
+	}
+
+	prices := &Prices{
+		Time:   time.Unix(int64(raw["time"]), 0),
+		Rates:  make(map[string]float64, len(raw)),
+		Source: "mempool",
+	}
+	for currency, rate := range raw {
+		if currency != "time" {
+			prices.Rates[currency] = rate
+		}
+	}
+	return prices, nil
+}
+
 // CalculateAddressBalance calculates total balance from UTXOs
 func (c *Client) CalculateAddressBalance(address string) (int64, int64, error) {
 	utxos, err := c.GetAddressUTXOs(address)
@@ -120,3 +120,12 @@ type RateLimitError struct {
 func (e *RateLimitError) Error() string {
 	return e.Message
 }
+
+// Prices holds the BTC exchange rates returned by /v1/prices, keyed by
+// currency code (e.g. "USD"), when they were published and by which API,
+// e.g. "mempool"
+type Prices struct {
+	Time   time.Time
+	Rates  map[string]float64
+	Source string
+}
@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobBuckets are the upper bounds, in seconds, of histograms of work that
+// takes longer than a request, such as collector runs and deployments
+var JobBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// Default holds the metrics recorded by shared packages, such as LND calls,
+// collector runs and cache lookups. Services expose it next to their own.
+var Default = NewRegistry()
+
+// Counter is a family of counters, one per combination of label values
+type Counter struct{ f *family }
+
+// Gauge is a family of gauges, one per combination of label values
+type Gauge struct{ f *family }
+
+// Histogram is a family of histograms, one per combination of label values
+type Histogram struct{ f *family }
+
+// family is the series of one metric name
+type family struct {
+	name    string
+	help    string
+	kind    string // counter, gauge or histogram, as in its # TYPE line
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	// value is the counter or gauge value
+	value float64
+	// buckets[i] counts observations up to the family's buckets[i]
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// NewCounter registers a counter named name, e.g. lnd_call_errors_total,
+// with the given label names
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, "counter", nil, labels)}
+}
+
+// NewGauge registers a gauge named name with the given label names
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, "gauge", nil, labels)}
+}
+
+// NewHistogram registers a histogram of durations in seconds, counted into
+// buckets, e.g. Buckets or JobBuckets
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r.register(name, help, "histogram", buckets, labels)}
+}
+
+func (r *Registry) register(name, help, kind string, buckets []float64, labels []string) *family {
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.families {
+		if existing.name == name {
+			panic(fmt.Sprintf("metrics: %s registered twice", name))
+		}
+	}
+	r.families = append(r.families, f)
+	return f
+}
+
+// Inc adds one to the counter of the label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta, which must not be negative, to the counter of the label values
+func (c *Counter) Add(delta float64, values ...string) {
+	c.f.update(values, func(s *series) { s.value += delta })
+}
+
+// Set sets the gauge of the label values
+func (g *Gauge) Set(value float64, values ...string) {
+	g.f.update(values, func(s *series) { s.value = value })
+}
+
+// Observe records one duration of the label values
+func (h *Histogram) Observe(duration time.Duration, values ...string) {
+	seconds := duration.Seconds()
+	h.f.update(values, func(s *series) {
+		s.count++
+		s.sum += seconds
+		for i, bound := range h.f.buckets {
+			if seconds <= bound {
+				s.buckets[i]++
+			}
+		}
+	})
+}
+
+func (f *family) update(values []string, apply func(*series)) {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\x00")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: values, buckets: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	apply(s)
+}
+
+// write appends the family in the Prometheus text format. Families without
+// series yet are left out.
+func (f *family) write(b *strings.Builder, namespace string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.series) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	name := namespace + "_" + f.name
+	fmt.Fprintf(b, "# HELP %s %s\n", name, f.help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, f.kind)
+	for _, key := range keys {
+		s := f.series[key]
+		labels := f.labelPairs(s.values)
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", name, braces(labels), formatValue(s.value))
+			continue
+		}
+		for i, bound := range f.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", name, braces(append(labels, fmt.Sprintf("le=\"%g\"", bound))), s.buckets[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", name, braces(append(labels, `le="+Inf"`)), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", name, braces(labels), formatValue(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", name, braces(labels), s.count)
+	}
+}
+
+func (f *family) labelPairs(values []string) []string {
+	pairs := make([]string, len(values), len(values)+1)
+	for i, value := range values {
+		pairs[i] = fmt.Sprintf("%s=%q", f.labels[i], value)
+	}
+	return pairs
+}
+
+// formatValue writes sats and unix times in full rather than as 1.2e+06
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func braces(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CacheLookups counts the reads of the caches in front of LND, Bitcoin Core
+// and price sources, by cache and whether they were answered from it
+var CacheLookups = Default.NewCounter("cache_lookups_total",
+	"Cache reads, by cache and result (hit or miss)", "cache", "result")
+
+// CacheLookup records one read of cache
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheLookups.Inc(cache, result)
+}
@@ -0,0 +1,316 @@
+// Package metrics counts requests, errors and durations per route of the HTTP
+// services, and exposes them in the Prometheus text format and as JSON.
+//
+// Routes are identified by their template (e.g. /api/v1/onchain/addresses/{id})
+// so an endpoint's requests are aggregated whatever IDs they carry. Durations
+// go into a fixed histogram, from which percentiles are estimated the way
+// Prometheus' histogram_quantile does.
+//
+// Besides requests, a registry holds counters, gauges and histograms of any
+// other work, such as collector runs. Shared packages record theirs in
+// Default, which every service's /metrics includes.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Buckets are the upper bounds, in seconds, of the request duration histogram
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds the counters of one service
+type Registry struct {
+	mu       sync.Mutex
+	routes   map[routeKey]*routeCounters
+	families []*family
+	scrapes  []func()
+	started  time.Time
+}
+
+type routeKey struct {
+	method string
+	route  string
+}
+
+type routeCounters struct {
+	requests     uint64
+	clientErrors uint64
+	serverErrors uint64
+	seconds      float64
+	maxSeconds   float64
+	// buckets[i] counts requests up to Buckets[i]; slower ones only count
+	// toward requests
+	buckets []uint64
+}
+
+// RouteStats summarizes the requests to one route
+type RouteStats struct {
+	Method       string  `json:"method"`
+	Route        string  `json:"route"`
+	Requests     uint64  `json:"requests"`
+	ClientErrors uint64  `json:"client_errors"`
+	ServerErrors uint64  `json:"server_errors"`
+	ErrorRate    float64 `json:"error_rate"` // share of requests answered with 5xx
+	MeanMs       float64 `json:"mean_ms"`
+	P50Ms        float64 `json:"p50_ms"`
+	P95Ms        float64 `json:"p95_ms"`
+	MaxMs        float64 `json:"max_ms"`
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{routes: make(map[routeKey]*routeCounters), started: time.Now()}
+}
+
+// Started returns when the registry began counting
+func (r *Registry) Started() time.Time {
+	return r.started
+}
+
+// Observe records one request
+func (r *Registry) Observe(method, route string, status int, duration time.Duration) {
+	seconds := duration.Seconds()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := routeKey{method: method, route: route}
+	c, ok := r.routes[key]
+	if !ok {
+		c = &routeCounters{buckets: make([]uint64, len(Buckets))}
+		r.routes[key] = c
+	}
+
+	c.requests++
+	switch {
+	case status >= 500:
+		c.serverErrors++
+	case status >= 400:
+		c.clientErrors++
+	}
+	c.seconds += seconds
+	c.maxSeconds = math.Max(c.maxSeconds, seconds)
+	for i, bound := range Buckets {
+		if seconds <= bound {
+			c.buckets[i]++
+		}
+	}
+}
+
+// Middleware records every request passed to next under the route name
+// returns for it
+func (r *Registry) Middleware(route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				// A handler that panics, e.g. to abort a stream, failed
+				status := recorder.status
+				p := recover()
+				if p != nil {
+					status = http.StatusInternalServerError
+				}
+				r.Observe(req.Method, route(req), status, time.Since(start))
+				if p != nil {
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(recorder, req)
+		})
+	}
+}
+
+// Stats returns every route's summary, slowest p95 first
+func (r *Registry) Stats() []RouteStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]RouteStats, 0, len(r.routes))
+	for key, c := range r.routes {
+		stats = append(stats, RouteStats{
+			Method:       key.method,
+			Route:        key.route,
+			Requests:     c.requests,
+			ClientErrors: c.clientErrors,
+			ServerErrors: c.serverErrors,
+			ErrorRate:    float64(c.serverErrors) / float64(c.requests),
+			MeanMs:       c.seconds / float64(c.requests) * 1000,
+			P50Ms:        c.quantile(0.5) * 1000,
+			P95Ms:        c.quantile(0.95) * 1000,
+			MaxMs:        c.maxSeconds * 1000,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].P95Ms != stats[j].P95Ms {
+			return stats[i].P95Ms > stats[j].P95Ms
+		}
+		if stats[i].Route != stats[j].Route {
+			return stats[i].Route < stats[j].Route
+		}
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// quantile estimates the q-quantile duration in seconds by interpolating
+// within the histogram bucket it falls in. Beyond the last bucket the
+// slowest request seen is the best estimate.
+func (c *routeCounters) quantile(q float64) float64 {
+	rank := q * float64(c.requests)
+	lower, below := 0.0, uint64(0)
+	for i, bound := range Buckets {
+		if float64(c.buckets[i]) >= rank {
+			inBucket := c.buckets[i] - below
+			if inBucket == 0 {
+				return bound
+			}
+			estimate := lower + (bound-lower)*(rank-float64(below))/float64(inBucket)
+			return math.Min(estimate, c.maxSeconds)
+		}
+		lower, below = bound, c.buckets[i]
+	}
+	return c.maxSeconds
+}
+
+// OnScrape registers fn to run before every WritePrometheus, to set gauges
+// read from elsewhere, e.g. the latest balances in the database
+func (r *Registry) OnScrape(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scrapes = append(r.scrapes, fn)
+}
+
+// WritePrometheus writes every counter in the Prometheus text format, with
+// metric names prefixed by namespace, e.g. portfolio_api, followed by the
+// metrics of others, e.g. Default. Others that are r itself are skipped.
+func (r *Registry) WritePrometheus(w io.Writer, namespace string, others ...*Registry) error {
+	var b strings.Builder
+	r.writeRoutes(&b, namespace)
+	r.writeFamilies(&b, namespace)
+	for _, other := range others {
+		if other != r {
+			other.writeFamilies(&b, namespace)
+		}
+	}
+
+	fmt.Fprintf(&b, "# HELP %s_start_time_seconds When the service started, in unix seconds\n", namespace)
+	fmt.Fprintf(&b, "# TYPE %s_start_time_seconds gauge\n", namespace)
+	fmt.Fprintf(&b, "%s_start_time_seconds %d\n", namespace, r.started.Unix())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRoutes writes the request counters. Services without routes, such as
+// collectors, have none to write.
+func (r *Registry) writeRoutes(b *strings.Builder, namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.routes) == 0 {
+		return
+	}
+
+	keys := make([]routeKey, 0, len(r.routes))
+	for key := range r.routes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	fmt.Fprintf(b, "# HELP %s_http_requests_total Requests served, by route\n", namespace)
+	fmt.Fprintf(b, "# TYPE %s_http_requests_total counter\n", namespace)
+	for _, key := range keys {
+		fmt.Fprintf(b, "%s_http_requests_total{%s} %d\n", namespace, key.labels(), r.routes[key].requests)
+	}
+
+	fmt.Fprintf(b, "# HELP %s_http_request_errors_total Requests answered with an error, by route and status class\n", namespace)
+	fmt.Fprintf(b, "# TYPE %s_http_request_errors_total counter\n", namespace)
+	for _, key := range keys {
+		c := r.routes[key]
+		fmt.Fprintf(b, "%s_http_request_errors_total{%s,class=\"4xx\"} %d\n", namespace, key.labels(), c.clientErrors)
+		fmt.Fprintf(b, "%s_http_request_errors_total{%s,class=\"5xx\"} %d\n", namespace, key.labels(), c.serverErrors)
+	}
+
+	fmt.Fprintf(b, "# HELP %s_http_request_duration_seconds Request durations, by route\n", namespace)
+	fmt.Fprintf(b, "# TYPE %s_http_request_duration_seconds histogram\n", namespace)
+	for _, key := range keys {
+		c := r.routes[key]
+		for i, bound := range Buckets {
+			fmt.Fprintf(b, "%s_http_request_duration_seconds_bucket{%s,le=\"%g\"} %d\n", namespace, key.labels(), bound, c.buckets[i])
+		}
+		fmt.Fprintf(b, "%s_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", namespace, key.labels(), c.requests)
+		fmt.Fprintf(b, "%s_http_request_duration_seconds_sum{%s} %g\n", namespace, key.labels(), c.seconds)
+		fmt.Fprintf(b, "%s_http_request_duration_seconds_count{%s} %d\n", namespace, key.labels(), c.requests)
+	}
+}
+
+// writeFamilies runs the scrape hooks, then writes the families in the order
+// they were registered
+func (r *Registry) writeFamilies(b *strings.Builder, namespace string) {
+	r.mu.Lock()
+	scrapes := slices.Clone(r.scrapes)
+	r.mu.Unlock()
+	for _, fn := range scrapes {
+		fn()
+	}
+
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+	for _, f := range families {
+		f.write(b, namespace)
+	}
+}
+
+// Handler serves the Prometheus text format of r and others
+func (r *Registry) Handler(namespace string, others ...*Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WritePrometheus(w, namespace, others...)
+	})
+}
+
+func (k routeKey) labels() string {
+	return fmt.Sprintf("method=%q,route=%q", k.method, k.route)
+}
+
+// statusRecorder keeps the status code a handler writes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which
+// streaming handlers flush through
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Hijack hands the connection over to WebSocket handlers, which answer the
+// upgrade with 101 Switching Protocols on the connection itself
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(s.ResponseWriter).Hijack()
+	if err == nil {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestRegistryStats(t *testing.T) {
+	r := NewRegistry()
+	for i := 0; i < 18; i++ {
+		r.Observe("GET", "/api/v1/onchain/addresses/{id}/history", 200, 40*time.Millisecond)
+	}
+	r.Observe("GET", "/api/v1/onchain/addresses/{id}/history", 500, 3*time.Second)
+	r.Observe("GET", "/api/v1/onchain/addresses/{id}/history", 404, 2*time.Millisecond)
+	r.Observe("GET", "/api/v1/health", 200, time.Millisecond)
+
+	stats := r.Stats()
+	testutils.AssertEqual(t, len(stats), 2)
+
+	// Slowest first
+	history := stats[0]
+	testutils.AssertEqual(t, history.Route, "/api/v1/onchain/addresses/{id}/history")
+	testutils.AssertEqual(t, history.Requests, uint64(20))
+	testutils.AssertEqual(t, history.ClientErrors, uint64(1))
+	testutils.AssertEqual(t, history.ServerErrors, uint64(1))
+	testutils.AssertEqual(t, history.ErrorRate, 0.05)
+	testutils.AssertEqual(t, history.MaxMs, 3000.0)
+	// 19 of 20 requests took at most 50ms, so p95 falls in the 25-50ms bucket
+	if history.P95Ms <= 25 || history.P95Ms > 50 {
+		t.Errorf("p95 = %vms, want within the 25-50ms bucket", history.P95Ms)
+	}
+	if history.P50Ms <= 25 || history.P50Ms > 50 {
+		t.Errorf("p50 = %vms, want within the 25-50ms bucket", history.P50Ms)
+	}
+
+	testutils.AssertEqual(t, stats[1].Route, "/api/v1/health")
+	testutils.AssertEqual(t, stats[1].P95Ms, 1.0)
+}
+
+func TestMiddleware(t *testing.T) {
+	r := NewRegistry()
+	route := func(*http.Request) string { return "/export" }
+	handler := r.Middleware(route)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("fail") != "" {
+			http.Error(w, "bad", http.StatusBadRequest)
+			return
+		}
+		if req.URL.Query().Get("abort") != "" {
+			panic(http.ErrAbortHandler)
+		}
+		// Streaming handlers can still flush through the recorder
+		testutils.AssertNoError(t, http.NewResponseController(w).Flush())
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/export", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/export?fail=1", nil))
+	func() {
+		defer func() {
+			testutils.AssertEqual(t, recover(), interface{}(http.ErrAbortHandler))
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/export?abort=1", nil))
+	}()
+
+	stats := r.Stats()
+	testutils.AssertEqual(t, len(stats), 1)
+	testutils.AssertEqual(t, stats[0].Requests, uint64(3))
+	testutils.AssertEqual(t, stats[0].ClientErrors, uint64(1))
+	testutils.AssertEqual(t, stats[0].ServerErrors, uint64(1))
+}
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	r.Observe("POST", "/api/v1/onchain/addresses", 200, 20*time.Millisecond)
+	r.Observe("POST", "/api/v1/onchain/addresses", 503, 2*time.Second)
+
+	var b strings.Builder
+	testutils.AssertNoError(t, r.WritePrometheus(&b, "portfolio_api"))
+	out := b.String()
+
+	for _, line := range []string{
+		"# TYPE portfolio_api_http_requests_total counter",
+		`portfolio_api_http_requests_total{method="POST",route="/api/v1/onchain/addresses"} 2`,
+		`portfolio_api_http_request_errors_total{method="POST",route="/api/v1/onchain/addresses",class="5xx"} 1`,
+		`portfolio_api_http_request_duration_seconds_bucket{method="POST",route="/api/v1/onchain/addresses",le="0.025"} 1`,
+		`portfolio_api_http_request_duration_seconds_bucket{method="POST",route="/api/v1/onchain/addresses",le="2.5"} 2`,
+		`portfolio_api_http_request_duration_seconds_bucket{method="POST",route="/api/v1/onchain/addresses",le="+Inf"} 2`,
+		`portfolio_api_http_request_duration_seconds_count{method="POST",route="/api/v1/onchain/addresses"} 2`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+}
+
+func TestFamilies(t *testing.T) {
+	r := NewRegistry()
+	runs := r.NewCounter("runs_total", "Runs, by collector and status", "collector", "status")
+	balance := r.NewGauge("balance_sats", "Balance")
+	duration := r.NewHistogram("run_duration_seconds", "Run durations", JobBuckets, "collector")
+
+	runs.Inc("strike", "success")
+	runs.Inc("strike", "success")
+	runs.Inc("liquid", "failed")
+	duration.Observe(3*time.Second, "strike")
+	duration.Observe(45*time.Second, "strike")
+	scrapes := 0
+	r.OnScrape(func() {
+		scrapes++
+		balance.Set(1250000)
+	})
+
+	shared := NewRegistry()
+	shared.NewCounter("cache_lookups_total", "Cache reads", "cache", "result").Inc("btc_price", "hit")
+	// Families nothing was recorded in are left out
+	shared.NewCounter("unused_total", "Never incremented")
+
+	var b strings.Builder
+	testutils.AssertNoError(t, r.WritePrometheus(&b, "collector", shared, r))
+	out := b.String()
+	testutils.AssertEqual(t, scrapes, 1)
+
+	for _, line := range []string{
+		"# TYPE collector_runs_total counter",
+		`collector_runs_total{collector="liquid",status="failed"} 1`,
+		`collector_runs_total{collector="strike",status="success"} 2`,
+		"# TYPE collector_balance_sats gauge",
+		"collector_balance_sats 1250000",
+		"# TYPE collector_run_duration_seconds histogram",
+		`collector_run_duration_seconds_bucket{collector="strike",le="2.5"} 0`,
+		`collector_run_duration_seconds_bucket{collector="strike",le="5"} 1`,
+		`collector_run_duration_seconds_bucket{collector="strike",le="60"} 2`,
+		`collector_run_duration_seconds_bucket{collector="strike",le="+Inf"} 2`,
+		`collector_run_duration_seconds_sum{collector="strike"} 48`,
+		`collector_cache_lookups_total{cache="btc_price",result="hit"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+	// Without requests there is no HTTP section, and r is written once
+	testutils.AssertEqual(t, strings.Contains(out, "http_requests_total"), false)
+	testutils.AssertEqual(t, strings.Contains(out, "unused_total"), false)
+	testutils.AssertEqual(t, strings.Count(out, "# TYPE collector_runs_total"), 1)
+}
+
+func TestFamilyMisuse(t *testing.T) {
+	r := NewRegistry()
+	runs := r.NewCounter("runs_total", "Runs", "collector")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic for a missing label value")
+			}
+		}()
+		runs.Inc()
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic for a name registered twice")
+			}
+		}()
+		r.NewGauge("runs_total", "Runs")
+	}()
+}
+
+func TestServe(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("runs_total", "Runs").Inc()
+	testutils.AssertNoError(t, r.Serve("", "collector"))
+
+	socket := filepath.Join(t.TempDir(), "metrics.sock")
+	testutils.AssertNoError(t, r.Serve("unix:"+socket, "collector"))
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://collector/metrics")
+	testutils.AssertNoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, resp.StatusCode, http.StatusOK)
+	testutils.AssertEqual(t, strings.Contains(string(body), "collector_runs_total 1\n"), true)
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/brewgator/lightning-node-tools/internal/listen"
+)
+
+// AddrFlagUsage describes the --metrics-addr flag of the services that are
+// not HTTP servers themselves, such as the collectors
+const AddrFlagUsage = "Serve Prometheus metrics on /metrics at host:port or unix:/path (empty disables)"
+
+// Serve serves the metrics of r and Default on /metrics at addr in the
+// background for as long as the process runs. It returns once addr is
+// listened on; an empty addr serves nothing.
+func (r *Registry) Serve(addr, namespace string) error {
+	if addr == "" {
+		return nil
+	}
+	listener, err := listen.Listen(addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", r.Handler(namespace, Default))
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Printf("⚠️  Metrics endpoint stopped: %v", err)
+		}
+	}()
+	log.Printf("📈 Serving metrics on %s/metrics", addr)
+	return nil
+}
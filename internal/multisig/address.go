@@ -0,0 +1,190 @@
+package multisig
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// Script opcodes used by multisig witness scripts
+const (
+	opPushData1     = 0x01
+	opBase          = 0x50 // OP_1 through OP_16 are opBase+1 through opBase+16
+	opCheckMultisig = 0xae
+)
+
+// witnessScript returns "<threshold> <pubkeys...> <n> OP_CHECKMULTISIG"
+func witnessScript(threshold int, pubKeys [][]byte) []byte {
+	script := pushNumber(threshold)
+	for _, pubKey := range pubKeys {
+		script = append(script, byte(len(pubKey)))
+		script = append(script, pubKey...)
+	}
+	script = append(script, pushNumber(len(pubKeys))...)
+	return append(script, opCheckMultisig)
+}
+
+// pushNumber encodes a small number the way Bitcoin Core's descriptors do
+func pushNumber(n int) []byte {
+	if n >= 1 && n <= 16 {
+		return []byte{byte(opBase + n)}
+	}
+	return []byte{opPushData1, byte(n)}
+}
+
+// p2wshAddress returns the native segwit address paying to script
+func p2wshAddress(script []byte, testnet bool) string {
+	hrp := "bc"
+	if testnet {
+		hrp = "tb"
+	}
+	program := sha256.Sum256(script)
+	return segwitAddress(hrp, 0, program[:])
+}
+
+// bech32Charset maps 5 bit values to bech32 characters
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// segwitAddress encodes a version 0 witness program with bech32 (BIP173).
+// Later versions use bech32m and are not needed for multisig.
+func segwitAddress(hrp string, version byte, program []byte) string {
+	data := append([]byte{version}, convertBits(program, 8, 5)...)
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ 1
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range data {
+		sb.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(polymod>>(5*(5-i)))&31])
+	}
+	return sb.String()
+}
+
+// Constants a valid bech32 or bech32m (BIP350) checksum leaves in the polymod
+const (
+	bech32Constant  = 1
+	bech32mConstant = 0x2bc830a3
+)
+
+// destination is what the size of a transaction depends on of the address
+// it pays to
+type destination struct {
+	scriptSize int
+	segwit     bool
+	testnet    bool
+}
+
+// parseDestination checks an address and returns its output script size
+func parseDestination(address string) (destination, error) {
+	lower := strings.ToLower(address)
+	switch {
+	case strings.HasPrefix(lower, "bc1") || strings.HasPrefix(lower, "tb1"):
+		program, err := decodeSegwitAddress(lower)
+		if err != nil {
+			return destination{}, err
+		}
+		// OP_n <push of the program>
+		return destination{scriptSize: 2 + program, segwit: true, testnet: strings.HasPrefix(lower, "tb1")}, nil
+	case !utils.ValidateBitcoinAddress(address):
+		return destination{}, fmt.Errorf("invalid address %s", address)
+	case address[0] == '1' || address[0] == 'm' || address[0] == 'n':
+		// OP_DUP OP_HASH160 <20 bytes> OP_EQUALVERIFY OP_CHECKSIG
+		return destination{scriptSize: 25, testnet: address[0] != '1'}, nil
+	default:
+		// OP_HASH160 <20 bytes> OP_EQUAL
+		return destination{scriptSize: 23, testnet: address[0] != '3'}, nil
+	}
+}
+
+// decodeSegwitAddress checks a lowercase bech32 or bech32m address and
+// returns the length of its witness program
+func decodeSegwitAddress(address string) (int, error) {
+	separator := strings.LastIndex(address, "1")
+	if separator < 1 || len(address)-separator < 8 {
+		return 0, fmt.Errorf("invalid address %s", address)
+	}
+
+	var data []byte
+	for _, r := range address[separator+1:] {
+		value := strings.IndexRune(bech32Charset, r)
+		if value < 0 {
+			return 0, fmt.Errorf("invalid address %s", address)
+		}
+		data = append(data, byte(value))
+	}
+	version := data[0]
+	want := uint32(bech32Constant)
+	if version > 0 {
+		want = bech32mConstant
+	}
+	if bech32Polymod(append(bech32HRPExpand(address[:separator]), data...)) != want {
+		return 0, fmt.Errorf("address %s has an invalid checksum", address)
+	}
+
+	// The program is what is left of the 5 bit groups after dropping the
+	// version and the checksum, without the padding bits
+	program := (len(data) - 7) * 5 / 8
+	switch {
+	case version > 16:
+		return 0, fmt.Errorf("address %s has invalid witness version %d", address, version)
+	case version == 0 && program != 20 && program != 32:
+		return 0, errors.New("version 0 witness programs are 20 or 32 bytes")
+	case program < 2 || program > 40:
+		return 0, fmt.Errorf("address %s has an invalid witness program", address)
+	}
+	return program, nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// convertBits regroups data from groups of from bits into groups of to
+// bits, padding the last group with zeros
+func convertBits(data []byte, from, to uint) []byte {
+	var out []byte
+	acc, bits := uint32(0), uint(0)
+	maxValue := uint32(1)<<to - 1
+	for _, b := range data {
+		acc = acc<<from | uint32(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxValue))
+		}
+	}
+	if bits > 0 {
+		out = append(out, byte(acc<<(to-bits)&maxValue))
+	}
+	return out
+}
@@ -0,0 +1,517 @@
+// Package multisig derives the addresses of wsh(sortedmulti(...)) and
+// wsh(multi(...)) wallet descriptors, and what each cosigner's hardware
+// wallet shows for them, so an address can be checked on every device
+// before funds are sent to it. It works offline and never needs a node.
+package multisig
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// MaxCosigners is the most keys OP_CHECKMULTISIG accepts
+const MaxCosigners = 20
+
+// Descriptor is a multisig wallet descriptor
+type Descriptor struct {
+	Threshold int
+	// Sorted is true for sortedmulti, whose script orders the keys, and
+	// false for multi, which keeps the descriptor's order
+	Sorted    bool
+	Cosigners []Cosigner
+}
+
+// Cosigner is one key of a multisig descriptor
+type Cosigner struct {
+	Fingerprint string // master key fingerprint, lowercase hex
+	OriginPath  string // path from the master key to XPub, e.g. "48'/0'/0'/2'"
+	XPub        string // as written in the descriptor
+	key         *ExtendedKey
+	steps       []pathStep
+}
+
+// pathStep is one derivation step after the xpub
+type pathStep struct {
+	receive, change uint32 // the same unless the step is <receive;change>
+	multipath       bool
+	wildcard        bool
+}
+
+// DerivedKey is a cosigner's key of one address
+type DerivedKey struct {
+	Fingerprint string
+	Path        string // full derivation path, e.g. "m/48'/0'/0'/2'/0/5"
+	PubKey      []byte
+}
+
+// Address is one multisig address with what is needed to verify it
+type Address struct {
+	Index         uint32
+	Change        bool
+	Address       string
+	WitnessScript []byte
+	Keys          []DerivedKey // in descriptor order
+	ScriptKeys    [][]byte     // public keys in the order of the script
+}
+
+// Parse parses a wsh(sortedmulti(...)) or wsh(multi(...)) descriptor whose
+// keys are ranged xpubs with key origins, e.g.
+// [f5acc2fd/48'/0'/0'/2']xpub.../<0;1>/*. A #checksum is verified if given.
+func Parse(descriptor string) (*Descriptor, error) {
+	descriptor = strings.TrimSpace(descriptor)
+	if body, checksum, ok := strings.Cut(descriptor, "#"); ok {
+		want, err := descriptorChecksum(body)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != want {
+			return nil, fmt.Errorf("descriptor checksum is %s, want %s; check for typos", checksum, want)
+		}
+		descriptor = body
+	}
+
+	inner, ok := unwrap(descriptor, "wsh")
+	if !ok {
+		return nil, errors.New("only wsh(sortedmulti(...)) and wsh(multi(...)) descriptors are supported")
+	}
+	d := &Descriptor{}
+	args, sorted := unwrap(inner, "sortedmulti")
+	if !sorted {
+		if args, ok = unwrap(inner, "multi"); !ok {
+			return nil, errors.New("only wsh(sortedmulti(...)) and wsh(multi(...)) descriptors are supported")
+		}
+	}
+	d.Sorted = sorted
+
+	parts := strings.Split(args, ",")
+	threshold, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold %q", parts[0])
+	}
+	keys := parts[1:]
+	if len(keys) == 0 || len(keys) > MaxCosigners {
+		return nil, fmt.Errorf("a multisig needs 1 to %d keys, got %d", MaxCosigners, len(keys))
+	}
+	if threshold < 1 || threshold > len(keys) {
+		return nil, fmt.Errorf("threshold %d is not between 1 and %d", threshold, len(keys))
+	}
+	d.Threshold = threshold
+
+	for i, expression := range keys {
+		cosigner, err := parseKey(expression)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i+1, err)
+		}
+		if i > 0 && cosigner.key.Testnet != d.Cosigners[0].key.Testnet {
+			return nil, errors.New("keys mix mainnet and testnet")
+		}
+		d.Cosigners = append(d.Cosigners, cosigner)
+	}
+	return d, nil
+}
+
+// unwrap returns the argument of name(...) if s is that expression
+func unwrap(s, name string) (string, bool) {
+	if !strings.HasPrefix(s, name+"(") || !strings.HasSuffix(s, ")") {
+		return "", false
+	}
+	return s[len(name)+1 : len(s)-1], true
+}
+
+// parseKey parses a key expression: [fingerprint/origin]xpub/steps/*
+func parseKey(expression string) (Cosigner, error) {
+	var c Cosigner
+	origin, rest, ok := strings.Cut(strings.TrimPrefix(expression, "["), "]")
+	if !strings.HasPrefix(expression, "[") || !ok {
+		return c, errors.New("key has no [fingerprint/path] origin, which devices need to recognize their key")
+	}
+
+	originParts := strings.Split(origin, "/")
+	fingerprint, err := hex.DecodeString(originParts[0])
+	if err != nil || len(fingerprint) != 4 {
+		return c, fmt.Errorf("invalid fingerprint %q", originParts[0])
+	}
+	c.Fingerprint = hex.EncodeToString(fingerprint)
+	var path []string
+	for _, element := range originParts[1:] {
+		index, hardened, err := parsePathElement(element)
+		if err != nil {
+			return c, err
+		}
+		if hardened {
+			path = append(path, fmt.Sprintf("%d'", index))
+		} else {
+			path = append(path, fmt.Sprint(index))
+		}
+	}
+	c.OriginPath = strings.Join(path, "/")
+
+	elements := strings.Split(rest, "/")
+	c.XPub = elements[0]
+	if c.key, err = ParseExtendedKey(c.XPub); err != nil {
+		return c, err
+	}
+
+	multipaths := 0
+	for i, element := range elements[1:] {
+		var step pathStep
+		switch {
+		case element == "*":
+			if i != len(elements)-2 {
+				return c, errors.New("* must be the last step")
+			}
+			step.wildcard = true
+		case strings.HasPrefix(element, "<") && strings.HasSuffix(element, ">"):
+			receive, change, ok := strings.Cut(element[1:len(element)-1], ";")
+			if !ok {
+				return c, fmt.Errorf("only two-way <receive;change> steps are supported, got %s", element)
+			}
+			if step.receive, err = parseUnhardened(receive); err != nil {
+				return c, err
+			}
+			if step.change, err = parseUnhardened(change); err != nil {
+				return c, err
+			}
+			step.multipath = true
+			multipaths++
+		default:
+			if step.receive, err = parseUnhardened(element); err != nil {
+				return c, err
+			}
+			step.change = step.receive
+		}
+		c.steps = append(c.steps, step)
+	}
+	if len(c.steps) == 0 || !c.steps[len(c.steps)-1].wildcard {
+		return c, errors.New("key is not ranged, end it with /*")
+	}
+	if multipaths > 1 {
+		return c, errors.New("key has more than one <receive;change> step")
+	}
+	return c, nil
+}
+
+// parsePathElement parses a path element such as 48' or 48h
+func parsePathElement(element string) (uint32, bool, error) {
+	hardened := strings.HasSuffix(element, "'") || strings.HasSuffix(element, "h") || strings.HasSuffix(element, "H")
+	number := strings.TrimRight(element, "'hH")
+	index, err := strconv.ParseUint(number, 10, 31)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid path element %q", element)
+	}
+	return uint32(index), hardened, nil
+}
+
+// parseUnhardened parses a path element after the xpub, which cannot be
+// hardened without the private key
+func parseUnhardened(element string) (uint32, error) {
+	index, hardened, err := parsePathElement(element)
+	if err != nil {
+		return 0, err
+	}
+	if hardened {
+		return 0, fmt.Errorf("hardened step %s after the xpub needs the private key", element)
+	}
+	return index, nil
+}
+
+// Derive returns the receive or change address at index
+func (d *Descriptor) Derive(index uint32, change bool) (*Address, error) {
+	if index >= hardenedOffset {
+		return nil, fmt.Errorf("index %d is too large", index)
+	}
+	address := &Address{Index: index, Change: change}
+	for i, c := range d.Cosigners {
+		key, err := c.derive(index, change)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i+1, err)
+		}
+		address.Keys = append(address.Keys, key)
+		address.ScriptKeys = append(address.ScriptKeys, key.PubKey)
+	}
+	if d.Sorted {
+		sort.Slice(address.ScriptKeys, func(i, j int) bool {
+			return bytes.Compare(address.ScriptKeys[i], address.ScriptKeys[j]) < 0
+		})
+	}
+
+	address.WitnessScript = witnessScript(d.Threshold, address.ScriptKeys)
+	address.Address = p2wshAddress(address.WitnessScript, d.Cosigners[0].key.Testnet)
+	return address, nil
+}
+
+// ChainDescriptors returns the receive and change descriptors of d with
+// their checksums, each with a single path for tools that do not accept
+// <receive;change> steps. Keys without such a step have only a receive chain.
+func (d *Descriptor) ChainDescriptors() ([]string, error) {
+	chains := []bool{false}
+	for _, step := range d.Cosigners[0].steps {
+		if step.multipath {
+			chains = append(chains, true)
+		}
+	}
+
+	var descriptors []string
+	for _, change := range chains {
+		descriptor, err := d.render(func(c Cosigner) (string, error) { return c.expression(change, false) })
+		if err != nil {
+			return nil, err
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors, nil
+}
+
+// Text returns d as one descriptor with its checksum, keys keeping their
+// <receive;change> steps, in the form Parse reads. Wallets read from a
+// device export are stored this way.
+func (d *Descriptor) Text() (string, error) {
+	return d.render(func(c Cosigner) (string, error) { return c.expression(false, true) })
+}
+
+// render writes d with the key expressions returned by key, followed by
+// its checksum
+func (d *Descriptor) render(key func(Cosigner) (string, error)) (string, error) {
+	function := "multi"
+	if d.Sorted {
+		function = "sortedmulti"
+	}
+	expressions := []string{strconv.Itoa(d.Threshold)}
+	for _, c := range d.Cosigners {
+		expression, err := key(c)
+		if err != nil {
+			return "", err
+		}
+		expressions = append(expressions, expression)
+	}
+	body := fmt.Sprintf("wsh(%s(%s))", function, strings.Join(expressions, ","))
+	checksum, err := descriptorChecksum(body)
+	if err != nil {
+		return "", err
+	}
+	return body + "#" + checksum, nil
+}
+
+// OutputIndex returns the address index of a descriptor whose keys carry
+// their full origin paths, such as the one scantxoutset reports for each
+// output: the last step of the first key's origin
+func OutputIndex(descriptor string) (uint32, error) {
+	elements, err := outputOrigin(descriptor)
+	if err != nil {
+		return 0, err
+	}
+	return parseUnhardened(elements[len(elements)-1])
+}
+
+// OutputChange reports whether an output descriptor of d, as OutputIndex
+// reads them, is on the change chain: the first key's <receive;change>
+// step took its change value
+func (d *Descriptor) OutputChange(descriptor string) (bool, error) {
+	elements, err := outputOrigin(descriptor)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range d.Cosigners {
+		if c.Fingerprint != strings.ToLower(elements[0]) {
+			continue
+		}
+		depth := 1
+		if c.OriginPath != "" {
+			depth += len(strings.Split(c.OriginPath, "/"))
+		}
+		for i, step := range c.steps {
+			if !step.multipath {
+				continue
+			}
+			if depth+i >= len(elements) {
+				return false, fmt.Errorf("descriptor %q has a shorter key origin than the wallet", descriptor)
+			}
+			value, err := parseUnhardened(elements[depth+i])
+			return err == nil && value == step.change && step.change != step.receive, err
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("descriptor %q has no key of the wallet", descriptor)
+}
+
+// outputOrigin splits the first key origin of an output descriptor into
+// the fingerprint and the path elements
+func outputOrigin(descriptor string) ([]string, error) {
+	_, rest, ok := strings.Cut(descriptor, "[")
+	origin, _, closed := strings.Cut(rest, "]")
+	elements := strings.Split(origin, "/")
+	if !ok || !closed || len(elements) < 2 {
+		return nil, fmt.Errorf("descriptor %q has no key origin with an index", descriptor)
+	}
+	return elements, nil
+}
+
+// expression writes the cosigner's key expression for the receive or
+// change chain, or with multipath set for both as a <receive;change> step.
+// SLIP-132 keys are written as xpub or tpub, the only prefixes Bitcoin Core
+// accepts.
+func (c Cosigner) expression(change, multipath bool) (string, error) {
+	xpub, _, err := utils.NormalizeXPub(c.XPub)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("[" + c.Fingerprint)
+	if c.OriginPath != "" {
+		sb.WriteString("/" + c.OriginPath)
+	}
+	sb.WriteString("]" + xpub)
+	for _, step := range c.steps {
+		switch {
+		case step.wildcard:
+			sb.WriteString("/*")
+		case multipath && step.multipath:
+			fmt.Fprintf(&sb, "/<%d;%d>", step.receive, step.change)
+		case change:
+			fmt.Fprintf(&sb, "/%d", step.change)
+		default:
+			fmt.Fprintf(&sb, "/%d", step.receive)
+		}
+	}
+	return sb.String(), nil
+}
+
+// derive returns the cosigner's key at index
+func (c Cosigner) derive(index uint32, change bool) (DerivedKey, error) {
+	key := c.key
+	path := []string{"m"}
+	if c.OriginPath != "" {
+		path = append(path, c.OriginPath)
+	}
+
+	hasMultipath := false
+	for _, step := range c.steps {
+		child := step.receive
+		switch {
+		case step.wildcard:
+			child = index
+		case step.multipath && change:
+			child = step.change
+		}
+		hasMultipath = hasMultipath || step.multipath
+
+		var err error
+		if key, err = key.Child(child); err != nil {
+			return DerivedKey{}, err
+		}
+		path = append(path, fmt.Sprint(child))
+	}
+	if change && !hasMultipath {
+		return DerivedKey{}, errors.New("key has no <receive;change> step, use the change descriptor instead")
+	}
+
+	return DerivedKey{Fingerprint: c.Fingerprint, Path: strings.Join(path, "/"), PubKey: key.PubKey}, nil
+}
+
+// Differences lists how other, a device's view of the wallet, differs from
+// d at the given address. No differences means the device shows the same
+// address and recognizes the same cosigners.
+func (d *Descriptor) Differences(other *Descriptor, index uint32, change bool) ([]string, error) {
+	var differences []string
+	if d.Threshold != other.Threshold || len(d.Cosigners) != len(other.Cosigners) {
+		differences = append(differences, fmt.Sprintf("policy is %d of %d, want %d of %d",
+			other.Threshold, len(other.Cosigners), d.Threshold, len(d.Cosigners)))
+	}
+	if d.Sorted != other.Sorted {
+		differences = append(differences, "keys are not ordered the same way (sortedmulti and multi)")
+	}
+
+	for i, c := range d.Cosigners {
+		match := other.cosigner(c.key)
+		switch {
+		case match == nil:
+			differences = append(differences, fmt.Sprintf("key %d [%s] is missing", i+1, c.Fingerprint))
+		case match.Fingerprint != c.Fingerprint || match.OriginPath != c.OriginPath:
+			differences = append(differences, fmt.Sprintf("key %d has origin [%s/%s], want [%s/%s]",
+				i+1, match.Fingerprint, match.OriginPath, c.Fingerprint, c.OriginPath))
+		}
+	}
+
+	ours, err := d.Derive(index, change)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := other.Derive(index, change)
+	if err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	if theirs.Address != ours.Address {
+		differences = append(differences, fmt.Sprintf("address is %s, want %s", theirs.Address, ours.Address))
+	}
+	return differences, nil
+}
+
+// cosigner returns the cosigner using key, whatever its SLIP-132 prefix
+func (d *Descriptor) cosigner(key *ExtendedKey) *Cosigner {
+	for i, c := range d.Cosigners {
+		if bytes.Equal(c.key.PubKey, key.PubKey) && bytes.Equal(c.key.ChainCode, key.ChainCode) {
+			return &d.Cosigners[i]
+		}
+	}
+	return nil
+}
+
+// Descriptor checksum character sets (BIP380)
+const (
+	checksumInputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+		"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+		"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	checksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// descriptorChecksum returns the 8 character checksum of a descriptor
+func descriptorChecksum(descriptor string) (string, error) {
+	generator := [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+	chk := uint64(1)
+	polymod := func(value uint64) {
+		top := chk >> 35
+		chk = (chk&0x7ffffffff)<<5 ^ value
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	var groups []uint64
+	for _, r := range descriptor {
+		position := strings.IndexRune(checksumInputCharset, r)
+		if position < 0 {
+			return "", fmt.Errorf("invalid character %q in descriptor", r)
+		}
+		polymod(uint64(position) & 31)
+		groups = append(groups, uint64(position)>>5)
+		if len(groups) == 3 {
+			polymod(groups[0]*9 + groups[1]*3 + groups[2])
+			groups = groups[:0]
+		}
+	}
+	switch len(groups) {
+	case 1:
+		polymod(groups[0])
+	case 2:
+		polymod(groups[0]*3 + groups[1])
+	}
+	for i := 0; i < 8; i++ {
+		polymod(0)
+	}
+	chk ^= 1
+
+	checksum := make([]byte, 8)
+	for i := range checksum {
+		checksum[i] = checksumCharset[(chk>>(5*(7-i)))&31]
+	}
+	return string(checksum), nil
+}
@@ -0,0 +1,205 @@
+package multisig
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+// Extended public keys from the BIP32 test vectors
+const (
+	testKey1 = "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+	testKey2 = "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw"
+	testKey3 = "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB"
+)
+
+// testDescriptor is a 2 of 3 with each key's origin as Coldcard writes it
+func testDescriptor(function string, keys ...string) string {
+	var expressions []string
+	for i, key := range keys {
+		expressions = append(expressions, fmt.Sprintf("[0000000%d/48'/0'/0'/2']%s/<0;1>/*", i+1, key))
+	}
+	return fmt.Sprintf("wsh(%s(2,%s))", function, strings.Join(expressions, ","))
+}
+
+func TestDescriptorChecksum(t *testing.T) {
+	checksum, err := descriptorChecksum("raw(deadbeef)")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, checksum, "89f8spxm")
+
+	descriptor := testDescriptor("sortedmulti", testKey1, testKey2, testKey3)
+	checksum, err = descriptorChecksum(descriptor)
+	testutils.AssertNoError(t, err)
+	_, err = Parse(descriptor + "#" + checksum)
+	testutils.AssertNoError(t, err)
+
+	_, err = Parse(descriptor + "#qqqqqqqq")
+	testutils.AssertError(t, err, "check for typos")
+}
+
+// derivedAddresses are the addresses of testDescriptor("sortedmulti",
+// testKey1, testKey2, testKey3) by branch/index, computed apart from this
+// package with the BIP32 and BIP173 reference algorithms. Bitcoin Core
+// checks them with the descriptor's /<0;1>/* replaced by /0/* or /1/*:
+//
+//	bitcoin-cli deriveaddresses "$(bitcoin-cli getdescriptorinfo '<descriptor>' | jq -r .descriptor)" "[0,5]"
+var derivedAddresses = map[string]string{
+	"0/0": "bc1qs4chg434yxaqdeuqn0a2jegcrpgz8ampvdqcfjwjygv362xtaqcsrf2l67",
+	"0/5": "bc1q83e83e6snl35d3heg2uskw9syt4aphc0tgvjz4szdfrmcr863g4qj8pgru",
+	"1/5": "bc1qyscyjywvspfe8p5s97apgqs5wkef0av82xh359e8hvwddp3zsyfqaztedm",
+}
+
+func TestDerive(t *testing.T) {
+	d, err := Parse(testDescriptor("sortedmulti", testKey1, testKey2, testKey3))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, d.Threshold, 2)
+	testutils.AssertEqual(t, d.Cosigners[1].Fingerprint, "00000002")
+	testutils.AssertEqual(t, d.Cosigners[1].OriginPath, "48'/0'/0'/2'")
+
+	receive, err := d.Derive(5, false)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, receive.Keys[0].Path, "m/48'/0'/0'/2'/0/5")
+	testutils.AssertEqual(t, receive.Address, derivedAddresses["0/5"])
+
+	// Each key is the cosigner's xpub derived by /0/5
+	parent, err := ParseExtendedKey(testKey2)
+	testutils.AssertNoError(t, err)
+	chain, err := parent.Child(0)
+	testutils.AssertNoError(t, err)
+	child, err := chain.Child(5)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, bytes.Equal(receive.Keys[1].PubKey, child.PubKey), true)
+
+	// OP_2 <three sorted keys> OP_3 OP_CHECKMULTISIG
+	script := receive.WitnessScript
+	testutils.AssertEqual(t, len(script), 3+3*34)
+	testutils.AssertEqual(t, script[0], byte(0x52))
+	testutils.AssertEqual(t, script[len(script)-2], byte(0x53))
+	testutils.AssertEqual(t, script[len(script)-1], byte(0xae))
+	for i := 1; i < len(receive.ScriptKeys); i++ {
+		testutils.AssertEqual(t, bytes.Compare(receive.ScriptKeys[i-1], receive.ScriptKeys[i]), -1)
+	}
+
+	change, err := d.Derive(5, true)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, change.Keys[2].Path, "m/48'/0'/0'/2'/1/5")
+	testutils.AssertEqual(t, change.Address, derivedAddresses["1/5"])
+
+	first, err := d.Derive(0, false)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, first.Address, derivedAddresses["0/0"])
+
+	// sortedmulti ignores the order of the keys, multi does not
+	reordered, err := Parse(testDescriptor("sortedmulti", testKey3, testKey1, testKey2))
+	testutils.AssertNoError(t, err)
+	address, err := reordered.Derive(5, false)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, address.Address, receive.Address)
+
+	unsorted, err := Parse(testDescriptor("multi", testKey3, testKey1, testKey2))
+	testutils.AssertNoError(t, err)
+	address, err = unsorted.Derive(5, false)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, bytes.Equal(address.ScriptKeys[0], address.Keys[0].PubKey), true)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct{ descriptor, want string }{
+		{"sh(sortedmulti(1,[00000001/48'/0'/0'/1']" + testKey1 + "/0/*))", "only wsh"},
+		{"wsh(sortedmulti(3,[00000001]" + testKey1 + "/0/*,[00000002]" + testKey2 + "/0/*))", "threshold 3"},
+		{"wsh(sortedmulti(1," + testKey1 + "/0/*))", "no [fingerprint/path] origin"},
+		{"wsh(sortedmulti(1,[00000001]" + testKey1 + "/0'/*))", "needs the private key"},
+		{"wsh(sortedmulti(1,[00000001]" + testKey1 + "/0/5))", "not ranged"},
+		{"wsh(sortedmulti(1,[0001]" + testKey1 + "/0/*))", "invalid fingerprint"},
+	} {
+		_, err := Parse(tc.descriptor)
+		testutils.AssertError(t, err, tc.want)
+	}
+
+	// Change addresses need a <receive;change> step
+	d, err := Parse("wsh(sortedmulti(1,[00000001/48'/0'/0'/2']" + testKey1 + "/0/*))")
+	testutils.AssertNoError(t, err)
+	_, err = d.Derive(0, true)
+	testutils.AssertError(t, err, "use the change descriptor")
+}
+
+func TestChainDescriptors(t *testing.T) {
+	d, err := Parse(testDescriptor("sortedmulti", testKey1, testKey2, testKey3))
+	testutils.AssertNoError(t, err)
+	chains, err := d.ChainDescriptors()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(chains), 2)
+	testutils.AssertEqual(t, strings.Contains(chains[0], "[00000001/48'/0'/0'/2']"+testKey1+"/0/*"), true)
+	testutils.AssertEqual(t, strings.Contains(chains[1], testKey1+"/1/*"), true)
+
+	// Each chain, checksum included, derives the addresses of its half of d
+	for i, change := range []bool{false, true} {
+		chain, err := Parse(chains[i])
+		testutils.AssertNoError(t, err)
+		want, err := d.Derive(7, change)
+		testutils.AssertNoError(t, err)
+		got, err := chain.Derive(7, false)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, got.Address, want.Address)
+	}
+
+	// Without a <receive;change> step there is only the receive chain
+	d, err = Parse("wsh(multi(1,[00000001/48'/0'/0'/2']" + testKey1 + "/0/*))")
+	testutils.AssertNoError(t, err)
+	chains, err = d.ChainDescriptors()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(chains), 1)
+	testutils.AssertEqual(t, strings.HasPrefix(chains[0], "wsh(multi(1,"), true)
+}
+
+func TestText(t *testing.T) {
+	// Hardened steps written with h come back with '
+	d, err := Parse(strings.Replace(testDescriptor("sortedmulti", testKey1, testKey2, testKey3), "48'/0'/0'/2'", "48h/0h/0h/2h", 1))
+	testutils.AssertNoError(t, err)
+	text, err := d.Text()
+	testutils.AssertNoError(t, err)
+	body, _, _ := strings.Cut(text, "#")
+	testutils.AssertEqual(t, body, testDescriptor("sortedmulti", testKey1, testKey2, testKey3))
+
+	// It parses, checksum included, to the same wallet
+	again, err := Parse(text)
+	testutils.AssertNoError(t, err)
+	differences, err := d.Differences(again, 3, true)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, strings.Join(differences, "; "), "")
+}
+
+func TestOutputIndex(t *testing.T) {
+	index, err := OutputIndex("wsh(sortedmulti(2,[00000001/48'/0'/0'/2'/1/42]02756de182c5dd4b717ea87e693006da62dbb3cddaa4a5cad2ed1f5bbab755f0f5," +
+		"[00000002/48'/0'/0'/2'/1/42]027b6a7dd645507d775215a9035be06700e1ed8c541da9351b4bd14bd50ab61428))#checksum")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, index, uint32(42))
+
+	_, err = OutputIndex("addr(bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4)")
+	testutils.AssertError(t, err, "no key origin")
+	_, err = OutputIndex("wsh(multi(1,[00000001]02756de182c5dd4b717ea87e693006da62dbb3cddaa4a5cad2ed1f5bbab755f0f5))")
+	testutils.AssertError(t, err, "no key origin")
+}
+
+func TestOutputChange(t *testing.T) {
+	d, err := Parse(testDescriptor("sortedmulti", testKey1, testKey2, testKey3))
+	testutils.AssertNoError(t, err)
+	output := func(origin string) string {
+		return "wsh(sortedmulti(2,[" + origin + "]02756de182c5dd4b717ea87e693006da62dbb3cddaa4a5cad2ed1f5bbab755f0f5))#checksum"
+	}
+
+	change, err := d.OutputChange(output("00000001/48'/0'/0'/2'/0/42"))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, change, false)
+	change, err = d.OutputChange(output("00000002/48h/0h/0h/2h/1/0"))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, change, true)
+
+	_, err = d.OutputChange(output("0000000f/48'/0'/0'/2'/1/0"))
+	testutils.AssertError(t, err, "no key of the wallet")
+	_, err = d.OutputChange(output("00000001/48'/0'/0'/2'"))
+	testutils.AssertError(t, err, "shorter key origin")
+}
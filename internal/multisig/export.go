@@ -0,0 +1,221 @@
+package multisig
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Export formats ParseExport recognizes
+const (
+	FormatDescriptor   = "descriptor"
+	FormatColdcard     = "Coldcard multisig setup file"
+	FormatLedgerPolicy = "Ledger wallet policy"
+	FormatCaravan      = "Caravan wallet config"
+)
+
+// ParseExport reads the wallet a device or coordinator has registered from
+// one of its exports: a Coldcard multisig setup file, a Ledger wallet policy
+// as JSON (name, descriptor_template, keys_info), a Caravan or Unchained
+// wallet config, or descriptors such as Sparrow's output descriptor export.
+// It returns the wallet as a descriptor and the format found.
+func ParseExport(data []byte) (*Descriptor, string, error) {
+	text := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(text, "{"):
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(text), &fields); err != nil {
+			return nil, "", fmt.Errorf("invalid JSON export: %w", err)
+		}
+		if _, ok := fields["quorum"]; ok {
+			d, err := parseCaravanConfig([]byte(text))
+			return d, FormatCaravan, err
+		}
+		d, err := parseLedgerPolicy([]byte(text))
+		return d, FormatLedgerPolicy, err
+	case isDescriptorExport(text):
+		d, err := parseDescriptorExport(text)
+		return d, FormatDescriptor, err
+	default:
+		d, err := parseColdcardSetup(text)
+		return d, FormatColdcard, err
+	}
+}
+
+// descriptorLines returns the lines of text that are not blank or comments
+func descriptorLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// isDescriptorExport reports whether text is one or more descriptors,
+// possibly with # comment lines
+func isDescriptorExport(text string) bool {
+	lines := descriptorLines(text)
+	return len(lines) > 0 && (strings.HasPrefix(lines[0], "wsh(") || strings.HasPrefix(lines[0], "sh("))
+}
+
+// parseDescriptorExport reads a file of descriptors. Sparrow writes the
+// <0;1> descriptor along with separate receive and change descriptors for
+// Bitcoin Core, each under a comment; the one covering both chains is used
+// when there is one, otherwise the first.
+func parseDescriptorExport(text string) (*Descriptor, error) {
+	lines := descriptorLines(text)
+	for _, line := range lines {
+		if strings.Contains(line, "<") {
+			return Parse(line)
+		}
+	}
+	return Parse(lines[0])
+}
+
+// caravanConfig is a wallet config as Caravan and Unchained export it
+type caravanConfig struct {
+	Name        string `json:"name"`
+	AddressType string `json:"addressType"`
+	Network     string `json:"network"`
+	Quorum      struct {
+		RequiredSigners int `json:"requiredSigners"`
+		TotalSigners    int `json:"totalSigners"`
+	} `json:"quorum"`
+	ExtendedPublicKeys []struct {
+		Name      string `json:"name"`
+		BIP32Path string `json:"bip32Path"`
+		XPub      string `json:"xpub"`
+		XFP       string `json:"xfp"`
+	} `json:"extendedPublicKeys"`
+}
+
+// parseCaravanConfig turns a Caravan wallet config into a descriptor.
+// Caravan always sorts the keys of the script (BIP67) and derives
+// addresses at /0/* and /1/* below each key.
+func parseCaravanConfig(data []byte) (*Descriptor, error) {
+	var config caravanConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid Caravan wallet config: %w", err)
+	}
+	if !strings.EqualFold(config.AddressType, "P2WSH") {
+		return nil, fmt.Errorf("only P2WSH multisig is supported, got %s", config.AddressType)
+	}
+	if config.Quorum.TotalSigners != len(config.ExtendedPublicKeys) {
+		return nil, fmt.Errorf("quorum is %d of %d but the config lists %d keys",
+			config.Quorum.RequiredSigners, config.Quorum.TotalSigners, len(config.ExtendedPublicKeys))
+	}
+
+	var keys []string
+	for i, key := range config.ExtendedPublicKeys {
+		// Caravan writes "Unknown" for keys added without their origin
+		path := strings.TrimPrefix(strings.TrimPrefix(key.BIP32Path, "m"), "/")
+		if len(key.XFP) != 8 || strings.EqualFold(key.BIP32Path, "unknown") {
+			return nil, fmt.Errorf("key %d (%s) has no fingerprint and path, which devices need to recognize it", i+1, key.Name)
+		}
+		origin := strings.ToLower(key.XFP)
+		if path != "" {
+			origin += "/" + path
+		}
+		keys = append(keys, fmt.Sprintf("[%s]%s/<0;1>/*", origin, key.XPub))
+	}
+	return Parse(fmt.Sprintf("wsh(sortedmulti(%d,%s))", config.Quorum.RequiredSigners, strings.Join(keys, ",")))
+}
+
+// ledgerPolicy is a wallet policy as registered with Ledger's Bitcoin app
+type ledgerPolicy struct {
+	Name               string   `json:"name"`
+	DescriptorTemplate string   `json:"descriptor_template"`
+	KeysInfo           []string `json:"keys_info"`
+}
+
+var policyKeyPlaceholder = regexp.MustCompile(`@(\d+)`)
+
+// parseLedgerPolicy expands a wallet policy into a descriptor. @i/** in the
+// template stands for key i followed by /<0;1>/*.
+func parseLedgerPolicy(data []byte) (*Descriptor, error) {
+	var policy ledgerPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid wallet policy: %w", err)
+	}
+	if policy.DescriptorTemplate == "" || len(policy.KeysInfo) == 0 {
+		return nil, errors.New("wallet policy has no descriptor_template or keys_info")
+	}
+
+	var missing error
+	template := strings.ReplaceAll(policy.DescriptorTemplate, "/**", "/<0;1>/*")
+	descriptor := policyKeyPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		i, _ := strconv.Atoi(placeholder[1:])
+		if i >= len(policy.KeysInfo) {
+			missing = fmt.Errorf("wallet policy uses %s but has %d keys", placeholder, len(policy.KeysInfo))
+			return placeholder
+		}
+		return policy.KeysInfo[i]
+	})
+	if missing != nil {
+		return nil, missing
+	}
+	return Parse(descriptor)
+}
+
+// parseColdcardSetup turns a Coldcard multisig setup file into a
+// descriptor. The file has "Policy: M of N", "Format: P2WSH" and
+// "Derivation: m/..." lines, the last one applying to the
+// "FINGERPRINT: xpub" lines after it.
+func parseColdcardSetup(text string) (*Descriptor, error) {
+	threshold, total := 0, 0
+	derivation := ""
+	var keys []string
+
+	scanner := bufio.NewScanner(bytes.NewBufferString(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("unexpected line %q in Coldcard setup file", line)
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "name":
+		case "policy":
+			if _, err := fmt.Sscanf(value, "%d of %d", &threshold, &total); err != nil {
+				return nil, fmt.Errorf("invalid policy %q", value)
+			}
+		case "format":
+			if !strings.EqualFold(value, "P2WSH") {
+				return nil, fmt.Errorf("only P2WSH multisig is supported, got %s", value)
+			}
+		case "derivation":
+			derivation = strings.TrimPrefix(strings.TrimPrefix(value, "m"), "/")
+		default:
+			if derivation == "" {
+				return nil, fmt.Errorf("key %s comes before any Derivation line", name)
+			}
+			fingerprint := strings.ToLower(strings.TrimSpace(name))
+			keys = append(keys, fmt.Sprintf("[%s/%s]%s/<0;1>/*", fingerprint, derivation, value))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if threshold == 0 {
+		return nil, errors.New("Coldcard setup file has no Policy line")
+	}
+	if total != len(keys) {
+		return nil, fmt.Errorf("policy is %d of %d but the file lists %d keys", threshold, total, len(keys))
+	}
+	// Coldcard always sorts the keys of the script
+	return Parse(fmt.Sprintf("wsh(sortedmulti(%d,%s))", threshold, strings.Join(keys, ",")))
+}
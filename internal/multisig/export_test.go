@@ -0,0 +1,84 @@
+package multisig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseExport(t *testing.T) {
+	ours, err := Parse(testDescriptor("sortedmulti", testKey1, testKey2, testKey3))
+	testutils.AssertNoError(t, err)
+
+	coldcard := `# Coldcard Multisig setup file (created by Sparrow)
+#
+Name: Vault
+Policy: 2 of 3
+Derivation: m/48h/0h/0h/2h
+Format: P2WSH
+
+00000001: ` + testKey1 + `
+00000002: ` + testKey2 + `
+00000003: ` + testKey3 + `
+`
+	ledger := `{"name": "Vault", "descriptor_template": "wsh(sortedmulti(2,@0/**,@1/**,@2/**))", "keys_info": [
+		"[00000003/48'/0'/0'/2']` + testKey3 + `",
+		"[00000001/48'/0'/0'/2']` + testKey1 + `",
+		"[00000002/48'/0'/0'/2']` + testKey2 + `"]}`
+
+	caravan := `{"name": "Vault", "addressType": "P2WSH", "network": "mainnet", "client": {"type": "public"},
+		"quorum": {"requiredSigners": 2, "totalSigners": 3}, "startingAddressIndex": 0, "extendedPublicKeys": [
+		{"name": "Coldcard", "bip32Path": "m/48'/0'/0'/2'", "xpub": "` + testKey1 + `", "xfp": "00000001"},
+		{"name": "Ledger", "bip32Path": "m/48'/0'/0'/2'", "xpub": "` + testKey2 + `", "xfp": "00000002"},
+		{"name": "Trezor", "bip32Path": "m/48'/0'/0'/2'", "xpub": "` + testKey3 + `", "xfp": "00000003"}]}`
+	receive, err := Parse(strings.ReplaceAll(testDescriptor("sortedmulti", testKey1, testKey2, testKey3), "<0;1>", "0"))
+	testutils.AssertNoError(t, err)
+	receiveChains, err := receive.ChainDescriptors()
+	testutils.AssertNoError(t, err)
+	sparrow := "# Receive and change descriptor (BIP389):\n" + testDescriptor("sortedmulti", testKey1, testKey2, testKey3) +
+		"\n\n# Receive descriptor (Bitcoin Core):\n" + receiveChains[0] + "\n"
+
+	for _, tc := range []struct{ export, format string }{
+		{coldcard, FormatColdcard},
+		{ledger, FormatLedgerPolicy},
+		{caravan, FormatCaravan},
+		{sparrow, FormatDescriptor},
+		{"wsh(sortedmulti(2,[00000002/48'/0'/0'/2']" + testKey2 + "/<0;1>/*,[00000001/48h/0h/0h/2h]" + testKey1 +
+			"/<0;1>/*,[00000003/48'/0'/0'/2']" + testKey3 + "/<0;1>/*))", FormatDescriptor},
+	} {
+		theirs, format, err := ParseExport([]byte(tc.export))
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, format, tc.format)
+
+		differences, err := ours.Differences(theirs, 7, true)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, strings.Join(differences, "; "), "")
+	}
+
+	// A device that registered another path for a key, and lost another one
+	other := strings.Replace(coldcard, "Policy: 2 of 3", "Policy: 2 of 2", 1)
+	other = strings.Replace(other, "00000003: "+testKey3, "", 1)
+	other = strings.Replace(other, "00000002: ", "Derivation: m/48h/0h/1h/2h\n00000002: ", 1)
+	theirs, _, err := ParseExport([]byte(other))
+	testutils.AssertNoError(t, err)
+	differences, err := ours.Differences(theirs, 0, false)
+	testutils.AssertNoError(t, err)
+	joined := strings.Join(differences, "; ")
+	testutils.AssertEqual(t, strings.Contains(joined, "policy is 2 of 2, want 2 of 3"), true)
+	testutils.AssertEqual(t, strings.Contains(joined, "key 2 has origin [00000002/48'/0'/1'/2']"), true)
+	testutils.AssertEqual(t, strings.Contains(joined, "key 3 [00000003] is missing"), true)
+	testutils.AssertEqual(t, strings.Contains(joined, "address is"), true)
+
+	for _, tc := range []struct{ export, want string }{
+		{strings.Replace(coldcard, "P2WSH", "P2SH-P2WSH", 1), "only P2WSH"},
+		{strings.Replace(coldcard, "Policy: 2 of 3", "Policy: 2 of 4", 1), "lists 3 keys"},
+		{`{"descriptor_template": "wsh(sortedmulti(1,@0/**,@1/**))", "keys_info": ["[00000001/48'/0'/0'/2']` + testKey1 + `"]}`, "uses @1 but has 1 keys"},
+		{strings.Replace(caravan, `"P2WSH"`, `"P2SH"`, 1), "only P2WSH"},
+		{strings.Replace(caravan, `"bip32Path": "m/48'/0'/0'/2'", "xpub": "`+testKey2, `"bip32Path": "Unknown", "xpub": "`+testKey2, 1), "key 2 (Ledger) has no fingerprint and path"},
+		{strings.Replace(caravan, `"totalSigners": 3`, `"totalSigners": 4`, 1), "lists 3 keys"},
+	} {
+		_, _, err := ParseExport([]byte(tc.export))
+		testutils.AssertError(t, err, tc.want)
+	}
+}
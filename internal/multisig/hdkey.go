@@ -0,0 +1,174 @@
+package multisig
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// hardenedOffset is the first hardened BIP32 child index
+const hardenedOffset = 1 << 31
+
+// secp256k1 parameters. Only public derivation is needed, so the affine
+// arithmetic below favours being easy to check over being fast.
+var (
+	curveP  = hexInt("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f")
+	curveN  = hexInt("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+	curveGx = hexInt("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	curveGy = hexInt("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8")
+)
+
+func hexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid curve constant " + s)
+	}
+	return n
+}
+
+// point is a point on the curve; the zero value is the point at infinity
+type point struct {
+	x, y *big.Int
+}
+
+func (p point) infinity() bool {
+	return p.x == nil
+}
+
+func addPoints(a, b point) point {
+	if a.infinity() {
+		return b
+	}
+	if b.infinity() {
+		return a
+	}
+
+	var num, den *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		if a.y.Cmp(b.y) != 0 || a.y.Sign() == 0 {
+			return point{}
+		}
+		// Doubling: the slope of the tangent is 3x² / 2y
+		num = new(big.Int).Mul(a.x, a.x)
+		num.Mul(num, big.NewInt(3))
+		den = new(big.Int).Lsh(a.y, 1)
+	} else {
+		num = new(big.Int).Sub(b.y, a.y)
+		den = new(big.Int).Sub(b.x, a.x)
+	}
+	den.Mod(den, curveP)
+	slope := num.Mul(num, den.ModInverse(den, curveP))
+	slope.Mod(slope, curveP)
+
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, a.x)
+	x.Sub(x, b.x)
+	x.Mod(x, curveP)
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, slope)
+	y.Sub(y, a.y)
+	y.Mod(y, curveP)
+	return point{x, y}
+}
+
+// multiplyBase returns k·G
+func multiplyBase(k *big.Int) point {
+	var result point
+	addend := point{curveGx, curveGy}
+	for i := 0; i < k.BitLen(); i++ {
+		if k.Bit(i) == 1 {
+			result = addPoints(result, addend)
+		}
+		addend = addPoints(addend, addend)
+	}
+	return result
+}
+
+// parsePubKey decodes a 33 byte compressed public key
+func parsePubKey(b []byte) (point, error) {
+	if len(b) != 33 || (b[0] != 2 && b[0] != 3) {
+		return point{}, errors.New("not a compressed public key")
+	}
+	x := new(big.Int).SetBytes(b[1:])
+	if x.Cmp(curveP) >= 0 {
+		return point{}, errors.New("public key is not on the curve")
+	}
+
+	// y² = x³ + 7
+	ySquared := new(big.Int).Exp(x, big.NewInt(3), curveP)
+	ySquared.Add(ySquared, big.NewInt(7))
+	ySquared.Mod(ySquared, curveP)
+	y := new(big.Int).ModSqrt(ySquared, curveP)
+	if y == nil {
+		return point{}, errors.New("public key is not on the curve")
+	}
+	if y.Bit(0) != uint(b[0]&1) {
+		y.Sub(curveP, y)
+	}
+	return point{x, y}, nil
+}
+
+// compressed encodes p as a 33 byte compressed public key
+func (p point) compressed() []byte {
+	b := make([]byte, 33)
+	b[0] = 2 + byte(p.y.Bit(0))
+	p.x.FillBytes(b[1:])
+	return b
+}
+
+// ExtendedKey is a BIP32 extended public key
+type ExtendedKey struct {
+	PubKey    []byte // compressed
+	ChainCode []byte
+	Testnet   bool
+}
+
+// ParseExtendedKey decodes an xpub or tpub, or a SLIP-132 variant of one
+func ParseExtendedKey(key string) (*ExtendedKey, error) {
+	payload, err := utils.DecodeXPub(key)
+	if err != nil {
+		return nil, err
+	}
+	extended := &ExtendedKey{
+		ChainCode: payload[13:45],
+		PubKey:    payload[45:78],
+		Testnet:   utils.IsTestnetXPub(payload),
+	}
+	if _, err := parsePubKey(extended.PubKey); err != nil {
+		return nil, err
+	}
+	return extended, nil
+}
+
+// Child derives the non-hardened child key at index (BIP32 CKDpub)
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if index >= hardenedOffset {
+		return nil, fmt.Errorf("hardened child %d needs the private key", index-hardenedOffset)
+	}
+	parent, err := parsePubKey(k.PubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(k.PubKey)
+	mac.Write(binary.BigEndian.AppendUint32(nil, index))
+	sum := mac.Sum(nil)
+
+	// Both failure cases have a probability below 2^-127 and BIP32 says to
+	// move on to the next index
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(curveN) >= 0 {
+		return nil, fmt.Errorf("child %d is invalid, use the next index", index)
+	}
+	child := addPoints(multiplyBase(tweak), parent)
+	if child.infinity() {
+		return nil, fmt.Errorf("child %d is invalid, use the next index", index)
+	}
+	return &ExtendedKey{PubKey: child.compressed(), ChainCode: sum[32:], Testnet: k.Testnet}, nil
+}
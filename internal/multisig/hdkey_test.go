@@ -0,0 +1,132 @@
+package multisig
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestChildMatchesBIP32Vector(t *testing.T) {
+	// Test vector 1: m/0H and m/0H/1
+	parent, err := ParseExtendedKey("xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, hex.EncodeToString(parent.PubKey), "035a784662a4a20a65bf6aab9ae98a6c068a81c52e4b032c0fb5400c706cfccc56")
+	testutils.AssertEqual(t, parent.Testnet, false)
+
+	child, err := parent.Child(1)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, hex.EncodeToString(child.PubKey), "03501e454bf00751f24b1b489aa925215d66af2234e3891c3b21a52bedb3cd711c")
+
+	_, err = parent.Child(hardenedOffset)
+	testutils.AssertError(t, err, "needs the private key")
+}
+
+// bip32Step is a key of a BIP32 test vector chain
+type bip32Step struct {
+	index uint32
+	xpub  string
+}
+
+// bip32Vectors are the extended public keys of BIP32 test vectors 1 to 3,
+// each chain listed from the master key down. The index is the one that
+// derives the key from the previous one.
+var bip32Vectors = []struct {
+	name  string
+	chain []bip32Step
+}{
+	{"vector 1", []bip32Step{
+		{0, "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"},
+		{hardenedOffset, "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw"},
+		{1, "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ"},
+		{hardenedOffset + 2, "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5"},
+		{2, "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV"},
+		{1000000000, "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy"},
+	}},
+	{"vector 2", []bip32Step{
+		{0, "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB"},
+		{0, "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH"},
+		{hardenedOffset + 2147483647, "xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a"},
+		{1, "xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon"},
+		{hardenedOffset + 2147483646, "xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL"},
+		{2, "xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt"},
+	}},
+	{"vector 3", []bip32Step{
+		{0, "xpub661MyMwAqRbcEZVB4dScxMAdx6d4nFc9nvyvH3v4gJL378CSRZiYmhRoP7mBy6gSPSCYk6SzXPTf3ND1cZAceL7SfJ1Z3GC8vBgp2epUt13"},
+		{hardenedOffset, "xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y"},
+	}},
+}
+
+// TestChildMatchesBIP32Vectors derives every non-hardened step of the BIP32
+// test vectors from the parent xpub. Hardened steps need the private key, so
+// they are only checked to be refused.
+func TestChildMatchesBIP32Vectors(t *testing.T) {
+	for _, vector := range bip32Vectors {
+		t.Run(vector.name, func(t *testing.T) {
+			parent, err := ParseExtendedKey(vector.chain[0].xpub)
+			testutils.AssertNoError(t, err)
+
+			for _, step := range vector.chain[1:] {
+				want, err := ParseExtendedKey(step.xpub)
+				testutils.AssertNoError(t, err)
+
+				child, err := parent.Child(step.index)
+				if step.index >= hardenedOffset {
+					testutils.AssertError(t, err, "needs the private key")
+				} else {
+					testutils.AssertNoError(t, err)
+					testutils.AssertEqual(t, hex.EncodeToString(child.PubKey), hex.EncodeToString(want.PubKey))
+					testutils.AssertEqual(t, hex.EncodeToString(child.ChainCode), hex.EncodeToString(want.ChainCode))
+				}
+				parent = want
+			}
+		})
+	}
+}
+
+func TestParseExtendedKeyRejectsInvalidKeys(t *testing.T) {
+	// Variants of the vector 1 master key: its public key replaced as in BIP32
+	// test vector 5, the xprv instead of the xpub, and a corrupted checksum
+	for name, tc := range map[string]struct{ key, want string }{
+		"uncompressed prefix 04": {"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ45ycVBsADt89FVXeDkYqbSeZmpjjnJETkyyiMwXokWPisrtUjm", "not a compressed public key"},
+		"prefix 01":              {"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gYxFk5nqmbwrSjnkQvUtYydeKpRyanfmc6qmeyusqpnVEF2j8DGn", "not a compressed public key"},
+		"x = 7, off the curve":   {"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gYym6yCVZtiQKSpLUqpuy2xafsZZR8vydJmD1kZ1yXu2Lp8uNH4N", "not on the curve"},
+		"x above the field":      {"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ1hr9Rwbk95YadvBkQXxzHBSngB8ndpW6QH7zhhsXZ2jHrohi8A", "not on the curve"},
+		"private key":            {"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi", "invalid extended public key"},
+		"bad checksum":           {"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet9", "invalid extended public key"},
+	} {
+		if _, err := ParseExtendedKey(tc.key); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected %q, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestPointArithmetic(t *testing.T) {
+	generator := point{curveGx, curveGy}
+	testutils.AssertEqual(t, multiplyBase(big.NewInt(1)).x.Cmp(curveGx), 0)
+	if !multiplyBase(curveN).infinity() {
+		t.Error("expected n·G to be the point at infinity")
+	}
+	negated := point{curveGx, new(big.Int).Sub(curveP, curveGy)}
+	if !addPoints(generator, negated).infinity() {
+		t.Error("expected G + -G to be the point at infinity")
+	}
+
+	// 2G by doubling and by the scalar multiple agree, and round trip
+	doubled := addPoints(generator, generator)
+	testutils.AssertEqual(t, hex.EncodeToString(doubled.compressed()), "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	testutils.AssertEqual(t, hex.EncodeToString(multiplyBase(big.NewInt(2)).compressed()), hex.EncodeToString(doubled.compressed()))
+	parsed, err := parsePubKey(doubled.compressed())
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, parsed.y.Cmp(doubled.y), 0)
+}
+
+func TestP2WSHAddress(t *testing.T) {
+	// BIP173: a witness script of <generator point> OP_CHECKSIG
+	script, err := hex.DecodeString("210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, p2wshAddress(script, false), "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3")
+	testutils.AssertEqual(t, p2wshAddress(script, true), "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7")
+}
@@ -0,0 +1,183 @@
+package multisig
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Errors SimulateSpend returns for amounts it cannot pay
+var (
+	// ErrInsufficientFunds means the wallet's coins cannot pay for the
+	// spend and its fee
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrBelowDust means the amount is too small for nodes to relay
+	ErrBelowDust = errors.New("amount is below the dust limit")
+)
+
+// Sizes in bytes of the parts of a transaction spending P2WSH coins
+const (
+	// txOverhead is the version and the lock time
+	txOverhead = 8
+	// segwitMarker is the marker and flag, which count as witness data
+	segwitMarker = 2
+	// inputBase is the outpoint, the empty script sig and the sequence
+	inputBase = 36 + 1 + 4
+	// signatureSize is the largest DER signature with a low S plus the
+	// sighash byte. Signers that grind for a low R make it a byte smaller.
+	signatureSize = 72
+	// p2wshScriptSize is OP_0 and the push of the script hash
+	p2wshScriptSize = 34
+)
+
+// Coin is an unspent output of the wallet
+type Coin struct {
+	TxID   string `json:"txid"`
+	Vout   uint32 `json:"vout"`
+	Amount int64  `json:"amount"`
+}
+
+// Spend is a simulated transaction paying Amount to Destination
+type Spend struct {
+	Destination string `json:"destination"`
+	Amount      int64  `json:"amount"`
+	Inputs      []Coin `json:"inputs"`
+	InputTotal  int64  `json:"input_total"`
+	// Change is 0 when what is left over is below the dust limit and goes
+	// to the miners instead
+	Change  int64   `json:"change"`
+	Fee     int64   `json:"fee"`
+	FeeRate float64 `json:"fee_rate"` // sat/vB
+	Weight  int     `json:"weight"`
+	VSize   int     `json:"vsize"`
+	// InputVSize is what each multisig input adds to the transaction
+	InputVSize float64 `json:"input_vsize"`
+}
+
+// SimulateSpend selects coins to pay amount sats to address at feeRate
+// sat/vB and works out the size and fee of the transaction, without building
+// or signing it. Coins are taken largest first, leaving out those worth less
+// than the fee of spending them. Signatures are counted at their largest, so
+// the real transaction is at most a few vbytes smaller.
+func (d *Descriptor) SimulateSpend(coins []Coin, address string, amount int64, feeRate float64) (*Spend, error) {
+	to, err := parseDestination(address)
+	if err != nil {
+		return nil, err
+	}
+	if to.testnet != d.Cosigners[0].key.Testnet {
+		return nil, fmt.Errorf("%s is not on the same network as the wallet", address)
+	}
+	if limit := dustLimit(to.scriptSize, to.segwit); amount < limit {
+		return nil, fmt.Errorf("%w of %d sats for %s", ErrBelowDust, limit, address)
+	}
+	if feeRate <= 0 {
+		return nil, errors.New("fee rate must be positive")
+	}
+
+	candidates := append([]Coin(nil), coins...)
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Amount != candidates[j].Amount {
+			return candidates[i].Amount > candidates[j].Amount
+		}
+		if candidates[i].TxID != candidates[j].TxID {
+			return candidates[i].TxID < candidates[j].TxID
+		}
+		return candidates[i].Vout < candidates[j].Vout
+	})
+
+	inputWeight := d.inputWeight()
+	inputFee := fee(inputWeight, feeRate)
+	payment := []int{to.scriptSize}
+	withChange := []int{to.scriptSize, p2wshScriptSize}
+	changeDust := dustLimit(p2wshScriptSize, true)
+
+	spend := &Spend{
+		Destination: address,
+		Amount:      amount,
+		FeeRate:     feeRate,
+		InputVSize:  float64(inputWeight) / 4,
+	}
+	for _, coin := range candidates {
+		if coin.Amount <= inputFee {
+			break
+		}
+		spend.Inputs = append(spend.Inputs, coin)
+		spend.InputTotal += coin.Amount
+
+		weight := txWeight(len(spend.Inputs), inputWeight, payment)
+		if spend.InputTotal < amount+fee(weight, feeRate) {
+			continue
+		}
+		changeWeight := txWeight(len(spend.Inputs), inputWeight, withChange)
+		if change := spend.InputTotal - amount - fee(changeWeight, feeRate); change >= changeDust {
+			weight = changeWeight
+			spend.Change = change
+		}
+		spend.Weight = weight
+		spend.VSize = vsize(weight)
+		spend.Fee = spend.InputTotal - amount - spend.Change
+		return spend, nil
+	}
+
+	needed := amount + fee(txWeight(len(spend.Inputs), inputWeight, payment), feeRate)
+	return nil, fmt.Errorf("%w: %d coins worth spending hold %d sats, the spend needs %d", ErrInsufficientFunds,
+		len(spend.Inputs), spend.InputTotal, needed)
+}
+
+// inputWeight returns the weight of one input of d: its outpoint and
+// sequence, and a witness of the empty item OP_CHECKMULTISIG pops by
+// mistake, Threshold signatures and the witness script
+func (d *Descriptor) inputWeight() int {
+	n := len(d.Cosigners)
+	script := len(pushNumber(d.Threshold)) + n*34 + len(pushNumber(n)) + 1
+	witness := varintSize(d.Threshold+2) + 1 + d.Threshold*(1+signatureSize) + varintSize(script) + script
+	return 4*inputBase + witness
+}
+
+// txWeight returns the weight of a transaction with inputs P2WSH inputs of
+// inputWeight each and outputs with the given script sizes
+func txWeight(inputs, inputWeight int, outputScripts []int) int {
+	base := txOverhead + varintSize(inputs) + varintSize(len(outputScripts))
+	for _, script := range outputScripts {
+		base += outputSize(script)
+	}
+	return 4*base + segwitMarker + inputs*inputWeight
+}
+
+// outputSize returns the size of an output: amount and script
+func outputSize(script int) int {
+	return 8 + varintSize(script) + script
+}
+
+// dustLimit is the smallest output Bitcoin Core relays at its default dust
+// relay fee of 3 sat/vB, which is what the output and an input spending it
+// would cost
+func dustLimit(script int, segwit bool) int64 {
+	spend := 148
+	if segwit {
+		spend = 67
+	}
+	return int64(3 * (outputSize(script) + spend))
+}
+
+func vsize(weight int) int {
+	return (weight + 3) / 4
+}
+
+// fee returns the fee of weight at feeRate sat/vB, rounded up
+func fee(weight int, feeRate float64) int64 {
+	return int64(math.Ceil(float64(vsize(weight)) * feeRate))
+}
+
+// varintSize returns the size of n as a Bitcoin compact size
+func varintSize(n int) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	default:
+		return 5
+	}
+}
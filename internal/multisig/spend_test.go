@@ -0,0 +1,92 @@
+package multisig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+// testDestination is the BIP173 P2WPKH test vector
+const testDestination = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+
+func TestParseDestination(t *testing.T) {
+	for _, tc := range []struct {
+		address string
+		script  int
+		segwit  bool
+		testnet bool
+	}{
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", 25, false, false},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", 23, false, false},
+		{testDestination, 22, true, false},
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", 22, true, false},
+		{"bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3", 34, true, false},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", 34, true, true},
+		{"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", 34, true, false},
+	} {
+		to, err := parseDestination(tc.address)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, to, destination{tc.script, tc.segwit, tc.testnet})
+	}
+
+	_, err := parseDestination("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5")
+	testutils.AssertError(t, err, "invalid checksum")
+	_, err = parseDestination("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3")
+	testutils.AssertError(t, err, "invalid address")
+}
+
+func TestSpendWeights(t *testing.T) {
+	d, err := Parse(testDescriptor("sortedmulti", testKey1, testKey2, testKey3))
+	testutils.AssertNoError(t, err)
+
+	// 41 bytes outside the witness, then 4 items: the empty one, two
+	// signatures and the 105 byte script
+	testutils.AssertEqual(t, d.inputWeight(), 4*41+1+1+2*73+1+105)
+	// A 2 of 3 input is the well known 104.5 vbytes
+	testutils.AssertEqual(t, float64(d.inputWeight())/4, 104.5)
+	// One input, a P2WPKH payment and P2WSH change
+	testutils.AssertEqual(t, vsize(txWeight(1, d.inputWeight(), []int{22, 34})), 189)
+	testutils.AssertEqual(t, dustLimit(34, true), int64(330))
+	testutils.AssertEqual(t, dustLimit(25, false), int64(546))
+}
+
+func TestSimulateSpend(t *testing.T) {
+	d, err := Parse(testDescriptor("sortedmulti", testKey1, testKey2, testKey3))
+	testutils.AssertNoError(t, err)
+	coins := []Coin{
+		{TxID: "aa", Vout: 0, Amount: 50000},
+		{TxID: "bb", Vout: 1, Amount: 200}, // costs more to spend than it holds
+		{TxID: "cc", Vout: 0, Amount: 100000},
+		{TxID: "dd", Vout: 2, Amount: 20000},
+	}
+
+	// The two largest coins, a payment and change: 293.5 vbytes
+	spend, err := d.SimulateSpend(coins, testDestination, 120000, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(spend.Inputs), 2)
+	testutils.AssertEqual(t, spend.Inputs[0].TxID, "cc")
+	testutils.AssertEqual(t, spend.Inputs[1].TxID, "aa")
+	testutils.AssertEqual(t, spend.InputTotal, int64(150000))
+	testutils.AssertEqual(t, spend.VSize, 294)
+	testutils.AssertEqual(t, spend.Fee, int64(2940))
+	testutils.AssertEqual(t, spend.Change, int64(27060))
+	testutils.AssertEqual(t, spend.InputVSize, 104.5)
+
+	// Leftovers below the dust limit go to the fee instead of a change output
+	spend, err = d.SimulateSpend(coins, testDestination, 98030, 10)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(spend.Inputs), 1)
+	testutils.AssertEqual(t, spend.VSize, 146)
+	testutils.AssertEqual(t, spend.Change, int64(0))
+	testutils.AssertEqual(t, spend.Fee, int64(1970))
+
+	_, err = d.SimulateSpend(coins, testDestination, 1000000, 10)
+	testutils.AssertEqual(t, errors.Is(err, ErrInsufficientFunds), true)
+	testutils.AssertError(t, err, "3 coins worth spending hold 170000 sats")
+
+	_, err = d.SimulateSpend(coins, "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", 10000, 10)
+	testutils.AssertError(t, err, "same network")
+	_, err = d.SimulateSpend(coins, testDestination, 100, 10)
+	testutils.AssertError(t, err, "dust limit of 294 sats")
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// telegramAPI is the Telegram Bot API base URL
+const telegramAPI = "https://api.telegram.org"
+
+// Telegram sends HTML messages to one chat through a bot
+type Telegram struct {
+	BotToken string
+	ChatID   string
+}
+
+// telegramMessage is the sendMessage request body
+type telegramMessage struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Enabled reports whether both the bot token and chat ID are set
+func (t Telegram) Enabled() bool {
+	return t.BotToken != "" && t.ChatID != ""
+}
+
+// Send posts message to the chat
+func (t Telegram) Send(message string) error {
+	jsonData, err := json.Marshal(telegramMessage{
+		ChatID:    t.ChatID,
+		Text:      message,
+		ParseMode: "HTML",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram message: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", telegramAPI, t.BotToken)
+	resp, err := httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to send telegram message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Package performance measures portfolio returns with deposits and
+// withdrawals stripped out, so adding sats is not mistaken for growth.
+package performance
+
+import (
+	"sort"
+	"time"
+)
+
+// ValuePoint is the portfolio value in sats at a point in time
+type ValuePoint struct {
+	Time  time.Time
+	Value int64
+}
+
+// Flow is sats moved into (positive) or out of (negative) the portfolio
+type Flow struct {
+	Time   time.Time
+	Amount int64
+}
+
+// Result summarizes performance between the first and last value points.
+//
+// TWR is the time-weighted return: the value change in each interval
+// between points, less that interval's flows, chained together. It ignores
+// how much was invested when, so it measures the holdings' performance.
+// ROI is the gain over the starting value plus deposits, which weights
+// returns by the sats at stake.
+type Result struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	StartValue  int64     `json:"start_value"`
+	EndValue    int64     `json:"end_value"`
+	Deposits    int64     `json:"deposits"`
+	Withdrawals int64     `json:"withdrawals"`
+	NetFlows    int64     `json:"net_flows"`
+	Gain        int64     `json:"gain"`
+	TWR         float64   `json:"twr"`
+	ROI         float64   `json:"roi"`
+}
+
+// Calculate computes the performance of points, which need not be sorted.
+// Flows are assumed to land at the end of the interval they fall in, i.e.
+// they are included in the next point's value. Flows outside the points'
+// span are ignored. Intervals starting from an empty portfolio have no
+// return and are skipped.
+func Calculate(points []ValuePoint, flows []Flow) Result {
+	if len(points) == 0 {
+		return Result{}
+	}
+
+	points = append([]ValuePoint(nil), points...)
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	flows = append([]Flow(nil), flows...)
+	sort.Slice(flows, func(i, j int) bool { return flows[i].Time.Before(flows[j].Time) })
+
+	first, last := points[0], points[len(points)-1]
+	result := Result{From: first.Time, To: last.Time, StartValue: first.Value, EndValue: last.Value}
+
+	growth := 1.0
+	next := 0
+	// Skip flows before the first point
+	for next < len(flows) && !flows[next].Time.After(first.Time) {
+		next++
+	}
+
+	for i := 1; i < len(points); i++ {
+		var intervalFlows int64
+		for next < len(flows) && !flows[next].Time.After(points[i].Time) {
+			amount := flows[next].Amount
+			intervalFlows += amount
+			if amount > 0 {
+				result.Deposits += amount
+			} else {
+				result.Withdrawals -= amount
+			}
+			next++
+		}
+
+		if start := points[i-1].Value; start > 0 {
+			growth *= float64(points[i].Value-intervalFlows) / float64(start)
+		}
+	}
+
+	result.NetFlows = result.Deposits - result.Withdrawals
+	result.Gain = result.EndValue - result.StartValue - result.NetFlows
+	result.TWR = growth - 1
+	if invested := result.StartValue + result.Deposits; invested > 0 {
+		result.ROI = float64(result.Gain) / float64(invested)
+	}
+	return result
+}
+
+// Since returns the points from from onwards, starting with the value held at
+// from: the last earlier point's value carried forward to from. points must
+// be sorted oldest first.
+func Since(points []ValuePoint, from time.Time) []ValuePoint {
+	start := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(from) })
+	if start == 0 || (start < len(points) && points[start].Time.Equal(from)) {
+		return points[start:]
+	}
+
+	since := make([]ValuePoint, 0, len(points)-start+1)
+	since = append(since, ValuePoint{Time: from, Value: points[start-1].Value})
+	return append(since, points[start:]...)
+}
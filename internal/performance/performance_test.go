@@ -0,0 +1,67 @@
+package performance
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestCalculate(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, 1+n, 0, 0, 0, 0, time.UTC) }
+
+	// 1M grows 10%, then a 1M deposit, then the 2.1M grows 10%
+	points := []ValuePoint{
+		{day(2), 2310000},
+		{day(0), 1000000},
+		{day(1), 2100000},
+	}
+	flows := []Flow{
+		{day(1), 1000000},
+		{day(-5), 500000}, // Before the first point
+	}
+
+	result := Calculate(points, flows)
+	testutils.AssertEqual(t, result.StartValue, int64(1000000))
+	testutils.AssertEqual(t, result.EndValue, int64(2310000))
+	testutils.AssertEqual(t, result.Deposits, int64(1000000))
+	testutils.AssertEqual(t, result.NetFlows, int64(1000000))
+	testutils.AssertEqual(t, result.Gain, int64(310000))
+	if math.Abs(result.TWR-0.21) > 1e-9 {
+		t.Errorf("TWR = %v, want 0.21", result.TWR)
+	}
+	if math.Abs(result.ROI-0.155) > 1e-9 {
+		t.Errorf("ROI = %v, want 0.155", result.ROI)
+	}
+}
+
+func TestCalculateStacking(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, 1+n, 0, 0, 0, 0, time.UTC) }
+
+	// Growth made entirely of deposits and a withdrawal is no return
+	points := []ValuePoint{{day(0), 0}, {day(1), 500000}, {day(2), 1500000}, {day(3), 1200000}}
+	flows := []Flow{{day(1), 500000}, {day(2), 1000000}, {day(3), -300000}}
+
+	result := Calculate(points, flows)
+	testutils.AssertEqual(t, result.Withdrawals, int64(300000))
+	testutils.AssertEqual(t, result.Gain, int64(0))
+	testutils.AssertEqual(t, result.TWR, 0.0)
+	testutils.AssertEqual(t, result.ROI, 0.0)
+
+	testutils.AssertEqual(t, Calculate(nil, flows).TWR, 0.0)
+}
+
+func TestSince(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, 1+n, 0, 0, 0, 0, time.UTC) }
+	points := []ValuePoint{{day(0), 100}, {day(2), 200}, {day(4), 300}}
+
+	since := Since(points, day(3))
+	testutils.AssertEqual(t, len(since), 2)
+	testutils.AssertEqual(t, since[0].Time, day(3))
+	testutils.AssertEqual(t, since[0].Value, int64(200))
+
+	testutils.AssertEqual(t, len(Since(points, day(-1))), 3)
+	testutils.AssertEqual(t, len(Since(points, day(2))), 2)
+	testutils.AssertEqual(t, len(Since(points, day(5))), 1)
+}
@@ -0,0 +1,75 @@
+package price
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+)
+
+// DefaultCoinbaseURL is Coinbase's public API
+const DefaultCoinbaseURL = "https://api.coinbase.com"
+
+// Coinbase fetches BTC prices from Coinbase's exchange rates. Its rates
+// carry no publication time, so they are dated when fetched.
+type Coinbase struct {
+	baseURL    string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewCoinbase returns a Coinbase source using the API at baseURL
+func NewCoinbase(baseURL string) *Coinbase {
+	if baseURL == "" {
+		baseURL = DefaultCoinbaseURL
+	}
+	return &Coinbase{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		now:        time.Now,
+	}
+}
+
+// GetPrices gets the current BTC price in each currency Coinbase quotes
+func (c *Coinbase) GetPrices() (*mempool.Prices, error) {
+	resp, err := c.httpClient.Get(c.baseURL + "/v2/exchange-rates?currency=BTC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Coinbase prices: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Coinbase API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	// The response is {"data": {"currency": "BTC", "rates": {"USD": "<price>", ...}}}
+	var raw struct {
+		Data struct {
+			Rates map[string]string `json:"rates"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode Coinbase prices: %w", err)
+	}
+
+	prices := &mempool.Prices{
+		Time:   c.now().Truncate(time.Second),
+		Rates:  make(map[string]float64, len(raw.Data.Rates)),
+		Source: "coinbase",
+	}
+	for currency, rate := range raw.Data.Rates {
+		if value, err := strconv.ParseFloat(rate, 64); err == nil {
+			prices.Rates[currency] = value
+		}
+	}
+	if len(prices.Rates) == 0 {
+		return nil, fmt.Errorf("Coinbase returned no prices")
+	}
+	return prices, nil
+}
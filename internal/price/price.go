@@ -0,0 +1,263 @@
+// Package price provides the BTC exchange rate for fiat conversions, cached
+// so API requests do not each hit the upstream price sources. Fetched prices
+// can be stored to value past amounts at the price of their time.
+package price
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+)
+
+// DefaultCacheTTL is how long fetched prices are reused before refreshing
+const DefaultCacheTTL = 5 * time.Minute
+
+// Source fetches current BTC prices; *mempool.Client and *Coinbase satisfy it
+type Source interface {
+	GetPrices() (*mempool.Prices, error)
+}
+
+// Fallback is a Source asking each of its sources in turn, returning the
+// prices of the first that answers
+type Fallback []Source
+
+// GetPrices returns the prices of the first source that has them
+func (f Fallback) GetPrices() (*mempool.Prices, error) {
+	var errs []error
+	for _, source := range f {
+		prices, err := source.GetPrices()
+		if err == nil {
+			return prices, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no price source configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Store keeps fetched prices; *db.Database satisfies it
+type Store interface {
+	InsertBTCPrices(prices []db.BTCPrice) (int64, error)
+	GetBTCPriceAt(currency string, date time.Time) (*db.BTCPrice, error)
+	GetBTCPriceHistory(currency string, from, to time.Time) ([]db.BTCPrice, error)
+}
+
+// Quote is the price of one BTC in Currency
+type Quote struct {
+	Currency string    `json:"currency"`
+	Price    float64   `json:"price"`
+	Time     time.Time `json:"time"`
+}
+
+// CentsToSats converts an amount in hundredths of Currency, e.g. US cents,
+// to sats at this price, rounded to the nearest sat
+func (q Quote) CentsToSats(cents int64) int64 {
+	return int64(math.Round(float64(cents) / 100 / q.Price * 1e8))
+}
+
+// Service caches prices from a Source. When a refresh fails the last known
+// prices are served until one succeeds, so a flaky upstream does not break
+// conversions.
+type Service struct {
+	source Source
+	ttl    time.Duration
+	now    func() time.Time
+
+	// store keeps the prices of currencies on every fetch; nil disables
+	// recording and valuing at past prices
+	store      Store
+	currencies []string
+
+	mu        sync.Mutex
+	prices    *mempool.Prices
+	fetchedAt time.Time
+}
+
+// NewService creates a price service that refreshes from source every ttl
+func NewService(source Source, ttl time.Duration) *Service {
+	return &Service{source: source, ttl: ttl, now: time.Now}
+}
+
+// Record stores the fetched prices of currencies, e.g. "USD", in store from
+// now on, which BTCPriceAt and History then read
+func (s *Service) Record(store Store, currencies []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+	s.currencies = make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		s.currencies = append(s.currencies, strings.ToUpper(currency))
+	}
+}
+
+// Run fetches prices every interval, so they are recorded while no request
+// asks for them, until stop is closed
+func (s *Service) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Refresh(); err != nil {
+			log.Printf("price: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh fetches prices now, whether or not the cached ones have expired
+func (s *Service) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.fetch()
+	return err
+}
+
+// BTCPrice returns the price of one BTC in currency, e.g. "USD"
+func (s *Service) BTCPrice(currency string) (Quote, error) {
+	currency = strings.ToUpper(currency)
+
+	prices, err := s.current()
+	if err != nil {
+		return Quote{}, err
+	}
+
+	rate, ok := prices.Rates[currency]
+	if !ok || rate <= 0 {
+		return Quote{}, fmt.Errorf("no BTC price for currency %s", currency)
+	}
+	return Quote{Currency: currency, Price: rate, Time: prices.Time}, nil
+}
+
+// current returns the cached prices, refreshing them once the TTL has passed
+func (s *Service) current() (*mempool.Prices, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.prices != nil && s.now().Sub(s.fetchedAt) < s.ttl {
+		metrics.CacheLookup("btc_price", true)
+		return s.prices, nil
+	}
+	metrics.CacheLookup("btc_price", false)
+
+	prices, err := s.fetch()
+	if err != nil {
+		if s.prices != nil {
+			return s.prices, nil
+		}
+		return nil, err
+	}
+	return prices, nil
+}
+
+// fetch gets prices from the source, caches and records them. s.mu must be
+// held.
+func (s *Service) fetch() (*mempool.Prices, error) {
+	prices, err := s.source.GetPrices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch BTC price: %w", err)
+	}
+	s.prices = prices
+	s.fetchedAt = s.now()
+
+	if s.store != nil {
+		var records []db.BTCPrice
+		for _, currency := range s.currencies {
+			if rate, ok := prices.Rates[currency]; ok && rate > 0 {
+				records = append(records, db.BTCPrice{Timestamp: prices.Time, Currency: currency, Price: rate, Source: prices.Source})
+			}
+		}
+		// A failed write only costs history, the prices are still good
+		if _, err := s.store.InsertBTCPrices(records); err != nil {
+			log.Printf("price: failed to record prices: %v", err)
+		}
+	}
+	return prices, nil
+}
+
+// BTCPriceAt returns the last recorded price of currency at or before at.
+// Without one, e.g. before recording started, it returns the current price,
+// whose Time tells the two apart.
+func (s *Service) BTCPriceAt(currency string, at time.Time) (Quote, error) {
+	history, err := s.History(currency, at, at)
+	if err != nil {
+		return Quote{}, err
+	}
+	return history.At(at), nil
+}
+
+// History returns the prices of currency recorded between from and to, plus
+// the one in force at from, to value a series of amounts
+func (s *Service) History(currency string, from, to time.Time) (*History, error) {
+	current, err := s.BTCPrice(currency)
+	if err != nil {
+		return nil, err
+	}
+	history := &History{current: current}
+
+	s.mu.Lock()
+	store := s.store
+	s.mu.Unlock()
+	if store == nil {
+		return history, nil
+	}
+
+	first, err := store.GetBTCPriceAt(current.Currency, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recorded BTC price: %w", err)
+	}
+	if first != nil {
+		history.quotes = append(history.quotes, quoteOf(*first))
+	}
+	recorded, err := store.GetBTCPriceHistory(current.Currency, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recorded BTC prices: %w", err)
+	}
+	for _, price := range recorded {
+		history.quotes = append(history.quotes, quoteOf(price))
+	}
+	// The current price may not be recorded yet
+	if n := len(history.quotes); n > 0 && current.Time.After(history.quotes[n-1].Time) {
+		history.quotes = append(history.quotes, current)
+	}
+	return history, nil
+}
+
+// History values amounts at the price of their time
+type History struct {
+	// quotes are the recorded prices, oldest first
+	quotes  []Quote
+	current Quote
+}
+
+// At returns the last recorded price at or before t, or the current price
+// when none was recorded by then
+func (h *History) At(t time.Time) Quote {
+	i := sort.Search(len(h.quotes), func(i int) bool { return h.quotes[i].Time.After(t) })
+	if i == 0 {
+		return h.current
+	}
+	return h.quotes[i-1]
+}
+
+// Current is the price at the time the history was read
+func (h *History) Current() Quote {
+	return h.current
+}
+
+func quoteOf(price db.BTCPrice) Quote {
+	return Quote{Currency: price.Currency, Price: price.Price, Time: price.Timestamp}
+}
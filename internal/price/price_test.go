@@ -0,0 +1,132 @@
+package price
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+type fakeSource struct {
+	prices *mempool.Prices
+	err    error
+	calls  int
+}
+
+func (f *fakeSource) GetPrices() (*mempool.Prices, error) {
+	f.calls++
+	return f.prices, f.err
+}
+
+func TestBTCPrice(t *testing.T) {
+	published := time.Unix(1700000000, 0)
+	source := &fakeSource{prices: &mempool.Prices{Time: published, Rates: map[string]float64{"USD": 50000, "EUR": 45000}}}
+
+	now := time.Unix(1700000000, 0)
+	service := NewService(source, DefaultCacheTTL)
+	service.now = func() time.Time { return now }
+
+	quote, err := service.BTCPrice("usd")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, quote.Currency, "USD")
+	testutils.AssertEqual(t, quote.Price, 50000.0)
+	testutils.AssertEqual(t, quote.Time, published)
+
+	_, err = service.BTCPrice("XYZ")
+	testutils.AssertError(t, err, "no BTC price for currency XYZ")
+
+	// Cached until the TTL passes
+	_, _ = service.BTCPrice("EUR")
+	testutils.AssertEqual(t, source.calls, 1)
+
+	// A failed refresh falls back to the last known prices
+	now = now.Add(DefaultCacheTTL)
+	source.err = errors.New("upstream down")
+	quote, err = service.BTCPrice("EUR")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, quote.Price, 45000.0)
+	testutils.AssertEqual(t, source.calls, 2)
+}
+
+func TestBTCPriceUnavailable(t *testing.T) {
+	service := NewService(&fakeSource{err: errors.New("upstream down")}, DefaultCacheTTL)
+
+	_, err := service.BTCPrice("USD")
+	testutils.AssertError(t, err, "failed to fetch BTC price")
+}
+
+func TestCentsToSats(t *testing.T) {
+	quote := Quote{Currency: "USD", Price: 50000}
+	testutils.AssertEqual(t, quote.CentsToSats(100000), int64(2000000))
+	testutils.AssertEqual(t, quote.CentsToSats(1), int64(20))
+	testutils.AssertEqual(t, quote.CentsToSats(0), int64(0))
+}
+
+func TestFallback(t *testing.T) {
+	down := &fakeSource{err: errors.New("upstream down")}
+	up := &fakeSource{prices: &mempool.Prices{Rates: map[string]float64{"USD": 50000}, Source: "coinbase"}}
+
+	prices, err := Fallback{down, up}.GetPrices()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, prices.Source, "coinbase")
+	testutils.AssertEqual(t, down.calls, 1)
+
+	_, err = Fallback{down, down}.GetPrices()
+	testutils.AssertError(t, err, "upstream down")
+}
+
+func TestHistory(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &fakeSource{prices: &mempool.Prices{Time: start, Rates: map[string]float64{"USD": 42000, "EUR": 38000, "JPY": 6e6}, Source: "mempool"}}
+	service := NewService(source, DefaultCacheTTL)
+	service.Record(database, []string{"usd", "eur"})
+
+	// Each fetch records the prices of the chosen currencies
+	testutils.AssertNoError(t, service.Refresh())
+	source.prices = &mempool.Prices{Time: start.AddDate(0, 0, 1), Rates: map[string]float64{"USD": 44000, "EUR": 40000}, Source: "mempool"}
+	testutils.AssertNoError(t, service.Refresh())
+	price, err := database.GetBTCPriceAt("JPY", start.AddDate(0, 0, 1))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, price == nil, true)
+
+	history, err := service.History("USD", start.Add(time.Hour), start.AddDate(0, 0, 2))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, history.At(start.Add(12*time.Hour)).Price, 42000.0)
+	testutils.AssertEqual(t, history.At(start.AddDate(0, 0, 1)).Price, 44000.0)
+	// Before recording started the current price stands in
+	testutils.AssertEqual(t, history.At(start.Add(-time.Hour)).Time, history.Current().Time)
+
+	quote, err := service.BTCPriceAt("eur", start.Add(time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, quote.Price, 38000.0)
+	testutils.AssertEqual(t, quote.Time, start)
+}
+
+func TestCoinbase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutils.AssertEqual(t, r.URL.Path, "/v2/exchange-rates")
+		testutils.AssertEqual(t, r.URL.Query().Get("currency"), "BTC")
+		w.Write([]byte(`{"data": {"currency": "BTC", "rates": {"USD": "43210.5", "EUR": "39876.25", "XYZ": "n/a"}}}`))
+	}))
+	defer server.Close()
+
+	fetched := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	coinbase := NewCoinbase(server.URL)
+	coinbase.now = func() time.Time { return fetched }
+
+	prices, err := coinbase.GetPrices()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, prices.Source, "coinbase")
+	testutils.AssertEqual(t, prices.Time, fetched)
+	testutils.AssertEqual(t, prices.Rates["USD"], 43210.5)
+	testutils.AssertEqual(t, len(prices.Rates), 2)
+}
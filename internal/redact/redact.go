@@ -0,0 +1,54 @@
+// Package redact keeps balances and addresses out of logs. Journald and
+// stdout are often shipped off the node or pasted into bug reports, so by
+// default amounts are hidden and addresses shortened to enough characters to
+// tell them apart. SetVerbose restores full logging for debugging.
+package redact
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Hidden replaces redacted amounts
+const Hidden = "[redacted]"
+
+// FlagUsage describes the flag services use to turn on verbose logging
+const FlagUsage = "Log full addresses and amounts, which are redacted by default"
+
+var verbose atomic.Bool
+
+// SetVerbose turns full logging of addresses and amounts on or off
+func SetVerbose(on bool) {
+	verbose.Store(on)
+}
+
+// Verbose reports whether addresses and amounts are logged in full
+func Verbose() bool {
+	return verbose.Load()
+}
+
+// Amount returns a bare amount for a log line, or Hidden
+func Amount(amount int64) string {
+	if verbose.Load() {
+		return fmt.Sprintf("%d", amount)
+	}
+	return Hidden
+}
+
+// Sats returns an amount in satoshis for a log line, e.g. "1500000 sats",
+// or Hidden
+func Sats(amount int64) string {
+	if verbose.Load() {
+		return fmt.Sprintf("%d sats", amount)
+	}
+	return Hidden
+}
+
+// Address returns an address, xpub or txid for a log line, shortened to its
+// first and last 8 characters unless logging is verbose
+func Address(address string) string {
+	if verbose.Load() || len(address) <= 16 {
+		return address
+	}
+	return fmt.Sprintf("%s...%s", address[:8], address[len(address)-8:])
+}
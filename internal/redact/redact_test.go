@@ -0,0 +1,23 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestRedaction(t *testing.T) {
+	const address = "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh"
+	defer SetVerbose(false)
+
+	testutils.AssertEqual(t, Verbose(), false)
+	testutils.AssertEqual(t, Sats(1500000), Hidden)
+	testutils.AssertEqual(t, Amount(-250), Hidden)
+	testutils.AssertEqual(t, Address(address), "bc1qxy2k...fjhx0wlh")
+	testutils.AssertEqual(t, Address("bc1qshort"), "bc1qshort")
+
+	SetVerbose(true)
+	testutils.AssertEqual(t, Sats(1500000), "1500000 sats")
+	testutils.AssertEqual(t, Amount(-250), "-250")
+	testutils.AssertEqual(t, Address(address), address)
+}
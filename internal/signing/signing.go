@@ -0,0 +1,181 @@
+// Package signing signs API responses so consumers collecting several
+// nodes' dashboards can check which node a response came from. A signature
+// covers a short message naming the request, the time of signing and the
+// SHA-256 of the body. The message stays small enough for lncli signmessage
+// whatever the size of the body.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+)
+
+// Schemes of the signatures
+const (
+	// SchemeEd25519 signatures are base64 and checked against the hex public
+	// key of the signing key
+	SchemeEd25519 = "ed25519"
+	// SchemeLND signatures are made by lncli signmessage with the node's
+	// identity key and checked by lncli verifymessage
+	SchemeLND = "lnd"
+)
+
+// Headers carrying a response's signature
+const (
+	HeaderSignature = "X-Signature"
+	HeaderScheme    = "X-Signature-Scheme"
+	HeaderKey       = "X-Signature-Key"
+	HeaderTimestamp = "X-Signature-Timestamp"
+)
+
+// messagePrefix versions the format of signed messages
+const messagePrefix = "lightning-node-tools response v1"
+
+// Message returns what is signed for a response to requestURI, e.g.
+// "/api/v1/portfolio/current?units=btc", signed at unix time timestamp
+func Message(timestamp int64, requestURI string, body []byte) string {
+	digest := sha256.Sum256(body)
+	return fmt.Sprintf("%s\n%d\n%s\n%s", messagePrefix, timestamp, requestURI, hex.EncodeToString(digest[:]))
+}
+
+// Signer signs messages
+type Signer interface {
+	// Scheme is SchemeEd25519 or SchemeLND
+	Scheme() string
+	// PublicKey is the hex key signatures are checked against
+	PublicKey() string
+	Sign(message string) (string, error)
+}
+
+// Ed25519Signer signs with a key of its own
+type Ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+// LoadOrCreateKey reads the PKCS #8 PEM key at path, creating one readable
+// by its owner only when there is none
+func LoadOrCreateKey(path string) (*Ed25519Signer, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(path, block, 0600); err != nil {
+			return nil, fmt.Errorf("failed to save signing key: %w", err)
+		}
+		return &Ed25519Signer{key: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return &Ed25519Signer{key: key}, nil
+}
+
+func (s *Ed25519Signer) Scheme() string { return SchemeEd25519 }
+
+func (s *Ed25519Signer) PublicKey() string {
+	return hex.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+func (s *Ed25519Signer) Sign(message string) (string, error) {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, []byte(message))), nil
+}
+
+// NodeSigner signs with the LND node's identity key
+type NodeSigner struct {
+	pubkey string
+}
+
+// NewNodeSigner returns a signer using the node lncli talks to
+func NewNodeSigner() (*NodeSigner, error) {
+	pubkey, err := lnd.GetNodePubkey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node pubkey: %w", err)
+	}
+	return &NodeSigner{pubkey: pubkey}, nil
+}
+
+func (s *NodeSigner) Scheme() string    { return SchemeLND }
+func (s *NodeSigner) PublicKey() string { return s.pubkey }
+
+func (s *NodeSigner) Sign(message string) (string, error) {
+	return lnd.SignMessage(message)
+}
+
+// Verify checks the signature headers of a response to requestURI against
+// the public key the consumer expects. Ed25519 signatures are checked here,
+// LND ones with lncli verifymessage, which needs a node that knows the
+// signing node from the graph.
+func Verify(header interface{ Get(string) string }, requestURI string, body []byte, publicKey string) error {
+	signature := header.Get(HeaderSignature)
+	if signature == "" {
+		return errors.New("response is not signed")
+	}
+	if header.Get(HeaderKey) != publicKey {
+		return fmt.Errorf("signed by %s, not %s", header.Get(HeaderKey), publicKey)
+	}
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", HeaderTimestamp, err)
+	}
+	message := Message(timestamp, requestURI, body)
+
+	switch scheme := header.Get(HeaderScheme); scheme {
+	case SchemeEd25519:
+		key, err := hex.DecodeString(publicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid Ed25519 public key %s", publicKey)
+		}
+		sig, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
+		}
+		if !ed25519.Verify(key, []byte(message), sig) {
+			return errors.New("signature does not match the response")
+		}
+	case SchemeLND:
+		pubkey, valid, err := lnd.VerifyMessage(message, signature)
+		if err != nil {
+			return fmt.Errorf("lncli verifymessage failed: %w", err)
+		}
+		if pubkey != publicKey {
+			return errors.New("signature does not match the response")
+		}
+		if !valid {
+			return fmt.Errorf("node %s is not in the local graph, its signature cannot be trusted", pubkey)
+		}
+	default:
+		return fmt.Errorf("unknown signature scheme %q", scheme)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package signing
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestEd25519(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signing.key")
+	signer, err := LoadOrCreateKey(path)
+	testutils.AssertNoError(t, err)
+	info, err := os.Stat(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, info.Mode().Perm(), os.FileMode(0600))
+
+	// The saved key is the one used
+	loaded, err := LoadOrCreateKey(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, loaded.PublicKey(), signer.PublicKey())
+
+	body := []byte(`{"total_balance":100}`)
+	signature, err := signer.Sign(Message(1700000000, "/api/v1/portfolio/current", body))
+	testutils.AssertNoError(t, err)
+	header := http.Header{}
+	header.Set(HeaderSignature, signature)
+	header.Set(HeaderScheme, signer.Scheme())
+	header.Set(HeaderKey, signer.PublicKey())
+	header.Set(HeaderTimestamp, strconv.Itoa(1700000000))
+
+	testutils.AssertNoError(t, Verify(header, "/api/v1/portfolio/current", body, signer.PublicKey()))
+	testutils.AssertError(t, Verify(header, "/api/v1/portfolio/current", []byte(`{"total_balance":999}`), signer.PublicKey()), "does not match")
+	testutils.AssertError(t, Verify(header, "/api/v1/portfolio/history", body, signer.PublicKey()), "does not match")
+	testutils.AssertError(t, Verify(header, "/api/v1/portfolio/current", body, "00"), "signed by")
+	testutils.AssertError(t, Verify(http.Header{}, "/api/v1/portfolio/current", body, signer.PublicKey()), "not signed")
+
+	testutils.AssertNoError(t, os.WriteFile(path, []byte("not a key"), 0600))
+	_, err = LoadOrCreateKey(path)
+	testutils.AssertError(t, err, "not a PEM file")
+}
@@ -0,0 +1,65 @@
+// Package startup lets services wait for the nodes they depend on, so a
+// service started at boot before LND or Bitcoin Core is ready does not die.
+package startup
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/notify"
+)
+
+// DefaultMaxWait is how long daemons wait for their nodes by default
+const DefaultMaxWait = 5 * time.Minute
+
+// Delays between connection attempts, doubling from the first to the max
+const (
+	firstRetryDelay = 2 * time.Second
+	maxRetryDelay   = 30 * time.Second
+)
+
+// sleep is replaced in tests
+var sleep = time.Sleep
+
+// WaitFor calls connect until it succeeds or maxWait has been spent
+// waiting between attempts, and returns the last error if it never does.
+// A maxWait of 0 tries once. name is the dependency in log messages.
+func WaitFor[T any](name string, maxWait time.Duration, connect func() (T, error)) (T, error) {
+	delay := firstRetryDelay
+	var waited time.Duration
+	for {
+		client, err := connect()
+		if err == nil {
+			if waited > 0 {
+				log.Printf("✅ %s is available after %v", name, waited)
+			}
+			return client, nil
+		}
+		if waited >= maxWait {
+			return client, err
+		}
+
+		delay = min(delay, maxWait-waited)
+		log.Printf("⏳ %s is not available yet, retrying in %v: %v", name, delay, err)
+		sleep(delay)
+		waited += delay
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// NotifyDegraded reports that service started without a dependency it gave
+// up waiting for. The message is logged and, when the monitor's bot is
+// configured with BOT_TOKEN and CHAT_ID, sent to Telegram.
+func NotifyDegraded(service, dependency string, err error) {
+	message := fmt.Sprintf("⚠️ %s started degraded: %s is not available (%v)", service, dependency, err)
+	log.Println(message)
+
+	telegram := notify.Telegram{BotToken: os.Getenv("BOT_TOKEN"), ChatID: os.Getenv("CHAT_ID")}
+	if telegram.Enabled() {
+		if err := telegram.Send(message); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
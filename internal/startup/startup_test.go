@@ -0,0 +1,47 @@
+package startup
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestWaitFor(t *testing.T) {
+	var slept []time.Duration
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleep = time.Sleep }()
+
+	// Ready on the fourth attempt
+	attempts := 0
+	client, err := WaitFor("LND", time.Minute, func() (string, error) {
+		attempts++
+		if attempts < 4 {
+			return "", fmt.Errorf("connection refused")
+		}
+		return "lnd", nil
+	})
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, client, "lnd")
+	testutils.AssertEqual(t, fmt.Sprint(slept), "[2s 4s 8s]")
+
+	// Never ready: the delays are capped and add up to the max wait
+	slept = nil
+	_, err = WaitFor("Bitcoin Core", time.Minute, func() (string, error) {
+		return "", fmt.Errorf("connection refused")
+	})
+	testutils.AssertError(t, err, "connection refused")
+	testutils.AssertEqual(t, fmt.Sprint(slept), "[2s 4s 8s 16s 30s]")
+
+	// No wait tries once
+	slept = nil
+	attempts = 0
+	_, err = WaitFor("LND", 0, func() (string, error) {
+		attempts++
+		return "", fmt.Errorf("connection refused")
+	})
+	testutils.AssertError(t, err, "connection refused")
+	testutils.AssertEqual(t, attempts, 1)
+	testutils.AssertEqual(t, len(slept), 0)
+}
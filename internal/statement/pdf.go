@@ -0,0 +1,138 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page layout in PDF points
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 50
+	bodyFontSize = 10
+	lineHeight   = 14
+)
+
+// pdfLine is one line of text placed on a page
+type pdfLine struct {
+	font string // "F1" (Courier) or "F2" (Helvetica-Bold)
+	size int
+	x, y int
+	text string
+}
+
+// pdfDocument lays out lines of text top to bottom, starting a new page when
+// one is full. Body text is set in Courier so columns line up by padding.
+type pdfDocument struct {
+	pages [][]pdfLine
+	y     int
+}
+
+func newPDFDocument() *pdfDocument {
+	d := &pdfDocument{}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, nil)
+	d.y = pageHeight - pageMargin
+}
+
+func (d *pdfDocument) add(font string, size int, text string) {
+	if d.y < pageMargin+size {
+		d.newPage()
+	}
+	d.y -= size
+	page := len(d.pages) - 1
+	d.pages[page] = append(d.pages[page], pdfLine{font: font, size: size, x: pageMargin, y: d.y, text: text})
+	d.y -= lineHeight - bodyFontSize
+}
+
+// Title adds a large bold line
+func (d *pdfDocument) Title(text string) {
+	d.add("F2", 16, text)
+	d.Space()
+}
+
+// Heading adds a bold section heading
+func (d *pdfDocument) Heading(text string) {
+	d.Space()
+	d.add("F2", 12, text)
+}
+
+// Text adds a line of body text
+func (d *pdfDocument) Text(text string) {
+	d.add("F1", bodyFontSize, text)
+}
+
+// Space adds a blank line
+func (d *pdfDocument) Space() {
+	d.y -= lineHeight
+}
+
+// WriteTo writes the document as a PDF file
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// Objects 1-4 are the catalog, page tree and fonts; each page then takes
+	// a page object followed by its content stream
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range d.pages {
+		var content bytes.Buffer
+		for _, line := range lines {
+			fmt.Fprintf(&content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", line.font, line.size, line.x, line.y, pdfEscape(line.text))
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.WriteTo(w)
+}
+
+// pdfEscape escapes a string for a PDF literal. The standard fonts only cover
+// Latin-1, so other characters (e.g. emoji in node aliases) become "?".
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		case r > 0x7e:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/insights"
+)
+
+// topChannelCount is how many channels a report lists by fees earned
+const topChannelCount = 5
+
+// Report is a statement with the context needed to present it: the opening
+// balances from the month before, the channels that earned the most and
+// observations about the month
+type Report struct {
+	Statement *db.Statement
+	// Opening is the previous month's statement, built on the fly if it was never closed
+	Opening *db.Statement
+	// Closed is false for a month that has not been closed yet, whose figures may change
+	Closed      bool
+	TopChannels []ReportChannel
+	Insights    []insights.Insight
+}
+
+// ReportChannel is a channel's forwarding earnings with its peer's alias
+// and the operator's name for it, if any
+type ReportChannel struct {
+	db.ChannelFeeStats
+	PeerAlias string `json:"peer_alias"`
+	Name      string `json:"name,omitempty"`
+}
+
+// Label is the channel's name, or its peer's alias when it has none
+func (c ReportChannel) Label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.PeerAlias
+}
+
+// Gain is the change in portfolio value over the month less net transfers,
+// i.e. what the portfolio earned or lost rather than what was added to it
+func (r *Report) Gain() int64 {
+	return r.Statement.TotalPortfolio - r.Opening.TotalPortfolio - (r.Statement.Deposits - r.Statement.Withdrawals)
+}
+
+// BuildReport returns the report for the month starting at start, using the
+// closed statement when there is one
+func BuildReport(database *db.Database, start time.Time) (*Report, error) {
+	month := start.Format(MonthLayout)
+	report := &Report{}
+
+	closed, err := database.GetStatement(month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statement for %s: %w", month, err)
+	}
+	report.Statement, report.Closed = closed, closed != nil
+	if closed == nil {
+		if report.Statement, err = Build(database, start); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := start.AddDate(0, -1, 0)
+	if report.Opening, err = database.GetStatement(previous.Format(MonthLayout)); err != nil {
+		return nil, fmt.Errorf("failed to get opening statement: %w", err)
+	}
+	if report.Opening == nil {
+		if report.Opening, err = Build(database, previous); err != nil {
+			return nil, err
+		}
+	}
+
+	channels, err := database.GetTopFeeChannels(report.Statement.PeriodStart, report.Statement.PeriodEnd, topChannelCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top channels: %w", err)
+	}
+	aliases := make(map[string]string)
+	if snapshots, err := database.GetLatestChannelSnapshots(); err == nil {
+		for _, snapshot := range snapshots {
+			aliases[snapshot.ChannelID] = snapshot.PeerAlias
+		}
+	}
+	names, err := database.GetChannelNameMap()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel names: %w", err)
+	}
+	for _, channel := range channels {
+		report.TopChannels = append(report.TopChannels, ReportChannel{
+			ChannelFeeStats: channel, PeerAlias: aliases[channel.ChannelID], Name: names[channel.ChannelID],
+		})
+	}
+
+	if report.Insights, err = insights.Generate(database, report.Statement.PeriodStart, report.Statement.PeriodEnd); err != nil {
+		return nil, fmt.Errorf("failed to generate insights: %w", err)
+	}
+
+	return report, nil
+}
+
+// WritePDF renders report as a one or more page PDF
+func WritePDF(w io.Writer, report *Report) error {
+	s := report.Statement
+	doc := newPDFDocument()
+
+	doc.Title("Monthly Statement - " + s.PeriodStart.Format("January 2006"))
+	doc.Text(fmt.Sprintf("Period: %s to %s (UTC)", s.PeriodStart.Format("2006-01-02"), s.PeriodEnd.Format("2006-01-02")))
+	if report.Closed {
+		doc.Text("Closed: " + s.ClosedAt.Format("2006-01-02 15:04 MST"))
+	} else {
+		doc.Text("Provisional: this month has not been closed and may change")
+	}
+	doc.Text("All amounts in sats")
+
+	row := func(label string, value int64) {
+		doc.Text(fmt.Sprintf("%-44s %20s", label, formatSats(value)))
+	}
+
+	doc.Heading("Balances at month end")
+	row("Lightning (local balance)", s.LightningLocal)
+	row("On-chain wallet", s.OnchainBalance)
+	row("Tracked addresses", s.TrackedAddresses)
+	row("Cold storage", s.ColdStorage)
+	row("Liquid (L-BTC)", s.Liquid)
+	row("Ecash (Fedimint, Cashu)", s.Ecash)
+	row("Total portfolio", s.TotalPortfolio)
+	row("Lightning remote balance (not included)", s.LightningRemote)
+
+	doc.Heading("Income")
+	row("Routing fees", s.ForwardingFees)
+	doc.Text(fmt.Sprintf("%-44s %20d", "Forwards", s.ForwardCount))
+	row("Volume routed", s.ForwardVolume)
+	if s.ForwardVolume > 0 {
+		doc.Text(fmt.Sprintf("%-44s %20.2f", "Effective fee rate (ppm)", float64(s.ForwardingFees)*1e6/float64(s.ForwardVolume)))
+	}
+
+	doc.Heading("Profit and loss")
+	row("Opening balance", report.Opening.TotalPortfolio)
+	row("Deposits", s.Deposits)
+	row("Withdrawals", -s.Withdrawals)
+	row("Closing balance", s.TotalPortfolio)
+	row("Gain excluding transfers", report.Gain())
+	row("  of which routing fees", s.ForwardingFees)
+	doc.Text("Costs (rebalancing, on-chain fees) are not tracked and are")
+	doc.Text("included in the gain.")
+
+	doc.Heading("Top channels by routing fees")
+	if len(report.TopChannels) == 0 {
+		doc.Text("No forwards this month")
+	} else {
+		doc.Text(fmt.Sprintf("%-20s %-22s %8s %14s", "Channel", "Peer", "Forwards", "Fees"))
+		for _, channel := range report.TopChannels {
+			doc.Text(fmt.Sprintf("%-20s %-22s %8d %14s", channel.ChannelID, truncate(channel.Label(), 22),
+				channel.ForwardCount, formatSats(channel.Fees)))
+		}
+	}
+
+	if len(report.Insights) > 0 {
+		doc.Heading("Observations")
+		for _, insight := range report.Insights {
+			doc.Text("- " + insight.Message)
+		}
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// formatSats formats an amount with thousands separators, e.g. "-1,250,000"
+func formatSats(amount int64) string {
+	digits := fmt.Sprintf("%d", amount)
+	var b strings.Builder
+	if amount < 0 {
+		b.WriteByte('-')
+		digits = digits[1:]
+	}
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+func truncate(text string, max int) string {
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max-1]) + "~"
+	}
+	return text
+}
@@ -0,0 +1,200 @@
+// Package statement closes calendar months into immutable statements and
+// archives each one as CSV and PDF files for record keeping and taxes.
+package statement
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// MonthLayout is the format of statement months, e.g. "2024-05"
+const MonthLayout = "2006-01"
+
+// ParseMonth parses a "YYYY-MM" month into the first instant of that month in UTC
+func ParseMonth(month string) (time.Time, error) {
+	start, err := time.Parse(MonthLayout, month)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM", month)
+	}
+	return start, nil
+}
+
+// PreviousMonth returns the last month that has fully ended at now, in UTC
+func PreviousMonth(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+}
+
+// Build computes the statement for the month starting at start from the
+// stored balances, forwards and transfers. It does not store it.
+func Build(database *db.Database, start time.Time) (*db.Statement, error) {
+	start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0).Add(-time.Second)
+
+	statement := &db.Statement{
+		Month:       start.Format(MonthLayout),
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+
+	lightning, err := database.GetLightningBalanceAt(end)
+	switch {
+	case err == nil:
+		statement.LightningLocal = lightning.LightningLocal
+		statement.LightningRemote = lightning.LightningRemote
+		statement.OnchainBalance = lightning.OnchainBalance
+	case !errors.Is(err, db.ErrNotFound):
+		return nil, fmt.Errorf("failed to get Lightning balance: %w", err)
+	}
+
+	if statement.TrackedAddresses, err = database.GetTrackedAddressTotalAt(end); err != nil {
+		return nil, fmt.Errorf("failed to get tracked address balances: %w", err)
+	}
+	if statement.ColdStorage, err = database.GetColdStorageTotalAt(end); err != nil {
+		return nil, fmt.Errorf("failed to get cold storage balance: %w", err)
+	}
+	if statement.Liquid, err = database.GetLiquidBalanceAt(end); err != nil {
+		return nil, fmt.Errorf("failed to get Liquid balance: %w", err)
+	}
+	if statement.Ecash, err = database.GetEcashBalanceAt(end); err != nil {
+		return nil, fmt.Errorf("failed to get ecash balance: %w", err)
+	}
+	statement.TotalPortfolio = statement.LightningLocal + statement.OnchainBalance +
+		statement.TrackedAddresses + statement.ColdStorage + statement.Liquid + statement.Ecash
+
+	stats, err := database.GetForwardingStats(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forwarding stats: %w", err)
+	}
+	statement.ForwardingFees = stats.TotalFees
+	statement.ForwardCount = stats.ForwardCount
+	statement.ForwardVolume = stats.TotalVolume
+
+	transfers, err := database.GetPortfolioTransfers(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+	for _, transfer := range transfers {
+		if transfer.Amount > 0 {
+			statement.Deposits += transfer.Amount
+		} else {
+			statement.Withdrawals -= transfer.Amount
+		}
+	}
+
+	return statement, nil
+}
+
+// Close builds and stores the statement for the month starting at start and
+// writes its CSV and PDF archives to archiveDir. Only months that have ended
+// can be closed; closing a month twice returns db.ErrStatementExists.
+func Close(database *db.Database, start time.Time, archiveDir string, now time.Time) (*db.Statement, error) {
+	if start.AddDate(0, 1, 0).After(now) {
+		return nil, fmt.Errorf("month %s has not ended", start.Format(MonthLayout))
+	}
+
+	statement, err := Build(database, start)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.InsertStatement(statement); err != nil {
+		return nil, err
+	}
+
+	return statement, ArchiveMissing(database, statement, archiveDir)
+}
+
+// ArchivePath is where the archive of month with extension ext ("csv" or
+// "pdf") is written in archiveDir
+func ArchivePath(archiveDir, month, ext string) string {
+	return filepath.Join(archiveDir, fmt.Sprintf("statement-%s.%s", month, ext))
+}
+
+// ArchiveMissing writes whichever of a closed statement's CSV and PDF
+// archives do not exist yet. Existing archives are left untouched, since
+// statements never change.
+func ArchiveMissing(database *db.Database, statement *db.Statement, archiveDir string) error {
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	err := writeArchive(ArchivePath(archiveDir, statement.Month, "csv"), func(w io.Writer) error {
+		return WriteCSV(w, statement)
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeArchive(ArchivePath(archiveDir, statement.Month, "pdf"), func(w io.Writer) error {
+		start, err := ParseMonth(statement.Month)
+		if err != nil {
+			return err
+		}
+		report, err := BuildReport(database, start)
+		if err != nil {
+			return err
+		}
+		return WritePDF(w, report)
+	})
+}
+
+// writeArchive creates a read-only file at path with write, unless it exists
+func writeArchive(path string, write func(w io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV writes statement as section,item,value rows. Amounts are in sats.
+func WriteCSV(w io.Writer, statement *db.Statement) error {
+	sats := func(v int64) string { return strconv.FormatInt(v, 10) }
+	rows := [][]string{
+		{"section", "item", "value"},
+		{"period", "month", statement.Month},
+		{"period", "start", statement.PeriodStart.Format(time.RFC3339)},
+		{"period", "end", statement.PeriodEnd.Format(time.RFC3339)},
+		{"balances", "lightning_local", sats(statement.LightningLocal)},
+		{"balances", "lightning_remote", sats(statement.LightningRemote)},
+		{"balances", "onchain_wallet", sats(statement.OnchainBalance)},
+		{"balances", "tracked_addresses", sats(statement.TrackedAddresses)},
+		{"balances", "cold_storage", sats(statement.ColdStorage)},
+		{"balances", "liquid", sats(statement.Liquid)},
+		{"balances", "ecash", sats(statement.Ecash)},
+		{"balances", "total_portfolio", sats(statement.TotalPortfolio)},
+		{"income", "forwarding_fees", sats(statement.ForwardingFees)},
+		{"income", "forward_count", sats(statement.ForwardCount)},
+		{"income", "forward_volume", sats(statement.ForwardVolume)},
+		{"transfers", "deposits", sats(statement.Deposits)},
+		{"transfers", "withdrawals", sats(statement.Withdrawals)},
+		{"closed", "closed_at", statement.ClosedAt.Format(time.RFC3339)},
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write statement CSV: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/insights"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestClose(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	may, err := ParseMonth("2024-05")
+	testutils.AssertNoError(t, err)
+
+	_, err = database.InsertColdStorageEntry("Vault", 2000000, "")
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, database.InsertLiquidBalanceSnapshot(&db.LiquidBalanceSnapshot{
+		Timestamp: may.AddDate(0, 0, 15), Confirmed: 400000, Unconfirmed: 10000,
+	}))
+	testutils.AssertNoError(t, database.InsertEcashBalanceSnapshot(&db.EcashBalanceSnapshot{
+		Timestamp: may.AddDate(0, 0, 12), Protocol: "cashu", Mint: "https://mint.example", Name: "mint.example", Amount: 25000,
+	}))
+	testutils.AssertNoError(t, database.InsertForwardingEvent(&db.ForwardingEvent{
+		Timestamp: may.AddDate(0, 0, 10), ChannelInID: "1", ChannelOutID: "2", AmountIn: 100100, AmountOut: 100000, Fee: 100,
+	}))
+	testutils.AssertNoError(t, database.InsertForwardingEvent(&db.ForwardingEvent{
+		Timestamp: may.AddDate(0, 1, 2), ChannelInID: "1", ChannelOutID: "2", AmountIn: 50050, AmountOut: 50000, Fee: 50,
+	}))
+	testutils.AssertNoError(t, database.InsertPortfolioTransfer(&db.PortfolioTransfer{Timestamp: may.AddDate(0, 0, 3), Amount: 300000}))
+	testutils.AssertNoError(t, database.InsertPortfolioTransfer(&db.PortfolioTransfer{Timestamp: may.AddDate(0, 0, 20), Amount: -100000}))
+
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	statement, err := Close(database, may, dir, now)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, statement.Month, "2024-05")
+	testutils.AssertEqual(t, statement.ColdStorage, int64(2000000))
+	testutils.AssertEqual(t, statement.Liquid, int64(410000))
+	testutils.AssertEqual(t, statement.Ecash, int64(25000))
+	testutils.AssertEqual(t, statement.TotalPortfolio, int64(2435000))
+	testutils.AssertEqual(t, statement.ForwardingFees, int64(100))
+	testutils.AssertEqual(t, statement.ForwardCount, int64(1))
+	testutils.AssertEqual(t, statement.Deposits, int64(300000))
+	testutils.AssertEqual(t, statement.Withdrawals, int64(100000))
+
+	archive, err := os.ReadFile(ArchivePath(dir, "2024-05", "csv"))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, strings.Contains(string(archive), "income,forwarding_fees,100\n"), true)
+	pdf, err := os.ReadFile(ArchivePath(dir, "2024-05", "pdf"))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, strings.HasPrefix(string(pdf), "%PDF-1.4"), true)
+
+	_, err = Close(database, may, dir, now)
+	testutils.AssertEqual(t, err, db.ErrStatementExists)
+
+	_, err = Close(database, PreviousMonth(now).AddDate(0, 1, 0), dir, now)
+	testutils.AssertError(t, err, "has not ended")
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	testutils.AssertNoError(t, WriteCSV(&buf, &db.Statement{Month: "2024-05", TotalPortfolio: 42}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	testutils.AssertEqual(t, lines[0], "section,item,value")
+	testutils.AssertEqual(t, lines[1], "period,month,2024-05")
+	testutils.AssertEqual(t, strings.Contains(buf.String(), "balances,total_portfolio,42\n"), true)
+}
+
+func TestPreviousMonth(t *testing.T) {
+	testutils.AssertEqual(t, PreviousMonth(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)).Format(MonthLayout), "2023-12")
+
+	_, err := ParseMonth("2024-13")
+	testutils.AssertError(t, err, "expected YYYY-MM")
+}
+
+func TestWritePDF(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	report := &Report{
+		Statement: &db.Statement{Month: "2024-05", PeriodStart: start, PeriodEnd: start.AddDate(0, 1, 0), TotalPortfolio: 2500000, Deposits: 1000000},
+		Opening:   &db.Statement{TotalPortfolio: 1000000},
+		TopChannels: []ReportChannel{
+			{ChannelFeeStats: db.ChannelFeeStats{ChannelID: "123", ForwardCount: 4, Fees: 1234}, PeerAlias: "⚡ (peer) ⚡"},
+			{ChannelFeeStats: db.ChannelFeeStats{ChannelID: "456", ForwardCount: 1, Fees: 10}, PeerAlias: "Kraken", Name: "Kraken drain"},
+		},
+		Insights: []insights.Insight{{Kind: insights.KindFeeShare, Message: "Channel ACINQ earned 40% of routing fees"}},
+	}
+	testutils.AssertEqual(t, report.Gain(), int64(500000))
+
+	var buf bytes.Buffer
+	testutils.AssertNoError(t, WritePDF(&buf, report))
+	pdf := buf.String()
+
+	testutils.AssertEqual(t, strings.Contains(pdf, "Monthly Statement - May 2024"), true)
+	testutils.AssertEqual(t, strings.Contains(pdf, "? \\(peer\\) ?"), true)
+	testutils.AssertEqual(t, strings.Contains(pdf, "Kraken drain"), true)
+	testutils.AssertEqual(t, strings.Contains(pdf, "2,500,000"), true)
+	testutils.AssertEqual(t, strings.Contains(pdf, "Channel ACINQ earned 40% of routing fees"), true)
+
+	// Every xref entry must point at its object and startxref at the table
+	xref := strings.LastIndex(pdf, "\nxref\n") + 1
+	var startxref int
+	_, err := fmt.Sscanf(pdf[strings.LastIndex(pdf, "startxref\n"):], "startxref\n%d", &startxref)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, startxref, xref)
+
+	entries := strings.Split(pdf[xref:], "\n")[3:]
+	for i, entry := range entries {
+		if !strings.HasSuffix(entry, " n ") {
+			break
+		}
+		var offset int
+		_, err := fmt.Sscanf(entry, "%d", &offset)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj", i+1)), true)
+	}
+}
+
+func TestFormatSats(t *testing.T) {
+	testutils.AssertEqual(t, formatSats(0), "0")
+	testutils.AssertEqual(t, formatSats(999), "999")
+	testutils.AssertEqual(t, formatSats(1250000), "1,250,000")
+	testutils.AssertEqual(t, formatSats(-100000), "-100,000")
+}
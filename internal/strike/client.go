@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -126,29 +127,44 @@ func (c *Client) GetAccountBalance() ([]BalanceDetail, error) {
 	return details, nil
 }
 
-// parseAmountToSmallestUnit converts Strike API string amounts to smallest units
-// For BTC: converts to satoshis (multiply by 100,000,000)
-// For fiat: converts to cents (multiply by 100)
+// parseAmountToSmallestUnit converts Strike API decimal strings to the
+// smallest unit: satoshis (8 places) for BTC and cents (2 places) for fiat.
+// The conversion is exact; amounts with more places than the unit has are
+// refused rather than rounded through a float.
 func parseAmountToSmallestUnit(amountStr, currency string) (int64, error) {
-	if amountStr == "" || amountStr == "0" {
+	if amountStr == "" {
 		return 0, nil
 	}
 
-	// Parse as float first
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid amount format: %w", err)
+	places := 2
+	if currency == "BTC" {
+		places = 8
 	}
 
-	// Convert to smallest unit based on currency
-	var multiplier float64
-	if currency == "BTC" {
-		multiplier = 100_000_000 // Convert BTC to satoshis
-	} else {
-		multiplier = 100 // Convert fiat to cents
+	negative := strings.HasPrefix(amountStr, "-")
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(amountStr, "-"), ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > places {
+		if strings.Trim(frac[places:], "0") != "" {
+			return 0, fmt.Errorf("invalid amount %q: more than %d decimal places", amountStr, places)
+		}
+		frac = frac[:places]
 	}
 
-	// Round to nearest integer
-	smallestUnit := int64(amount*multiplier + 0.5)
-	return smallestUnit, nil
+	digits := whole + frac + strings.Repeat("0", places-len(frac))
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid amount format %q", amountStr)
+		}
+	}
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount format: %w", err)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
 }
@@ -0,0 +1,36 @@
+package strike
+
+import (
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseAmountToSmallestUnit(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		want             int64
+	}{
+		{"0.29", "USD", 29},
+		{"1000.1", "USD", 100010},
+		{"0.00000001", "BTC", 1},
+		{"0.57", "BTC", 57000000},
+		{"20999999.99999999", "BTC", 2099999999999999},
+		{"12.3400", "EUR", 1234},
+		// Rounding through a float64 by adding 0.5 made this -28
+		{"-0.29", "USD", -29},
+		{"", "BTC", 0},
+	}
+	for _, tt := range tests {
+		got, err := parseAmountToSmallestUnit(tt.amount, tt.currency)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, got, tt.want)
+	}
+
+	for _, amount := range []string{"0.001", "1e5", "abc", "1.2.3"} {
+		_, err := parseAmountToSmallestUnit(amount, "USD")
+		if err == nil {
+			t.Errorf("expected %q to be refused", amount)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package strike
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// Money is an amount in the Strike API's decimal string form
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// PaymentQuote is a priced payment out of the account that Strike holds
+// for a short time until it is executed
+type PaymentQuote struct {
+	PaymentQuoteID string `json:"paymentQuoteId"`
+	ValidUntil     string `json:"validUntil"`
+	Amount         Money  `json:"amount"`
+	TotalFee       Money  `json:"totalFee"`
+	TotalAmount    Money  `json:"totalAmount"`
+}
+
+// FeeSats returns the quote's total fee in sats, or 0 when Strike charges
+// it in another currency
+func (q *PaymentQuote) FeeSats() int64 {
+	if q.TotalFee.Currency != "BTC" {
+		return 0
+	}
+	fee, err := parseAmountToSmallestUnit(q.TotalFee.Amount, "BTC")
+	if err != nil {
+		return 0
+	}
+	return fee
+}
+
+// Payment states reported when a quote is executed
+const (
+	PaymentPending   = "PENDING"
+	PaymentCompleted = "COMPLETED"
+	PaymentFailed    = "FAILED"
+)
+
+// Payment is the result of executing a payment quote
+type Payment struct {
+	PaymentID string `json:"paymentId"`
+	State     string `json:"state"`
+}
+
+// QuoteOnchainPayment prices sending amount sats from the BTC balance to address
+func (c *Client) QuoteOnchainPayment(address string, amount int64) (*PaymentQuote, error) {
+	var quote PaymentQuote
+	err := c.do("POST", "/payment-quotes/onchain", map[string]interface{}{
+		"btcAddress":     address,
+		"sourceCurrency": "BTC",
+		"amount":         Money{Amount: utils.FormatBTC(amount), Currency: "BTC"},
+	}, &quote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote on-chain payment: %w", err)
+	}
+	return &quote, nil
+}
+
+// QuoteLightningPayment prices paying invoice from the BTC balance
+func (c *Client) QuoteLightningPayment(invoice string) (*PaymentQuote, error) {
+	var quote PaymentQuote
+	err := c.do("POST", "/payment-quotes/lightning", map[string]interface{}{
+		"lnInvoice":      invoice,
+		"sourceCurrency": "BTC",
+	}, &quote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote Lightning payment: %w", err)
+	}
+	return &quote, nil
+}
+
+// ExecuteQuote sends the payment priced by a quote
+func (c *Client) ExecuteQuote(quoteID string) (*Payment, error) {
+	var payment Payment
+	if err := c.do("PATCH", "/payment-quotes/"+quoteID+"/execute", nil, &payment); err != nil {
+		return nil, fmt.Errorf("failed to execute payment quote %s: %w", quoteID, err)
+	}
+	return &payment, nil
+}
+
+// do sends a JSON request to the Strike API and decodes the response into out
+func (c *Client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("strike API returned status %d: %s", resp.StatusCode, string(data))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package strike
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestOnchainPayment(t *testing.T) {
+	var quoteRequest map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutils.AssertEqual(t, r.Header.Get("Authorization"), "Bearer key")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /payment-quotes/onchain":
+			testutils.AssertNoError(t, json.NewDecoder(r.Body).Decode(&quoteRequest))
+			w.Write([]byte(`{"paymentQuoteId": "q1", "totalFee": {"amount": "0.00000450", "currency": "BTC"}}`))
+		case "PATCH /payment-quotes/q1/execute":
+			w.Write([]byte(`{"paymentId": "p1", "state": "PENDING"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("key")
+	client.baseURL = server.URL
+
+	quote, err := client.QuoteOnchainPayment("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", 1500000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, quote.PaymentQuoteID, "q1")
+	testutils.AssertEqual(t, quote.FeeSats(), int64(450))
+	testutils.AssertEqual(t, quoteRequest["amount"].(map[string]interface{})["amount"], "0.01500000")
+
+	payment, err := client.ExecuteQuote(quote.PaymentQuoteID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, payment.State, PaymentPending)
+
+	_, err = client.ExecuteQuote("missing")
+	testutils.AssertError(t, err, "status 404")
+}
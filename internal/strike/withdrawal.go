@@ -0,0 +1,289 @@
+package strike
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// Approval modes of automatic withdrawals
+const (
+	// ApprovalManual proposes withdrawals that wait for approval through
+	// the API before they are executed
+	ApprovalManual = "manual"
+	// ApprovalAuto executes withdrawals as soon as they are proposed
+	ApprovalAuto = "auto"
+)
+
+// WithdrawalPolicy moves BTC off Strike once the available balance passes
+// Threshold, leaving Keep behind
+type WithdrawalPolicy struct {
+	Threshold int64 // Sats; 0 disables withdrawals
+	Keep      int64 // Sats left on Strike after a withdrawal
+	// DestinationType is db.WithdrawalOnchain, paying Address, or
+	// db.WithdrawalLightning, paying an invoice from the local node
+	DestinationType string
+	Address         string
+	Approval        string
+}
+
+// Validate checks that the policy can propose withdrawals
+func (p WithdrawalPolicy) Validate() error {
+	switch {
+	case p.Threshold <= 0:
+		return errors.New("threshold must be positive")
+	case p.Keep < 0 || p.Keep >= p.Threshold:
+		return errors.New("the amount kept must be below the threshold")
+	case p.Approval != ApprovalManual && p.Approval != ApprovalAuto:
+		return fmt.Errorf("approval must be %s or %s", ApprovalManual, ApprovalAuto)
+	}
+	switch p.DestinationType {
+	case db.WithdrawalOnchain:
+		if !utils.ValidateBitcoinAddress(p.Address) {
+			return fmt.Errorf("invalid withdrawal address %q", p.Address)
+		}
+	case db.WithdrawalLightning:
+	default:
+		return errors.New("a withdrawal address or Lightning is required")
+	}
+	return nil
+}
+
+// Amount returns the sats to withdraw from an available balance, 0 while it
+// does not exceed the threshold
+func (p WithdrawalPolicy) Amount(available int64) int64 {
+	if p.Threshold <= 0 || available <= p.Threshold {
+		return 0
+	}
+	return available - p.Keep
+}
+
+// FailureBackoff is how long no withdrawal is proposed after one failed
+const FailureBackoff = 24 * time.Hour
+
+// StuckAfter is how long a withdrawal may stay executing before it is taken
+// for interrupted; paying one takes seconds
+const StuckAfter = 10 * time.Minute
+
+// Stuck reports whether a withdrawal was left executing, e.g. by a crash
+// mid-payment. It blocks new withdrawals until it is resolved by hand after
+// checking its quote on Strike.
+func Stuck(withdrawal db.StrikeWithdrawal, now time.Time) bool {
+	return withdrawal.Status == db.WithdrawalExecuting && now.Sub(withdrawal.UpdatedAt) >= StuckAfter
+}
+
+// Payer sends payments from the Strike balance; *Client satisfies it
+type Payer interface {
+	QuoteOnchainPayment(address string, amount int64) (*PaymentQuote, error)
+	QuoteLightningPayment(invoice string) (*PaymentQuote, error)
+	ExecuteQuote(quoteID string) (*Payment, error)
+}
+
+// Withdrawer proposes withdrawals by a policy and executes the approved ones,
+// keeping the log in the strike_withdrawals table
+type Withdrawer struct {
+	Policy WithdrawalPolicy
+	DB     *db.Database
+	Payer  Payer
+	// Invoice creates the invoice Lightning withdrawals pay, lnd.AddInvoice
+	Invoice func(amount int64, memo string) (string, error)
+	// Notify sends a message about a withdrawal, e.g. to Telegram
+	Notify func(message string)
+
+	// reported holds the stuck withdrawals already notified about
+	reported map[int64]bool
+	// now is time.Now unless a test sets it
+	now func() time.Time
+}
+
+// Run proposes a withdrawal when the available BTC balance passes the
+// threshold, then executes the approved ones. Nothing is proposed while
+// another withdrawal is open or was interrupted, or for FailureBackoff after
+// one failed; failed withdrawals are not retried. Interrupted withdrawals are
+// reported through Notify once they are Stuck.
+func (w *Withdrawer) Run(available int64) error {
+	open, err := w.DB.GetStrikeWithdrawals(db.WithdrawalPendingApproval, db.WithdrawalApproved)
+	if err != nil {
+		return fmt.Errorf("failed to get open withdrawals: %w", err)
+	}
+	blocked, err := w.DB.GetStrikeWithdrawals(db.WithdrawalExecuting, db.WithdrawalFailed)
+	if err != nil {
+		return fmt.Errorf("failed to get failed withdrawals: %w", err)
+	}
+	canPropose := len(open) == 0
+	for _, withdrawal := range blocked {
+		if withdrawal.Status == db.WithdrawalExecuting || w.clock().Sub(withdrawal.UpdatedAt) < FailureBackoff {
+			canPropose = false
+		}
+		if Stuck(withdrawal, w.clock()) {
+			w.reportStuck(withdrawal)
+		}
+	}
+
+	if amount := w.Policy.Amount(available); amount > 0 && canPropose {
+		withdrawal, err := w.propose(amount)
+		if err != nil {
+			return err
+		}
+		open = append(open, *withdrawal)
+	}
+
+	for i := range open {
+		if open[i].Status != db.WithdrawalApproved {
+			continue
+		}
+		if err := w.execute(&open[i], available); err != nil {
+			return err
+		}
+		if open[i].Status == db.WithdrawalExecuted {
+			available -= open[i].Amount + open[i].Fee
+		}
+	}
+	return nil
+}
+
+// propose stores a new withdrawal, approved straight away in automatic mode
+func (w *Withdrawer) propose(amount int64) (*db.StrikeWithdrawal, error) {
+	withdrawal := &db.StrikeWithdrawal{
+		Amount:          amount,
+		DestinationType: w.Policy.DestinationType,
+		Destination:     w.Policy.Address,
+		Status:          db.WithdrawalPendingApproval,
+	}
+	if w.Policy.Approval == ApprovalAuto {
+		withdrawal.Status = db.WithdrawalApproved
+	}
+	if err := w.DB.InsertStrikeWithdrawal(withdrawal); err != nil {
+		return nil, fmt.Errorf("failed to store withdrawal: %w", err)
+	}
+
+	log.Printf("Proposed Strike withdrawal #%d of %s (%s)", withdrawal.ID, redact.Sats(amount), withdrawal.Status)
+	if withdrawal.Status == db.WithdrawalPendingApproval {
+		w.notify(fmt.Sprintf("🏦 <b>Strike withdrawal #%d needs approval</b>\n%s to %s\nApprove with POST /api/v1/strike/withdrawals/%d/approve",
+			withdrawal.ID, utils.FormatSats(amount), w.describeDestination(withdrawal), withdrawal.ID))
+	}
+	return withdrawal, nil
+}
+
+// execute pays out an approved withdrawal. It is moved to executing first,
+// so one interrupted midway is never paid twice.
+func (w *Withdrawer) execute(withdrawal *db.StrikeWithdrawal, available int64) error {
+	if err := w.DB.TransitionStrikeWithdrawal(withdrawal.ID, db.WithdrawalApproved, db.WithdrawalExecuting); err != nil {
+		if errors.Is(err, db.ErrStatusChanged) {
+			return nil
+		}
+		return fmt.Errorf("failed to start withdrawal %d: %w", withdrawal.ID, err)
+	}
+	withdrawal.Status = db.WithdrawalExecuting
+
+	payment, err := w.pay(withdrawal, available)
+	if err != nil {
+		withdrawal.Status = db.WithdrawalFailed
+		withdrawal.Error = err.Error()
+		log.Printf("Strike withdrawal #%d failed: %v", withdrawal.ID, err)
+		w.notify(fmt.Sprintf("❌ <b>Strike withdrawal #%d failed</b>\n%s", withdrawal.ID, err))
+	} else {
+		withdrawal.Status = db.WithdrawalExecuted
+		withdrawal.PaymentID = payment.PaymentID
+		log.Printf("Strike withdrawal #%d sent as payment %s (%s)", withdrawal.ID, payment.PaymentID, payment.State)
+		w.notify(fmt.Sprintf("✅ <b>Strike withdrawal #%d sent</b>\n%s to %s, fee %s (%s)",
+			withdrawal.ID, utils.FormatSats(withdrawal.Amount), w.describeDestination(withdrawal),
+			utils.FormatSats(withdrawal.Fee), payment.State))
+	}
+
+	if err := w.DB.UpdateStrikeWithdrawal(withdrawal); err != nil {
+		return fmt.Errorf("failed to record withdrawal %d: %w", withdrawal.ID, err)
+	}
+	return nil
+}
+
+// pay quotes and executes the payment of a withdrawal, filling in its
+// destination, quote and fee
+func (w *Withdrawer) pay(withdrawal *db.StrikeWithdrawal, available int64) (*Payment, error) {
+	if withdrawal.Amount > available {
+		return nil, errors.New("the available balance is below the withdrawal amount")
+	}
+
+	var quote *PaymentQuote
+	var err error
+	switch withdrawal.DestinationType {
+	case db.WithdrawalOnchain:
+		quote, err = w.Payer.QuoteOnchainPayment(withdrawal.Destination, withdrawal.Amount)
+	case db.WithdrawalLightning:
+		withdrawal.Destination, err = w.Invoice(withdrawal.Amount, fmt.Sprintf("Strike withdrawal #%d", withdrawal.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create invoice: %w", err)
+		}
+		quote, err = w.Payer.QuoteLightningPayment(withdrawal.Destination)
+	default:
+		return nil, fmt.Errorf("unknown destination type %q", withdrawal.DestinationType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	withdrawal.QuoteID = quote.PaymentQuoteID
+	withdrawal.Fee = quote.FeeSats()
+	// Strike takes the fee from the balance on top of the amount
+	if withdrawal.Amount+withdrawal.Fee > available {
+		return nil, fmt.Errorf("the available balance is below the withdrawal amount plus its %s fee", redact.Sats(withdrawal.Fee))
+	}
+	// The quote is what a withdrawal interrupted from here on is looked up by
+	if err := w.DB.UpdateStrikeWithdrawal(withdrawal); err != nil {
+		return nil, fmt.Errorf("failed to record quote: %w", err)
+	}
+
+	payment, err := w.Payer.ExecuteQuote(quote.PaymentQuoteID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.State == PaymentFailed {
+		return nil, fmt.Errorf("strike reported payment %s as failed", payment.PaymentID)
+	}
+	return payment, nil
+}
+
+func (w *Withdrawer) clock() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
+
+// reportStuck notifies once per process about a withdrawal left executing
+func (w *Withdrawer) reportStuck(withdrawal db.StrikeWithdrawal) {
+	if w.reported[withdrawal.ID] {
+		return
+	}
+	if w.reported == nil {
+		w.reported = make(map[int64]bool)
+	}
+	w.reported[withdrawal.ID] = true
+
+	quote := withdrawal.QuoteID
+	if quote == "" {
+		quote = "none, it was not quoted"
+	}
+	log.Printf("⚠️  Strike withdrawal #%d is stuck executing since %s (quote %s), no withdrawals are proposed until it is resolved",
+		withdrawal.ID, withdrawal.UpdatedAt.Format(time.RFC3339), quote)
+	w.notify(fmt.Sprintf("⚠️ <b>Strike withdrawal #%d is stuck</b>\n%s was interrupted while executing (quote %s).\n"+
+		"Check the payment on Strike, then resolve it with POST /api/v1/strike/withdrawals/%d/resolve",
+		withdrawal.ID, utils.FormatSats(withdrawal.Amount), quote, withdrawal.ID))
+}
+
+func (w *Withdrawer) describeDestination(withdrawal *db.StrikeWithdrawal) string {
+	if withdrawal.DestinationType == db.WithdrawalLightning {
+		return "the node over Lightning"
+	}
+	return withdrawal.Destination
+}
+
+func (w *Withdrawer) notify(message string) {
+	if w.Notify != nil {
+		w.Notify(message)
+	}
+}
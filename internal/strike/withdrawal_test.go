@@ -0,0 +1,197 @@
+package strike
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+type fakePayer struct {
+	quoted   []string
+	executed int
+	err      error
+	// onExecute runs as the quote is executed
+	onExecute func()
+}
+
+func (p *fakePayer) QuoteOnchainPayment(address string, amount int64) (*PaymentQuote, error) {
+	p.quoted = append(p.quoted, address)
+	return &PaymentQuote{PaymentQuoteID: "q", TotalFee: Money{Amount: "0.00000300", Currency: "BTC"}}, p.err
+}
+
+func (p *fakePayer) QuoteLightningPayment(invoice string) (*PaymentQuote, error) {
+	p.quoted = append(p.quoted, invoice)
+	return &PaymentQuote{PaymentQuoteID: "q"}, p.err
+}
+
+func (p *fakePayer) ExecuteQuote(quoteID string) (*Payment, error) {
+	p.executed++
+	if p.onExecute != nil {
+		p.onExecute()
+	}
+	return &Payment{PaymentID: "p", State: PaymentCompleted}, nil
+}
+
+func TestWithdrawalPolicy(t *testing.T) {
+	policy := WithdrawalPolicy{Threshold: 1000000, Keep: 100000, DestinationType: db.WithdrawalLightning, Approval: ApprovalManual}
+	testutils.AssertNoError(t, policy.Validate())
+	testutils.AssertEqual(t, policy.Amount(1000000), int64(0))
+	testutils.AssertEqual(t, policy.Amount(1500000), int64(1400000))
+
+	for _, invalid := range []WithdrawalPolicy{
+		{Threshold: 1000, Keep: 1000, DestinationType: db.WithdrawalLightning, Approval: ApprovalAuto},
+		{Threshold: 1000, DestinationType: db.WithdrawalOnchain, Address: "nope", Approval: ApprovalAuto},
+		{Threshold: 1000, DestinationType: db.WithdrawalLightning, Approval: "sometimes"},
+		{Threshold: 1000, Approval: ApprovalAuto},
+	} {
+		if invalid.Validate() == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestWithdrawerManualApproval(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	const address = "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh"
+	payer := &fakePayer{}
+	var messages []string
+	withdrawer := &Withdrawer{
+		Policy: WithdrawalPolicy{Threshold: 1000000, DestinationType: db.WithdrawalOnchain, Address: address, Approval: ApprovalManual},
+		DB:     database,
+		Payer:  payer,
+		Notify: func(message string) { messages = append(messages, message) },
+	}
+
+	// Above the threshold a withdrawal is proposed once and waits
+	testutils.AssertNoError(t, withdrawer.Run(1200000))
+	testutils.AssertNoError(t, withdrawer.Run(1300000))
+	withdrawals, err := database.GetStrikeWithdrawals()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(withdrawals), 1)
+	testutils.AssertEqual(t, withdrawals[0].Status, db.WithdrawalPendingApproval)
+	testutils.AssertEqual(t, withdrawals[0].Amount, int64(1200000))
+	testutils.AssertEqual(t, payer.executed, 0)
+	testutils.AssertEqual(t, len(messages), 1)
+
+	// Once approved the next run pays it
+	testutils.AssertNoError(t, database.TransitionStrikeWithdrawal(withdrawals[0].ID, db.WithdrawalPendingApproval, db.WithdrawalApproved))
+	testutils.AssertNoError(t, withdrawer.Run(1300000))
+	executed, err := database.GetStrikeWithdrawal(withdrawals[0].ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, executed.Status, db.WithdrawalExecuted)
+	testutils.AssertEqual(t, executed.Fee, int64(300))
+	testutils.AssertEqual(t, executed.PaymentID, "p")
+	testutils.AssertEqual(t, payer.quoted[0], address)
+	testutils.AssertEqual(t, payer.executed, 1)
+}
+
+func TestWithdrawerChecksFeeAgainstBalance(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	payer := &fakePayer{}
+	withdrawer := &Withdrawer{
+		Policy: WithdrawalPolicy{Threshold: 1000000, DestinationType: db.WithdrawalOnchain, Address: "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", Approval: ApprovalManual},
+		DB:     database,
+		Payer:  payer,
+	}
+	testutils.AssertNoError(t, withdrawer.Run(1200000))
+	withdrawals, err := database.GetStrikeWithdrawals()
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, database.TransitionStrikeWithdrawal(withdrawals[0].ID, db.WithdrawalPendingApproval, db.WithdrawalApproved))
+
+	// The amount fits, but not with the 300 sat fee on top
+	testutils.AssertNoError(t, withdrawer.Run(1200100))
+	failed, err := database.GetStrikeWithdrawal(withdrawals[0].ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, failed.Status, db.WithdrawalFailed)
+	if !strings.Contains(failed.Error, "fee") {
+		t.Errorf("expected the error to name the fee, got %q", failed.Error)
+	}
+	testutils.AssertEqual(t, payer.executed, 0)
+}
+
+func TestWithdrawerAutomaticLightning(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	payer := &fakePayer{}
+	withdrawer := &Withdrawer{
+		Policy:  WithdrawalPolicy{Threshold: 1000000, Keep: 200000, DestinationType: db.WithdrawalLightning, Approval: ApprovalAuto},
+		DB:      database,
+		Payer:   payer,
+		Invoice: func(amount int64, memo string) (string, error) { return "lnbc-invoice", nil },
+	}
+
+	testutils.AssertNoError(t, withdrawer.Run(1500000))
+	withdrawals, err := database.GetStrikeWithdrawals()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(withdrawals), 1)
+	testutils.AssertEqual(t, withdrawals[0].Status, db.WithdrawalExecuted)
+	testutils.AssertEqual(t, withdrawals[0].Amount, int64(1300000))
+	testutils.AssertEqual(t, withdrawals[0].Destination, "lnbc-invoice")
+
+	// A failed quote is logged on the withdrawal, which is not retried, and
+	// holds off new ones for a day
+	payer.err = errors.New("quote expired")
+	testutils.AssertNoError(t, withdrawer.Run(1500000))
+	testutils.AssertNoError(t, withdrawer.Run(1500000))
+	withdrawals, err = database.GetStrikeWithdrawals()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(withdrawals), 2)
+	testutils.AssertEqual(t, withdrawals[0].Status, db.WithdrawalFailed)
+	testutils.AssertEqual(t, withdrawals[0].Error, "quote expired")
+	testutils.AssertEqual(t, payer.executed, 1)
+}
+
+func TestWithdrawerReportsStuckWithdrawals(t *testing.T) {
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	var messages []string
+	withdrawer := &Withdrawer{
+		Policy:  WithdrawalPolicy{Threshold: 1000000, DestinationType: db.WithdrawalLightning, Approval: ApprovalAuto},
+		DB:      database,
+		Invoice: func(amount int64, memo string) (string, error) { return "lnbc-invoice", nil },
+		Notify:  func(message string) { messages = append(messages, message) },
+	}
+
+	// The quote is recorded before it is executed, so a withdrawal
+	// interrupted while paying can be looked up on Strike
+	var executing *db.StrikeWithdrawal
+	withdrawer.Payer = &fakePayer{onExecute: func() {
+		withdrawals, err := database.GetStrikeWithdrawals(db.WithdrawalExecuting)
+		testutils.AssertNoError(t, err)
+		executing = &withdrawals[0]
+	}}
+	testutils.AssertNoError(t, withdrawer.Run(1500000))
+	testutils.AssertEqual(t, executing.QuoteID, "q")
+	testutils.AssertEqual(t, Stuck(*executing, executing.UpdatedAt.Add(StuckAfter-time.Second)), false)
+	testutils.AssertEqual(t, Stuck(*executing, executing.UpdatedAt.Add(StuckAfter)), true)
+
+	// A withdrawal left executing blocks new ones and is reported once
+	interrupted := &db.StrikeWithdrawal{Amount: 500000, DestinationType: db.WithdrawalLightning, Status: db.WithdrawalExecuting}
+	testutils.AssertNoError(t, database.InsertStrikeWithdrawal(interrupted))
+	messages = nil
+	testutils.AssertNoError(t, withdrawer.Run(1500000))
+	testutils.AssertEqual(t, len(messages), 0)
+
+	withdrawer.now = func() time.Time { return time.Now().Add(StuckAfter) }
+	testutils.AssertNoError(t, withdrawer.Run(1500000))
+	testutils.AssertNoError(t, withdrawer.Run(1500000))
+	testutils.AssertEqual(t, len(messages), 1)
+	testutils.AssertEqual(t, strings.Contains(messages[0], "#2 is stuck"), true)
+	withdrawals, err := database.GetStrikeWithdrawals()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(withdrawals), 2)
+}
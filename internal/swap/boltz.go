@@ -0,0 +1,124 @@
+package swap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBoltzURL is the public Boltz API
+const DefaultBoltzURL = "https://api.boltz.exchange/v2"
+
+// BoltzClient quotes swaps from the Boltz pair fee schedule. Boltz swaps
+// are non-custodial and need no account, so the public API is used.
+type BoltzClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewBoltzClient creates a client for the Boltz API at baseURL
+func NewBoltzClient(baseURL string) *BoltzClient {
+	return &BoltzClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// Name returns the provider name
+func (c *BoltzClient) Name() string {
+	return "boltz"
+}
+
+// boltzLimits are the smallest and largest swaps of a pair
+type boltzLimits struct {
+	Minimal int64 `json:"minimal"`
+	Maximal int64 `json:"maximal"`
+}
+
+// Quote prices a reverse swap for direction out and a submarine swap for in
+func (c *BoltzClient) Quote(direction string, amount int64) (*Quote, error) {
+	if direction == DirectionOut {
+		var pairs map[string]map[string]struct {
+			Limits boltzLimits `json:"limits"`
+			Fees   struct {
+				Percentage float64 `json:"percentage"`
+				MinerFees  struct {
+					Lockup int64 `json:"lockup"`
+					Claim  int64 `json:"claim"`
+				} `json:"minerFees"`
+			} `json:"fees"`
+		}
+		if err := c.get("/swap/reverse", &pairs); err != nil {
+			return nil, err
+		}
+		pair, ok := pairs["BTC"]["BTC"]
+		if !ok {
+			return nil, fmt.Errorf("boltz does not offer BTC reverse swaps")
+		}
+		if err := checkLimits(amount, pair.Limits); err != nil {
+			return nil, err
+		}
+		// Boltz's lockup fee is added to the invoice; the claim is our own transaction
+		return newQuote(c.Name(), direction, amount, percentageFee(amount, pair.Fees.Percentage),
+			pair.Fees.MinerFees.Lockup+pair.Fees.MinerFees.Claim), nil
+	}
+
+	var pairs map[string]map[string]struct {
+		Limits boltzLimits `json:"limits"`
+		Fees   struct {
+			Percentage float64 `json:"percentage"`
+			MinerFees  int64   `json:"minerFees"`
+		} `json:"fees"`
+	}
+	if err := c.get("/swap/submarine", &pairs); err != nil {
+		return nil, err
+	}
+	pair, ok := pairs["BTC"]["BTC"]
+	if !ok {
+		return nil, fmt.Errorf("boltz does not offer BTC submarine swaps")
+	}
+	if err := checkLimits(amount, pair.Limits); err != nil {
+		return nil, err
+	}
+	quote := newQuote(c.Name(), direction, amount, percentageFee(amount, pair.Fees.Percentage), pair.Fees.MinerFees)
+	quote.Note = "excludes the fee of your own lockup transaction"
+	return quote, nil
+}
+
+func (c *BoltzClient) get(path string, v interface{}) error {
+	resp, err := c.client.Get(c.baseURL + path)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("boltz API returned status %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+func checkLimits(amount int64, limits boltzLimits) error {
+	if amount < limits.Minimal || (limits.Maximal > 0 && amount > limits.Maximal) {
+		return fmt.Errorf("amount %d is outside the limits of %d to %d sats", amount, limits.Minimal, limits.Maximal)
+	}
+	return nil
+}
+
+// percentageFee rounds a percentage fee up to whole sats, as Boltz does
+func percentageFee(amount int64, percentage float64) int64 {
+	return int64(math.Ceil(float64(amount) * percentage / 100))
+}
@@ -0,0 +1,165 @@
+// Package swap compares submarine swap quotes from several providers, so a
+// liquidity shift can be made through the cheapest one.
+//
+// A swap out (Loop Out, Boltz reverse swap) pays a Lightning invoice and
+// receives on-chain funds, moving local balance to the remote side and
+// gaining inbound liquidity. A swap in (Loop In, Boltz submarine swap) pays
+// on-chain and receives over Lightning, gaining outbound liquidity.
+package swap
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Swap directions
+const (
+	// DirectionOut moves Lightning funds on-chain, gaining inbound liquidity
+	DirectionOut = "out"
+	// DirectionIn moves on-chain funds into Lightning, gaining outbound liquidity
+	DirectionIn = "in"
+)
+
+// Directions lists the valid swap directions
+var Directions = []string{DirectionOut, DirectionIn}
+
+// Quote is what one provider charges to swap Amount sats
+type Quote struct {
+	Provider  string `json:"provider"`
+	Direction string `json:"direction"`
+	Amount    int64  `json:"amount"`
+	// ServiceFee is the provider's fee
+	ServiceFee int64 `json:"service_fee"`
+	// MinerFee is the on-chain fee the provider passes on or estimates
+	MinerFee int64 `json:"miner_fee"`
+	TotalFee int64 `json:"total_fee"`
+	// FeePPM is TotalFee per million sats swapped
+	FeePPM int64 `json:"fee_ppm"`
+	// Note explains what the quote leaves out, if anything
+	Note string `json:"note,omitempty"`
+	// Error is set when the provider could not quote the swap
+	Error string `json:"error,omitempty"`
+}
+
+// newQuote fills in the totals of a successful quote
+func newQuote(provider, direction string, amount, serviceFee, minerFee int64) *Quote {
+	total := serviceFee + minerFee
+	return &Quote{
+		Provider:   provider,
+		Direction:  direction,
+		Amount:     amount,
+		ServiceFee: serviceFee,
+		MinerFee:   minerFee,
+		TotalFee:   total,
+		FeePPM:     total * 1000000 / amount,
+	}
+}
+
+// Provider quotes swaps in one or both directions
+type Provider interface {
+	Name() string
+	Quote(direction string, amount int64) (*Quote, error)
+}
+
+// Comparison holds the quotes of every provider, cheapest first. Quotes that
+// failed are listed last with their error.
+type Comparison struct {
+	Direction string  `json:"direction"`
+	Amount    int64   `json:"amount"`
+	Quotes    []Quote `json:"quotes"`
+	// Recommended is the cheapest successful quote, or nil if none succeeded
+	Recommended *Quote `json:"recommended"`
+}
+
+// Compare fetches a quote from every provider concurrently
+func Compare(providers []Provider, direction string, amount int64) (*Comparison, error) {
+	if direction != DirectionOut && direction != DirectionIn {
+		return nil, fmt.Errorf("invalid direction %q, expected %s or %s", direction, DirectionOut, DirectionIn)
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	quotes := make([]Quote, len(providers))
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Add(1)
+		go func(i int, provider Provider) {
+			defer wg.Done()
+			quote, err := provider.Quote(direction, amount)
+			if err != nil {
+				quotes[i] = Quote{Provider: provider.Name(), Direction: direction, Amount: amount, Error: err.Error()}
+				return
+			}
+			quotes[i] = *quote
+		}(i, provider)
+	}
+	wg.Wait()
+
+	sort.SliceStable(quotes, func(i, j int) bool {
+		if (quotes[i].Error == "") != (quotes[j].Error == "") {
+			return quotes[i].Error == ""
+		}
+		return quotes[i].TotalFee < quotes[j].TotalFee
+	})
+
+	comparison := &Comparison{Direction: direction, Amount: amount, Quotes: quotes}
+	if len(quotes) > 0 && quotes[0].Error == "" {
+		comparison.Recommended = &quotes[0]
+	}
+	return comparison, nil
+}
+
+// ShiftForChannel returns the swap that brings a channel's local balance to
+// half its capacity: out when it holds too much local balance, in when it
+// holds too little
+func ShiftForChannel(localBalance, capacity int64) (direction string, amount int64, err error) {
+	shift := localBalance - capacity/2
+	switch {
+	case shift > 0:
+		return DirectionOut, shift, nil
+	case shift < 0:
+		return DirectionIn, -shift, nil
+	}
+	return "", 0, fmt.Errorf("channel is already balanced")
+}
+
+// Config selects the providers to compare. An empty URL disables a provider.
+type Config struct {
+	BoltzURL     string
+	LoopURL      string
+	LoopMacaroon string
+	LoopTLSCert  string
+}
+
+// DefaultConfig uses the public Boltz API and a local loopd
+func DefaultConfig() Config {
+	return Config{
+		BoltzURL:     DefaultBoltzURL,
+		LoopURL:      DefaultLoopURL,
+		LoopMacaroon: filepath.Join(DefaultLoopDir(), "loop.macaroon"),
+		LoopTLSCert:  filepath.Join(DefaultLoopDir(), "tls.cert"),
+	}
+}
+
+// Providers creates the enabled providers. A provider that cannot be set up,
+// e.g. Loop without a readable macaroon, is left out and its error returned
+// so the caller can report it.
+func (c Config) Providers() ([]Provider, []error) {
+	var providers []Provider
+	var errs []error
+	if c.BoltzURL != "" {
+		providers = append(providers, NewBoltzClient(c.BoltzURL))
+	}
+	if c.LoopURL != "" {
+		loop, err := NewLoopClient(c.LoopURL, c.LoopMacaroon, c.LoopTLSCert)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("loop quotes disabled: %w", err))
+		} else {
+			providers = append(providers, loop)
+		}
+	}
+	return providers, errs
+}
@@ -0,0 +1,143 @@
+package swap
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+type fakeProvider struct {
+	name string
+	fee  int64
+	err  error
+}
+
+func (p fakeProvider) Name() string { return p.name }
+
+func (p fakeProvider) Quote(direction string, amount int64) (*Quote, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return newQuote(p.name, direction, amount, p.fee, 0), nil
+}
+
+func TestCompare(t *testing.T) {
+	comparison, err := Compare([]Provider{
+		fakeProvider{name: "down", err: fmt.Errorf("connection refused")},
+		fakeProvider{name: "pricey", fee: 4000},
+		fakeProvider{name: "cheap", fee: 1500},
+	}, DirectionOut, 1000000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, comparison.Recommended.Provider, "cheap")
+	testutils.AssertEqual(t, comparison.Recommended.FeePPM, int64(1500))
+	testutils.AssertEqual(t, comparison.Quotes[1].Provider, "pricey")
+	testutils.AssertEqual(t, comparison.Quotes[2].Error, "connection refused")
+
+	comparison, err = Compare([]Provider{fakeProvider{name: "down", err: fmt.Errorf("timeout")}}, DirectionIn, 1000)
+	testutils.AssertNoError(t, err)
+	if comparison.Recommended != nil {
+		t.Errorf("Expected no recommendation when every quote failed, got %+v", comparison.Recommended)
+	}
+
+	_, err = Compare(nil, "sideways", 1000)
+	testutils.AssertError(t, err, "invalid direction")
+}
+
+func TestShiftForChannel(t *testing.T) {
+	direction, amount, err := ShiftForChannel(900000, 1000000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, direction, DirectionOut)
+	testutils.AssertEqual(t, amount, int64(400000))
+
+	direction, amount, err = ShiftForChannel(100000, 1000000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, direction, DirectionIn)
+	testutils.AssertEqual(t, amount, int64(400000))
+
+	_, _, err = ShiftForChannel(500000, 1000000)
+	testutils.AssertError(t, err, "already balanced")
+}
+
+func TestBoltzQuote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/swap/reverse":
+			w.Write([]byte(`{"BTC": {"BTC": {"limits": {"minimal": 25000, "maximal": 25000000},
+				"fees": {"percentage": 0.5, "minerFees": {"lockup": 462, "claim": 333}}}}}`))
+		case "/v2/swap/submarine":
+			w.Write([]byte(`{"BTC": {"BTC": {"limits": {"minimal": 25000, "maximal": 25000000},
+				"fees": {"percentage": 0.1, "minerFees": 201}}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewBoltzClient(server.URL + "/v2/")
+	quote, err := client.Quote(DirectionOut, 1000000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, quote.ServiceFee, int64(5000))
+	testutils.AssertEqual(t, quote.MinerFee, int64(795))
+	testutils.AssertEqual(t, quote.TotalFee, int64(5795))
+
+	quote, err = client.Quote(DirectionIn, 1000001)
+	testutils.AssertNoError(t, err)
+	// Percentage fees round up to a whole sat
+	testutils.AssertEqual(t, quote.ServiceFee, int64(1001))
+	testutils.AssertEqual(t, quote.TotalFee, int64(1202))
+
+	_, err = client.Quote(DirectionOut, 1000)
+	testutils.AssertError(t, err, "outside the limits")
+}
+
+func TestLoopQuote(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Grpc-Metadata-macaroon") != "6d6163" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": 2, "message": "permission denied"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/loop/out/quote/500000":
+			w.Write([]byte(`{"swap_fee_sat": "1250", "prepay_amt_sat": "1337", "htlc_sweep_fee_sat": "420"}`))
+		case "/v1/loop/in/quote/500000":
+			w.Write([]byte(`{"swap_fee_sat": "900", "htlc_publish_fee_sat": "1100"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"code": 2, "message": "amount too low"}`))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	macaroonPath := filepath.Join(dir, "loop.macaroon")
+	certPath := filepath.Join(dir, "tls.cert")
+	testutils.AssertNoError(t, os.WriteFile(macaroonPath, []byte("mac"), 0600))
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	testutils.AssertNoError(t, os.WriteFile(certPath, cert, 0600))
+
+	client, err := NewLoopClient(server.URL, macaroonPath, certPath)
+	testutils.AssertNoError(t, err)
+
+	quote, err := client.Quote(DirectionOut, 500000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, quote.TotalFee, int64(1670))
+	testutils.AssertEqual(t, quote.FeePPM, int64(3340))
+
+	// Our own lockup transaction is left out of loop in quotes
+	quote, err = client.Quote(DirectionIn, 500000)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, quote.TotalFee, int64(900))
+
+	_, err = client.Quote(DirectionOut, 10)
+	testutils.AssertError(t, err, "loop: amount too low")
+
+	_, err = NewLoopClient(server.URL, filepath.Join(dir, "missing"), certPath)
+	testutils.AssertError(t, err, "failed to read loop macaroon")
+}
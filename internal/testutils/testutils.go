@@ -7,7 +7,7 @@ import (
 )
 
 // CreateTestDBPath creates a temporary SQLite database file path for testing
-func CreateTestDBPath(t *testing.T) string {
+func CreateTestDBPath(t testing.TB) string {
 	t.Helper()
 
 	// Create temporary directory
@@ -16,7 +16,7 @@ func CreateTestDBPath(t *testing.T) string {
 }
 
 // AssertNoError is a helper to check for no error
-func AssertNoError(t *testing.T, err error) {
+func AssertNoError(t testing.TB, err error) {
 	t.Helper()
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -24,7 +24,7 @@ func AssertNoError(t *testing.T, err error) {
 }
 
 // AssertError is a helper to check for expected error
-func AssertError(t *testing.T, err error, expectedMsg string) {
+func AssertError(t testing.TB, err error, expectedMsg string) {
 	t.Helper()
 	if err == nil {
 		t.Fatalf("Expected error containing '%s', got nil", expectedMsg)
@@ -33,7 +33,7 @@ func AssertError(t *testing.T, err error, expectedMsg string) {
 }
 
 // AssertEqual checks if two values are equal
-func AssertEqual(t *testing.T, got, want interface{}) {
+func AssertEqual(t testing.TB, got, want interface{}) {
 	t.Helper()
 	if got != want {
 		t.Errorf("got %v, want %v", got, want)
@@ -41,7 +41,7 @@ func AssertEqual(t *testing.T, got, want interface{}) {
 }
 
 // AssertNotEqual checks if two values are not equal
-func AssertNotEqual(t *testing.T, got, notWant interface{}) {
+func AssertNotEqual(t testing.TB, got, notWant interface{}) {
 	t.Helper()
 	if got == notWant {
 		t.Errorf("got %v, expected it to be different", got)
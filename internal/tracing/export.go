@@ -0,0 +1,249 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ExportInterval is how often finished spans are sent to the collector
+const ExportInterval = 5 * time.Second
+
+const (
+	// exportBatch spans queued send them before the interval is up
+	exportBatch = 512
+	// maxQueued spans are kept while the collector is unreachable; later
+	// ones are dropped
+	maxQueued = 8192
+)
+
+// Tracer exports the spans of one service
+type Tracer struct {
+	endpoint string
+	service  string
+	ratio    float64
+	client   *http.Client
+
+	mu      sync.Mutex
+	queued  []*Span
+	dropped int
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New returns a tracer exporting to the OTLP/HTTP collector at endpoint,
+// e.g. http://localhost:4318, as service. ratio is the share of requests
+// traced, from 0 to 1. Close flushes the spans still queued.
+func New(endpoint, service string, ratio float64) (*Tracer, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: must be http(s)://host:port", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("sample ratio %g is not between 0 and 1", ratio)
+	}
+
+	t := &Tracer{
+		endpoint: u.String(),
+		service:  service,
+		ratio:    ratio,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t, nil
+}
+
+// Endpoint returns the URL spans are posted to
+func (t *Tracer) Endpoint() string {
+	return t.endpoint
+}
+
+// Close exports the queued spans and stops the tracer
+func (t *Tracer) Close() {
+	close(t.stop)
+	<-t.done
+}
+
+func (t *Tracer) queue(span *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queued) >= maxQueued {
+		t.dropped++
+		return
+	}
+	t.queued = append(t.queued, span)
+	if len(t.queued) == exportBatch {
+		select {
+		case t.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(ExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.wake:
+		case <-t.stop:
+			t.export()
+			return
+		}
+		t.export()
+	}
+}
+
+// export sends the queued spans, keeping them for the next try when the
+// collector cannot be reached
+func (t *Tracer) export() {
+	t.mu.Lock()
+	spans, dropped := t.queued, t.dropped
+	t.queued, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("⚠️  Tracing: dropped %d spans, the collector at %s is not keeping up", dropped, t.endpoint)
+	}
+	if len(spans) == 0 {
+		return
+	}
+
+	if err := t.post(spans); err != nil {
+		log.Printf("⚠️  Tracing: failed to export %d spans: %v", len(spans), err)
+		t.mu.Lock()
+		if room := maxQueued - len(t.queued); room > 0 {
+			t.queued = append(spans[:min(len(spans), room)], t.queued...)
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *Tracer) post(spans []*Span) error {
+	body, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector answered %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP/HTTP JSON encoding of an ExportTraceServiceRequest. IDs are hex and
+// 64-bit integers strings, as the OTLP JSON mapping requires.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []otlpAttr `json:"attributes,omitempty"`
+		Status            otlpStatus `json:"status"`
+	}
+	otlpStatus struct {
+		// Code is 0 unset, 1 ok or 2 error
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttr struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+)
+
+// scopeName identifies the spans recorded by this package
+const scopeName = "github.com/brewgator/lightning-node-tools/internal/tracing"
+
+func (t *Tracer) request(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        encodeAttrs(s.attrs),
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != "" {
+			span.Status = otlpStatus{Code: 2, Message: s.err}
+		}
+		encoded = append(encoded, span)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttrs([]Attr{String("service.name", t.service)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: encoded}},
+	}}}
+}
+
+func encodeAttrs(attrs []Attr) []otlpAttr {
+	encoded := make([]otlpAttr, 0, len(attrs))
+	for _, attr := range attrs {
+		var value otlpValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		encoded = append(encoded, otlpAttr{Key: attr.Key, Value: value})
+	}
+	return encoded
+}
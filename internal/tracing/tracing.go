@@ -0,0 +1,252 @@
+// Package tracing records where API requests spend their time as
+// OpenTelemetry spans, exported over OTLP/HTTP in its JSON encoding to a
+// collector such as Jaeger or Grafana Tempo.
+//
+// Each sampled request gets a server span. Work done on its behalf, such as
+// database queries and lncli and bitcoin-cli calls, adds child spans when it
+// is given the request's context; work without a traced context, e.g. the
+// collectors', records nothing. Requests carrying a W3C traceparent header
+// continue the caller's trace.
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Span kinds, numbered as in OTLP
+const (
+	kindInternal = 1
+	kindServer   = 2
+	kindClient   = 3
+)
+
+// HeaderTraceID tells clients which trace their request was recorded in
+const HeaderTraceID = "X-Trace-Id"
+
+// Attr is a span attribute, a string, int64 or bool
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute
+func String(key, value string) Attr { return Attr{Key: key, Value: value} }
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attr { return Attr{Key: key, Value: value} }
+
+// Span is an operation within a trace. A nil *Span is valid and records
+// nothing, which is what Start returns outside a traced request.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    []Attr
+	err      string
+}
+
+type contextKey struct{}
+
+// FromContext returns the span ctx carries, nil if none
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(contextKey{}).(*Span)
+	return span
+}
+
+// Start begins a span within the span of ctx. Without one it records
+// nothing and returns ctx and a nil span.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return start(ctx, kindInternal, name, attrs)
+}
+
+// StartClient begins a span for a call to another system, e.g. a database
+// query or an lncli command, within the span of ctx
+func StartClient(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return start(ctx, kindClient, name, attrs)
+}
+
+func start(ctx context.Context, kind int, name string, attrs []Attr) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		tracer:   parent.tracer,
+		traceID:  parent.traceID,
+		spanID:   newSpanID(),
+		parentID: parent.spanID,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+		attrs:    attrs,
+	}
+	return context.WithValue(ctx, contextKey{}, span), span
+}
+
+// TraceID returns the hex ID of the span's trace
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SetName renames the span, e.g. once a request's route is known
+func (s *Span) SetName(name string) {
+	if s != nil {
+		s.name = name
+	}
+}
+
+// SetAttr adds attributes to the span
+func (s *Span) SetAttr(attrs ...Attr) {
+	if s != nil {
+		s.attrs = append(s.attrs, attrs...)
+	}
+}
+
+// End finishes the span, marking it failed when err is not nil, and queues
+// it for export. Only the first call counts.
+func (s *Span) End(err error) {
+	if s == nil || !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.tracer.queue(s)
+}
+
+// Handler starts a server span for every sampled request to next. Requests
+// with a traceparent header are sampled as their caller decided, others at
+// the tracer's ratio.
+func (t *Tracer) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID, parentID, sampled, ok := parseTraceparent(r.Header.Get("traceparent"))
+		if !ok {
+			traceID = newTraceID()
+			sampled = t.ratio >= 1 || mathrand.Float64() < t.ratio
+		}
+		if !sampled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		span := &Span{
+			tracer:   t,
+			traceID:  traceID,
+			spanID:   newSpanID(),
+			parentID: parentID,
+			name:     r.Method,
+			kind:     kindServer,
+			start:    time.Now(),
+			attrs:    []Attr{String("http.request.method", r.Method), String("url.path", r.URL.Path)},
+		}
+		w.Header().Set(HeaderTraceID, span.TraceID())
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			// A handler that panics, e.g. to abort a stream, failed
+			status := recorder.status
+			p := recover()
+			if p != nil {
+				status = http.StatusInternalServerError
+			}
+			span.SetAttr(Int("http.response.status_code", int64(status)))
+			var err error
+			if status >= http.StatusInternalServerError {
+				err = fmt.Errorf("%d %s", status, http.StatusText(status))
+			}
+			span.End(err)
+			if p != nil {
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), contextKey{}, span)))
+	})
+}
+
+// Route names the span of every request to next after the route name
+// returns for it, e.g. "GET /api/v1/portfolio/history", so a route's traces
+// can be found whatever IDs its requests carry
+func Route(route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if span := FromContext(r.Context()); span != nil {
+				template := route(r)
+				span.SetName(r.Method + " " + template)
+				span.SetAttr(String("http.route", template))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// parseTraceparent reads a W3C traceparent header,
+// 00-<trace id>-<parent span id>-<flags>
+func parseTraceparent(header string) (traceID [16]byte, parentID [8]byte, sampled, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return traceID, parentID, false, false
+	}
+	if n, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || n != len(traceID) || len(parts[1]) != 32 {
+		return traceID, parentID, false, false
+	}
+	if n, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || n != len(parentID) || len(parts[2]) != 16 {
+		return traceID, parentID, false, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || traceID == [16]byte{} || parentID == [8]byte{} {
+		return traceID, parentID, false, false
+	}
+	return traceID, parentID, flags[0]&1 == 1, true
+}
+
+func newTraceID() (id [16]byte) {
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() (id [8]byte) {
+	rand.Read(id[:])
+	return id
+}
+
+// statusRecorder keeps the status code a handler writes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Hijack hands the connection over to WebSocket handlers
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(s.ResponseWriter).Hijack()
+	if err == nil {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
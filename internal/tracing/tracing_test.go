@@ -0,0 +1,172 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+// collector is an OTLP/HTTP endpoint keeping the spans posted to it
+type collector struct {
+	mu       sync.Mutex
+	service  string
+	spans    []otlpSpan
+	requests int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request otlpRequest
+	if r.URL.Path != "/v1/traces" || json.NewDecoder(r.Body).Decode(&request) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+	for _, resource := range request.ResourceSpans {
+		c.service = *resource.Resource.Attributes[0].Value.StringValue
+		for _, scope := range resource.ScopeSpans {
+			c.spans = append(c.spans, scope.Spans...)
+		}
+	}
+}
+
+func (c *collector) span(name string) *otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.spans {
+		if c.spans[i].Name == name {
+			return &c.spans[i]
+		}
+	}
+	return nil
+}
+
+func newTestTracer(t *testing.T, ratio float64) (*Tracer, *collector) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	t.Cleanup(server.Close)
+	tracer, err := New(server.URL, "test-api", ratio)
+	testutils.AssertNoError(t, err)
+	return tracer, c
+}
+
+func TestHandler(t *testing.T) {
+	tracer, c := newTestTracer(t, 1)
+	handler := tracer.Handler(Route(func(*http.Request) string { return "/api/v1/things/{id}" })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := Start(r.Context(), "load thing", String("thing", "7"))
+			_, call := StartClient(ctx, "lncli getinfo")
+			call.End(errors.New("lncli command failed"))
+			span.End(nil)
+			w.WriteHeader(http.StatusNotFound)
+		})))
+
+	req := httptest.NewRequest("GET", "/api/v1/things/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+	testutils.AssertEqual(t, rr.Header().Get(HeaderTraceID), "4bf92f3577b34da6a3ce929d0e0e4736")
+	tracer.Close()
+
+	testutils.AssertEqual(t, c.service, "test-api")
+	server := c.span("GET /api/v1/things/{id}")
+	if server == nil {
+		t.Fatal("expected the request's span to be named after its route")
+	}
+	// The caller's trace is continued
+	testutils.AssertEqual(t, server.TraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	testutils.AssertEqual(t, server.ParentSpanID, "00f067aa0ba902b7")
+	testutils.AssertEqual(t, server.Kind, kindServer)
+	// Client errors do not fail the span
+	testutils.AssertEqual(t, server.Status.Code, 0)
+
+	load := c.span("load thing")
+	testutils.AssertEqual(t, load.ParentSpanID, server.SpanID)
+	testutils.AssertEqual(t, *load.Attributes[0].Value.StringValue, "7")
+	call := c.span("lncli getinfo")
+	testutils.AssertEqual(t, call.ParentSpanID, load.SpanID)
+	testutils.AssertEqual(t, call.Kind, kindClient)
+	testutils.AssertEqual(t, call.Status.Code, 2)
+	testutils.AssertEqual(t, call.Status.Message, "lncli command failed")
+}
+
+func TestHandlerSampling(t *testing.T) {
+	tracer, c := newTestTracer(t, 0)
+	handler := tracer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "work")
+		span.End(nil)
+	}))
+
+	// Unsampled requests, and callers that did not sample theirs, record nothing
+	for _, traceparent := range []string{"", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("traceparent", traceparent)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		testutils.AssertEqual(t, rr.Header().Get(HeaderTraceID), "")
+	}
+	// A caller's sampled trace is recorded whatever the ratio
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	tracer.Close()
+	testutils.AssertEqual(t, len(c.spans), 2)
+}
+
+func TestStartWithoutTrace(t *testing.T) {
+	ctx, span := Start(context.Background(), "work")
+	testutils.AssertEqual(t, span == nil, true)
+	testutils.AssertEqual(t, FromContext(ctx) == nil, true)
+	// A nil span is safe to use
+	span.SetName("renamed")
+	span.SetAttr(Int("count", 1))
+	span.End(errors.New("failed"))
+	testutils.AssertEqual(t, span.TraceID(), "")
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header  string
+		sampled bool
+		ok      bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, true},
+		{"", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+	}
+	for _, tt := range tests {
+		_, _, sampled, ok := parseTraceparent(tt.header)
+		testutils.AssertEqual(t, ok, tt.ok)
+		testutils.AssertEqual(t, sampled, tt.sampled)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tracer, err := New("http://localhost:4318", "api", 0.5)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, tracer.Endpoint(), "http://localhost:4318/v1/traces")
+	tracer.Close()
+
+	tracer, err = New("https://tempo.example.com/otlp/v1/traces", "api", 1)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, tracer.Endpoint(), "https://tempo.example.com/otlp/v1/traces")
+	tracer.Close()
+
+	_, err = New("localhost:4318", "api", 1)
+	testutils.AssertError(t, err, "invalid OTLP endpoint")
+	_, err = New("http://localhost:4318", "api", 2)
+	testutils.AssertError(t, err, "not between 0 and 1")
+}
@@ -0,0 +1,145 @@
+// Package updater checks GitHub for new releases of lightning-node-tools, so
+// a node box running unattended can tell its owner when to update.
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/version"
+)
+
+// DefaultReleasesURL is the GitHub API endpoint for the latest release
+const DefaultReleasesURL = "https://api.github.com/repos/brewgator/lightning-node-tools/releases/latest"
+
+// MaxHighlights is how many changelog lines an update notification quotes
+const MaxHighlights = 5
+
+// Release is a published GitHub release
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"` // Changelog, in markdown
+	URL         string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Client fetches releases from the GitHub API
+type Client struct {
+	releasesURL string
+	httpClient  *http.Client
+}
+
+// NewClient creates a client for releasesURL, DefaultReleasesURL when empty
+func NewClient(releasesURL string) *Client {
+	if releasesURL == "" {
+		releasesURL = DefaultReleasesURL
+	}
+
+	return &Client{
+		releasesURL: releasesURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// LatestRelease returns the newest published release. Drafts and
+// prereleases are never returned by GitHub's latest release endpoint.
+func (c *Client) LatestRelease() (*Release, error) {
+	req, err := http.NewRequest("GET", c.releasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "lightning-node-tools/"+version.Version)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GitHub API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+
+	return &release, nil
+}
+
+// IsNewer reports whether release is a later version than running, the
+// version the binary was built as (see version.Version)
+func (r *Release) IsNewer(running string) (bool, error) {
+	latest, err := version.ParseSemver(r.TagName)
+	if err != nil {
+		return false, fmt.Errorf("invalid release tag: %w", err)
+	}
+	current, err := version.ParseSemver(running)
+	if err != nil {
+		return false, fmt.Errorf("running version is not a release: %w", err)
+	}
+	return latest.AtLeast(current) && latest != current, nil
+}
+
+// listItemPattern matches a markdown bullet or numbered list item
+var listItemPattern = regexp.MustCompile(`^\s*(?:[-*+]|\d+\.)\s+(.+)$`)
+
+// Highlights returns up to max top-level items of a markdown changelog,
+// with emphasis and links reduced to plain text
+func Highlights(changelog string, max int) []string {
+	var highlights []string
+	for _, line := range strings.Split(changelog, "\n") {
+		if len(highlights) == max {
+			break
+		}
+		// Nested items are details of the item above
+		if strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		match := listItemPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		highlights = append(highlights, plainText(match[1]))
+	}
+	return highlights
+}
+
+var markdownLink = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+
+// plainText strips the markdown that reads badly in a chat message
+func plainText(s string) string {
+	s = markdownLink.ReplaceAllString(s, "$1")
+	s = strings.NewReplacer("**", "", "__", "", "`", "").Replace(s)
+	return strings.TrimSpace(s)
+}
+
+// Message formats a notification that release is available
+func (r *Release) Message(running string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🆕 <b>lightning-node-tools %s available</b>\nRunning: %s", r.TagName, running)
+	if highlights := Highlights(r.Body, MaxHighlights); len(highlights) > 0 {
+		b.WriteString("\n\n<b>Highlights:</b>")
+		for _, highlight := range highlights {
+			fmt.Fprintf(&b, "\n• %s", highlight)
+		}
+	}
+	if r.URL != "" {
+		fmt.Fprintf(&b, "\n\n%s", r.URL)
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+const changelog = `## What's Changed
+- **Payment prober** with reliability alerts
+  - Runs every 30 minutes
+* Fee ppm histograms per channel ([#42](https://github.com/brewgator/lightning-node-tools/pull/42))
+1. ` + "`--startup-wait`" + ` for collectors
+
+Full changelog: v1.2.0...v1.3.0`
+
+func TestLatestRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutils.AssertEqual(t, r.Header.Get("Accept"), "application/vnd.github+json")
+		w.Write([]byte(`{"tag_name": "v1.3.0", "name": "v1.3.0", "html_url": "https://example.com/v1.3.0",
+			"body": "- Payment prober", "published_at": "2026-10-01T12:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	release, err := NewClient(server.URL).LatestRelease()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, release.TagName, "v1.3.0")
+	testutils.AssertEqual(t, release.URL, "https://example.com/v1.3.0")
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	_, err = NewClient(notFound.URL).LatestRelease()
+	testutils.AssertError(t, err, "GitHub API error 404")
+}
+
+func TestReleaseIsNewer(t *testing.T) {
+	release := &Release{TagName: "v1.3.0"}
+
+	tests := []struct {
+		running string
+		want    bool
+	}{
+		{"v1.2.0", true},
+		{"v1.2.0-7-gabc1234", true},
+		{"v1.3.0", false},
+		{"v1.3.0-2-gdef5678-dirty", false},
+		{"v1.4.0", false},
+	}
+	for _, tt := range tests {
+		newer, err := release.IsNewer(tt.running)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, newer, tt.want)
+	}
+
+	_, err := release.IsNewer("dev")
+	testutils.AssertError(t, err, "running version is not a release")
+}
+
+func TestHighlights(t *testing.T) {
+	highlights := Highlights(changelog, MaxHighlights)
+	testutils.AssertEqual(t, len(highlights), 3)
+	testutils.AssertEqual(t, highlights[0], "Payment prober with reliability alerts")
+	testutils.AssertEqual(t, highlights[1], "Fee ppm histograms per channel (#42)")
+	testutils.AssertEqual(t, highlights[2], "--startup-wait for collectors")
+
+	testutils.AssertEqual(t, len(Highlights(changelog, 1)), 1)
+}
+
+func TestReleaseMessage(t *testing.T) {
+	release := &Release{TagName: "v1.3.0", Body: changelog, URL: "https://example.com/v1.3.0"}
+	message := release.Message("v1.2.0")
+
+	for _, want := range []string{"v1.3.0 available", "Running: v1.2.0", "• Payment prober", "https://example.com/v1.3.0"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("expected message to contain %q, got %q", want, message)
+		}
+	}
+}
@@ -2,11 +2,24 @@ package utils
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+const (
+	// SatsPerBTC is the number of satoshis in one bitcoin
+	SatsPerBTC = 100000000
+	// MaxSupplySats is the maximum number of satoshis that can ever exist
+	MaxSupplySats = 21000000 * SatsPerBTC
+
+	// UnitSats and UnitBTC are the supported amount units
+	UnitSats = "sats"
+	UnitBTC  = "BTC"
+)
+
 // FormatSats formats satoshi amounts in a human-readable way
 // Uses the more precise format suitable for both tools
 func FormatSats(amount int64) string {
@@ -39,6 +52,108 @@ func FormatSatsCompact(amount int64) string {
 	return fmt.Sprintf("%d", amount)
 }
 
+// FormatBTC formats a satoshi amount as an exact BTC decimal string with 8 places
+func FormatBTC(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, amount/SatsPerBTC, amount%SatsPerBTC)
+}
+
+// NormalizeUnit maps user supplied unit names to UnitSats or UnitBTC.
+// An empty unit defaults to sats.
+func NormalizeUnit(unit string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "", "sat", "sats", "satoshi", "satoshis":
+		return UnitSats, nil
+	case "btc":
+		return UnitBTC, nil
+	}
+	return "", fmt.Errorf("unsupported unit %q, must be sats or BTC", unit)
+}
+
+// ParseAmountToSats converts a decimal amount string in the given unit to satoshis.
+// Parsing is exact (no floating point): sats must be whole numbers and BTC may have
+// at most 8 decimal places. Negative amounts and amounts above the 21M BTC supply
+// are rejected, which catches most sats/BTC mix-ups.
+func ParseAmountToSats(amount, unit string) (int64, error) {
+	normalized, err := NormalizeUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+
+	amount = strings.ReplaceAll(strings.TrimSpace(amount), "_", "")
+	if amount == "" {
+		return 0, errors.New("amount is required")
+	}
+	if strings.HasPrefix(amount, "-") {
+		return 0, errors.New("amount cannot be negative")
+	}
+
+	whole, frac, hasFrac := strings.Cut(amount, ".")
+	// strconv accepts a sign, which must not slip in after the decimal point
+	if !isDigits(whole) || !isDigits(frac) || whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid %s amount %q", normalized, amount)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	var sats int64
+	switch normalized {
+	case UnitSats:
+		if hasFrac {
+			return 0, errors.New("sats amounts must be whole numbers, use BTC for decimal amounts")
+		}
+		sats, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid sats amount %q", amount)
+		}
+	case UnitBTC:
+		if len(frac) > 8 {
+			return 0, errors.New("BTC amounts cannot have more than 8 decimal places")
+		}
+		btc, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil || btc > MaxSupplySats/SatsPerBTC {
+			return 0, fmt.Errorf("invalid BTC amount %q", amount)
+		}
+		var fracSats int64
+		if frac != "" {
+			fracSats, err = strconv.ParseInt(frac+strings.Repeat("0", 8-len(frac)), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid BTC amount %q", amount)
+			}
+		}
+		sats = btc*SatsPerBTC + fracSats
+	}
+
+	if sats > MaxSupplySats {
+		return 0, fmt.Errorf("amount exceeds the 21M BTC supply, check the unit (%s)", normalized)
+	}
+
+	return sats, nil
+}
+
+// isDigits reports whether s holds nothing but ASCII digits
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatAmount formats a satoshi amount in the given unit without rounding
+func FormatAmount(amount int64, unit string) string {
+	if unit == UnitBTC {
+		return FormatBTC(amount) + " BTC"
+	}
+	return fmt.Sprintf("%d sats", amount)
+}
+
 // Base58 alphabet used in Bitcoin addresses
 const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
 
@@ -63,6 +178,20 @@ func ValidateBitcoinAddress(address string) bool {
 	return false
 }
 
+// ValidateNodePubkey validates a Lightning node pubkey: a 33-byte compressed
+// public key in lowercase hex
+func ValidateNodePubkey(pubkey string) bool {
+	if len(pubkey) != 66 || (pubkey[:2] != "02" && pubkey[:2] != "03") {
+		return false
+	}
+	for _, c := range pubkey {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
 // validateBech32Address validates Bech32 encoded addresses (SegWit and Taproot)
 func validateBech32Address(address string) bool {
 	// Basic length and format checks
@@ -202,7 +331,7 @@ func ValidateXPub(xpub string) bool {
 	}
 
 	// Check prefix
-	validPrefixes := []string{"xpub", "ypub", "zpub", "tpub", "upub", "vpub"}
+	validPrefixes := []string{"xpub", "ypub", "zpub", "tpub", "upub", "vpub", "Ypub", "Zpub", "Upub", "Vpub"}
 	validPrefix := false
 	for _, prefix := range validPrefixes {
 		if strings.HasPrefix(xpub, prefix) {
@@ -233,14 +362,14 @@ func ValidateXPub(xpub string) bool {
 		return false
 	}
 
-	// Extended keys should be 78 bytes (74 payload + 4 checksum)
-	if len(decoded) != 78 {
+	// Extended keys should be 82 bytes (78 payload + 4 checksum)
+	if len(decoded) != 82 {
 		return false
 	}
 
 	// Verify checksum
-	payload := decoded[:74]
-	checksum := decoded[74:]
+	payload := decoded[:78]
+	checksum := decoded[78:]
 
 	hash1 := sha256.Sum256(payload)
 	hash2 := sha256.Sum256(hash1[:])
@@ -253,3 +382,116 @@ func ValidateXPub(xpub string) bool {
 
 	return true
 }
+
+// Script types implied by extended public key prefixes. The values match the
+// Bitcoin Core descriptor function names.
+const (
+	ScriptTypeP2PKH      = "pkh"
+	ScriptTypeP2SHP2WPKH = "sh(wpkh)"
+	ScriptTypeP2WPKH     = "wpkh"
+	// Multisig cosigner keys
+	ScriptTypeP2SHP2WSH = "sh(wsh)"
+	ScriptTypeP2WSH     = "wsh"
+)
+
+// Extended public key version bytes accepted by Bitcoin Core descriptors
+var (
+	xpubVersion = [4]byte{0x04, 0x88, 0xb2, 0x1e}
+	tpubVersion = [4]byte{0x04, 0x35, 0x87, 0xcf}
+)
+
+// xpubPrefixes maps SLIP-132 prefixes to the version bytes Bitcoin Core
+// accepts and the script type the prefix implies
+var xpubPrefixes = map[string]struct {
+	version    [4]byte
+	scriptType string
+}{
+	"xpub": {xpubVersion, ScriptTypeP2PKH},
+	"ypub": {xpubVersion, ScriptTypeP2SHP2WPKH},
+	"zpub": {xpubVersion, ScriptTypeP2WPKH},
+	"tpub": {tpubVersion, ScriptTypeP2PKH},
+	"upub": {tpubVersion, ScriptTypeP2SHP2WPKH},
+	"vpub": {tpubVersion, ScriptTypeP2WPKH},
+	"Ypub": {xpubVersion, ScriptTypeP2SHP2WSH},
+	"Zpub": {xpubVersion, ScriptTypeP2WSH},
+	"Upub": {tpubVersion, ScriptTypeP2SHP2WSH},
+	"Vpub": {tpubVersion, ScriptTypeP2WSH},
+}
+
+// NormalizeXPub converts an extended public key to the xpub (or tpub) form
+// used in descriptors and reports the script type implied by its prefix.
+// Plain xpub keys report P2PKH since the prefix does not say otherwise.
+func NormalizeXPub(key string) (normalized, scriptType string, err error) {
+	if !ValidateXPub(key) {
+		return "", "", errors.New("invalid extended public key")
+	}
+
+	prefix, ok := xpubPrefixes[key[:4]]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported extended key prefix %q", key[:4])
+	}
+
+	decoded, err := base58Decode(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	payload := append(prefix.version[:], decoded[4:78]...)
+	hash1 := sha256.Sum256(payload)
+	hash2 := sha256.Sum256(hash1[:])
+
+	return base58Encode(append(payload, hash2[:4]...)), prefix.scriptType, nil
+}
+
+// DecodeXPub returns the 78 byte serialization of an extended public key,
+// with SLIP-132 version bytes replaced by the xpub (or tpub) ones
+func DecodeXPub(key string) ([]byte, error) {
+	normalized, _, err := NormalizeXPub(key)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := base58Decode(normalized)
+	if err != nil {
+		return nil, err
+	}
+	return decoded[:78], nil
+}
+
+// IsTestnetXPub reports whether a serialized extended public key, as
+// returned by DecodeXPub, is for testnet
+func IsTestnetXPub(payload []byte) bool {
+	return len(payload) >= 4 && [4]byte(payload[:4]) == tpubVersion
+}
+
+// base58Encode encodes bytes using the Bitcoin base58 alphabet
+func base58Encode(input []byte) string {
+	zeros := 0
+	for zeros < len(input) && input[zeros] == 0 {
+		zeros++
+	}
+
+	// Repeatedly divide the big-endian number by 58
+	digits := make([]byte, 0, len(input)*138/100+1)
+	for _, b := range input {
+		carry := int(b)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(zeros + len(digits))
+	for i := 0; i < zeros; i++ {
+		sb.WriteByte(base58Alphabet[0])
+	}
+	for i := len(digits) - 1; i >= 0; i-- {
+		sb.WriteByte(base58Alphabet[digits[i]])
+	}
+	return sb.String()
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+// Known-good keys from the BIP32 and BIP84 test vectors
+const (
+	testXPub = "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+	testZPub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+)
+
+// Fuzz targets run their seed corpus as part of go test; make test-fuzz
+// explores further
+
+func FuzzValidateBitcoinAddress(f *testing.F) {
+	for _, seed := range []string{
+		"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
+		"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+		"bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh",
+		"bc1p5d7rjq7g6rdk2yhzks9smlaqtedr4dekq08ge8ztwac72sfr9rusxg3297",
+		"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+		"1", "bc1", "11111111111111111111111111", "",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, address string) {
+		if !ValidateBitcoinAddress(address) {
+			return
+		}
+		// Anything accepted must be safe to pass on to bitcoin-cli
+		if strings.ContainsAny(address, "\x00;|&$`\n\r<>(){}[] ") {
+			t.Errorf("accepted address %q contains shell metacharacters", address)
+		}
+		if len(address) > 90 {
+			t.Errorf("accepted address %q is %d characters long", address, len(address))
+		}
+	})
+}
+
+func FuzzValidateNodePubkey(f *testing.F) {
+	f.Add("03864ef025fde8fb587d989186ce6a4a186895ee44a926bfc370e2c366597a3f8f")
+	f.Add("02")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, pubkey string) {
+		if ValidateNodePubkey(pubkey) && len(pubkey) != 66 {
+			t.Errorf("accepted pubkey %q of length %d", pubkey, len(pubkey))
+		}
+	})
+}
+
+func FuzzValidateXPub(f *testing.F) {
+	f.Add(testXPub)
+	f.Add(testZPub)
+	f.Add("xpub" + strings.Repeat("1", 107))
+	f.Add(strings.Repeat("z", 111))
+
+	f.Fuzz(func(t *testing.T, key string) {
+		if !ValidateXPub(key) {
+			if _, _, err := NormalizeXPub(key); err == nil {
+				t.Errorf("NormalizeXPub accepted %q, which ValidateXPub rejects", key)
+			}
+			return
+		}
+
+		normalized, scriptType, err := NormalizeXPub(key)
+		if err != nil {
+			t.Fatalf("NormalizeXPub(%q) failed for a valid key: %v", key, err)
+		}
+		if !ValidateXPub(normalized) {
+			t.Errorf("NormalizeXPub(%q) = %q, which is not a valid key", key, normalized)
+		}
+		if !strings.HasPrefix(normalized, "xpub") && !strings.HasPrefix(normalized, "tpub") {
+			t.Errorf("NormalizeXPub(%q) = %q, want an xpub or tpub", key, normalized)
+		}
+		if scriptType == "" {
+			t.Errorf("NormalizeXPub(%q) returned no script type", key)
+		}
+
+		// Normalizing is idempotent apart from the script type the prefix implied
+		again, _, err := NormalizeXPub(normalized)
+		if err != nil || again != normalized {
+			t.Errorf("NormalizeXPub(%q) = %q, %v; want it unchanged", normalized, again, err)
+		}
+	})
+}
+
+func TestNormalizeXPub(t *testing.T) {
+	normalized, scriptType, err := NormalizeXPub(testZPub)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, scriptType, ScriptTypeP2WPKH)
+	testutils.AssertEqual(t, normalized, "xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V")
+
+	_, _, err = NormalizeXPub("zpub")
+	testutils.AssertError(t, err, "invalid extended public key")
+}
+
+func TestMultisigXPub(t *testing.T) {
+	// The test xpub re-encoded with SLIP-132's Zpub version bytes
+	decoded, err := base58Decode(testXPub)
+	testutils.AssertNoError(t, err)
+	payload := append([]byte{0x02, 0xaa, 0x7e, 0xd3}, decoded[4:78]...)
+	hash1 := sha256.Sum256(payload)
+	hash2 := sha256.Sum256(hash1[:])
+	zpub := base58Encode(append(payload, hash2[:4]...))
+	testutils.AssertEqual(t, zpub[:4], "Zpub")
+
+	normalized, scriptType, err := NormalizeXPub(zpub)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, normalized, testXPub)
+	testutils.AssertEqual(t, scriptType, ScriptTypeP2WSH)
+
+	raw, err := DecodeXPub(zpub)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(raw), 78)
+	testutils.AssertEqual(t, IsTestnetXPub(raw), false)
+}
+
+func TestParseAmountToSats(t *testing.T) {
+	for _, tc := range []struct {
+		amount, unit string
+		want         int64
+	}{
+		{"1.5", "BTC", 150000000},
+		{"0.00000001", "btc", 1},
+		{".5", "BTC", 50000000},
+		{"2.", "BTC", 200000000},
+		{"1_000", "sats", 1000},
+		{" 21000000 ", "BTC", MaxSupplySats},
+	} {
+		got, err := ParseAmountToSats(tc.amount, tc.unit)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, got, tc.want)
+	}
+
+	for _, tc := range []struct{ amount, unit, want string }{
+		{"-1", "BTC", "cannot be negative"},
+		{"+1.5", "BTC", "invalid BTC amount"},
+		{"1.-5", "BTC", "invalid BTC amount"},
+		{"1.+5", "BTC", "invalid BTC amount"},
+		{"+100", "sats", "invalid sats amount"},
+		{".", "BTC", "invalid BTC amount"},
+		{"1e3", "sats", "invalid sats amount"},
+		{"1.5", "sats", "whole numbers"},
+		{"0.123456789", "BTC", "more than 8 decimal places"},
+		{"21000001", "BTC", "invalid BTC amount"},
+	} {
+		_, err := ParseAmountToSats(tc.amount, tc.unit)
+		testutils.AssertError(t, err, tc.want)
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultNumberLocale is the locale numbers are written in unless a user
+// picks another
+const DefaultNumberLocale = "en"
+
+// NumberLocale is how a locale writes numbers: the separator between groups
+// of three digits and the decimal separator
+type NumberLocale struct {
+	Tag     string
+	Group   string
+	Decimal string
+}
+
+// numberLocales are the supported locales by lowercase BCP 47 tag. French
+// groups with a narrow no-break space and Swedish and Polish with a no-break
+// space, so amounts never wrap across lines.
+var numberLocales = map[string]NumberLocale{
+	"en":    {Tag: "en", Group: ",", Decimal: "."},
+	"de":    {Tag: "de", Group: ".", Decimal: ","},
+	"de-ch": {Tag: "de-CH", Group: "'", Decimal: "."},
+	"es":    {Tag: "es", Group: ".", Decimal: ","},
+	"fr":    {Tag: "fr", Group: "\u202f", Decimal: ","},
+	"it":    {Tag: "it", Group: ".", Decimal: ","},
+	"nl":    {Tag: "nl", Group: ".", Decimal: ","},
+	"pl":    {Tag: "pl", Group: "\u00a0", Decimal: ","},
+	"pt":    {Tag: "pt", Group: ".", Decimal: ","},
+	"sv":    {Tag: "sv", Group: "\u00a0", Decimal: ","},
+}
+
+// NumberLocales returns the supported locale tags in alphabetical order
+func NumberLocales() []string {
+	tags := make([]string, 0, len(numberLocales))
+	for _, locale := range numberLocales {
+		tags = append(tags, locale.Tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// ParseNumberLocale returns the number format of a locale tag such as "de",
+// "de-CH" or "fr_FR". Tags without an entry of their own fall back to their
+// language, so "en-GB" writes numbers like "en".
+func ParseNumberLocale(tag string) (NumberLocale, error) {
+	normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(tag)), "_", "-")
+	if locale, ok := numberLocales[normalized]; ok {
+		return locale, nil
+	}
+	language, _, _ := strings.Cut(normalized, "-")
+	if locale, ok := numberLocales[language]; ok {
+		return locale, nil
+	}
+	return NumberLocale{}, fmt.Errorf("unsupported locale %q, must be one of %s", tag, strings.Join(NumberLocales(), ", "))
+}
+
+// FormatInt writes n with the locale's group separator, e.g. 1234567 as
+// "1.234.567" in "de"
+func (l NumberLocale) FormatInt(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	return sign + l.group(digits)
+}
+
+// FormatFloat writes v rounded to decimals places with the locale's
+// separators, e.g. 1234.5 with 2 decimals as "1.234,50" in "de"
+func (l NumberLocale) FormatFloat(v float64, decimals int) string {
+	formatted := strconv.FormatFloat(v, 'f', decimals, 64)
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(formatted, ".")
+	if !hasFrac {
+		return sign + l.group(whole)
+	}
+	return sign + l.group(whole) + l.Decimal + frac
+}
+
+// group inserts the group separator every three digits from the right
+func (l NumberLocale) group(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if i > 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestNumberLocale(t *testing.T) {
+	tests := []struct {
+		tag      string
+		n        int64
+		v        float64
+		decimals int
+		wantInt  string
+		wantFlt  string
+	}{
+		{"en", 1234567, 1234.5, 2, "1,234,567", "1,234.50"},
+		{"de", 1234567, 1234.5, 2, "1.234.567", "1.234,50"},
+		{"fr_FR", 1234567, 0.123, 8, "1\u202f234\u202f567", "0,12300000"},
+		{"de-CH", -1234567, -1234567.891, 2, "-1'234'567", "-1'234'567.89"},
+		{"en-GB", 999, 999, 0, "999", "999"},
+		{"sv", 100000, 100000, 0, "100\u00a0000", "100\u00a0000"},
+	}
+
+	for _, tt := range tests {
+		locale, err := ParseNumberLocale(tt.tag)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, locale.FormatInt(tt.n), tt.wantInt)
+		testutils.AssertEqual(t, locale.FormatFloat(tt.v, tt.decimals), tt.wantFlt)
+	}
+
+	_, err := ParseNumberLocale("xx")
+	testutils.AssertError(t, err, "unsupported locale")
+}
@@ -0,0 +1,68 @@
+package version
+
+import "fmt"
+
+// Nodes whose versions are tracked
+const (
+	NodeLND         = "lnd"
+	NodeBitcoinCore = "bitcoin_core"
+)
+
+// Feature is something that needs an RPC only newer node versions have
+type Feature struct {
+	Name       string
+	Node       string
+	MinVersion Semver
+}
+
+// Features gated on node versions
+var (
+	// MissionControl needs querymc to report amounts per node pair
+	MissionControl = Feature{Name: "mission-control", Node: NodeLND, MinVersion: Semver{0, 10, 0}}
+	// DescriptorWallet needs descriptor wallets and importdescriptors, which
+	// real-time address tracking is built on. Bitcoin Core 0.21 reports itself
+	// as version 210000, i.e. 21.0.0.
+	DescriptorWallet = Feature{Name: "descriptor-wallet", Node: NodeBitcoinCore, MinVersion: Semver{21, 0, 0}}
+)
+
+// Features lists every gated feature
+var Features = []Feature{MissionControl, DescriptorWallet}
+
+// Nodes holds the detected version of each node, keyed by node. A node
+// missing from it was not reachable or could not report its version.
+type Nodes map[string]Semver
+
+// Check returns an error explaining why feature is unavailable, or nil. An
+// undetected node version does not block a feature: the RPC is tried and
+// reports its own error.
+func (n Nodes) Check(feature Feature) error {
+	v, ok := n[feature.Node]
+	if !ok || v.AtLeast(feature.MinVersion) {
+		return nil
+	}
+	return fmt.Errorf("%s needs %s %s or newer, running %s", feature.Name, feature.Node, feature.MinVersion, v)
+}
+
+// Supported reports which features are available, keyed by name
+func (n Nodes) Supported() map[string]bool {
+	supported := make(map[string]bool, len(Features))
+	for _, feature := range Features {
+		supported[feature.Name] = n.Check(feature) == nil
+	}
+	return supported
+}
+
+// Detect asks node for its version with get and stores it in n. It returns
+// the version as the node reports it.
+func (n Nodes) Detect(node string, get func() (string, error)) (string, error) {
+	raw, err := get()
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s version: %w", node, err)
+	}
+	v, err := ParseSemver(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s version: %w", node, err)
+	}
+	n[node] = v
+	return raw, nil
+}
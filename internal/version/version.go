@@ -0,0 +1,94 @@
+// Package version reports the build of these tools and the versions of the
+// nodes they talk to, and gates features on node versions.
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+)
+
+// Set at build time, see LDFLAGS in the Makefile:
+//
+//	go build -ldflags "-X github.com/brewgator/lightning-node-tools/internal/version.Commit=abc1234"
+var (
+	Commit    string
+	BuildDate string
+	// Version is the release the binary was built from, as git describe
+	// reports it, e.g. v1.2.0 or v1.2.0-3-gabc1234 for commits after it
+	Version = "dev"
+)
+
+// GitCommit returns the commit the binary was built from: Commit when set
+// with -ldflags, else the revision the Go toolchain embeds when building
+// inside a git checkout, else "unknown". It never shells out to git, so it
+// works in containers.
+func GitCommit() string {
+	if Commit != "" {
+		return Commit
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "unknown"
+	}
+	if len(revision) > 7 {
+		revision = revision[:7]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// Semver is a major.minor.patch version
+type Semver struct {
+	Major, Minor, Patch int
+}
+
+func (v Semver) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is min or newer
+func (v Semver) AtLeast(min Semver) bool {
+	if v.Major != min.Major {
+		return v.Major > min.Major
+	}
+	if v.Minor != min.Minor {
+		return v.Minor > min.Minor
+	}
+	return v.Patch >= min.Patch
+}
+
+var semverPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseSemver reads the first version number in s, so decorated versions
+// such as LND's "0.17.0-beta commit=v0.17.0-beta" parse too
+func ParseSemver(s string) (Semver, error) {
+	match := semverPattern.FindStringSubmatch(s)
+	if match == nil {
+		return Semver{}, fmt.Errorf("no version number in %q", s)
+	}
+	var v Semver
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		v.Patch, _ = strconv.Atoi(match[3])
+	}
+	return v, nil
+}
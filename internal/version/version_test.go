@@ -0,0 +1,40 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Semver
+	}{
+		{"0.17.0-beta commit=v0.17.0-beta", Semver{0, 17, 0}},
+		{"v0.18.3-beta", Semver{0, 18, 3}},
+		{"/Satoshi:26.0/", Semver{26, 0, 0}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSemver(tt.input)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, got, tt.want)
+	}
+
+	_, err := ParseSemver("unknown")
+	testutils.AssertError(t, err, "no version number")
+}
+
+func TestNodesCheck(t *testing.T) {
+	nodes := Nodes{NodeLND: {0, 9, 2}, NodeBitcoinCore: {26, 0, 0}}
+
+	testutils.AssertError(t, nodes.Check(MissionControl), "needs lnd 0.10.0 or newer, running 0.9.2")
+	testutils.AssertNoError(t, nodes.Check(DescriptorWallet))
+
+	// Undetected versions do not block anything
+	testutils.AssertNoError(t, Nodes{}.Check(MissionControl))
+
+	supported := nodes.Supported()
+	testutils.AssertEqual(t, supported["mission-control"], false)
+	testutils.AssertEqual(t, supported["descriptor-wallet"], true)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// parseAllowedIPs parses a comma separated list of CIDRs and bare IPs, e.g.
+// "140.82.112.0/20,192.0.2.10". An empty list allows every source.
+func parseAllowedIPs(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range splitList(list) {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// sourceIP returns the address a request came from. Connections from a
+// reverse proxy on this machine (loopback or a unix socket) are attributed to
+// the last X-Forwarded-For entry, which is the peer the proxy saw; the header
+// is ignored on any other connection, where a client could forge it.
+func sourceIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+
+	if ip == nil || ip.IsLoopback() {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			if proxied := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); proxied != nil {
+				return proxied
+			}
+		}
+	}
+	return ip
+}
+
+// sourceName formats a request's source for logs
+func sourceName(r *http.Request) string {
+	if ip := sourceIP(r); ip != nil {
+		return ip.String()
+	}
+	return "local socket"
+}
+
+// allowedSource reports whether a request comes from an allowlisted network.
+// With no allowlist configured every source is allowed.
+func (d *Deployer) allowedSource(r *http.Request) bool {
+	if len(d.config.AllowedIPs) == 0 {
+		return true
+	}
+	ip := sourceIP(r)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range d.config.AllowedIPs {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validStatusAuth reports whether a request carries the --status-user
+// credentials. Both sides are hashed first so the comparison takes the same
+// time whatever their lengths.
+func (d *Deployer) validStatusAuth(r *http.Request) bool {
+	if d.config.StatusUser == "" {
+		return false
+	}
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	userHash, wantUserHash := sha256.Sum256([]byte(user)), sha256.Sum256([]byte(d.config.StatusUser))
+	passHash, wantPassHash := sha256.Sum256([]byte(password)), sha256.Sum256([]byte(d.config.StatusPassword))
+	return subtle.ConstantTimeCompare(userHash[:], wantUserHash[:]) == 1 &&
+		subtle.ConstantTimeCompare(passHash[:], wantPassHash[:]) == 1
+}
+
+// requireAllowedSource refuses requests from outside the allowlist
+func (d *Deployer) requireAllowedSource(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.allowedSource(r) {
+			log.Printf("🚫 Rejected %s %s from %s: not in --allow-ips", r.Method, r.URL.Path, sourceName(r))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireStatusAccess guards /status. With basic auth configured, valid
+// credentials are accepted from any source, so the status can be checked from
+// outside the allowlist; otherwise the allowlist alone applies.
+func (d *Deployer) requireStatusAccess(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.validStatusAuth(r) {
+			next(w, r)
+			return
+		}
+		if d.config.StatusUser != "" {
+			if len(d.config.AllowedIPs) == 0 || !d.allowedSource(r) {
+				log.Printf("🚫 Rejected %s %s from %s: missing or wrong credentials", r.Method, r.URL.Path, sourceName(r))
+				w.Header().Set("WWW-Authenticate", `Basic realm="webhook-deployer"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		d.requireAllowedSource(next)(w, r)
+	}
+}
+
+// statusRecorder keeps the status code written by a handler for logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// metricsNamespace prefixes the deployer's Prometheus metric names
+const metricsNamespace = "webhook_deployer"
+
+// routePattern names a request by the ServeMux pattern that served it, which
+// ServeMux records on the request once it has matched
+func routePattern(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	return r.Pattern
+}
+
+// logRequests logs every request with its source, status and duration
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		log.Printf("📨 %s %s from %s: %d in %v", r.Method, r.URL.Path, sourceName(r), recorder.status, time.Since(start).Round(time.Millisecond))
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseAllowedIPs(t *testing.T) {
+	nets, err := parseAllowedIPs(" 140.82.112.0/20, 192.0.2.10,2001:db8::/32,")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(nets), 3)
+	testutils.AssertEqual(t, nets[1].String(), "192.0.2.10/32")
+
+	nets, err = parseAllowedIPs("")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(nets), 0)
+
+	_, err = parseAllowedIPs("140.82.112.0/40")
+	testutils.AssertError(t, err, "invalid CIDR")
+	_, err = parseAllowedIPs("github.com")
+	testutils.AssertError(t, err, "invalid IP")
+}
+
+func TestSourceIP(t *testing.T) {
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", "140.82.115.1:4321", "", "140.82.115.1"},
+		{"forged header ignored", "203.0.113.5:4321", "140.82.115.1", "203.0.113.5"},
+		{"loopback proxy", "127.0.0.1:4321", "10.0.0.1, 140.82.115.1", "140.82.115.1"},
+		{"unix socket proxy", "@", "140.82.115.1", "140.82.115.1"},
+		{"unix socket without proxy", "@", "", "<nil>"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/webhook", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			testutils.AssertEqual(t, sourceIP(r).String(), tc.want)
+		})
+	}
+}
+
+func TestAccessControl(t *testing.T) {
+	allowed, err := parseAllowedIPs("140.82.112.0/20")
+	testutils.AssertNoError(t, err)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	request := func(handler http.HandlerFunc, remoteAddr, user, password string) int {
+		r := httptest.NewRequest("GET", "/status", nil)
+		r.RemoteAddr = remoteAddr
+		if user != "" {
+			r.SetBasicAuth(user, password)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, r)
+		return rr.Code
+	}
+
+	open := &Deployer{config: &Config{}}
+	testutils.AssertEqual(t, request(open.requireAllowedSource(ok), "203.0.113.5:1", "", ""), http.StatusOK)
+	testutils.AssertEqual(t, request(open.requireStatusAccess(ok), "203.0.113.5:1", "", ""), http.StatusOK)
+
+	listed := &Deployer{config: &Config{AllowedIPs: allowed}}
+	testutils.AssertEqual(t, request(listed.requireAllowedSource(ok), "140.82.115.1:1", "", ""), http.StatusOK)
+	testutils.AssertEqual(t, request(listed.requireAllowedSource(ok), "203.0.113.5:1", "", ""), http.StatusForbidden)
+	testutils.AssertEqual(t, request(listed.requireAllowedSource(ok), "@", "", ""), http.StatusForbidden)
+
+	authed := &Deployer{config: &Config{StatusUser: "ops", StatusPassword: "hunter2"}}
+	testutils.AssertEqual(t, request(authed.requireStatusAccess(ok), "203.0.113.5:1", "", ""), http.StatusUnauthorized)
+	testutils.AssertEqual(t, request(authed.requireStatusAccess(ok), "203.0.113.5:1", "ops", "wrong"), http.StatusUnauthorized)
+	testutils.AssertEqual(t, request(authed.requireStatusAccess(ok), "203.0.113.5:1", "ops", "hunter2"), http.StatusOK)
+
+	// Credentials are the fallback for sources outside the allowlist
+	both := &Deployer{config: &Config{AllowedIPs: allowed, StatusUser: "ops", StatusPassword: "hunter2"}}
+	testutils.AssertEqual(t, request(both.requireStatusAccess(ok), "140.82.115.1:1", "", ""), http.StatusOK)
+	testutils.AssertEqual(t, request(both.requireStatusAccess(ok), "203.0.113.5:1", "", ""), http.StatusUnauthorized)
+	testutils.AssertEqual(t, request(both.requireStatusAccess(ok), "203.0.113.5:1", "ops", "hunter2"), http.StatusOK)
+	testutils.AssertEqual(t, request(both.requireAllowedSource(ok), "203.0.113.5:1", "ops", "hunter2"), http.StatusForbidden)
+}
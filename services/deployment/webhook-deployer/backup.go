@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// commitID matches the commit IDs backups are labelled with
+var commitID = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// backupDatabases backs up every --backup-db before the new version's
+// services start and migrate them. It returns the backups as
+// DEPLOY_DB_BACKUPS lines, <database>=<backup>, which the deploy script
+// restores when it rolls back.
+func (d *Deployer) backupDatabases(payload WebhookPayload) (string, error) {
+	label := shortCommit(payload.HeadCommit.ID)
+	if !commitID.MatchString(label) {
+		label = ""
+	}
+
+	var lines []string
+	for _, path := range d.config.BackupDBs {
+		// Relative to the repository, which runScript changed into
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		backup, err := db.BackupBeforeMigration(path, label, d.config.BackupKeep, time.Now())
+		if err != nil {
+			return "", fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		if backup == "" {
+			log.Printf("ℹ️  No database at %s to back up", path)
+			continue
+		}
+		log.Printf("💾 Backed up %s to %s", path, backup)
+		lines = append(lines, path+"="+backup)
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestBackupDatabases(t *testing.T) {
+	repo := t.TempDir()
+	t.Chdir(repo)
+	database, err := db.NewDatabase(filepath.Join(repo, "portfolio.db"))
+	testutils.AssertNoError(t, err)
+	database.Close()
+
+	d := newDeployer(&Config{RepoPath: repo, BackupDBs: []string{"portfolio.db", "missing.db"}, BackupKeep: 5})
+	backups, err := d.backupDatabases(push("0123456789abcdef"))
+	testutils.AssertNoError(t, err)
+
+	// Databases that do not exist yet are skipped
+	path, backup, ok := strings.Cut(backups, "=")
+	testutils.AssertEqual(t, ok, true)
+	testutils.AssertEqual(t, path, filepath.Join(repo, "portfolio.db"))
+	testutils.AssertEqual(t, filepath.Dir(backup), db.BackupDir(path))
+	testutils.AssertEqual(t, strings.HasSuffix(backup, "-01234567.db"), true)
+	if _, err := os.Stat(backup); err != nil {
+		t.Fatalf("expected a backup at %s: %v", backup, err)
+	}
+
+	// IDs that are not commits are left out of file names
+	backups, err = d.backupDatabases(push("../../etc"))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, strings.Contains(backups, "etc"), false)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// githubAPI is the GitHub REST API base URL
+	githubAPI = "https://api.github.com"
+	// checksPollInterval is how often pending checks are looked up again
+	checksPollInterval = 30 * time.Second
+)
+
+var (
+	repoPattern   = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+	commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// checkGate holds a deployment until named GitHub check runs on the pushed
+// commit have passed, so a push that skipped or failed CI is never deployed
+type checkGate struct {
+	apiURL   string
+	token    string
+	required []string
+	timeout  time.Duration
+	interval time.Duration
+	client   *http.Client
+}
+
+// checkRun is the part of a GitHub check run the gate looks at
+type checkRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+}
+
+func newCheckGate(required []string, token string, timeout time.Duration) *checkGate {
+	return &checkGate{
+		apiURL:   githubAPI,
+		token:    token,
+		required: required,
+		timeout:  timeout,
+		interval: checksPollInterval,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// verify waits up to the gate's timeout for every required check on commit
+// to complete, and returns an error unless all of them passed
+func (g *checkGate) verify(repo, commit string) error {
+	if !repoPattern.MatchString(repo) {
+		return fmt.Errorf("invalid repository %q", repo)
+	}
+	if !commitPattern.MatchString(commit) {
+		return fmt.Errorf("invalid commit %q", commit)
+	}
+
+	deadline := time.Now().Add(g.timeout)
+	for {
+		runs, err := g.fetch(repo, commit)
+		if err != nil {
+			return err
+		}
+		pending, err := evaluateChecks(runs, g.required)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if time.Now().Add(g.interval).After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for %s", g.timeout, strings.Join(pending, ", "))
+		}
+		time.Sleep(g.interval)
+	}
+}
+
+// fetch lists the check runs on a commit
+func (g *checkGate) fetch(repo, commit string) ([]checkRun, error) {
+	url := fmt.Sprintf("%s/repos/%s/commits/%s/check-runs?per_page=100", g.apiURL, repo, commit)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch check runs: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned %s for check runs", resp.Status)
+	}
+
+	var body struct {
+		CheckRuns []checkRun `json:"check_runs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse check runs: %w", err)
+	}
+	return body.CheckRuns, nil
+}
+
+// evaluateChecks returns the required checks still pending, or an error if
+// one finished without passing. A re-run check is judged by its latest run.
+func evaluateChecks(runs []checkRun, required []string) ([]string, error) {
+	latest := make(map[string]checkRun)
+	for _, run := range runs {
+		if previous, ok := latest[run.Name]; !ok || run.ID > previous.ID {
+			latest[run.Name] = run
+		}
+	}
+
+	var pending []string
+	for _, name := range required {
+		run, ok := latest[name]
+		if !ok || run.Status != "completed" {
+			pending = append(pending, name)
+			continue
+		}
+		switch run.Conclusion {
+		case "success", "neutral", "skipped":
+		default:
+			return nil, fmt.Errorf("check %q concluded %s", name, run.Conclusion)
+		}
+	}
+	return pending, nil
+}
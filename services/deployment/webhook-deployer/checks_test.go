@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+func TestEvaluateChecks(t *testing.T) {
+	required := []string{"Pre-deployment Tests", "lint"}
+
+	pending, err := evaluateChecks(nil, required)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(pending), 2)
+
+	pending, err = evaluateChecks([]checkRun{
+		{ID: 1, Name: "Pre-deployment Tests", Status: "completed", Conclusion: "success"},
+		{ID: 2, Name: "lint", Status: "in_progress"},
+		{ID: 3, Name: "unrelated", Status: "completed", Conclusion: "failure"},
+	}, required)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(pending), 1)
+	testutils.AssertEqual(t, pending[0], "lint")
+
+	// A re-run is judged by its latest run
+	pending, err = evaluateChecks([]checkRun{
+		{ID: 5, Name: "lint", Status: "completed", Conclusion: "success"},
+		{ID: 4, Name: "lint", Status: "completed", Conclusion: "failure"},
+		{ID: 1, Name: "Pre-deployment Tests", Status: "completed", Conclusion: "skipped"},
+	}, required)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(pending), 0)
+
+	_, err = evaluateChecks([]checkRun{
+		{ID: 1, Name: "lint", Status: "completed", Conclusion: "failure"},
+	}, required)
+	testutils.AssertError(t, err, `check "lint" concluded failure`)
+}
+
+func TestCheckGateVerify(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutils.AssertEqual(t, r.URL.Path, "/repos/brewgator/lightning-node-tools/commits/"+testCommit+"/check-runs")
+		testutils.AssertEqual(t, r.Header.Get("Authorization"), "Bearer token")
+		run := checkRun{ID: 1, Name: "Pre-deployment Tests", Status: "in_progress"}
+		if calls.Add(1) > 1 {
+			run.Status, run.Conclusion = "completed", "success"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total_count": 1, "check_runs": []checkRun{run}})
+	}))
+	defer server.Close()
+
+	gate := newCheckGate([]string{"Pre-deployment Tests"}, "token", time.Second)
+	gate.apiURL = server.URL
+	gate.interval = 10 * time.Millisecond
+	testutils.AssertNoError(t, gate.verify("brewgator/lightning-node-tools", testCommit))
+	testutils.AssertEqual(t, calls.Load(), int32(2))
+
+	gate.required = []string{"never reported"}
+	testutils.AssertError(t, gate.verify("brewgator/lightning-node-tools", testCommit), "timed out")
+
+	testutils.AssertError(t, gate.verify("../../user", testCommit), "invalid repository")
+	testutils.AssertError(t, gate.verify("brewgator/lightning-node-tools", "main"), "invalid commit")
+}
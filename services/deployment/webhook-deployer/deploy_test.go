@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func push(commit string) WebhookPayload {
+	var payload WebhookPayload
+	payload.HeadCommit.ID = commit
+	return payload
+}
+
+func TestDeployQueuesLatestPush(t *testing.T) {
+	started := make(chan string)
+	release := make(chan struct{})
+	d := newDeployer(&Config{})
+	d.run = func(payload WebhookPayload) {
+		started <- payload.HeadCommit.ID
+		<-release
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.deploy(push("aaaaaaaaaa"))
+		close(done)
+	}()
+	testutils.AssertEqual(t, <-started, "aaaaaaaaaa")
+
+	// Pushes during a deployment wait in one slot, newest first
+	d.deploy(push("bbbbbbbbbb"))
+	d.deploy(push("cccccccccc"))
+	deploying, pending := d.state()
+	testutils.AssertEqual(t, deploying, true)
+	testutils.AssertEqual(t, pending, "cccccccccc")
+
+	release <- struct{}{}
+	testutils.AssertEqual(t, <-started, "cccccccccc")
+	release <- struct{}{}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deploy did not return after the queue drained")
+	}
+	deploying, pending = d.state()
+	testutils.AssertEqual(t, deploying, false)
+	testutils.AssertEqual(t, pending, "")
+}
+
+func TestRunScriptMetrics(t *testing.T) {
+	repo := t.TempDir()
+	testutils.AssertNoError(t, os.WriteFile(filepath.Join(repo, "ok.sh"), []byte("exit 0\n"), 0o644))
+	testutils.AssertNoError(t, os.WriteFile(filepath.Join(repo, "fail.sh"), []byte("exit 1\n"), 0o644))
+	// runScript changes into the repository
+	t.Chdir(repo)
+
+	d := newDeployer(&Config{RepoPath: repo, DeployScript: "ok.sh"})
+	d.runScript(push("aaaaaaaaaa"))
+	d.config.DeployScript = "fail.sh"
+	d.runScript(push("bbbbbbbbbb"))
+
+	var b strings.Builder
+	testutils.AssertNoError(t, d.metrics.WritePrometheus(&b, metricsNamespace))
+	for _, line := range []string{
+		`webhook_deployer_deployments_total{result="failed"} 1`,
+		`webhook_deployer_deployments_total{result="success"} 1`,
+		`webhook_deployer_deployment_duration_seconds_count 2`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestDeployMessages(t *testing.T) {
+	payload := push("0123456789abcdef")
+	payload.HeadCommit.Message = "Fix <script> & stuff\n\nLonger body"
+	payload.HeadCommit.Author.Name = "Satoshi"
+
+	testutils.AssertEqual(t, startMessage(payload, "main"),
+		"🚀 <b>Deploying</b> <code>01234567</code> on main by Satoshi\nFix &lt;script&gt; &amp; stuff")
+	testutils.AssertEqual(t, successMessage(payload, "main", 92*time.Second+300*time.Millisecond),
+		"✅ <b>Deployed</b> in 1m32s: <code>01234567</code> on main by Satoshi\nFix &lt;script&gt; &amp; stuff")
+
+	output := []byte("step 1\nstep 2\ngo: build failed <main.go:3>\n")
+	message := failureMessage(payload, "main", 5*time.Second, errors.New("exit status 1"), output)
+	testutils.AssertEqual(t, strings.HasSuffix(message, "exit status 1\n<pre>step 1\nstep 2\ngo: build failed &lt;main.go:3&gt;</pre>"), true)
+	testutils.AssertEqual(t, strings.Contains(failureMessage(payload, "main", 0, errors.New("no repo"), nil), "<pre>"), false)
+}
+
+func TestLogTail(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 50; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	tail := logTail([]byte(strings.Join(lines, "\n") + "\n"))
+	testutils.AssertEqual(t, strings.Count(tail, "\n"), logTailLines-1)
+	testutils.AssertEqual(t, strings.HasPrefix(tail, "line 31\n"), true)
+	testutils.AssertEqual(t, strings.HasSuffix(tail, "line 50"), true)
+
+	long := logTail([]byte("first\n" + strings.Repeat("é", logTailBytes)))
+	testutils.AssertEqual(t, len(long) <= logTailBytes, true)
+	testutils.AssertEqual(t, utf8.ValidString(long), true)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -9,21 +10,38 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/listen"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/notify"
 )
 
 type Config struct {
-	Port         string
+	Addr         string
 	SecretKey    string
 	RepoPath     string
 	Branch       string
 	DeployScript string
-	AllowedIPs   []string
+	// Canary asks the deploy script to smoke test the new build first
+	Canary bool
+	// AllowedIPs limits /webhook and /status to these networks, empty allows all
+	AllowedIPs []*net.IPNet
+	// StatusUser and StatusPassword enable basic auth on /status
+	StatusUser     string
+	StatusPassword string
+	// BackupDBs are backed up before every deployment, keeping the last
+	// BackupKeep backups of each
+	BackupDBs  []string
+	BackupKeep int
 }
 
 type WebhookPayload struct {
@@ -43,18 +61,54 @@ type WebhookPayload struct {
 }
 
 type Deployer struct {
-	config    *Config
+	config *Config
+	// run performs one deployment, runScript outside tests
+	run func(payload WebhookPayload)
+	// telegram receives deploy start, success and failure messages
+	telegram notify.Telegram
+	// checks, when set, must pass on a commit before it is deployed
+	checks *checkGate
+	// metrics counts requests per endpoint and deployments by result
+	metrics        *metrics.Registry
+	deployments    *metrics.Counter
+	deployDuration *metrics.Histogram
+
 	mutex     sync.Mutex
 	deploying bool
+	// pending is the latest push received during a deployment, run next
+	pending *WebhookPayload
+}
+
+func newDeployer(config *Config) *Deployer {
+	d := &Deployer{
+		config: config,
+		// Notifications go to Telegram when the monitor's bot is configured
+		telegram: notify.Telegram{BotToken: os.Getenv("BOT_TOKEN"), ChatID: os.Getenv("CHAT_ID")},
+		metrics:  metrics.NewRegistry(),
+	}
+	d.deployments = d.metrics.NewCounter("deployments_total",
+		"Deployments run, by result (success, failed or checks_failed)", "result")
+	d.deployDuration = d.metrics.NewHistogram("deployment_duration_seconds",
+		"Deployment durations, from the push to the script's exit", metrics.JobBuckets)
+	d.run = d.runScript
+	return d
 }
 
 func main() {
 	var (
-		port         = flag.String("port", "9000", "Port to listen on")
-		secretKey    = flag.String("secret", "", "GitHub webhook secret key (or set WEBHOOK_SECRET env var)")
-		repoPath     = flag.String("repo", "/opt/lightning-node-tools", "Path to repository on server")
-		branch       = flag.String("branch", "main", "Branch to deploy")
-		deployScript = flag.String("script", "./scripts/auto-deploy.sh", "Deployment script to run")
+		port          = flag.String("port", "9000", "Port to listen on")
+		listenAddr    = flag.String("listen", "", "Serve on host:port or unix:/path instead of --port, e.g. unix:/run/webhook-deployer/webhook.sock behind a reverse proxy")
+		secretKey     = flag.String("secret", "", "GitHub webhook secret key (or set WEBHOOK_SECRET env var)")
+		repoPath      = flag.String("repo", "/opt/lightning-node-tools", "Path to repository on server")
+		branch        = flag.String("branch", "main", "Branch to deploy")
+		deployScript  = flag.String("script", "./scripts/auto-deploy.sh", "Deployment script to run")
+		allowIPs      = flag.String("allow-ips", "", "Comma separated CIDRs or IPs allowed to call /webhook and /status, e.g. GitHub's hook ranges from https://api.github.com/meta (empty allows all)")
+		canary        = flag.Bool("canary", false, "Smoke test the new API in mock mode on a temporary port before swapping services (sets DEPLOY_CANARY for the deploy script)")
+		requireChecks = flag.String("require-checks", "", "Comma separated GitHub check run names that must pass on the pushed commit before deploying, e.g. \"Pre-deployment Tests\" (GITHUB_TOKEN for private repos)")
+		checksTimeout = flag.Duration("checks-timeout", 30*time.Minute, "How long to wait for --require-checks to complete")
+		statusUser    = flag.String("status-user", "", "Require basic auth with this user on /status, password from WEBHOOK_STATUS_PASSWORD; valid credentials are accepted from outside --allow-ips")
+		backupDBs     = flag.String("backup-db", "", "Comma separated databases, relative to --repo, to back up before each deployment migrates them, e.g. data/portfolio.db (empty disables)")
+		backupKeep    = flag.Int("backup-keep", 5, "Pre-migration backups kept per database")
 	)
 	flag.Parse()
 
@@ -66,30 +120,73 @@ func main() {
 		log.Fatal("❌ Webhook secret required! Use --secret flag or WEBHOOK_SECRET environment variable")
 	}
 
+	addr := *listenAddr
+	if addr == "" {
+		addr = ":" + *port
+	}
+
+	allowedIPs, err := parseAllowedIPs(*allowIPs)
+	if err != nil {
+		log.Fatalf("❌ Invalid --allow-ips: %v", err)
+	}
+	statusPassword := os.Getenv("WEBHOOK_STATUS_PASSWORD")
+	if *statusUser != "" && statusPassword == "" {
+		log.Fatal("❌ --status-user requires the WEBHOOK_STATUS_PASSWORD environment variable")
+	}
+
+	if *backupKeep < 1 {
+		log.Fatal("❌ --backup-keep must be at least 1")
+	}
+
 	config := &Config{
-		Port:         *port,
-		SecretKey:    *secretKey,
-		RepoPath:     *repoPath,
-		Branch:       *branch,
-		DeployScript: *deployScript,
-		// Add your server IPs here for additional security
-		AllowedIPs: []string{}, // Empty means allow all (GitHub webhooks come from various IPs)
+		Addr:           addr,
+		SecretKey:      *secretKey,
+		RepoPath:       *repoPath,
+		Branch:         *branch,
+		DeployScript:   *deployScript,
+		Canary:         *canary,
+		AllowedIPs:     allowedIPs,
+		StatusUser:     *statusUser,
+		StatusPassword: statusPassword,
+		BackupDBs:      splitList(*backupDBs),
+		BackupKeep:     *backupKeep,
 	}
 
-	deployer := &Deployer{config: config}
+	deployer := newDeployer(config)
+	if names := splitList(*requireChecks); len(names) > 0 {
+		deployer.checks = newCheckGate(names, os.Getenv("GITHUB_TOKEN"), *checksTimeout)
+	}
 
-	http.HandleFunc("/webhook", deployer.handleWebhook)
+	http.HandleFunc("/webhook", deployer.requireAllowedSource(deployer.handleWebhook))
 	http.HandleFunc("/health", deployer.handleHealth)
-	http.HandleFunc("/status", deployer.handleStatus)
+	http.HandleFunc("/status", deployer.requireStatusAccess(deployer.handleStatus))
+	http.Handle("/metrics", deployer.requireStatusAccess(deployer.metrics.Handler(metricsNamespace, metrics.Default).ServeHTTP))
+
+	listener, err := listen.Listen(config.Addr)
+	if err != nil {
+		log.Fatalf("❌ Server failed to start: %v", err)
+	}
 
-	log.Printf("🚀 Webhook deployer starting on port %s", config.Port)
+	log.Printf("🚀 Webhook deployer starting on %s", config.Addr)
 	log.Printf("📁 Repository path: %s", config.RepoPath)
 	log.Printf("🌿 Target branch: %s", config.Branch)
 	log.Printf("📜 Deploy script: %s", config.DeployScript)
+	if len(config.BackupDBs) > 0 {
+		log.Printf("💾 Backing up before each deployment: %s (keeping %d)", strings.Join(config.BackupDBs, ", "), config.BackupKeep)
+	}
+	if len(config.AllowedIPs) > 0 {
+		log.Printf("🛡️  Allowed sources: %s", *allowIPs)
+	} else {
+		log.Printf("⚠️  No --allow-ips set, any source can reach /webhook (signatures are still checked)")
+	}
 
-	if err := http.ListenAndServe(":"+config.Port, nil); err != nil {
-		log.Fatalf("❌ Server failed to start: %v", err)
+	// Shutting down closes the listener, which removes a unix socket
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := listen.Serve(ctx, listen.Endpoint{Listener: listener, Handler: logRequests(deployer.metrics.Middleware(routePattern)(http.DefaultServeMux))}); err != nil {
+		log.Fatalf("❌ Server failed: %v", err)
 	}
+	log.Printf("👋 Webhook deployer stopped")
 }
 
 func (d *Deployer) handleWebhook(w http.ResponseWriter, r *http.Request) {
@@ -114,7 +211,7 @@ func (d *Deployer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !d.verifySignature(signature, body) {
-		log.Printf("❌ Invalid signature from %s", r.RemoteAddr)
+		log.Printf("❌ Invalid signature from %s", sourceName(r))
 		http.Error(w, "Invalid signature", http.StatusUnauthorized)
 		return
 	}
@@ -137,7 +234,7 @@ func (d *Deployer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("🎯 Webhook received for %s", payload.Repository.FullName)
-	log.Printf("📝 Commit: %s", payload.HeadCommit.ID[:8])
+	log.Printf("📝 Commit: %s", shortCommit(payload.HeadCommit.ID))
 	log.Printf("💬 Message: %s", payload.HeadCommit.Message)
 	log.Printf("👤 Author: %s <%s>", payload.HeadCommit.Author.Name, payload.HeadCommit.Author.Email)
 
@@ -160,24 +257,91 @@ func (d *Deployer) verifySignature(signature string, body []byte) bool {
 	return hmac.Equal([]byte(signature), []byte(expectedSignature))
 }
 
+// deploy runs a deployment for payload. A push that arrives while one is
+// running waits in a single pending slot, so the latest commit is deployed
+// next and any older waiting push is dropped.
 func (d *Deployer) deploy(payload WebhookPayload) {
 	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
 	if d.deploying {
-		log.Printf("⚠️  Deployment already in progress, skipping")
+		if d.pending != nil {
+			log.Printf("⏭️  Replacing queued deployment of %s with %s", shortCommit(d.pending.HeadCommit.ID), shortCommit(payload.HeadCommit.ID))
+		} else {
+			log.Printf("⏳ Deployment in progress, queued %s to run next", shortCommit(payload.HeadCommit.ID))
+		}
+		d.pending = &payload
+		d.mutex.Unlock()
 		return
 	}
-
 	d.deploying = true
-	defer func() { d.deploying = false }()
+	d.mutex.Unlock()
+
+	for {
+		d.run(payload)
+
+		d.mutex.Lock()
+		if d.pending == nil {
+			d.deploying = false
+			d.mutex.Unlock()
+			return
+		}
+		payload = *d.pending
+		d.pending = nil
+		d.mutex.Unlock()
+		log.Printf("▶️  Running queued deployment of %s", shortCommit(payload.HeadCommit.ID))
+	}
+}
+
+// state returns whether a deployment is running and the commit queued after
+// it, if any
+func (d *Deployer) state() (bool, string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.pending == nil {
+		return d.deploying, ""
+	}
+	return d.deploying, d.pending.HeadCommit.ID
+}
+
+// shortCommit abbreviates a commit hash for logs
+func shortCommit(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
 
+// runScript runs the deploy script for one push
+func (d *Deployer) runScript(payload WebhookPayload) {
 	log.Printf("🚀 Starting deployment...")
+	d.notify(startMessage(payload, d.config.Branch))
 	startTime := time.Now()
 
+	if d.checks != nil {
+		log.Printf("🔎 Waiting for checks on %s: %s", shortCommit(payload.HeadCommit.ID), strings.Join(d.checks.required, ", "))
+		if err := d.checks.verify(payload.Repository.FullName, payload.HeadCommit.ID); err != nil {
+			log.Printf("🛑 Not deploying %s: %v", shortCommit(payload.HeadCommit.ID), err)
+			d.notify(failureMessage(payload, d.config.Branch, time.Since(startTime), fmt.Errorf("required checks did not pass: %w", err), nil))
+			d.deployments.Inc("checks_failed")
+			return
+		}
+		log.Printf("✅ Required checks passed")
+	}
+
 	// Change to repository directory
 	if err := os.Chdir(d.config.RepoPath); err != nil {
 		log.Printf("❌ Failed to change to repo directory: %v", err)
+		d.notify(failureMessage(payload, d.config.Branch, time.Since(startTime), err, nil))
+		d.deployments.Inc("failed")
+		return
+	}
+
+	// The new version migrates the databases when its services start, so
+	// nothing is deployed without a backup of them
+	backups, err := d.backupDatabases(payload)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		d.notify(failureMessage(payload, d.config.Branch, time.Since(startTime), err, nil))
+		d.deployments.Inc("failed")
 		return
 	}
 
@@ -189,27 +353,39 @@ func (d *Deployer) deploy(payload WebhookPayload) {
 		fmt.Sprintf("DEPLOY_AUTHOR=%s", payload.HeadCommit.Author.Name),
 		fmt.Sprintf("DEPLOY_BRANCH=%s", d.config.Branch),
 	)
+	if d.config.Canary {
+		cmd.Env = append(cmd.Env, "DEPLOY_CANARY=true")
+	}
+	if backups != "" {
+		cmd.Env = append(cmd.Env, "DEPLOY_DB_BACKUPS="+backups)
+	}
 
 	output, err := cmd.CombinedOutput()
 	duration := time.Since(startTime)
+	d.deployDuration.Observe(duration)
 
 	if err != nil {
 		log.Printf("❌ Deployment failed after %v: %v", duration, err)
 		log.Printf("📜 Output: %s", string(output))
+		d.notify(failureMessage(payload, d.config.Branch, duration, err, output))
+		d.deployments.Inc("failed")
 		return
 	}
 
 	log.Printf("✅ Deployment completed successfully in %v", duration)
 	log.Printf("📜 Output: %s", string(output))
+	d.notify(successMessage(payload, d.config.Branch, duration))
+	d.deployments.Inc("success")
 }
 
 func (d *Deployer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	deploying, _ := d.state()
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":    "healthy",
 		"timestamp": time.Now().UTC(),
-		"deploying": d.deploying,
+		"deploying": deploying,
 	})
 }
 
@@ -228,6 +404,8 @@ func (d *Deployer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	messageBytes, _ := cmd.Output()
 	lastMessage := strings.TrimSpace(string(messageBytes))
 
+	deploying, pendingCommit := d.state()
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":         "ready",
@@ -235,7 +413,19 @@ func (d *Deployer) handleStatus(w http.ResponseWriter, r *http.Request) {
 		"target_branch":  d.config.Branch,
 		"current_commit": currentCommit,
 		"last_message":   lastMessage,
-		"deploying":      d.deploying,
+		"deploying":      deploying,
+		"pending_commit": pendingCommit,
 		"timestamp":      time.Now().UTC(),
 	})
 }
+
+// splitList splits a comma separated flag value, dropping empty entries
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// logTailLines and logTailBytes bound the deploy output quoted in a
+	// failure notification; Telegram messages are capped at 4096 characters
+	logTailLines = 20
+	logTailBytes = 2000
+)
+
+// notify sends a deployment message to Telegram when the monitor's bot is
+// configured. Failing to notify never fails the deployment.
+func (d *Deployer) notify(message string) {
+	if !d.telegram.Enabled() {
+		return
+	}
+	if err := d.telegram.Send(message); err != nil {
+		log.Printf("⚠️  Failed to send deploy notification: %v", err)
+	}
+}
+
+// describePush names a push's commit, author and message for notifications
+func describePush(payload WebhookPayload, branch string) string {
+	message, _, _ := strings.Cut(payload.HeadCommit.Message, "\n")
+	return fmt.Sprintf("<code>%s</code> on %s by %s\n%s",
+		html.EscapeString(shortCommit(payload.HeadCommit.ID)),
+		html.EscapeString(branch),
+		html.EscapeString(payload.HeadCommit.Author.Name),
+		html.EscapeString(message))
+}
+
+// startMessage announces a deployment
+func startMessage(payload WebhookPayload, branch string) string {
+	return "🚀 <b>Deploying</b> " + describePush(payload, branch)
+}
+
+// successMessage reports a finished deployment
+func successMessage(payload WebhookPayload, branch string, duration time.Duration) string {
+	return fmt.Sprintf("✅ <b>Deployed</b> in %v: %s", duration.Round(time.Second), describePush(payload, branch))
+}
+
+// failureMessage reports a failed deployment with the end of its output
+func failureMessage(payload WebhookPayload, branch string, duration time.Duration, err error, output []byte) string {
+	message := fmt.Sprintf("❌ <b>Deploy failed</b> after %v: %s\n%s",
+		duration.Round(time.Second), describePush(payload, branch), html.EscapeString(err.Error()))
+	if tail := logTail(output); tail != "" {
+		message += "\n<pre>" + html.EscapeString(tail) + "</pre>"
+	}
+	return message
+}
+
+// logTail returns the last lines of a deploy script's output, at most
+// logTailLines lines and logTailBytes bytes
+func logTail(output []byte) string {
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if len(lines) > logTailLines {
+		lines = lines[len(lines)-logTailLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	if len(tail) > logTailBytes {
+		tail = tail[len(tail)-logTailBytes:]
+		// Start on a whole line, and never inside a multi-byte character
+		if i := strings.IndexByte(tail, '\n'); i >= 0 {
+			tail = tail[i+1:]
+		} else {
+			tail = strings.ToValidUTF8(tail, "")
+		}
+	}
+	return tail
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/ecash"
+	"github.com/brewgator/lightning-node-tools/internal/lock"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+)
+
+// collectorName is recorded in the collector_runs table
+const collectorName = "ecash-balance"
+
+// metricsNamespace prefixes the collector's Prometheus metric names
+const metricsNamespace = "ecash_balance_collector"
+
+type BalanceCollector struct {
+	sources  []ecash.Source
+	db       *db.Database
+	mockMode bool
+}
+
+func main() {
+	var (
+		dbPath       = flag.String("db", "data/portfolio.db", "Path to SQLite database")
+		profile      = flag.String("profile", db.DefaultProfile, db.ProfileFlagUsage)
+		interval     = flag.Duration("interval", 15*time.Minute, "Collection interval")
+		oneshot      = flag.Bool("oneshot", false, "Run once and exit (for testing)")
+		mockMode     = flag.Bool("mock", false, "Use mock data for testing without ecash wallets")
+		ignoreMode   = flag.Bool("ignore-mode-check", false, db.ModeCheckFlagUsage)
+		dryRun       = flag.Bool("dry-run", false, db.DryRunFlagUsage)
+		verboseLogs  = flag.Bool("verbose-logs", false, redact.FlagUsage)
+		fedimintDirs = flag.String("fedimint-data-dir", "", "Comma-separated fedimint-cli data directories, one per federation")
+		cashuURL     = flag.String("cashu-url", "", "Nutshell Cashu wallet API URL (e.g. "+ecash.DefaultCashuURL+")")
+		metricsAddr  = flag.String("metrics-addr", "", metrics.AddrFlagUsage)
+	)
+	flag.Parse()
+	redact.SetVerbose(*verboseLogs)
+
+	// A dry run collects once and exits
+	if *dryRun {
+		*oneshot = true
+	}
+
+	profilePath, err := db.ProfilePath(*dbPath, *profile)
+	if err != nil {
+		log.Fatalf("Invalid --profile: %v", err)
+	}
+	*dbPath = profilePath
+
+	// Ensure data directory exists
+	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	instance, err := lock.AcquireUnlessDryRun(*dbPath, "ecash-balance-collector", *mockMode, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer instance.Release()
+
+	// Initialize database with mock mode support
+	openDatabase := db.NewDatabaseWithMockMode
+	if *dryRun {
+		openDatabase = db.OpenDryRun
+	}
+	database, err := openDatabase(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+	if *dryRun {
+		defer database.LogDryRun(redact.Verbose())
+	}
+
+	if !*ignoreMode {
+		if err := database.CheckMode(time.Now()); err != nil {
+			log.Fatalf("❌ %v; check --mock, or pass --ignore-mode-check to start anyway", err)
+		}
+	}
+
+	collector := &BalanceCollector{
+		db:       database,
+		mockMode: *mockMode,
+	}
+
+	if *mockMode {
+		fmt.Println("📊 Using mock database tables (data will not affect real data)")
+		fmt.Println("⚠️  Running in mock mode - using test data")
+	} else {
+		for _, dir := range strings.Split(*fedimintDirs, ",") {
+			dir = strings.TrimSpace(dir)
+			if dir == "" {
+				continue
+			}
+			client, err := ecash.NewFedimintClient(dir)
+			if err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+			collector.sources = append(collector.sources, client)
+			fmt.Printf("🪙 Using Fedimint client in %s\n", dir)
+		}
+		if *cashuURL != "" {
+			collector.sources = append(collector.sources, ecash.NewCashuClient(*cashuURL))
+			fmt.Printf("🥜 Using Cashu wallet API at %s\n", *cashuURL)
+		}
+		if len(collector.sources) == 0 {
+			log.Fatal("❌ No ecash wallets configured: set --fedimint-data-dir and/or --cashu-url")
+		}
+	}
+
+	if *oneshot {
+		fmt.Println("Running ecash balance collection once...")
+		if err := collector.collectBalances(); err != nil {
+			log.Fatalf("Ecash balance collection failed: %v", err)
+		}
+		fmt.Println("Ecash balance collection completed successfully")
+		return
+	}
+
+	if err := metrics.Default.Serve(*metricsAddr, metricsNamespace); err != nil {
+		log.Fatalf("Failed to serve metrics: %v", err)
+	}
+
+	// Set up signal handling for graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Starting ecash balance collection every %v...\n", *interval)
+
+	// Collect initial data
+	if err := collector.collectBalances(); err != nil {
+		log.Printf("Initial ecash balance collection failed: %v", err)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := collector.collectBalances(); err != nil {
+				log.Printf("Ecash balance collection failed: %v", err)
+			}
+		case <-sigChan:
+			fmt.Println("Received shutdown signal, exiting...")
+			return
+		}
+	}
+}
+
+// collectBalances runs one recorded collection
+func (c *BalanceCollector) collectBalances() error {
+	return c.db.RecordCollectorRun(collectorName, c.collectBalanceSnapshots)
+}
+
+// collectBalanceSnapshots stores one snapshot per federation and mint. All
+// snapshots of a run share a timestamp. A wallet that cannot be read is
+// counted as an error and skipped, so the other balances are still stored.
+func (c *BalanceCollector) collectBalanceSnapshots(run *db.CollectorRun) error {
+	timestamp := time.Now()
+	fmt.Printf("[%s] Collecting ecash balances...\n", timestamp.Format("2006-01-02 15:04:05"))
+
+	var balances []ecash.Balance
+	if c.mockMode {
+		balances = []ecash.Balance{
+			{Protocol: ecash.ProtocolFedimint, Mint: "mock-federation", Name: "Mock Federation", Amount: 150000},
+			{Protocol: ecash.ProtocolCashu, Mint: "https://mint.example.com", Name: "mint.example.com", Amount: 21000},
+		}
+	} else {
+		for _, source := range c.sources {
+			sourceBalances, err := source.GetBalances()
+			if err != nil {
+				log.Printf("  ❌ Failed to get ecash balances: %v", err)
+				run.Errors++
+				continue
+			}
+			balances = append(balances, sourceBalances...)
+		}
+		if len(balances) == 0 && run.Errors > 0 {
+			return fmt.Errorf("failed to read any ecash wallet")
+		}
+	}
+
+	for _, balance := range balances {
+		snapshot := &db.EcashBalanceSnapshot{
+			Timestamp: timestamp,
+			Protocol:  balance.Protocol,
+			Mint:      balance.Mint,
+			Name:      balance.Name,
+			Amount:    balance.Amount,
+		}
+		if err := c.db.InsertEcashBalanceSnapshot(snapshot); err != nil {
+			return fmt.Errorf("failed to store ecash balance for %s: %w", balance.Name, err)
+		}
+		run.ItemsInserted++
+
+		fmt.Printf("  🪙 %s (%s): %s\n", balance.Name, balance.Protocol, redact.Sats(balance.Amount))
+	}
+	return nil
+}
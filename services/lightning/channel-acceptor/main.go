@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/lock"
+	"github.com/brewgator/lightning-node-tools/internal/notify"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// reconnectDelay is how long to wait before re-registering after the stream fails
+const reconnectDelay = 30 * time.Second
+
+// ChannelAcceptor answers inbound channel opens according to a Policy
+type ChannelAcceptor struct {
+	policy   Policy
+	db       *db.Database
+	dryRun   bool
+	telegram notify.Telegram
+}
+
+func main() {
+	defaults := lnd.DefaultRESTConfig()
+	var (
+		restHost       = flag.String("rest-host", defaults.Host, "LND REST host:port")
+		tlsCert        = flag.String("tls-cert", defaults.TLSCertPath, "Path to LND's tls.cert")
+		macaroon       = flag.String("macaroon", defaults.MacaroonPath, "Path to a macaroon allowed to accept channels")
+		minSize        = flag.Int64("min-size", 100000, "Reject channels smaller than this many sats (0 disables)")
+		minPrivateSize = flag.Int64("min-private-size", 1000000, "Reject private channels smaller than this many sats (0 disables)")
+		blocklist      = flag.String("blocklist", "", "Comma-separated node pubkeys whose channels are always rejected")
+		allowlist      = flag.String("allowlist", "", "Comma-separated node pubkeys whose channels skip the size checks")
+		allowlistOnly  = flag.Bool("allowlist-only", false, "Reject channels from every peer that is neither allowlisted nor preferred")
+		dryRun         = flag.Bool("dry-run", false, "Log decisions but accept every channel")
+		dbPath         = flag.String("db", "data/portfolio.db", "Path to SQLite database with peer policies")
+	)
+	flag.Parse()
+
+	blocked, err := parsePubkeyList(*blocklist)
+	if err != nil {
+		log.Fatalf("Invalid --blocklist: %v", err)
+	}
+	allowed, err := parsePubkeyList(*allowlist)
+	if err != nil {
+		log.Fatalf("Invalid --allowlist: %v", err)
+	}
+
+	// A second acceptor would register with LND too and decide every open twice
+	instance, err := lock.Acquire(lock.PathFor(*dbPath, "channel-acceptor", false))
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer instance.Release()
+
+	database, err := db.NewDatabase(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	acceptor := &ChannelAcceptor{
+		policy: Policy{
+			MinChannelSize:        *minSize,
+			MinPrivateChannelSize: *minPrivateSize,
+			Blocklist:             blocked,
+			Allowlist:             allowed,
+			AllowlistOnly:         *allowlistOnly,
+		},
+		db:     database,
+		dryRun: *dryRun,
+		// Decisions are also sent to Telegram when the monitor's bot is configured
+		telegram: notify.Telegram{BotToken: os.Getenv("BOT_TOKEN"), ChatID: os.Getenv("CHAT_ID")},
+	}
+
+	cfg := lnd.RESTConfig{Host: *restHost, TLSCertPath: *tlsCert, MacaroonPath: *macaroon}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if acceptor.dryRun {
+		fmt.Println("🧪 Dry run: decisions are logged but every channel is accepted")
+	}
+	fmt.Printf("Channel acceptor policy: min %s, min private %s, %d blocklisted, %d allowlisted\n",
+		utils.FormatSats(*minSize), utils.FormatSats(*minPrivateSize), len(blocked), len(allowed))
+
+	for {
+		fmt.Printf("🔌 Registering channel acceptor with LND at %s\n", cfg.Host)
+		err := lnd.RunChannelAcceptor(ctx, cfg, acceptor.decide)
+		if ctx.Err() != nil {
+			fmt.Println("Received shutdown signal, exiting...")
+			return
+		}
+		log.Printf("Channel acceptor stream ended: %v (LND accepts channels by its own rules until reconnected)", err)
+
+		select {
+		case <-time.After(reconnectDelay):
+		case <-ctx.Done():
+			fmt.Println("Received shutdown signal, exiting...")
+			return
+		}
+	}
+}
+
+// decide evaluates one inbound channel open, logs the decision and notifies
+// Telegram without holding up the response to LND
+func (a *ChannelAcceptor) decide(request lnd.ChannelAcceptRequest) lnd.ChannelAcceptResponse {
+	open := OpenRequest{
+		Pubkey:     request.Pubkey(),
+		FundingSat: request.FundingSat(),
+		Private:    request.Private(),
+	}
+	peer, err := a.db.GetPeerPolicy(open.Pubkey)
+	if err != nil {
+		log.Printf("Warning: failed to read peer policy for %s, using flags only: %v", open.Pubkey, err)
+	}
+	open.Peer = peer
+	decision := a.policy.Evaluate(open)
+
+	message := decisionMessage(open, decision, a.dryRun, lnd.GetNodeAlias(open.Pubkey))
+	log.Println(message)
+	if a.telegram.Enabled() {
+		go func() {
+			if err := a.telegram.Send(message); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}()
+	}
+
+	if decision.Accept || a.dryRun {
+		return lnd.ChannelAcceptResponse{Accept: true}
+	}
+	return lnd.ChannelAcceptResponse{Accept: false, Error: decision.PeerError}
+}
+
+// decisionMessage describes a decision for the log and Telegram
+func decisionMessage(open OpenRequest, decision Decision, dryRun bool, alias string) string {
+	visibility := "public"
+	if open.Private {
+		visibility = "private"
+	}
+
+	verdict := "✅ Accepted"
+	if !decision.Accept {
+		verdict = "🚫 Rejected"
+		if dryRun {
+			verdict = "🧪 Would reject"
+		}
+	}
+
+	return fmt.Sprintf("%s %s channel of %s from %s (%s): %s",
+		verdict, visibility, utils.FormatSats(open.FundingSat), alias, open.Pubkey, decision.Reason)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// Policy holds the rules applied to inbound channel opens
+type Policy struct {
+	// MinChannelSize rejects smaller channels; 0 disables the check
+	MinChannelSize int64
+	// MinPrivateChannelSize rejects smaller unannounced channels; 0 disables the check
+	MinPrivateChannelSize int64
+	// Blocklist peers are always rejected, as are peers blocklisted in the peer policy table
+	Blocklist map[string]bool
+	// Allowlist peers skip the size checks, as do preferred peers in the peer policy table
+	Allowlist map[string]bool
+	// AllowlistOnly rejects every peer that is neither allowlisted nor preferred
+	AllowlistOnly bool
+}
+
+// OpenRequest is the part of an inbound channel open the policy looks at
+type OpenRequest struct {
+	Pubkey     string
+	FundingSat int64
+	Private    bool
+	// Peer is the stored policy for Pubkey, nil if it has none
+	Peer *db.PeerPolicy
+}
+
+// Decision is the outcome of evaluating an OpenRequest. Reason explains the
+// decision in the log; PeerError is what a rejected peer is told.
+type Decision struct {
+	Accept    bool
+	Reason    string
+	PeerError string
+}
+
+// Evaluate applies the policy rules in order: blocklist, allowlist and
+// preferred peers, then sizes
+func (p Policy) Evaluate(req OpenRequest) Decision {
+	if p.Blocklist[req.Pubkey] || (req.Peer != nil && req.Peer.Blocklisted) {
+		return Decision{Reason: "peer is blocklisted", PeerError: "channel rejected"}
+	}
+
+	if p.Allowlist[req.Pubkey] {
+		return Decision{Accept: true, Reason: "peer is allowlisted"}
+	}
+	if req.Peer != nil && req.Peer.Preferred {
+		return Decision{Accept: true, Reason: "peer is preferred"}
+	}
+	if p.AllowlistOnly {
+		return Decision{Reason: "peer is not allowlisted", PeerError: "channel rejected"}
+	}
+
+	if p.MinChannelSize > 0 && req.FundingSat < p.MinChannelSize {
+		return Decision{
+			Reason:    fmt.Sprintf("channel below minimum size of %s", utils.FormatSats(p.MinChannelSize)),
+			PeerError: fmt.Sprintf("minimum channel size is %d sats", p.MinChannelSize),
+		}
+	}
+	if req.Private && p.MinPrivateChannelSize > 0 && req.FundingSat < p.MinPrivateChannelSize {
+		return Decision{
+			Reason:    fmt.Sprintf("private channel below minimum size of %s", utils.FormatSats(p.MinPrivateChannelSize)),
+			PeerError: fmt.Sprintf("minimum private channel size is %d sats", p.MinPrivateChannelSize),
+		}
+	}
+
+	return Decision{Accept: true, Reason: "meets policy"}
+}
+
+// parsePubkeyList parses a comma-separated list of hex node pubkeys
+func parsePubkeyList(list string) (map[string]bool, error) {
+	pubkeys := make(map[string]bool)
+	for _, pubkey := range strings.Split(list, ",") {
+		pubkey = strings.ToLower(strings.TrimSpace(pubkey))
+		if pubkey == "" {
+			continue
+		}
+		if !utils.ValidateNodePubkey(pubkey) {
+			return nil, fmt.Errorf("invalid node pubkey %q", pubkey)
+		}
+		pubkeys[pubkey] = true
+	}
+	return pubkeys, nil
+}
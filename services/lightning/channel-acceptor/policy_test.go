@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+const (
+	blockedPeer = "02aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	trustedPeer = "03bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	otherPeer   = "02cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+)
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy := Policy{
+		MinChannelSize:        100000,
+		MinPrivateChannelSize: 1000000,
+		Blocklist:             map[string]bool{blockedPeer: true},
+		Allowlist:             map[string]bool{trustedPeer: true},
+	}
+
+	tests := []struct {
+		name    string
+		request OpenRequest
+		accept  bool
+	}{
+		{"blocklisted", OpenRequest{Pubkey: blockedPeer, FundingSat: 5000000}, false},
+		{"allowlisted tiny private", OpenRequest{Pubkey: trustedPeer, FundingSat: 20000, Private: true}, true},
+		{"too small", OpenRequest{Pubkey: otherPeer, FundingSat: 50000}, false},
+		{"small public", OpenRequest{Pubkey: otherPeer, FundingSat: 500000}, true},
+		{"small private", OpenRequest{Pubkey: otherPeer, FundingSat: 500000, Private: true}, false},
+		{"large private", OpenRequest{Pubkey: otherPeer, FundingSat: 2000000, Private: true}, true},
+	}
+
+	for _, tt := range tests {
+		decision := policy.Evaluate(tt.request)
+		if decision.Accept != tt.accept {
+			t.Errorf("%s: got accept=%v (%s), want %v", tt.name, decision.Accept, decision.Reason, tt.accept)
+		}
+		if !decision.Accept && decision.PeerError == "" {
+			t.Errorf("%s: rejection without a peer error", tt.name)
+		}
+	}
+
+	stored := &db.PeerPolicy{Pubkey: otherPeer, Blocklisted: true}
+	testutils.AssertEqual(t, policy.Evaluate(OpenRequest{Pubkey: otherPeer, FundingSat: 5000000, Peer: stored}).Accept, false)
+	stored = &db.PeerPolicy{Pubkey: otherPeer, Preferred: true}
+	testutils.AssertEqual(t, policy.Evaluate(OpenRequest{Pubkey: otherPeer, FundingSat: 20000, Peer: stored}).Accept, true)
+
+	policy.AllowlistOnly = true
+	testutils.AssertEqual(t, policy.Evaluate(OpenRequest{Pubkey: otherPeer, FundingSat: 5000000, Peer: stored}).Accept, true)
+	testutils.AssertEqual(t, policy.Evaluate(OpenRequest{Pubkey: otherPeer, FundingSat: 5000000}).Accept, false)
+	testutils.AssertEqual(t, policy.Evaluate(OpenRequest{Pubkey: trustedPeer, FundingSat: 5000000}).Accept, true)
+}
+
+func TestParsePubkeyList(t *testing.T) {
+	pubkeys, err := parsePubkeyList(" " + strings.ToUpper(blockedPeer) + ", ," + trustedPeer)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(pubkeys), 2)
+	testutils.AssertEqual(t, pubkeys[blockedPeer], true)
+
+	_, err = parsePubkeyList("02abc")
+	testutils.AssertError(t, err, "invalid node pubkey")
+}
+
+func TestDecisionMessage(t *testing.T) {
+	open := OpenRequest{Pubkey: otherPeer, FundingSat: 50000, Private: true}
+	decision := Decision{Reason: "channel below minimum size of 100.0K sats"}
+
+	message := decisionMessage(open, decision, true, "Peer")
+	testutils.AssertEqual(t, strings.HasPrefix(message, "🧪 Would reject private channel of 50.0K sats from Peer"), true)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+)
+
+const (
+	// balanceSnapshotDelay lets a burst of money movement, such as a
+	// multi-part payment or a rebalance, settle into one snapshot
+	balanceSnapshotDelay = 5 * time.Second
+	// subscriptionReconnectDelay is how long to wait before resubscribing
+	// after LND's streams drop
+	subscriptionReconnectDelay = 30 * time.Second
+)
+
+// watchBalanceEvents forwards every settled invoice and completed payment to
+// events until ctx is cancelled, resubscribing whenever LND's streams drop
+func watchBalanceEvents(ctx context.Context, cfg lnd.RESTConfig, events chan<- lnd.BalanceEvent) {
+	for {
+		fmt.Printf("🔌 Subscribing to invoices and payments at %s\n", cfg.Host)
+		err := lnd.SubscribeBalanceEvents(ctx, cfg, func(event lnd.BalanceEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Balance event subscription ended: %v (balances are only snapshotted every interval until resubscribed)", err)
+
+		select {
+		case <-time.After(subscriptionReconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// describeBalanceEvent returns a log line for event
+func describeBalanceEvent(event lnd.BalanceEvent) string {
+	if event.Type == lnd.BalanceEventInvoice {
+		return fmt.Sprintf("⚡ Invoice settled: received %s", redact.Sats(event.AmountSat))
+	}
+	return fmt.Sprintf("⚡ Payment succeeded: sent %s", redact.Sats(event.AmountSat))
+}
+
+// snapshotBalances records channel balances and Lightning balance history
+// right after money moved, rather than at the next collection
+func (c *ForwardingCollector) snapshotBalances() {
+	if err := c.db.RecordCollectorRun(channelSnapshotCollectorName, c.collectChannelSnapshots); err != nil {
+		log.Printf("Channel snapshot collection failed: %v", err)
+	}
+	if c.mockMode {
+		return
+	}
+
+	if err := c.connectLND(); err != nil {
+		log.Printf("Skipping Lightning history sync: %v", err)
+		return
+	}
+	if c.history == nil {
+		c.history = lnd.NewLightningHistoryScannerWithStore(c.config.LNDClient, c.db)
+	}
+	if err := c.history.Sync(); err != nil {
+		log.Printf("Lightning history sync failed: %v", err)
+	}
+}
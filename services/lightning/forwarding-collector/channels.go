@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+)
+
+// channelSnapshotCollectorName is the collector_runs name for channel balance snapshots
+const channelSnapshotCollectorName = "channel-snapshots"
+
+// collectChannelSnapshots records the balance and fee policy of every channel
+func (c *ForwardingCollector) collectChannelSnapshots(run *db.CollectorRun) error {
+	if c.mockMode {
+		return c.collectMockChannelSnapshots(run)
+	}
+
+	channels, err := lnd.GetChannels()
+	if err != nil {
+		return fmt.Errorf("failed to list channels: %w", err)
+	}
+
+	fees := make(map[string]lnd.ChannelFeeReport)
+	if report, err := lnd.GetFeeReport(); err != nil {
+		log.Printf("Warning: failed to get fee report, channel fees will be empty: %v", err)
+		run.Errors++
+	} else {
+		for _, fee := range report.ChannelFees {
+			fees[fee.ChanID] = fee
+		}
+	}
+
+	now := time.Now()
+	snapshots := make([]db.ChannelSnapshot, 0, len(channels))
+	peers := make(map[string]string, len(channels))
+	for _, channel := range channels {
+		peers[channel.ChanID] = channel.RemotePubkey
+		capacity, _ := strconv.ParseInt(channel.Capacity, 10, 64)
+		local, _ := strconv.ParseInt(channel.LocalBalance, 10, 64)
+		remote, _ := strconv.ParseInt(channel.RemoteBalance, 10, 64)
+
+		snapshot := db.ChannelSnapshot{
+			Timestamp:     now,
+			ChannelID:     channel.ChanID,
+			Capacity:      capacity,
+			LocalBalance:  local,
+			RemoteBalance: remote,
+			Active:        channel.Active,
+			PeerAlias:     c.peerAlias(channel.RemotePubkey),
+		}
+		if fee, ok := fees[channel.ChanID]; ok {
+			snapshot.FeePPM, _ = strconv.ParseInt(fee.FeePerMil, 10, 64)
+			baseFeeMsat, _ := strconv.ParseInt(fee.BaseFeeMsat, 10, 64)
+			snapshot.BaseFee = baseFeeMsat / 1000
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := c.db.InsertChannelSnapshots(snapshots); err != nil {
+		return fmt.Errorf("failed to store channel snapshots: %w", err)
+	}
+	run.ItemsInserted = int64(len(snapshots))
+	fmt.Printf("✅ Recorded balances of %d channels\n", len(snapshots))
+
+	// The peers let channel views show the notes kept about them
+	if err := c.db.SetChannelPeers(peers); err != nil {
+		log.Printf("Warning: failed to record channel peers: %v", err)
+		run.Errors++
+	}
+
+	c.recordPoolLeases(channels, run)
+	return nil
+}
+
+// recordPoolLeases adds a lease for each Lightning Pool channel that has none
+// yet. LND only knows the expiry height, so the premium is left at zero for
+// the operator to fill in. The seller is the side that opened the channel.
+func (c *ForwardingCollector) recordPoolLeases(channels []lnd.Channel, run *db.CollectorRun) {
+	for _, channel := range channels {
+		if channel.CommitmentType != lnd.CommitmentTypeScriptEnforcedLease || channel.ThawHeight == 0 {
+			continue
+		}
+
+		side := db.LeaseSideBought
+		if channel.Initiator {
+			side = db.LeaseSideSold
+		}
+		added, err := c.db.RecordDetectedChannelLease(db.ChannelLease{
+			ChannelID:    channel.ChanID,
+			Side:         side,
+			Provider:     "pool",
+			ExpiryHeight: channel.ThawHeight,
+		})
+		if err != nil {
+			log.Printf("Warning: failed to record lease of channel %s: %v", channel.ChanID, err)
+			run.Errors++
+			continue
+		}
+		if added {
+			fmt.Printf("📜 Recorded %s Pool lease of channel %s, expiring at block %d\n", side, channel.ChanID, channel.ThawHeight)
+		}
+	}
+}
+
+// peerAlias looks up a node alias once per collector lifetime
+func (c *ForwardingCollector) peerAlias(pubkey string) string {
+	if alias, ok := c.aliases[pubkey]; ok {
+		return alias
+	}
+	if c.aliases == nil {
+		c.aliases = make(map[string]string)
+	}
+	alias := lnd.GetNodeAlias(pubkey)
+	c.aliases[pubkey] = alias
+	return alias
+}
+
+func (c *ForwardingCollector) collectMockChannelSnapshots(run *db.CollectorRun) error {
+	now := time.Now()
+
+	// Balances drift with the time of day so charts show movement
+	drift := int64(now.Hour()*60+now.Minute()) * 500
+	snapshots := []db.ChannelSnapshot{
+		{
+			Timestamp:     now,
+			ChannelID:     "123456789:1:0",
+			Capacity:      2000000,
+			LocalBalance:  1500000 - drift,
+			RemoteBalance: 500000 + drift,
+			Active:        true,
+			PeerAlias:     "MockPeer-A",
+			FeePPM:        250,
+			BaseFee:       1,
+		},
+		{
+			Timestamp:     now,
+			ChannelID:     "987654321:1:0",
+			Capacity:      1000000,
+			LocalBalance:  200000,
+			RemoteBalance: 800000,
+			Active:        true,
+			PeerAlias:     "MockPeer-B",
+			FeePPM:        100,
+			BaseFee:       0,
+		},
+	}
+
+	if err := c.db.InsertChannelSnapshots(snapshots); err != nil {
+		return fmt.Errorf("failed to store mock channel snapshots: %w", err)
+	}
+	run.ItemsInserted = int64(len(snapshots))
+	fmt.Printf("✅ Recorded balances of %d mock channels\n", len(snapshots))
+	return nil
+}
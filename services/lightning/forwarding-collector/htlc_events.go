@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+)
+
+// watchForwardEvents passes every forward that settles or fails to events
+// until ctx is cancelled, resubscribing whenever LND's HTLC event stream
+// drops. Forwards missed while it is down are found by the next collection.
+func watchForwardEvents(ctx context.Context, cfg lnd.RESTConfig, events chan<- lnd.ForwardEvent) {
+	for {
+		fmt.Printf("🔌 Subscribing to HTLC events at %s\n", cfg.Host)
+		err := lnd.SubscribeForwardEvents(ctx, cfg, func(event lnd.ForwardEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("HTLC event subscription ended: %v (forwards are only collected every interval until resubscribed)", err)
+
+		select {
+		case <-time.After(subscriptionReconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// recordForwardEvent stores a forward seen on the HTLC event stream: a
+// settled one as a forwarding event, to be confirmed by the next collection,
+// and a failed one as an HTLC failure
+func (c *ForwardingCollector) recordForwardEvent(event lnd.ForwardEvent) {
+	if event.Outcome != lnd.ForwardSettled {
+		failure := &db.HTLCFailure{
+			Timestamp:    event.Time,
+			ChannelInID:  event.ChanIDIn,
+			ChannelOutID: event.ChanIDOut,
+			AmountIn:     event.AmtInMsat / 1000,
+			AmountOut:    event.AmtOutMsat / 1000,
+			Fee:          event.FeeMsat() / 1000,
+			Outcome:      event.Outcome,
+			Failure:      event.Failure,
+		}
+		if err := c.db.InsertHTLCFailure(failure); err != nil {
+			log.Printf("Warning: failed to store HTLC failure: %v", err)
+			return
+		}
+		htlcFailures.Inc(event.Outcome)
+		log.Println(describeHTLCFailure(failure))
+		return
+	}
+
+	forward := &db.ForwardingEvent{
+		Timestamp:    event.Time,
+		ChannelInID:  event.ChanIDIn,
+		ChannelOutID: event.ChanIDOut,
+		AmountIn:     event.AmtInMsat / 1000,
+		AmountOut:    event.AmtOutMsat / 1000,
+		Fee:          event.FeeMsat() / 1000,
+	}
+	inserted, err := c.db.InsertStreamedForwardingEvent(forward)
+	if err != nil {
+		log.Printf("Warning: failed to store streamed forward: %v", err)
+		return
+	}
+	if inserted {
+		recordForward(forward)
+		log.Printf("⚡ Forwarded %s, earning %s", redact.Sats(forward.AmountOut), redact.Sats(forward.Fee))
+	}
+}
+
+// describeHTLCFailure returns a log line for failure
+func describeHTLCFailure(failure *db.HTLCFailure) string {
+	if failure.Outcome == lnd.ForwardLinkFailed {
+		return fmt.Sprintf("⚠️  Refused to forward %s out of %s: %s",
+			redact.Sats(failure.AmountOut), failure.ChannelOutID, failure.Failure)
+	}
+	return fmt.Sprintf("⚠️  Forward of %s out of %s failed downstream",
+		redact.Sats(failure.AmountOut), failure.ChannelOutID)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -11,8 +12,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/brewgator/lightning-node-tools/internal/capture"
 	"github.com/brewgator/lightning-node-tools/internal/db"
 	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/lock"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/startup"
+	"github.com/brewgator/lightning-node-tools/internal/version"
+)
+
+// Collector names recorded in the collector_runs table
+const (
+	collectorName        = "forwarding"
+	catchupCollectorName = "forwarding-catchup"
 )
 
 type Config struct {
@@ -25,36 +38,94 @@ type ForwardingCollector struct {
 	config        *Config
 	db            *db.Database
 	mockMode      bool
-	lastTimestamp int64 // Track last collected timestamp to avoid duplicates
+	lastTimestamp int64             // Track last collected timestamp to avoid duplicates
+	aliases       map[string]string // Peer aliases by pubkey, looked up once
+	nodes         version.Nodes     // LND version, detected on connecting
+	// history stores Lightning balance points when money moves; created on
+	// the first balance event
+	history *lnd.LightningHistoryScanner
 }
 
 func main() {
+	restDefaults := lnd.DefaultRESTConfig()
 	var (
-		dbPath   = flag.String("db", "data/portfolio.db", "Path to SQLite database")
-		interval = flag.Duration("interval", 5*time.Minute, "Collection interval")
-		oneshot  = flag.Bool("oneshot", false, "Run once and exit (for testing)")
-		mockMode = flag.Bool("mock", false, "Use mock data for testing without LND")
-		catchup  = flag.Bool("catchup", false, "Collect entire forwarding history (one-time operation)")
-		days     = flag.Int("days", 30, "Number of days to catch up (only used with --catchup)")
+		dbPath      = flag.String("db", "data/portfolio.db", "Path to SQLite database")
+		profile     = flag.String("profile", db.DefaultProfile, db.ProfileFlagUsage)
+		interval    = flag.Duration("interval", 5*time.Minute, "Collection interval")
+		oneshot     = flag.Bool("oneshot", false, "Run once and exit (for testing)")
+		mockMode    = flag.Bool("mock", false, "Use mock data for testing without LND")
+		ignoreMode  = flag.Bool("ignore-mode-check", false, db.ModeCheckFlagUsage)
+		dryRun      = flag.Bool("dry-run", false, db.DryRunFlagUsage)
+		verboseLogs = flag.Bool("verbose-logs", false, redact.FlagUsage)
+		catchup     = flag.Bool("catchup", false, "Collect entire forwarding history (one-time operation)")
+		days        = flag.Int("days", 30, "Number of days to catch up (only used with --catchup)")
+		resume      = flag.Bool("resume", false, "Continue an interrupted catch-up from its last completed chunk (only used with --catchup)")
+		wait        = flag.Duration("startup-wait", startup.DefaultMaxWait, "How long to wait for LND at startup before starting degraded (0 tries once)")
+		captures    = flag.String("capture-dir", "", "Keep raw lncli responses in this directory for debugging (empty disables)")
+		capMB       = flag.Int64("capture-max-mb", capture.DefaultMaxBytes>>20, "Size cap of --capture-dir in MB; the oldest responses are deleted first")
+		subscribe   = flag.Bool("subscribe", true, "Snapshot balances within seconds of settled invoices and completed payments (needs LND's REST port)")
+		htlcEvents  = flag.Bool("htlc-events", true, "Record forwards and failed HTLCs as they happen, with polling as catch-up (needs LND's REST port)")
+		restHost    = flag.String("rest-host", restDefaults.Host, "LND REST host:port (only used with --subscribe and --htlc-events)")
+		tlsCert     = flag.String("tls-cert", restDefaults.TLSCertPath, "Path to LND's tls.cert (only used with --subscribe and --htlc-events)")
+		macaroon    = flag.String("macaroon", restDefaults.MacaroonPath, "Path to a macaroon allowed to read invoices, payments and HTLC events (only used with --subscribe and --htlc-events)")
+		metricsAddr = flag.String("metrics-addr", "", metrics.AddrFlagUsage)
 	)
 	flag.Parse()
+	redact.SetVerbose(*verboseLogs)
+
+	// A dry run collects once and exits
+	if *dryRun {
+		*oneshot = true
+	}
+
+	profilePath, err := db.ProfilePath(*dbPath, *profile)
+	if err != nil {
+		log.Fatalf("Invalid --profile: %v", err)
+	}
+	*dbPath = profilePath
 
 	// Ensure data directory exists
 	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
 		log.Fatalf("Failed to create data directory: %v", err)
 	}
 
+	instance, err := lock.AcquireUnlessDryRun(*dbPath, "forwarding-collector", *mockMode, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer instance.Release()
+
 	// Initialize database with mock mode support
-	database, err := db.NewDatabaseWithMockMode(*dbPath, *mockMode)
+	openDatabase := db.NewDatabaseWithMockMode
+	if *dryRun {
+		openDatabase = db.OpenDryRun
+	}
+	database, err := openDatabase(*dbPath, *mockMode)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.Close()
+	if *dryRun {
+		defer database.LogDryRun(redact.Verbose())
+	}
+
+	if !*ignoreMode {
+		if err := database.CheckMode(time.Now()); err != nil {
+			log.Fatalf("❌ %v; check --mock, or pass --ignore-mode-check to start anyway", err)
+		}
+	}
 
 	if *mockMode {
 		fmt.Println("📊 Using mock database tables (data will not affect real data)")
 	}
 
+	if *captures != "" {
+		if err := capture.EnableDir(*captures, *capMB); err != nil {
+			log.Fatalf("Failed to enable debug capture: %v", err)
+		}
+		fmt.Printf("🐛 Capturing raw LND responses in %s (up to %d MB)\n", *captures, *capMB)
+	}
+
 	// Initialize LND client
 	var lndClient *lnd.Client
 
@@ -63,9 +134,13 @@ func main() {
 		lndClient = nil
 	} else {
 		var err error
-		lndClient, err = lnd.NewClient()
+		lndClient, err = startup.WaitFor("LND", *wait, lnd.NewClient)
 		if err != nil {
-			log.Fatalf("Failed to initialize LND client: %v (try --mock for testing)", err)
+			// One-off runs need LND now; the daemon keeps retrying on every collection
+			if *oneshot || *catchup {
+				log.Fatalf("Failed to initialize LND client: %v (try --mock for testing)", err)
+			}
+			startup.NotifyDegraded("forwarding-collector", "LND", err)
 		}
 	}
 
@@ -80,29 +155,68 @@ func main() {
 		db:            database,
 		mockMode:      *mockMode,
 		lastTimestamp: time.Now().Add(-24 * time.Hour).Unix(), // Start from 24h ago initially
+		nodes:         version.Nodes{},
+	}
+	if lndClient != nil {
+		collector.detectLNDVersion()
 	}
 
 	if *catchup {
+		startTime := time.Now().AddDate(0, 0, -*days)
+		if *resume {
+			if resumeFrom, ok := collector.resumePoint(catchupCollectorName); ok {
+				startTime = resumeFrom
+				fmt.Printf("Resuming catch-up from %s\n", startTime.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Println("No previous catch-up run to resume, starting from the beginning")
+			}
+		}
+
 		fmt.Printf("Running catch-up collection for the last %d days...\n", *days)
-		if err := collector.catchupForwardingEvents(*days); err != nil {
+		err := database.RecordCollectorRun(catchupCollectorName, func(run *db.CollectorRun) error {
+			return collector.catchupForwardingEvents(run, startTime)
+		})
+		if err != nil {
 			log.Fatalf("Catch-up collection failed: %v", err)
 		}
 		fmt.Println("Catch-up collection completed successfully")
 		return
 	}
 
+	// Pick up where the last completed run stopped
+	if resumeFrom, ok := collector.resumePoint(collectorName); ok {
+		collector.lastTimestamp = resumeFrom.Unix()
+	}
+
 	if *oneshot {
 		fmt.Println("Running forwarding event collection once...")
-		if err := collector.collectForwardingEvents(); err != nil {
+		if err := collector.collect(); err != nil {
 			log.Fatalf("Forwarding event collection failed: %v", err)
 		}
 		fmt.Println("Forwarding event collection completed successfully")
 		return
 	}
 
+	if err := registry.Serve(*metricsAddr, metricsNamespace); err != nil {
+		log.Fatalf("Failed to serve metrics: %v", err)
+	}
+
 	// Set up signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Money movement triggers an extra snapshot between collections, and
+	// forwards are stored as they settle or fail rather than at the next one
+	cfg := lnd.RESTConfig{Host: *restHost, TLSCertPath: *tlsCert, MacaroonPath: *macaroon}
+	events := make(chan lnd.BalanceEvent)
+	if *subscribe && !*mockMode {
+		go watchBalanceEvents(ctx, cfg, events)
+	}
+	forwards := make(chan lnd.ForwardEvent)
+	if *htlcEvents && !*mockMode {
+		go watchForwardEvents(ctx, cfg, forwards)
+	}
+	var snapshotDue <-chan time.Time
 
 	// Start collection loop
 	ticker := time.NewTicker(config.CollectionInterval)
@@ -111,35 +225,121 @@ func main() {
 	fmt.Printf("Starting forwarding event collection every %v...\n", config.CollectionInterval)
 
 	// Collect initial data
-	if err := collector.collectForwardingEvents(); err != nil {
+	if err := collector.collect(); err != nil {
 		log.Printf("Initial forwarding event collection failed: %v", err)
 	}
 
 	for {
 		select {
 		case <-ticker.C:
-			if err := collector.collectForwardingEvents(); err != nil {
+			if err := collector.collect(); err != nil {
 				log.Printf("Forwarding event collection failed: %v", err)
 			}
-		case <-sigChan:
+		case event := <-events:
+			log.Println(describeBalanceEvent(event))
+			if snapshotDue == nil {
+				snapshotDue = time.After(balanceSnapshotDelay)
+			}
+		case forward := <-forwards:
+			collector.recordForwardEvent(forward)
+		case <-snapshotDue:
+			snapshotDue = nil
+			collector.snapshotBalances()
+		case <-ctx.Done():
 			fmt.Println("Received shutdown signal, exiting...")
 			return
 		}
 	}
 }
 
-func (c *ForwardingCollector) collectForwardingEvents() error {
+// collect runs one recorded collection of channel balances and forwarding
+// events, plus a mission control snapshot and daily summaries when due
+func (c *ForwardingCollector) collect() error {
+	if err := c.db.RecordCollectorRun(channelSnapshotCollectorName, c.collectChannelSnapshots); err != nil {
+		log.Printf("Channel snapshot collection failed: %v", err)
+	}
+	if err := c.nodes.Check(version.MissionControl); err != nil {
+		log.Printf("Skipping mission control snapshot: %v", err)
+	} else if c.missionControlDue(time.Now()) {
+		if err := c.db.RecordCollectorRun(missionControlCollectorName, c.collectMissionControl); err != nil {
+			log.Printf("Mission control collection failed: %v", err)
+		}
+	}
+	err := c.db.RecordCollectorRun(collectorName, c.collectForwardingEvents)
+
+	// Store the totals of days that have ended for the chart endpoints; a
+	// no-op until the next UTC day starts
+	if err := c.db.MaterializeDailySummaries(time.Now()); err != nil {
+		log.Printf("Daily summary materialization failed: %v", err)
+	}
+	return err
+}
+
+// connectLND connects to LND if the collector started without it
+func (c *ForwardingCollector) connectLND() error {
+	if c.config.LNDClient != nil {
+		return nil
+	}
+	client, err := lnd.NewClient()
+	if err != nil {
+		return err
+	}
+	log.Println("✅ Connected to LND")
+	c.config.LNDClient = client
+	c.detectLNDVersion()
+	return nil
+}
+
+// detectLNDVersion records the LND version so upgrades are logged and
+// features LND is too old for are skipped
+func (c *ForwardingCollector) detectLNDVersion() {
+	raw, err := c.nodes.Detect(version.NodeLND, lnd.GetVersion)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	changed, err := c.db.RecordNodeVersion(version.NodeLND, raw)
+	if err != nil {
+		log.Printf("Warning: failed to record LND version: %v", err)
+		return
+	}
+	if changed {
+		fmt.Printf("📦 Detected LND %s\n", raw)
+	}
+}
+
+// resumePoint returns the unix timestamp saved by the most recent run of
+// collector that recorded one
+func (c *ForwardingCollector) resumePoint(collector string) (time.Time, bool) {
+	resumePoint, err := c.db.GetLastResumePoint(collector)
+	if err != nil {
+		log.Printf("Warning: failed to read last %s resume point: %v", collector, err)
+		return time.Time{}, false
+	}
+	if resumePoint == "" {
+		return time.Time{}, false
+	}
+
+	unix, err := strconv.ParseInt(resumePoint, 10, 64)
+	if err != nil {
+		log.Printf("Warning: ignoring invalid %s resume point %q", collector, resumePoint)
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+func (c *ForwardingCollector) collectForwardingEvents(run *db.CollectorRun) error {
 	currentTime := time.Now()
 	fmt.Printf("[%s] Collecting forwarding events since %s...\n",
 		currentTime.Format("2006-01-02 15:04:05"),
 		time.Unix(c.lastTimestamp, 0).Format("2006-01-02 15:04:05"))
 
 	if c.mockMode {
-		return c.collectMockForwardingEvents()
+		return c.collectMockForwardingEvents(run)
 	}
 
-	if c.config.LNDClient == nil {
-		return fmt.Errorf("LND client is nil")
+	if err := c.connectLND(); err != nil {
+		return err
 	}
 
 	// Get forwarding history since last collection
@@ -154,6 +354,7 @@ func (c *ForwardingCollector) collectForwardingEvents() error {
 	if len(history.ForwardingEvents) == 0 {
 		fmt.Printf("✅ No new forwarding events since last collection\n")
 		c.lastTimestamp = currentTime.Unix()
+		run.ResumePoint = strconv.FormatInt(c.lastTimestamp, 10)
 		return nil
 	}
 
@@ -163,6 +364,7 @@ func (c *ForwardingCollector) collectForwardingEvents() error {
 		timestamp, err := strconv.ParseInt(event.Timestamp, 10, 64)
 		if err != nil {
 			log.Printf("Warning: invalid timestamp in forwarding event: %v", err)
+			run.Errors++
 			continue
 		}
 
@@ -191,21 +393,29 @@ func (c *ForwardingCollector) collectForwardingEvents() error {
 			Fee:          feeSat,
 		}
 
-		if err := c.db.InsertForwardingEvent(dbEvent); err != nil {
+		// Re-running over the same window must not duplicate events
+		inserted, err := c.db.InsertForwardingEventIfNew(dbEvent)
+		if err != nil {
 			log.Printf("Warning: failed to insert forwarding event: %v", err)
+			run.Errors++
 			continue
 		}
 
-		insertedCount++
+		if inserted {
+			insertedCount++
+			recordForward(dbEvent)
+		}
 	}
 
 	c.lastTimestamp = currentTime.Unix()
+	run.ItemsInserted = int64(insertedCount)
+	run.ResumePoint = strconv.FormatInt(c.lastTimestamp, 10)
 	fmt.Printf("✅ Inserted %d new forwarding events\n", insertedCount)
 
 	return nil
 }
 
-func (c *ForwardingCollector) collectMockForwardingEvents() error {
+func (c *ForwardingCollector) collectMockForwardingEvents(run *db.CollectorRun) error {
 	// Create mock forwarding events for testing
 	now := time.Now()
 
@@ -235,40 +445,50 @@ func (c *ForwardingCollector) collectMockForwardingEvents() error {
 			continue
 		}
 
-		if err := c.db.InsertForwardingEvent(event); err != nil {
+		inserted, err := c.db.InsertForwardingEventIfNew(event)
+		if err != nil {
 			log.Printf("Warning: failed to insert mock forwarding event: %v", err)
+			run.Errors++
 			continue
 		}
-		insertedCount++
+		if inserted {
+			insertedCount++
+			recordForward(event)
+		}
 	}
 
 	c.lastTimestamp = now.Unix()
+	run.ItemsInserted = int64(insertedCount)
+	run.ResumePoint = strconv.FormatInt(c.lastTimestamp, 10)
 	fmt.Printf("✅ Inserted %d mock forwarding events\n", insertedCount)
 
 	return nil
 }
 
-func (c *ForwardingCollector) catchupForwardingEvents(days int) error {
+// catchupForwardingEvents collects forwarding history from startTime until now.
+// The run's resume point advances past each chunk once it and every chunk
+// before it have been stored, so an interrupted catch-up can be resumed.
+func (c *ForwardingCollector) catchupForwardingEvents(run *db.CollectorRun, startTime time.Time) error {
 	if c.mockMode {
 		fmt.Println("⚠️  Mock mode - catch-up will create synthetic historical data")
-		return c.catchupMockForwardingEvents(days)
+		return c.catchupMockForwardingEvents(run, startTime)
 	}
 
-	if c.config.LNDClient == nil {
-		return fmt.Errorf("LND client is nil")
+	if err := c.connectLND(); err != nil {
+		return err
 	}
 
 	endTime := time.Now()
-	startTime := endTime.AddDate(0, 0, -days)
 
 	fmt.Printf("📅 Collecting forwarding history from %s to %s (%d days)\n",
 		startTime.Format("2006-01-02"),
 		endTime.Format("2006-01-02"),
-		days)
+		int(endTime.Sub(startTime).Hours()/24))
 
 	// Process in chunks to avoid overwhelming LND API
 	chunkDays := 7 // Process 1 week at a time
 	totalInserted := 0
+	chunkFailed := false
 
 	for currentStart := startTime; currentStart.Before(endTime); {
 		currentEnd := currentStart.AddDate(0, 0, chunkDays)
@@ -287,6 +507,8 @@ func (c *ForwardingCollector) catchupForwardingEvents(days int) error {
 		if err != nil {
 			log.Printf("Warning: failed to get forwarding history for chunk %s-%s: %v",
 				currentStart.Format("2006-01-02"), currentEnd.Format("2006-01-02"), err)
+			run.Errors++
+			chunkFailed = true
 			currentStart = currentEnd
 			continue
 		}
@@ -296,6 +518,7 @@ func (c *ForwardingCollector) catchupForwardingEvents(days int) error {
 			timestamp, err := strconv.ParseInt(event.Timestamp, 10, 64)
 			if err != nil {
 				log.Printf("Warning: invalid timestamp in forwarding event: %v", err)
+				run.Errors++
 				continue
 			}
 
@@ -317,18 +540,30 @@ func (c *ForwardingCollector) catchupForwardingEvents(days int) error {
 				Fee:          feeSat,
 			}
 
-			// Use INSERT OR IGNORE to handle potential duplicates
-			if err := c.db.InsertForwardingEventIgnoreDuplicate(dbEvent); err != nil {
+			// Skip duplicates so overlapping or resumed catch-ups are safe
+			inserted, err := c.db.InsertForwardingEventIfNew(dbEvent)
+			if err != nil {
 				log.Printf("Warning: failed to insert forwarding event: %v", err)
+				run.Errors++
 				continue
 			}
 
-			chunkInserted++
+			if inserted {
+				chunkInserted++
+			}
 		}
 
 		totalInserted += chunkInserted
 		fmt.Printf("✅ Chunk complete: %d events inserted (%d total so far)\n", chunkInserted, totalInserted)
 
+		run.ItemsInserted = int64(totalInserted)
+		if !chunkFailed {
+			run.ResumePoint = strconv.FormatInt(currentEnd.Unix(), 10)
+		}
+		if err := c.db.UpdateCollectorRunProgress(run); err != nil {
+			log.Printf("Warning: failed to save catch-up progress: %v", err)
+		}
+
 		currentStart = currentEnd
 
 		// Small delay between chunks to be nice to LND
@@ -340,11 +575,10 @@ func (c *ForwardingCollector) catchupForwardingEvents(days int) error {
 	return nil
 }
 
-func (c *ForwardingCollector) catchupMockForwardingEvents(days int) error {
-	fmt.Printf("🎭 Creating mock forwarding history for %d days\n", days)
-
+func (c *ForwardingCollector) catchupMockForwardingEvents(run *db.CollectorRun, startTime time.Time) error {
 	endTime := time.Now()
-	startTime := endTime.AddDate(0, 0, -days)
+	days := int(endTime.Sub(startTime).Hours()/24) + 1
+	fmt.Printf("🎭 Creating mock forwarding history for %d days\n", days)
 
 	// Create mock events distributed across the time range
 	eventsPerDay := 10
@@ -374,14 +608,20 @@ func (c *ForwardingCollector) catchupMockForwardingEvents(days int) error {
 		// Calculate amount out based on fee
 		mockEvent.AmountOut = mockEvent.AmountIn - mockEvent.Fee
 
-		if err := c.db.InsertForwardingEventIgnoreDuplicate(mockEvent); err != nil {
+		inserted, err := c.db.InsertForwardingEventIfNew(mockEvent)
+		if err != nil {
 			log.Printf("Warning: failed to insert mock forwarding event: %v", err)
+			run.Errors++
 			continue
 		}
 
-		totalInserted++
+		if inserted {
+			totalInserted++
+		}
 	}
 
+	run.ItemsInserted = int64(totalInserted)
+	run.ResumePoint = strconv.FormatInt(endTime.Unix(), 10)
 	fmt.Printf("✅ Mock catch-up complete: %d events created\n", totalInserted)
 	return nil
 }
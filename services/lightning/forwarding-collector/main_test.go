@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
 	"github.com/brewgator/lightning-node-tools/internal/testutils"
 )
 
@@ -90,3 +92,74 @@ func TestForwardingEventDuplicateHandling(t *testing.T) {
 		testutils.AssertEqual(t, feeData[0].ForwardCount, int64(1))
 	}
 }
+
+func TestMissionControlSnapshotInterval(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+	database, err := db.NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	collector := &ForwardingCollector{db: database, mockMode: true}
+
+	now := time.Now()
+	testutils.AssertEqual(t, collector.missionControlDue(now), true)
+
+	err = database.RecordCollectorRun(missionControlCollectorName, collector.collectMissionControl)
+	testutils.AssertNoError(t, err)
+
+	pairs, err := database.GetLatestMissionControlPairs("")
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(pairs), 2)
+
+	testutils.AssertEqual(t, collector.missionControlDue(now.Add(time.Minute)), false)
+	testutils.AssertEqual(t, collector.missionControlDue(now.Add(missionControlInterval+time.Minute)), true)
+}
+
+func TestSnapshotBalances(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+	database, err := db.NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	collector := &ForwardingCollector{db: database, mockMode: true}
+	collector.snapshotBalances()
+
+	snapshots, err := database.GetLatestChannelSnapshots()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(snapshots) > 0, true)
+	// Mock mode has no LND to sync Lightning history from
+	testutils.AssertEqual(t, collector.history == nil, true)
+
+	testutils.AssertEqual(t, describeBalanceEvent(lnd.BalanceEvent{Type: lnd.BalanceEventInvoice, AmountSat: 21000}),
+		"⚡ Invoice settled: received "+redact.Sats(21000))
+}
+
+func TestRecordForwardEvent(t *testing.T) {
+	dbPath := testutils.CreateTestDBPath(t)
+	database, err := db.NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	defer database.Close()
+
+	collector := &ForwardingCollector{db: database, mockMode: true}
+	now := time.Now()
+	collector.recordForwardEvent(lnd.ForwardEvent{
+		Outcome: lnd.ForwardSettled, Time: now.Add(-time.Minute), ChanIDIn: "111", ChanIDOut: "222",
+		AmtInMsat: 100_200_500, AmtOutMsat: 100_000_000,
+	})
+	collector.recordForwardEvent(lnd.ForwardEvent{
+		Outcome: lnd.ForwardLinkFailed, Time: now, ChanIDIn: "111", ChanIDOut: "333",
+		AmtInMsat: 2_000_020_000, AmtOutMsat: 2_000_000_000, Failure: "INSUFFICIENT_BALANCE",
+	})
+
+	stats, err := database.GetForwardingStats(now.Add(-time.Hour), now.Add(time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stats.ForwardCount, int64(1))
+	testutils.AssertEqual(t, stats.TotalFees, int64(200))
+
+	failures, err := database.GetHTLCFailures(now.Add(-time.Hour), now.Add(time.Hour))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(failures), 1)
+	testutils.AssertEqual(t, failures[0].AmountOut, int64(2_000_000))
+	testutils.AssertEqual(t, failures[0].Fee, int64(20))
+	testutils.AssertEqual(t, failures[0].Failure, "INSUFFICIENT_BALANCE")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+)
+
+// metricsNamespace prefixes the collector's Prometheus metric names
+const metricsNamespace = "forwarding_collector"
+
+// registry holds the forwarding totals, served next to the shared collector
+// run and lncli metrics with --metrics-addr
+var registry = metrics.NewRegistry()
+
+var (
+	forwards = registry.NewCounter("forwards_total",
+		"Forwarding events collected")
+	forwardedSats = registry.NewCounter("forwarded_sats_total",
+		"Sats sent out by the forwarding events collected")
+	forwardingFees = registry.NewCounter("forwarding_fees_sats_total",
+		"Fees earned by the forwarding events collected, in sats")
+	htlcFailures = registry.NewCounter("htlc_failures_total",
+		"Forwards seen failing on LND's HTLC event stream, by outcome (failed or link_failed)", "outcome")
+)
+
+// recordForward counts a newly collected forwarding event
+func recordForward(event *db.ForwardingEvent) {
+	forwards.Inc()
+	forwardedSats.Add(float64(event.AmountOut))
+	forwardingFees.Add(float64(event.Fee))
+}
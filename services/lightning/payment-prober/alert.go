@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// ReliabilityAlert tracks whether payment reliability is below Threshold so
+// that one alert is sent when it drops and one when it recovers, rather than
+// one per probe round
+type ReliabilityAlert struct {
+	// Threshold is the success rate (0-1) below which reliability is degraded
+	Threshold float64
+	// MinProbes is the number of probes in the window needed before alerting
+	MinProbes int64
+	Window    time.Duration
+
+	degraded bool
+}
+
+// Check compares the stats of the alert window against the threshold and
+// returns a message when reliability crossed it, or "" otherwise
+func (a *ReliabilityAlert) Check(stats db.ProbeStats) string {
+	if stats.Probes < a.MinProbes {
+		return ""
+	}
+
+	switch {
+	case !a.degraded && stats.SuccessRate < a.Threshold:
+		a.degraded = true
+		return fmt.Sprintf("⚠️ Payment reliability dropped to %.0f%% (%d of %d probes reached their destination in the last %v, threshold %.0f%%)",
+			stats.SuccessRate*100, stats.Successes, stats.Probes, a.Window, a.Threshold*100)
+	case a.degraded && stats.SuccessRate >= a.Threshold:
+		a.degraded = false
+		return fmt.Sprintf("✅ Payment reliability recovered to %.0f%% (%d of %d probes in the last %v)",
+			stats.SuccessRate*100, stats.Successes, stats.Probes, a.Window)
+	}
+	return ""
+}
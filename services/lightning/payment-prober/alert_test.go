@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestReliabilityAlert(t *testing.T) {
+	alert := &ReliabilityAlert{Threshold: 0.8, MinProbes: 5, Window: 6 * time.Hour}
+
+	stats := func(successes, probes int64) db.ProbeStats {
+		return db.ProbeStats{Probes: probes, Successes: successes, SuccessRate: float64(successes) / float64(probes)}
+	}
+
+	tests := []struct {
+		name  string
+		stats db.ProbeStats
+		want  string
+	}{
+		{"too few probes", stats(0, 4), ""},
+		{"healthy", stats(9, 10), ""},
+		{"drops", stats(5, 10), "dropped to 50%"},
+		{"still degraded", stats(4, 10), ""},
+		{"recovers", stats(8, 10), "recovered to 80%"},
+		{"stays healthy", stats(10, 10), ""},
+	}
+
+	for _, tt := range tests {
+		message := alert.Check(tt.stats)
+		if tt.want == "" {
+			testutils.AssertEqual(t, message, "")
+			continue
+		}
+		if !strings.Contains(message, tt.want) {
+			t.Errorf("%s: expected message containing %q, got %q", tt.name, tt.want, message)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/lock"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/notify"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// collectorName is recorded in the collector_runs table
+const collectorName = "payment-prober"
+
+// metricsNamespace prefixes the collector's Prometheus metric names
+const metricsNamespace = "payment_prober"
+
+// defaultTargets are large, well-connected routing nodes that most payments
+// can reach: ACINQ, Bitfinex, Kraken and Wallet of Satoshi
+var defaultTargets = []string{
+	"03864ef025fde8fb587d989186ce6a4a186895ee44a926bfc370e2c366597a3f8f",
+	"033d8656219478701227199cbd6f670335c8d408a92ae88b962c49d4dc0e83e025",
+	"02f1a8c87607f415c8f22c00593002775941dea48869ce23096af27b0cfdcc0b69",
+	"035e4ff418fc8b5554c5d9eea66396c227bd429a3251c8cbc711002ba215bfc226",
+}
+
+type PaymentProber struct {
+	db       *db.Database
+	targets  []string
+	amount   int64
+	feeLimit int64
+	timeout  time.Duration
+	alert    *ReliabilityAlert
+	telegram notify.Telegram
+	mockMode bool
+}
+
+func main() {
+	var (
+		dbPath         = flag.String("db", "data/portfolio.db", "Path to SQLite database")
+		profile        = flag.String("profile", db.DefaultProfile, db.ProfileFlagUsage)
+		interval       = flag.Duration("interval", 30*time.Minute, "Probe interval")
+		oneshot        = flag.Bool("oneshot", false, "Run once and exit (for testing)")
+		mockMode       = flag.Bool("mock", false, "Use mock data for testing without LND")
+		ignoreMode     = flag.Bool("ignore-mode-check", false, db.ModeCheckFlagUsage)
+		dryRun         = flag.Bool("dry-run", false, db.DryRunFlagUsage)
+		verboseLogs    = flag.Bool("verbose-logs", false, redact.FlagUsage)
+		targets        = flag.String("targets", strings.Join(defaultTargets, ","), "Comma-separated node pubkeys to probe")
+		amount         = flag.Int64("amount", 1000, "Probe amount in sats; probes never settle, so nothing is spent")
+		feeLimit       = flag.Int64("fee-limit", 10, "Maximum routing fee in sats a probe route may cost")
+		timeout        = flag.Duration("timeout", time.Minute, "How long a probe may search for a route")
+		alertThreshold = flag.Float64("alert-threshold", 0.8, "Alert when the success rate over --alert-window drops below this ratio (0 disables)")
+		alertWindow    = flag.Duration("alert-window", 6*time.Hour, "Window the alert success rate is measured over")
+		alertMinProbes = flag.Int64("alert-min-probes", 5, "Probes needed in the window before alerting")
+		metricsAddr    = flag.String("metrics-addr", "", metrics.AddrFlagUsage)
+	)
+	flag.Parse()
+	redact.SetVerbose(*verboseLogs)
+
+	// A dry run collects once and exits
+	if *dryRun {
+		*oneshot = true
+	}
+
+	targetList, err := parseTargets(*targets)
+	if err != nil {
+		log.Fatalf("Invalid --targets: %v", err)
+	}
+	if *amount <= 0 {
+		log.Fatal("--amount must be positive")
+	}
+	if *alertThreshold < 0 || *alertThreshold > 1 {
+		log.Fatal("--alert-threshold must be between 0 and 1")
+	}
+
+	profilePath, err := db.ProfilePath(*dbPath, *profile)
+	if err != nil {
+		log.Fatalf("Invalid --profile: %v", err)
+	}
+	*dbPath = profilePath
+
+	// Ensure data directory exists
+	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	instance, err := lock.AcquireUnlessDryRun(*dbPath, "payment-prober", *mockMode, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer instance.Release()
+
+	// Initialize database with mock mode support
+	openDatabase := db.NewDatabaseWithMockMode
+	if *dryRun {
+		openDatabase = db.OpenDryRun
+	}
+	database, err := openDatabase(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+	if *dryRun {
+		defer database.LogDryRun(redact.Verbose())
+	}
+
+	if !*ignoreMode {
+		if err := database.CheckMode(time.Now()); err != nil {
+			log.Fatalf("❌ %v; check --mock, or pass --ignore-mode-check to start anyway", err)
+		}
+	}
+
+	prober := &PaymentProber{
+		db:       database,
+		targets:  targetList,
+		amount:   *amount,
+		feeLimit: *feeLimit,
+		timeout:  *timeout,
+		// Alerts go to Telegram when the monitor's bot is configured
+		telegram: notify.Telegram{BotToken: os.Getenv("BOT_TOKEN"), ChatID: os.Getenv("CHAT_ID")},
+		mockMode: *mockMode,
+	}
+	// A dry run never alerts, it only shows what the probes found
+	if *alertThreshold > 0 && !*dryRun {
+		prober.alert = &ReliabilityAlert{Threshold: *alertThreshold, MinProbes: *alertMinProbes, Window: *alertWindow}
+	}
+
+	if *mockMode {
+		fmt.Println("📊 Using mock database tables (data will not affect real data)")
+		fmt.Println("⚠️  Running in mock mode - using test data")
+	}
+	fmt.Printf("🎯 Probing %d nodes with %d sat payments\n", len(targetList), *amount)
+
+	if *oneshot {
+		fmt.Println("Running payment probes once...")
+		if err := prober.probe(); err != nil {
+			log.Fatalf("Payment probes failed: %v", err)
+		}
+		fmt.Println("Payment probes completed successfully")
+		return
+	}
+
+	if err := metrics.Default.Serve(*metricsAddr, metricsNamespace); err != nil {
+		log.Fatalf("Failed to serve metrics: %v", err)
+	}
+
+	// Set up signal handling for graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Starting payment probes every %v...\n", *interval)
+
+	// Probe once at startup
+	if err := prober.probe(); err != nil {
+		log.Printf("Initial payment probes failed: %v", err)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := prober.probe(); err != nil {
+				log.Printf("Payment probes failed: %v", err)
+			}
+		case <-sigChan:
+			fmt.Println("Received shutdown signal, exiting...")
+			return
+		}
+	}
+}
+
+// parseTargets validates a comma-separated list of node pubkeys
+func parseTargets(list string) ([]string, error) {
+	var targets []string
+	for _, pubkey := range strings.Split(list, ",") {
+		pubkey = strings.ToLower(strings.TrimSpace(pubkey))
+		if pubkey == "" {
+			continue
+		}
+		if !utils.ValidateNodePubkey(pubkey) {
+			return nil, fmt.Errorf("invalid node pubkey %q", pubkey)
+		}
+		targets = append(targets, pubkey)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no node pubkeys given")
+	}
+	return targets, nil
+}
+
+// probe runs one recorded round of probes and then checks the alert
+func (p *PaymentProber) probe() error {
+	if err := p.db.RecordCollectorRun(collectorName, p.probeTargets); err != nil {
+		return err
+	}
+	p.checkReliability()
+	return nil
+}
+
+// probeTargets sends one probe to each target in turn. A probe that could
+// not be sent at all, e.g. because lncli failed, is counted as an error
+// rather than a failed probe so it does not lower the reliability figure.
+func (p *PaymentProber) probeTargets(run *db.CollectorRun) error {
+	fmt.Printf("[%s] Sending payment probes...\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	for _, target := range p.targets {
+		timestamp := time.Now()
+		var result *lnd.ProbeResult
+		if p.mockMode {
+			result = mockProbe()
+		} else {
+			var err error
+			result, err = lnd.SendProbe(target, p.amount, p.feeLimit, p.timeout)
+			if err != nil {
+				log.Printf("  ❌ Failed to probe %s: %v", target, err)
+				run.Errors++
+				continue
+			}
+		}
+
+		record := &db.ProbeResult{
+			Timestamp:     timestamp,
+			Destination:   target,
+			Amount:        p.amount,
+			Success:       result.Reached,
+			FailureReason: result.FailureReason,
+			LatencyMs:     result.Latency.Milliseconds(),
+			Attempts:      int64(result.Attempts),
+			Hops:          int64(result.Hops),
+			FeeMsat:       result.FeeMsat,
+		}
+		if err := p.db.InsertProbeResult(record); err != nil {
+			return fmt.Errorf("failed to store probe of %s: %w", target, err)
+		}
+		run.ItemsInserted++
+
+		if result.Reached {
+			fmt.Printf("  ✅ %s reached in %v over %d hops\n", target, result.Latency.Round(time.Millisecond), result.Hops)
+		} else {
+			fmt.Printf("  ❌ %s not reached after %d attempts: %s\n", target, result.Attempts, result.FailureReason)
+		}
+	}
+
+	if run.ItemsInserted == 0 && run.Errors > 0 {
+		return fmt.Errorf("failed to send any probe")
+	}
+	return nil
+}
+
+// checkReliability measures the success rate over the alert window and
+// notifies Telegram when it crosses the threshold
+func (p *PaymentProber) checkReliability() {
+	if p.alert == nil {
+		return
+	}
+
+	now := time.Now()
+	stats, _, err := p.db.GetProbeStats(now.Add(-p.alert.Window), now)
+	if err != nil {
+		log.Printf("Warning: failed to read probe stats: %v", err)
+		return
+	}
+	fmt.Printf("📶 Payment reliability over the last %v: %.0f%% of %d probes\n",
+		p.alert.Window, stats.SuccessRate*100, stats.Probes)
+
+	message := p.alert.Check(*stats)
+	if message == "" {
+		return
+	}
+	log.Println(message)
+	if p.telegram.Enabled() {
+		if err := p.telegram.Send(message); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// mockProbe returns a made-up probe that reaches its destination nine times
+// out of ten
+func mockProbe() *lnd.ProbeResult {
+	hops := 2 + rand.Intn(3)
+	if rand.Intn(10) == 0 {
+		return &lnd.ProbeResult{FailureReason: "FAILURE_REASON_NO_ROUTE", Attempts: 3, Hops: hops}
+	}
+	return &lnd.ProbeResult{
+		Reached:  true,
+		Attempts: 1 + rand.Intn(2),
+		Hops:     hops,
+		FeeMsat:  int64(hops) * 1000,
+		Latency:  time.Duration(500+rand.Intn(2500)) * time.Millisecond,
+	}
+}
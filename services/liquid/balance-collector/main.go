@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/liquid"
+	"github.com/brewgator/lightning-node-tools/internal/lock"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/startup"
+)
+
+// collectorName is recorded in the collector_runs table
+const collectorName = "liquid-balance"
+
+// metricsNamespace prefixes the collector's Prometheus metric names
+const metricsNamespace = "liquid_balance_collector"
+
+type BalanceCollector struct {
+	client   *liquid.Client
+	wallet   string
+	db       *db.Database
+	mockMode bool
+}
+
+func main() {
+	var (
+		dbPath      = flag.String("db", "data/portfolio.db", "Path to SQLite database")
+		profile     = flag.String("profile", db.DefaultProfile, db.ProfileFlagUsage)
+		interval    = flag.Duration("interval", 15*time.Minute, "Collection interval")
+		oneshot     = flag.Bool("oneshot", false, "Run once and exit (for testing)")
+		mockMode    = flag.Bool("mock", false, "Use mock data for testing without an Elements node")
+		ignoreMode  = flag.Bool("ignore-mode-check", false, db.ModeCheckFlagUsage)
+		dryRun      = flag.Bool("dry-run", false, db.DryRunFlagUsage)
+		verboseLogs = flag.Bool("verbose-logs", false, redact.FlagUsage)
+		wallet      = flag.String("wallet", "", "Elements wallet to read, for nodes with more than one loaded")
+		wait        = flag.Duration("startup-wait", startup.DefaultMaxWait, "How long to wait for Elements at startup before starting degraded (0 tries once)")
+		metricsAddr = flag.String("metrics-addr", "", metrics.AddrFlagUsage)
+	)
+	flag.Parse()
+	redact.SetVerbose(*verboseLogs)
+
+	// A dry run collects once and exits
+	if *dryRun {
+		*oneshot = true
+	}
+
+	profilePath, err := db.ProfilePath(*dbPath, *profile)
+	if err != nil {
+		log.Fatalf("Invalid --profile: %v", err)
+	}
+	*dbPath = profilePath
+
+	// Ensure data directory exists
+	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	instance, err := lock.AcquireUnlessDryRun(*dbPath, "liquid-balance-collector", *mockMode, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer instance.Release()
+
+	// Initialize database with mock mode support
+	openDatabase := db.NewDatabaseWithMockMode
+	if *dryRun {
+		openDatabase = db.OpenDryRun
+	}
+	database, err := openDatabase(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+	if *dryRun {
+		defer database.LogDryRun(redact.Verbose())
+	}
+
+	if !*ignoreMode {
+		if err := database.CheckMode(time.Now()); err != nil {
+			log.Fatalf("❌ %v; check --mock, or pass --ignore-mode-check to start anyway", err)
+		}
+	}
+
+	collector := &BalanceCollector{
+		wallet:   *wallet,
+		db:       database,
+		mockMode: *mockMode,
+	}
+
+	if *mockMode {
+		fmt.Println("📊 Using mock database tables (data will not affect real data)")
+		fmt.Println("⚠️  Running in mock mode - using test data")
+	} else {
+		collector.client, err = startup.WaitFor("Elements", *wait, func() (*liquid.Client, error) {
+			return liquid.NewClient(*wallet)
+		})
+		if err != nil {
+			// A one-off run needs Elements now; the daemon keeps retrying on every collection
+			if *oneshot {
+				log.Fatalf("❌ %v", err)
+			}
+			startup.NotifyDegraded("liquid-balance-collector", "Elements", err)
+		} else {
+			fmt.Println("💧 Connected to Elements node")
+		}
+	}
+
+	if *oneshot {
+		fmt.Println("Running Liquid balance collection once...")
+		if err := collector.collectBalance(); err != nil {
+			log.Fatalf("Liquid balance collection failed: %v", err)
+		}
+		fmt.Println("Liquid balance collection completed successfully")
+		return
+	}
+
+	if err := metrics.Default.Serve(*metricsAddr, metricsNamespace); err != nil {
+		log.Fatalf("Failed to serve metrics: %v", err)
+	}
+
+	// Set up signal handling for graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Starting Liquid balance collection every %v...\n", *interval)
+
+	// Collect initial data
+	if err := collector.collectBalance(); err != nil {
+		log.Printf("Initial Liquid balance collection failed: %v", err)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := collector.collectBalance(); err != nil {
+				log.Printf("Liquid balance collection failed: %v", err)
+			}
+		case <-sigChan:
+			fmt.Println("Received shutdown signal, exiting...")
+			return
+		}
+	}
+}
+
+// collectBalance runs one recorded collection
+func (c *BalanceCollector) collectBalance() error {
+	return c.db.RecordCollectorRun(collectorName, c.collectBalanceSnapshot)
+}
+
+func (c *BalanceCollector) collectBalanceSnapshot(run *db.CollectorRun) error {
+	fmt.Printf("[%s] Collecting Liquid balance...\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	var snapshot *db.LiquidBalanceSnapshot
+	if c.mockMode {
+		snapshot = &db.LiquidBalanceSnapshot{
+			Timestamp:   time.Now(),
+			Confirmed:   2500000,
+			Unconfirmed: 10000,
+		}
+	} else {
+		if c.client == nil {
+			client, err := liquid.NewClient(c.wallet)
+			if err != nil {
+				return err
+			}
+			fmt.Println("💧 Connected to Elements node")
+			c.client = client
+		}
+		balance, err := c.client.GetBalance()
+		if err != nil {
+			return fmt.Errorf("failed to get Liquid balance: %w", err)
+		}
+		snapshot = &db.LiquidBalanceSnapshot{
+			Timestamp:   balance.Timestamp,
+			Confirmed:   balance.Confirmed,
+			Unconfirmed: balance.Unconfirmed,
+		}
+	}
+
+	if err := c.db.InsertLiquidBalanceSnapshot(snapshot); err != nil {
+		return fmt.Errorf("failed to store Liquid balance: %w", err)
+	}
+	run.ItemsInserted = 1
+
+	fmt.Printf("  💧 L-BTC: Confirmed=%s, Unconfirmed=%s\n", redact.Amount(snapshot.Confirmed), redact.Amount(snapshot.Unconfirmed))
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/brewgator/lightning-node-tools/internal/listen"
+)
+
+// The API is served on two listeners. The public listener is what the
+// dashboard and any reverse proxy talk to, and apart from channel requests
+// (see channel_requests.go) it only reads. The admin listener is bound to a
+// loopback address or a unix socket and additionally serves the endpoints
+// that change data or expose internals: imports, transfers, leases, peer
+// policies, peer notes, tracked addresses, offline accounts, withdrawal and
+// channel request approvals and /system. Both listeners share one route table;
+// registerAPIRoutes wraps admin endpoints so the public listener refuses them.
+// Endpoints served on both listeners check isAdminRequest to leave private
+// details, such as the notes about a channel's peer, out of public answers.
+
+// DefaultAdminAddr is where the admin listener is served by default
+const DefaultAdminAddr = "127.0.0.1:8091"
+
+// adminRoute serves an admin endpoint on the admin listener
+func adminRoute(next http.HandlerFunc) http.HandlerFunc {
+	return next
+}
+
+type adminKey struct{}
+
+// markAdmin flags the requests of the admin listener for isAdminRequest
+func markAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminKey{}, true)))
+	})
+}
+
+// isAdminRequest reports whether r came in on the admin listener
+func isAdminRequest(r *http.Request) bool {
+	admin, _ := r.Context().Value(adminKey{}).(bool)
+	return admin
+}
+
+// adminOnly stands in for admin endpoints on the public listener. Refusing
+// them with 403, rather than leaving them unrouted, tells clients the
+// endpoint exists and where to find it.
+func (s *Server) adminOnly(http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.writeError(w, http.StatusForbidden, "This endpoint is only served on the admin listener (--admin-addr)")
+	}
+}
+
+// listenAdmin opens the admin listener. addr is a loopback host:port, or
+// unix:/path for a socket only local users in the service's group can open.
+// Other addresses are refused so the admin API cannot be exposed by mistake.
+func listenAdmin(addr string) (net.Listener, error) {
+	if _, ok := listen.IsUnix(addr); ok {
+		return listen.Listen(addr)
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin address %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("admin address %q is not a loopback address or unix socket", addr)
+	}
+	return listen.Listen(addr)
+}
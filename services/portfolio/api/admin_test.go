@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/listen"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestAdminEndpointsOnlyOnAdminListener(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	serve := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	const payload = `{"address": "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "label": "Test"}`
+
+	for _, prefix := range []string{APIPrefix, LegacyAPIPrefix} {
+		rr := serve(server.router, "POST", prefix+"/onchain/addresses", payload)
+		testutils.AssertEqual(t, rr.Code, http.StatusForbidden)
+		testutils.AssertEqual(t, strings.Contains(rr.Body.String(), "admin listener"), true)
+
+		testutils.AssertEqual(t, serve(server.router, "GET", prefix+"/system/collector-runs", "").Code, http.StatusForbidden)
+	}
+
+	// Nothing was added through the public listener
+	addresses, err := server.db.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(addresses), 0)
+
+	testutils.AssertEqual(t, serve(server.adminRouter, "POST", "/api/v1/onchain/addresses", payload).Code, http.StatusOK)
+	testutils.AssertEqual(t, serve(server.adminRouter, "GET", "/api/v1/system/collector-runs", "").Code, http.StatusOK)
+
+	// Reads are served on both listeners
+	testutils.AssertEqual(t, serve(server.router, "GET", "/api/v1/onchain/addresses", "").Code, http.StatusOK)
+	testutils.AssertEqual(t, serve(server.adminRouter, "GET", "/api/v1/onchain/addresses", "").Code, http.StatusOK)
+	testutils.AssertEqual(t, serve(server.router, "GET", "/api/v1/health", "").Code, http.StatusOK)
+
+	// Unrouted methods are still reported per listener
+	rr := serve(server.adminRouter, "PATCH", "/api/v1/onchain/addresses", "")
+	testutils.AssertEqual(t, rr.Code, http.StatusMethodNotAllowed)
+	testutils.AssertEqual(t, rr.Header().Get("Allow"), "GET, POST")
+}
+
+func TestListenAdmin(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", "192.168.1.10:0", ":0", "example.com:0", "127.0.0.1"} {
+		if listener, err := listenAdmin(addr); err == nil {
+			listener.Close()
+			t.Errorf("listenAdmin(%q) succeeded, want it refused", addr)
+		}
+	}
+
+	for _, addr := range []string{"127.0.0.1:0", "localhost:0"} {
+		listener, err := listenAdmin(addr)
+		testutils.AssertNoError(t, err)
+		listener.Close()
+	}
+}
+
+func TestListenAdminUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+
+	listener, err := listenAdmin("unix:" + path)
+	testutils.AssertNoError(t, err)
+	info, err := os.Stat(path)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, info.Mode().Perm(), os.FileMode(listen.SocketMode))
+	listener.Close()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// MaxAnnotationLength caps the text of an annotation, in characters
+const MaxAnnotationLength = 280
+
+// AnnotationRequest represents the request body for adding an annotation
+type AnnotationRequest struct {
+	// Timestamp defaults to now
+	Timestamp time.Time `json:"timestamp"`
+	Text      string    `json:"text"`
+}
+
+// handleGetAnnotations handles GET /api/annotations
+func (s *Server) handleGetAnnotations(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+
+	annotations, err := s.db.GetAnnotations(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleGetAnnotations: failed to get annotations: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get annotations")
+		return
+	}
+	if annotations == nil {
+		annotations = []db.Annotation{}
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: annotations})
+}
+
+// handleAddAnnotation handles POST /api/annotations
+func (s *Server) handleAddAnnotation(w http.ResponseWriter, r *http.Request) {
+	var req AnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "text", Message: "text is required"})
+		return
+	}
+	if utf8.RuneCountInString(text) > MaxAnnotationLength {
+		s.writeValidationError(w, &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   "text",
+			Message: fmt.Sprintf("text must be at most %d characters", MaxAnnotationLength),
+		})
+		return
+	}
+	if req.Timestamp.IsZero() {
+		req.Timestamp = time.Now()
+	}
+	if req.Timestamp.After(time.Now()) {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeOutOfRange, Field: "timestamp", Message: "timestamp must not be in the future"})
+		return
+	}
+
+	annotation := &db.Annotation{Timestamp: req.Timestamp.UTC(), Text: text}
+	if err := s.db.InsertAnnotation(annotation); err != nil {
+		log.Printf("handleAddAnnotation: failed to insert annotation: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to add annotation")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: annotation})
+}
+
+// handleDeleteAnnotation handles DELETE /api/annotations/{id}
+func (s *Server) handleDeleteAnnotation(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "annotation")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	err := s.db.DeleteAnnotation(id)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Annotation not found")
+		return
+	}
+	if err != nil {
+		log.Printf("handleDeleteAnnotation: failed to delete annotation %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to delete annotation")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"message": "Annotation deleted successfully",
+			"id":      id,
+		},
+	})
+}
+
+// writeHistory writes a history response with the annotations in its time
+// range alongside the data. Annotations only decorate the chart, so failing
+// to read them leaves them out rather than failing the request.
+func (s *Server) writeHistory(w http.ResponseWriter, r *http.Request, data interface{}) {
+	tr := timeRangeFrom(r)
+	annotations, err := s.db.GetAnnotations(tr.From, tr.To)
+	if err != nil {
+		log.Printf("Warning: Failed to get annotations: %v", err)
+	}
+	s.writeJSON(w, APIResponse{Success: true, Data: data, Annotations: annotations})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestAnnotationsEndpoints(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	timestamp := time.Now().AddDate(0, 0, -3).UTC().Format(time.RFC3339)
+	rr := do("POST", "/api/v1/annotations", `{"text": " opened 3 channels ", "timestamp": "`+timestamp+`"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var created struct {
+		Data db.Annotation `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	testutils.AssertEqual(t, created.Data.Text, "opened 3 channels")
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	for _, body := range []string{
+		`{"text": "  "}`,
+		`{"text": "` + strings.Repeat("a", MaxAnnotationLength+1) + `"}`,
+		`{"text": "later", "timestamp": "` + future + `"}`,
+	} {
+		testutils.AssertEqual(t, do("POST", "/api/v1/annotations", body).Code, http.StatusBadRequest)
+	}
+
+	// History responses carry the annotations in their range
+	var history struct {
+		Annotations []db.Annotation `json:"annotations"`
+	}
+	rr = do("GET", "/api/v1/portfolio/history?days=7", "")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &history))
+	testutils.AssertEqual(t, len(history.Annotations), 1)
+	testutils.AssertEqual(t, history.Annotations[0].ID, created.Data.ID)
+
+	rr = do("GET", "/api/v1/portfolio/history?days=1", "")
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(), `"annotations"`), false)
+
+	path := fmt.Sprintf("/api/v1/annotations/%d", created.Data.ID)
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusOK)
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusNotFound)
+}
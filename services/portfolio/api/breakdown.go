@@ -0,0 +1,511 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// Groups of portfolio components, the inner ring of a sunburst chart
+const (
+	GroupOnchain     = "onchain"
+	GroupLightning   = "lightning"
+	GroupLiquid      = "liquid"
+	GroupEcash       = "ecash"
+	GroupColdStorage = "cold_storage"
+	GroupExchange    = "exchange"
+)
+
+// unlabeledGroup names the tracked addresses that have no label
+const unlabeledGroup = "Unlabeled"
+
+// PortfolioComponent is the balance held by one concrete source
+type PortfolioComponent struct {
+	Group string `json:"group"`
+	// Kind is address_group, xpub, lnd_wallet, channels, liquid_wallet,
+	// fedimint, cashu, cold_account or strike
+	Kind string `json:"kind"`
+	// ID identifies the source within its kind: the xpub's or cold
+	// account's ID, the federation ID or mint URL, or the Strike currency
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Balance     int64  `json:"balance"`     // Confirmed plus unconfirmed
+	Unconfirmed int64  `json:"unconfirmed"` // Included in Balance
+	// Addresses is how many tracked addresses an address group holds
+	Addresses int `json:"addresses,omitempty"`
+	// InTotal is false for sources the portfolio total leaves out, which
+	// are exchange balances
+	InTotal bool `json:"in_total"`
+	// Currency and FiatCents are the balance of a fiat source, whose
+	// Balance is converted from it at the current BTC price
+	Currency  string `json:"currency,omitempty"`
+	FiatCents int64  `json:"fiat_cents,omitempty"`
+	// AsOf is when the balance of a past portfolio was recorded, for
+	// sources recorded as a whole
+	AsOf *time.Time `json:"as_of,omitempty"`
+}
+
+// PortfolioBreakdown is the current portfolio total split into components.
+// Total is the sum of the components counted in it and matches
+// total_portfolio of /portfolio/current.
+type PortfolioBreakdown struct {
+	Timestamp  time.Time            `json:"timestamp"`
+	Total      int64                `json:"total"`
+	Components []PortfolioComponent `json:"components"`
+	// Tracked addresses left out because their query failed or timed out
+	TrackedFailed   []string `json:"tracked_failed,omitempty"`
+	TrackedTimedOut []string `json:"tracked_timed_out,omitempty"`
+}
+
+// handlePortfolioBreakdown handles GET /api/portfolio/breakdown
+func (s *Server) handlePortfolioBreakdown(w http.ResponseWriter, r *http.Request) {
+	if s.mockMode {
+		s.writeJSON(w, APIResponse{Success: true, Data: convertBreakdown(mockBreakdown(), unitsFrom(r))})
+		return
+	}
+
+	if s.realtimeService == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Real-time balance service not available")
+		return
+	}
+
+	breakdown, err := s.portfolioBreakdown(r)
+	if err != nil {
+		log.Printf("handlePortfolioBreakdown: failed to break down the portfolio: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to break down current portfolio")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: convertBreakdown(breakdown, unitsFrom(r))})
+}
+
+// portfolioBreakdown collects the components from the same sources
+// handleCurrentPortfolio sums. Like there, LND failures only leave the LND
+// components out.
+func (s *Server) portfolioBreakdown(r *http.Request) (*PortfolioBreakdown, error) {
+	breakdown := &PortfolioBreakdown{Timestamp: time.Now()}
+
+	addresses, err := s.db.GetOnchainAddresses()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked addresses: %w", err)
+	}
+	tracked, err := s.realtimeService.GetTrackedAddressesBalance(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	breakdown.TrackedFailed = tracked.Failed
+	breakdown.TrackedTimedOut = tracked.TimedOut
+	breakdown.add(trackedComponents(addresses, tracked.Balances)...)
+
+	if s.lndClient != nil {
+		if wallet, err := s.lndClient.GetWalletBalance(); err != nil {
+			log.Printf("Warning: Failed to get on-chain wallet balance: %v", err)
+		} else {
+			breakdown.add(PortfolioComponent{
+				Group:       GroupOnchain,
+				Kind:        "lnd_wallet",
+				Name:        "LND wallet",
+				Balance:     wallet.ConfirmedBalance + wallet.UnconfirmedBalance,
+				Unconfirmed: wallet.UnconfirmedBalance,
+			})
+		}
+		if channels, err := s.lndClient.GetChannelBalances(); err != nil {
+			log.Printf("Warning: Failed to get Lightning balances: %v", err)
+		} else {
+			breakdown.add(PortfolioComponent{
+				Group:   GroupLightning,
+				Kind:    "channels",
+				Name:    "Lightning channels",
+				Balance: channels.LocalBalance,
+			})
+		}
+	}
+
+	liquid, err := s.db.GetLatestLiquidBalance()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Liquid balance: %w", err)
+	}
+	if liquid != nil {
+		breakdown.add(PortfolioComponent{
+			Group:       GroupLiquid,
+			Kind:        "liquid_wallet",
+			Name:        "Liquid wallet",
+			Balance:     liquid.Confirmed + liquid.Unconfirmed,
+			Unconfirmed: liquid.Unconfirmed,
+		})
+	}
+
+	ecash, err := s.db.GetLatestEcashBalances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ecash balances: %w", err)
+	}
+	for _, mint := range ecash {
+		breakdown.add(PortfolioComponent{
+			Group:   GroupEcash,
+			Kind:    mint.Protocol,
+			ID:      mint.Mint,
+			Name:    mint.Name,
+			Balance: mint.Amount,
+		})
+	}
+
+	accounts, err := s.db.GetColdStorageEntries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cold storage entries: %w", err)
+	}
+	for _, account := range accounts {
+		breakdown.add(PortfolioComponent{
+			Group:   GroupColdStorage,
+			Kind:    "cold_account",
+			ID:      fmt.Sprint(account.ID),
+			Name:    account.Name,
+			Balance: account.Balance,
+		})
+	}
+
+	strike, err := s.strikeComponents()
+	if err != nil {
+		return nil, err
+	}
+	breakdown.Components = append(breakdown.Components, strike...)
+
+	return breakdown, nil
+}
+
+// handlePortfolioAt handles GET /api/portfolio/at?timestamp=...
+// Reconstructs the portfolio as it was at a past moment from the balances the
+// collectors recorded, split into the same components as the breakdown.
+func (s *Server) handlePortfolioAt(w http.ResponseWriter, r *http.Request) {
+	at, fieldErr := parseTimestamp("timestamp", r.URL.Query().Get("timestamp"), time.Now())
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	breakdown, err := s.portfolioAt(at)
+	if err != nil {
+		log.Printf("handlePortfolioAt: failed to reconstruct the portfolio at %s: %v", at.Format(time.RFC3339), err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to reconstruct portfolio")
+		return
+	}
+
+	// Value at the prices recorded around then, not the current ones
+	conv := unitsFrom(r)
+	if conv.Fiat != nil {
+		if conv.Fiat, err = s.fiatValuation(conv.Fiat.Currency, at, at); err != nil {
+			log.Printf("handlePortfolioAt: failed to get BTC prices: %v", err)
+			s.writeError(w, http.StatusServiceUnavailable, "BTC price not available")
+			return
+		}
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: convertBreakdown(breakdown, conv)})
+}
+
+// portfolioAt collects every source's last balance recorded at or before at.
+// Sources deleted since are included, and those not recorded by then left
+// out. Fiat Strike balances are left out too, as there is no BTC price of
+// the time to convert them at.
+func (s *Server) portfolioAt(at time.Time) (*PortfolioBreakdown, error) {
+	breakdown := &PortfolioBreakdown{Timestamp: at}
+
+	tracked, err := s.db.GetAddressBalancesAt(at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked address balances: %w", err)
+	}
+	addresses := make([]db.OnchainAddress, 0, len(tracked))
+	balances := make([]bitcoin.AddressBalanceResult, 0, len(tracked))
+	for _, b := range tracked {
+		addresses = append(addresses, b.OnchainAddress)
+		balances = append(balances, bitcoin.AddressBalanceResult{Address: b.Address, Balance: b.Balance, Confirmed: b.Balance})
+	}
+	breakdown.add(trackedComponents(addresses, balances)...)
+
+	lnd, err := s.lndBalancesAt(at)
+	if err != nil {
+		return nil, err
+	}
+	if lnd != nil {
+		breakdown.add(
+			PortfolioComponent{Group: GroupOnchain, Kind: "lnd_wallet", Name: "LND wallet",
+				Balance: lnd.wallet, Unconfirmed: lnd.unconfirmed, AsOf: &lnd.recordedAt},
+			PortfolioComponent{Group: GroupLightning, Kind: "channels", Name: "Lightning channels",
+				Balance: lnd.channels, AsOf: &lnd.recordedAt},
+		)
+	}
+
+	liquid, err := s.db.GetLiquidBalanceSnapshotAt(at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Liquid balance: %w", err)
+	}
+	if liquid != nil {
+		breakdown.add(PortfolioComponent{
+			Group:       GroupLiquid,
+			Kind:        "liquid_wallet",
+			Name:        "Liquid wallet",
+			Balance:     liquid.Confirmed + liquid.Unconfirmed,
+			Unconfirmed: liquid.Unconfirmed,
+			AsOf:        &liquid.Timestamp,
+		})
+	}
+
+	ecash, err := s.db.GetEcashBalancesAt(at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ecash balances: %w", err)
+	}
+	for i, mint := range ecash {
+		breakdown.add(PortfolioComponent{
+			Group:   GroupEcash,
+			Kind:    mint.Protocol,
+			ID:      mint.Mint,
+			Name:    mint.Name,
+			Balance: mint.Amount,
+			AsOf:    &ecash[i].Timestamp,
+		})
+	}
+
+	accounts, err := s.db.GetColdStorageBalancesAt(at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cold storage balances: %w", err)
+	}
+	for _, account := range accounts {
+		breakdown.add(PortfolioComponent{
+			Group:   GroupColdStorage,
+			Kind:    "cold_account",
+			ID:      fmt.Sprint(account.AccountID),
+			Name:    account.Name,
+			Balance: account.Balance,
+		})
+	}
+
+	strike, err := s.db.GetStrikeBalancesAt(at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Strike balances: %w", err)
+	}
+	for _, balance := range strike {
+		total, pending, err := balance.Sats()
+		if errors.Is(err, db.ErrNotSats) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		breakdown.Components = append(breakdown.Components, PortfolioComponent{
+			Group: GroupExchange, Kind: "strike", ID: balance.Currency, Name: "Strike",
+			Balance: total, Unconfirmed: pending, AsOf: &balance.Timestamp,
+		})
+	}
+
+	return breakdown, nil
+}
+
+// lndBalances are the LND wallet and channel balances recorded at one time
+type lndBalances struct {
+	wallet      int64 // Confirmed plus unconfirmed
+	unconfirmed int64
+	channels    int64 // Local balance
+	recordedAt  time.Time
+}
+
+// lndBalancesAt returns the LND balances last recorded at or before at,
+// whether by the Lightning balance ledger or a portfolio snapshot, or nil
+// when neither had recorded any
+func (s *Server) lndBalancesAt(at time.Time) (*lndBalances, error) {
+	point, err := s.db.GetLightningBalanceAt(at)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get Lightning balance: %w", err)
+	}
+	snapshot, err := s.db.GetBalanceSnapshotAt(at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance snapshot: %w", err)
+	}
+
+	switch {
+	case snapshot != nil && (point == nil || snapshot.Timestamp.After(point.Timestamp)):
+		return &lndBalances{
+			wallet:      snapshot.OnchainConfirmed + snapshot.OnchainUnconfirmed,
+			unconfirmed: snapshot.OnchainUnconfirmed,
+			channels:    snapshot.LightningLocal,
+			recordedAt:  snapshot.Timestamp,
+		}, nil
+	case point != nil:
+		return &lndBalances{wallet: point.OnchainBalance, channels: point.LightningLocal, recordedAt: point.Timestamp}, nil
+	}
+	return nil, nil
+}
+
+// strikeComponents lists the latest Strike balance of every currency. Fiat
+// balances are kept in cents, so they are converted at the current BTC price
+// and left out when there is none.
+func (s *Server) strikeComponents() ([]PortfolioComponent, error) {
+	balances, err := s.db.GetLatestStrikeBalances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Strike balances: %w", err)
+	}
+
+	var components []PortfolioComponent
+	for _, balance := range balances {
+		component := PortfolioComponent{Group: GroupExchange, Kind: "strike", ID: balance.Currency, Name: "Strike"}
+
+		total, pending, err := balance.Sats()
+		switch {
+		case err == nil:
+			component.Balance, component.Unconfirmed = total, pending
+		case errors.Is(err, db.ErrNotSats):
+			if s.prices == nil {
+				continue
+			}
+			quote, err := s.prices.BTCPrice(balance.Currency)
+			if err != nil {
+				log.Printf("Warning: Failed to convert the Strike %s balance: %v", balance.Currency, err)
+				continue
+			}
+			component.Name = "Strike " + balance.Currency
+			component.Balance = quote.CentsToSats(balance.Total)
+			component.Unconfirmed = quote.CentsToSats(balance.Pending)
+			component.Currency, component.FiatCents = balance.Currency, balance.Total
+		default:
+			return nil, err
+		}
+		components = append(components, component)
+	}
+	return components, nil
+}
+
+// add appends components that count toward the total
+func (b *PortfolioBreakdown) add(components ...PortfolioComponent) {
+	for _, component := range components {
+		component.InTotal = true
+		b.Total += component.Balance
+		b.Components = append(b.Components, component)
+	}
+}
+
+// trackedComponents turns the balances of tracked addresses into one
+// component per label and one per xpub, address groups first and each in
+// name order
+func trackedComponents(addresses []db.OnchainAddress, balances []bitcoin.AddressBalanceResult) []PortfolioComponent {
+	byAddress := make(map[string]db.OnchainAddress, len(addresses))
+	for _, address := range addresses {
+		byAddress[address.Address] = address
+	}
+
+	groups := make(map[string]*PortfolioComponent)
+	var xpubs []PortfolioComponent
+	for _, balance := range balances {
+		address := byAddress[balance.Address]
+		if utils.ValidateXPub(balance.Address) {
+			name := address.Label
+			if name == "" {
+				name = balance.Address
+			}
+			xpubs = append(xpubs, PortfolioComponent{
+				Group:       GroupOnchain,
+				Kind:        "xpub",
+				ID:          fmt.Sprint(address.ID),
+				Name:        name,
+				Balance:     balance.Balance,
+				Unconfirmed: balance.Unconfirmed,
+			})
+			continue
+		}
+
+		label := address.Label
+		if label == "" {
+			label = unlabeledGroup
+		}
+		group, ok := groups[label]
+		if !ok {
+			group = &PortfolioComponent{Group: GroupOnchain, Kind: "address_group", Name: label}
+			groups[label] = group
+		}
+		group.Balance += balance.Balance
+		group.Unconfirmed += balance.Unconfirmed
+		group.Addresses++
+	}
+
+	components := make([]PortfolioComponent, 0, len(groups)+len(xpubs))
+	for _, group := range groups {
+		components = append(components, *group)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	sort.Slice(xpubs, func(i, j int) bool { return xpubs[i].Name < xpubs[j].Name })
+	return append(components, xpubs...)
+}
+
+// mockBreakdown splits the mock current portfolio
+func mockBreakdown() *PortfolioBreakdown {
+	breakdown := &PortfolioBreakdown{Timestamp: time.Now()}
+	breakdown.add(
+		PortfolioComponent{Group: GroupOnchain, Kind: "address_group", Name: "savings", Balance: 1000000, Addresses: 2},
+		PortfolioComponent{Group: GroupOnchain, Kind: "xpub", ID: "3", Name: "hardware wallet", Balance: 500000, Unconfirmed: 50000},
+		PortfolioComponent{Group: GroupOnchain, Kind: "lnd_wallet", Name: "LND wallet", Balance: 2100000, Unconfirmed: 100000},
+		PortfolioComponent{Group: GroupLightning, Kind: "channels", Name: "Lightning channels", Balance: 5000000},
+		PortfolioComponent{Group: GroupColdStorage, Kind: "cold_account", ID: "1", Name: "Coldcard", Balance: 10000000},
+	)
+	return breakdown
+}
+
+// convertBreakdown returns breakdown with its amounts in the requested
+// units, valued in fiat at the breakdown's time for the currency parameter.
+// Sats breakdowns are returned unchanged.
+func convertBreakdown(breakdown *PortfolioBreakdown, conv UnitConverter) interface{} {
+	if conv.Units == UnitsSats && conv.Fiat == nil {
+		return breakdown
+	}
+
+	components := make([]map[string]interface{}, 0, len(breakdown.Components))
+	for _, component := range breakdown.Components {
+		converted := map[string]interface{}{
+			"group":       component.Group,
+			"kind":        component.Kind,
+			"name":        component.Name,
+			"balance":     conv.Convert(component.Balance),
+			"unconfirmed": conv.Convert(component.Unconfirmed),
+			"in_total":    component.InTotal,
+		}
+		if component.ID != "" {
+			converted["id"] = component.ID
+		}
+		if component.Addresses > 0 {
+			converted["addresses"] = component.Addresses
+		}
+		if component.Currency != "" {
+			converted["currency"] = component.Currency
+			converted["fiat_cents"] = component.FiatCents
+		}
+		if component.AsOf != nil {
+			converted["as_of"] = component.AsOf
+		}
+		if conv.Fiat != nil {
+			converted["fiat"] = conv.Fiat.Value(component.Balance, breakdown.Timestamp)
+		}
+		components = append(components, converted)
+	}
+
+	converted := map[string]interface{}{
+		"timestamp":  breakdown.Timestamp,
+		"total":      conv.Convert(breakdown.Total),
+		"components": components,
+	}
+	if len(breakdown.TrackedFailed) > 0 {
+		converted["tracked_failed"] = breakdown.TrackedFailed
+	}
+	if len(breakdown.TrackedTimedOut) > 0 {
+		converted["tracked_timed_out"] = breakdown.TrackedTimedOut
+	}
+	conv.describe(converted)
+	if conv.Fiat != nil {
+		fiat := conv.Fiat.describe(breakdown.Timestamp)
+		fiat["total"] = conv.Fiat.Value(breakdown.Total, breakdown.Timestamp)
+		converted["fiat"] = fiat
+	}
+	return converted
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/price"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestTrackedComponents(t *testing.T) {
+	const xpub = "xpub6CUGRUonZSQ4TWtTMmzXdrXDtypWKiKrhko4egpiMZbpiaQL2jkwSB1icqYh2cfDfVxdx4df189oLKnC5fSwqPfgyP3hooxujYzAu3fDVmz"
+	addresses := []db.OnchainAddress{
+		{ID: 1, Address: "bc1qa", Label: "savings"},
+		{ID: 2, Address: "bc1qb", Label: "savings"},
+		{ID: 3, Address: "bc1qc"},
+		{ID: 4, Address: xpub, Label: "hardware wallet"},
+	}
+	balances := []bitcoin.AddressBalanceResult{
+		{Address: "bc1qa", Balance: 1000, Confirmed: 1000},
+		{Address: "bc1qb", Balance: 500, Confirmed: 400, Unconfirmed: 100},
+		{Address: "bc1qc", Balance: 200, Confirmed: 200},
+		{Address: xpub, Balance: 7000, Confirmed: 7000},
+	}
+
+	components := trackedComponents(addresses, balances)
+	testutils.AssertEqual(t, len(components), 3)
+	testutils.AssertEqual(t, components[0].Name, unlabeledGroup)
+	testutils.AssertEqual(t, components[0].Balance, int64(200))
+	testutils.AssertEqual(t, components[1].Name, "savings")
+	testutils.AssertEqual(t, components[1].Balance, int64(1500))
+	testutils.AssertEqual(t, components[1].Unconfirmed, int64(100))
+	testutils.AssertEqual(t, components[1].Addresses, 2)
+	testutils.AssertEqual(t, components[2].Kind, "xpub")
+	testutils.AssertEqual(t, components[2].ID, "4")
+	testutils.AssertEqual(t, components[2].Balance, int64(7000))
+}
+
+func TestPortfolioBreakdownMatchesCurrentTotal(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	get := func(url string) map[string]interface{} {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+		testutils.AssertEqual(t, rr.Code, http.StatusOK)
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response.Data.(map[string]interface{})
+	}
+
+	current := get("/api/v1/portfolio/current")
+	breakdown := get("/api/v1/portfolio/breakdown")
+	testutils.AssertEqual(t, breakdown["total"], current["total_portfolio"])
+
+	var sum float64
+	for _, component := range breakdown["components"].([]interface{}) {
+		sum += component.(map[string]interface{})["balance"].(float64)
+	}
+	testutils.AssertEqual(t, sum, breakdown["total"])
+
+	btc := get("/api/v1/portfolio/breakdown?units=btc")
+	testutils.AssertEqual(t, btc["units"], UnitsBTC)
+	testutils.AssertEqual(t, btc["total"], 0.186)
+}
+
+func TestStrikeComponents(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	now := time.Now()
+	for _, snapshot := range []db.StrikeBalanceSnapshot{
+		{Timestamp: now, Currency: "BTC", Total: 190000, Pending: 10000},
+		{Timestamp: now, Currency: "USD", Total: 12550},
+		{Timestamp: now, Currency: "EUR", Total: 5000},
+	} {
+		testutils.AssertNoError(t, server.db.InsertStrikeBalanceSnapshot(&snapshot))
+	}
+
+	// Without prices the cents cannot be counted as sats, so only BTC is listed
+	components, err := server.strikeComponents()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(components), 1)
+	testutils.AssertEqual(t, components[0].Balance, int64(190000))
+
+	// EUR has no price either way
+	server.prices = price.NewService(fixedPrices{}, price.DefaultCacheTTL)
+	components, err = server.strikeComponents()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(components), 2)
+	testutils.AssertEqual(t, components[1].Name, "Strike USD")
+	testutils.AssertEqual(t, components[1].Balance, int64(251000))
+	testutils.AssertEqual(t, components[1].FiatCents, int64(12550))
+	testutils.AssertEqual(t, components[1].InTotal, false)
+}
+
+func TestPortfolioAt(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	now := time.Now().Truncate(time.Second)
+	address, err := server.db.InsertOnchainAddress("bc1qretired", "retired")
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, server.db.InsertAddressBalance(&db.AddressBalance{AddressID: address.ID, Timestamp: now.Add(-40 * time.Hour), Balance: 250000}))
+	testutils.AssertNoError(t, server.db.DeleteOnchainAddress(address.ID))
+	// A ledger point newer than the snapshot before it wins
+	_, err = server.db.InsertLightningBalancePoints([]db.LightningBalancePoint{
+		{EventKey: "balance:1", Timestamp: now.Add(-30 * time.Hour), EventType: "balance", OnchainBalance: 550000, LightningLocal: 1050000},
+	})
+	testutils.AssertNoError(t, err)
+
+	get := func(at time.Time) PortfolioBreakdown {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/portfolio/at?timestamp="+at.UTC().Format(time.RFC3339), nil))
+		testutils.AssertEqual(t, rr.Code, http.StatusOK)
+		var response struct {
+			Data PortfolioBreakdown `json:"data"`
+		}
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response.Data
+	}
+	balances := func(breakdown PortfolioBreakdown) map[string]int64 {
+		byKind := make(map[string]int64)
+		var sum int64
+		for _, component := range breakdown.Components {
+			byKind[component.Kind] = component.Balance
+			if component.InTotal {
+				sum += component.Balance
+			}
+		}
+		testutils.AssertEqual(t, breakdown.Total, sum)
+		return byKind
+	}
+
+	// Between the first snapshot and the ledger point
+	byKind := balances(get(now.Add(-36 * time.Hour)))
+	testutils.AssertEqual(t, byKind["address_group"], int64(250000))
+	testutils.AssertEqual(t, byKind["lnd_wallet"], int64(500000))
+	testutils.AssertEqual(t, byKind["channels"], int64(1000000))
+
+	byKind = balances(get(now.Add(-28 * time.Hour)))
+	testutils.AssertEqual(t, byKind["lnd_wallet"], int64(550000))
+	testutils.AssertEqual(t, byKind["channels"], int64(1050000))
+
+	// The next snapshot is newer again
+	byKind = balances(get(now.Add(-12 * time.Hour)))
+	testutils.AssertEqual(t, byKind["lnd_wallet"], int64(650000))
+	testutils.AssertEqual(t, byKind["channels"], int64(1100000))
+
+	// Nothing was recorded yet
+	testutils.AssertEqual(t, len(get(now.Add(-72*time.Hour)).Components), 0)
+
+	for _, url := range []string{"/api/v1/portfolio/at", "/api/v1/portfolio/at?timestamp=2999-01-01"} {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+		testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+	}
+}
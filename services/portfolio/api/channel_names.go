@@ -0,0 +1,101 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// ChannelNameRequest represents the request body for naming a channel
+type ChannelNameRequest struct {
+	Name string `json:"name"`
+}
+
+// handleGetChannelNames handles GET /api/lightning/channels/names
+func (s *Server) handleGetChannelNames(w http.ResponseWriter, r *http.Request) {
+	names, err := s.db.GetChannelNames()
+	if err != nil {
+		log.Printf("handleGetChannelNames: failed to get channel names: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel names")
+		return
+	}
+	if names == nil {
+		names = []db.ChannelName{}
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: names})
+}
+
+// handleSetChannelName handles PUT /api/lightning/channels/{id}/name,
+// replacing the channel's name if it has one
+func (s *Server) handleSetChannelName(w http.ResponseWriter, r *http.Request) {
+	channelID, fieldErr := parseChannelID(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	var req ChannelNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "name", Message: "name is required"})
+		return
+	}
+	if utf8.RuneCountInString(name) > db.MaxChannelNameLength {
+		s.writeValidationError(w, &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   "name",
+			Message: fmt.Sprintf("name must be at most %d characters", db.MaxChannelNameLength),
+		})
+		return
+	}
+
+	named, err := s.db.SetChannelName(channelID, name)
+	if err != nil {
+		log.Printf("handleSetChannelName: failed to set channel name: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to save channel name")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: named})
+}
+
+// handleDeleteChannelName handles DELETE /api/lightning/channels/{id}/name,
+// going back to the peer's alias
+func (s *Server) handleDeleteChannelName(w http.ResponseWriter, r *http.Request) {
+	channelID, fieldErr := parseChannelID(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	err := s.db.DeleteChannelName(channelID)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Channel name not found")
+		return
+	}
+	if err != nil {
+		log.Printf("handleDeleteChannelName: failed to delete channel name: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to delete channel name")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"message":    "Channel name deleted successfully",
+			"channel_id": channelID,
+		},
+	})
+}
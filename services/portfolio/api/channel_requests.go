@@ -0,0 +1,398 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/notify"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// Channel requests are the one write the public listener accepts: other
+// nodes ask for a channel from ours through web/static/channel-request.html.
+// Requests are only stored and announced; opening a channel takes an
+// approval on the admin listener.
+
+// Limits of submitted channel requests
+const (
+	// MinChannelRequestSize is the smallest channel LND opens
+	MinChannelRequestSize = 20000
+	// MaxChannelRequestSize is the largest channel peers without
+	// option_support_large_channel accept
+	MaxChannelRequestSize   = 16777215
+	MaxChannelContactLength = 200
+	MaxChannelMessageLength = 500
+	// maxChannelRequestBody caps the request body read from the public listener
+	maxChannelRequestBody = 4096
+)
+
+// DefaultChannelRequestsPerHour is how many requests one client may submit
+// in an hour
+const DefaultChannelRequestsPerHour = 3
+
+// DefaultMaxPendingChannelRequests is how many requests may wait for review
+// before new ones are refused
+const DefaultMaxPendingChannelRequests = 20
+
+// channelRequestInbox accepts channel requests from the public listener
+type channelRequestInbox struct {
+	limiter    *clientLimiter
+	maxPending int
+	// notify announces a new request, e.g. on Telegram; nil disables it
+	notify func(message string)
+}
+
+// newChannelRequestInbox accepts perHour requests per client and up to
+// maxPending pending ones, announcing them on Telegram when BOT_TOKEN and
+// CHAT_ID are set
+func newChannelRequestInbox(perHour, maxPending int) *channelRequestInbox {
+	return &channelRequestInbox{
+		limiter:    newClientLimiter(perHour, time.Hour),
+		maxPending: maxPending,
+		notify:     telegramNotifier("channel request"),
+	}
+}
+
+// telegramNotifier returns a function sending messages on Telegram when
+// BOT_TOKEN and CHAT_ID are set, logging failures as what notifications, or
+// nil when they are not
+func telegramNotifier(what string) func(message string) {
+	telegram := notify.Telegram{BotToken: os.Getenv("BOT_TOKEN"), ChatID: os.Getenv("CHAT_ID")}
+	if !telegram.Enabled() {
+		return nil
+	}
+	return func(message string) {
+		if err := telegram.Send(message); err != nil {
+			log.Printf("⚠️  Failed to send %s notification: %v", what, err)
+		}
+	}
+}
+
+// openChannelFunc opens a channel to a peer address, lnd.ConnectAndOpenChannel
+type openChannelFunc func(peerAddress string, localAmt, satPerVbyte int64) (*lnd.OpenChannelResponse, error)
+
+// clientLimiter allows each client a number of submissions per window. A
+// client is the remote IP, so behind a reverse proxy every visitor shares
+// the proxy's allowance.
+type clientLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	seen   map[string][]time.Time
+	now    func() time.Time
+}
+
+func newClientLimiter(limit int, window time.Duration) *clientLimiter {
+	return &clientLimiter{limit: limit, window: window, seen: map[string][]time.Time{}, now: time.Now}
+}
+
+// allow records a submission from client, reporting false when it is over
+// its limit
+func (l *clientLimiter) allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	// Forget clients whose submissions all left the window
+	for other, times := range l.seen {
+		if other != client && now.Sub(times[len(times)-1]) >= l.window {
+			delete(l.seen, other)
+		}
+	}
+
+	recent := l.seen[client][:0]
+	for _, at := range l.seen[client] {
+		if now.Sub(at) < l.window {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.seen[client] = recent
+		return false
+	}
+	l.seen[client] = append(recent, now)
+	return true
+}
+
+// clientOf returns the remote IP of a request
+func clientOf(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ChannelRequestSubmission is the request body of POST /api/channel-requests
+type ChannelRequestSubmission struct {
+	Pubkey   string `json:"pubkey"`
+	Host     string `json:"host"` // host:port our node connects to
+	Capacity int64  `json:"capacity"`
+	Contact  string `json:"contact"`
+	Message  string `json:"message"`
+}
+
+// validate normalizes a submission and checks it, returning the request to store
+func (sub ChannelRequestSubmission) validate() (*db.ChannelRequest, *FieldError) {
+	pubkey := strings.ToLower(strings.TrimSpace(sub.Pubkey))
+	if pubkey == "" {
+		return nil, &FieldError{Code: ErrCodeRequired, Field: "pubkey", Message: "pubkey is required"}
+	}
+	if !utils.ValidateNodePubkey(pubkey) {
+		return nil, &FieldError{Code: ErrCodeInvalid, Field: "pubkey", Message: "Invalid node pubkey. Must be 66 hex characters starting with 02 or 03"}
+	}
+
+	host := strings.TrimSpace(sub.Host)
+	if host == "" {
+		return nil, &FieldError{Code: ErrCodeRequired, Field: "host", Message: "host is required"}
+	}
+	if name, port, err := net.SplitHostPort(host); err != nil || name == "" || !validPort(port) {
+		return nil, &FieldError{Code: ErrCodeInvalid, Field: "host", Message: "host must be host:port, e.g. node.example.com:9735"}
+	}
+
+	if sub.Capacity < MinChannelRequestSize || sub.Capacity > MaxChannelRequestSize {
+		return nil, &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   "capacity",
+			Message: fmt.Sprintf("capacity must be between %d and %d sats", MinChannelRequestSize, MaxChannelRequestSize),
+		}
+	}
+
+	contact := strings.TrimSpace(sub.Contact)
+	if contact == "" {
+		return nil, &FieldError{Code: ErrCodeRequired, Field: "contact", Message: "contact is required"}
+	}
+	if utf8.RuneCountInString(contact) > MaxChannelContactLength {
+		return nil, &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   "contact",
+			Message: fmt.Sprintf("contact must be at most %d characters", MaxChannelContactLength),
+		}
+	}
+	message := strings.TrimSpace(sub.Message)
+	if utf8.RuneCountInString(message) > MaxChannelMessageLength {
+		return nil, &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   "message",
+			Message: fmt.Sprintf("message must be at most %d characters", MaxChannelMessageLength),
+		}
+	}
+
+	return &db.ChannelRequest{
+		Pubkey:   pubkey,
+		Host:     host,
+		Capacity: sub.Capacity,
+		Contact:  contact,
+		Message:  message,
+		Status:   db.ChannelRequestPending,
+	}, nil
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// handleSubmitChannelRequest handles POST /api/channel-requests on both
+// listeners. It is refused unless the API runs with --channel-requests.
+func (s *Server) handleSubmitChannelRequest(w http.ResponseWriter, r *http.Request) {
+	inbox := s.channelRequests
+	if inbox == nil {
+		s.writeError(w, http.StatusForbidden, "This node does not accept channel requests")
+		return
+	}
+
+	var sub ChannelRequestSubmission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChannelRequestBody)).Decode(&sub); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+	request, fieldErr := sub.validate()
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	if !inbox.limiter.allow(clientOf(r)) {
+		s.writeError(w, http.StatusTooManyRequests, "Too many channel requests, try again later")
+		return
+	}
+
+	pending, err := s.db.GetChannelRequests(db.ChannelRequestPending)
+	if err != nil {
+		log.Printf("handleSubmitChannelRequest: failed to get pending requests: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to submit channel request")
+		return
+	}
+	for _, other := range pending {
+		if other.Pubkey == request.Pubkey {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeConflict, Field: "pubkey", Message: "A request for this node is already waiting for review"})
+			return
+		}
+	}
+	if len(pending) >= inbox.maxPending {
+		s.writeError(w, http.StatusServiceUnavailable, "This node is not taking channel requests right now, try again later")
+		return
+	}
+
+	if err := s.db.InsertChannelRequest(request); err != nil {
+		log.Printf("handleSubmitChannelRequest: failed to insert channel request: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to submit channel request")
+		return
+	}
+
+	log.Printf("Channel request #%d for %s from %s", request.ID, redact.Sats(request.Capacity), request.Pubkey)
+	if inbox.notify != nil {
+		go inbox.notify(fmt.Sprintf("📨 <b>Channel request #%d</b>\n%s from %s@%s\nContact: %s\n%s",
+			request.ID, utils.FormatSats(request.Capacity), request.Pubkey, html.EscapeString(request.Host),
+			html.EscapeString(request.Contact), html.EscapeString(request.Message)))
+	}
+
+	// The submitter only learns that the request was received
+	s.writeJSON(w, APIResponse{Success: true, Data: map[string]interface{}{
+		"id":     request.ID,
+		"status": request.Status,
+	}})
+}
+
+// channelRequestStatuses are the values accepted by the status filter of
+// /channel-requests
+var channelRequestStatuses = []string{
+	db.ChannelRequestPending, db.ChannelRequestOpening, db.ChannelRequestOpened,
+	db.ChannelRequestFailed, db.ChannelRequestRejected,
+}
+
+// handleGetChannelRequests handles GET /api/channel-requests, the requests
+// submitted by other nodes with their contact details, newest first
+func (s *Server) handleGetChannelRequests(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if fieldErr := validateEnum("status", status, channelRequestStatuses); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	var statuses []string
+	if status != "" {
+		statuses = append(statuses, status)
+	}
+	requests, err := s.db.GetChannelRequests(statuses...)
+	if err != nil {
+		log.Printf("handleGetChannelRequests: failed to get channel requests: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel requests")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: requests})
+}
+
+// ApproveChannelRequest is the request body of
+// POST /api/channel-requests/{id}/approve
+type ApproveChannelRequest struct {
+	FeeRate int64 `json:"fee_rate"` // sat/vB of the funding transaction
+}
+
+// handleApproveChannelRequest handles POST /api/channel-requests/{id}/approve.
+// The channel is opened right away, like channel-manager open-channel.
+func (s *Server) handleApproveChannelRequest(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "channel request")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	var req ApproveChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+	if req.FeeRate <= 0 {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "fee_rate", Message: "fee_rate must be a positive sat/vB rate"})
+		return
+	}
+	if s.openChannel == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "LND is not available")
+		return
+	}
+
+	if !s.transitionChannelRequest(w, id, db.ChannelRequestOpening) {
+		return
+	}
+	request, err := s.db.GetChannelRequest(id)
+	if err != nil {
+		log.Printf("handleApproveChannelRequest: failed to get channel request %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel request")
+		return
+	}
+
+	request.FeeRate = req.FeeRate
+	response, err := s.openChannel(request.Pubkey+"@"+request.Host, request.Capacity, req.FeeRate)
+	if err != nil {
+		request.Status = db.ChannelRequestFailed
+		request.Error = err.Error()
+		log.Printf("Channel request #%d failed: %v", id, err)
+	} else {
+		request.Status = db.ChannelRequestOpened
+		request.FundingTxid = response.FundingTxidStr
+		log.Printf("Channel request #%d opened in %s", id, response.FundingTxidStr)
+	}
+	if err := s.db.UpdateChannelRequest(request); err != nil {
+		log.Printf("handleApproveChannelRequest: failed to record channel request %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to record channel request")
+		return
+	}
+
+	// A failed open is still a decided request; its error is in the response
+	s.writeJSON(w, APIResponse{Success: true, Data: request})
+}
+
+// handleRejectChannelRequest handles POST /api/channel-requests/{id}/reject
+func (s *Server) handleRejectChannelRequest(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "channel request")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	if !s.transitionChannelRequest(w, id, db.ChannelRequestRejected) {
+		return
+	}
+
+	request, err := s.db.GetChannelRequest(id)
+	if err != nil {
+		log.Printf("handleRejectChannelRequest: failed to get channel request %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel request")
+		return
+	}
+	s.writeJSON(w, APIResponse{Success: true, Data: request})
+}
+
+// transitionChannelRequest moves a pending request to status, writing the
+// error response and returning false when it cannot
+func (s *Server) transitionChannelRequest(w http.ResponseWriter, id int64, status string) bool {
+	err := s.db.TransitionChannelRequest(id, db.ChannelRequestPending, status)
+	switch {
+	case err == sql.ErrNoRows:
+		s.writeError(w, http.StatusNotFound, "Channel request not found")
+	case errors.Is(err, db.ErrStatusChanged):
+		s.writeValidationError(w, &FieldError{Code: ErrCodeConflict, Field: "status", Message: "Channel request is not pending"})
+	case err != nil:
+		log.Printf("transitionChannelRequest: failed to set channel request %d to %s: %v", id, status, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to update channel request")
+	default:
+		return true
+	}
+	return false
+}
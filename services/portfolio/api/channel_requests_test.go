@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/apikeys"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+func TestChannelRequests(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	do := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "198.51.100.7:40000"
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	submission := func(pubkeyByte string) string {
+		return `{"pubkey": "02` + strings.Repeat(pubkeyByte, 32) + `", "host": "node.example.com:9735",
+			"capacity": 1000000, "contact": "ops@example.com"}`
+	}
+
+	// Requests are refused unless the API runs with --channel-requests
+	testutils.AssertEqual(t, do(server.router, "POST", "/api/v1/channel-requests", submission("aa")).Code, http.StatusForbidden)
+
+	notified := make(chan string, 1)
+	server.channelRequests = &channelRequestInbox{limiter: newClientLimiter(3, time.Hour), maxPending: 2, notify: func(message string) { notified <- message }}
+
+	rr := do(server.router, "POST", "/api/v1/channel-requests", submission("aa"))
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, strings.Contains(<-notified, "ops@example.com"), true)
+	// The submitter does not see the stored request
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(), "contact"), false)
+
+	for _, body := range []string{
+		`{"pubkey": "02zz", "host": "node.example.com:9735", "capacity": 1000000, "contact": "x"}`,
+		strings.Replace(submission("bb"), "node.example.com:9735", "node.example.com", 1),
+		strings.Replace(submission("bb"), "1000000", "1000", 1),
+		strings.Replace(submission("bb"), "ops@example.com", " ", 1),
+		// A second request for the same node while the first is pending
+		submission("aa"),
+	} {
+		testutils.AssertEqual(t, do(server.router, "POST", "/api/v1/channel-requests", body).Code, http.StatusBadRequest)
+	}
+
+	// One client may submit three times an hour, counting the duplicate
+	if rr := do(server.router, "POST", "/api/v1/channel-requests", submission("bb")); rr.Code != http.StatusOK {
+		t.Fatalf("submission failed: %s", rr.Body.String())
+	}
+	<-notified
+	testutils.AssertEqual(t, do(server.router, "POST", "/api/v1/channel-requests", submission("cc")).Code, http.StatusTooManyRequests)
+
+	// With the limit lifted, the pending cap of 2 still holds
+	server.channelRequests.limiter = newClientLimiter(10, time.Hour)
+	testutils.AssertEqual(t, do(server.router, "POST", "/api/v1/channel-requests", submission("cc")).Code, http.StatusServiceUnavailable)
+
+	// Reviewing happens on the admin listener only
+	testutils.AssertEqual(t, do(server.router, "GET", "/api/v1/channel-requests", "").Code, http.StatusForbidden)
+	rr = do(server.adminRouter, "GET", "/api/v1/channel-requests?status=pending", "")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var listed struct {
+		Data []db.ChannelRequest `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	testutils.AssertEqual(t, len(listed.Data), 2)
+	first, second := listed.Data[1], listed.Data[0]
+
+	var opened string
+	server.openChannel = func(peerAddress string, localAmt, satPerVbyte int64) (*lnd.OpenChannelResponse, error) {
+		opened = fmt.Sprintf("%s %d %d", peerAddress, localAmt, satPerVbyte)
+		if strings.HasPrefix(peerAddress, second.Pubkey) {
+			return nil, errors.New("peer is offline")
+		}
+		return &lnd.OpenChannelResponse{FundingTxidStr: "f00d"}, nil
+	}
+
+	path := fmt.Sprintf("/api/v1/channel-requests/%d", first.ID)
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", path+"/approve", `{}`).Code, http.StatusBadRequest)
+	rr = do(server.adminRouter, "POST", path+"/approve", `{"fee_rate": 5}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var decided struct {
+		Data db.ChannelRequest `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &decided))
+	testutils.AssertEqual(t, decided.Data.Status, db.ChannelRequestOpened)
+	testutils.AssertEqual(t, decided.Data.FundingTxid, "f00d")
+	testutils.AssertEqual(t, opened, first.Pubkey+"@node.example.com:9735 1000000 5")
+
+	// Decided requests cannot be decided again
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", path+"/reject", "").Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", "/api/v1/channel-requests/999/reject", "").Code, http.StatusNotFound)
+
+	rr = do(server.adminRouter, "POST", fmt.Sprintf("/api/v1/channel-requests/%d/approve", second.ID), `{"fee_rate": 5}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &decided))
+	testutils.AssertEqual(t, decided.Data.Status, db.ChannelRequestFailed)
+	testutils.AssertEqual(t, decided.Data.Error, "peer is offline")
+}
+
+func TestChannelRequestsWithAPIKeys(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+	server.channelRequests = &channelRequestInbox{limiter: newClientLimiter(3, time.Hour), maxPending: 2, notify: func(string) {}}
+
+	keys := newKeyring(apiKeys{personalKey: {Profile: db.DefaultProfile, Key: personalKey, Scope: apikeys.ScopeRead}})
+	public := keys.handler(map[string]*Server{db.DefaultProfile: server}, func(s *Server) *mux.Router { return s.router })
+
+	serve := func(method, path, body string) int {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "198.51.100.7:40000"
+		public.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Other nodes submit requests without a key, on either prefix
+	for i, path := range []string{"/api/v1/channel-requests", "/api/channel-requests"} {
+		body := `{"pubkey": "02` + strings.Repeat(fmt.Sprintf("%02x", i+1), 32) + `", "host": "node.example.com:9735",
+			"capacity": 1000000, "contact": "ops@example.com"}`
+		testutils.AssertEqual(t, serve("POST", path, body), http.StatusOK)
+	}
+	requests, err := server.db.GetChannelRequests()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(requests), 2)
+
+	// Reading them still needs a key
+	testutils.AssertEqual(t, serve("GET", "/api/v1/channel-requests", ""), http.StatusUnauthorized)
+}
+
+func TestClientLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newClientLimiter(2, time.Hour)
+	limiter.now = func() time.Time { return now }
+
+	testutils.AssertEqual(t, limiter.allow("a"), true)
+	testutils.AssertEqual(t, limiter.allow("a"), true)
+	testutils.AssertEqual(t, limiter.allow("a"), false)
+	testutils.AssertEqual(t, limiter.allow("b"), true)
+
+	now = now.Add(time.Hour)
+	testutils.AssertEqual(t, limiter.allow("a"), true)
+	testutils.AssertEqual(t, len(limiter.seen), 1)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// exportFormats lists the formats exports can be streamed in
+var exportFormats = []string{"json", "ndjson"}
+
+// exportFlushRows is how many rows are written between flushes, so clients
+// receive a long export as it is read instead of all at the end
+const exportFlushRows = 500
+
+// handleLightningForwardsExport handles GET /api/lightning/forwards/export
+// Streams every forwarding event in the time range, oldest first, without
+// holding them in memory. format=json (default) wraps the events in the usual
+// response envelope; format=ndjson writes one event per line. Archived
+// forwards, see internal/archive, come first.
+func (s *Server) handleLightningForwardsExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if fieldErr := validateEnum("format", format, exportFormats); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	tr := timeRangeFrom(r)
+
+	stream := newExportStream(w, format == "ndjson")
+	write := func(event *db.ForwardingEvent) error {
+		return stream.Write(event)
+	}
+	err := s.eachArchivedForward(tr.From, tr.To, write)
+	if err == nil {
+		err = s.db.EachForwardingEvent(tr.From, tr.To, write)
+	}
+	if err == nil {
+		err = stream.Close()
+	}
+	if err != nil {
+		log.Printf("handleLightningForwardsExport: failed to export forwarding events: %v", err)
+		if !stream.started {
+			s.writeError(w, http.StatusInternalServerError, "Failed to export forwarding events")
+			return
+		}
+		// The status is sent already; dropping the connection stops the client
+		// from mistaking a truncated export for a complete one
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// eachArchivedForward calls fn for the archived forwards within a time
+// range. Forwards at or after the day archiving stopped at are read from the
+// database, even when a run that failed part way also wrote them to the
+// archive.
+func (s *Server) eachArchivedForward(from, to time.Time, fn func(*db.ForwardingEvent) error) error {
+	if s.archive == nil {
+		return nil
+	}
+	before, err := s.db.ArchivedBefore()
+	if err != nil || !from.Before(before) {
+		return err
+	}
+	if !to.Before(before) {
+		to = before.Add(-time.Nanosecond)
+	}
+	return s.archive.EachForwardingEvent(from, to, fn)
+}
+
+// handleChannelSnapshotsExport handles GET /api/lightning/channels/{id}/export.
+// Streams every snapshot of one channel in the time range, archived ones
+// first, in the formats of /api/lightning/forwards/export.
+func (s *Server) handleChannelSnapshotsExport(w http.ResponseWriter, r *http.Request) {
+	channelID, fieldErr := parseChannelID(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if fieldErr := validateEnum("format", format, exportFormats); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	tr := timeRangeFrom(r)
+
+	// The snapshots still stored are rebuilt together from their keyframes
+	live, err := s.db.GetChannelSnapshots(channelID, tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleChannelSnapshotsExport: failed to get snapshots for channel %s: %v", channelID, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to export channel snapshots")
+		return
+	}
+	stored := make(map[int64]bool, len(live))
+	for _, snapshot := range live {
+		stored[snapshot.ID] = true
+	}
+
+	stream := newExportStream(w, format == "ndjson")
+	if s.archive != nil {
+		err = s.archive.EachChannelSnapshot(channelID, tr.From, tr.To, func(snapshot *db.ChannelSnapshot) error {
+			if stored[snapshot.ID] {
+				return nil
+			}
+			return stream.Write(snapshot)
+		})
+	}
+	for i := 0; err == nil && i < len(live); i++ {
+		err = stream.Write(&live[i])
+	}
+	if err == nil {
+		err = stream.Close()
+	}
+	if err != nil {
+		log.Printf("handleChannelSnapshotsExport: failed to export snapshots for channel %s: %v", channelID, err)
+		if !stream.started {
+			s.writeError(w, http.StatusInternalServerError, "Failed to export channel snapshots")
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportStream writes rows to a response as they arrive. Nothing is written
+// until the first row, so errors before it still get a normal error response.
+type exportStream struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	ndjson  bool
+	started bool
+	rows    int
+}
+
+func newExportStream(w http.ResponseWriter, ndjson bool) *exportStream {
+	return &exportStream{w: w, enc: json.NewEncoder(w), ndjson: ndjson}
+}
+
+func (e *exportStream) start() error {
+	e.started = true
+	if e.ndjson {
+		e.w.Header().Set("Content-Type", "application/x-ndjson")
+		return nil
+	}
+	e.w.Header().Set("Content-Type", "application/json")
+	_, err := io.WriteString(e.w, `{"success":true,"data":[`)
+	return err
+}
+
+// Write encodes one row
+func (e *exportStream) Write(row interface{}) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if !e.ndjson && e.rows > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	if err := e.enc.Encode(row); err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return http.NewResponseController(e.w).Flush()
+	}
+	return nil
+}
+
+// Close finishes the response after the last row
+func (e *exportStream) Close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if e.ndjson {
+		return nil
+	}
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
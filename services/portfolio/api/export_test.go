@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/archive"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestLightningForwardsExport(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	// More than exportFlushRows, so the response is flushed part way through
+	const count = exportFlushRows*2 + 1
+	start := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	for i := range count {
+		testutils.AssertNoError(t, server.db.InsertForwardingEvent(&db.ForwardingEvent{
+			Timestamp:    start.Add(time.Duration(i) * time.Second),
+			ChannelInID:  "123456789:1:0",
+			ChannelOutID: "987654321:1:0",
+			AmountIn:     100100,
+			AmountOut:    100000,
+			Fee:          100,
+		}))
+	}
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/lightning/forwards/export?days=1", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, rr.Header().Get("Content-Type"), "application/json")
+
+	var response struct {
+		Success bool                 `json:"success"`
+		Data    []db.ForwardingEvent `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	testutils.AssertEqual(t, response.Success, true)
+	if len(response.Data) < count {
+		t.Fatalf("got %d events, want at least %d", len(response.Data), count)
+	}
+	for i := 1; i < len(response.Data); i++ {
+		if response.Data[i].Timestamp.Before(response.Data[i-1].Timestamp) {
+			t.Fatalf("event %d is older than the one before it", i)
+		}
+	}
+	last := response.Data[len(response.Data)-1]
+	testutils.AssertEqual(t, last.FeePPM, int64(1000))
+
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/lightning/forwards/export?days=1&format=ndjson", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, rr.Header().Get("Content-Type"), "application/x-ndjson")
+
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(rr.Body.Bytes()))
+	for scanner.Scan() {
+		var event db.ForwardingEvent
+		testutils.AssertNoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		lines++
+	}
+	testutils.AssertNoError(t, scanner.Err())
+	testutils.AssertEqual(t, lines, len(response.Data))
+}
+
+func TestLightningForwardsExportEmpty(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/lightning/forwards/export?from=2020-01-01&to=2020-01-31", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, rr.Body.String(), `{"success":true,"data":[]}`+"\n")
+
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/lightning/forwards/export?from=2020-01-01&to=2020-01-31&format=ndjson", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, rr.Body.Len(), 0)
+}
+
+func TestLightningForwardsExportInvalidFormat(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/lightning/forwards/export?format=xml", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestExportsReadArchive(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+	server.archive = archive.New(t.TempDir())
+
+	start := time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC)
+	for day := range 20 {
+		timestamp := start.AddDate(0, 0, day)
+		testutils.AssertNoError(t, server.db.InsertForwardingEvent(&db.ForwardingEvent{
+			Timestamp: timestamp, ChannelInID: "123456789:1:0", ChannelOutID: "987654321:1:0", AmountIn: 100100, AmountOut: 100000, Fee: 100,
+		}))
+		testutils.AssertNoError(t, server.db.InsertChannelSnapshots([]db.ChannelSnapshot{
+			{Timestamp: timestamp, ChannelID: "987654321:1:0", Capacity: 1000000, LocalBalance: int64(day) * 1000},
+		}))
+	}
+	testutils.AssertNoError(t, server.db.MaterializeDailySummaries(start.AddDate(0, 0, 20)))
+	result, err := server.archive.Run(server.db, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, result.ForwardingEvents, int64(12))
+
+	export := func(url string) []time.Time {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+		testutils.AssertEqual(t, rr.Code, http.StatusOK)
+		var response struct {
+			Data []struct {
+				Timestamp time.Time `json:"timestamp"`
+			} `json:"data"`
+		}
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		var timestamps []time.Time
+		for _, row := range response.Data {
+			timestamps = append(timestamps, row.Timestamp)
+		}
+		return timestamps
+	}
+
+	for _, url := range []string{
+		"/api/v1/lightning/forwards/export?from=2024-01-01&to=2024-03-31",
+		"/api/v1/lightning/channels/987654321:1:0/export?from=2024-01-01&to=2024-03-31",
+	} {
+		timestamps := export(url)
+		testutils.AssertEqual(t, len(timestamps), 20)
+		for i, timestamp := range timestamps {
+			testutils.AssertEqual(t, timestamp.Equal(start.AddDate(0, 0, i)), true)
+		}
+	}
+	// Ranges within the archive only read it
+	testutils.AssertEqual(t, len(export("/api/v1/lightning/forwards/export?from=2024-01-25&to=2024-01-27")), 3)
+}
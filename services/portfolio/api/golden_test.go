@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/fixtures"
+	"github.com/brewgator/lightning-node-tools/internal/liquidity"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/swap"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/brewgator/lightning-node-tools/internal/version"
+	"github.com/gorilla/mux"
+)
+
+// update rewrites the golden files from the current responses:
+//
+//	go test ./services/portfolio/api -run Golden -update
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// goldenRange limits time-ranged endpoints to the month the fixtures cover
+const goldenRange = "from=2024-01-01&to=2024-01-31"
+
+// goldenCase is one request whose response is compared with testdata/golden/<name>
+type goldenCase struct {
+	name  string
+	route string // route template, used to check every GET route is covered
+	url   string
+	// volatile lists the dotted paths of values that differ between runs,
+	// e.g. "data.go_version"; array elements share their array's path
+	volatile []string
+	// admin requests the admin listener instead of the public one
+	admin bool
+}
+
+var goldenCases = []goldenCase{
+	{name: "portfolio-current", route: "/portfolio/current", url: "/portfolio/current"},
+	{name: "portfolio-history", route: "/portfolio/history", url: "/portfolio/history?" + goldenRange},
+	{name: "portfolio-breakdown", route: "/portfolio/breakdown", url: "/portfolio/breakdown"},
+	{name: "portfolio-performance", route: "/portfolio/performance", url: "/portfolio/performance?window=all"},
+	{name: "portfolio-at", route: "/portfolio/at", url: "/portfolio/at?timestamp=2024-01-15T12:00:00Z"},
+	{name: "portfolio-transfers", route: "/portfolio/transfers", url: "/portfolio/transfers?" + goldenRange},
+	// Without an Upgrade header /ws answers with an error
+	{name: "ws", route: "/ws", url: "/ws"},
+	{name: "annotations", route: "/annotations", url: "/annotations?" + goldenRange},
+	{name: "insights", route: "/insights", url: "/insights?" + goldenRange},
+	{name: "chart-themes", route: "/charts/themes", url: "/charts/themes"},
+	{name: "lightning-fees", route: "/lightning/fees", url: "/lightning/fees?" + goldenRange},
+	{name: "lightning-forwards", route: "/lightning/forwards", url: "/lightning/forwards?" + goldenRange},
+	{name: "lightning-forwards-export", route: "/lightning/forwards/export", url: "/lightning/forwards/export?" + goldenRange},
+	{name: "lightning-forward-stats", route: "/lightning/forwards/stats", url: "/lightning/forwards/stats?" + goldenRange},
+	{name: "lightning-forward-failures", route: "/lightning/forwards/failures", url: "/lightning/forwards/failures?" + goldenRange},
+	{name: "lightning-ppm-histogram", route: "/lightning/forwards/ppm-histogram", url: "/lightning/forwards/ppm-histogram?" + goldenRange},
+	{name: "lightning-channels", route: "/lightning/channels", url: "/lightning/channels", volatile: []string{"data.lease.estimated_expiry"}},
+	{name: "lightning-channel-fees", route: "/lightning/channels/fees", url: "/lightning/channels/fees?" + goldenRange},
+	{name: "lightning-channel-names", route: "/lightning/channels/names", url: "/lightning/channels/names"},
+	{name: "lightning-channel", route: "/lightning/channels/{id}", url: "/lightning/channels/" + fixtures.ChannelACINQ},
+	// Only the admin listener adds the notes about the channel's peer
+	{name: "lightning-channel-admin", route: "/lightning/channels/{id}", url: "/lightning/channels/" + fixtures.ChannelACINQ, admin: true},
+	{name: "lightning-channel-export", route: "/lightning/channels/{id}/export",
+		url: "/lightning/channels/" + fixtures.ChannelACINQ + "/export?" + goldenRange},
+	{name: "lightning-channel-balance-history", route: "/lightning/channels/{id}/balance-history",
+		url: "/lightning/channels/" + fixtures.ChannelACINQ + "/balance-history?" + goldenRange},
+	{name: "lightning-mission-control", route: "/lightning/mission-control", url: "/lightning/mission-control"},
+	{name: "lightning-reliability", route: "/lightning/reliability", url: "/lightning/reliability?" + goldenRange},
+	{name: "swap-quotes", route: "/swaps/quotes", url: "/swaps/quotes?channel_id=" + fixtures.ChannelACINQ},
+	{name: "lightning-leases", route: "/lightning/leases", url: "/lightning/leases", volatile: []string{"data.leases.estimated_expiry"}},
+	{name: "lightning-channel-lease", route: "/lightning/channels/{id}/lease", url: "/lightning/channels/" + fixtures.ChannelWoS + "/lease",
+		volatile: []string{"data.estimated_expiry"}},
+	{name: "peer-policies", route: "/peers/policies", url: "/peers/policies"},
+	{name: "peer-policy", route: "/peers/policies/{pubkey}", url: "/peers/policies/03864ef025fde8fb587d989186ce6a4a186895ee44a926bfc370e2c366597a3f8f"},
+	{name: "peer-notes", route: "/peers/notes", url: "/peers/notes", admin: true},
+	{name: "peer-note", route: "/peers/notes/{pubkey}", url: "/peers/notes/03864ef025fde8fb587d989186ce6a4a186895ee44a926bfc370e2c366597a3f8f", admin: true},
+	{name: "onchain-addresses", route: "/onchain/addresses", url: "/onchain/addresses"},
+	{name: "onchain-addresses-deleted", route: "/onchain/addresses/deleted", url: "/onchain/addresses/deleted"},
+	{name: "onchain-address-history", route: "/onchain/addresses/{id:[0-9]+}/history", url: "/onchain/addresses/1/history?" + goldenRange},
+	{name: "onchain-history", route: "/onchain/history", url: "/onchain/history?address=" + fixtures.TrackedAddress + "&" + goldenRange},
+	{name: "onchain-multisig-spend", route: "/onchain/multisig/spend", url: "/onchain/multisig/spend?descriptor=" +
+		url.QueryEscape(fixtures.MultisigDescriptor) + "&destination=bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4&amount=2600000"},
+	{name: "onchain-multisig-wallets", route: "/onchain/multisig/wallets", url: "/onchain/multisig/wallets", admin: true},
+	{name: "offline-accounts", route: "/offline/accounts", url: "/offline/accounts"},
+	{name: "offline-accounts-deleted", route: "/offline/accounts/deleted", url: "/offline/accounts/deleted"},
+	{name: "offline-account-history", route: "/offline/accounts/{id:[0-9]+}/history", url: "/offline/accounts/1/history?" + goldenRange},
+	{name: "offline-history", route: "/offline/history", url: "/offline/history?account=1&" + goldenRange},
+	{name: "strike-balance-current", route: "/strike/balance/current", url: "/strike/balance/current"},
+	{name: "strike-balance-history", route: "/strike/balance/history", url: "/strike/balance/history?" + goldenRange},
+	{name: "strike-withdrawals", route: "/strike/withdrawals", url: "/strike/withdrawals"},
+	{name: "liquid-balance-current", route: "/liquid/balance/current", url: "/liquid/balance/current"},
+	{name: "liquid-balance-history", route: "/liquid/balance/history", url: "/liquid/balance/history?" + goldenRange},
+	{name: "ecash-balances", route: "/ecash/balances", url: "/ecash/balances"},
+	{name: "ecash-balance-history", route: "/ecash/balance/history", url: "/ecash/balance/history?" + goldenRange},
+	{name: "reports-statements", route: "/reports/statements", url: "/reports/statements"},
+	{name: "reports-statement", route: "/reports/statement", url: "/reports/statement?month=2023-12&format=csv"},
+	{name: "channel-requests", route: "/channel-requests", url: "/channel-requests", admin: true},
+	{name: "system-collector-runs", route: "/system/collector-runs", url: "/system/collector-runs", admin: true},
+	{name: "system-quarantine", route: "/system/quarantine", url: "/system/quarantine", admin: true},
+	{name: "system-captures", route: "/system/captures", url: "/system/captures", admin: true},
+	{name: "system-stats", route: "/system/stats", url: "/system/stats", admin: true,
+		volatile: []string{"data.started_at", "data.uptime_seconds"}},
+	{name: "health", route: "/health", url: "/health"},
+	{name: "version", route: "/version", url: "/version", volatile: []string{"data.commit", "data.go_version"}},
+}
+
+// setupGoldenServer returns a server wired like main's real-time mode, with
+// LND and Bitcoin Core answered from internal/fixtures and the database
+// seeded with fixtures.Seed
+func setupGoldenServer(t *testing.T) *Server {
+	t.Helper()
+
+	// Dates in query parameters and responses are in the local time zone
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+
+	t.Cleanup(lnd.SetRunner(fixtures.LNDRunner))
+	t.Cleanup(bitcoin.SetRunner(fixtures.BitcoinRunner))
+
+	database, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	testutils.AssertNoError(t, fixtures.Seed(database))
+
+	bitcoinClient, err := bitcoin.NewClient()
+	testutils.AssertNoError(t, err)
+	lndClient, err := lnd.NewClient()
+	testutils.AssertNoError(t, err)
+
+	nodes := version.Nodes{}
+	detectNodeVersion(database, nodes, version.NodeBitcoinCore, bitcoinClient.GetVersion)
+	detectNodeVersion(database, nodes, version.NodeLND, lnd.GetVersion)
+
+	realtimeService, err := bitcoin.NewRealtimeServiceBuilder(database).
+		WithBitcoin(bitcoinClient).
+		WithLightning(lndClient).
+		Build()
+	testutils.AssertNoError(t, err)
+
+	server := &Server{
+		db:              database,
+		router:          mux.NewRouter(),
+		adminRouter:     mux.NewRouter(),
+		realtimeService: realtimeService,
+		lndClient:       lndClient,
+		bitcoinClient:   bitcoinClient,
+		nodes:           nodes,
+		liquidity:       liquidity.NewConfig(),
+		confirmations:   bitcoin.DefaultConfirmationPolicy(),
+		blockHeight:     lnd.GetBlockHeight,
+		captureDir:      t.TempDir(),
+		swapProviders:   []swap.Provider{fixedSwapProvider{"loop", 3000}, fixedSwapProvider{"boltz", 2000}},
+		metrics:         metrics.NewRegistry(),
+	}
+	server.stream = newStreamHub(server, DefaultStreamInterval)
+	server.setupRoutes()
+	return server
+}
+
+func TestGoldenResponses(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := setupGoldenServer(t)
+
+			req := httptest.NewRequest("GET", "/api/v1"+tc.url, nil)
+			rr := httptest.NewRecorder()
+			if tc.admin {
+				server.adminRouter.ServeHTTP(rr, req)
+			} else {
+				server.router.ServeHTTP(rr, req)
+			}
+
+			got, ext := normalizeGolden(t, rr, tc.volatile)
+			path := filepath.Join("testdata", "golden", tc.name+ext)
+			if *update {
+				testutils.AssertNoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				testutils.AssertNoError(t, os.WriteFile(path, got, 0644))
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Response for %s differs from %s (run with -update if the change is intended)\n--- got\n%s\n--- want\n%s",
+					tc.url, path, got, want)
+			}
+		})
+	}
+}
+
+// TestGoldenCasesCoverRoutes fails when a GET endpoint has no golden case, so
+// new endpoints get their response shape pinned too
+func TestGoldenCasesCoverRoutes(t *testing.T) {
+	server := &Server{router: mux.NewRouter(), adminRouter: mux.NewRouter(), metrics: metrics.NewRegistry()}
+	server.setupRoutes()
+	assertGoldenCasesCover(t, server.router)
+}
+
+// assertGoldenCasesCover fails the test for every GET route of router under
+// /api/v1 that has no golden case
+func assertGoldenCasesCover(t *testing.T, router *mux.Router) {
+	t.Helper()
+	covered := make(map[string]bool)
+	for _, tc := range goldenCases {
+		covered[tc.route] = true
+	}
+
+	var missing []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(template, "/api/v1/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if path := strings.TrimPrefix(template, "/api/v1"); method == "GET" && !covered[path] {
+				missing = append(missing, path)
+			}
+		}
+		return nil
+	})
+	testutils.AssertNoError(t, err)
+
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("GET routes without a golden case: %s", strings.Join(missing, ", "))
+	}
+}
+
+// normalizeGolden returns the response body in a stable form along with the
+// golden file extension. JSON is indented with volatile values replaced by
+// "<volatile>"; in JSON and text alike, times near the wall clock, such as
+// created_at, are replaced by "<now>".
+func normalizeGolden(t *testing.T, rr *httptest.ResponseRecorder, volatile []string) ([]byte, string) {
+	t.Helper()
+	now := time.Now()
+
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json") {
+		text := timestampPattern.ReplaceAllStringFunc(rr.Body.String(), func(ts string) string {
+			return scrubGolden(ts, "", nil, now).(string)
+		})
+		return []byte(text), ".txt"
+	}
+
+	var body interface{}
+	decoder := json.NewDecoder(rr.Body)
+	decoder.UseNumber()
+	testutils.AssertNoError(t, decoder.Decode(&body))
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	testutils.AssertNoError(t, encoder.Encode(map[string]interface{}{
+		"status": rr.Code,
+		"body":   scrubGolden(body, "", volatile, now),
+	}))
+	return out.Bytes(), ".json"
+}
+
+// timestampPattern matches RFC 3339 timestamps in text responses such as CSV
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// scrubGolden replaces values at the volatile paths with "<volatile>" and
+// timestamps within an hour of now with "<now>". path is the dotted path of value.
+func scrubGolden(value interface{}, path string, volatile []string, now time.Time) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			itemPath := key
+			if path != "" {
+				itemPath = path + "." + key
+			}
+			if item != nil && slices.Contains(volatile, itemPath) {
+				v[key] = "<volatile>"
+				continue
+			}
+			v[key] = scrubGolden(item, itemPath, volatile, now)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrubGolden(item, path, volatile, now)
+		}
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, v); err == nil && ts.Sub(now).Abs() < time.Hour {
+			return "<now>"
+		}
+	}
+	return value
+}
+
+func TestScrubGolden(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	body := map[string]interface{}{
+		"created_at": now.Add(-time.Minute).Format(time.RFC3339Nano),
+		"timestamp":  "2024-01-15T00:00:00Z",
+		"go_version": "go1.25.0",
+		"items":      []interface{}{map[string]interface{}{"id": "7", "updated_at": now.Format(time.RFC3339)}},
+	}
+
+	scrubbed := scrubGolden(body, "", []string{"go_version", "items.id"}, now).(map[string]interface{})
+	testutils.AssertEqual(t, scrubbed["created_at"], "<now>")
+	testutils.AssertEqual(t, scrubbed["timestamp"], "2024-01-15T00:00:00Z")
+	testutils.AssertEqual(t, scrubbed["go_version"], "<volatile>")
+	item := scrubbed["items"].([]interface{})[0].(map[string]interface{})
+	testutils.AssertEqual(t, item["id"], "<volatile>")
+	testutils.AssertEqual(t, item["updated_at"], "<now>")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/insights"
+)
+
+// DefaultInsightsRange is the window insights cover without range parameters
+const DefaultInsightsRange = "7d"
+
+// handleInsights handles GET /api/insights, observations about the node and
+// portfolio over the last week or the given history range
+func (s *Server) handleInsights(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("days") == "" && query.Get("range") == "" && query.Get("from") == "" && query.Get("to") == "" {
+		query.Set("range", DefaultInsightsRange)
+	}
+	tr, fieldErr := ParseTimeRange(query, time.Now())
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	observations, err := insights.Generate(s.db, tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleInsights: failed to generate insights: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to generate insights")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"from":     tr.From,
+			"to":       tr.To,
+			"insights": observations,
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// FormatHints tell a client how to write a chart's values, e.g. sats as
+// "1 234 567" or "1,234,567"
+type FormatHints struct {
+	// Unit is what the dataset values count, e.g. "sats", "BTC" or "forwards"
+	Unit string `json:"unit"`
+	// Decimals is how many decimal places to show
+	Decimals         int    `json:"decimals"`
+	Locale           string `json:"locale"`
+	GroupSeparator   string `json:"group_separator"`
+	DecimalSeparator string `json:"decimal_separator"`
+}
+
+type localeKey struct{}
+
+// withLocale validates the locale query parameter before the handler runs
+// and makes its number format available through localeFrom. Without the
+// parameter the server's --locale applies.
+func (s *Server) withLocale(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locale := s.numberLocale
+		if tag := r.URL.Query().Get("locale"); tag != "" {
+			parsed, err := utils.ParseNumberLocale(tag)
+			if err != nil {
+				s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "locale", Message: err.Error()})
+				return
+			}
+			locale = parsed
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), localeKey{}, locale)))
+	}
+}
+
+// localeFrom returns the number format set up by withLocale, falling back to
+// the default locale
+func localeFrom(r *http.Request) utils.NumberLocale {
+	if locale, ok := r.Context().Value(localeKey{}).(utils.NumberLocale); ok && locale.Tag != "" {
+		return locale
+	}
+	locale, _ := utils.ParseNumberLocale(utils.DefaultNumberLocale)
+	return locale
+}
+
+// addFormatHints adds the format block to a chart's metadata
+func addFormatHints(metadata map[string]interface{}, r *http.Request, unit string, decimals int) {
+	locale := localeFrom(r)
+	metadata["format"] = FormatHints{
+		Unit:             unit,
+		Decimals:         decimals,
+		Locale:           locale.Tag,
+		GroupSeparator:   locale.Group,
+		DecimalSeparator: locale.Decimal,
+	}
+}
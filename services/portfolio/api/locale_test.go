@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+func TestChartFormatHints(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	formatHints := func(url string) FormatHints {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+		testutils.AssertEqual(t, rr.Code, http.StatusOK)
+		var response struct {
+			Data struct {
+				Metadata struct {
+					Format FormatHints `json:"format"`
+				} `json:"metadata"`
+			} `json:"data"`
+		}
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response.Data.Metadata.Format
+	}
+
+	hints := formatHints("/api/v1/lightning/fees")
+	testutils.AssertEqual(t, hints, FormatHints{Unit: "sats", Decimals: 0, Locale: "en", GroupSeparator: ",", DecimalSeparator: "."})
+
+	hints = formatHints("/api/v1/lightning/fees?units=btc&locale=de-AT")
+	testutils.AssertEqual(t, hints, FormatHints{Unit: "BTC", Decimals: 8, Locale: "de", GroupSeparator: ".", DecimalSeparator: ","})
+
+	hints = formatHints("/api/v1/lightning/forwards?locale=fr_FR")
+	testutils.AssertEqual(t, hints.Unit, "forwards")
+	testutils.AssertEqual(t, hints.GroupSeparator, "\u202f")
+
+	// --locale changes the default, the parameter still wins
+	server.numberLocale, _ = utils.ParseNumberLocale("de-CH")
+	testutils.AssertEqual(t, formatHints("/api/v1/lightning/forwards").GroupSeparator, "'")
+	testutils.AssertEqual(t, formatHints("/api/v1/lightning/forwards?locale=en").GroupSeparator, ",")
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/lightning/forwards?locale=klingon", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+}
@@ -1,26 +1,59 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
-	"os/exec"
-	"strconv"
+	"os/signal"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/brewgator/lightning-node-tools/internal/archive"
 	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/capture"
 	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/fixtures"
+	"github.com/brewgator/lightning-node-tools/internal/importer"
+	"github.com/brewgator/lightning-node-tools/internal/liquidity"
+	"github.com/brewgator/lightning-node-tools/internal/listen"
 	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/performance"
+	"github.com/brewgator/lightning-node-tools/internal/price"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/startup"
+	"github.com/brewgator/lightning-node-tools/internal/statement"
+	"github.com/brewgator/lightning-node-tools/internal/swap"
+	"github.com/brewgator/lightning-node-tools/internal/tracing"
 	"github.com/brewgator/lightning-node-tools/internal/utils"
+	"github.com/brewgator/lightning-node-tools/internal/version"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 )
 
 const (
+	// DefaultCollectorRuns and MaxCollectorRuns bound the collector run log listing
+	DefaultCollectorRuns = 50
+	MaxCollectorRuns     = 500
+	// DefaultCaptures and MaxCaptures bound the captured response listing
+	DefaultCaptures = 20
+	MaxCaptures     = 200
+	// DefaultQuarantined and MaxQuarantined bound the quarantined record listing
+	DefaultQuarantined = 50
+	MaxQuarantined     = 500
 	// MaxHistoryDays is the maximum number of days that can be requested for historical data
 	MaxHistoryDays = 365
 	// BitcoinGenesisDate is the date of the Bitcoin genesis block (January 3, 2009)
@@ -28,27 +61,82 @@ const (
 )
 
 type Server struct {
-	db              *db.Database
-	router          *mux.Router
+	db     *db.Database
+	router *mux.Router
+	// adminRouter additionally serves the admin endpoints, see admin.go
+	adminRouter     *mux.Router
 	balanceService  *bitcoin.BalanceService
 	realtimeService *bitcoin.RealtimeBalanceService
 	lndClient       *lnd.Client
-	mockMode        bool
+	// bitcoinClient answers multisig spend simulations; nil without Bitcoin Core
+	bitcoinClient *bitcoin.Client
+	// nodes holds the node versions detected at startup, for feature gating
+	nodes    version.Nodes
+	mockMode bool
+	// liquidity classifies channels as balanced, depleted or saturated
+	liquidity liquidity.Config
+	// prices converts amounts to fiat for units=fiat and the currency
+	// parameter; nil disables both
+	prices       *price.Service
+	fiatCurrency string
+	// fiatCurrencies are the currencies the currency parameter accepts and
+	// whose prices are recorded, fiatCurrency first
+	fiatCurrencies []string
+	// chartTheme names the theme charts use without a theme parameter
+	chartTheme string
+	// numberLocale is how chart format hints write numbers without a locale
+	// parameter
+	numberLocale utils.NumberLocale
+	// confirmations is how many confirmations each address class needs
+	confirmations bitcoin.ConfirmationPolicy
+	// blockHeight returns the chain height for lease expiry countdowns; nil leaves them out
+	blockHeight func() (int64, error)
+	// captureDir is where debug capture keeps node responses; empty when off
+	captureDir string
+	// swapProviders are compared by the swap quote endpoint
+	swapProviders []swap.Provider
+	// metrics counts requests per route on both listeners
+	metrics *metrics.Registry
+	// channelRequests accepts channel requests from other nodes; nil when
+	// they are not accepted
+	channelRequests *channelRequestInbox
+	// openChannel opens approved channel requests; nil without LND
+	openChannel openChannelFunc
+	// notify announces funds received by imported multisig wallets, e.g. on
+	// Telegram; nil disables it
+	notify func(message string)
+	// stream pushes updates to /ws clients; nil disables it
+	stream *streamHub
+	// archive holds the forwards and channel snapshots moved out of the
+	// database by lnt archive; nil in mock mode
+	archive *archive.Archive
 }
 
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success bool         `json:"success"`
+	Data    interface{}  `json:"data,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+	// Annotations are the notes within the range of a history response
+	Annotations []db.Annotation `json:"annotations,omitempty"`
 }
 
-func getVersion() string {
-	// Use absolute path to git and set working directory
-	cmd := exec.Command("/usr/bin/git", "rev-parse", "--short", "HEAD")
-	if output, err := cmd.Output(); err == nil {
-		return strings.TrimSpace(string(output))
+// detectNodeVersion asks a node for its version, adds it to nodes for
+// feature gating and records it so upgrades show up in the version history
+func detectNodeVersion(database *db.Database, nodes version.Nodes, node string, get func() (string, error)) {
+	raw, err := nodes.Detect(node, get)
+	if err != nil {
+		log.Printf("⚠️  Warning: %v", err)
+		return
+	}
+	changed, err := database.RecordNodeVersion(node, raw)
+	if err != nil {
+		log.Printf("⚠️  Warning: failed to record %s version: %v", node, err)
+		return
+	}
+	if changed {
+		fmt.Printf("📦 Detected %s %s\n", node, raw)
 	}
-	return "unknown"
 }
 
 func main() {
@@ -56,10 +144,69 @@ func main() {
 		dbPath        = flag.String("db", "data/portfolio.db", "Path to SQLite database")
 		port          = flag.String("port", "8090", "Port to serve on")
 		host          = flag.String("host", "127.0.0.1", "Host to serve on")
+		listenAddr    = flag.String("listen", "", "Serve on host:port or unix:/path instead of --host and --port, e.g. unix:/run/portfolio-api/api.sock for a reverse proxy")
 		mockMode      = flag.Bool("mock", false, "Use mock data for testing without real data")
+		verboseLogs   = flag.Bool("verbose-logs", false, redact.FlagUsage)
 		noBitcoinNode = flag.Bool("no-bitcoin", false, "Disable Bitcoin node integration")
+		fallbackURL   = flag.String("history-fallback", "", "Esplora or mempool.space API for address history below a pruned node's prune height")
+		lowRatio      = flag.Float64("channel-low-ratio", liquidity.DefaultLowRatio, "Local balance ratio below which a channel is depleted")
+		highRatio     = flag.Float64("channel-high-ratio", liquidity.DefaultHighRatio, "Local balance ratio above which a channel is saturated")
+		ratioOverride = flag.String("channel-ratio-overrides", "", "Per-channel thresholds as chan_id:low:high, comma separated")
+		cacheTTL      = flag.Duration("balance-cache-ttl", bitcoin.DefaultBalanceCacheTTL, "How long real-time address balances are cached")
+		priceURL      = flag.String("price-api", "https://mempool.space/api", "mempool.space API used for BTC prices (empty disables units=fiat)")
+		fiatCurrency  = flag.String("fiat-currency", "USD", "Currency used for units=fiat")
+		fiatExtra     = flag.String("fiat-currencies", "", "More currencies accepted by the currency parameter, comma separated; their prices are recorded along with --fiat-currency's")
+		coinbaseURL   = flag.String("coinbase-api", price.DefaultCoinbaseURL, "Coinbase API asked for BTC prices when --price-api fails (empty disables)")
+		priceEvery    = flag.Duration("price-interval", time.Hour, "How often BTC prices are fetched and recorded while no request asks for them (0 records only those requests fetch)")
+		chartTheme    = flag.String("chart-theme", DefaultChartTheme, "Chart color theme used without a theme parameter: "+strings.Join(chartThemeNames(), ", "))
+		numberLocale  = flag.String("locale", utils.DefaultNumberLocale, "Locale of the number format hints in chart metadata without a locale parameter: "+strings.Join(utils.NumberLocales(), ", "))
+		minConfs      = flag.String("min-confirmations", bitcoin.DefaultConfirmationPolicy().String(), "Confirmations before funds count as confirmed, as class:count per address class, comma separated")
+		boltzURL      = flag.String("boltz-api", swap.DefaultBoltzURL, "Boltz API used for swap quotes (empty disables)")
+		loopURL       = flag.String("loop-rest", swap.DefaultLoopURL, "loopd REST API used for swap quotes (empty disables)")
+		loopMacaroon  = flag.String("loop-macaroon", swap.DefaultConfig().LoopMacaroon, "loopd macaroon file")
+		loopTLSCert   = flag.String("loop-tls-cert", swap.DefaultConfig().LoopTLSCert, "loopd TLS certificate file")
+		startupWait   = flag.Duration("startup-wait", 0, "How long to wait for Bitcoin Core and LND at startup before serving without them (0 tries once)")
+		captureDir    = flag.String("capture-dir", "", "Keep raw bitcoin-cli and lncli responses in this directory for debugging, and list them at /system/captures (empty disables)")
+		captureMaxMB  = flag.Int64("capture-max-mb", capture.DefaultMaxBytes>>20, "Size cap of --capture-dir in MB; the oldest responses are deleted first")
+		adminAddr     = flag.String("admin-addr", DefaultAdminAddr, "Loopback host:port, or unix:/path, serving the endpoints that change data and /system (empty disables them)")
+		apiKeysPath   = flag.String("api-keys", "", "File of \"profile key [read|write]\" lines, managed with lnt api-keys; when set, API requests need a key in an "+apiKeyHeader+" or bearer Authorization header, which selects the profile served. SIGHUP reloads it")
+		rateLimit     = flag.Int("rate-limit", DefaultRateLimit, "API requests one client, by API key or else IP, may make per minute on the public listener (0 disables)")
+		chanRequests  = flag.Bool("channel-requests", false, "Accept channel requests from other nodes on the public listener (POST /api/v1/channel-requests)")
+		chanReqRate   = flag.Int("channel-requests-per-hour", DefaultChannelRequestsPerHour, "Channel requests one client IP may submit per hour")
+		chanReqMax    = flag.Int("channel-requests-max-pending", DefaultMaxPendingChannelRequests, "Pending channel requests beyond which new ones are refused")
+		streamEvery   = flag.Duration("stream-interval", DefaultStreamInterval, "How often /ws checks for portfolio, forward and channel updates while clients are connected")
+		signMode      = flag.String("sign", "", "Sign API responses so consumers can verify which node they came from: \"key\" with --sign-key, \"node\" with the LND node's identity key (empty disables)")
+		signKeyPath   = flag.String("sign-key", "data/signing.key", "Ed25519 key used by --sign key, created when missing")
+		otlpEndpoint  = flag.String("otlp-endpoint", "", "OTLP/HTTP collector traces are exported to, e.g. http://localhost:4318 for Jaeger or Tempo (empty disables tracing)")
+		traceSample   = flag.Float64("trace-sample", 1, "Share of requests traced with --otlp-endpoint, from 0 to 1; requests with a traceparent header follow their caller")
 	)
 	flag.Parse()
+	redact.SetVerbose(*verboseLogs)
+
+	liquidityConfig := liquidity.NewConfig()
+	liquidityConfig.Default.Low = *lowRatio
+	liquidityConfig.Default.High = *highRatio
+	if err := liquidityConfig.Default.Validate(); err != nil {
+		log.Fatalf("Invalid channel ratio thresholds: %v", err)
+	}
+	overrides, err := liquidity.ParseOverrides(*ratioOverride, liquidityConfig.Default.Hysteresis)
+	if err != nil {
+		log.Fatalf("Invalid --channel-ratio-overrides: %v", err)
+	}
+	liquidityConfig.Overrides = overrides
+
+	if _, ok := chartThemes[*chartTheme]; !ok {
+		log.Fatalf("Invalid --chart-theme %q: must be one of %s", *chartTheme, strings.Join(chartThemeNames(), ", "))
+	}
+	locale, err := utils.ParseNumberLocale(*numberLocale)
+	if err != nil {
+		log.Fatalf("Invalid --locale: %v", err)
+	}
+
+	confirmations, err := bitcoin.ParseConfirmationPolicy(*minConfs)
+	if err != nil {
+		log.Fatalf("Invalid --min-confirmations: %v", err)
+	}
 
 	// Initialize database with mock mode support
 	database, err := db.NewDatabaseWithMockMode(*dbPath, *mockMode)
@@ -72,142 +219,440 @@ func main() {
 		fmt.Println("📊 API running in mock mode (using mock database tables)")
 	}
 
+	if *captureDir != "" {
+		if err := capture.EnableDir(*captureDir, *captureMaxMB); err != nil {
+			log.Fatalf("Failed to enable debug capture: %v", err)
+		}
+		fmt.Printf("🐛 Capturing raw node responses in %s (up to %d MB)\n", *captureDir, *captureMaxMB)
+	}
+
 	var balanceService *bitcoin.BalanceService
 	var realtimeService *bitcoin.RealtimeBalanceService
+	var bitcoinClient *bitcoin.Client
 	var lndClient *lnd.Client
+	nodes := version.Nodes{}
 
-	// Initialize real-time Bitcoin service if not disabled
+	// Initialize real-time services if not disabled
 	if !*noBitcoinNode && !*mockMode {
-		bitcoinClient, err := bitcoin.NewClient()
+		builder := bitcoin.NewRealtimeServiceBuilder(database).
+			WithCacheTTL(*cacheTTL).
+			WithConfirmationPolicy(confirmations)
+
+		var err error
+		bitcoinClient, err = startup.WaitFor("Bitcoin Core", *startupWait, bitcoin.NewClient)
 		if err != nil {
-			log.Printf("⚠️  Warning: Failed to connect to Bitcoin node: %v", err)
+			startup.NotifyDegraded("portfolio-api", "Bitcoin Core", err)
 			log.Printf("💡 Real-time balance updates will be disabled. Ensure bitcoin-cli is available and Bitcoin Core is running.")
 		} else {
-			fmt.Println("₿ Connected to Bitcoin Core node for real-time queries")
-			// Create real-time service with LND client (will be set below if available)
-			realtimeService = bitcoin.NewRealtimeBalanceService(bitcoinClient, database, nil)
+			detectNodeVersion(database, nodes, version.NodeBitcoinCore, bitcoinClient.GetVersion)
+			if err := nodes.Check(version.DescriptorWallet); err != nil {
+				log.Printf("⚠️  Real-time balance updates disabled: %v", err)
+			} else {
+				fmt.Println("₿ Connected to Bitcoin Core node for real-time queries")
+				builder.WithBitcoin(bitcoinClient)
+			}
 		}
 
 		// Initialize LND client for Lightning data
-		lndClient, err = lnd.NewClient()
+		lndClient, err = startup.WaitFor("LND", *startupWait, lnd.NewClient)
 		if err != nil {
-			log.Printf("⚠️  Warning: Failed to connect to LND: %v", err)
+			startup.NotifyDegraded("portfolio-api", "LND", err)
 			log.Printf("💡 Lightning balance data will not be available")
 		} else {
 			fmt.Println("⚡ Connected to LND node")
-			// Update real-time service with LND client if both Bitcoin and LND are available
-			if realtimeService != nil {
-				realtimeService = bitcoin.NewRealtimeBalanceService(bitcoinClient, database, lndClient)
+			detectNodeVersion(database, nodes, version.NodeLND, lnd.GetVersion)
+			builder.WithLightning(lndClient)
+		}
+
+		if *fallbackURL != "" {
+			builder.WithHistoryFallback(mempool.NewClient(*fallbackURL))
+		}
+
+		realtimeService, err = builder.Build()
+		if err != nil {
+			log.Printf("⚠️  Real-time service unavailable: %v", err)
+		} else {
+			if builder.HasLightning() {
 				log.Println("📊 Real-time service enhanced with Lightning data")
 			}
+			go realtimeService.WarmCache()
 		}
 	}
 
 	server := &Server{
 		db:              database,
 		router:          mux.NewRouter(),
+		adminRouter:     mux.NewRouter(),
 		balanceService:  balanceService,
 		realtimeService: realtimeService,
 		lndClient:       lndClient,
+		bitcoinClient:   bitcoinClient,
+		nodes:           nodes,
 		mockMode:        *mockMode,
+		liquidity:       liquidityConfig,
+		fiatCurrency:    strings.ToUpper(*fiatCurrency),
+		fiatCurrencies:  fiatCurrencies(*fiatCurrency, *fiatExtra),
+		chartTheme:      *chartTheme,
+		numberLocale:    locale,
+		confirmations:   confirmations,
+		captureDir:      *captureDir,
+		metrics:         metrics.NewRegistry(),
+		notify:          telegramNotifier("multisig"),
+	}
+	if lndClient != nil {
+		server.blockHeight = lnd.GetBlockHeight
+		server.openChannel = lnd.ConnectAndOpenChannel
+	}
+	if !*mockMode {
+		server.archive = archive.New(archive.DirFor(*dbPath))
+	}
+	if *mockMode {
+		if _, err := server.useFixtureNode(); err != nil {
+			log.Fatalf("Failed to set up the mock node: %v", err)
+		}
+	}
+	if *streamEvery <= 0 {
+		log.Fatalf("Invalid --stream-interval: must be positive")
+	}
+	server.stream = newStreamHub(server, *streamEvery)
+	if *chanRequests {
+		if *chanReqRate <= 0 || *chanReqMax <= 0 {
+			log.Fatalf("Invalid channel request limits: --channel-requests-per-hour and --channel-requests-max-pending must be positive")
+		}
+		server.channelRequests = newChannelRequestInbox(*chanReqRate, *chanReqMax)
+		fmt.Printf("📨 Accepting channel requests (%d per client per hour, up to %d pending)\n", *chanReqRate, *chanReqMax)
+	}
+	swapConfig := swap.Config{BoltzURL: *boltzURL, LoopURL: *loopURL, LoopMacaroon: *loopMacaroon, LoopTLSCert: *loopTLSCert}
+	providers, errs := swapConfig.Providers()
+	for _, err := range errs {
+		log.Printf("⚠️  Warning: %v", err)
+	}
+	server.swapProviders = providers
+	var priceSources price.Fallback
+	if *priceURL != "" {
+		priceSources = append(priceSources, mempool.NewClient(*priceURL))
+	}
+	if *coinbaseURL != "" {
+		priceSources = append(priceSources, price.NewCoinbase(*coinbaseURL))
+	}
+	if len(priceSources) > 0 {
+		server.prices = price.NewService(priceSources, price.DefaultCacheTTL)
+		server.prices.Record(database, server.fiatCurrencies)
+		if *priceEvery > 0 {
+			go server.prices.Run(*priceEvery, nil)
+		}
 	}
 
+	// The gauges show the default profile's balances
+	server.registerBalanceMetrics()
 	server.setupRoutes()
 
+	publicHandler, adminHandler := http.Handler(server.router), http.Handler(server.adminRouter)
+	rateClient := clientOf
+	if *apiKeysPath != "" {
+		keys, err := loadAPIKeys(*apiKeysPath)
+		if err != nil {
+			log.Fatalf("Invalid --api-keys: %v", err)
+		}
+		servers := map[string]*Server{db.DefaultProfile: server}
+		for _, profile := range keys.profiles() {
+			if profile == db.DefaultProfile {
+				continue
+			}
+			path, err := db.ProfilePath(*dbPath, profile)
+			if err != nil {
+				log.Fatalf("Invalid --api-keys: %v", err)
+			}
+			profileDB, err := db.NewDatabaseWithMockMode(path, *mockMode)
+			if err != nil {
+				log.Fatalf("Failed to initialize database of profile %s: %v", profile, err)
+			}
+			defer profileDB.Close()
+			servers[profile] = server.forProfile(profileDB)
+			if !*mockMode {
+				servers[profile].archive = archive.New(archive.DirFor(path))
+			}
+		}
+		ring := newKeyring(keys)
+		publicHandler = ring.handler(servers, func(s *Server) *mux.Router { return s.router })
+		adminHandler = ring.handler(servers, func(s *Server) *mux.Router { return s.adminRouter })
+		rateClient = ring.client
+		go reloadKeysOnHangup(ring, *apiKeysPath, servers)
+		fmt.Printf("🔑 API keys required, serving profiles %s\n", strings.Join(keys.profiles(), ", "))
+	}
+	signer, err := newSigner(*signMode, *signKeyPath)
+	if err != nil {
+		log.Fatalf("Invalid --sign: %v", err)
+	}
+	if signer != nil {
+		publicHandler = signed(publicHandler, signer)
+		adminHandler = signed(adminHandler, signer)
+		fmt.Printf("✍️  Signing API responses (%s key %s)\n", signer.Scheme(), signer.PublicKey())
+	}
+	if *rateLimit > 0 {
+		publicHandler = server.rateLimited(publicHandler, *rateLimit, rateClient)
+	}
+	if *otlpEndpoint != "" {
+		tracer, err := tracing.New(*otlpEndpoint, "portfolio-api", *traceSample)
+		if err != nil {
+			log.Fatalf("Invalid tracing flags: %v", err)
+		}
+		defer tracer.Close()
+		publicHandler = tracer.Handler(publicHandler)
+		adminHandler = tracer.Handler(adminHandler)
+		fmt.Printf("🔭 Tracing %g of requests to %s\n", *traceSample, tracer.Endpoint())
+	}
+
 	// Setup CORS
 	c := cors.New(cors.Options{
 		// TODO: Replace with your actual frontend domain(s) in production.
 		AllowedOrigins: []string{"https://your-frontend-domain.com"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		// Changes go through the admin listener, which is not cross-origin
+		AllowedMethods: []string{"GET", "OPTIONS"},
 		AllowedHeaders: []string{"*"},
 	})
 
-	handler := c.Handler(server.router)
+	handler := c.Handler(publicHandler)
 
-	addr := fmt.Sprintf("%s:%s", *host, *port)
-	fmt.Printf("🚀 Portfolio Dashboard API starting on http://%s\n", addr)
+	addr := *listenAddr
+	if addr == "" {
+		addr = fmt.Sprintf("%s:%s", *host, *port)
+	}
+	listener, err := listen.Listen(addr)
+	if err != nil {
+		log.Fatalf("Failed to start API: %v", err)
+	}
+	if path, ok := listen.IsUnix(addr); ok {
+		fmt.Printf("🚀 Portfolio Dashboard API starting on unix socket %s\n", path)
+	} else {
+		fmt.Printf("🚀 Portfolio Dashboard API starting on http://%s\n", addr)
+	}
 	fmt.Printf("📊 Database: %s", *dbPath)
 	if *mockMode {
 		fmt.Printf(" (mock mode)")
 	}
 	fmt.Printf("\n")
 
-	// Real-time service doesn't need cleanup (no background processes)
+	endpoints := []listen.Endpoint{{Listener: listener, Handler: handler}}
+	if *adminAddr != "" {
+		adminListener, err := listenAdmin(*adminAddr)
+		if err != nil {
+			log.Fatalf("Failed to start admin API: %v", err)
+		}
+		fmt.Printf("🔐 Admin API on %s\n", *adminAddr)
+		endpoints = append(endpoints, listen.Endpoint{Listener: adminListener, Handler: adminHandler})
+	} else {
+		fmt.Println("🔐 Admin API disabled, data can only be changed by the collectors")
+	}
 
-	log.Fatal(http.ListenAndServe(addr, handler))
+	// Real-time service doesn't need cleanup (no background processes).
+	// Shutting down closes the listeners, which removes unix sockets.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := listen.Serve(ctx, endpoints...); err != nil {
+		log.Fatalf("API server failed: %v", err)
+	}
+	fmt.Println("Received shutdown signal, exiting...")
 }
 
 func (s *Server) setupRoutes() {
-	// API routes
-	api := s.router.PathPrefix("/api").Subrouter()
+	// API routes are served under /api/v1, with /api kept as a deprecated alias
+	s.mountAPI(s.router, func(api *mux.Router) { s.registerAPIRoutes(api, s.adminOnly) })
+	s.mountAPI(s.adminRouter, func(api *mux.Router) { s.registerAPIRoutes(api, adminRoute) })
+
+	// Requests are counted per route on both listeners; Prometheus scrapes
+	// the admin listener
+	s.router.Use(s.metrics.Middleware(routeTemplate))
+	s.adminRouter.Use(s.metrics.Middleware(routeTemplate))
+	s.adminRouter.Use(markAdmin)
+	// Traced requests' spans are named after their route too
+	s.router.Use(tracing.Route(routeTemplate))
+	s.adminRouter.Use(tracing.Route(routeTemplate))
+	// The shared LND, collector and cache metrics are scraped with the requests
+	s.adminRouter.Handle("/metrics", s.metrics.Handler(metricsNamespace, metrics.Default)).Methods("GET")
+
+	// Unknown API paths get a JSON error; everything else is a static file
+	static := http.FileServer(http.Dir("web/static/"))
+	s.router.NotFoundHandler = s.handleNotFound(s.router, static)
+	s.adminRouter.NotFoundHandler = s.handleNotFound(s.adminRouter, static)
+}
 
+// registerAPIRoutes adds every API endpoint to the given router. It is called
+// once per mounted prefix and listener; admin wraps the endpoints that are
+// only served on the admin listener, see admin.go.
+func (s *Server) registerAPIRoutes(api *mux.Router, admin func(http.HandlerFunc) http.HandlerFunc) {
 	// Portfolio endpoints
-	api.HandleFunc("/portfolio/current", s.handleCurrentPortfolio).Methods("GET")
-	api.HandleFunc("/portfolio/history", s.handlePortfolioHistory).Methods("GET")
+	api.HandleFunc("/portfolio/current", s.withUnits(s.handleCurrentPortfolio)).Methods("GET")
+	api.HandleFunc("/portfolio/history", s.withTimeRange(s.withUnits(s.handlePortfolioHistory))).Methods("GET")
+	api.HandleFunc("/portfolio/breakdown", s.withUnits(s.handlePortfolioBreakdown)).Methods("GET")
+	api.HandleFunc("/portfolio/at", s.withUnits(s.handlePortfolioAt)).Methods("GET")
+	api.HandleFunc("/portfolio/import", admin(s.handlePortfolioImport)).Methods("POST")
+	api.HandleFunc("/portfolio/performance", s.handlePortfolioPerformance).Methods("GET")
+	api.HandleFunc("/portfolio/transfers", s.withTimeRange(s.handleGetPortfolioTransfers)).Methods("GET")
+	api.HandleFunc("/portfolio/transfers", admin(s.handleAddPortfolioTransfer)).Methods("POST")
+	api.HandleFunc("/portfolio/transfers/{id:[0-9]+}", admin(s.handleDeletePortfolioTransfer)).Methods("DELETE")
+
+	// Live updates of the portfolio, forwards and channels over a WebSocket
+	api.HandleFunc("/ws", s.withUnits(s.handleStream)).Methods("GET")
+
+	// Chart endpoints
+	api.HandleFunc("/charts/themes", s.handleChartThemes).Methods("GET")
+
+	// Insight endpoints
+	api.HandleFunc("/insights", s.handleInsights).Methods("GET")
+
+	// Annotation endpoints
+	api.HandleFunc("/annotations", s.withTimeRange(s.handleGetAnnotations)).Methods("GET")
+	api.HandleFunc("/annotations", admin(s.handleAddAnnotation)).Methods("POST")
+	api.HandleFunc("/annotations/{id:[0-9]+}", admin(s.handleDeleteAnnotation)).Methods("DELETE")
 
 	// Lightning endpoints
-	api.HandleFunc("/lightning/fees", s.handleLightningFees).Methods("GET")
-	api.HandleFunc("/lightning/forwards", s.handleLightningForwards).Methods("GET")
+	api.HandleFunc("/lightning/fees", s.withTimeRange(s.withUnits(s.withTheme(s.withLocale(s.handleLightningFees))))).Methods("GET")
+	api.HandleFunc("/lightning/forwards", s.withTimeRange(s.withTheme(s.withLocale(s.handleLightningForwards)))).Methods("GET")
+	api.HandleFunc("/lightning/forwards/export", s.withTimeRange(s.handleLightningForwardsExport)).Methods("GET")
+	api.HandleFunc("/lightning/forwards/stats", s.withTimeRange(s.handleLightningForwardStats)).Methods("GET")
+	api.HandleFunc("/lightning/forwards/failures", s.withTimeRange(s.handleHTLCFailures)).Methods("GET")
+	api.HandleFunc("/lightning/forwards/ppm-histogram", s.withTimeRange(s.withTheme(s.withLocale(s.handleFeePPMHistogram)))).Methods("GET")
+	api.HandleFunc("/lightning/channels", s.handleLightningChannels).Methods("GET")
+	api.HandleFunc("/lightning/channels/fees", s.withTimeRange(s.handleChannelFees)).Methods("GET")
+	api.HandleFunc("/lightning/channels/names", s.handleGetChannelNames).Methods("GET")
+	api.HandleFunc("/lightning/channels/{id}", s.handleLightningChannel).Methods("GET")
+	api.HandleFunc("/lightning/channels/{id}/export", s.withTimeRange(s.handleChannelSnapshotsExport)).Methods("GET")
+	api.HandleFunc("/lightning/channels/{id}/balance-history", s.withTimeRange(s.withUnits(s.withTheme(s.withLocale(s.handleChannelBalanceHistory))))).Methods("GET")
+	api.HandleFunc("/lightning/mission-control", s.handleMissionControl).Methods("GET")
+	api.HandleFunc("/lightning/reliability", s.withTimeRange(s.withTheme(s.withLocale(s.handleReliability)))).Methods("GET")
+	api.HandleFunc("/swaps/quotes", s.handleSwapQuotes).Methods("GET")
+	api.HandleFunc("/lightning/leases", s.handleChannelLeases).Methods("GET")
+	api.HandleFunc("/lightning/channels/{id}/lease", s.handleGetChannelLease).Methods("GET")
+	api.HandleFunc("/lightning/channels/{id}/lease", admin(s.handleSetChannelLease)).Methods("PUT")
+	api.HandleFunc("/lightning/channels/{id}/lease", admin(s.handleDeleteChannelLease)).Methods("DELETE")
+	api.HandleFunc("/lightning/channels/{id}/name", admin(s.handleSetChannelName)).Methods("PUT")
+	api.HandleFunc("/lightning/channels/{id}/name", admin(s.handleDeleteChannelName)).Methods("DELETE")
+
+	// Peer policy endpoints
+	api.HandleFunc("/peers/policies", s.handleGetPeerPolicies).Methods("GET")
+	api.HandleFunc("/peers/policies/{pubkey}", s.handleGetPeerPolicy).Methods("GET")
+	api.HandleFunc("/peers/policies/{pubkey}", admin(s.handleSetPeerPolicy)).Methods("PUT")
+	api.HandleFunc("/peers/policies/{pubkey}", admin(s.handleDeletePeerPolicy)).Methods("DELETE")
+	api.HandleFunc("/peers/notes", admin(s.handleGetPeerNotes)).Methods("GET")
+	api.HandleFunc("/peers/notes/{pubkey}", admin(s.handleGetPeerNote)).Methods("GET")
+	api.HandleFunc("/peers/notes/{pubkey}", admin(s.handleSetPeerNote)).Methods("PUT")
+	api.HandleFunc("/peers/notes/{pubkey}", admin(s.handleDeletePeerNote)).Methods("DELETE")
+
+	// Channel requests: submitted by other nodes on the public listener,
+	// reviewed on the admin listener
+	api.HandleFunc("/channel-requests", s.handleSubmitChannelRequest).Methods("POST")
+	api.HandleFunc("/channel-requests", admin(s.handleGetChannelRequests)).Methods("GET")
+	api.HandleFunc("/channel-requests/{id:[0-9]+}/approve", admin(s.handleApproveChannelRequest)).Methods("POST")
+	api.HandleFunc("/channel-requests/{id:[0-9]+}/reject", admin(s.handleRejectChannelRequest)).Methods("POST")
 
 	// Onchain endpoints
 	api.HandleFunc("/onchain/addresses", s.handleGetOnchainAddresses).Methods("GET")
-	api.HandleFunc("/onchain/addresses", s.handleAddOnchainAddress).Methods("POST")
-	api.HandleFunc("/onchain/addresses/{id:[0-9]+}", s.handleDeleteOnchainAddress).Methods("DELETE")
-	api.HandleFunc("/onchain/history", s.handleOnchainHistory).Methods("GET")
+	api.HandleFunc("/onchain/addresses", admin(s.handleAddOnchainAddress)).Methods("POST")
+	api.HandleFunc("/onchain/addresses/{id:[0-9]+}", admin(s.handleUpdateOnchainAddress)).Methods("PUT")
+	api.HandleFunc("/onchain/addresses/{id:[0-9]+}", admin(s.handleDeleteOnchainAddress)).Methods("DELETE")
+	api.HandleFunc("/onchain/addresses/deleted", s.handleGetDeletedOnchainAddresses).Methods("GET")
+	api.HandleFunc("/onchain/addresses/{id:[0-9]+}/restore", admin(s.handleRestoreOnchainAddress)).Methods("POST")
+	api.HandleFunc("/onchain/addresses/{id:[0-9]+}/history", s.withTimeRange(s.withUnits(s.withTheme(s.withLocale(s.handleOnchainAddressHistory))))).Methods("GET")
+	api.HandleFunc("/onchain/history", s.withTimeRange(s.withUnits(s.withTheme(s.withLocale(s.handleOnchainHistory))))).Methods("GET")
+	api.HandleFunc("/onchain/multisig/spend", s.handleMultisigSpend).Methods("GET")
+	api.HandleFunc("/onchain/multisig/wallets", admin(s.handleGetMultisigWallets)).Methods("GET")
+	api.HandleFunc("/onchain/multisig/wallets", admin(s.handleImportMultisigWallet)).Methods("POST")
 
 	// Offline/Cold storage endpoints (consolidated)
 	api.HandleFunc("/offline/accounts", s.handleGetOfflineAccounts).Methods("GET")
-	api.HandleFunc("/offline/accounts", s.handleAddOfflineAccount).Methods("POST")
-	api.HandleFunc("/offline/accounts/{id:[0-9]+}/balance", s.handleUpdateOfflineAccountBalance).Methods("PUT")
-	api.HandleFunc("/offline/accounts/{id:[0-9]+}", s.handleDeleteOfflineAccount).Methods("DELETE")
-	api.HandleFunc("/offline/history", s.handleOfflineHistory).Methods("GET")
+	api.HandleFunc("/offline/accounts", admin(s.handleAddOfflineAccount)).Methods("POST")
+	api.HandleFunc("/offline/accounts/{id:[0-9]+}/balance", admin(s.handleUpdateOfflineAccountBalance)).Methods("PUT")
+	api.HandleFunc("/offline/accounts/{id:[0-9]+}/metadata", admin(s.handleUpdateOfflineAccountMetadata)).Methods("PUT")
+	api.HandleFunc("/offline/accounts/{id:[0-9]+}", admin(s.handleDeleteOfflineAccount)).Methods("DELETE")
+	api.HandleFunc("/offline/accounts/deleted", s.handleGetDeletedOfflineAccounts).Methods("GET")
+	api.HandleFunc("/offline/accounts/{id:[0-9]+}/restore", admin(s.handleRestoreOfflineAccount)).Methods("POST")
+	api.HandleFunc("/offline/accounts/{id:[0-9]+}/history", s.withTimeRange(s.withUnits(s.withTheme(s.withLocale(s.handleOfflineAccountHistory))))).Methods("GET")
+	api.HandleFunc("/offline/history", s.withTimeRange(s.withUnits(s.withTheme(s.withLocale(s.handleOfflineHistory))))).Methods("GET")
 
 	// Strike balance endpoints
 	api.HandleFunc("/strike/balance/current", s.handleStrikeCurrentBalance).Methods("GET")
-	api.HandleFunc("/strike/balance/history", s.handleStrikeBalanceHistory).Methods("GET")
+	api.HandleFunc("/strike/balance/history", s.withTimeRange(s.withTheme(s.withLocale(s.handleStrikeBalanceHistory)))).Methods("GET")
+	api.HandleFunc("/strike/withdrawals", s.handleGetWithdrawals).Methods("GET")
+	api.HandleFunc("/strike/withdrawals/{id:[0-9]+}/approve", admin(s.handleApproveWithdrawal)).Methods("POST")
+	api.HandleFunc("/strike/withdrawals/{id:[0-9]+}/reject", admin(s.handleRejectWithdrawal)).Methods("POST")
+	api.HandleFunc("/strike/withdrawals/{id:[0-9]+}/resolve", admin(s.handleResolveWithdrawal)).Methods("POST")
+
+	// Liquid (L-BTC) balance endpoints
+	api.HandleFunc("/liquid/balance/current", s.withUnits(s.handleLiquidCurrentBalance)).Methods("GET")
+	api.HandleFunc("/liquid/balance/history", s.withTimeRange(s.withUnits(s.withTheme(s.withLocale(s.handleLiquidBalanceHistory))))).Methods("GET")
+
+	// Ecash (Fedimint, Cashu) balance endpoints
+	api.HandleFunc("/ecash/balances", s.withUnits(s.handleEcashBalances)).Methods("GET")
+	api.HandleFunc("/ecash/balance/history", s.withTimeRange(s.withUnits(s.withTheme(s.withLocale(s.handleEcashBalanceHistory))))).Methods("GET")
+
+	// Report endpoints
+	api.HandleFunc("/reports/statements", s.handleGetStatements).Methods("GET")
+	api.HandleFunc("/reports/statement", s.handleStatementReport).Methods("GET")
+
+	// System endpoints
+	api.HandleFunc("/system/collector-runs", admin(s.handleCollectorRuns)).Methods("GET")
+	api.HandleFunc("/system/quarantine", admin(s.handleQuarantine)).Methods("GET")
+	api.HandleFunc("/system/captures", admin(s.handleCaptures)).Methods("GET")
+	api.HandleFunc("/system/stats", admin(s.handleSystemStats)).Methods("GET")
 
 	// Health check
 	api.HandleFunc("/health", s.handleHealth).Methods("GET")
 
 	// Version info
 	api.HandleFunc("/version", s.handleVersion).Methods("GET")
-
-	// Static file serving
-	s.router.PathPrefix("/").Handler(http.FileServer(http.Dir("web/static/")))
 }
 
 func (s *Server) handleCurrentPortfolio(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.currentPortfolio(r.Context())
+	if errors.Is(err, errRealtimeUnavailable) {
+		s.writeError(w, http.StatusServiceUnavailable, "Real-time balance service not available")
+		return
+	}
+	if err != nil {
+		log.Printf("handleCurrentPortfolio: failed to calculate real-time portfolio: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to calculate current portfolio")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: convertSnapshot(*snapshot, unitsFrom(r))})
+}
+
+// errRealtimeUnavailable is returned by currentPortfolio when the API runs
+// without the real-time balance service
+var errRealtimeUnavailable = errors.New("real-time balance service not available")
+
+// currentPortfolio calculates the portfolio as it is now, with Lightning and
+// on-chain wallet balances from LND when it is connected
+func (s *Server) currentPortfolio(ctx context.Context) (*bitcoin.PortfolioSnapshot, error) {
 	if s.mockMode {
 		// Return mock data for testing
-		mockSnapshot := &bitcoin.PortfolioSnapshot{
+		return &bitcoin.PortfolioSnapshot{
 			Timestamp:          time.Now(),
 			LightningLocal:     5000000,
 			LightningRemote:    3000000,
 			OnchainConfirmed:   2000000,
 			OnchainUnconfirmed: 100000,
 			TrackedAddresses:   1500000,
+			TrackedConfirmed:   1450000,
+			TrackedUnconfirmed: 50000,
 			ColdStorage:        10000000,
 			TotalPortfolio:     18600000,
 			TotalLiquid:        8600000,
-		}
-		s.writeJSON(w, APIResponse{Success: true, Data: mockSnapshot})
-		return
+			TotalConfirmed:     18450000,
+		}, nil
 	}
 
 	// Use real-time service if available
 	if s.realtimeService == nil {
-		s.writeError(w, http.StatusServiceUnavailable, "Real-time balance service not available")
-		return
+		return nil, errRealtimeUnavailable
 	}
 
 	// Get real-time portfolio calculation
-	snapshot, err := s.realtimeService.GetCurrentPortfolio()
+	snapshot, err := s.realtimeService.GetCurrentPortfolio(ctx)
 	if err != nil {
-		log.Printf("handleCurrentPortfolio: failed to calculate real-time portfolio: %v", err)
-		s.writeError(w, http.StatusInternalServerError, "Failed to calculate current portfolio")
-		return
+		return nil, err
 	}
-
 	// Add Lightning data if LND client is available
 	if s.lndClient != nil {
 		lightningBalances, err := s.lndClient.GetChannelBalances()
@@ -217,7 +662,7 @@ func (s *Server) handleCurrentPortfolio(w http.ResponseWriter, r *http.Request)
 			snapshot.LightningLocal = lightningBalances.LocalBalance
 			snapshot.LightningRemote = lightningBalances.RemoteBalance
 			// Recalculate totals with Lightning data
-			snapshot.TotalLiquid = snapshot.TrackedAddresses + snapshot.LightningLocal
+			snapshot.TotalLiquid = snapshot.TrackedAddresses + snapshot.Liquid + snapshot.Ecash + snapshot.LightningLocal
 			snapshot.TotalPortfolio = snapshot.TotalLiquid + snapshot.ColdStorage
 		}
 
@@ -229,43 +674,59 @@ func (s *Server) handleCurrentPortfolio(w http.ResponseWriter, r *http.Request)
 			snapshot.OnchainConfirmed = onchainBalance.ConfirmedBalance
 			snapshot.OnchainUnconfirmed = onchainBalance.UnconfirmedBalance
 			// Update totals with on-chain data
-			snapshot.TotalLiquid = snapshot.TrackedAddresses + snapshot.LightningLocal + snapshot.OnchainConfirmed + snapshot.OnchainUnconfirmed
+			snapshot.TotalLiquid = snapshot.TrackedAddresses + snapshot.Liquid + snapshot.Ecash + snapshot.LightningLocal + snapshot.OnchainConfirmed + snapshot.OnchainUnconfirmed
 			snapshot.TotalPortfolio = snapshot.TotalLiquid + snapshot.ColdStorage
 		}
+		snapshot.TotalConfirmed = snapshot.TotalPortfolio - snapshot.Pending()
 	}
 
-	s.writeJSON(w, APIResponse{Success: true, Data: snapshot})
+	return snapshot, nil
 }
 
 func (s *Server) handlePortfolioHistory(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	daysStr := r.URL.Query().Get("days")
-	days := 30 // default
-	var from, to time.Time
-
-	if daysStr == "all" {
-		// For "all" data, get from the earliest possible date
-		to = time.Now()
-		genesisDate, _ := time.Parse("2006-01-02", BitcoinGenesisDate)
-		from = genesisDate
-	} else if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= MaxHistoryDays {
-			days = d
-		} else {
-			s.writeError(w, http.StatusBadRequest, "Invalid days parameter. Must be a number between 1 and "+strconv.Itoa(MaxHistoryDays)+", or 'all'")
-			return
-		}
-		// Calculate time range
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	} else {
-		// Default case
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
+	tr := timeRangeFrom(r)
+
+	if !s.mockMode && s.realtimeService == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Real-time balance service not available")
+		return
+	}
+
+	snapshots, err := s.portfolioHistory(r.Context(), tr.From, tr.To)
+	if err != nil {
+		log.Printf("handlePortfolioHistory: failed to generate portfolio history: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to generate portfolio history")
+		return
+	}
+
+	s.writeHistory(w, r, convertSnapshots(snapshots, unitsFrom(r)))
+}
+
+// useFixtureNode answers lncli and bitcoin-cli calls from internal/fixtures
+// in mock mode, so endpoints that need a node, such as multisig spends and
+// lease countdowns, answer with the same shape as against a real one. It
+// returns a function that restores the real node.
+func (s *Server) useFixtureNode() (restore func(), err error) {
+	restoreLND := lnd.SetRunner(fixtures.LNDRunner)
+	restoreBitcoin := bitcoin.SetRunner(fixtures.BitcoinRunner)
+	restore = func() {
+		restoreLND()
+		restoreBitcoin()
+	}
+
+	s.bitcoinClient, err = bitcoin.NewClient()
+	if err != nil {
+		restore()
+		return nil, err
 	}
+	s.blockHeight = lnd.GetBlockHeight
+	return restore, nil
+}
 
+// portfolioHistory returns portfolio snapshots between from and to, generated
+// by the real-time service, traced within ctx, or mocked daily in mock mode.
+// Callers check that the real-time service is available outside mock mode.
+func (s *Server) portfolioHistory(ctx context.Context, from, to time.Time) ([]bitcoin.PortfolioSnapshot, error) {
 	if s.mockMode {
-		// Return mock historical data
 		var mockSnapshots []bitcoin.PortfolioSnapshot
 		current := from
 		for current.Before(to) || current.Equal(to) {
@@ -275,56 +736,236 @@ func (s *Server) handlePortfolioHistory(w http.ResponseWriter, r *http.Request)
 				ColdStorage:      10000000,
 				TotalPortfolio:   11500000 + int64((current.Unix()%1000)*100),
 				TotalLiquid:      1500000 + int64((current.Unix()%1000)*100),
+				TotalConfirmed:   11500000 + int64((current.Unix()%1000)*100),
 			})
 			current = current.AddDate(0, 0, 1)
 		}
-		s.writeJSON(w, APIResponse{Success: true, Data: mockSnapshots})
+		return mockSnapshots, nil
+	}
+
+	return s.realtimeService.GetPortfolioHistory(ctx, from, to)
+}
+
+// performanceWindows maps the window parameter values to how far back they reach
+var performanceWindows = map[string]func(now time.Time) time.Time{
+	"1m":  func(now time.Time) time.Time { return now.AddDate(0, -1, 0) },
+	"3m":  func(now time.Time) time.Time { return now.AddDate(0, -3, 0) },
+	"1y":  func(now time.Time) time.Time { return now.AddDate(-1, 0, 0) },
+	"all": func(time.Time) time.Time { return genesisDate() },
+}
+
+// performanceWindowNames lists the windows in the order they are returned
+var performanceWindowNames = []string{"1m", "3m", "1y", "all"}
+
+// PerformanceWindow is the portfolio performance over one window
+type PerformanceWindow struct {
+	Window string `json:"window"`
+	performance.Result
+}
+
+// handlePortfolioPerformance handles GET /api/portfolio/performance
+// Returns time-weighted return and ROI with recorded transfers stripped out,
+// for every window or the one given by window=1m|3m|1y|all.
+func (s *Server) handlePortfolioPerformance(w http.ResponseWriter, r *http.Request) {
+	window := r.URL.Query().Get("window")
+	if fieldErr := validateEnum("window", window, performanceWindowNames); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
 		return
 	}
+	windows := performanceWindowNames
+	if window != "" {
+		windows = []string{window}
+	}
 
-	// Use real-time service for historical data generation
-	if s.realtimeService == nil {
+	if !s.mockMode && s.realtimeService == nil {
 		s.writeError(w, http.StatusServiceUnavailable, "Real-time balance service not available")
 		return
 	}
 
-	snapshots, err := s.realtimeService.GetPortfolioHistory(from, to)
+	// One history covering the longest window is shared by all of them
+	now := time.Now()
+	from := now
+	for _, name := range windows {
+		if start := performanceWindows[name](now); start.Before(from) {
+			from = start
+		}
+	}
+
+	snapshots, err := s.portfolioHistory(r.Context(), from, now)
 	if err != nil {
-		log.Printf("handlePortfolioHistory: failed to generate portfolio history: %v", err)
+		log.Printf("handlePortfolioPerformance: failed to generate portfolio history: %v", err)
 		s.writeError(w, http.StatusInternalServerError, "Failed to generate portfolio history")
 		return
 	}
+	transfers, err := s.db.GetPortfolioTransfers(from, now)
+	if err != nil {
+		log.Printf("handlePortfolioPerformance: failed to get transfers: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get portfolio transfers")
+		return
+	}
+
+	points := make([]performance.ValuePoint, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		points = append(points, performance.ValuePoint{Time: snapshot.Timestamp, Value: snapshot.TotalPortfolio})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	flows := make([]performance.Flow, 0, len(transfers))
+	for _, transfer := range transfers {
+		flows = append(flows, performance.Flow{Time: transfer.Timestamp, Amount: transfer.Amount})
+	}
+
+	results := make([]PerformanceWindow, 0, len(windows))
+	for _, name := range windows {
+		since := performance.Since(points, performanceWindows[name](now))
+		results = append(results, PerformanceWindow{Window: name, Result: performance.Calculate(since, flows)})
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: map[string]interface{}{
+		"windows":   results,
+		"transfers": len(transfers),
+	}})
+}
+
+// PortfolioTransferRequest represents the request body for recording a transfer
+type PortfolioTransferRequest struct {
+	// Timestamp defaults to now
+	Timestamp time.Time `json:"timestamp"`
+	// Amount is sats deposited (positive) or withdrawn (negative)
+	Amount int64  `json:"amount"`
+	Notes  string `json:"notes"`
+}
+
+// handleGetPortfolioTransfers handles GET /api/portfolio/transfers
+func (s *Server) handleGetPortfolioTransfers(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+
+	transfers, err := s.db.GetPortfolioTransfers(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleGetPortfolioTransfers: failed to get transfers: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get portfolio transfers")
+		return
+	}
+	if transfers == nil {
+		transfers = []db.PortfolioTransfer{}
+	}
 
-	s.writeJSON(w, APIResponse{Success: true, Data: snapshots})
+	s.writeJSON(w, APIResponse{Success: true, Data: transfers})
 }
 
-func (s *Server) handleLightningFees(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	daysStr := r.URL.Query().Get("days")
-	days := 30 // default
-	var from, to time.Time
-
-	if daysStr == "all" {
-		// For "all" data, get from the earliest possible date
-		to = time.Now()
-		genesisDate, _ := time.Parse("2006-01-02", BitcoinGenesisDate)
-		from = genesisDate
-	} else if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= MaxHistoryDays {
-			days = d
-		} else {
-			s.writeError(w, http.StatusBadRequest, "Invalid days parameter. Must be a number between 1 and "+strconv.Itoa(MaxHistoryDays)+", or 'all'")
+// handleAddPortfolioTransfer handles POST /api/portfolio/transfers
+func (s *Server) handleAddPortfolioTransfer(w http.ResponseWriter, r *http.Request) {
+	var req PortfolioTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+
+	if req.Amount == 0 {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "amount", Message: "amount is required"})
+		return
+	}
+	if req.Amount > utils.MaxSupplySats || req.Amount < -utils.MaxSupplySats {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeOutOfRange, Field: "amount", Message: "amount exceeds the 21M BTC supply"})
+		return
+	}
+	if req.Timestamp.IsZero() {
+		req.Timestamp = time.Now()
+	}
+	if req.Timestamp.After(time.Now()) {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeOutOfRange, Field: "timestamp", Message: "timestamp must not be in the future"})
+		return
+	}
+
+	transfer := &db.PortfolioTransfer{
+		Timestamp: req.Timestamp.UTC(),
+		Amount:    req.Amount,
+		Notes:     strings.TrimSpace(req.Notes),
+	}
+	if err := s.db.InsertPortfolioTransfer(transfer); err != nil {
+		log.Printf("handleAddPortfolioTransfer: failed to insert transfer: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to record transfer")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: transfer})
+}
+
+// handleDeletePortfolioTransfer handles DELETE /api/portfolio/transfers/{id}
+func (s *Server) handleDeletePortfolioTransfer(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "transfer")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	err := s.db.DeletePortfolioTransfer(id)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Transfer not found")
+		return
+	}
+	if err != nil {
+		log.Printf("handleDeletePortfolioTransfer: failed to delete transfer %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to delete transfer")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"message": "Transfer deleted successfully",
+			"id":      id,
+		},
+	})
+}
+
+// MaxImportBytes limits the size of uploaded CSV imports
+const MaxImportBytes = 10 << 20
+
+// handlePortfolioImport handles POST /api/portfolio/import
+// The request body is CSV (see importer.ParseBalanceSnapshotsCSV). Optional query
+// parameters: unit=sats|BTC for the amount columns, dry_run=true to validate only.
+func (s *Server) handlePortfolioImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImportBytes)
+
+	snapshots, err := importer.ParseBalanceSnapshotsCSV(r.Body, r.URL.Query().Get("unit"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+		return
+	}
+
+	// Reject the upload rather than quarantining rows the user can fix
+	for _, snapshot := range snapshots {
+		if err := db.CheckTimestamp(snapshot.Timestamp, db.EarliestBitcoinTime); err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+			return
+		}
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	if !dryRun {
+		if err := s.db.InsertBalanceSnapshots(snapshots); err != nil {
+			log.Printf("handlePortfolioImport: failed to insert snapshots: %v", err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to import snapshots")
 			return
 		}
-		// Calculate time range
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	} else {
-		// Default case
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
 	}
 
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"imported": len(snapshots),
+			"from":     snapshots[0].Timestamp,
+			"to":       snapshots[len(snapshots)-1].Timestamp,
+			"dry_run":  dryRun,
+		},
+	})
+}
+
+func (s *Server) handleLightningFees(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+	from, to := tr.From, tr.To
+	theme := themeFrom(r)
+
 	feeData, err := s.db.GetForwardingEventsFees(from, to)
 	if err != nil {
 		log.Printf("handleLightningFees: failed to get forwarding fees: %v", err)
@@ -339,15 +980,15 @@ func (s *Server) handleLightningFees(w http.ResponseWriter, r *http.Request) {
 			{
 				"label":           "Daily Fees (sats)",
 				"data":            make([]int64, 0, len(feeData)),
-				"backgroundColor": "rgba(54, 162, 235, 0.2)",
-				"borderColor":     "rgba(54, 162, 235, 1)",
+				"backgroundColor": theme.Fill(ColorSecondary),
+				"borderColor":     theme.Border(ColorSecondary),
 				"borderWidth":     1,
 			},
 		},
 		"metadata": map[string]interface{}{
 			"total_fees":     int64(0),
 			"total_forwards": int64(0),
-			"days_requested": days,
+			"days_requested": tr.Days,
 			"days_with_data": len(feeData),
 		},
 	}
@@ -370,35 +1011,14 @@ func (s *Server) handleLightningFees(w http.ResponseWriter, r *http.Request) {
 	chartData["metadata"].(map[string]interface{})["total_fees"] = totalFees
 	chartData["metadata"].(map[string]interface{})["total_forwards"] = totalForwards
 
+	convertChart(chartData, r, "total_fees")
 	s.writeJSON(w, APIResponse{Success: true, Data: chartData})
 }
 
 func (s *Server) handleLightningForwards(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	daysStr := r.URL.Query().Get("days")
-	days := 30 // default
-	var from, to time.Time
-
-	if daysStr == "all" {
-		// For "all" data, get from the earliest possible date
-		to = time.Now()
-		genesisDate, _ := time.Parse("2006-01-02", BitcoinGenesisDate)
-		from = genesisDate
-	} else if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= MaxHistoryDays {
-			days = d
-		} else {
-			s.writeError(w, http.StatusBadRequest, "Invalid days parameter. Must be a number between 1 and "+strconv.Itoa(MaxHistoryDays)+", or 'all'")
-			return
-		}
-		// Calculate time range
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	} else {
-		// Default case
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	}
+	tr := timeRangeFrom(r)
+	from, to := tr.From, tr.To
+	theme := themeFrom(r)
 
 	forwardData, err := s.db.GetForwardingEventsFees(from, to)
 	if err != nil {
@@ -414,8 +1034,8 @@ func (s *Server) handleLightningForwards(w http.ResponseWriter, r *http.Request)
 			{
 				"label":           "Daily Forwards",
 				"data":            make([]int64, 0, len(forwardData)),
-				"backgroundColor": "rgba(75, 192, 192, 0.2)",
-				"borderColor":     "rgba(75, 192, 192, 1)",
+				"backgroundColor": theme.Fill(ColorPrimary),
+				"borderColor":     theme.Border(ColorPrimary),
 				"borderWidth":     1,
 			},
 		},
@@ -423,7 +1043,7 @@ func (s *Server) handleLightningForwards(w http.ResponseWriter, r *http.Request)
 			"total_forwards": int64(0),
 			"total_fees":     int64(0),
 			"success_rate":   float64(100.0), // Currently no failure data available
-			"days_requested": days,
+			"days_requested": tr.Days,
 			"days_with_data": len(forwardData),
 		},
 	}
@@ -445,68 +1065,1514 @@ func (s *Server) handleLightningForwards(w http.ResponseWriter, r *http.Request)
 	chartData["datasets"].([]map[string]interface{})[0]["data"] = data
 	chartData["metadata"].(map[string]interface{})["total_forwards"] = totalForwards
 	chartData["metadata"].(map[string]interface{})["total_fees"] = totalFees
+	addFormatHints(chartData["metadata"].(map[string]interface{}), r, "forwards", 0)
 
 	s.writeJSON(w, APIResponse{Success: true, Data: chartData})
 }
 
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+// handleLightningForwardStats handles GET /api/lightning/forwards/stats
+func (s *Server) handleLightningForwardStats(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+
+	stats, err := s.db.GetForwardingStats(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleLightningForwardStats: failed to get forwarding stats: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get Lightning forwarding stats")
+		return
+	}
+
 	s.writeJSON(w, APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"status":    "healthy",
-			"timestamp": time.Now(),
+			"stats": stats,
+			"metadata": map[string]interface{}{
+				"from":           tr.From,
+				"to":             tr.To,
+				"days_requested": tr.Days,
+			},
 		},
 	})
 }
 
-func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+// handleHTLCFailures handles GET /api/lightning/forwards/failures. Lists the
+// forwards that failed, newest first, as recorded by the forwarding
+// collector from LND's HTLC event stream, with how many failed for each
+// reason. Nothing is recorded while the collector runs without the stream.
+func (s *Server) handleHTLCFailures(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+
+	failures, err := s.db.GetHTLCFailures(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleHTLCFailures: failed to get HTLC failures: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get failed Lightning forwards")
+		return
+	}
+
+	// Failures past the next hop carry no reason
+	byReason := map[string]int{}
+	for _, failure := range failures {
+		reason := failure.Failure
+		if reason == "" {
+			reason = failure.Outcome
+		}
+		byReason[reason]++
+	}
+
 	s.writeJSON(w, APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"version": getVersion(),
+			"failures":  failures,
+			"by_reason": byReason,
+			"metadata": map[string]interface{}{
+				"from":           tr.From,
+				"to":             tr.To,
+				"days_requested": tr.Days,
+			},
 		},
 	})
 }
 
-func (s *Server) writeJSON(w http.ResponseWriter, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Failed to encode JSON response: %v", err)
+// handleChannelFees handles GET /api/lightning/channels/fees. Splits the
+// range's forwards by channel, counting each as the outgoing channel's
+// earnings and as routing the incoming channel brought in.
+func (s *Server) handleChannelFees(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+
+	channels, err := s.db.GetFeesByChannel(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleChannelFees: failed to get fees by channel: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get Lightning fees by channel")
+		return
 	}
-}
 
-func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(APIResponse{
-		Success: false,
-		Error:   message,
-	}); err != nil {
-		log.Printf("Failed to encode error response (status %d, message %q): %v", status, message, err)
+	var totalFees int64
+	for _, channel := range channels {
+		totalFees += channel.OutFees
 	}
-}
 
-// AddOnchainAddressRequest represents the request body for adding a new onchain address
-type AddOnchainAddressRequest struct {
-	// Address is the required Bitcoin on-chain address or extended public key (xpub) to track.
-	// It must be a valid Bitcoin address or xpub string.
-	Address string `json:"address"`
-	// Label is an optional human-readable description for the address; it may be empty.
-	Label string `json:"label"`
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"channels": channels,
+			"metadata": map[string]interface{}{
+				"from":           tr.From,
+				"to":             tr.To,
+				"days_requested": tr.Days,
+				"total_fees":     totalFees,
+			},
+		},
+	})
 }
 
-// EnhancedAddressInfo combines database info with real-time balance
-type EnhancedAddressInfo struct {
-	ID             int64     `json:"id"`
-	Address        string    `json:"address"`
-	Label          string    `json:"label"`
-	Active         bool      `json:"active"`
-	CurrentBalance int64     `json:"current_balance"`
-	TxCount        int64     `json:"tx_count"`
-	LastUpdated    time.Time `json:"last_updated"`
-	Source         string    `json:"source"` // "cache", "bitcoin-core", or "error"
-	Error          string    `json:"error,omitempty"`
-}
+// handleFeePPMHistogram handles GET /api/lightning/forwards/ppm-histogram.
+// Spreads forwards over fee rate buckets, overall and per outgoing channel,
+// to show whether income comes from many low-fee or few high-fee forwards.
+// With channel_id only that channel is returned and charted.
+func (s *Server) handleFeePPMHistogram(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+	theme := themeFrom(r)
+	channelID := r.URL.Query().Get("channel_id")
+	if channelID != "" && !channelIDPattern.MatchString(channelID) {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "channel_id", Message: "Invalid channel ID"})
+		return
+	}
+
+	overall, channels, err := s.db.GetFeePPMHistograms(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleFeePPMHistogram: failed to get fee rate histograms: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get fee rate histogram")
+		return
+	}
+
+	charted := overall
+	if channelID != "" {
+		index := -1
+		for i := range channels {
+			if channels[i].ChannelID == channelID {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			s.writeError(w, http.StatusNotFound, "No forwards out of this channel in the time range")
+			return
+		}
+		channels = channels[index : index+1]
+		charted = &channels[0]
+	}
+
+	labels := make([]string, 0, len(charted.Buckets))
+	forwards := make([]int64, 0, len(charted.Buckets))
+	for _, bucket := range charted.Buckets {
+		label := fmt.Sprintf("%d+ ppm", bucket.MinPPM)
+		if bucket.MaxPPM != nil {
+			label = fmt.Sprintf("%d-%d ppm", bucket.MinPPM, *bucket.MaxPPM-1)
+		}
+		labels = append(labels, label)
+		forwards = append(forwards, bucket.ForwardCount)
+	}
+	metadata := map[string]interface{}{
+		"from":           tr.From,
+		"to":             tr.To,
+		"days_requested": tr.Days,
+	}
+	addFormatHints(metadata, r, "forwards", 0)
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"overall":  overall,
+			"channels": channels,
+			// Format data for Chart.js consumption
+			"chart": map[string]interface{}{
+				"labels": labels,
+				"datasets": []map[string]interface{}{
+					{
+						"label":           "Forwards",
+						"data":            forwards,
+						"backgroundColor": theme.Fill(ColorPrimary),
+						"borderColor":     theme.Border(ColorPrimary),
+						"borderWidth":     1,
+					},
+				},
+			},
+			"metadata": metadata,
+		},
+	})
+}
+
+// channelStatuses are the values accepted by the status filter of
+// /lightning/channels; "unbalanced" matches depleted and saturated channels
+var channelStatuses = []string{liquidity.StateBalanced, liquidity.StateDepleted, liquidity.StateSaturated, "unbalanced"}
+
+// ChannelInfo is a channel from the latest snapshot with its liquidity state,
+// routing activity and health
+type ChannelInfo struct {
+	db.ChannelSnapshot
+	// Name is the operator's name for the channel, to show instead of
+	// PeerAlias when set
+	Name       string               `json:"name,omitempty"`
+	LocalRatio float64              `json:"local_ratio"`
+	Status     string               `json:"status"`
+	Thresholds liquidity.Thresholds `json:"thresholds"`
+	// Forwarding is the routing out of the channel over each of
+	// channelActivityDays before its snapshot
+	Forwarding []ChannelForwarding `json:"forwarding"`
+	// LastForward is the latest forward in or out of the channel up to its
+	// snapshot; nil with DaysSinceLastForward when it never forwarded
+	LastForward          *time.Time       `json:"last_forward"`
+	DaysSinceLastForward *int             `json:"days_since_last_forward"`
+	Health               liquidity.Health `json:"health"`
+	// Lease is set for channels bought or sold as a lease
+	Lease *LeaseInfo `json:"lease,omitempty"`
+	// Refill is set for depleted channels
+	Refill *liquidity.Refill `json:"refill,omitempty"`
+	// PeerPubkey is set once the forwarding collector recorded the channel's
+	// peer, and PeerNote on the admin listener when notes are kept about that peer
+	PeerPubkey string       `json:"peer_pubkey,omitempty"`
+	PeerNote   *db.PeerNote `json:"peer_note,omitempty"`
+}
+
+// channelActivityDays are the windows ChannelInfo.Forwarding covers; the
+// 30-day one feeds the health score
+var channelActivityDays = []int{7, 30, 90}
+
+// ChannelForwarding is the routing out of a channel over the days before its
+// snapshot. Fees are credited to the outgoing channel.
+type ChannelForwarding struct {
+	Days         int   `json:"days"`
+	ForwardCount int64 `json:"forward_count"`
+	Volume       int64 `json:"volume"`
+	Fees         int64 `json:"fees"`
+}
+
+// handleLightningChannels handles GET /api/lightning/channels.
+// Optional query parameter: status (balanced, depleted, saturated, unbalanced).
+// Depleted channels include a refill weighted by their recent fee income;
+// with status=depleted they are listed highest refill priority first.
+func (s *Server) handleLightningChannels(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if fieldErr := validateEnum("status", status, channelStatuses); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	snapshots, err := s.db.GetLatestChannelSnapshots()
+	if err != nil {
+		log.Printf("handleLightningChannels: failed to get channel snapshots: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channels")
+		return
+	}
+
+	leases, err := s.db.GetChannelLeases()
+	if err != nil {
+		log.Printf("handleLightningChannels: failed to get channel leases: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channels")
+		return
+	}
+	leaseByChannel := make(map[string]db.ChannelLease, len(leases))
+	for _, lease := range leases {
+		leaseByChannel[lease.ChannelID] = lease
+	}
+	var height int64
+	if len(leases) > 0 {
+		height = s.currentBlockHeight()
+	}
+
+	channels := []ChannelInfo{}
+	for _, snapshot := range snapshots {
+		var lease *db.ChannelLease
+		if l, ok := leaseByChannel[snapshot.ChannelID]; ok {
+			lease = &l
+		}
+		channel, err := s.channelInfo(snapshot, lease, height, isAdminRequest(r))
+		if err != nil {
+			log.Printf("handleLightningChannels: channel %s: %v", snapshot.ChannelID, err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to get channels")
+			return
+		}
+
+		switch status {
+		case "":
+		case "unbalanced":
+			if channel.Status == liquidity.StateBalanced {
+				continue
+			}
+		default:
+			if channel.Status != status {
+				continue
+			}
+		}
+		channels = append(channels, channel)
+	}
+
+	if status == liquidity.StateDepleted {
+		sort.SliceStable(channels, func(i, j int) bool {
+			a, b := channels[i].Refill, channels[j].Refill
+			if a == nil || b == nil {
+				return b == nil && a != nil
+			}
+			if a.Priority != b.Priority {
+				return a.Priority > b.Priority
+			}
+			return a.Amount > b.Amount
+		})
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: channels})
+}
+
+// handleLightningChannel handles GET /api/lightning/channels/{id}.
+// Returns one channel of the latest snapshot as listed by
+// /api/lightning/channels, or 404 when it is not open.
+func (s *Server) handleLightningChannel(w http.ResponseWriter, r *http.Request) {
+	channelID, fieldErr := parseChannelID(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	snapshots, err := s.db.GetLatestChannelSnapshots()
+	if err != nil {
+		log.Printf("handleLightningChannel: failed to get channel snapshots: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel")
+		return
+	}
+	index := slices.IndexFunc(snapshots, func(snapshot db.ChannelSnapshot) bool { return snapshot.ChannelID == channelID })
+	if index < 0 {
+		s.writeError(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+
+	lease, err := s.db.GetChannelLease(channelID)
+	if err != nil {
+		log.Printf("handleLightningChannel: failed to get channel lease: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel")
+		return
+	}
+	var height int64
+	if lease != nil {
+		height = s.currentBlockHeight()
+	}
+
+	channel, err := s.channelInfo(snapshots[index], lease, height, isAdminRequest(r))
+	if err != nil {
+		log.Printf("handleLightningChannel: channel %s: %v", channelID, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel")
+		return
+	}
+	s.writeJSON(w, APIResponse{Success: true, Data: channel})
+}
+
+// channelInfo adds liquidity state, routing activity, health and, with
+// withNote, the notes about its peer to a channel's snapshot. lease is nil for
+// channels that are not leased.
+func (s *Server) channelInfo(snapshot db.ChannelSnapshot, lease *db.ChannelLease, height int64, withNote bool) (ChannelInfo, error) {
+	thresholds := s.liquidity.For(snapshot.ChannelID)
+	ratio := liquidity.LocalRatio(snapshot.LocalBalance, snapshot.Capacity)
+	channel := ChannelInfo{
+		ChannelSnapshot: snapshot,
+		LocalRatio:      ratio,
+		Status:          liquidity.Classify(ratio, thresholds, ""),
+		Thresholds:      thresholds,
+	}
+
+	var fees30d db.ChannelFeeStats
+	for _, days := range channelActivityDays {
+		fees, err := s.db.GetChannelFeeStats(snapshot.ChannelID, snapshot.Timestamp.AddDate(0, 0, -days), snapshot.Timestamp)
+		if err != nil {
+			return ChannelInfo{}, fmt.Errorf("failed to get fee stats: %w", err)
+		}
+		channel.Forwarding = append(channel.Forwarding, ChannelForwarding{
+			Days: days, ForwardCount: fees.ForwardCount, Volume: fees.Volume, Fees: fees.Fees,
+		})
+		if days == 30 {
+			fees30d = *fees
+		}
+	}
+	last, err := s.db.GetChannelLastForward(snapshot.ChannelID, snapshot.Timestamp)
+	if err != nil {
+		return ChannelInfo{}, fmt.Errorf("failed to get last forward: %w", err)
+	}
+	if last != nil {
+		days := int(snapshot.Timestamp.Sub(*last).Hours() / 24)
+		channel.LastForward = last
+		channel.DaysSinceLastForward = &days
+	}
+	channel.Health = liquidity.HealthOf(snapshot, fees30d, last)
+
+	if lease != nil {
+		info, err := s.leaseInfo(*lease, snapshot.Capacity, height)
+		if err != nil {
+			return ChannelInfo{}, fmt.Errorf("failed to get lease metrics: %w", err)
+		}
+		channel.Lease = info
+	}
+	if channel.Status == liquidity.StateDepleted {
+		now := time.Now()
+		fees, err := s.db.GetChannelFeeStats(snapshot.ChannelID, now.AddDate(0, 0, -liquidity.RefillLookbackDays), now)
+		if err != nil {
+			return ChannelInfo{}, fmt.Errorf("failed to get fee stats: %w", err)
+		}
+		channel.Refill = liquidity.RefillFor(snapshot.LocalBalance, snapshot.Capacity, *fees, liquidity.RefillLookbackDays)
+	}
+
+	name, err := s.db.GetChannelName(snapshot.ChannelID)
+	if err != nil {
+		return ChannelInfo{}, fmt.Errorf("failed to get channel name: %w", err)
+	}
+	if name != nil {
+		channel.Name = name.Name
+	}
+
+	channel.PeerPubkey, err = s.db.GetChannelPeer(snapshot.ChannelID)
+	if err != nil {
+		return ChannelInfo{}, fmt.Errorf("failed to get channel peer: %w", err)
+	}
+	if withNote && channel.PeerPubkey != "" {
+		channel.PeerNote, err = s.db.GetPeerNote(channel.PeerPubkey)
+		if err != nil {
+			return ChannelInfo{}, fmt.Errorf("failed to get peer note: %w", err)
+		}
+	}
+	return channel, nil
+}
+
+// defaultMissionControlAmount is the amount success probabilities are estimated for
+const defaultMissionControlAmount = 100000
+
+// MissionControlPairInfo is a stored mission control pair with its estimated
+// success probability for the requested amount
+type MissionControlPairInfo struct {
+	db.MissionControlPair
+	SuccessProbability float64 `json:"success_probability"`
+}
+
+// handleMissionControl handles GET /api/lightning/mission-control.
+// Returns the latest mission control snapshot. Optional query parameters:
+// node (pubkey; only pairs from or to it) and amount_sat (default 100000).
+func (s *Server) handleMissionControl(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	node := query.Get("node")
+
+	amountSat, fieldErr := parseIntParam("amount_sat", query.Get("amount_sat"), defaultMissionControlAmount, 1, 100000000)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	pairs, err := s.db.GetLatestMissionControlPairs(node)
+	if err != nil {
+		log.Printf("handleMissionControl: failed to get mission control pairs: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get mission control data")
+		return
+	}
+
+	now := time.Now()
+	amountMsat := int64(amountSat) * 1000
+	infos := []MissionControlPairInfo{}
+	var snapshotTime *time.Time
+	for _, pair := range pairs {
+		history := lnd.PairHistory{
+			SuccessTime:    pair.SuccessTime,
+			SuccessAmtMsat: pair.SuccessAmtMsat,
+			FailTime:       pair.FailTime,
+			FailAmtMsat:    pair.FailAmtMsat,
+		}
+		infos = append(infos, MissionControlPairInfo{
+			MissionControlPair: pair,
+			SuccessProbability: history.SuccessProbability(amountMsat, now),
+		})
+		snapshotTime = &pair.Timestamp
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"snapshot_time": snapshotTime,
+			"amount_sat":    amountSat,
+			"pairs":         infos,
+		},
+	})
+}
+
+// handleReliability handles GET /api/lightning/reliability. Reports the
+// share of probe payments that reached their destination and how long they
+// took, overall, per destination and as a chart. Points are hourly for
+// ranges of up to two days and daily beyond.
+func (s *Server) handleReliability(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+	theme := themeFrom(r)
+
+	overall, destinations, err := s.db.GetProbeStats(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleReliability: failed to get probe stats: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get payment reliability")
+		return
+	}
+	results, err := s.db.GetProbeResults(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleReliability: failed to get probe results: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get payment reliability")
+		return
+	}
+
+	bucketFormat := "2006-01-02"
+	if tr.Days <= 2 {
+		bucketFormat = "2006-01-02 15:00"
+	}
+	type bucket struct{ probes, successes, latency int64 }
+	labels := []string{}
+	var buckets []*bucket
+	for _, result := range results {
+		label := result.Timestamp.Format(bucketFormat)
+		if len(labels) == 0 || labels[len(labels)-1] != label {
+			labels = append(labels, label)
+			buckets = append(buckets, &bucket{})
+		}
+		current := buckets[len(buckets)-1]
+		current.probes++
+		if result.Success {
+			current.successes++
+			current.latency += result.LatencyMs
+		}
+	}
+
+	rateData := make([]float64, 0, len(buckets))
+	latencyData := make([]int64, 0, len(buckets))
+	for _, bucket := range buckets {
+		rateData = append(rateData, math.Round(float64(bucket.successes)/float64(bucket.probes)*1000)/10)
+		var latency int64
+		if bucket.successes > 0 {
+			latency = bucket.latency / bucket.successes
+		}
+		latencyData = append(latencyData, latency)
+	}
+	// The hints describe the success rate; latencies are whole milliseconds
+	metadata := map[string]interface{}{
+		"days_requested": tr.Days,
+		"points":         len(labels),
+	}
+	addFormatHints(metadata, r, "%", 1)
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"overall":      overall,
+			"destinations": destinations,
+			// Format data for Chart.js consumption
+			"chart": map[string]interface{}{
+				"labels": labels,
+				"datasets": []map[string]interface{}{
+					{
+						"label":           "Success rate (%)",
+						"data":            rateData,
+						"backgroundColor": theme.Fill(ColorPrimary),
+						"borderColor":     theme.Border(ColorPrimary),
+						"borderWidth":     2,
+						"fill":            false,
+					},
+					{
+						"label":           "Average latency (ms)",
+						"data":            latencyData,
+						"backgroundColor": theme.Fill(ColorTertiary),
+						"borderColor":     theme.Border(ColorTertiary),
+						"borderWidth":     2,
+						"fill":            false,
+					},
+				},
+			},
+			"metadata": metadata,
+		},
+	})
+}
+
+// maxSwapAmount caps swap quote requests at 100 BTC
+const maxSwapAmount = 10000000000
+
+// handleSwapQuotes handles GET /api/swaps/quotes. Compares Loop and Boltz
+// quotes for a swap given by direction (out or in) and amount, or for the
+// swap that brings channel_id back to half local balance.
+func (s *Server) handleSwapQuotes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	direction := query.Get("direction")
+	var amount int64
+
+	if channelID := query.Get("channel_id"); channelID != "" {
+		if !channelIDPattern.MatchString(channelID) {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "channel_id", Message: "Invalid channel ID"})
+			return
+		}
+		snapshots, err := s.db.GetLatestChannelSnapshots()
+		if err != nil {
+			log.Printf("handleSwapQuotes: failed to get channel snapshots: %v", err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to get channel balance")
+			return
+		}
+		var channel *db.ChannelSnapshot
+		for i := range snapshots {
+			if snapshots[i].ChannelID == channelID {
+				channel = &snapshots[i]
+				break
+			}
+		}
+		if channel == nil {
+			s.writeError(w, http.StatusNotFound, "Channel not found")
+			return
+		}
+		direction, amount, err = swap.ShiftForChannel(channel.LocalBalance, channel.Capacity)
+		if err != nil {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeConflict, Field: "channel_id", Message: "Channel is already balanced"})
+			return
+		}
+	} else {
+		if direction == "" {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "direction", Message: "direction or channel_id is required"})
+			return
+		}
+		if fieldErr := validateEnum("direction", direction, swap.Directions); fieldErr != nil {
+			s.writeValidationError(w, fieldErr)
+			return
+		}
+		n, fieldErr := parseIntParam("amount", query.Get("amount"), 0, 1, maxSwapAmount)
+		if fieldErr == nil && n == 0 {
+			fieldErr = &FieldError{Code: ErrCodeRequired, Field: "amount", Message: "amount is required"}
+		}
+		if fieldErr != nil {
+			s.writeValidationError(w, fieldErr)
+			return
+		}
+		amount = int64(n)
+	}
+
+	if len(s.swapProviders) == 0 {
+		s.writeError(w, http.StatusServiceUnavailable, "No swap providers configured")
+		return
+	}
+
+	comparison, err := swap.Compare(s.swapProviders, direction, amount)
+	if err != nil {
+		log.Printf("handleSwapQuotes: failed to compare swap quotes: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to compare swap quotes")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: comparison})
+}
+
+// LeaseInfo is a channel lease with its expiry countdown and returns
+type LeaseInfo struct {
+	db.ChannelLease
+	liquidity.LeaseMetrics
+}
+
+// currentBlockHeight returns the chain height, or 0 when it is not available
+func (s *Server) currentBlockHeight() int64 {
+	if s.blockHeight == nil {
+		return 0
+	}
+	height, err := s.blockHeight()
+	if err != nil {
+		log.Printf("Warning: failed to get block height, lease countdowns left out: %v", err)
+		return 0
+	}
+	return height
+}
+
+// leaseInfo computes the metrics of a lease on a channel of the given
+// capacity. Routing fees are counted from the start of the lease, or over
+// the channel's whole history when the start is not recorded.
+func (s *Server) leaseInfo(lease db.ChannelLease, capacity, height int64) (*LeaseInfo, error) {
+	now := time.Now()
+	var from time.Time
+	if lease.StartedAt != nil {
+		from = *lease.StartedAt
+	}
+	fees, err := s.db.GetChannelFeeStats(lease.ChannelID, from, now)
+	if err != nil {
+		return nil, err
+	}
+	return &LeaseInfo{
+		ChannelLease: lease,
+		LeaseMetrics: liquidity.Lease(lease, capacity, *fees, height, now),
+	}, nil
+}
+
+// handleChannelLeases handles GET /api/lightning/leases. Lists every lease,
+// soonest expiry first, including leases of channels that have closed.
+func (s *Server) handleChannelLeases(w http.ResponseWriter, r *http.Request) {
+	leases, err := s.db.GetChannelLeases()
+	if err != nil {
+		log.Printf("handleChannelLeases: failed to get channel leases: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel leases")
+		return
+	}
+	snapshots, err := s.db.GetLatestChannelSnapshots()
+	if err != nil {
+		log.Printf("handleChannelLeases: failed to get channel snapshots: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel leases")
+		return
+	}
+	capacities := make(map[string]int64, len(snapshots))
+	for _, snapshot := range snapshots {
+		capacities[snapshot.ChannelID] = snapshot.Capacity
+	}
+
+	height := s.currentBlockHeight()
+	infos := []LeaseInfo{}
+	var premiumPaid, premiumEarned, feesEarned, netReturn int64
+	for _, lease := range leases {
+		info, err := s.leaseInfo(lease, capacities[lease.ChannelID], height)
+		if err != nil {
+			log.Printf("handleChannelLeases: failed to get lease metrics for channel %s: %v", lease.ChannelID, err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to get channel leases")
+			return
+		}
+		if lease.Side == db.LeaseSideSold {
+			premiumEarned += lease.Premium
+		} else {
+			premiumPaid += lease.Premium
+		}
+		feesEarned += info.FeesEarned
+		netReturn += info.NetReturn
+		infos = append(infos, *info)
+	}
+
+	var blockHeight *int64
+	if height > 0 {
+		blockHeight = &height
+	}
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"block_height": blockHeight,
+			"leases":       infos,
+			"totals": map[string]int64{
+				"premium_paid":   premiumPaid,
+				"premium_earned": premiumEarned,
+				"fees_earned":    feesEarned,
+				"net_return":     netReturn,
+			},
+		},
+	})
+}
+
+// handleGetChannelLease handles GET /api/lightning/channels/{id}/lease
+func (s *Server) handleGetChannelLease(w http.ResponseWriter, r *http.Request) {
+	channelID, fieldErr := parseChannelID(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	lease, err := s.db.GetChannelLease(channelID)
+	if err != nil {
+		log.Printf("handleGetChannelLease: failed to get channel lease: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel lease")
+		return
+	}
+	if lease == nil {
+		s.writeError(w, http.StatusNotFound, "Channel lease not found")
+		return
+	}
+
+	var capacity int64
+	if snapshots, err := s.db.GetChannelSnapshots(channelID, time.Time{}, time.Now()); err != nil {
+		log.Printf("handleGetChannelLease: failed to get channel snapshots: %v", err)
+	} else if len(snapshots) > 0 {
+		capacity = snapshots[len(snapshots)-1].Capacity
+	}
+
+	info, err := s.leaseInfo(*lease, capacity, s.currentBlockHeight())
+	if err != nil {
+		log.Printf("handleGetChannelLease: failed to get lease metrics: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel lease")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: info})
+}
+
+// SetChannelLeaseRequest represents the request body for recording or
+// editing a channel lease. Omitted fields keep their current value; side is
+// required for a new lease.
+type SetChannelLeaseRequest struct {
+	Side           *string `json:"side"`
+	Provider       *string `json:"provider"`
+	Premium        *int64  `json:"premium"`
+	DurationBlocks *int64  `json:"duration_blocks"`
+	ExpiryHeight   *int64  `json:"expiry_height"`
+	// StartedAt is a YYYY-MM-DD date; an empty string clears it
+	StartedAt *string `json:"started_at"`
+	Notes     *string `json:"notes"`
+}
+
+// handleSetChannelLease handles PUT /api/lightning/channels/{id}/lease,
+// creating the lease if the channel has none
+func (s *Server) handleSetChannelLease(w http.ResponseWriter, r *http.Request) {
+	channelID, fieldErr := parseChannelID(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	var req SetChannelLeaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+
+	existing, err := s.db.GetChannelLease(channelID)
+	if err != nil {
+		log.Printf("handleSetChannelLease: failed to get channel lease: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to check channel lease")
+		return
+	}
+
+	lease := db.ChannelLease{ChannelID: channelID}
+	if existing != nil {
+		lease = *existing
+	} else if req.Side == nil {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "side", Message: "side is required for a new lease"})
+		return
+	}
+	if req.Side != nil {
+		if !db.IsValidLeaseSide(*req.Side) {
+			s.writeValidationError(w, invalidEnum("side", db.LeaseSides))
+			return
+		}
+		lease.Side = *req.Side
+	}
+	if req.Provider != nil {
+		lease.Provider = strings.TrimSpace(*req.Provider)
+	}
+	for _, field := range []struct {
+		name  string
+		value *int64
+		dest  *int64
+	}{
+		{"premium", req.Premium, &lease.Premium},
+		{"duration_blocks", req.DurationBlocks, &lease.DurationBlocks},
+		{"expiry_height", req.ExpiryHeight, &lease.ExpiryHeight},
+	} {
+		if field.value == nil {
+			continue
+		}
+		if *field.value < 0 {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeOutOfRange, Field: field.name, Message: field.name + " must not be negative"})
+			return
+		}
+		*field.dest = *field.value
+	}
+	if req.StartedAt != nil {
+		lease.StartedAt = nil
+		if *req.StartedAt != "" {
+			startedAt, fieldErr := parseDate("started_at", *req.StartedAt, time.UTC)
+			if fieldErr == nil {
+				fieldErr = checkDateBounds("started_at", startedAt, time.Now())
+			}
+			if fieldErr != nil {
+				s.writeValidationError(w, fieldErr)
+				return
+			}
+			lease.StartedAt = &startedAt
+		}
+	}
+	if req.Notes != nil {
+		lease.Notes = strings.TrimSpace(*req.Notes)
+	}
+
+	updated, err := s.db.SetChannelLease(lease)
+	if err != nil {
+		log.Printf("handleSetChannelLease: failed to set channel lease: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to save channel lease")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: updated})
+}
+
+// handleDeleteChannelLease handles DELETE /api/lightning/channels/{id}/lease
+func (s *Server) handleDeleteChannelLease(w http.ResponseWriter, r *http.Request) {
+	channelID, fieldErr := parseChannelID(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	err := s.db.DeleteChannelLease(channelID)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Channel lease not found")
+		return
+	}
+	if err != nil {
+		log.Printf("handleDeleteChannelLease: failed to delete channel lease: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to delete channel lease")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"message":    "Channel lease deleted successfully",
+			"channel_id": channelID,
+		},
+	})
+}
+
+// SetPeerPolicyRequest represents the request body for creating or editing a
+// peer policy. Omitted fields keep their current value, or false/empty for a new policy.
+type SetPeerPolicyRequest struct {
+	Blocklisted *bool   `json:"blocklisted"`
+	Preferred   *bool   `json:"preferred"`
+	Notes       *string `json:"notes"`
+}
+
+// handleGetPeerPolicies handles GET /api/peers/policies
+func (s *Server) handleGetPeerPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := s.db.GetPeerPolicies()
+	if err != nil {
+		log.Printf("handleGetPeerPolicies: failed to get peer policies: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get peer policies")
+		return
+	}
+	if policies == nil {
+		policies = []db.PeerPolicy{}
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: policies})
+}
+
+// handleGetPeerPolicy handles GET /api/peers/policies/{pubkey}
+func (s *Server) handleGetPeerPolicy(w http.ResponseWriter, r *http.Request) {
+	pubkey, fieldErr := parsePubkey(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	policy, err := s.db.GetPeerPolicy(pubkey)
+	if err != nil {
+		log.Printf("handleGetPeerPolicy: failed to get peer policy: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get peer policy")
+		return
+	}
+	if policy == nil {
+		s.writeError(w, http.StatusNotFound, "Peer policy not found")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: policy})
+}
+
+// handleSetPeerPolicy handles PUT /api/peers/policies/{pubkey}, creating the
+// policy if the peer has none
+func (s *Server) handleSetPeerPolicy(w http.ResponseWriter, r *http.Request) {
+	pubkey, fieldErr := parsePubkey(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	var req SetPeerPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+
+	existing, err := s.db.GetPeerPolicy(pubkey)
+	if err != nil {
+		log.Printf("handleSetPeerPolicy: failed to get peer policy: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to check peer policy")
+		return
+	}
+
+	policy := db.PeerPolicy{Pubkey: pubkey}
+	if existing != nil {
+		policy = *existing
+	}
+	if req.Blocklisted != nil {
+		policy.Blocklisted = *req.Blocklisted
+	}
+	if req.Preferred != nil {
+		policy.Preferred = *req.Preferred
+	}
+	if req.Notes != nil {
+		policy.Notes = strings.TrimSpace(*req.Notes)
+	}
+
+	if policy.Blocklisted && policy.Preferred {
+		s.writeValidationError(w, &FieldError{
+			Code:    ErrCodeConflict,
+			Field:   "preferred",
+			Message: "A peer cannot be both blocklisted and preferred",
+		})
+		return
+	}
+
+	updated, err := s.db.SetPeerPolicy(policy)
+	if err != nil {
+		log.Printf("handleSetPeerPolicy: failed to set peer policy: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to save peer policy")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: updated})
+}
+
+// handleDeletePeerPolicy handles DELETE /api/peers/policies/{pubkey}
+func (s *Server) handleDeletePeerPolicy(w http.ResponseWriter, r *http.Request) {
+	pubkey, fieldErr := parsePubkey(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	err := s.db.DeletePeerPolicy(pubkey)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Peer policy not found")
+		return
+	}
+	if err != nil {
+		log.Printf("handleDeletePeerPolicy: failed to delete peer policy: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to delete peer policy")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"message": "Peer policy deleted successfully",
+			"pubkey":  pubkey,
+		},
+	})
+}
+
+// SetPeerNoteRequest represents the request body for creating or editing the
+// notes about a peer. Omitted fields keep their current value, or empty for new notes.
+type SetPeerNoteRequest struct {
+	Contact   *string `json:"contact"`
+	Agreement *string `json:"agreement"`
+	AgreedOn  *string `json:"agreed_on"`
+	Notes     *string `json:"notes"`
+}
+
+// handleGetPeerNotes handles GET /api/peers/notes
+func (s *Server) handleGetPeerNotes(w http.ResponseWriter, r *http.Request) {
+	notes, err := s.db.GetPeerNotes()
+	if err != nil {
+		log.Printf("handleGetPeerNotes: failed to get peer notes: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get peer notes")
+		return
+	}
+	if notes == nil {
+		notes = []db.PeerNote{}
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: notes})
+}
+
+// handleGetPeerNote handles GET /api/peers/notes/{pubkey}
+func (s *Server) handleGetPeerNote(w http.ResponseWriter, r *http.Request) {
+	pubkey, fieldErr := parsePubkey(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	note, err := s.db.GetPeerNote(pubkey)
+	if err != nil {
+		log.Printf("handleGetPeerNote: failed to get peer note: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get peer note")
+		return
+	}
+	if note == nil {
+		s.writeError(w, http.StatusNotFound, "Peer note not found")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: note})
+}
+
+// handleSetPeerNote handles PUT /api/peers/notes/{pubkey}, creating the
+// notes if the peer has none
+func (s *Server) handleSetPeerNote(w http.ResponseWriter, r *http.Request) {
+	pubkey, fieldErr := parsePubkey(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	var req SetPeerNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+
+	existing, err := s.db.GetPeerNote(pubkey)
+	if err != nil {
+		log.Printf("handleSetPeerNote: failed to get peer note: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to check peer note")
+		return
+	}
+
+	note := db.PeerNote{Pubkey: pubkey}
+	if existing != nil {
+		note = *existing
+	}
+	if req.Contact != nil {
+		note.Contact = strings.TrimSpace(*req.Contact)
+	}
+	if req.Agreement != nil {
+		note.Agreement = strings.TrimSpace(*req.Agreement)
+	}
+	if req.AgreedOn != nil {
+		note.AgreedOn = strings.TrimSpace(*req.AgreedOn)
+	}
+	if req.Notes != nil {
+		note.Notes = strings.TrimSpace(*req.Notes)
+	}
+
+	if note.AgreedOn != "" {
+		if _, err := time.Parse(DateLayout, note.AgreedOn); err != nil {
+			s.writeValidationError(w, &FieldError{
+				Code:    ErrCodeInvalid,
+				Field:   "agreed_on",
+				Message: "Invalid agreed_on. Must be a date in YYYY-MM-DD format",
+			})
+			return
+		}
+	}
+
+	updated, err := s.db.SetPeerNote(note)
+	if err != nil {
+		log.Printf("handleSetPeerNote: failed to set peer note: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to save peer note")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: updated})
+}
+
+// handleDeletePeerNote handles DELETE /api/peers/notes/{pubkey}
+func (s *Server) handleDeletePeerNote(w http.ResponseWriter, r *http.Request) {
+	pubkey, fieldErr := parsePubkey(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	err := s.db.DeletePeerNote(pubkey)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Peer note not found")
+		return
+	}
+	if err != nil {
+		log.Printf("handleDeletePeerNote: failed to delete peer note: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to delete peer note")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"message": "Peer note deleted successfully",
+			"pubkey":  pubkey,
+		},
+	})
+}
+
+// handleChannelBalanceHistory handles GET /api/lightning/channels/{id}/balance-history.
+// Ranges up to a week are charted hourly, longer ones daily; each point is the
+// last snapshot of its hour or day.
+func (s *Server) handleChannelBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	channelID, fieldErr := parseChannelID(r)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	tr := timeRangeFrom(r)
+
+	snapshots, err := s.db.GetChannelSnapshots(channelID, tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleChannelBalanceHistory: failed to get snapshots for channel %s: %v", channelID, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get channel balance history")
+		return
+	}
+
+	layout := "2006-01-02"
+	if tr.Days <= 7 {
+		layout = "2006-01-02 15:00"
+	}
+
+	labels := make([]string, 0, len(snapshots))
+	local := make([]int64, 0, len(snapshots))
+	remote := make([]int64, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		label := snapshot.Timestamp.Local().Format(layout)
+		if n := len(labels); n > 0 && labels[n-1] == label {
+			local[n-1] = snapshot.LocalBalance
+			remote[n-1] = snapshot.RemoteBalance
+			continue
+		}
+		labels = append(labels, label)
+		local = append(local, snapshot.LocalBalance)
+		remote = append(remote, snapshot.RemoteBalance)
+	}
+
+	metadata := map[string]interface{}{
+		"channel_id":     channelID,
+		"days_requested": tr.Days,
+		"snapshots":      len(snapshots),
+	}
+	if name, err := s.db.GetChannelName(channelID); err != nil {
+		log.Printf("handleChannelBalanceHistory: failed to get channel name: %v", err)
+	} else if name != nil {
+		metadata["name"] = name.Name
+	}
+	if len(snapshots) > 0 {
+		latest := snapshots[len(snapshots)-1]
+		metadata["peer_alias"] = latest.PeerAlias
+		metadata["capacity"] = latest.Capacity
+		metadata["active"] = latest.Active
+		if latest.Capacity > 0 {
+			metadata["local_ratio"] = float64(latest.LocalBalance) / float64(latest.Capacity)
+		}
+	}
+
+	theme := themeFrom(r)
+	// Format data for Chart.js consumption
+	chartData := map[string]interface{}{
+		"labels": labels,
+		"datasets": []map[string]interface{}{
+			{
+				"label":           "Local Balance (sats)",
+				"data":            local,
+				"backgroundColor": theme.Fill(ColorPrimary),
+				"borderColor":     theme.Border(ColorPrimary),
+				"borderWidth":     1,
+			},
+			{
+				"label":           "Remote Balance (sats)",
+				"data":            remote,
+				"backgroundColor": theme.Fill(ColorNegative),
+				"borderColor":     theme.Border(ColorNegative),
+				"borderWidth":     1,
+			},
+		},
+		"metadata": metadata,
+	}
+
+	convertChart(chartData, r, "capacity")
+	s.writeJSON(w, APIResponse{Success: true, Data: chartData})
+}
+
+// handleGetStatements handles GET /api/reports/statements
+func (s *Server) handleGetStatements(w http.ResponseWriter, r *http.Request) {
+	statements, err := s.db.GetStatements()
+	if err != nil {
+		log.Printf("handleGetStatements: failed to get statements: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get statements")
+		return
+	}
+	if statements == nil {
+		statements = []db.Statement{}
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: statements})
+}
+
+var statementFormats = []string{"pdf", "csv"}
+
+// handleStatementReport handles GET /api/reports/statement
+// Optional query parameters: month=YYYY-MM (default last month), format=pdf|csv
+// (default pdf). Months that are not closed yet are rendered as provisional.
+func (s *Server) handleStatementReport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if fieldErr := validateEnum("format", format, statementFormats); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	if format == "" {
+		format = "pdf"
+	}
+
+	now := time.Now()
+	start := statement.PreviousMonth(now)
+	if month := r.URL.Query().Get("month"); month != "" {
+		var err error
+		if start, err = statement.ParseMonth(month); err != nil {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "month", Message: "Invalid month. Must be YYYY-MM"})
+			return
+		}
+		if start.After(now) {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeOutOfRange, Field: "month", Message: "month must not be in the future"})
+			return
+		}
+	}
+
+	report, err := statement.BuildReport(s.db, start)
+	if err != nil {
+		log.Printf("handleStatementReport: failed to build statement for %s: %v", start.Format(statement.MonthLayout), err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to build statement")
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "application/pdf"
+	if format == "csv" {
+		contentType = "text/csv"
+		err = statement.WriteCSV(&buf, report.Statement)
+	} else {
+		err = statement.WritePDF(&buf, report)
+	}
+	if err != nil {
+		log.Printf("handleStatementReport: failed to render statement: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to render statement")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s.%s"`, report.Statement.Month, format))
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("handleStatementReport: failed to write response: %v", err)
+	}
+}
+
+// handleCollectorRuns handles GET /api/system/collector-runs
+// Optional query parameters: collector to filter by name, limit (default 50).
+func (s *Server) handleCollectorRuns(w http.ResponseWriter, r *http.Request) {
+	limit, fieldErr := parseIntParam("limit", r.URL.Query().Get("limit"), DefaultCollectorRuns, 1, MaxCollectorRuns)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	runs, err := s.db.GetCollectorRuns(r.URL.Query().Get("collector"), limit)
+	if err != nil {
+		log.Printf("handleCollectorRuns: failed to get collector runs: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get collector runs")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: runs})
+}
+
+// handleQuarantine handles GET /api/system/quarantine, reporting records
+// rejected for impossible timestamps. Optional query parameters: source to
+// filter by table, limit for the number of records.
+func (s *Server) handleQuarantine(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("source")
+	if fieldErr := validateEnum("source", source, db.QuarantineSources); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	limit, fieldErr := parseIntParam("limit", r.URL.Query().Get("limit"), DefaultQuarantined, 1, MaxQuarantined)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	counts, err := s.db.GetQuarantineCounts()
+	if err != nil {
+		log.Printf("handleQuarantine: failed to count quarantined records: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get quarantined records")
+		return
+	}
+	records, err := s.db.GetQuarantinedRecords(source, limit)
+	if err != nil {
+		log.Printf("handleQuarantine: failed to get quarantined records: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get quarantined records")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"counts":  counts,
+			"records": records,
+		},
+	})
+}
+
+// handleCaptures handles GET /api/system/captures, listing captured raw node
+// responses newest first. Optional query parameters: collector and run_id to
+// see what a collector run was given, limit for the number of responses.
+// The capture directory may be shared with a collector's --capture-dir.
+func (s *Server) handleCaptures(w http.ResponseWriter, r *http.Request) {
+	if s.captureDir == "" {
+		s.writeError(w, http.StatusNotFound, "Debug capture is not enabled, start the API with --capture-dir")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, fieldErr := parseIntParam("limit", query.Get("limit"), DefaultCaptures, 1, MaxCaptures)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	runID, fieldErr := parseIntParam("run_id", query.Get("run_id"), 0, 1, math.MaxInt32)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	filter := capture.Filter{Collector: query.Get("collector"), RunID: int64(runID)}
+	records, err := capture.List(s.captureDir, filter, limit)
+	if err != nil {
+		log.Printf("handleCaptures: failed to list captures: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to list captured responses")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: records})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"status":    "healthy",
+			"timestamp": time.Now(),
+		},
+	})
+}
+
+// handleVersion handles GET /api/version: the commit the API was built from,
+// the last recorded version of each node and which gated features they allow
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	nodes, err := s.db.GetNodeVersions()
+	if err != nil {
+		log.Printf("handleVersion: failed to get node versions: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get node versions")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"version":    version.GitCommit(),
+			"release":    version.Version,
+			"build_date": version.BuildDate,
+			"go_version": runtime.Version(),
+			"nodes":      nodes,
+			"features":   s.nodes.Supported(),
+		},
+	})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSONStatus(w, status, APIResponse{
+		Success: false,
+		Error:   message,
+	})
+}
+
+func (s *Server) writeJSONStatus(w http.ResponseWriter, status int, response APIResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode error response (status %d, message %q): %v", status, response.Error, err)
+	}
+}
+
+// AddOnchainAddressRequest represents the request body for adding a new onchain address
+type AddOnchainAddressRequest struct {
+	// Address is the required Bitcoin on-chain address or extended public key (xpub) to track.
+	// It must be a valid Bitcoin address or xpub string.
+	Address string `json:"address"`
+	// Label is an optional human-readable description for the address; it may be empty.
+	Label string `json:"label"`
+	// BirthHeight is the optional first block that can hold the address's
+	// transactions. History scans and rescans start there.
+	BirthHeight int64 `json:"birth_height"`
+	// BirthDate is the optional creation date, YYYY-MM-DD, for when the height is not known.
+	BirthDate string `json:"birth_date"`
+	// Class is "hot" (the default) or "cold" and sets how many confirmations
+	// the address's funds need to count as confirmed.
+	Class string `json:"class"`
+}
+
+// UpdateOnchainAddressRequest represents the request body for editing a tracked onchain address.
+// Omitted fields are left unchanged.
+type UpdateOnchainAddressRequest struct {
+	// Label replaces the human-readable description when set.
+	Label *string `json:"label"`
+	// Active pauses (false) or resumes (true) balance collection without deleting history.
+	Active *bool `json:"active"`
+	// BirthHeight replaces the birth height when set; 0 clears it.
+	BirthHeight *int64 `json:"birth_height"`
+	// BirthDate replaces the birth date when set; an empty string clears it.
+	BirthDate *string `json:"birth_date"`
+	// Class replaces the address class when set.
+	Class *string `json:"class"`
+}
+
+// EnhancedAddressInfo combines database info with real-time balance
+type EnhancedAddressInfo struct {
+	ID               int64      `json:"id"`
+	Address          string     `json:"address"`
+	Label            string     `json:"label"`
+	Active           bool       `json:"active"`
+	BirthHeight      int64      `json:"birth_height,omitempty"`
+	BirthDate        *time.Time `json:"birth_date,omitempty"`
+	Class            string     `json:"class"`
+	MinConfirmations int64      `json:"min_confirmations"` // Required by the class
+	CurrentBalance   int64      `json:"current_balance"`   // Confirmed plus unconfirmed
+	Confirmed        int64      `json:"confirmed_balance"`
+	Unconfirmed      int64      `json:"unconfirmed_balance"` // Below MinConfirmations
+	TxCount          int64      `json:"tx_count"`
+	LastUpdated      time.Time  `json:"last_updated"`
+	Source           string     `json:"source"` // "cache", "bitcoin-core", or "error"
+	Error            string     `json:"error,omitempty"`
+}
 
 // handleGetOnchainAddresses handles GET /api/onchain/addresses
 func (s *Server) handleGetOnchainAddresses(w http.ResponseWriter, r *http.Request) {
@@ -522,14 +2588,19 @@ func (s *Server) handleGetOnchainAddresses(w http.ResponseWriter, r *http.Reques
 		var mockAddresses []EnhancedAddressInfo
 		for _, addr := range addresses {
 			mockAddresses = append(mockAddresses, EnhancedAddressInfo{
-				ID:             addr.ID,
-				Address:        addr.Address,
-				Label:          addr.Label,
-				Active:         addr.Active,
-				CurrentBalance: 100000 + int64(addr.ID)*10000, // Mock balance
-				TxCount:        5,
-				LastUpdated:    time.Now(),
-				Source:         "mock",
+				ID:               addr.ID,
+				Address:          addr.Address,
+				Label:            addr.Label,
+				Active:           addr.Active,
+				BirthHeight:      addr.BirthHeight,
+				BirthDate:        addr.BirthDate,
+				Class:            addr.Class,
+				MinConfirmations: s.confirmations.MinConfirmations(addr.Class),
+				CurrentBalance:   100000 + int64(addr.ID)*10000, // Mock balance
+				Confirmed:        100000 + int64(addr.ID)*10000,
+				TxCount:          5,
+				LastUpdated:      time.Now(),
+				Source:           "mock",
 			})
 		}
 		s.writeJSON(w, APIResponse{Success: true, Data: mockAddresses})
@@ -540,24 +2611,30 @@ func (s *Server) handleGetOnchainAddresses(w http.ResponseWriter, r *http.Reques
 	var enhancedAddresses []EnhancedAddressInfo
 	for _, addr := range addresses {
 		enhanced := EnhancedAddressInfo{
-			ID:      addr.ID,
-			Address: addr.Address,
-			Label:   addr.Label,
-			Active:  addr.Active,
-			Source:  "error",
+			ID:               addr.ID,
+			Address:          addr.Address,
+			Label:            addr.Label,
+			Active:           addr.Active,
+			BirthHeight:      addr.BirthHeight,
+			BirthDate:        addr.BirthDate,
+			Class:            addr.Class,
+			MinConfirmations: s.confirmations.MinConfirmations(addr.Class),
+			Source:           "error",
 		}
 
 		// Get real-time balance if real-time service is available and address is active
 		if s.realtimeService != nil && addr.Active {
-			result, err := s.realtimeService.GetAddressBalance(addr.Address)
+			result, err := s.realtimeService.GetAddressBalance(addr.Address, addr.Class)
 			if err != nil {
-				log.Printf("⚠️  Failed to get balance for %s: %v", addr.Address, err)
+				log.Printf("⚠️  Failed to get balance for %s: %v", redact.Address(addr.Address), err)
 				enhanced.Error = err.Error()
 				enhanced.CurrentBalance = 0
 				enhanced.TxCount = 0
 				enhanced.LastUpdated = time.Now()
 			} else {
 				enhanced.CurrentBalance = result.Balance
+				enhanced.Confirmed = result.Confirmed
+				enhanced.Unconfirmed = result.Unconfirmed
 				enhanced.TxCount = result.TxCount
 				enhanced.LastUpdated = result.LastUpdated
 				enhanced.Source = result.Source
@@ -595,55 +2672,163 @@ func (s *Server) handleAddOnchainAddress(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if fieldErr := validateEnum("class", req.Class, db.AddressClasses); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	birthDate, fieldErr := parseBirthday(req.BirthHeight, req.BirthDate, time.Now())
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	birthday := bitcoin.Birthday{Height: req.BirthHeight}
+	if birthDate != nil {
+		birthday.Time = *birthDate
+	}
+
+	// A deleted address keeps its row, and with it the address
+	deletedID, err := s.db.GetDeletedOnchainAddressID(req.Address)
+	if err != nil {
+		log.Printf("handleAddOnchainAddress: failed to look up deleted addresses: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to add address")
+		return
+	}
+	if deletedID != 0 {
+		s.writeError(w, http.StatusConflict, fmt.Sprintf(
+			"Address was deleted, restore it with POST %s/onchain/addresses/%d/restore", APIPrefix, deletedID))
+		return
+	}
+
+	var address *db.OnchainAddress
 	// If Bitcoin balance service is available, use it to import and track the address
 	if s.balanceService != nil {
-		newAddress, err := s.balanceService.ImportAndTrackAddress(req.Address, req.Label)
+		address, err = s.balanceService.ImportAndTrackAddress(req.Address, req.Label, birthday)
+	} else {
+		// Fallback to basic database insertion if no balance service
+		address, err = s.db.InsertOnchainAddress(req.Address, req.Label)
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			s.writeError(w, http.StatusConflict, "Address is already being tracked")
+			return
+		}
+		log.Printf("handleAddOnchainAddress: failed to add address: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to add address")
+		return
+	}
+
+	if !birthday.IsZero() {
+		address, err = s.db.SetOnchainAddressBirthday(address.ID, req.BirthHeight, birthDate)
 		if err != nil {
-			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-				s.writeError(w, http.StatusConflict, "Address is already being tracked")
-				return
-			}
-			log.Printf("handleAddOnchainAddress: failed to import address via balance service: %v", err)
-			s.writeError(w, http.StatusInternalServerError, "Failed to add address")
+			log.Printf("handleAddOnchainAddress: failed to set birthday: %v", err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to set address birthday")
 			return
 		}
+	}
 
-		s.writeJSON(w, APIResponse{
-			Success: true,
-			Data:    newAddress,
-		})
-	} else {
-		// Fallback to basic database insertion if no balance service
-		address, err := s.db.InsertOnchainAddress(req.Address, req.Label)
+	if req.Class != "" && req.Class != address.Class {
+		address, err = s.db.SetOnchainAddressClass(address.ID, req.Class)
 		if err != nil {
-			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-				s.writeError(w, http.StatusConflict, "Address is already being tracked")
-				return
-			}
-			log.Printf("handleAddOnchainAddress: failed to insert address: %v", err)
-			s.writeError(w, http.StatusInternalServerError, "Failed to add address")
+			log.Printf("handleAddOnchainAddress: failed to set class: %v", err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to set address class")
+			return
+		}
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data:    address,
+	})
+}
+
+// handleUpdateOnchainAddress handles PUT /api/onchain/addresses/{id}
+func (s *Server) handleUpdateOnchainAddress(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "address")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	var req UpdateOnchainAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+
+	if req.Label == nil && req.Active == nil && req.BirthHeight == nil && req.BirthDate == nil && req.Class == nil {
+		s.writeError(w, http.StatusBadRequest, "At least one of label, active, birth_height, birth_date or class is required")
+		return
+	}
+	// Unlike on add, an empty class is not a default here
+	if req.Class != nil && !db.IsValidAddressClass(*req.Class) {
+		s.writeValidationError(w, invalidEnum("class", db.AddressClasses))
+		return
+	}
+
+	// Check if address exists
+	address, err := s.db.GetOnchainAddressByID(id)
+	if err != nil {
+		log.Printf("handleUpdateOnchainAddress: failed to get address by ID: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to check address")
+		return
+	}
+	if address == nil {
+		s.writeError(w, http.StatusNotFound, "Address not found")
+		return
+	}
+
+	birthHeight, birthDate := address.BirthHeight, address.BirthDate
+	if req.BirthHeight != nil || req.BirthDate != nil {
+		if req.BirthHeight != nil {
+			birthHeight = *req.BirthHeight
+		}
+		date := ""
+		if req.BirthDate != nil {
+			date = *req.BirthDate
+		} else if birthDate != nil {
+			date = birthDate.Format(DateLayout)
+		}
+		var fieldErr *FieldError
+		if birthDate, fieldErr = parseBirthday(birthHeight, date, time.Now()); fieldErr != nil {
+			s.writeValidationError(w, fieldErr)
 			return
 		}
+	}
+
+	label := address.Label
+	if req.Label != nil {
+		label = *req.Label
+	}
+	active := address.Active
+	if req.Active != nil {
+		active = *req.Active
+	}
 
-		s.writeJSON(w, APIResponse{
-			Success: true,
-			Data:    address,
-		})
+	updated, err := s.db.UpdateOnchainAddress(id, label, active)
+	if err == nil && (req.BirthHeight != nil || req.BirthDate != nil) {
+		updated, err = s.db.SetOnchainAddressBirthday(id, birthHeight, birthDate)
+	}
+	if err == nil && req.Class != nil {
+		updated, err = s.db.SetOnchainAddressClass(id, *req.Class)
+	}
+	if err != nil {
+		log.Printf("handleUpdateOnchainAddress: failed to update address: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to update address")
+		return
 	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data:    updated,
+	})
 }
 
 // handleDeleteOnchainAddress handles DELETE /api/onchain/addresses/:id
 func (s *Server) handleDeleteOnchainAddress(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr, ok := vars["id"]
-	if !ok {
-		s.writeError(w, http.StatusBadRequest, "Address ID is required")
-		return
-	}
-
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		s.writeError(w, http.StatusBadRequest, "Invalid address ID")
+	id, fieldErr := parsePathID(r, "address")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
 		return
 	}
 
@@ -676,6 +2861,40 @@ func (s *Server) handleDeleteOnchainAddress(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// handleGetDeletedOnchainAddresses handles GET /api/onchain/addresses/deleted
+func (s *Server) handleGetDeletedOnchainAddresses(w http.ResponseWriter, r *http.Request) {
+	addresses, err := s.db.GetDeletedOnchainAddresses()
+	if err != nil {
+		log.Printf("handleGetDeletedOnchainAddresses: failed to get deleted addresses: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get deleted addresses")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: addresses})
+}
+
+// handleRestoreOnchainAddress handles POST /api/onchain/addresses/{id}/restore
+func (s *Server) handleRestoreOnchainAddress(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "address")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	address, err := s.db.RestoreOnchainAddress(id)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Deleted address not found")
+		return
+	}
+	if err != nil {
+		log.Printf("handleRestoreOnchainAddress: failed to restore address: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to restore address")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: address})
+}
+
 // handleOnchainHistory handles GET /api/onchain/history
 func (s *Server) handleOnchainHistory(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
@@ -685,31 +2904,40 @@ func (s *Server) handleOnchainHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	daysStr := r.URL.Query().Get("days")
-	days := 30 // default
-	var from, to time.Time
+	s.writeAddressHistory(w, r, address, 0, bitcoin.Birthday{})
+}
+
+// handleOnchainAddressHistory handles GET /api/onchain/addresses/{id}/history
+func (s *Server) handleOnchainAddressHistory(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "address")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
 
-	if daysStr == "all" {
-		// For "all" data, get from the earliest possible date
-		to = time.Now()
-		genesisDate, _ := time.Parse("2006-01-02", BitcoinGenesisDate)
-		from = genesisDate
-	} else if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= MaxHistoryDays {
-			days = d
-		} else {
-			s.writeError(w, http.StatusBadRequest, "Invalid days parameter. Must be a number between 1 and "+strconv.Itoa(MaxHistoryDays)+", or 'all'")
-			return
-		}
-		// Calculate time range
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	} else {
-		// Default case
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
+	// Only addresses tracked in this database may be queried
+	address, err := s.db.GetOnchainAddressByID(id)
+	if err != nil {
+		log.Printf("handleOnchainAddressHistory: failed to get address by ID: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to check address")
+		return
+	}
+	if address == nil {
+		s.writeError(w, http.StatusNotFound, "Address not found")
+		return
 	}
 
+	s.writeAddressHistory(w, r, address.Address, address.ID, bitcoin.BirthdayOf(*address))
+}
+
+// writeAddressHistory writes the Chart.js formatted balance history for an address.
+// addressID is included in the metadata when the address was resolved from a tracked ID,
+// and the scan starts at birthday when the tracked address has one.
+func (s *Server) writeAddressHistory(w http.ResponseWriter, r *http.Request, address string, addressID int64, birthday bitcoin.Birthday) {
+	tr := timeRangeFrom(r)
+	from, to := tr.From, tr.To
+	theme := themeFrom(r)
+
 	if s.mockMode {
 		// Return mock address history data
 		var mockBalances []bitcoin.AddressBalanceResult
@@ -735,18 +2963,21 @@ func (s *Server) handleOnchainHistory(w http.ResponseWriter, r *http.Request) {
 				{
 					"label":           fmt.Sprintf("Balance for %s", address),
 					"data":            make([]int64, 0, len(mockBalances)),
-					"backgroundColor": "rgba(255, 159, 64, 0.2)",
-					"borderColor":     "rgba(255, 159, 64, 1)",
+					"backgroundColor": theme.Fill(ColorTertiary),
+					"borderColor":     theme.Border(ColorTertiary),
 					"borderWidth":     1,
 				},
 			},
 			"metadata": map[string]interface{}{
 				"address":        address,
-				"days_requested": days,
+				"days_requested": tr.Days,
 				"days_with_data": len(mockBalances),
 				"source":         "mock",
 			},
 		}
+		if addressID > 0 {
+			chartData["metadata"].(map[string]interface{})["address_id"] = addressID
+		}
 
 		// Populate chart data
 		labels := chartData["labels"].([]string)
@@ -761,7 +2992,8 @@ func (s *Server) handleOnchainHistory(w http.ResponseWriter, r *http.Request) {
 		chartData["labels"] = labels
 		chartData["datasets"].([]map[string]interface{})[0]["data"] = data
 
-		s.writeJSON(w, APIResponse{Success: true, Data: chartData})
+		convertChart(chartData, r)
+		s.writeHistory(w, r, chartData)
 		return
 	}
 
@@ -771,9 +3003,9 @@ func (s *Server) handleOnchainHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	balances, err := s.realtimeService.GetAddressHistory(address, from, to)
+	balances, err := s.realtimeService.GetAddressHistory(address, birthday, from, to)
 	if err != nil {
-		log.Printf("handleOnchainHistory: failed to get address history for %s: %v", address, err)
+		log.Printf("writeAddressHistory: failed to get address history for %s: %v", redact.Address(address), err)
 		s.writeError(w, http.StatusInternalServerError, "Failed to scan address transaction history")
 		return
 	}
@@ -785,17 +3017,25 @@ func (s *Server) handleOnchainHistory(w http.ResponseWriter, r *http.Request) {
 			{
 				"label":           fmt.Sprintf("Balance for %s", address),
 				"data":            make([]int64, 0, len(balances)),
-				"backgroundColor": "rgba(255, 159, 64, 0.2)",
-				"borderColor":     "rgba(255, 159, 64, 1)",
+				"backgroundColor": theme.Fill(ColorTertiary),
+				"borderColor":     theme.Border(ColorTertiary),
 				"borderWidth":     1,
 			},
 		},
 		"metadata": map[string]interface{}{
 			"address":        address,
-			"days_requested": days,
+			"days_requested": tr.Days,
 			"days_with_data": len(balances),
+			"source":         "bitcoin-core",
 		},
 	}
+	if len(balances) > 0 {
+		// Pruned nodes report which backends supplied the history
+		chartData["metadata"].(map[string]interface{})["source"] = balances[0].Source
+	}
+	if addressID > 0 {
+		chartData["metadata"].(map[string]interface{})["address_id"] = addressID
+	}
 
 	// Populate chart data
 	labels := chartData["labels"].([]string)
@@ -810,26 +3050,84 @@ func (s *Server) handleOnchainHistory(w http.ResponseWriter, r *http.Request) {
 	chartData["labels"] = labels
 	chartData["datasets"].([]map[string]interface{})[0]["data"] = data
 
-	s.writeJSON(w, APIResponse{Success: true, Data: chartData})
+	convertChart(chartData, r)
+	s.writeHistory(w, r, chartData)
 }
 
 // OfflineAccountRequest represents the request body for offline account operations
 type OfflineAccountRequest struct {
 	Name     string `json:"name"`
-	Balance  int64  `json:"balance"`
+	Balance  int64  `json:"balance"` // Always sats; ignored when Amount is set
 	Notes    string `json:"notes"`
 	Verified bool   `json:"verified"`
+
+	// Amount is an optional decimal string in Unit (e.g. "0.5" with unit "BTC").
+	// It is normalized to sats server-side and takes precedence over Balance.
+	Amount string `json:"amount,omitempty"`
+	// Unit is "sats" (default) or "BTC" and is remembered as the entry's display unit
+	Unit string `json:"unit,omitempty"`
+
+	// Optional custody metadata, only applied when creating an account
+	db.ColdStorageMetadata
+}
+
+// resolveBalance validates the unit and returns the request balance in sats
+func (req *OfflineAccountRequest) resolveBalance() (int64, string, error) {
+	unit, err := utils.NormalizeUnit(req.Unit)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if req.Amount != "" {
+		balance, err := utils.ParseAmountToSats(req.Amount, unit)
+		if err != nil {
+			return 0, "", err
+		}
+		return balance, unit, nil
+	}
+
+	if req.Balance < 0 {
+		return 0, "", fmt.Errorf("balance cannot be negative")
+	}
+	if req.Balance > utils.MaxSupplySats {
+		return 0, "", fmt.Errorf("balance exceeds the 21M BTC supply, balance must be given in sats")
+	}
+
+	return req.Balance, unit, nil
 }
 
 // handleGetOfflineAccounts handles GET /api/offline/accounts
+// Optional query parameters: custody_type to filter, sort (name, balance, last_updated, custody_type) to order.
 func (s *Server) handleGetOfflineAccounts(w http.ResponseWriter, r *http.Request) {
-	entries, err := s.db.GetColdStorageEntriesWithWarnings()
+	filter := db.ColdStorageFilter{
+		CustodyType: r.URL.Query().Get("custody_type"),
+		SortBy:      r.URL.Query().Get("sort"),
+	}
+
+	if fieldErr := validateEnum("custody_type", filter.CustodyType, db.CustodyTypes); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	if fieldErr := validateEnum("sort", filter.SortBy, db.ColdStorageSorts); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	entries, err := s.db.GetColdStorageEntriesFiltered(filter)
 	if err != nil {
 		log.Printf("handleGetOfflineAccounts: failed to get offline accounts: %v", err)
 		s.writeError(w, http.StatusInternalServerError, "Failed to get offline accounts")
 		return
 	}
 
+	// Display hint in the unit each entry is documented in
+	for _, entry := range entries {
+		unit, _ := entry["display_unit"].(string)
+		balance, _ := entry["balance"].(int64)
+		entry["display_balance"] = utils.FormatAmount(balance, unit)
+	}
+
 	s.writeJSON(w, APIResponse{Success: true, Data: entries})
 }
 
@@ -847,8 +3145,9 @@ func (s *Server) handleAddOfflineAccount(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if req.Balance < 0 {
-		s.writeError(w, http.StatusBadRequest, "Balance cannot be negative")
+	balance, unit, err := req.resolveBalance()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid balance: "+err.Error())
 		return
 	}
 
@@ -857,8 +3156,26 @@ func (s *Server) handleAddOfflineAccount(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if fieldErr := validateEnum("custody_type", req.CustodyType, db.CustodyTypes); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	// A deleted account keeps its name
+	deletedID, err := s.db.GetDeletedColdStorageEntryID(req.Name)
+	if err != nil {
+		log.Printf("handleAddOfflineAccount: failed to look up deleted accounts: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to add offline account")
+		return
+	}
+	if deletedID != 0 {
+		s.writeError(w, http.StatusConflict, fmt.Sprintf(
+			"A deleted account has this name, restore it with POST %s/offline/accounts/%d/restore", APIPrefix, deletedID))
+		return
+	}
+
 	// Add the offline account to database
-	entry, err := s.db.InsertColdStorageEntry(req.Name, req.Balance, req.Notes)
+	entry, err := s.db.InsertColdStorageEntry(req.Name, balance, req.Notes)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			s.writeError(w, http.StatusConflict, "An account with this name already exists")
@@ -869,6 +3186,24 @@ func (s *Server) handleAddOfflineAccount(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if unit != utils.UnitSats {
+		if err := s.db.UpdateColdStorageDisplayUnit(entry.ID, unit); err != nil {
+			log.Printf("handleAddOfflineAccount: failed to set display unit: %v", err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to save offline account unit")
+			return
+		}
+		entry.DisplayUnit = unit
+	}
+
+	if req.ColdStorageMetadata != (db.ColdStorageMetadata{}) {
+		entry, err = s.db.UpdateColdStorageMetadata(entry.ID, req.ColdStorageMetadata)
+		if err != nil {
+			log.Printf("handleAddOfflineAccount: failed to set custody metadata: %v", err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to save offline account metadata")
+			return
+		}
+	}
+
 	s.writeJSON(w, APIResponse{
 		Success: true,
 		Data:    entry,
@@ -877,16 +3212,9 @@ func (s *Server) handleAddOfflineAccount(w http.ResponseWriter, r *http.Request)
 
 // handleUpdateOfflineAccountBalance handles PUT /api/offline/accounts/{id}/balance
 func (s *Server) handleUpdateOfflineAccountBalance(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr, ok := vars["id"]
-	if !ok {
-		s.writeError(w, http.StatusBadRequest, "Entry ID is required")
-		return
-	}
-
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		s.writeError(w, http.StatusBadRequest, "Invalid entry ID")
+	id, fieldErr := parsePathID(r, "entry")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
 		return
 	}
 
@@ -902,8 +3230,9 @@ func (s *Server) handleUpdateOfflineAccountBalance(w http.ResponseWriter, r *htt
 		return
 	}
 
-	if req.Balance < 0 {
-		s.writeError(w, http.StatusBadRequest, "Balance cannot be negative")
+	balance, unit, err := req.resolveBalance()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid balance: "+err.Error())
 		return
 	}
 
@@ -925,7 +3254,7 @@ func (s *Server) handleUpdateOfflineAccountBalance(w http.ResponseWriter, r *htt
 	}
 
 	// Update the entry
-	updatedEntry, err := s.db.UpdateColdStorageEntry(id, req.Name, req.Balance, req.Notes)
+	updatedEntry, err := s.db.UpdateColdStorageEntry(id, req.Name, balance, req.Notes)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			s.writeError(w, http.StatusConflict, "An account with this name already exists")
@@ -936,24 +3265,63 @@ func (s *Server) handleUpdateOfflineAccountBalance(w http.ResponseWriter, r *htt
 		return
 	}
 
+	// Only change the display unit when the client sent one
+	if req.Unit != "" && unit != updatedEntry.DisplayUnit {
+		if err := s.db.UpdateColdStorageDisplayUnit(id, unit); err != nil {
+			log.Printf("handleUpdateOfflineAccountBalance: failed to set display unit: %v", err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to save offline account unit")
+			return
+		}
+		updatedEntry.DisplayUnit = unit
+	}
+
 	s.writeJSON(w, APIResponse{
 		Success: true,
 		Data:    updatedEntry,
 	})
 }
 
-// handleDeleteOfflineAccount handles DELETE /api/offline/accounts/{id}
-func (s *Server) handleDeleteOfflineAccount(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr, ok := vars["id"]
-	if !ok {
-		s.writeError(w, http.StatusBadRequest, "Entry ID is required")
+// handleUpdateOfflineAccountMetadata handles PUT /api/offline/accounts/{id}/metadata
+func (s *Server) handleUpdateOfflineAccountMetadata(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "entry")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
 		return
 	}
 
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	var req db.ColdStorageMetadata
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+
+	if fieldErr := validateEnum("custody_type", req.CustodyType, db.CustodyTypes); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	entry, err := s.db.UpdateColdStorageMetadata(id, req)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Offline account not found")
+		return
+	}
 	if err != nil {
-		s.writeError(w, http.StatusBadRequest, "Invalid entry ID")
+		log.Printf("handleUpdateOfflineAccountMetadata: failed to update metadata: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to update offline account metadata")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data:    entry,
+	})
+}
+
+// handleDeleteOfflineAccount handles DELETE /api/offline/accounts/{id}
+func (s *Server) handleDeleteOfflineAccount(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "entry")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
 		return
 	}
 
@@ -986,49 +3354,84 @@ func (s *Server) handleDeleteOfflineAccount(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// handleGetDeletedOfflineAccounts handles GET /api/offline/accounts/deleted
+func (s *Server) handleGetDeletedOfflineAccounts(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.db.GetDeletedColdStorageEntries()
+	if err != nil {
+		log.Printf("handleGetDeletedOfflineAccounts: failed to get deleted offline accounts: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get deleted offline accounts")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: entries})
+}
+
+// handleRestoreOfflineAccount handles POST /api/offline/accounts/{id}/restore
+func (s *Server) handleRestoreOfflineAccount(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "entry")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	entry, err := s.db.RestoreColdStorageEntry(id)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, "Deleted offline account not found")
+		return
+	}
+	if err != nil {
+		log.Printf("handleRestoreOfflineAccount: failed to restore offline account: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to restore offline account")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: entry})
+}
+
 // handleOfflineHistory handles GET /api/offline/history
 func (s *Server) handleOfflineHistory(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
-	accountIDStr := r.URL.Query().Get("account")
-	if accountIDStr == "" {
-		s.writeError(w, http.StatusBadRequest, "Account ID parameter is required")
+	accountID, fieldErr := parseID("account", r.URL.Query().Get("account"), "account")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
 		return
 	}
 
-	accountID, err := strconv.ParseInt(accountIDStr, 10, 64)
+	s.writeOfflineAccountHistory(w, r, accountID)
+}
+
+// handleOfflineAccountHistory handles GET /api/offline/accounts/{id}/history
+func (s *Server) handleOfflineAccountHistory(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "entry")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	// Only accounts tracked in this database may be queried
+	entry, err := s.db.GetColdStorageEntryByID(id)
 	if err != nil {
-		s.writeError(w, http.StatusBadRequest, "Invalid account ID")
+		log.Printf("handleOfflineAccountHistory: failed to get offline account by ID: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to check offline account")
+		return
+	}
+	if entry == nil {
+		s.writeError(w, http.StatusNotFound, "Offline account not found")
 		return
 	}
 
-	daysStr := r.URL.Query().Get("days")
-	days := 30 // default
-	var from, to time.Time
+	s.writeOfflineAccountHistory(w, r, entry.ID)
+}
 
-	if daysStr == "all" {
-		// For "all" data, get from the earliest possible date
-		to = time.Now()
-		genesisDate, _ := time.Parse("2006-01-02", BitcoinGenesisDate)
-		from = genesisDate
-	} else if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= MaxHistoryDays {
-			days = d
-		} else {
-			s.writeError(w, http.StatusBadRequest, "Invalid days parameter. Must be a number between 1 and "+strconv.Itoa(MaxHistoryDays)+", or 'all'")
-			return
-		}
-		// Calculate time range
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	} else {
-		// Default case
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	}
+// writeOfflineAccountHistory writes the Chart.js formatted balance history for an offline account
+func (s *Server) writeOfflineAccountHistory(w http.ResponseWriter, r *http.Request, accountID int64) {
+	tr := timeRangeFrom(r)
+	from, to := tr.From, tr.To
+	theme := themeFrom(r)
 
 	history, err := s.db.GetColdStorageHistory(accountID, from, to)
 	if err != nil {
-		log.Printf("handleOfflineHistory: failed to get history for account %d: %v", accountID, err)
+		log.Printf("writeOfflineAccountHistory: failed to get history for account %d: %v", accountID, err)
 		s.writeError(w, http.StatusInternalServerError, "Failed to get account balance history")
 		return
 	}
@@ -1040,8 +3443,8 @@ func (s *Server) handleOfflineHistory(w http.ResponseWriter, r *http.Request) {
 			{
 				"label":           fmt.Sprintf("Balance History"),
 				"data":            make([]int64, 0, len(history)),
-				"backgroundColor": "rgba(255, 159, 64, 0.2)",
-				"borderColor":     "rgba(255, 159, 64, 1)",
+				"backgroundColor": theme.Fill(ColorTertiary),
+				"borderColor":     theme.Border(ColorTertiary),
 				"borderWidth":     2,
 				"fill":            false,
 				"tension":         0.3,
@@ -1049,7 +3452,7 @@ func (s *Server) handleOfflineHistory(w http.ResponseWriter, r *http.Request) {
 		},
 		"metadata": map[string]interface{}{
 			"account_id":     accountID,
-			"days_requested": days,
+			"days_requested": tr.Days,
 			"days_with_data": len(history),
 		},
 	}
@@ -1067,7 +3470,8 @@ func (s *Server) handleOfflineHistory(w http.ResponseWriter, r *http.Request) {
 	chartData["labels"] = labels
 	chartData["datasets"].([]map[string]interface{})[0]["data"] = data
 
-	s.writeJSON(w, APIResponse{Success: true, Data: chartData})
+	convertChart(chartData, r)
+	s.writeHistory(w, r, chartData)
 }
 
 // handleStrikeCurrentBalance handles GET /api/strike/balance/current
@@ -1097,31 +3501,8 @@ func (s *Server) handleStrikeBalanceHistory(w http.ResponseWriter, r *http.Reque
 		currency = "BTC"
 	}
 
-	// Parse days parameter
-	daysStr := r.URL.Query().Get("days")
-	days := 30 // default
-	var from, to time.Time
-
-	if daysStr == "all" {
-		// For "all" data, get from the earliest possible date
-		to = time.Now()
-		genesisDate, _ := time.Parse("2006-01-02", BitcoinGenesisDate)
-		from = genesisDate
-	} else if daysStr != "" {
-		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= MaxHistoryDays {
-			days = d
-		} else {
-			s.writeError(w, http.StatusBadRequest, "Invalid days parameter. Must be a number between 1 and "+strconv.Itoa(MaxHistoryDays)+", or 'all'")
-			return
-		}
-		// Calculate time range
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	} else {
-		// Default case
-		to = time.Now()
-		from = to.AddDate(0, 0, -days)
-	}
+	tr := timeRangeFrom(r)
+	from, to := tr.From, tr.To
 
 	balances, err := s.db.GetStrikeBalanceHistory(currency, from, to)
 	if err != nil {
@@ -1130,6 +3511,7 @@ func (s *Server) handleStrikeBalanceHistory(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	theme := themeFrom(r)
 	// Format data for Chart.js consumption
 	chartData := map[string]interface{}{
 		"labels": make([]string, 0, len(balances)),
@@ -1137,23 +3519,23 @@ func (s *Server) handleStrikeBalanceHistory(w http.ResponseWriter, r *http.Reque
 			{
 				"label":           fmt.Sprintf("Available Balance (%s)", currency),
 				"data":            make([]int64, 0, len(balances)),
-				"backgroundColor": "rgba(255, 159, 64, 0.2)",
-				"borderColor":     "rgba(255, 159, 64, 1)",
+				"backgroundColor": theme.Fill(ColorTertiary),
+				"borderColor":     theme.Border(ColorTertiary),
 				"borderWidth":     2,
 				"fill":            false,
 			},
 			{
 				"label":           fmt.Sprintf("Total Balance (%s)", currency),
 				"data":            make([]int64, 0, len(balances)),
-				"backgroundColor": "rgba(54, 162, 235, 0.2)",
-				"borderColor":     "rgba(54, 162, 235, 1)",
+				"backgroundColor": theme.Fill(ColorSecondary),
+				"borderColor":     theme.Border(ColorSecondary),
 				"borderWidth":     2,
 				"fill":            false,
 			},
 		},
 		"metadata": map[string]interface{}{
 			"currency":       currency,
-			"days_requested": days,
+			"days_requested": tr.Days,
 			"days_with_data": len(balances),
 		},
 	}
@@ -1174,5 +3556,185 @@ func (s *Server) handleStrikeBalanceHistory(w http.ResponseWriter, r *http.Reque
 	chartData["datasets"].([]map[string]interface{})[0]["data"] = availableData
 	chartData["datasets"].([]map[string]interface{})[1]["data"] = totalData
 
-	s.writeJSON(w, APIResponse{Success: true, Data: chartData})
+	// Strike balances are kept in sats for BTC and cents for fiat
+	unit := db.StrikeUnit(currency)
+	if unit == db.StrikeUnitCents {
+		unit = currency + " cents"
+	}
+	addFormatHints(chartData["metadata"].(map[string]interface{}), r, unit, 0)
+
+	s.writeHistory(w, r, chartData)
+}
+
+// handleLiquidCurrentBalance handles GET /api/liquid/balance/current
+func (s *Server) handleLiquidCurrentBalance(w http.ResponseWriter, r *http.Request) {
+	balance, err := s.db.GetLatestLiquidBalance()
+	if err != nil {
+		log.Printf("handleLiquidCurrentBalance: failed to get latest Liquid balance: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get current Liquid balance")
+		return
+	}
+	if balance == nil {
+		s.writeError(w, http.StatusNotFound, "No Liquid balance has been collected yet")
+		return
+	}
+
+	conv := unitsFrom(r)
+	data := map[string]interface{}{
+		"timestamp":   balance.Timestamp,
+		"confirmed":   conv.Convert(balance.Confirmed),
+		"unconfirmed": conv.Convert(balance.Unconfirmed),
+		"total":       conv.Convert(balance.Confirmed + balance.Unconfirmed),
+	}
+	conv.describe(data)
+	s.writeJSON(w, APIResponse{Success: true, Data: data})
+}
+
+// handleLiquidBalanceHistory handles GET /api/liquid/balance/history
+func (s *Server) handleLiquidBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+	theme := themeFrom(r)
+
+	balances, err := s.db.GetLiquidBalanceHistory(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleLiquidBalanceHistory: failed to get Liquid balance history: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get Liquid balance history")
+		return
+	}
+
+	labels := make([]string, 0, len(balances))
+	confirmedData := make([]int64, 0, len(balances))
+	totalData := make([]int64, 0, len(balances))
+	for _, balance := range balances {
+		labels = append(labels, balance.Timestamp.Format("2006-01-02 15:04"))
+		confirmedData = append(confirmedData, balance.Confirmed)
+		totalData = append(totalData, balance.Confirmed+balance.Unconfirmed)
+	}
+
+	// Format data for Chart.js consumption
+	chartData := map[string]interface{}{
+		"labels": labels,
+		"datasets": []map[string]interface{}{
+			{
+				"label":           "Confirmed L-BTC",
+				"data":            confirmedData,
+				"backgroundColor": theme.Fill(ColorPrimary),
+				"borderColor":     theme.Border(ColorPrimary),
+				"borderWidth":     2,
+				"fill":            false,
+			},
+			{
+				"label":           "Total L-BTC",
+				"data":            totalData,
+				"backgroundColor": theme.Fill(ColorSecondary),
+				"borderColor":     theme.Border(ColorSecondary),
+				"borderWidth":     2,
+				"fill":            false,
+			},
+		},
+		"metadata": map[string]interface{}{
+			"days_requested": tr.Days,
+			"points":         len(balances),
+		},
+	}
+
+	convertChart(chartData, r)
+	s.writeHistory(w, r, chartData)
+}
+
+// handleEcashBalances handles GET /api/ecash/balances
+func (s *Server) handleEcashBalances(w http.ResponseWriter, r *http.Request) {
+	balances, err := s.db.GetLatestEcashBalances()
+	if err != nil {
+		log.Printf("handleEcashBalances: failed to get ecash balances: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get ecash balances")
+		return
+	}
+
+	conv := unitsFrom(r)
+	var total int64
+	mints := make([]map[string]interface{}, 0, len(balances))
+	for _, balance := range balances {
+		total += balance.Amount
+		mints = append(mints, map[string]interface{}{
+			"protocol":  balance.Protocol,
+			"mint":      balance.Mint,
+			"name":      balance.Name,
+			"amount":    conv.Convert(balance.Amount),
+			"timestamp": balance.Timestamp,
+		})
+	}
+
+	data := map[string]interface{}{
+		"mints": mints,
+		"total": conv.Convert(total),
+	}
+	conv.describe(data)
+	s.writeJSON(w, APIResponse{Success: true, Data: data})
+}
+
+// handleEcashBalanceHistory handles GET /api/ecash/balance/history. Each point
+// is the total across all federations and mints after one collector run.
+func (s *Server) handleEcashBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	tr := timeRangeFrom(r)
+	theme := themeFrom(r)
+
+	// Mints that were not collected in the range keep their earlier balance
+	opening, err := s.db.GetEcashBalancesAt(tr.From)
+	if err != nil {
+		log.Printf("handleEcashBalanceHistory: failed to get opening ecash balances: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get ecash balance history")
+		return
+	}
+	balances, err := s.db.GetEcashBalanceHistory(tr.From, tr.To)
+	if err != nil {
+		log.Printf("handleEcashBalanceHistory: failed to get ecash balance history: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get ecash balance history")
+		return
+	}
+
+	perMint := make(map[string]int64)
+	for _, balance := range opening {
+		perMint[balance.Protocol+":"+balance.Mint] = balance.Amount
+	}
+
+	labels := make([]string, 0, len(balances))
+	totalData := make([]int64, 0, len(balances))
+	for i, balance := range balances {
+		perMint[balance.Protocol+":"+balance.Mint] = balance.Amount
+		// One collector run stores all mints with the same timestamp
+		if i+1 < len(balances) && balances[i+1].Timestamp.Equal(balance.Timestamp) {
+			continue
+		}
+
+		var total int64
+		for _, amount := range perMint {
+			total += amount
+		}
+		labels = append(labels, balance.Timestamp.Format("2006-01-02 15:04"))
+		totalData = append(totalData, total)
+	}
+
+	// Format data for Chart.js consumption
+	chartData := map[string]interface{}{
+		"labels": labels,
+		"datasets": []map[string]interface{}{
+			{
+				"label":           "Total ecash",
+				"data":            totalData,
+				"backgroundColor": theme.Fill(ColorQuaternary),
+				"borderColor":     theme.Border(ColorQuaternary),
+				"borderWidth":     2,
+				"fill":            false,
+			},
+		},
+		"metadata": map[string]interface{}{
+			"days_requested": tr.Days,
+			"points":         len(labels),
+			"mints":          len(perMint),
+		},
+	}
+
+	convertChart(chartData, r)
+	s.writeHistory(w, r, chartData)
 }
@@ -3,14 +3,24 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/capture"
 	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/liquidity"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/price"
+	"github.com/brewgator/lightning-node-tools/internal/swap"
 	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/brewgator/lightning-node-tools/internal/version"
 	"github.com/gorilla/mux"
 )
 
@@ -109,9 +119,13 @@ func setupTestServer(t *testing.T) *Server {
 	seedTestData(t, database)
 
 	server := &Server{
-		db:       database,
-		router:   mux.NewRouter(),
-		mockMode: true,
+		db:            database,
+		router:        mux.NewRouter(),
+		adminRouter:   mux.NewRouter(),
+		mockMode:      true,
+		liquidity:     liquidity.NewConfig(),
+		confirmations: bitcoin.DefaultConfirmationPolicy(),
+		metrics:       metrics.NewRegistry(),
 	}
 	server.setupRoutes()
 
@@ -177,7 +191,7 @@ func TestCurrentPortfolioEndpoint(t *testing.T) {
 	requiredFields := []string{
 		"timestamp", "lightning_local", "lightning_remote",
 		"onchain_confirmed", "onchain_unconfirmed", "tracked_addresses",
-		"cold_storage", "total_portfolio", "total_liquid",
+		"tracked_confirmed", "tracked_unconfirmed", "cold_storage", "total_portfolio", "total_liquid",
 	}
 
 	for _, field := range requiredFields {
@@ -233,59 +247,1143 @@ func TestPortfolioHistoryWithInvalidDays(t *testing.T) {
 
 	// Should return bad request for invalid days
 	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+
+	// The structured error names the offending parameter
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(response.Errors), 1)
+	testutils.AssertEqual(t, response.Errors[0].Field, "days")
+	testutils.AssertEqual(t, response.Errors[0].Code, ErrCodeOutOfRange)
+}
+
+func TestPortfolioHistoryWithPreset(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/v1/portfolio/history?range=mtd", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+}
+
+type fixedPrices struct{}
+
+func (fixedPrices) GetPrices() (*mempool.Prices, error) {
+	return &mempool.Prices{Time: time.Unix(1700000000, 0), Rates: map[string]float64{"USD": 50000}}, nil
+}
+
+func TestUnitsParameter(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Fiat needs a price service
+	testutils.AssertEqual(t, get("/api/v1/portfolio/current?units=fiat").Code, http.StatusServiceUnavailable)
+	testutils.AssertEqual(t, get("/api/v1/portfolio/current?units=eur").Code, http.StatusBadRequest)
+
+	server.prices = price.NewService(fixedPrices{}, price.DefaultCacheTTL)
+	server.fiatCurrency = "USD"
+
+	var current struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(get("/api/v1/portfolio/current?units=btc").Body.Bytes(), &current))
+	testutils.AssertEqual(t, current.Data["total_portfolio"], 0.186)
+	testutils.AssertEqual(t, current.Data["units"], "btc")
+
+	testutils.AssertNoError(t, json.Unmarshal(get("/api/v1/portfolio/current?units=FIAT").Body.Bytes(), &current))
+	testutils.AssertEqual(t, current.Data["cold_storage"], 5000.0)
+	testutils.AssertEqual(t, current.Data["currency"], "USD")
+	testutils.AssertEqual(t, current.Data["btc_price"], 50000.0)
+
+	// Sats responses are unchanged
+	var sats struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(get("/api/v1/portfolio/current").Body.Bytes(), &sats))
+	testutils.AssertEqual(t, sats.Data["total_portfolio"], 18600000.0)
+	_, hasUnits := sats.Data["units"]
+	testutils.AssertEqual(t, hasUnits, false)
+
+	var chart struct {
+		Data struct {
+			Datasets []struct {
+				Label string    `json:"label"`
+				Data  []float64 `json:"data"`
+			} `json:"datasets"`
+			Metadata map[string]interface{} `json:"metadata"`
+		} `json:"data"`
+	}
+	rr := get("/api/v1/lightning/fees?days=30&units=fiat")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &chart))
+	testutils.AssertEqual(t, chart.Data.Datasets[0].Label, "Daily Fees (USD)")
+	testutils.AssertEqual(t, chart.Data.Metadata["units"], "fiat")
+}
+
+func TestCurrencyParameter(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	get := func(path string, data interface{}) int {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if data != nil {
+			testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), data))
+		}
+		return rr.Code
+	}
+
+	server.fiatCurrencies = []string{"USD"}
+	testutils.AssertEqual(t, get("/api/v1/portfolio/current?currency=usd", nil), http.StatusServiceUnavailable)
+
+	server.prices = price.NewService(fixedPrices{}, price.DefaultCacheTTL)
+	server.prices.Record(server.db, server.fiatCurrencies)
+	testutils.AssertEqual(t, get("/api/v1/portfolio/current?currency=eur", nil), http.StatusBadRequest)
+
+	now := time.Now().Truncate(time.Second)
+	_, err := server.db.InsertBTCPrices([]db.BTCPrice{
+		{Timestamp: now.Add(-72 * time.Hour), Currency: "USD", Price: 40000},
+		{Timestamp: now.Add(-36 * time.Hour), Currency: "USD", Price: 45000},
+	})
+	testutils.AssertNoError(t, err)
+
+	// Amounts stay in sats, valued at the price of their time alongside
+	var history struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	testutils.AssertEqual(t, get("/api/v1/portfolio/history?days=7&currency=usd", &history), http.StatusOK)
+	for _, snapshot := range history.Data {
+		timestamp, err := time.Parse(time.RFC3339Nano, snapshot["timestamp"].(string))
+		testutils.AssertNoError(t, err)
+		// Before the first stored price the one recorded from fixedPrices applies
+		want := 50000.0
+		switch {
+		case !timestamp.Before(now.Add(-36 * time.Hour)):
+			want = 45000
+		case !timestamp.Before(now.Add(-72 * time.Hour)):
+			want = 40000
+		}
+		fiat := snapshot["fiat"].(map[string]interface{})
+		testutils.AssertEqual(t, fiat["currency"], "USD")
+		testutils.AssertEqual(t, fiat["btc_price"], want)
+		total := snapshot["total_portfolio"].(float64)
+		testutils.AssertEqual(t, fiat["total_portfolio"], math.Round(total/1e8*want*100)/100)
+	}
+
+	// units=fiat converts at the current price in the chosen currency
+	var current struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	testutils.AssertEqual(t, get("/api/v1/portfolio/current?units=fiat&currency=usd", &current), http.StatusOK)
+	testutils.AssertEqual(t, current.Data["btc_price"], 50000.0)
+	testutils.AssertEqual(t, current.Data["fiat"].(map[string]interface{})["btc_price"], 45000.0)
+
+	var chart struct {
+		Data struct {
+			Labels   []string `json:"labels"`
+			Datasets []struct {
+				Data     []float64 `json:"data"`
+				FiatData []float64 `json:"fiat_data"`
+			} `json:"datasets"`
+			Metadata map[string]interface{} `json:"metadata"`
+		} `json:"data"`
+	}
+	testutils.AssertEqual(t, get("/api/v1/lightning/fees?days=7&currency=usd", &chart), http.StatusOK)
+	testutils.AssertEqual(t, len(chart.Data.Datasets[0].FiatData), len(chart.Data.Labels))
+	prices := chart.Data.Metadata["fiat"].(map[string]interface{})["btc_prices"].([]interface{})
+	testutils.AssertEqual(t, len(prices), len(chart.Data.Labels))
+}
+
+func TestLightningFeesWithDateRange(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	// A full tax year
+	req, err := http.NewRequest("GET", "/api/v1/lightning/fees?from=2023-01-01&to=2023-12-31", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	data := response.Data.(map[string]interface{})
+	metadata := data["metadata"].(map[string]interface{})
+	testutils.AssertEqual(t, metadata["days_requested"], float64(365))
+}
+
+func TestHistoryRejectsFutureDates(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	future := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+	req, err := http.NewRequest("GET", "/api/v1/lightning/forwards?from=2024-01-01&to="+future, nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, response.Errors[0].Field, "to")
+}
+
+func TestInvalidOfflineAccountSortReturnsFieldError(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/v1/offline/accounts?sort=size", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(response.Errors), 1)
+	testutils.AssertEqual(t, response.Errors[0], FieldError{
+		Code:    ErrCodeInvalid,
+		Field:   "sort",
+		Message: "Invalid sort. Must be one of: name, balance, last_updated, custody_type",
+	})
+}
+
+func TestLightningFeesEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/lightning/fees?days=7", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	testutils.AssertEqual(t, response.Success, true)
+
+	// Verify Chart.js format
+	dataMap, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected data to be a map")
+	}
+
+	// Check required Chart.js fields
+	requiredFields := []string{"labels", "datasets", "metadata"}
+	for _, field := range requiredFields {
+		if _, exists := dataMap[field]; !exists {
+			t.Errorf("Missing required Chart.js field: %s", field)
+		}
+	}
+
+	// Check metadata structure
+	metadata, ok := dataMap["metadata"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected metadata to be a map")
+	}
+
+	requiredMetadata := []string{"total_fees", "total_forwards", "days_requested", "days_with_data"}
+	for _, field := range requiredMetadata {
+		if _, exists := metadata[field]; !exists {
+			t.Errorf("Missing required metadata field: %s", field)
+		}
+	}
+}
+
+func TestLightningForwardsEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/lightning/forwards?days=7", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	testutils.AssertEqual(t, response.Success, true)
+
+	// Verify Chart.js format
+	dataMap, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected data to be a map")
+	}
+
+	// Check required Chart.js fields
+	requiredFields := []string{"labels", "datasets", "metadata"}
+	for _, field := range requiredFields {
+		if _, exists := dataMap[field]; !exists {
+			t.Errorf("Missing required Chart.js field: %s", field)
+		}
+	}
+
+	// Check metadata structure
+	metadata, ok := dataMap["metadata"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected metadata to be a map")
+	}
+
+	// Should have success_rate field
+	if _, exists := metadata["success_rate"]; !exists {
+		t.Error("Missing success_rate in forwards metadata")
+	}
+}
+
+func TestLightningForwardStatsEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/v1/lightning/forwards/stats?days=7", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Stats db.ForwardingStats `json:"stats"`
+		} `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	stats := response.Data.Stats
+	testutils.AssertEqual(t, stats.ForwardCount, int64(3))
+	testutils.AssertEqual(t, stats.TotalFees, int64(350))
+	testutils.AssertEqual(t, stats.MedianForwardSize, int64(49900))
+	testutils.AssertEqual(t, stats.LargestForward.AmountOut, int64(99800))
+	testutils.AssertEqual(t, stats.BusiestChannel.ChannelID, "123456789:1:0")
+}
+
+func TestChannelBalanceHistoryEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	hour := time.Now().Truncate(time.Hour)
+	snapshots := []db.ChannelSnapshot{
+		{Timestamp: hour.Add(-26 * time.Hour), ChannelID: "123456789:1:0", Capacity: 1000000,
+			LocalBalance: 900000, RemoteBalance: 100000, Active: true, PeerAlias: "Peer"},
+		{Timestamp: hour.Add(-2 * time.Hour), ChannelID: "123456789:1:0", Capacity: 1000000,
+			LocalBalance: 500000, RemoteBalance: 500000, Active: true, PeerAlias: "Peer"},
+		// Same hour as the previous snapshot, so it replaces it in the chart
+		{Timestamp: hour.Add(-2*time.Hour + 30*time.Minute), ChannelID: "123456789:1:0", Capacity: 1000000,
+			LocalBalance: 40000, RemoteBalance: 960000, Active: true, PeerAlias: "Peer"},
+		{Timestamp: hour.Add(-1 * time.Hour), ChannelID: "987654321:1:0", Capacity: 500000,
+			LocalBalance: 250000, RemoteBalance: 250000, Active: true},
+	}
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots(snapshots))
+
+	req, err := http.NewRequest("GET", "/api/v1/lightning/channels/123456789:1:0/balance-history?days=7", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Labels   []string `json:"labels"`
+			Datasets []struct {
+				Label string  `json:"label"`
+				Data  []int64 `json:"data"`
+			} `json:"datasets"`
+			Metadata map[string]interface{} `json:"metadata"`
+		} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+	testutils.AssertEqual(t, len(response.Data.Labels), 2)
+	testutils.AssertEqual(t, len(response.Data.Datasets), 2)
+	local := response.Data.Datasets[0].Data
+	remote := response.Data.Datasets[1].Data
+	testutils.AssertEqual(t, local[0], int64(900000))
+	testutils.AssertEqual(t, local[1], int64(40000))
+	testutils.AssertEqual(t, remote[1], int64(960000))
+	testutils.AssertEqual(t, response.Data.Metadata["peer_alias"], "Peer")
+	testutils.AssertEqual(t, response.Data.Metadata["local_ratio"], 0.04)
+}
+
+func TestChannelBalanceHistoryInvalidID(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/v1/lightning/channels/abc/balance-history", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestLightningChannelsStatusFilter(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+	server.liquidity.Overrides["333:1:0"] = liquidity.Thresholds{Low: 0.3, High: 0.9, Hysteresis: 0.02}
+
+	now := time.Now()
+	older := []db.ChannelSnapshot{
+		{Timestamp: now.Add(-time.Hour), ChannelID: "999:1:0", Capacity: 1000000, LocalBalance: 0},
+	}
+	latest := []db.ChannelSnapshot{
+		{Timestamp: now, ChannelID: "111:1:0", Capacity: 1000000, LocalBalance: 20000, RemoteBalance: 980000},
+		{Timestamp: now, ChannelID: "222:1:0", Capacity: 1000000, LocalBalance: 980000, RemoteBalance: 20000},
+		{Timestamp: now, ChannelID: "333:1:0", Capacity: 1000000, LocalBalance: 200000, RemoteBalance: 800000},
+		{Timestamp: now, ChannelID: "444:1:0", Capacity: 1000000, LocalBalance: 500000, RemoteBalance: 500000},
+	}
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots(older))
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots(latest))
+	// 333 earns fees, so it is refilled before the emptier but idle 111
+	testutils.AssertNoError(t, server.db.InsertForwardingEvent(&db.ForwardingEvent{
+		Timestamp: now.Add(-24 * time.Hour), ChannelInID: "222:1:0", ChannelOutID: "333:1:0", AmountIn: 100300, AmountOut: 100000, Fee: 300,
+	}))
+
+	tests := []struct {
+		status string
+		want   []string
+	}{
+		{"", []string{"111:1:0", "222:1:0", "333:1:0", "444:1:0"}},
+		{"unbalanced", []string{"111:1:0", "222:1:0", "333:1:0"}},
+		{"depleted", []string{"333:1:0", "111:1:0"}},
+		{"saturated", []string{"222:1:0"}},
+		{"balanced", []string{"444:1:0"}},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", "/api/v1/lightning/channels?status="+tt.status, nil)
+		testutils.AssertNoError(t, err)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+		var response struct {
+			Data []ChannelInfo `json:"data"`
+		}
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+		var got []string
+		for _, channel := range response.Data {
+			got = append(got, channel.ChannelID)
+			if (channel.Status == liquidity.StateDepleted) != (channel.Refill != nil) {
+				t.Errorf("channel %s (%s): unexpected refill %+v", channel.ChannelID, channel.Status, channel.Refill)
+			}
+		}
+		testutils.AssertEqual(t, strings.Join(got, ","), strings.Join(tt.want, ","))
+		if tt.status == "depleted" {
+			testutils.AssertEqual(t, response.Data[0].Refill.Amount, int64(300000))
+			testutils.AssertEqual(t, response.Data[0].Refill.FeesPerDay, 10.0)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/lightning/channels?status=empty", nil)
+	testutils.AssertNoError(t, err)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestChannelFeesEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots([]db.ChannelSnapshot{
+		{Timestamp: now, ChannelID: "111:1:0", Capacity: 1000000, PeerAlias: "ACINQ"},
+	}))
+	for _, event := range []db.ForwardingEvent{
+		{Timestamp: now.Add(-time.Hour), ChannelInID: "222:1:0", ChannelOutID: "111:1:0", AmountIn: 100200, AmountOut: 100000, Fee: 200},
+		{Timestamp: now.Add(-2 * time.Hour), ChannelInID: "111:1:0", ChannelOutID: "222:1:0", AmountIn: 50050, AmountOut: 50000, Fee: 50},
+	} {
+		event := event
+		testutils.AssertNoError(t, server.db.InsertForwardingEvent(&event))
+	}
+
+	// Registered ahead of /lightning/channels/{id}, which would take "fees" for an ID
+	req, err := http.NewRequest("GET", "/api/v1/lightning/channels/fees?days=7", nil)
+	testutils.AssertNoError(t, err)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Data struct {
+			Channels []db.ChannelFees `json:"channels"`
+			Metadata struct {
+				TotalFees int64 `json:"total_fees"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	// The mock tables hold forwards of their own
+	channels := map[string]db.ChannelFees{}
+	var totalFees int64
+	for _, channel := range response.Data.Channels {
+		channels[channel.ChannelID] = channel
+		totalFees += channel.OutFees
+	}
+	testutils.AssertEqual(t, channels["111:1:0"], db.ChannelFees{ChannelID: "111:1:0", PeerAlias: "ACINQ",
+		OutForwardCount: 1, OutVolume: 100000, OutFees: 200, InForwardCount: 1, InVolume: 50050, InFees: 50})
+	testutils.AssertEqual(t, channels["222:1:0"].PeerAlias, "")
+	testutils.AssertEqual(t, channels["222:1:0"].OutFees, int64(50))
+	testutils.AssertEqual(t, response.Data.Metadata.TotalFees, totalFees)
+}
+
+func TestLightningChannelEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots([]db.ChannelSnapshot{
+		{Timestamp: now, ChannelID: "111:1:0", Capacity: 1000000, LocalBalance: 500000, RemoteBalance: 500000, Active: true},
+		{Timestamp: now, ChannelID: "222:1:0", Capacity: 1000000, LocalBalance: 500000, RemoteBalance: 500000, Active: true},
+	}))
+	// A forward out of 111 ten days ago and one into it 40 days ago, after
+	// which 222 never forwarded
+	for _, event := range []db.ForwardingEvent{
+		{Timestamp: now.AddDate(0, 0, -10), ChannelInID: "222:1:0", ChannelOutID: "111:1:0", AmountIn: 100200, AmountOut: 100000, Fee: 200},
+		{Timestamp: now.AddDate(0, 0, -40), ChannelInID: "111:1:0", ChannelOutID: "333:1:0", AmountIn: 50050, AmountOut: 50000, Fee: 50},
+	} {
+		event := event
+		testutils.AssertNoError(t, server.db.InsertForwardingEvent(&event))
+	}
+
+	get := func(id string) (*httptest.ResponseRecorder, ChannelInfo) {
+		req, err := http.NewRequest("GET", "/api/v1/lightning/channels/"+id, nil)
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		var response struct {
+			Data ChannelInfo `json:"data"`
+		}
+		if rr.Code == http.StatusOK {
+			testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		}
+		return rr, response.Data
+	}
+
+	rr, channel := get("111:1:0")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	want := []ChannelForwarding{
+		{Days: 7},
+		{Days: 30, ForwardCount: 1, Volume: 100000, Fees: 200},
+		{Days: 90, ForwardCount: 1, Volume: 100000, Fees: 200},
+	}
+	testutils.AssertEqual(t, len(channel.Forwarding), len(want))
+	for i := range want {
+		testutils.AssertEqual(t, channel.Forwarding[i], want[i])
+	}
+	testutils.AssertEqual(t, *channel.DaysSinceLastForward, 10)
+	testutils.AssertEqual(t, channel.Health, liquidity.HealthOf(channel.ChannelSnapshot, db.ChannelFeeStats{Fees: 200}, channel.LastForward))
+
+	// Forwarding into a channel counts as activity but earns it nothing
+	rr, channel = get("222:1:0")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, *channel.DaysSinceLastForward, 10)
+	testutils.AssertEqual(t, channel.Health.Earnings, 0)
+
+	rr, _ = get("999:1:0")
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+	rr, _ = get("not-a-channel")
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestMissionControlEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	now := time.Now()
+	err := server.db.InsertMissionControlSnapshot(now, []db.MissionControlPair{
+		{NodeFrom: "aa", NodeTo: "bb", SuccessTime: now.Unix(), SuccessAmtMsat: 200000000},
+		{NodeFrom: "bb", NodeTo: "cc", FailTime: now.Unix(), FailAmtMsat: 50000000},
+	})
+	testutils.AssertNoError(t, err)
+
+	req, err := http.NewRequest("GET", "/api/v1/lightning/mission-control?node=bb&amount_sat=100000", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Data struct {
+			AmountSat int                      `json:"amount_sat"`
+			Pairs     []MissionControlPairInfo `json:"pairs"`
+		} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	testutils.AssertEqual(t, response.Data.AmountSat, 100000)
+	testutils.AssertEqual(t, len(response.Data.Pairs), 2)
+	testutils.AssertEqual(t, response.Data.Pairs[0].SuccessProbability, 0.95)
+	if p := response.Data.Pairs[1].SuccessProbability; p > 0.01 {
+		t.Errorf("expected a fresh failure to have near zero probability, got %f", p)
+	}
+
+	req, err = http.NewRequest("GET", "/api/v1/lightning/mission-control?amount_sat=0", nil)
+	testutils.AssertNoError(t, err)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestPortfolioPerformanceEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	timestamp := time.Now().AddDate(0, 0, -10).UTC().Format(time.RFC3339)
+	rr := do("POST", "/api/v1/portfolio/transfers", `{"amount": 500000, "notes": "DCA", "timestamp": "`+timestamp+`"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var created struct {
+		Data db.PortfolioTransfer `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	testutils.AssertEqual(t, created.Data.Amount, int64(500000))
+
+	testutils.AssertEqual(t, do("POST", "/api/v1/portfolio/transfers", `{"notes": "no amount"}`).Code, http.StatusBadRequest)
+
+	var performance struct {
+		Data struct {
+			Windows   []PerformanceWindow `json:"windows"`
+			Transfers int                 `json:"transfers"`
+		} `json:"data"`
+	}
+	rr = do("GET", "/api/v1/portfolio/performance", "")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &performance))
+	testutils.AssertEqual(t, len(performance.Data.Windows), 4)
+	testutils.AssertEqual(t, performance.Data.Windows[0].Window, "1m")
+	testutils.AssertEqual(t, performance.Data.Windows[0].Deposits, int64(500000))
+	testutils.AssertEqual(t, performance.Data.Transfers, 1)
+
+	rr = do("GET", "/api/v1/portfolio/performance?window=3m", "")
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &performance))
+	testutils.AssertEqual(t, len(performance.Data.Windows), 1)
+	testutils.AssertEqual(t, do("GET", "/api/v1/portfolio/performance?window=2w", "").Code, http.StatusBadRequest)
+
+	path := fmt.Sprintf("/api/v1/portfolio/transfers/%d", created.Data.ID)
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusOK)
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusNotFound)
+}
+
+func TestStatementsEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	testutils.AssertNoError(t, server.db.InsertStatement(&db.Statement{
+		Month: "2024-05", PeriodStart: start, PeriodEnd: start.AddDate(0, 1, 0), TotalPortfolio: 1000,
+	}))
+
+	req, err := http.NewRequest("GET", "/api/v1/reports/statements", nil)
+	testutils.AssertNoError(t, err)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Data []db.Statement `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	testutils.AssertEqual(t, len(response.Data), 1)
+	testutils.AssertEqual(t, response.Data[0].TotalPortfolio, int64(1000))
+}
+
+func TestStatementReportEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/v1/reports/statement?month=2024-05")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, rr.Header().Get("Content-Type"), "application/pdf")
+	testutils.AssertEqual(t, rr.Header().Get("Content-Disposition"), `attachment; filename="statement-2024-05.pdf"`)
+	testutils.AssertEqual(t, strings.HasPrefix(rr.Body.String(), "%PDF-"), true)
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(), "Provisional"), true)
+
+	rr = get("/api/v1/reports/statement?month=2024-05&format=csv")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, strings.HasPrefix(rr.Body.String(), "section,item,value"), true)
+
+	testutils.AssertEqual(t, get("/api/v1/reports/statement?month=May").Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, get("/api/v1/reports/statement?format=docx").Code, http.StatusBadRequest)
+	future := time.Now().AddDate(0, 2, 0).Format("2006-01")
+	testutils.AssertEqual(t, get("/api/v1/reports/statement?month="+future).Code, http.StatusBadRequest)
+}
+
+func TestPeerPolicyEndpoints(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	pubkey := "02" + strings.Repeat("ab", 32)
+	path := "/api/v1/peers/policies/" + pubkey
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	testutils.AssertEqual(t, do("GET", path, "").Code, http.StatusNotFound)
+
+	rr := do("PUT", path, `{"blocklisted": true, "notes": " force closes "}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	// Omitted fields keep their value and pubkeys are matched case-insensitively
+	rr = do("PUT", "/api/v1/peers/policies/"+strings.ToUpper(pubkey), `{"notes": "spams opens"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Data db.PeerPolicy `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(do("GET", path, "").Body.Bytes(), &response))
+	testutils.AssertEqual(t, response.Data.Blocklisted, true)
+	testutils.AssertEqual(t, response.Data.Notes, "spams opens")
+
+	testutils.AssertEqual(t, do("PUT", path, `{"preferred": true}`).Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do("PUT", "/api/v1/peers/policies/02abc", `{}`).Code, http.StatusBadRequest)
+
+	var list struct {
+		Data []db.PeerPolicy `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(do("GET", "/api/v1/peers/policies", "").Body.Bytes(), &list))
+	testutils.AssertEqual(t, len(list.Data), 1)
+
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusOK)
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusNotFound)
+}
+
+func TestPeerNoteEndpoints(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	pubkey := "02" + strings.Repeat("ab", 32)
+	path := "/api/v1/peers/notes/" + pubkey
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	testutils.AssertEqual(t, do("GET", path, "").Code, http.StatusNotFound)
+
+	rr := do("PUT", path, `{"contact": " @alice ", "agreement": "we both keep 1k ppm", "agreed_on": "2024-03-01"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	// Omitted fields keep their value
+	rr = do("PUT", path, `{"notes": "prefers Signal"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Data db.PeerNote `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(do("GET", path, "").Body.Bytes(), &response))
+	testutils.AssertEqual(t, response.Data.Contact, "@alice")
+	testutils.AssertEqual(t, response.Data.AgreedOn, "2024-03-01")
+	testutils.AssertEqual(t, response.Data.Notes, "prefers Signal")
+
+	// Notes are private, the public listener refuses to read them
+	for _, path := range []string{path, "/api/v1/peers/notes"} {
+		rr = httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		testutils.AssertEqual(t, rr.Code, http.StatusForbidden)
+	}
+
+	testutils.AssertEqual(t, do("PUT", path, `{"agreed_on": "March 1st"}`).Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do("PUT", "/api/v1/peers/notes/02abc", `{}`).Code, http.StatusBadRequest)
+
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusOK)
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusNotFound)
+}
+
+func TestChannelNameEndpoints(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("PUT", "/api/v1/lightning/channels/100/name", `{"name": " LOOP main "}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var list struct {
+		Data []db.ChannelName `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(do("GET", "/api/v1/lightning/channels/names", "").Body.Bytes(), &list))
+	index := slices.IndexFunc(list.Data, func(name db.ChannelName) bool { return name.ChannelID == "100" })
+	if index < 0 {
+		t.Fatal("expected channel 100 to be named")
+	}
+	testutils.AssertEqual(t, list.Data[index].Name, "LOOP main")
+
+	testutils.AssertEqual(t, do("PUT", "/api/v1/lightning/channels/100/name", `{"name": " "}`).Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do("PUT", "/api/v1/lightning/channels/100/name", `{"name": "`+strings.Repeat("x", 33)+`"}`).Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do("PUT", "/api/v1/lightning/channels/abc/name", `{"name": "x"}`).Code, http.StatusBadRequest)
+
+	testutils.AssertEqual(t, do("DELETE", "/api/v1/lightning/channels/100/name", "").Code, http.StatusOK)
+	testutils.AssertEqual(t, do("DELETE", "/api/v1/lightning/channels/100/name", "").Code, http.StatusNotFound)
+}
+
+func TestFeePPMHistogramEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	now := time.Now().Add(-time.Hour)
+	for i, event := range []db.ForwardingEvent{
+		{ChannelInID: "100", ChannelOutID: "200", AmountIn: 1000001, AmountOut: 1000000, Fee: 1},
+		{ChannelInID: "100", ChannelOutID: "300", AmountIn: 10030, AmountOut: 10000, Fee: 30},
+	} {
+		event.Timestamp = now.Add(time.Duration(i) * time.Minute)
+		testutils.AssertNoError(t, server.db.InsertForwardingEvent(&event))
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/v1/lightning/forwards/ppm-histogram?days=7&channel_id=300")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Data struct {
+			Overall  db.FeePPMHistogram   `json:"overall"`
+			Channels []db.FeePPMHistogram `json:"channels"`
+			Chart    struct {
+				Labels   []string `json:"labels"`
+				Datasets []struct {
+					Data []int64 `json:"data"`
+				} `json:"datasets"`
+			} `json:"chart"`
+		} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	testutils.AssertEqual(t, len(response.Data.Channels), 1)
+	testutils.AssertEqual(t, response.Data.Channels[0].MedianPPM, int64(3000))
+	testutils.AssertEqual(t, response.Data.Chart.Labels[0], "0-9 ppm")
+	testutils.AssertEqual(t, response.Data.Chart.Labels[7], "2500+ ppm")
+	testutils.AssertEqual(t, response.Data.Chart.Datasets[0].Data[7], int64(1))
+
+	testutils.AssertEqual(t, get("/api/v1/lightning/forwards/ppm-histogram?channel_id=999").Code, http.StatusNotFound)
+	testutils.AssertEqual(t, get("/api/v1/lightning/forwards/ppm-histogram?channel_id=abc").Code, http.StatusBadRequest)
+}
+
+func TestReliabilityEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	now := time.Now()
+	for i, success := range []bool{true, true, true, false} {
+		testutils.AssertNoError(t, server.db.InsertProbeResult(&db.ProbeResult{
+			Timestamp:   now.Add(-time.Duration(4-i) * time.Hour),
+			Destination: "02" + strings.Repeat("ab", 32),
+			Amount:      1000,
+			Success:     success,
+			LatencyMs:   1500,
+		}))
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/lightning/reliability?days=1", nil)
+	testutils.AssertNoError(t, err)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Data struct {
+			Overall      db.ProbeStats   `json:"overall"`
+			Destinations []db.ProbeStats `json:"destinations"`
+			Chart        struct {
+				Labels   []string `json:"labels"`
+				Datasets []struct {
+					Data []float64 `json:"data"`
+				} `json:"datasets"`
+			} `json:"chart"`
+		} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	testutils.AssertEqual(t, response.Data.Overall.SuccessRate, 0.75)
+	testutils.AssertEqual(t, response.Data.Overall.AvgLatencyMs, int64(1500))
+	testutils.AssertEqual(t, len(response.Data.Destinations), 1)
+	// One hourly point per probe
+	testutils.AssertEqual(t, len(response.Data.Chart.Labels), 4)
+	testutils.AssertEqual(t, response.Data.Chart.Datasets[0].Data[3], 0.0)
+}
+
+func TestChannelLeaseEndpoints(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+	server.blockHeight = func() (int64, error) { return 849856, nil }
+
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots([]db.ChannelSnapshot{
+		{Timestamp: time.Now(), ChannelID: "100", Capacity: 1000000, LocalBalance: 100000, RemoteBalance: 900000, Active: true},
+	}))
+	testutils.AssertNoError(t, server.db.InsertForwardingEvent(&db.ForwardingEvent{
+		Timestamp: time.Now().Add(-time.Hour), ChannelInID: "1", ChannelOutID: "100", AmountIn: 502500, AmountOut: 500000, Fee: 2500,
+	}))
+
+	path := "/api/v1/lightning/channels/100/lease"
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+		return rr
+	}
+
+	testutils.AssertEqual(t, do("GET", path, "").Code, http.StatusNotFound)
+	testutils.AssertEqual(t, do("PUT", path, `{"premium": 10000}`).Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do("PUT", path, `{"side": "rented"}`).Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do("PUT", path, `{"side": "bought", "premium": -1}`).Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do("PUT", path, `{"side": "bought", "started_at": "2999-01-01"}`).Code, http.StatusBadRequest)
+
+	rr := do("PUT", path, `{"side": "bought", "provider": " pool ", "premium": 10000, "duration_blocks": 12960, "expiry_height": 850000}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var lease struct {
+		Data LeaseInfo `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(do("GET", path, "").Body.Bytes(), &lease))
+	testutils.AssertEqual(t, lease.Data.Provider, "pool")
+	testutils.AssertEqual(t, *lease.Data.BlocksRemaining, int64(144))
+	testutils.AssertEqual(t, lease.Data.FeesEarned, int64(2500))
+	testutils.AssertEqual(t, lease.Data.NetReturn, int64(-7500))
+	testutils.AssertEqual(t, *lease.Data.PremiumCovered, 0.25)
+
+	var channels struct {
+		Data []ChannelInfo `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(do("GET", "/api/v1/lightning/channels", "").Body.Bytes(), &channels))
+	testutils.AssertEqual(t, len(channels.Data), 1)
+	testutils.AssertEqual(t, channels.Data[0].Lease.ExpiryHeight, int64(850000))
+
+	var leases struct {
+		Data struct {
+			BlockHeight int64            `json:"block_height"`
+			Leases      []LeaseInfo      `json:"leases"`
+			Totals      map[string]int64 `json:"totals"`
+		} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(do("GET", "/api/v1/lightning/leases", "").Body.Bytes(), &leases))
+	testutils.AssertEqual(t, leases.Data.BlockHeight, int64(849856))
+	testutils.AssertEqual(t, len(leases.Data.Leases), 1)
+	testutils.AssertEqual(t, leases.Data.Totals["premium_paid"], int64(10000))
+
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusOK)
+	testutils.AssertEqual(t, do("DELETE", path, "").Code, http.StatusNotFound)
+}
+
+// fixedSwapProvider quotes a flat fee
+type fixedSwapProvider struct {
+	name string
+	fee  int64
+}
+
+func (p fixedSwapProvider) Name() string { return p.name }
+
+func (p fixedSwapProvider) Quote(direction string, amount int64) (*swap.Quote, error) {
+	return &swap.Quote{Provider: p.name, Direction: direction, Amount: amount, ServiceFee: p.fee, TotalFee: p.fee}, nil
+}
+
+func TestSwapQuotesEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	get := func(url string) (*httptest.ResponseRecorder, swap.Comparison) {
+		req, err := http.NewRequest("GET", url, nil)
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		var response struct {
+			Data swap.Comparison `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr, response.Data
+	}
+
+	rr, _ := get("/api/v1/swaps/quotes?direction=out&amount=500000")
+	testutils.AssertEqual(t, rr.Code, http.StatusServiceUnavailable)
+
+	server.swapProviders = []swap.Provider{fixedSwapProvider{"loop", 3000}, fixedSwapProvider{"boltz", 2000}}
+	rr, comparison := get("/api/v1/swaps/quotes?direction=out&amount=500000")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, comparison.Recommended.Provider, "boltz")
+	testutils.AssertEqual(t, len(comparison.Quotes), 2)
+
+	for _, url := range []string{
+		"/api/v1/swaps/quotes?amount=500000",
+		"/api/v1/swaps/quotes?direction=sideways&amount=500000",
+		"/api/v1/swaps/quotes?direction=in",
+		"/api/v1/swaps/quotes?direction=in&amount=-5",
+		"/api/v1/swaps/quotes?channel_id=abc",
+	} {
+		rr, _ := get(url)
+		testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+	}
+
+	// A channel holding 90% local balance needs a swap out to reach half
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots([]db.ChannelSnapshot{
+		{Timestamp: time.Now(), ChannelID: "777", Capacity: 1000000, LocalBalance: 900000, RemoteBalance: 100000, Active: true},
+	}))
+	rr, comparison = get("/api/v1/swaps/quotes?channel_id=777")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, comparison.Direction, swap.DirectionOut)
+	testutils.AssertEqual(t, comparison.Amount, int64(400000))
+
+	rr, _ = get("/api/v1/swaps/quotes?channel_id=778")
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+}
+
+func TestCollectorRunsEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	for _, collector := range []string{"forwarding", "strike-balance", "forwarding"} {
+		err := server.db.RecordCollectorRun(collector, func(run *db.CollectorRun) error {
+			run.ItemsInserted = 3
+			return nil
+		})
+		testutils.AssertNoError(t, err)
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/system/collector-runs?collector=forwarding&limit=1", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Success bool              `json:"success"`
+		Data    []db.CollectorRun `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(response.Data), 1)
+	testutils.AssertEqual(t, response.Data[0].Collector, "forwarding")
+	testutils.AssertEqual(t, response.Data[0].Status, db.CollectorRunSuccess)
+
+	// Limit is bounded
+	req, err = http.NewRequest("GET", "/api/v1/system/collector-runs?limit=5000", nil)
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
 }
 
-func TestLightningFeesEndpoint(t *testing.T) {
+func TestQuarantineEndpoint(t *testing.T) {
 	server := setupTestServer(t)
 	defer server.db.Close()
 
-	req, err := http.NewRequest("GET", "/api/lightning/fees?days=7", nil)
+	_, err := server.db.InsertForwardingEventIfNew(&db.ForwardingEvent{
+		Timestamp: time.Now().Add(48 * time.Hour), ChannelInID: "111:1:0", ChannelOutID: "222:1:0", AmountIn: 1000, AmountOut: 999, Fee: 1,
+	})
+	testutils.AssertError(t, err, "record quarantined")
+
+	req, err := http.NewRequest("GET", "/api/v1/system/quarantine?source=forwarding_events", nil)
 	testutils.AssertNoError(t, err)
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusOK)
 
-	var response APIResponse
+	var response struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Counts  map[string]int64       `json:"counts"`
+			Records []db.QuarantinedRecord `json:"records"`
+		} `json:"data"`
+	}
 	err = json.Unmarshal(rr.Body.Bytes(), &response)
 	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, response.Data.Counts["forwarding_events"], int64(1))
+	testutils.AssertEqual(t, len(response.Data.Records), 1)
+	testutils.AssertEqual(t, response.Data.Records[0].Source, "forwarding_events")
 
-	testutils.AssertEqual(t, response.Success, true)
-
-	// Verify Chart.js format
-	dataMap, ok := response.Data.(map[string]interface{})
-	if !ok {
-		t.Fatal("Expected data to be a map")
-	}
-
-	// Check required Chart.js fields
-	requiredFields := []string{"labels", "datasets", "metadata"}
-	for _, field := range requiredFields {
-		if _, exists := dataMap[field]; !exists {
-			t.Errorf("Missing required Chart.js field: %s", field)
-		}
-	}
-
-	// Check metadata structure
-	metadata, ok := dataMap["metadata"].(map[string]interface{})
-	if !ok {
-		t.Fatal("Expected metadata to be a map")
-	}
+	req, err = http.NewRequest("GET", "/api/v1/system/quarantine?source=statements", nil)
+	testutils.AssertNoError(t, err)
 
-	requiredMetadata := []string{"total_fees", "total_forwards", "days_requested", "days_with_data"}
-	for _, field := range requiredMetadata {
-		if _, exists := metadata[field]; !exists {
-			t.Errorf("Missing required metadata field: %s", field)
-		}
-	}
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
 }
 
-func TestLightningForwardsEndpoint(t *testing.T) {
+func TestVersionEndpoint(t *testing.T) {
 	server := setupTestServer(t)
 	defer server.db.Close()
 
-	req, err := http.NewRequest("GET", "/api/lightning/forwards?days=7", nil)
+	server.nodes = version.Nodes{version.NodeLND: {Major: 0, Minor: 9, Patch: 2}}
+	_, err := server.db.RecordNodeVersion(version.NodeLND, "0.9.2-beta")
+	testutils.AssertNoError(t, err)
+
+	req, err := http.NewRequest("GET", "/api/v1/version", nil)
 	testutils.AssertNoError(t, err)
 
 	rr := httptest.NewRecorder()
@@ -293,36 +1391,57 @@ func TestLightningForwardsEndpoint(t *testing.T) {
 
 	testutils.AssertEqual(t, rr.Code, http.StatusOK)
 
-	var response APIResponse
+	var response struct {
+		Data struct {
+			Version  string           `json:"version"`
+			Nodes    []db.NodeVersion `json:"nodes"`
+			Features map[string]bool  `json:"features"`
+		} `json:"data"`
+	}
 	err = json.Unmarshal(rr.Body.Bytes(), &response)
 	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, response.Data.Version != "", true)
+	testutils.AssertEqual(t, len(response.Data.Nodes), 1)
+	testutils.AssertEqual(t, response.Data.Nodes[0].Version, "0.9.2-beta")
+	testutils.AssertEqual(t, response.Data.Features["mission-control"], false)
+	testutils.AssertEqual(t, response.Data.Features["descriptor-wallet"], true)
+}
 
-	testutils.AssertEqual(t, response.Success, true)
+func TestCapturesEndpoint(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
 
-	// Verify Chart.js format
-	dataMap, ok := response.Data.(map[string]interface{})
-	if !ok {
-		t.Fatal("Expected data to be a map")
+	get := func(url string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", url, nil)
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+		return rr
 	}
 
-	// Check required Chart.js fields
-	requiredFields := []string{"labels", "datasets", "metadata"}
-	for _, field := range requiredFields {
-		if _, exists := dataMap[field]; !exists {
-			t.Errorf("Missing required Chart.js field: %s", field)
-		}
-	}
+	testutils.AssertEqual(t, get("/api/v1/system/captures").Code, http.StatusNotFound)
 
-	// Check metadata structure
-	metadata, ok := dataMap["metadata"].(map[string]interface{})
-	if !ok {
-		t.Fatal("Expected metadata to be a map")
+	server.captureDir = t.TempDir()
+	store, err := capture.NewRingStore(server.captureDir, capture.DefaultMaxBytes)
+	testutils.AssertNoError(t, err)
+	for _, record := range []capture.Record{
+		{Command: "lncli", Args: []string{"fwdinghistory"}, Collector: "forwarding", RunID: 7, Output: "{}"},
+		{Command: "lncli", Args: []string{"listchannels"}, Collector: "channel-snapshots", RunID: 8, Output: "{}"},
+	} {
+		testutils.AssertNoError(t, store.Save(record))
 	}
 
-	// Should have success_rate field
-	if _, exists := metadata["success_rate"]; !exists {
-		t.Error("Missing success_rate in forwards metadata")
+	rr := get("/api/v1/system/captures?run_id=7")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response struct {
+		Data []capture.Record `json:"data"`
 	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	testutils.AssertEqual(t, len(response.Data), 1)
+	testutils.AssertEqual(t, response.Data[0].Args[0], "fwdinghistory")
+
+	testutils.AssertEqual(t, get("/api/v1/system/captures?limit=0").Code, http.StatusBadRequest)
 }
 
 func TestLightningFeesWithInvalidDays(t *testing.T) {
@@ -353,7 +1472,7 @@ func TestCORSHeaders(t *testing.T) {
 	testutils.AssertNoError(t, err)
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	// CORS may not be configured in test environment, so we accept various responses
 	// The important thing is that the server doesn't crash on OPTIONS requests
@@ -373,6 +1492,64 @@ func TestNonExistentEndpoint(t *testing.T) {
 	server.router.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+
+	// Unknown API paths still use the standard envelope
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, response.Success, false)
+	testutils.AssertEqual(t, response.Error, "Endpoint not found")
+}
+
+func TestVersionedAPIRoutes(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/v1/health", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, rr.Header().Get("API-Version"), APIVersion)
+	testutils.AssertEqual(t, rr.Header().Get("Deprecation"), "")
+}
+
+func TestLegacyAPIAliasIsDeprecated(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/portfolio/history?days=7", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, rr.Header().Get("Deprecation"), "true")
+	testutils.AssertEqual(t, rr.Header().Get("Link"), `</api/v1/portfolio/history?days=7>; rel="successor-version"`)
+}
+
+func TestAPIMethodNotAllowed(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	for _, path := range []string{"/api/v1/health", "/api/health"} {
+		req, err := http.NewRequest("DELETE", path, nil)
+		testutils.AssertNoError(t, err)
+
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+
+		testutils.AssertEqual(t, rr.Code, http.StatusMethodNotAllowed)
+		testutils.AssertEqual(t, rr.Header().Get("Allow"), "GET")
+
+		var response APIResponse
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		testutils.AssertNoError(t, err)
+		testutils.AssertEqual(t, response.Success, false)
+	}
 }
 
 func TestGetOnchainAddressesEmpty(t *testing.T) {
@@ -417,7 +1594,7 @@ func TestAddOnchainAddress(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusOK)
 
@@ -449,7 +1626,7 @@ func TestAddOnchainAddressInvalidJSON(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
 
@@ -471,7 +1648,7 @@ func TestAddOnchainAddressEmpty(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
 
@@ -496,7 +1673,7 @@ func TestAddOnchainAddressInvalidFormat(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
 
@@ -523,7 +1700,7 @@ func TestAddOnchainAddressDuplicate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 	testutils.AssertEqual(t, rr.Code, http.StatusOK)
 
 	// Try to add same address again
@@ -533,7 +1710,7 @@ func TestAddOnchainAddressDuplicate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr = httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusConflict)
 
@@ -564,7 +1741,7 @@ func TestGetOnchainAddressesWithData(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		rr := httptest.NewRecorder()
-		server.router.ServeHTTP(rr, req)
+		server.adminRouter.ServeHTTP(rr, req)
 		testutils.AssertEqual(t, rr.Code, http.StatusOK)
 	}
 
@@ -591,6 +1768,16 @@ func TestGetOnchainAddressesWithData(t *testing.T) {
 	if len(dataArray) != 2 {
 		t.Errorf("Expected 2 addresses, got %d", len(dataArray))
 	}
+
+	// Balances are reported with confirmed and unconfirmed components
+	first := dataArray[0].(map[string]interface{})
+	for _, field := range []string{"current_balance", "confirmed_balance", "unconfirmed_balance"} {
+		if _, exists := first[field]; !exists {
+			t.Errorf("Missing field: %s", field)
+		}
+	}
+	testutils.AssertEqual(t, first["confirmed_balance"].(float64)+first["unconfirmed_balance"].(float64),
+		first["current_balance"].(float64))
 }
 
 func TestDeleteOnchainAddress(t *testing.T) {
@@ -604,7 +1791,7 @@ func TestDeleteOnchainAddress(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 	testutils.AssertEqual(t, rr.Code, http.StatusOK)
 
 	var addResponse APIResponse
@@ -626,6 +1813,196 @@ func TestDeleteOnchainAddress(t *testing.T) {
 	testutils.AssertNoError(t, err)
 
 	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	testutils.AssertEqual(t, response.Success, true)
+}
+
+func TestUpdateOnchainAddress(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	address, err := server.db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Original")
+	testutils.AssertNoError(t, err)
+
+	// Pause tracking without touching the label
+	payload := `{"active": false}`
+	req, err := http.NewRequest("PUT", fmt.Sprintf("/api/onchain/addresses/%d", address.ID), strings.NewReader(payload))
+	testutils.AssertNoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	retrieved, err := server.db.GetOnchainAddressByID(address.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, retrieved.Label, "Original")
+	testutils.AssertEqual(t, retrieved.Active, false)
+
+	// Empty body changes nothing and is rejected
+	req, err = http.NewRequest("PUT", fmt.Sprintf("/api/onchain/addresses/%d", address.ID), strings.NewReader(`{}`))
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+
+	// Unknown address
+	req, err = http.NewRequest("PUT", "/api/onchain/addresses/99999", strings.NewReader(`{"label": "x"}`))
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+}
+
+func TestOnchainAddressBirthday(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	send := func(method, url, payload string) (*httptest.ResponseRecorder, APIResponse) {
+		req, err := http.NewRequest(method, url, strings.NewReader(payload))
+		testutils.AssertNoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr, response
+	}
+
+	rr, response := send("POST", "/api/onchain/addresses",
+		`{"address": "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "label": "New wallet", "birth_height": 832000, "birth_date": "2024-03-01"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	dataMap := response.Data.(map[string]interface{})
+	testutils.AssertEqual(t, dataMap["birth_height"], float64(832000))
+	testutils.AssertEqual(t, dataMap["birth_date"], "2024-03-01T00:00:00Z")
+	id := int64(dataMap["id"].(float64))
+
+	// Changing only the height keeps the date
+	rr, _ = send("PUT", fmt.Sprintf("/api/onchain/addresses/%d", id), `{"birth_height": 840000}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	retrieved, err := server.db.GetOnchainAddressByID(id)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, retrieved.BirthHeight, int64(840000))
+	testutils.AssertEqual(t, retrieved.BirthDate.Format("2006-01-02"), "2024-03-01")
+	testutils.AssertEqual(t, retrieved.Label, "New wallet")
+
+	// Clearing both scans from genesis again
+	rr, _ = send("PUT", fmt.Sprintf("/api/onchain/addresses/%d", id), `{"birth_height": 0, "birth_date": ""}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	retrieved, err = server.db.GetOnchainAddressByID(id)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, retrieved.BirthHeight, int64(0))
+	if retrieved.BirthDate != nil {
+		t.Errorf("Expected birth date to be cleared, got %v", retrieved.BirthDate)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		payload string
+		field   string
+	}{
+		{"negative height", "POST", "/api/onchain/addresses", `{"address": "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "birth_height": -1}`, "birth_height"},
+		{"malformed date", "POST", "/api/onchain/addresses", `{"address": "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "birth_date": "March 2024"}`, "birth_date"},
+		{"future date", "PUT", fmt.Sprintf("/api/onchain/addresses/%d", id), `{"birth_date": "2999-01-01"}`, "birth_date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, response := send(tt.method, tt.url, tt.payload)
+			testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+			testutils.AssertEqual(t, len(response.Errors), 1)
+			testutils.AssertEqual(t, response.Errors[0].Field, tt.field)
+		})
+	}
+}
+
+func TestOnchainAddressClass(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	send := func(method, url, payload string) (*httptest.ResponseRecorder, APIResponse) {
+		req, err := http.NewRequest(method, url, strings.NewReader(payload))
+		testutils.AssertNoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr, response
+	}
+
+	rr, response := send("POST", "/api/onchain/addresses",
+		`{"address": "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "label": "Vault", "class": "cold"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	dataMap := response.Data.(map[string]interface{})
+	testutils.AssertEqual(t, dataMap["class"], "cold")
+	id := int64(dataMap["id"].(float64))
+
+	// The listing reports the threshold the class needs
+	rr, response = send("GET", "/api/onchain/addresses", "")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var found bool
+	for _, item := range response.Data.([]interface{}) {
+		addr := item.(map[string]interface{})
+		if int64(addr["id"].(float64)) == id {
+			found = true
+			testutils.AssertEqual(t, addr["min_confirmations"], float64(6))
+		}
+	}
+	testutils.AssertEqual(t, found, true)
+
+	rr, _ = send("PUT", fmt.Sprintf("/api/onchain/addresses/%d", id), `{"class": "hot"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	retrieved, err := server.db.GetOnchainAddressByID(id)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, retrieved.Class, "hot")
+	testutils.AssertEqual(t, retrieved.Label, "Vault")
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		payload string
+	}{
+		{"unknown class on add", "POST", "/api/onchain/addresses", `{"address": "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "class": "warm"}`},
+		{"unknown class on update", "PUT", fmt.Sprintf("/api/onchain/addresses/%d", id), `{"class": "warm"}`},
+		{"empty class on update", "PUT", fmt.Sprintf("/api/onchain/addresses/%d", id), `{"class": ""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, response := send(tt.method, tt.url, tt.payload)
+			testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+			testutils.AssertEqual(t, len(response.Errors), 1)
+			testutils.AssertEqual(t, response.Errors[0].Field, "class")
+		})
+	}
+}
+
+func TestRestoreOnchainAddress(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	address, err := server.db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "Restorable")
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, server.db.DeleteOnchainAddress(address.ID))
+
+	req, err := http.NewRequest("GET", "/api/onchain/addresses/deleted", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
 	server.router.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusOK)
@@ -634,7 +2011,93 @@ func TestDeleteOnchainAddress(t *testing.T) {
 	err = json.Unmarshal(rr.Body.Bytes(), &response)
 	testutils.AssertNoError(t, err)
 
-	testutils.AssertEqual(t, response.Success, true)
+	deleted, ok := response.Data.([]interface{})
+	if !ok {
+		t.Fatal("Expected data to be an array")
+	}
+	testutils.AssertEqual(t, len(deleted), 1)
+
+	req, err = http.NewRequest("POST", fmt.Sprintf("/api/onchain/addresses/%d/restore", address.ID), nil)
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	retrieved, err := server.db.GetOnchainAddressByID(address.ID)
+	testutils.AssertNoError(t, err)
+	if retrieved == nil {
+		t.Fatal("Restored address should be visible again")
+	}
+
+	// A second restore has nothing to restore
+	req, err = http.NewRequest("POST", fmt.Sprintf("/api/onchain/addresses/%d/restore", address.ID), nil)
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+}
+
+func TestRestoreOfflineAccount(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	entry, err := server.db.InsertColdStorageEntry("Vault", 1000000, "")
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, server.db.DeleteColdStorageEntry(entry.ID))
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("/api/offline/accounts/%d/restore", entry.ID), nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	retrieved, err := server.db.GetColdStorageEntryByID(entry.ID)
+	testutils.AssertNoError(t, err)
+	if retrieved == nil {
+		t.Fatal("Restored account should be visible again")
+	}
+}
+
+func TestReAddDeletedEntities(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	post := func(path, payload string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, httptest.NewRequest("POST", path, strings.NewReader(payload)))
+		return rr
+	}
+
+	// Adding a deleted address again points to its restore endpoint
+	const payload = `{"address": "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "label": "Again"}`
+	rr := post("/api/v1/onchain/addresses", payload)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var added struct {
+		Data db.OnchainAddress `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &added))
+	testutils.AssertNoError(t, server.db.DeleteOnchainAddress(added.Data.ID))
+
+	rr = post("/api/v1/onchain/addresses", payload)
+	testutils.AssertEqual(t, rr.Code, http.StatusConflict)
+	restore := fmt.Sprintf("/api/v1/onchain/addresses/%d/restore", added.Data.ID)
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(), restore), true)
+	testutils.AssertEqual(t, post(restore, "").Code, http.StatusOK)
+	testutils.AssertEqual(t, strings.Contains(post("/api/v1/onchain/addresses", payload).Body.String(), "already being tracked"), true)
+
+	// So does a deleted account's name
+	entry, err := server.db.InsertColdStorageEntry("Vault", 1000000, "")
+	testutils.AssertNoError(t, err)
+	testutils.AssertNoError(t, server.db.DeleteColdStorageEntry(entry.ID))
+	rr = post("/api/v1/offline/accounts", `{"name": "Vault", "balance": 2000000, "verified": true}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusConflict)
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(), fmt.Sprintf("/api/v1/offline/accounts/%d/restore", entry.ID)), true)
 }
 
 func TestDeleteOnchainAddressNotFound(t *testing.T) {
@@ -646,7 +2109,7 @@ func TestDeleteOnchainAddressNotFound(t *testing.T) {
 	testutils.AssertNoError(t, err)
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
 
@@ -666,7 +2129,7 @@ func TestDeleteOnchainAddressInvalidID(t *testing.T) {
 	testutils.AssertNoError(t, err)
 
 	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	server.adminRouter.ServeHTTP(rr, req)
 
 	// The router may not match the route with non-numeric ID and return 404,
 	// or it may match and return 400. Both are acceptable for invalid input.
@@ -835,3 +2298,388 @@ func TestOnchainHistoryCustomDays(t *testing.T) {
 		t.Errorf("Expected days_requested to be 7, got %d", daysRequested)
 	}
 }
+
+func TestOnchainAddressHistoryByID(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	address, err := server.db.InsertOnchainAddress("bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "History")
+	testutils.AssertNoError(t, err)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/api/onchain/addresses/%d/history?days=7", address.ID), nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	testutils.AssertEqual(t, response.Success, true)
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected data to be a map")
+	}
+	metadata, ok := dataMap["metadata"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected metadata to be a map")
+	}
+
+	testutils.AssertEqual(t, metadata["address"], address.Address)
+	testutils.AssertEqual(t, metadata["address_id"], float64(address.ID))
+}
+
+func TestOnchainAddressHistoryByIDNotFound(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("GET", "/api/onchain/addresses/99999/history", nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+}
+
+func TestOfflineAccountHistoryByID(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	entry, err := server.db.InsertColdStorageEntry("Vault", 1000000, "")
+	testutils.AssertNoError(t, err)
+	_, err = server.db.UpdateColdStorageEntry(entry.ID, entry.Name, 2000000, "")
+	testutils.AssertNoError(t, err)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/api/offline/accounts/%d/history", entry.ID), nil)
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected data to be a map")
+	}
+	labels, ok := dataMap["labels"].([]interface{})
+	if !ok {
+		t.Fatal("Expected labels to be an array")
+	}
+	testutils.AssertEqual(t, len(labels), 1)
+
+	// Unknown accounts are rejected rather than returning an empty chart
+	req, err = http.NewRequest("GET", "/api/offline/accounts/99999/history", nil)
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+}
+
+func TestOfflineAccountCustodyMetadata(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	payload := `{"name": "Coldcard", "balance": 2000000, "verified": true, "custody_type": "hardware", "device_model": "Coldcard Mk4"}`
+	req, err := http.NewRequest("POST", "/api/offline/accounts", strings.NewReader(payload))
+	testutils.AssertNoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	_, err = server.db.InsertColdStorageEntry("Paper wallet", 500000, "")
+	testutils.AssertNoError(t, err)
+
+	req, err = http.NewRequest("GET", "/api/offline/accounts?custody_type=hardware", nil)
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	accounts, ok := response.Data.([]interface{})
+	if !ok {
+		t.Fatal("Expected data to be an array")
+	}
+	testutils.AssertEqual(t, len(accounts), 1)
+	account := accounts[0].(map[string]interface{})
+	testutils.AssertEqual(t, account["device_model"], "Coldcard Mk4")
+
+	// Unknown custody types and sort keys are rejected
+	for _, query := range []string{"custody_type=exchange", "sort=random"} {
+		req, err = http.NewRequest("GET", "/api/offline/accounts?"+query, nil)
+		testutils.AssertNoError(t, err)
+
+		rr = httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUpdateOfflineAccountMetadata(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	entry, err := server.db.InsertColdStorageEntry("Multisig vault", 5000000, "")
+	testutils.AssertNoError(t, err)
+
+	payload := `{"custody_type": "collaborative", "location_hint": "2-of-3 with provider"}`
+	req, err := http.NewRequest("PUT", fmt.Sprintf("/api/offline/accounts/%d/metadata", entry.ID), strings.NewReader(payload))
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	retrieved, err := server.db.GetColdStorageEntryByID(entry.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, retrieved.CustodyType, "collaborative")
+	testutils.AssertEqual(t, retrieved.LocationHint, "2-of-3 with provider")
+
+	req, err = http.NewRequest("PUT", "/api/offline/accounts/99999/metadata", strings.NewReader(`{}`))
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+}
+
+func TestAddOfflineAccountWithBTCUnit(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	payload := `{"name": "Documented in BTC", "amount": "0.5", "unit": "BTC", "verified": true}`
+	req, err := http.NewRequest("POST", "/api/offline/accounts", strings.NewReader(payload))
+	testutils.AssertNoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected data to be a map")
+	}
+	testutils.AssertEqual(t, dataMap["balance"], float64(50000000))
+	testutils.AssertEqual(t, dataMap["display_unit"], "BTC")
+
+	req, err = http.NewRequest("GET", "/api/offline/accounts", nil)
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	accounts, ok := response.Data.([]interface{})
+	if !ok || len(accounts) != 1 {
+		t.Fatalf("Expected one account, got %v", response.Data)
+	}
+	testutils.AssertEqual(t, accounts[0].(map[string]interface{})["display_balance"], "0.50000000 BTC")
+}
+
+func TestAddOfflineAccountInvalidAmounts(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	payloads := []string{
+		`{"name": "Fractional sats", "amount": "0.5", "unit": "sats", "verified": true}`,
+		`{"name": "Too precise", "amount": "0.123456789", "unit": "BTC", "verified": true}`,
+		`{"name": "Above supply", "amount": "2100000000000001", "unit": "sats", "verified": true}`,
+		`{"name": "Sats as BTC", "amount": "50000000", "unit": "BTC", "verified": true}`,
+		`{"name": "Unknown unit", "amount": "1", "unit": "mBTC", "verified": true}`,
+		`{"name": "Negative", "balance": -1, "verified": true}`,
+	}
+
+	for _, payload := range payloads {
+		req, err := http.NewRequest("POST", "/api/offline/accounts", strings.NewReader(payload))
+		testutils.AssertNoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", payload, rr.Code)
+		}
+	}
+}
+
+func TestPortfolioImport(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	csvBody := "date,lightning_local,cold_storage\n2020-01-01,100000,5000000\n2020-01-02,110000,5000000\n"
+
+	// Dry run validates without writing
+	req, err := http.NewRequest("POST", "/api/portfolio/import?dry_run=true", strings.NewReader(csvBody))
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	from := time.Date(2019, 12, 31, 0, 0, 0, 0, time.Local)
+	to := time.Date(2020, 1, 3, 0, 0, 0, 0, time.Local)
+	stored, err := server.db.GetBalanceSnapshots(from, to)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(stored), 0)
+
+	req, err = http.NewRequest("POST", "/api/portfolio/import", strings.NewReader(csvBody))
+	testutils.AssertNoError(t, err)
+
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	var response APIResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	testutils.AssertNoError(t, err)
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected data to be a map")
+	}
+	testutils.AssertEqual(t, dataMap["imported"], float64(2))
+
+	stored, err = server.db.GetBalanceSnapshots(from, to)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(stored), 2)
+	testutils.AssertEqual(t, stored[0].TotalPortfolio, int64(5100000))
+}
+
+func TestPortfolioImportInvalidCSV(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("POST", "/api/portfolio/import", strings.NewReader("date,balance\n2020-01-01,1\n"))
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestPortfolioImportRejectsImpossibleDates(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	req, err := http.NewRequest("POST", "/api/portfolio/import", strings.NewReader("date,lightning_local\n1970-01-01,1\n"))
+	testutils.AssertNoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, req)
+
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(), "before 2009-01-03"), true)
+}
+
+func TestLiquidBalanceEndpoints(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	get := func(url string) (*httptest.ResponseRecorder, APIResponse) {
+		req, err := http.NewRequest("GET", url, nil)
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr, response
+	}
+
+	rr, _ := get("/api/liquid/balance/current")
+	testutils.AssertEqual(t, rr.Code, http.StatusNotFound)
+
+	testutils.AssertNoError(t, server.db.InsertLiquidBalanceSnapshot(&db.LiquidBalanceSnapshot{
+		Timestamp: time.Now().Add(-time.Hour), Confirmed: 1500000, Unconfirmed: 50000,
+	}))
+
+	rr, response := get("/api/liquid/balance/current?units=btc")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	data := response.Data.(map[string]interface{})
+	testutils.AssertEqual(t, data["confirmed"], 0.015)
+	testutils.AssertEqual(t, data["total"], 0.0155)
+	testutils.AssertEqual(t, data["units"], "btc")
+
+	rr, response = get("/api/liquid/balance/history?days=7")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	chart := response.Data.(map[string]interface{})
+	testutils.AssertEqual(t, len(chart["labels"].([]interface{})), 1)
+	datasets := chart["datasets"].([]interface{})
+	total := datasets[1].(map[string]interface{})["data"].([]interface{})
+	testutils.AssertEqual(t, total[0], float64(1550000))
+}
+
+func TestEcashBalanceEndpoints(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	get := func(url string) (*httptest.ResponseRecorder, APIResponse) {
+		req, err := http.NewRequest("GET", url, nil)
+		testutils.AssertNoError(t, err)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr, response
+	}
+
+	// The federation was last collected before the requested range
+	now := time.Now().Truncate(time.Second)
+	for _, snapshot := range []db.EcashBalanceSnapshot{
+		{Timestamp: now.AddDate(0, 0, -10), Protocol: "fedimint", Mint: "fed1", Name: "Fedi", Amount: 30000},
+		{Timestamp: now.Add(-2 * time.Hour), Protocol: "cashu", Mint: "https://a.example", Name: "a.example", Amount: 1000},
+		{Timestamp: now.Add(-2 * time.Hour), Protocol: "cashu", Mint: "https://b.example", Name: "b.example", Amount: 2000},
+		{Timestamp: now.Add(-time.Hour), Protocol: "cashu", Mint: "https://a.example", Name: "a.example", Amount: 500},
+	} {
+		snapshot := snapshot
+		testutils.AssertNoError(t, server.db.InsertEcashBalanceSnapshot(&snapshot))
+	}
+
+	rr, response := get("/api/ecash/balances")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	data := response.Data.(map[string]interface{})
+	testutils.AssertEqual(t, len(data["mints"].([]interface{})), 3)
+	testutils.AssertEqual(t, data["total"], float64(32500))
+
+	rr, response = get("/api/ecash/balance/history?days=7")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	chart := response.Data.(map[string]interface{})
+	total := chart["datasets"].([]interface{})[0].(map[string]interface{})["data"].([]interface{})
+	testutils.AssertEqual(t, len(total), 2)
+	testutils.AssertEqual(t, total[0], float64(33000))
+	testutils.AssertEqual(t, total[1], float64(32500))
+}
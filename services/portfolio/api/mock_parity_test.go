@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/fixtures"
+	"github.com/brewgator/lightning-node-tools/internal/liquidity"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/swap"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+// setupMockServer returns a server wired like main's --mock mode: the mock
+// tables seeded with fixtures.Seed and node calls answered by the fixtures,
+// but without the real-time services
+func setupMockServer(t *testing.T) *Server {
+	t.Helper()
+
+	database, err := db.NewDatabaseWithMockMode(testutils.CreateTestDBPath(t), true)
+	testutils.AssertNoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	testutils.AssertNoError(t, fixtures.Seed(database))
+
+	server := &Server{
+		db:            database,
+		router:        mux.NewRouter(),
+		adminRouter:   mux.NewRouter(),
+		mockMode:      true,
+		liquidity:     liquidity.NewConfig(),
+		confirmations: bitcoin.DefaultConfirmationPolicy(),
+		captureDir:    t.TempDir(),
+		swapProviders: []swap.Provider{fixedSwapProvider{"loop", 3000}, fixedSwapProvider{"boltz", 2000}},
+		metrics:       metrics.NewRegistry(),
+	}
+	server.stream = newStreamHub(server, DefaultStreamInterval)
+	restore, err := server.useFixtureNode()
+	testutils.AssertNoError(t, err)
+	t.Cleanup(restore)
+	server.setupRoutes()
+	return server
+}
+
+// TestMockModeParity checks that every golden endpoint answers with the same
+// status and JSON schema in mock mode as against the fixture node, so the
+// frontend can be developed against --mock. Every GET route the mock server
+// registers must have a golden case, so none is left unchecked.
+func TestMockModeParity(t *testing.T) {
+	assertGoldenCasesCover(t, setupMockServer(t).router)
+
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			real := setupGoldenServer(t)
+			mock := setupMockServer(t)
+
+			serve := func(server *Server) *httptest.ResponseRecorder {
+				rr := httptest.NewRecorder()
+				req := httptest.NewRequest("GET", "/api/v1"+tc.url, nil)
+				if tc.admin {
+					server.adminRouter.ServeHTTP(rr, req)
+				} else {
+					server.router.ServeHTTP(rr, req)
+				}
+				return rr
+			}
+			realRR, mockRR := serve(real), serve(mock)
+
+			if mockRR.Code != realRR.Code {
+				t.Fatalf("mock mode answered %d, real mode %d: %s", mockRR.Code, realRR.Code, mockRR.Body.String())
+			}
+			if !strings.HasPrefix(realRR.Header().Get("Content-Type"), "application/json") {
+				return
+			}
+
+			realSchema, mockSchema := jsonSchema(t, realRR), jsonSchema(t, mockRR)
+			var diffs []string
+			for path, kind := range realSchema {
+				switch mockKind, ok := mockSchema[path]; {
+				case !ok && !inEmptyArray(mockSchema, path):
+					diffs = append(diffs, fmt.Sprintf("%s (%s) missing in mock mode", path, kind))
+				case ok && !sameKind(mockKind, kind):
+					diffs = append(diffs, fmt.Sprintf("%s is %s in mock mode, %s in real mode", path, mockKind, kind))
+				}
+			}
+			for path, kind := range mockSchema {
+				if _, ok := realSchema[path]; !ok && !inEmptyArray(realSchema, path) {
+					diffs = append(diffs, fmt.Sprintf("%s (%s) only in mock mode", path, kind))
+				}
+			}
+			sort.Strings(diffs)
+			if len(diffs) > 0 {
+				t.Errorf("mock mode response differs from real mode:\n%s", strings.Join(diffs, "\n"))
+			}
+		})
+	}
+}
+
+// jsonSchema returns the JSON type of every dotted path in a response.
+// Array elements share the path of their array with [] appended, and nulls
+// are left out, as they only say that a value is absent.
+func jsonSchema(t *testing.T, rr *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	var body interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	schema := make(map[string]string)
+	var walk func(value interface{}, path string)
+	walk = func(value interface{}, path string) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			schema[path] = "object"
+			for key, item := range v {
+				walk(item, strings.TrimPrefix(path+"."+key, "."))
+			}
+		case []interface{}:
+			schema[path] = "array"
+			if len(v) == 0 {
+				schema[path] = "empty array"
+			}
+			for _, item := range v {
+				walk(item, path+"[]")
+			}
+		case string:
+			schema[path] = "string"
+		case float64:
+			schema[path] = "number"
+		case bool:
+			schema[path] = "bool"
+		}
+	}
+	walk(body, "")
+	delete(schema, "")
+	return schema
+}
+
+// sameKind reports whether two schema types match; an empty array matches
+// any array
+func sameKind(a, b string) bool {
+	if a == "empty array" {
+		a = "array"
+	}
+	if b == "empty array" {
+		b = "array"
+	}
+	return a == b
+}
+
+// inEmptyArray reports whether path lies in an array that is empty in
+// schema, which says nothing about the shape of its elements
+func inEmptyArray(schema map[string]string, path string) bool {
+	for i := strings.Index(path, "[]"); i >= 0; {
+		if schema[path[:i]] == "empty array" {
+			return true
+		}
+		next := strings.Index(path[i+2:], "[]")
+		if next < 0 {
+			break
+		}
+		i += 2 + next
+	}
+	return false
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/multisig"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+const (
+	// DefaultFeeTarget is the confirmation target in blocks fee rates are
+	// estimated for when the request gives neither target nor fee_rate
+	DefaultFeeTarget = 6
+	// MaxFeeTarget is the longest target estimatesmartfee answers for
+	MaxFeeTarget = 1008
+	// MaxFeeRate caps fee_rate, in sat/vB
+	MaxFeeRate = 10000
+	// DefaultScanDepth and MaxScanDepth bound how many receive and change
+	// addresses of a wallet are scanned for coins
+	DefaultScanDepth = 1000
+	MaxScanDepth     = 10000
+	// multisigGapLimit is how many unused addresses a coordinator may hand
+	// out past the last used one. A coin found closer than this to the end
+	// of the scanned window means more may lie beyond it.
+	multisigGapLimit = 20
+	// MaxMultisigExportBytes limits the size of an uploaded wallet export
+	MaxMultisigExportBytes = 1 << 20
+)
+
+// MultisigSpend is a simulated spend from a multisig wallet
+type MultisigSpend struct {
+	Threshold int `json:"threshold"`
+	Cosigners int `json:"cosigners"`
+	// Coins and Balance cover every confirmed coin found in the wallet
+	Coins   int   `json:"coins"`
+	Balance int64 `json:"balance"`
+	// ScanDepth is how many receive and change addresses were scanned,
+	// more than requested when coins turned up near the end of the window
+	ScanDepth int `json:"scan_depth"`
+	// FeeTarget is the confirmation target the fee rate was estimated
+	// for; it is left out when the request set fee_rate
+	FeeTarget int `json:"fee_target,omitempty"`
+	// NextAddressIndex is an imported wallet's first receive address that
+	// has not received funds, after this scan; left out for a descriptor
+	NextAddressIndex *uint32 `json:"next_address_index,omitempty"`
+	*multisig.Spend
+}
+
+// handleGetMultisigWallets handles GET /api/onchain/multisig/wallets
+func (s *Server) handleGetMultisigWallets(w http.ResponseWriter, r *http.Request) {
+	wallets, err := s.db.GetMultisigWallets()
+	if err != nil {
+		log.Printf("handleGetMultisigWallets: failed to get wallets: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get multisig wallets")
+		return
+	}
+	if wallets == nil {
+		wallets = []db.MultisigWallet{}
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: wallets})
+}
+
+// handleImportMultisigWallet handles POST /api/onchain/multisig/wallets. The
+// export is uploaded as the file field of a multipart form, with an optional
+// name field, or sent as the request body with ?name=. Uploads are named
+// after the file unless a name is given. The format is detected as
+// multisig.ParseExport does: a Caravan or Unchained wallet config, a Coldcard
+// setup file, a Ledger wallet policy or descriptors such as Sparrow writes.
+func (s *Server) handleImportMultisigWallet(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxMultisigExportBytes)
+	name := r.URL.Query().Get("name")
+
+	var data []byte
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if errors.Is(err, http.ErrMissingFile) {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "file", Message: "file is required"})
+			return
+		}
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid multipart upload")
+			return
+		}
+		defer file.Close()
+		if data, err = io.ReadAll(file); err != nil {
+			s.writeError(w, http.StatusBadRequest, "Failed to read the uploaded file")
+			return
+		}
+		if value := r.FormValue("name"); value != "" {
+			name = value
+		}
+		if name == "" {
+			name = strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename))
+		}
+	} else if data, err = io.ReadAll(r.Body); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "name", Message: "name is required"})
+		return
+	}
+	if utf8.RuneCountInString(name) > db.MaxMultisigWalletNameLength {
+		s.writeValidationError(w, &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   "name",
+			Message: fmt.Sprintf("name must be at most %d characters", db.MaxMultisigWalletNameLength),
+		})
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "file", Message: "file is required"})
+		return
+	}
+
+	wallet, format, err := multisig.ParseExport(data)
+	var descriptor string
+	if err == nil {
+		descriptor, err = wallet.Text()
+	}
+	if err != nil {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "file", Message: fmt.Sprintf("Invalid wallet export: %v", err)})
+		return
+	}
+
+	stored := &db.MultisigWallet{Name: name, Descriptor: descriptor, Format: format}
+	if err := s.db.InsertMultisigWallet(stored); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			s.writeError(w, http.StatusConflict, fmt.Sprintf("A multisig wallet named %q already exists", name))
+			return
+		}
+		log.Printf("handleImportMultisigWallet: failed to store wallet: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to import multisig wallet")
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: stored})
+}
+
+// handleMultisigSpend handles GET /api/onchain/multisig/spend. It finds the
+// confirmed coins of the wallet, given as a descriptor or as the ID of an
+// imported one, in Bitcoin Core's UTXO set, selects enough of them to send
+// amount sats to destination and returns the size and fee of that
+// transaction at the current or given fee rate. No PSBT is built and
+// nothing is imported into the tracking wallet. An imported wallet's
+// next_address_index moves past the receive addresses found holding coins.
+func (s *Server) handleMultisigSpend(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	wallet, stored, ok := s.multisigWallet(w, query)
+	if !ok {
+		return
+	}
+	destination := query.Get("destination")
+	if destination == "" {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "destination", Message: "destination is required"})
+		return
+	}
+	amount, fieldErr := parseIntParam("amount", query.Get("amount"), 0, 1, utils.MaxSupplySats)
+	if fieldErr == nil && amount == 0 {
+		fieldErr = &FieldError{Code: ErrCodeRequired, Field: "amount", Message: "amount is required"}
+	}
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	target, fieldErr := parseIntParam("target", query.Get("target"), DefaultFeeTarget, 1, MaxFeeTarget)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	depth, fieldErr := parseIntParam("depth", query.Get("depth"), DefaultScanDepth, 1, MaxScanDepth)
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	var feeRate float64
+	var err error
+	if value := query.Get("fee_rate"); value != "" {
+		feeRate, err = strconv.ParseFloat(value, 64)
+		if err != nil || feeRate <= 0 || feeRate > MaxFeeRate {
+			s.writeValidationError(w, &FieldError{
+				Code:    ErrCodeOutOfRange,
+				Field:   "fee_rate",
+				Message: fmt.Sprintf("Invalid fee_rate. Must be a number of sat/vB above 0 and up to %d", MaxFeeRate),
+			})
+			return
+		}
+	}
+
+	if s.bitcoinClient == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Bitcoin Core not available")
+		return
+	}
+
+	result := &MultisigSpend{Threshold: wallet.Threshold, Cosigners: len(wallet.Cosigners)}
+	if feeRate == 0 {
+		if feeRate, err = s.bitcoinClient.EstimateFeeRate(target); err != nil {
+			log.Printf("handleMultisigSpend: failed to estimate fee rate: %v", err)
+			s.writeError(w, http.StatusServiceUnavailable, "No fee estimate available, set fee_rate instead")
+			return
+		}
+		result.FeeTarget = target
+	}
+
+	chains, err := wallet.ChainDescriptors()
+	if err != nil {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "descriptor", Message: fmt.Sprintf("Invalid descriptor: %v", err)})
+		return
+	}
+	if stored != nil {
+		// Scan past every address handed out so far
+		depth = min(max(depth, int(stored.NextAddressIndex)+multisigGapLimit), MaxScanDepth)
+	}
+	unspents, depth, err := s.scanMultisig(chains, depth)
+	if err != nil {
+		log.Printf("handleMultisigSpend: failed to scan the UTXO set: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to find the wallet's coins")
+		return
+	}
+	result.ScanDepth = depth
+	if stored != nil {
+		s.advanceMultisigWallet(stored, wallet, unspents)
+		result.NextAddressIndex = &stored.NextAddressIndex
+	}
+	coins := multisigCoins(unspents)
+	result.Coins = len(coins)
+	for _, coin := range coins {
+		result.Balance += coin.Amount
+	}
+
+	result.Spend, err = wallet.SimulateSpend(coins, destination, int64(amount), feeRate)
+	switch {
+	case errors.Is(err, multisig.ErrInsufficientFunds):
+		s.writeValidationError(w, &FieldError{Code: ErrCodeConflict, Field: "amount", Message: err.Error()})
+		return
+	case errors.Is(err, multisig.ErrBelowDust):
+		s.writeValidationError(w, &FieldError{Code: ErrCodeOutOfRange, Field: "amount", Message: err.Error()})
+		return
+	case err != nil:
+		s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "destination", Message: err.Error()})
+		return
+	}
+
+	s.writeJSON(w, APIResponse{Success: true, Data: result})
+}
+
+// multisigWallet reads the wallet of a request, given as a descriptor or as
+// the wallet parameter, the ID of an imported wallet, which it returns as
+// well. It writes the error response and returns false if there is none.
+func (s *Server) multisigWallet(w http.ResponseWriter, query url.Values) (*multisig.Descriptor, *db.MultisigWallet, bool) {
+	descriptor, walletID := query.Get("descriptor"), query.Get("wallet")
+	switch {
+	case descriptor != "" && walletID != "":
+		s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "wallet", Message: "Give descriptor or wallet, not both"})
+		return nil, nil, false
+	case descriptor != "":
+		wallet, err := multisig.Parse(descriptor)
+		if err != nil {
+			s.writeValidationError(w, &FieldError{Code: ErrCodeInvalid, Field: "descriptor", Message: fmt.Sprintf("Invalid descriptor: %v", err)})
+			return nil, nil, false
+		}
+		return wallet, nil, true
+	case walletID != "":
+		id, fieldErr := parseID("wallet", walletID, "wallet")
+		if fieldErr != nil {
+			s.writeValidationError(w, fieldErr)
+			return nil, nil, false
+		}
+		stored, err := s.db.GetMultisigWallet(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			s.writeError(w, http.StatusNotFound, "Multisig wallet not found")
+			return nil, nil, false
+		}
+		if err != nil {
+			log.Printf("multisigWallet: failed to get wallet %d: %v", id, err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to get multisig wallet")
+			return nil, nil, false
+		}
+		wallet, err := multisig.Parse(stored.Descriptor)
+		if err != nil {
+			log.Printf("multisigWallet: stored wallet %d has an invalid descriptor: %v", id, err)
+			s.writeError(w, http.StatusInternalServerError, "Failed to read multisig wallet")
+			return nil, nil, false
+		}
+		return wallet, stored, true
+	default:
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "descriptor", Message: "descriptor or wallet is required"})
+		return nil, nil, false
+	}
+}
+
+// scanMultisig scans the first depth addresses of each chain for coins.
+// While a coin sits within the gap limit of the end of the window, the
+// window is doubled, up to MaxScanDepth, so coins sent to addresses a
+// coordinator handed out later are not missed. It returns the coins and
+// the depth finally scanned.
+func (s *Server) scanMultisig(chains []string, depth int) ([]bitcoin.ScannedUTXO, int, error) {
+	for {
+		unspents, err := s.bitcoinClient.ScanDescriptors(chains, depth)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		highest := -1
+		for _, unspent := range unspents {
+			index, err := multisig.OutputIndex(unspent.Desc)
+			if err != nil {
+				log.Printf("Warning: no address index for coin %s:%d: %v", unspent.TxID, unspent.Vout, err)
+				continue
+			}
+			highest = max(highest, int(index))
+		}
+		if highest < depth-multisigGapLimit || depth >= MaxScanDepth {
+			return unspents, depth, nil
+		}
+
+		extended := min(max(2*depth, highest+1+multisigGapLimit), MaxScanDepth)
+		log.Printf("🔍 Multisig coins found at address %d of %d scanned, extending the scan to %d addresses", highest, depth, extended)
+		depth = extended
+	}
+}
+
+// advanceMultisigWallet moves an imported wallet's next_address_index past
+// the receive addresses at or beyond it that hold coins, which means the
+// coordinator handed them out and they were paid, and announces them
+func (s *Server) advanceMultisigWallet(stored *db.MultisigWallet, wallet *multisig.Descriptor, unspents []bitcoin.ScannedUTXO) {
+	received := map[uint32]bool{}
+	next := stored.NextAddressIndex
+	for _, unspent := range unspents {
+		change, err := wallet.OutputChange(unspent.Desc)
+		if err != nil {
+			log.Printf("Warning: no chain for coin %s:%d: %v", unspent.TxID, unspent.Vout, err)
+			continue
+		}
+		index, err := multisig.OutputIndex(unspent.Desc)
+		if err != nil || change || index < stored.NextAddressIndex {
+			continue
+		}
+		received[index] = true
+		next = max(next, index+1)
+	}
+	if next == stored.NextAddressIndex {
+		return
+	}
+
+	advanced, err := s.db.AdvanceMultisigWalletIndex(stored.ID, next)
+	if err != nil {
+		log.Printf("advanceMultisigWallet: failed to advance wallet %d to index %d: %v", stored.ID, next, err)
+		return
+	}
+	stored.NextAddressIndex = next
+	if !advanced {
+		// A concurrent scan got there first and announced the addresses
+		return
+	}
+
+	indexes := make([]uint32, 0, len(received))
+	for index := range received {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+	var lines []string
+	for _, index := range indexes {
+		address, err := wallet.Derive(index, false)
+		if err != nil {
+			log.Printf("Warning: failed to derive address %d of multisig wallet %d: %v", index, stored.ID, err)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("#%d %s", index, address.Address))
+	}
+	log.Printf("🔍 Multisig wallet %q received funds at %d new receive addresses, next address index is %d", stored.Name, len(indexes), next)
+	if s.notify != nil {
+		go s.notify(fmt.Sprintf("🔍 <b>Multisig wallet %s received funds</b>\n%s\nNext address index: %d",
+			html.EscapeString(stored.Name), strings.Join(lines, "\n"), next))
+	}
+}
+
+// multisigCoins converts scanned outputs to coins in sats
+func multisigCoins(unspents []bitcoin.ScannedUTXO) []multisig.Coin {
+	coins := make([]multisig.Coin, 0, len(unspents))
+	for _, unspent := range unspents {
+		coins = append(coins, multisig.Coin{
+			TxID:   unspent.TxID,
+			Vout:   unspent.Vout,
+			Amount: int64(math.Round(unspent.Amount * utils.SatsPerBTC)),
+		})
+	}
+	return coins
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/fixtures"
+	"github.com/brewgator/lightning-node-tools/internal/multisig"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestMultisigSpend(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	spend := func(query string) (*httptest.ResponseRecorder, APIResponse) {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/onchain/multisig/spend?"+query, nil))
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr, response
+	}
+	wallet := "descriptor=" + url.QueryEscape(fixtures.MultisigDescriptor) + "&destination=bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+
+	for _, tc := range []struct{ query, field string }{
+		{"destination=bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4&amount=1000", "descriptor"},
+		{"descriptor=wpkh(xpub)&destination=bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4&amount=1000", "descriptor"},
+		{wallet, "amount"},
+		{wallet + "&amount=1000&fee_rate=0", "fee_rate"},
+		{wallet + "&amount=1000&target=2000", "target"},
+	} {
+		rr, response := spend(tc.query)
+		testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+		testutils.AssertEqual(t, response.Errors[0].Field, tc.field)
+	}
+
+	// Mock mode has no Bitcoin Core to find coins with
+	rr, _ := spend(wallet + "&amount=1000")
+	testutils.AssertEqual(t, rr.Code, http.StatusServiceUnavailable)
+
+	t.Cleanup(bitcoin.SetRunner(fixtures.BitcoinRunner))
+	server.bitcoinClient = &bitcoin.Client{}
+
+	// The wallet holds 2,900,000 sats in two coins
+	rr, response := spend(wallet + "&amount=2900000&fee_rate=2")
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, response.Errors[0].Code, ErrCodeConflict)
+	rr, response = spend(wallet + "&amount=100")
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, response.Errors[0].Code, ErrCodeOutOfRange)
+	rr, response = spend(wallet[:len(wallet)-4] + "aaaa&amount=1000")
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, response.Errors[0].Field, "destination")
+
+	// One coin is enough; with fee_rate set there is no fee target
+	rr, response = spend(wallet + "&amount=1000000&fee_rate=2.5")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	data := response.Data.(map[string]interface{})
+	testutils.AssertEqual(t, len(data["inputs"].([]interface{})), 1)
+	testutils.AssertEqual(t, data["vsize"], 189.0)
+	testutils.AssertEqual(t, data["fee"], 473.0)
+	testutils.AssertEqual(t, data["change"], 2500000.0-1000000-473)
+	testutils.AssertEqual(t, data["fee_target"], nil)
+	testutils.AssertEqual(t, data["scan_depth"], float64(DefaultScanDepth))
+
+	// Change address 2 is within the gap limit of a 10 address window, so
+	// the scan goes on until 20 unused addresses follow it
+	rr, response = spend(wallet + "&amount=1000000&fee_rate=2.5&depth=10")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, response.Data.(map[string]interface{})["scan_depth"], 23.0)
+}
+
+func TestImportMultisigWallet(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	upload := func(router http.Handler, filename, name, export string) (*httptest.ResponseRecorder, APIResponse) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		if name != "" {
+			testutils.AssertNoError(t, form.WriteField("name", name))
+		}
+		file, err := form.CreateFormFile("file", filename)
+		testutils.AssertNoError(t, err)
+		file.Write([]byte(export))
+		testutils.AssertNoError(t, form.Close())
+
+		req := httptest.NewRequest("POST", "/api/v1/onchain/multisig/wallets", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr, response
+	}
+	post := func(query, export string) (*httptest.ResponseRecorder, APIResponse) {
+		rr := httptest.NewRecorder()
+		server.adminRouter.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/onchain/multisig/wallets?"+query, strings.NewReader(export)))
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr, response
+	}
+
+	// The fixture wallet as Caravan exports it
+	wallet, err := multisig.Parse(fixtures.MultisigDescriptor)
+	testutils.AssertNoError(t, err)
+	var keys []string
+	for _, c := range wallet.Cosigners {
+		keys = append(keys, `{"name": "Key", "bip32Path": "m/48'/0'/0'/2'", "xpub": "`+c.XPub+`", "xfp": "`+c.Fingerprint+`"}`)
+	}
+	caravan := `{"name": "Vault", "addressType": "P2WSH", "network": "mainnet",
+		"quorum": {"requiredSigners": 2, "totalSigners": 3}, "extendedPublicKeys": [` + strings.Join(keys, ",") + `]}`
+
+	// Only the admin listener takes imports
+	rr, _ := upload(server.router, "vault.json", "", caravan)
+	testutils.AssertEqual(t, rr.Code, http.StatusForbidden)
+
+	// An upload is named after its file and stored as a descriptor
+	rr, response := upload(server.adminRouter, "vault.json", "", caravan)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	data := response.Data.(map[string]interface{})
+	testutils.AssertEqual(t, data["name"], "vault")
+	testutils.AssertEqual(t, data["format"], multisig.FormatCaravan)
+	testutils.AssertEqual(t, data["descriptor"], fixtures.MultisigDescriptor)
+	id := int64(data["id"].(float64))
+
+	// A descriptor sent as the request body is detected too
+	rr, response = post("name=Sparrow+copy", "# Receive and change descriptor (BIP389):\n"+fixtures.MultisigDescriptor+"\n")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, response.Data.(map[string]interface{})["format"], multisig.FormatDescriptor)
+
+	rr, _ = upload(server.adminRouter, "other.json", "vault", caravan)
+	testutils.AssertEqual(t, rr.Code, http.StatusConflict)
+	for _, tc := range []struct{ query, export, field string }{
+		{"", caravan, "name"},
+		{"name=Empty", " \n", "file"},
+		{"name=Broken", `{"quorum": {"requiredSigners": 2}`, "file"},
+		{"name=Nested", strings.Replace(caravan, "P2WSH", "P2SH-P2WSH", 1), "file"},
+		{"name=" + strings.Repeat("x", db.MaxMultisigWalletNameLength+1), caravan, "name"},
+	} {
+		rr, response := post(tc.query, tc.export)
+		testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+		testutils.AssertEqual(t, response.Errors[0].Field, tc.field)
+	}
+
+	rr = httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/onchain/multisig/wallets", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var list APIResponse
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	testutils.AssertEqual(t, len(list.Data.([]interface{})), 2)
+
+	// Spends can name the imported wallet instead of giving its descriptor
+	t.Cleanup(bitcoin.SetRunner(fixtures.BitcoinRunner))
+	server.bitcoinClient = &bitcoin.Client{}
+	spend := func(query string) int {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/onchain/multisig/spend?destination=bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4&amount=1000000&fee_rate=2.5&"+query, nil))
+		return rr.Code
+	}
+	testutils.AssertEqual(t, spend(fmt.Sprintf("wallet=%d", id)), http.StatusOK)
+	testutils.AssertEqual(t, spend(fmt.Sprintf("wallet=%d", id+100)), http.StatusNotFound)
+	testutils.AssertEqual(t, spend(fmt.Sprintf("wallet=%d&descriptor=%s", id, url.QueryEscape(fixtures.MultisigDescriptor))), http.StatusBadRequest)
+}
+
+func TestMultisigSpendAdvancesNextAddressIndex(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+	t.Cleanup(bitcoin.SetRunner(fixtures.BitcoinRunner))
+	server.bitcoinClient = &bitcoin.Client{}
+	notified := make(chan string, 1)
+	server.notify = func(message string) { notified <- message }
+
+	stored := &db.MultisigWallet{Name: "Savings", Descriptor: fixtures.MultisigDescriptor, Format: multisig.FormatDescriptor}
+	testutils.AssertNoError(t, server.db.InsertMultisigWallet(stored))
+	spend := func() map[string]interface{} {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", fmt.Sprintf("/api/v1/onchain/multisig/spend?wallet=%d&destination=bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4&amount=1000000&fee_rate=2.5", stored.ID), nil))
+		testutils.AssertEqual(t, rr.Code, http.StatusOK)
+		var response APIResponse
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response.Data.(map[string]interface{})
+	}
+
+	// Receive address 0 holds a coin; the one on change address 2 does not
+	// count
+	testutils.AssertEqual(t, spend()["next_address_index"], 1.0)
+	wallet, err := multisig.Parse(fixtures.MultisigDescriptor)
+	testutils.AssertNoError(t, err)
+	address, err := wallet.Derive(0, false)
+	testutils.AssertNoError(t, err)
+	message := <-notified
+	if !strings.Contains(message, "Savings") || !strings.Contains(message, "#0 "+address.Address) {
+		t.Fatalf("expected the notification to name the wallet and address 0, got %q", message)
+	}
+	stored, err = server.db.GetMultisigWallet(stored.ID)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, stored.NextAddressIndex, uint32(1))
+
+	// Seen addresses are not announced again, and the index never moves back
+	testutils.AssertEqual(t, spend()["next_address_index"], 1.0)
+	_, err = server.db.AdvanceMultisigWalletIndex(stored.ID, 5)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, spend()["next_address_index"], 5.0)
+	select {
+	case message := <-notified:
+		t.Fatalf("unexpected notification %q", message)
+	default:
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/brewgator/lightning-node-tools/internal/apikeys"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/version"
+
+	"github.com/gorilla/mux"
+)
+
+// One deployment can serve several portfolios, e.g. personal and business
+// funds. Each profile is a database of its own (see db.ProfilePath) and is
+// selected by the API key a request carries, so a key only ever reaches its
+// own profile's data. Without --api-keys the API serves the --db database to
+// anyone, as before.
+
+// apiKeyHeader carries the API key of a request; an "Authorization: Bearer"
+// header may carry it instead
+const apiKeyHeader = "X-API-Key"
+
+// apiKeys maps each API key to its line of the key file
+type apiKeys map[string]apikeys.Key
+
+// loadAPIKeys reads a key file (see package apikeys)
+func loadAPIKeys(path string) (apiKeys, error) {
+	list, err := apikeys.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := apiKeys{}
+	for _, key := range list {
+		keys[key.Key] = key
+	}
+	return keys, nil
+}
+
+// profiles returns every profile that has a key, sorted
+func (k apiKeys) profiles() []string {
+	seen := map[string]bool{}
+	var profiles []string
+	for _, key := range k {
+		if !seen[key.Profile] {
+			seen[key.Profile] = true
+			profiles = append(profiles, key.Profile)
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// lookup returns the key r carries. Every key is compared in constant time
+// so response times do not leak key prefixes.
+func (k apiKeys) lookup(r *http.Request) (apikeys.Key, bool) {
+	given := []byte(requestKey(r))
+	var selected apikeys.Key
+	found := false
+	for secret, key := range k {
+		if subtle.ConstantTimeCompare(given, []byte(secret)) == 1 {
+			selected, found = key, true
+		}
+	}
+	return selected, found
+}
+
+// requestKey returns the key in the X-API-Key header, or else the bearer
+// token of the Authorization header
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// needsKey reports whether a request reads profile data. Static files, the
+// health check and channel requests, which other nodes submit without ever
+// holding a key, are served without a key, by the default profile.
+func needsKey(r *http.Request) bool {
+	path := r.URL.Path
+	if path != LegacyAPIPrefix && !strings.HasPrefix(path, LegacyAPIPrefix+"/") {
+		return false
+	}
+	switch path {
+	case APIPrefix + "/health", LegacyAPIPrefix + "/health":
+		return false
+	case APIPrefix + "/channel-requests", LegacyAPIPrefix + "/channel-requests":
+		return r.Method != http.MethodPost
+	}
+	return true
+}
+
+// keyring holds the API keys in force. Reloading the key file, on SIGHUP,
+// replaces them without a restart.
+type keyring struct {
+	mu   sync.RWMutex
+	keys apiKeys
+}
+
+func newKeyring(keys apiKeys) *keyring {
+	return &keyring{keys: keys}
+}
+
+// current returns the keys in force
+func (k *keyring) current() apiKeys {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys
+}
+
+// reload replaces the keys with those of the key file at path. Keys of
+// profiles outside served are left out, as serving a new profile needs a
+// restart; they are returned for the caller to report.
+func (k *keyring) reload(path string, served map[string]*Server) (skipped []apikeys.Key, err error) {
+	keys, err := loadAPIKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	for secret, key := range keys {
+		if served[key.Profile] == nil {
+			skipped = append(skipped, key)
+			delete(keys, secret)
+		}
+	}
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return skipped, nil
+}
+
+// reloadKeysOnHangup reloads the key file at path on every SIGHUP. A file
+// that fails to load keeps the keys in force.
+func reloadKeysOnHangup(ring *keyring, path string, served map[string]*Server) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		skipped, err := ring.reload(path, served)
+		if err != nil {
+			log.Printf("⚠️  Keeping the API keys in force, reloading %s failed: %v", path, err)
+			continue
+		}
+		for _, key := range skipped {
+			log.Printf("⚠️  Ignoring key %s of profile %s until a restart serves the profile", key.Redacted(), key.Profile)
+		}
+		log.Printf("🔑 Reloaded API keys from %s", path)
+	}
+}
+
+// client identifies the sender of r for rate limiting: its API key when it
+// carries a known one, otherwise its IP, so made-up keys share one allowance
+func (k *keyring) client(r *http.Request) string {
+	if key, ok := k.current().lookup(r); ok {
+		return "key:" + key.Key
+	}
+	return clientOf(r)
+}
+
+// handler routes every request to the profile its key selects, using the
+// router listener picks from that profile's server. Read keys are refused
+// anything but reading.
+func (k *keyring) handler(servers map[string]*Server, listener func(*Server) *mux.Router) http.Handler {
+	fallback := servers[db.DefaultProfile]
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !needsKey(r) {
+			listener(fallback).ServeHTTP(w, r)
+			return
+		}
+		key, ok := k.current().lookup(r)
+		if !ok {
+			fallback.writeError(w, http.StatusUnauthorized, "Missing or unknown API key in the "+apiKeyHeader+" or Authorization header")
+			return
+		}
+		if !key.CanWrite() && !readOnlyMethod(r.Method) {
+			fallback.writeError(w, http.StatusForbidden, "This API key may only read")
+			return
+		}
+		listener(servers[key.Profile]).ServeHTTP(w, r)
+	})
+}
+
+// readOnlyMethod reports whether method only reads
+func readOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// forProfile returns a copy of s serving database. Only the default profile
+// is backed by the node: the copy leaves out LND and real-time balances,
+// which would mix the node's funds into another portfolio.
+func (s *Server) forProfile(database *db.Database) *Server {
+	profile := *s
+	profile.db = database
+	profile.router = mux.NewRouter()
+	profile.adminRouter = mux.NewRouter()
+	profile.balanceService = nil
+	profile.realtimeService = nil
+	profile.lndClient = nil
+	profile.bitcoinClient = nil
+	profile.blockHeight = nil
+	profile.openChannel = nil
+	profile.channelRequests = nil
+	profile.nodes = version.Nodes{}
+	if s.stream != nil {
+		profile.stream = newStreamHub(&profile, s.stream.interval)
+	}
+	profile.setupRoutes()
+	return &profile
+}
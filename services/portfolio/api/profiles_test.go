@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/apikeys"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	personalKey = "personal-0123456789abcdef"
+	businessKey = "business-0123456789abcdef"
+)
+
+func writeKeyFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "api-keys")
+	testutils.AssertNoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	keys, err := loadAPIKeys(writeKeyFile(t, "# personal funds\ndefault "+personalKey+"\n\nbusiness "+businessKey+"\nbusiness business-second-key-0001\n"))
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(keys), 3)
+	testutils.AssertEqual(t, strings.Join(keys.profiles(), ","), "business,default")
+
+	for _, tc := range []struct{ content, want string }{
+		{"business", "line 1: want"},
+		{"Business " + businessKey, "line 1: invalid profile"},
+		{"business short", "shorter than 16 characters"},
+		{"default " + personalKey + "\nbusiness " + personalKey, "line 2: key is already used by profile default"},
+		{"# nothing yet\n", "has no API keys"},
+	} {
+		_, err := loadAPIKeys(writeKeyFile(t, tc.content))
+		testutils.AssertError(t, err, tc.want)
+	}
+}
+
+func TestProfilesAreIsolated(t *testing.T) {
+	personal := setupTestServer(t)
+	defer personal.db.Close()
+	businessDB, err := db.NewDatabase(testutils.CreateTestDBPath(t))
+	testutils.AssertNoError(t, err)
+	defer businessDB.Close()
+
+	servers := map[string]*Server{db.DefaultProfile: personal, "business": personal.forProfile(businessDB)}
+	keys := newKeyring(apiKeys{
+		personalKey: {Profile: db.DefaultProfile, Key: personalKey, Scope: apikeys.ScopeWrite},
+		businessKey: {Profile: "business", Key: businessKey, Scope: apikeys.ScopeWrite},
+	})
+	public := keys.handler(servers, func(s *Server) *mux.Router { return s.router })
+	admin := keys.handler(servers, func(s *Server) *mux.Router { return s.adminRouter })
+
+	serve := func(handler http.Handler, method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The business key adds an address to the business profile only
+	const payload = `{"address": "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh", "label": "Treasury"}`
+	testutils.AssertEqual(t, serve(admin, "POST", "/api/v1/onchain/addresses", businessKey, payload).Code, http.StatusOK)
+
+	business, err := businessDB.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(business), 1)
+	own, err := personal.db.GetOnchainAddresses()
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(own), 0)
+
+	rr := serve(public, "GET", "/api/v1/onchain/addresses", personalKey, "")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(), "Treasury"), false)
+	rr = serve(public, "GET", "/api/onchain/addresses", businessKey, "")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(), "Treasury"), true)
+
+	// Profile data needs a known key on both listeners
+	testutils.AssertEqual(t, serve(public, "GET", "/api/v1/onchain/addresses", "", "").Code, http.StatusUnauthorized)
+	testutils.AssertEqual(t, serve(admin, "GET", "/api/v1/system/collector-runs", "not-a-known-key-at-all", "").Code, http.StatusUnauthorized)
+
+	// The health check and static files do not
+	testutils.AssertEqual(t, serve(public, "GET", "/api/v1/health", "", "").Code, http.StatusOK)
+	testutils.AssertEqual(t, serve(public, "GET", "/index.html", "", "").Code == http.StatusUnauthorized, false)
+}
+
+func TestKeyScopesAndReload(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	const readKey = "dashboard-0123456789abcdef"
+	path := writeKeyFile(t, "default "+personalKey+"\ndefault "+readKey+" read\n")
+	keys, err := loadAPIKeys(path)
+	testutils.AssertNoError(t, err)
+	servers := map[string]*Server{db.DefaultProfile: server}
+	ring := newKeyring(keys)
+	admin := ring.handler(servers, func(s *Server) *mux.Router { return s.adminRouter })
+
+	serve := func(method, path, header, value string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"address": "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh"}`))
+		req.Header.Set(header, value)
+		rr := httptest.NewRecorder()
+		admin.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// A read key reads, in either header, but may not change data
+	testutils.AssertEqual(t, serve("GET", "/api/v1/onchain/addresses", "Authorization", "Bearer "+readKey), http.StatusOK)
+	testutils.AssertEqual(t, serve("GET", "/api/v1/onchain/addresses", apiKeyHeader, readKey), http.StatusOK)
+	testutils.AssertEqual(t, serve("POST", "/api/v1/onchain/addresses", "Authorization", "Bearer "+readKey), http.StatusForbidden)
+	testutils.AssertEqual(t, serve("POST", "/api/v1/onchain/addresses", "Authorization", "Bearer "+personalKey), http.StatusOK)
+	testutils.AssertEqual(t, serve("GET", "/api/v1/onchain/addresses", "Authorization", "Basic "+readKey), http.StatusUnauthorized)
+
+	// Reloading drops removed keys and ignores those of profiles not served
+	_, err = apikeys.Remove(path, readKey[:8])
+	testutils.AssertNoError(t, err)
+	added, err := apikeys.Add(path, "business", apikeys.ScopeRead)
+	testutils.AssertNoError(t, err)
+	skipped, err := ring.reload(path, servers)
+	testutils.AssertNoError(t, err)
+	testutils.AssertEqual(t, len(skipped), 1)
+	testutils.AssertEqual(t, skipped[0].Key, added.Key)
+	testutils.AssertEqual(t, serve("GET", "/api/v1/onchain/addresses", "Authorization", "Bearer "+readKey), http.StatusUnauthorized)
+	testutils.AssertEqual(t, serve("GET", "/api/v1/onchain/addresses", "Authorization", "Bearer "+added.Key), http.StatusUnauthorized)
+}
+
+func TestRateLimited(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	ring := newKeyring(apiKeys{personalKey: {Profile: db.DefaultProfile, Key: personalKey, Scope: apikeys.ScopeRead}})
+	handler := server.rateLimited(server.router, 2, ring.client)
+	serve := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	testutils.AssertEqual(t, serve("/api/v1/portfolio/current", personalKey).Code, http.StatusOK)
+	testutils.AssertEqual(t, serve("/api/v1/portfolio/current", personalKey).Code, http.StatusOK)
+	rr := serve("/api/v1/portfolio/current", personalKey)
+	testutils.AssertEqual(t, rr.Code, http.StatusTooManyRequests)
+	testutils.AssertEqual(t, rr.Header().Get("Retry-After"), "60")
+
+	// Unknown keys count against the client's IP, the health check not at all
+	testutils.AssertEqual(t, serve("/api/v1/portfolio/current", "made-up-key-0000000001").Code, http.StatusOK)
+	testutils.AssertEqual(t, serve("/api/v1/portfolio/current", "made-up-key-0000000002").Code, http.StatusOK)
+	testutils.AssertEqual(t, serve("/api/v1/portfolio/current", "made-up-key-0000000003").Code, http.StatusTooManyRequests)
+	testutils.AssertEqual(t, serve("/api/v1/health", "").Code, http.StatusOK)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRateLimit is how many API requests one client may make per minute
+// on the public listener
+const DefaultRateLimit = 600
+
+// rateLimitWindow is the window DefaultRateLimit and --rate-limit count in
+const rateLimitWindow = time.Minute
+
+// rateLimited answers API requests beyond limit per window from one client
+// with 429. client names the sender of a request, e.g. its API key. Static
+// files, the health check and channel requests, which have their own limit,
+// are not counted.
+func (s *Server) rateLimited(next http.Handler, limit int, client func(*http.Request) string) http.Handler {
+	limiter := newClientLimiter(limit, rateLimitWindow)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if needsKey(r) && !limiter.allow(client(r)) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(rateLimitWindow.Seconds())))
+			s.writeError(w, http.StatusTooManyRequests, "Too many requests, try again later")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
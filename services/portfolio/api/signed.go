@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/signing"
+	"github.com/gorilla/websocket"
+)
+
+// Values of --sign
+const (
+	signWithKey  = "key"
+	signWithNode = "node"
+)
+
+// newSigner returns the signer --sign asks for; nil when responses are not
+// signed
+func newSigner(mode, keyPath string) (signing.Signer, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case signWithKey:
+		return signing.LoadOrCreateKey(keyPath)
+	case signWithNode:
+		return signing.NewNodeSigner()
+	default:
+		return nil, fmt.Errorf("%q is not %s or %s", mode, signWithKey, signWithNode)
+	}
+}
+
+// signed adds a detached signature of the body to API responses, see
+// signing.Message. A response that fails to be signed is still served,
+// without the signature headers. WebSocket streams and responses flushed
+// while being written, such as exports, are not signed.
+func signed(next http.Handler, signer signing.Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !needsKey(r) || websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sr := &signedResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sr, r)
+		if sr.streaming {
+			return
+		}
+
+		timestamp := time.Now().Unix()
+		signature, err := signer.Sign(signing.Message(timestamp, r.URL.RequestURI(), sr.body.Bytes()))
+		if err != nil {
+			log.Printf("⚠️  Warning: failed to sign response to %s: %v", r.URL.Path, err)
+		} else {
+			header := w.Header()
+			header.Set(signing.HeaderSignature, signature)
+			header.Set(signing.HeaderScheme, signer.Scheme())
+			header.Set(signing.HeaderKey, signer.PublicKey())
+			header.Set(signing.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		}
+		w.WriteHeader(sr.status)
+		w.Write(sr.body.Bytes())
+	})
+}
+
+// signedResponse holds a response back until it is signed
+type signedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	// streaming is set once the handler flushes; the response then goes
+	// straight to the client unsigned
+	streaming bool
+}
+
+func (s *signedResponse) WriteHeader(status int) {
+	if s.streaming {
+		s.ResponseWriter.WriteHeader(status)
+		return
+	}
+	s.status = status
+}
+
+func (s *signedResponse) Write(b []byte) (int, error) {
+	if s.streaming {
+		return s.ResponseWriter.Write(b)
+	}
+	return s.body.Write(b)
+}
+
+// FlushError sends what was held back and lets the rest of the response through
+func (s *signedResponse) FlushError() error {
+	if !s.streaming {
+		s.streaming = true
+		s.ResponseWriter.WriteHeader(s.status)
+		if _, err := s.ResponseWriter.Write(s.body.Bytes()); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(s.ResponseWriter).Flush()
+}
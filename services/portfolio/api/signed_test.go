@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/signing"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestSigned(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	signer, err := newSigner(signWithKey, filepath.Join(t.TempDir(), "signing.key"))
+	testutils.AssertNoError(t, err)
+	handler := signed(server.router, signer)
+	serve := func(url string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+		return rr
+	}
+
+	rr := serve("/api/v1/portfolio/current?units=btc")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, rr.Header().Get(signing.HeaderScheme), signing.SchemeEd25519)
+	testutils.AssertNoError(t, signing.Verify(rr.Header(), "/api/v1/portfolio/current?units=btc", rr.Body.Bytes(), signer.PublicKey()))
+	// The signature names the request, so it cannot be passed off as another's
+	testutils.AssertError(t, signing.Verify(rr.Header(), "/api/v1/portfolio/current", rr.Body.Bytes(), signer.PublicKey()), "does not match")
+
+	// Errors are signed with their status kept
+	rr = serve("/api/v1/portfolio/history?days=-1")
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+	testutils.AssertNoError(t, signing.Verify(rr.Header(), "/api/v1/portfolio/history?days=-1", rr.Body.Bytes(), signer.PublicKey()))
+
+	// Responses flushed part way, like long exports, go out unsigned
+	streamed := signed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		testutils.AssertNoError(t, http.NewResponseController(w).Flush())
+		w.Write([]byte(" second"))
+	}), signer)
+	rr = httptest.NewRecorder()
+	streamed.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/lightning/forwards/export", nil))
+	testutils.AssertEqual(t, rr.Body.String(), "first second")
+	testutils.AssertEqual(t, rr.Header().Get(signing.HeaderSignature), "")
+
+	_, err = newSigner("pgp", "")
+	testutils.AssertError(t, err, `"pgp" is not key or node`)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// metricsNamespace prefixes the API's Prometheus metric names
+const metricsNamespace = "portfolio_api"
+
+// routeTemplate names a matched request by its route template, so requests
+// to /api/v1/onchain/addresses/3 and /5 count toward the same endpoint
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// handleSystemStats handles GET /api/system/stats, the per-endpoint request
+// counts, error rates and latencies since the API started, slowest first.
+// The same counters are scraped by Prometheus at /metrics on the admin
+// listener.
+func (s *Server) handleSystemStats(w http.ResponseWriter, r *http.Request) {
+	started := s.metrics.Started()
+	s.writeJSON(w, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"started_at":     started.UTC(),
+			"uptime_seconds": int64(time.Since(started).Seconds()),
+			"routes":         s.metrics.Stats(),
+		},
+	})
+}
+
+// registerBalanceMetrics exposes the latest balance snapshot as gauges, read
+// from the database on every scrape
+func (s *Server) registerBalanceMetrics() {
+	balance := s.metrics.NewGauge("balance_sats",
+		"Balances of the latest snapshot in sats, by kind", "kind")
+	snapshotTime := s.metrics.NewGauge("balance_snapshot_timestamp_seconds",
+		"When the latest balance snapshot was taken, in unix seconds")
+	s.metrics.OnScrape(func() {
+		snapshot, err := s.db.GetLatestBalanceSnapshot()
+		if err != nil {
+			log.Printf("⚠️  Failed to read the latest balance snapshot for metrics: %v", err)
+			return
+		}
+		if snapshot == nil {
+			return
+		}
+		for kind, sats := range map[string]int64{
+			"lightning_local":     snapshot.LightningLocal,
+			"lightning_remote":    snapshot.LightningRemote,
+			"onchain_confirmed":   snapshot.OnchainConfirmed,
+			"onchain_unconfirmed": snapshot.OnchainUnconfirmed,
+			"tracked_addresses":   snapshot.TrackedAddresses,
+			"cold_storage":        snapshot.ColdStorage,
+			"total_portfolio":     snapshot.TotalPortfolio,
+			"total_liquid":        snapshot.TotalLiquid,
+		} {
+			balance.Set(float64(sats), kind)
+		}
+		snapshotTime.Set(float64(snapshot.Timestamp.Unix()))
+	})
+}
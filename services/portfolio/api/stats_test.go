@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestSystemStats(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	serve := func(router http.Handler, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	serve(server.router, "/api/v1/onchain/addresses/1/history")
+	serve(server.router, "/api/v1/onchain/addresses/2/history")
+	serve(server.router, "/api/v1/lightning/fees?days=abc")
+	// Refused on the public listener, and counted there as a client error
+	testutils.AssertEqual(t, serve(server.router, "/api/v1/system/stats").Code, http.StatusForbidden)
+
+	rr := serve(server.adminRouter, "/api/v1/system/stats")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var response struct {
+		Data struct {
+			Routes []metrics.RouteStats `json:"routes"`
+		} `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+	byRoute := make(map[string]metrics.RouteStats)
+	for _, route := range response.Data.Routes {
+		byRoute[route.Route] = route
+	}
+	testutils.AssertEqual(t, len(byRoute), 3)
+	testutils.AssertEqual(t, byRoute["/api/v1/onchain/addresses/{id:[0-9]+}/history"].Requests, uint64(2))
+	testutils.AssertEqual(t, byRoute["/api/v1/lightning/fees"].ClientErrors, uint64(1))
+	testutils.AssertEqual(t, byRoute["/api/v1/system/stats"].ClientErrors, uint64(1))
+
+	// Prometheus scrapes the admin listener only
+	rr = serve(server.adminRouter, "/metrics")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	testutils.AssertEqual(t, strings.Contains(rr.Body.String(),
+		`portfolio_api_http_requests_total{method="GET",route="/api/v1/onchain/addresses/{id:[0-9]+}/history"} 2`), true)
+	testutils.AssertEqual(t, serve(server.router, "/metrics").Code, http.StatusNotFound)
+}
+
+func TestBalanceMetrics(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+	server.registerBalanceMetrics()
+
+	rr := httptest.NewRecorder()
+	server.adminRouter.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+
+	snapshot, err := server.db.GetLatestBalanceSnapshot()
+	testutils.AssertNoError(t, err)
+	for _, line := range []string{
+		"# TYPE portfolio_api_balance_sats gauge",
+		fmt.Sprintf(`portfolio_api_balance_sats{kind="total_portfolio"} %d`, snapshot.TotalPortfolio),
+		fmt.Sprintf(`portfolio_api_balance_sats{kind="lightning_local"} %d`, snapshot.LightningLocal),
+		fmt.Sprintf("portfolio_api_balance_snapshot_timestamp_seconds %d", snapshot.Timestamp.Unix()),
+	} {
+		if !strings.Contains(rr.Body.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/gorilla/websocket"
+)
+
+// DefaultStreamInterval is how often /ws looks for updates while clients
+// are connected
+const DefaultStreamInterval = 15 * time.Second
+
+// Types of the messages pushed on /ws
+const (
+	StreamPortfolio = "portfolio"
+	StreamForward   = "forward"
+	StreamChannel   = "channel"
+)
+
+const (
+	// streamForwardBatch caps the forwarding events read per poll; the rest
+	// follow on the next one
+	streamForwardBatch = 500
+	// streamSendBuffer is how many messages may queue for a client before it
+	// is disconnected as too slow
+	streamSendBuffer = 64
+	// streamWriteWait is how long writing one message may take
+	streamWriteWait = 10 * time.Second
+	// streamPongWait is how long a client may stay silent; it is pinged at
+	// nine tenths of that
+	streamPongWait = 60 * time.Second
+)
+
+// StreamMessage is one update pushed on /ws. Data is the current portfolio
+// in the requested units for portfolio messages, a db.ForwardingEvent for
+// forward messages and a ChannelUpdate for channel messages.
+type StreamMessage struct {
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// ChannelUpdate is a channel whose state changed since the previous poll,
+// or that was closed, in which case it holds the last state seen
+type ChannelUpdate struct {
+	db.ChannelSnapshot
+	Closed bool `json:"closed,omitempty"`
+}
+
+// streamHub pushes updates to the clients of /ws. It polls the portfolio,
+// the forwarding events and the channel snapshots only while clients are
+// connected, so forwards and channel changes show up as soon as the
+// collectors have stored them.
+type streamHub struct {
+	server   *Server
+	interval time.Duration
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	clients map[*streamClient]bool
+	// stop ends the running poller; nil while there is none
+	stop chan struct{}
+	// portfolio is the last portfolio polled, sent to clients as they join
+	portfolio *bitcoin.PortfolioSnapshot
+}
+
+// streamClient is one /ws connection
+type streamClient struct {
+	units UnitConverter
+	send  chan StreamMessage
+}
+
+// newStreamHub returns a hub serving server's data, polling every interval
+func newStreamHub(server *Server, interval time.Duration) *streamHub {
+	h := &streamHub{server: server, interval: interval, clients: make(map[*streamClient]bool)}
+	// The default origin check refuses pages of other sites
+	h.upgrader.Error = func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+		server.writeError(w, status, reason.Error())
+	}
+	return h
+}
+
+// handleStream handles GET /api/ws, upgrading the request to a WebSocket
+// that receives StreamMessages until the client disconnects. Clients are
+// sent the current portfolio first, then only changes.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	if s.stream == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Streaming not available")
+		return
+	}
+
+	conn, err := s.stream.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has answered the request
+		return
+	}
+
+	client := &streamClient{units: unitsFrom(r), send: make(chan StreamMessage, streamSendBuffer)}
+	go s.stream.write(conn, client)
+	s.stream.join(client)
+	defer s.stream.leave(client)
+
+	// Clients only send control messages, read to handle pongs and closes
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	})
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// write sends a client's messages until the hub drops it, pinging it
+// meanwhile, then closes the connection
+func (h *streamHub) write(conn *websocket.Conn, client *streamClient) {
+	ping := time.NewTicker(streamPongWait * 9 / 10)
+	defer func() {
+		ping.Stop()
+		conn.Close()
+		h.leave(client)
+	}()
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if snapshot, ok := message.Data.(bitcoin.PortfolioSnapshot); ok {
+				message.Data = convertSnapshot(snapshot, client.units)
+			}
+			if err := conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// join adds a client, starting the poller for the first one
+func (h *streamHub) join(client *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.clients[client] = true
+	if h.portfolio != nil {
+		client.send <- StreamMessage{Type: StreamPortfolio, Timestamp: time.Now(), Data: *h.portfolio}
+	}
+	if h.stop == nil {
+		h.stop = make(chan struct{})
+		go h.run(h.stop)
+	}
+}
+
+// leave drops a client, closing its send channel, and stops the poller
+// after the last one. Dropping a client twice is harmless.
+func (h *streamHub) leave(client *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(client)
+}
+
+// drop is leave with h.mu held
+func (h *streamHub) drop(client *streamClient) {
+	if !h.clients[client] {
+		return
+	}
+	delete(h.clients, client)
+	close(client.send)
+	if len(h.clients) == 0 && h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+		h.portfolio = nil
+	}
+}
+
+// broadcast queues a message for every client, dropping those too slow to
+// keep up
+func (h *streamHub) broadcast(message StreamMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if message.Type == StreamPortfolio {
+		snapshot := message.Data.(bitcoin.PortfolioSnapshot)
+		h.portfolio = &snapshot
+	}
+	for client := range h.clients {
+		select {
+		case client.send <- message:
+		default:
+			log.Printf("streamHub: dropping a client %d messages behind", streamSendBuffer)
+			h.drop(client)
+		}
+	}
+}
+
+// run polls every interval until stop is closed. Forwards and channel
+// changes from before the first poll are not sent.
+func (h *streamHub) run(stop chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	p := &streamPoller{hub: h}
+	var err error
+	if p.lastForward, err = h.server.db.LastForwardingEventID(); err != nil {
+		log.Printf("streamHub: failed to get the last forwarding event: %v", err)
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		p.poll(ctx)
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// streamPoller is what one run of the poller has seen so far
+type streamPoller struct {
+	hub         *streamHub
+	portfolio   *bitcoin.PortfolioSnapshot
+	lastForward int64
+	// channels is nil until the first poll
+	channels map[string]db.ChannelSnapshot
+}
+
+// poll broadcasts what changed since the previous poll. Failures are logged
+// and the affected updates retried on the next poll.
+func (p *streamPoller) poll(ctx context.Context) {
+	server := p.hub.server
+
+	snapshot, err := server.currentPortfolio(ctx)
+	switch {
+	case errors.Is(err, errRealtimeUnavailable), ctx.Err() != nil:
+	case err != nil:
+		log.Printf("streamHub: failed to calculate portfolio: %v", err)
+	case !samePortfolio(p.portfolio, snapshot):
+		p.portfolio = snapshot
+		p.hub.broadcast(StreamMessage{Type: StreamPortfolio, Timestamp: time.Now(), Data: *snapshot})
+	}
+
+	events, err := server.db.GetForwardingEventsAfter(p.lastForward, streamForwardBatch)
+	if err != nil {
+		log.Printf("streamHub: failed to get forwarding events: %v", err)
+	}
+	for _, event := range events {
+		p.hub.broadcast(StreamMessage{Type: StreamForward, Timestamp: time.Now(), Data: event})
+		p.lastForward = event.ID
+	}
+
+	snapshots, err := server.db.GetLatestChannelSnapshots()
+	if err != nil {
+		log.Printf("streamHub: failed to get channel snapshots: %v", err)
+		return
+	}
+	if p.channels != nil {
+		for _, update := range channelUpdates(p.channels, snapshots) {
+			p.hub.broadcast(StreamMessage{Type: StreamChannel, Timestamp: time.Now(), Data: update})
+		}
+	}
+	p.channels = make(map[string]db.ChannelSnapshot, len(snapshots))
+	for _, snapshot := range snapshots {
+		p.channels[snapshot.ChannelID] = snapshot
+	}
+}
+
+// samePortfolio reports whether two portfolios hold the same amounts,
+// whenever they were calculated
+func samePortfolio(a, b *bitcoin.PortfolioSnapshot) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	x, y := *a, *b
+	x.Timestamp, y.Timestamp = time.Time{}, time.Time{}
+	return reflect.DeepEqual(x, y)
+}
+
+// channelUpdates returns the channels in latest that are new or changed
+// since previous, ordered like latest, followed by the channels of previous
+// that are gone
+func channelUpdates(previous map[string]db.ChannelSnapshot, latest []db.ChannelSnapshot) []ChannelUpdate {
+	var updates []ChannelUpdate
+	seen := make(map[string]bool, len(latest))
+	for _, snapshot := range latest {
+		seen[snapshot.ChannelID] = true
+		before, ok := previous[snapshot.ChannelID]
+		if ok && sameChannelState(before, snapshot) {
+			continue
+		}
+		updates = append(updates, ChannelUpdate{ChannelSnapshot: snapshot})
+	}
+
+	var closed []ChannelUpdate
+	for id, snapshot := range previous {
+		if !seen[id] {
+			closed = append(closed, ChannelUpdate{ChannelSnapshot: snapshot, Closed: true})
+		}
+	}
+	// Map order is random, keep the messages stable
+	sort.Slice(closed, func(i, j int) bool { return closed[i].ChannelID < closed[j].ChannelID })
+	return append(updates, closed...)
+}
+
+// sameChannelState reports whether two snapshots of a channel agree on
+// everything but when they were taken
+func sameChannelState(a, b db.ChannelSnapshot) bool {
+	a.ID, a.Timestamp = b.ID, b.Timestamp
+	return a == b
+}
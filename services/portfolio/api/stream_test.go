@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+	"github.com/gorilla/websocket"
+)
+
+func TestStream(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+	server.stream = newStreamHub(server, 10*time.Millisecond)
+
+	now := time.Now().Truncate(time.Second)
+	channels := []db.ChannelSnapshot{
+		{Timestamp: now.Add(-time.Hour), ChannelID: "111:1:0", Capacity: 1000000, LocalBalance: 600000, RemoteBalance: 400000, Active: true},
+		{Timestamp: now.Add(-time.Hour), ChannelID: "222:1:0", Capacity: 500000, LocalBalance: 250000, RemoteBalance: 250000, Active: true},
+	}
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots(channels))
+
+	ts := httptest.NewServer(server.router)
+	defer ts.Close()
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/ws?units=btc", nil)
+	testutils.AssertNoError(t, err)
+	defer conn.Close()
+	testutils.AssertEqual(t, resp.StatusCode, http.StatusSwitchingProtocols)
+
+	var message struct {
+		Type string                 `json:"type"`
+		Data map[string]interface{} `json:"data"`
+	}
+	next := func() {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		testutils.AssertNoError(t, conn.ReadJSON(&message))
+	}
+
+	// The current portfolio comes first, in the requested units
+	next()
+	testutils.AssertEqual(t, message.Type, StreamPortfolio)
+	testutils.AssertEqual(t, message.Data["total_portfolio"], 0.186)
+
+	// Forwards stored from now on are pushed, the seeded ones are not
+	testutils.AssertNoError(t, server.db.InsertForwardingEvent(&db.ForwardingEvent{
+		Timestamp: now, ChannelInID: "111:1:0", ChannelOutID: "222:1:0", AmountIn: 100300, AmountOut: 100000, Fee: 300,
+	}))
+	next()
+	testutils.AssertEqual(t, message.Type, StreamForward)
+	testutils.AssertEqual(t, message.Data["fee"], 300.0)
+
+	// 111 went offline and 222 was closed; the unchanged portfolio is not
+	// pushed again
+	testutils.AssertNoError(t, server.db.InsertChannelSnapshots([]db.ChannelSnapshot{
+		{Timestamp: now, ChannelID: "111:1:0", Capacity: 1000000, LocalBalance: 600000, RemoteBalance: 400000},
+	}))
+	next()
+	testutils.AssertEqual(t, message.Type, StreamChannel)
+	testutils.AssertEqual(t, message.Data["channel_id"], "111:1:0")
+	testutils.AssertEqual(t, message.Data["active"], false)
+	next()
+	testutils.AssertEqual(t, message.Type, StreamChannel)
+	testutils.AssertEqual(t, message.Data["channel_id"], "222:1:0")
+	testutils.AssertEqual(t, message.Data["closed"], true)
+
+	// The poller stops with the last client
+	conn.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		server.stream.mu.Lock()
+		stopped := server.stream.stop == nil
+		server.stream.mu.Unlock()
+		if stopped {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("poller still running after the client disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestChannelUpdates(t *testing.T) {
+	now := time.Now()
+	previous := map[string]db.ChannelSnapshot{
+		"111:1:0": {ID: 1, Timestamp: now.Add(-time.Hour), ChannelID: "111:1:0", LocalBalance: 600000, Active: true},
+		"222:1:0": {ID: 2, Timestamp: now.Add(-time.Hour), ChannelID: "222:1:0", LocalBalance: 250000, Active: true},
+		"333:1:0": {ID: 3, Timestamp: now.Add(-time.Hour), ChannelID: "333:1:0", LocalBalance: 100000, Active: true},
+	}
+	latest := []db.ChannelSnapshot{
+		// Only the time of 111 changed
+		{ID: 4, Timestamp: now, ChannelID: "111:1:0", LocalBalance: 600000, Active: true},
+		{ID: 5, Timestamp: now, ChannelID: "222:1:0", LocalBalance: 200000, Active: true},
+		{ID: 6, Timestamp: now, ChannelID: "444:1:0", LocalBalance: 500000, Active: true},
+	}
+
+	updates := channelUpdates(previous, latest)
+	testutils.AssertEqual(t, len(updates), 3)
+	testutils.AssertEqual(t, updates[0].ChannelID, "222:1:0")
+	testutils.AssertEqual(t, updates[0].LocalBalance, int64(200000))
+	testutils.AssertEqual(t, updates[1].ChannelID, "444:1:0")
+	testutils.AssertEqual(t, updates[1].Closed, false)
+	testutils.AssertEqual(t, updates[2].ChannelID, "333:1:0")
+	testutils.AssertEqual(t, updates[2].Closed, true)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ChartColor is the role of a dataset's color. Each theme gives every role
+// an RGB value, so charts keep their series apart in whichever theme is
+// requested.
+type ChartColor int
+
+const (
+	// ColorPrimary is the main series: forwards, local balance, success rate
+	ColorPrimary ChartColor = iota
+	// ColorSecondary is fees and totals drawn next to another series
+	ColorSecondary
+	// ColorTertiary is balances and latency
+	ColorTertiary
+	// ColorNegative is what works against the node, the remote balance
+	ColorNegative
+	// ColorQuaternary is ecash
+	ColorQuaternary
+	chartColorCount
+)
+
+// DefaultChartTheme is the theme charts use without a theme parameter or
+// --chart-theme
+const DefaultChartTheme = "default"
+
+// fillAlpha is the opacity of the area under a dataset
+const fillAlpha = "0.2"
+
+// rgb is a color in 0-255 channels
+type rgb struct{ r, g, b uint8 }
+
+// ChartTheme is a palette for the Chart.js datasets the API returns
+type ChartTheme struct {
+	Name        string
+	Description string
+	colors      [chartColorCount]rgb
+}
+
+// chartThemes are the themes the theme parameter accepts
+var chartThemes = map[string]ChartTheme{
+	DefaultChartTheme: {
+		Name:        DefaultChartTheme,
+		Description: "Chart.js sample colors",
+		colors: [chartColorCount]rgb{
+			ColorPrimary:    {75, 192, 192},
+			ColorSecondary:  {54, 162, 235},
+			ColorTertiary:   {255, 159, 64},
+			ColorNegative:   {255, 99, 132},
+			ColorQuaternary: {153, 102, 255},
+		},
+	},
+	"colorblind": {
+		Name:        "colorblind",
+		Description: "Okabe-Ito palette, distinguishable with the common forms of color blindness",
+		colors: [chartColorCount]rgb{
+			ColorPrimary:    {0, 158, 115},
+			ColorSecondary:  {0, 114, 178},
+			ColorTertiary:   {230, 159, 0},
+			ColorNegative:   {213, 94, 0},
+			ColorQuaternary: {204, 121, 167},
+		},
+	},
+	"high-contrast": {
+		Name:        "high-contrast",
+		Description: "Saturated colors for dark backgrounds and projectors",
+		colors: [chartColorCount]rgb{
+			ColorPrimary:    {0, 230, 118},
+			ColorSecondary:  {41, 121, 255},
+			ColorTertiary:   {255, 214, 0},
+			ColorNegative:   {255, 23, 68},
+			ColorQuaternary: {213, 0, 249},
+		},
+	},
+}
+
+// chartThemeNames returns the names of the themes in alphabetical order
+func chartThemeNames() []string {
+	names := make([]string, 0, len(chartThemes))
+	for name := range chartThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Border returns the line color of color, e.g. "rgba(54, 162, 235, 1)"
+func (t ChartTheme) Border(color ChartColor) string {
+	return t.rgba(color, "1")
+}
+
+// Fill returns the translucent area color of color
+func (t ChartTheme) Fill(color ChartColor) string {
+	return t.rgba(color, fillAlpha)
+}
+
+func (t ChartTheme) rgba(color ChartColor, alpha string) string {
+	c := t.colors[color]
+	return fmt.Sprintf("rgba(%d, %d, %d, %s)", c.r, c.g, c.b, alpha)
+}
+
+type themeKey struct{}
+
+// withTheme validates the theme query parameter before the handler runs and
+// makes the theme available through themeFrom. Without the parameter the
+// server's --chart-theme applies.
+func (s *Server) withTheme(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.ToLower(r.URL.Query().Get("theme"))
+		if fieldErr := validateEnum("theme", name, chartThemeNames()); fieldErr != nil {
+			s.writeValidationError(w, fieldErr)
+			return
+		}
+		if name == "" {
+			name = s.chartTheme
+		}
+		theme, ok := chartThemes[name]
+		if !ok {
+			theme = chartThemes[DefaultChartTheme]
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), themeKey{}, theme)))
+	}
+}
+
+// themeFrom returns the theme set up by withTheme, the default if there is none
+func themeFrom(r *http.Request) ChartTheme {
+	if theme, ok := r.Context().Value(themeKey{}).(ChartTheme); ok {
+		return theme
+	}
+	return chartThemes[DefaultChartTheme]
+}
+
+// handleChartThemes handles GET /api/charts/themes, listing each theme's
+// colors so a frontend can draw legends for its own charts to match
+func (s *Server) handleChartThemes(w http.ResponseWriter, r *http.Request) {
+	themes := make([]map[string]interface{}, 0, len(chartThemes))
+	for _, name := range chartThemeNames() {
+		theme := chartThemes[name]
+		colors := make([]string, 0, chartColorCount)
+		for color := ChartColor(0); color < chartColorCount; color++ {
+			colors = append(colors, theme.Border(color))
+		}
+		themes = append(themes, map[string]interface{}{
+			"name":        theme.Name,
+			"description": theme.Description,
+			"colors":      colors,
+		})
+	}
+
+	defaultTheme := s.chartTheme
+	if defaultTheme == "" {
+		defaultTheme = DefaultChartTheme
+	}
+	s.writeJSON(w, APIResponse{Success: true, Data: map[string]interface{}{
+		"default": defaultTheme,
+		"themes":  themes,
+	}})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestChartThemes(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	borderColor := func(url string) string {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+		testutils.AssertEqual(t, rr.Code, http.StatusOK)
+		var response struct {
+			Data struct {
+				Datasets []map[string]interface{} `json:"datasets"`
+			} `json:"data"`
+		}
+		testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response.Data.Datasets[0]["borderColor"].(string)
+	}
+
+	testutils.AssertEqual(t, borderColor("/api/v1/lightning/forwards"), "rgba(75, 192, 192, 1)")
+	testutils.AssertEqual(t, borderColor("/api/v1/lightning/forwards?theme=colorblind"), "rgba(0, 158, 115, 1)")
+	testutils.AssertEqual(t, borderColor("/api/v1/lightning/fees?theme=Colorblind"), "rgba(0, 114, 178, 1)")
+
+	// --chart-theme changes the default, the parameter still wins
+	server.chartTheme = "high-contrast"
+	testutils.AssertEqual(t, borderColor("/api/v1/lightning/forwards"), "rgba(0, 230, 118, 1)")
+	testutils.AssertEqual(t, borderColor("/api/v1/lightning/forwards?theme=default"), "rgba(75, 192, 192, 1)")
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/lightning/forwards?theme=neon", nil))
+	testutils.AssertEqual(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestChartThemesDefineEveryColor(t *testing.T) {
+	for name, theme := range chartThemes {
+		testutils.AssertEqual(t, theme.Name, name)
+		for color := ChartColor(0); color < chartColorCount; color++ {
+			if theme.colors[color] == (rgb{}) {
+				t.Errorf("theme %s has no color %d", name, color)
+			}
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/price"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// Units accepted by the units query parameter
+const (
+	UnitsSats = "sats"
+	UnitsBTC  = "btc"
+	UnitsFiat = "fiat"
+)
+
+var amountUnits = []string{UnitsSats, UnitsBTC, UnitsFiat}
+
+// UnitConverter converts sat amounts into the units a client asked for.
+// Fiat amounts use the current BTC price, including for history.
+type UnitConverter struct {
+	Units     string
+	Currency  string
+	Price     float64
+	PriceTime time.Time
+	// Fiat values amounts in the currency parameter's currency alongside
+	// them; nil without one
+	Fiat *FiatValuation
+}
+
+// FiatValuation values sat amounts in a fiat currency at the BTC price
+// recorded for their time, falling back to the current price before the
+// first recorded one
+type FiatValuation struct {
+	Currency string
+	history  *price.History
+}
+
+// Value returns sats in the currency at the price of at, rounded to cents
+func (v *FiatValuation) Value(sats int64, at time.Time) float64 {
+	return math.Round(float64(sats)/utils.SatsPerBTC*v.history.At(at).Price*100) / 100
+}
+
+// describe returns the currency and the price used for at, to which the
+// caller adds the values
+func (v *FiatValuation) describe(at time.Time) map[string]interface{} {
+	quote := v.history.At(at)
+	return map[string]interface{}{
+		"currency":   quote.Currency,
+		"btc_price":  quote.Price,
+		"price_time": quote.Time,
+	}
+}
+
+// Convert returns sats in the converter's units
+func (c UnitConverter) Convert(sats int64) float64 {
+	switch c.Units {
+	case UnitsBTC:
+		return float64(sats) / utils.SatsPerBTC
+	case UnitsFiat:
+		// Fiat is rounded to cents
+		return math.Round(float64(sats)/utils.SatsPerBTC*c.Price*100) / 100
+	default:
+		return float64(sats)
+	}
+}
+
+// Label is the unit name shown in chart labels, e.g. "BTC" or "USD"
+func (c UnitConverter) Label() string {
+	switch c.Units {
+	case UnitsBTC:
+		return "BTC"
+	case UnitsFiat:
+		return c.Currency
+	default:
+		return "sats"
+	}
+}
+
+// Decimals is how many decimal places amounts in these units are shown with
+func (c UnitConverter) Decimals() int {
+	switch c.Units {
+	case UnitsBTC:
+		return 8
+	case UnitsFiat:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// describe adds the units, and for fiat the price used, to a response map
+func (c UnitConverter) describe(m map[string]interface{}) {
+	m["units"] = c.Units
+	if c.Units == UnitsFiat {
+		m["currency"] = c.Currency
+		m["btc_price"] = c.Price
+		m["price_time"] = c.PriceTime
+	}
+}
+
+type unitsKey struct{}
+
+// withUnits validates the units and currency query parameters before the
+// handler runs and makes a converter available through unitsFrom. currency
+// picks the currency of units=fiat and adds fiat values at the price of
+// their time, reading the recorded prices of the request's time range.
+// Requests needing a price fail with 503 when none can be had.
+func (s *Server) withUnits(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		units := strings.ToLower(r.URL.Query().Get("units"))
+		if fieldErr := validateEnum("units", units, amountUnits); fieldErr != nil {
+			s.writeValidationError(w, fieldErr)
+			return
+		}
+		currency := strings.ToLower(r.URL.Query().Get("currency"))
+		if fieldErr := validateEnum("currency", currency, s.currencyParams()); fieldErr != nil {
+			s.writeValidationError(w, fieldErr)
+			return
+		}
+
+		if (units == UnitsFiat || currency != "") && s.prices == nil {
+			s.writeError(w, http.StatusServiceUnavailable, "Price service not available")
+			return
+		}
+
+		conv := UnitConverter{Units: UnitsSats}
+		switch units {
+		case UnitsBTC:
+			conv.Units = UnitsBTC
+		case UnitsFiat:
+			fiatCurrency := s.fiatCurrency
+			if currency != "" {
+				fiatCurrency = currency
+			}
+			quote, err := s.prices.BTCPrice(fiatCurrency)
+			if err != nil {
+				log.Printf("withUnits: failed to get BTC price: %v", err)
+				s.writeError(w, http.StatusServiceUnavailable, "BTC price not available")
+				return
+			}
+			conv = UnitConverter{Units: UnitsFiat, Currency: quote.Currency, Price: quote.Price, PriceTime: quote.Time}
+		}
+
+		if currency != "" {
+			from, to := time.Now(), time.Now()
+			if tr, ok := r.Context().Value(timeRangeKey{}).(TimeRange); ok {
+				from, to = tr.From, tr.To
+			}
+			valuation, err := s.fiatValuation(currency, from, to)
+			if err != nil {
+				log.Printf("withUnits: failed to get BTC prices: %v", err)
+				s.writeError(w, http.StatusServiceUnavailable, "BTC price not available")
+				return
+			}
+			conv.Fiat = valuation
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), unitsKey{}, conv)))
+	}
+}
+
+// fiatValuation reads the BTC prices of currency from from to to
+func (s *Server) fiatValuation(currency string, from, to time.Time) (*FiatValuation, error) {
+	history, err := s.prices.History(currency, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &FiatValuation{Currency: history.Current().Currency, history: history}, nil
+}
+
+// fiatCurrencies returns the units=fiat currency followed by the comma
+// separated extra ones, upper case and without repeats
+func fiatCurrencies(main, extra string) []string {
+	var currencies []string
+	seen := make(map[string]bool)
+	for _, currency := range append([]string{main}, strings.Split(extra, ",")...) {
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if currency != "" && !seen[currency] {
+			seen[currency] = true
+			currencies = append(currencies, currency)
+		}
+	}
+	return currencies
+}
+
+// currencyParams lists the values the currency parameter accepts, the
+// configured fiat currencies in lower case
+func (s *Server) currencyParams() []string {
+	params := make([]string, 0, len(s.fiatCurrencies))
+	for _, currency := range s.fiatCurrencies {
+		params = append(params, strings.ToLower(currency))
+	}
+	return params
+}
+
+// unitsFrom returns the converter set up by withUnits, sats if there is none
+func unitsFrom(r *http.Request) UnitConverter {
+	if conv, ok := r.Context().Value(unitsKey{}).(UnitConverter); ok {
+		return conv
+	}
+	return UnitConverter{Units: UnitsSats}
+}
+
+// convertSnapshot returns snapshot with its amounts in the requested units,
+// and valued in fiat under "fiat" for the currency parameter. Sats snapshots
+// are returned unchanged.
+func convertSnapshot(snapshot bitcoin.PortfolioSnapshot, conv UnitConverter) interface{} {
+	if conv.Units == UnitsSats && conv.Fiat == nil {
+		return snapshot
+	}
+
+	// Round-trip through JSON so fields added to the snapshot later still appear
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return snapshot
+	}
+	var converted map[string]interface{}
+	if err := json.Unmarshal(raw, &converted); err != nil {
+		return snapshot
+	}
+
+	// The PortfolioSnapshot JSON fields holding sats
+	amounts := map[string]int64{
+		"lightning_local":     snapshot.LightningLocal,
+		"lightning_remote":    snapshot.LightningRemote,
+		"onchain_confirmed":   snapshot.OnchainConfirmed,
+		"onchain_unconfirmed": snapshot.OnchainUnconfirmed,
+		"tracked_addresses":   snapshot.TrackedAddresses,
+		"tracked_confirmed":   snapshot.TrackedConfirmed,
+		"tracked_unconfirmed": snapshot.TrackedUnconfirmed,
+		"cold_storage":        snapshot.ColdStorage,
+		"liquid":              snapshot.Liquid,
+		"liquid_unconfirmed":  snapshot.LiquidUnconfirmed,
+		"ecash":               snapshot.Ecash,
+		"total_portfolio":     snapshot.TotalPortfolio,
+		"total_liquid":        snapshot.TotalLiquid,
+		"total_confirmed":     snapshot.TotalConfirmed,
+	}
+	for field, sats := range amounts {
+		converted[field] = conv.Convert(sats)
+	}
+	conv.describe(converted)
+	if conv.Fiat != nil {
+		fiat := conv.Fiat.describe(snapshot.Timestamp)
+		for field, sats := range amounts {
+			fiat[field] = conv.Fiat.Value(sats, snapshot.Timestamp)
+		}
+		converted["fiat"] = fiat
+	}
+	return converted
+}
+
+// convertSnapshots converts every snapshot in a history
+func convertSnapshots(snapshots []bitcoin.PortfolioSnapshot, conv UnitConverter) interface{} {
+	if conv.Units == UnitsSats && conv.Fiat == nil {
+		return snapshots
+	}
+	converted := make([]interface{}, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		converted = append(converted, convertSnapshot(snapshot, conv))
+	}
+	return converted
+}
+
+// convertChart converts a Chart.js response in place into the requested
+// units: every dataset's sat data, "(sats)" in dataset labels and the named
+// metadata amounts. It also adds the format hints, the only change to sats
+// charts, and the fiat values for the currency parameter.
+func convertChart(chartData map[string]interface{}, r *http.Request, metadataAmounts ...string) {
+	conv := unitsFrom(r)
+	metadata := chartData["metadata"].(map[string]interface{})
+	addFormatHints(metadata, r, conv.Label(), conv.Decimals())
+	if conv.Fiat != nil {
+		addFiatData(chartData, conv.Fiat)
+	}
+	if conv.Units == UnitsSats {
+		return
+	}
+
+	for _, dataset := range chartData["datasets"].([]map[string]interface{}) {
+		if data, ok := dataset["data"].([]int64); ok {
+			converted := make([]float64, len(data))
+			for i, sats := range data {
+				converted[i] = conv.Convert(sats)
+			}
+			dataset["data"] = converted
+		}
+		if label, ok := dataset["label"].(string); ok {
+			dataset["label"] = strings.Replace(label, "(sats)", "("+conv.Label()+")", 1)
+		}
+	}
+
+	for _, key := range metadataAmounts {
+		if sats, ok := metadata[key].(int64); ok {
+			metadata[key] = conv.Convert(sats)
+		}
+	}
+	conv.describe(metadata)
+}
+
+// chartDayLayout and chartMinuteLayout are the layouts of the time labels of
+// sat charts
+const (
+	chartDayLayout    = "2006-01-02"
+	chartMinuteLayout = "2006-01-02 15:04"
+)
+
+// addFiatData values every dataset's sat data at the price of its label's
+// time as fiat_data, and lists the prices used in the metadata. Day labels
+// are valued at the day's last price, labels that are not times at the
+// current price.
+func addFiatData(chartData map[string]interface{}, fiat *FiatValuation) {
+	labels, _ := chartData["labels"].([]string)
+	times := make([]time.Time, len(labels))
+	prices := make([]float64, len(labels))
+	for i, label := range labels {
+		times[i] = time.Now()
+		if t, err := time.ParseInLocation(chartMinuteLayout, label, time.Local); err == nil {
+			times[i] = t
+		} else if t, err := time.ParseInLocation(chartDayLayout, label, time.Local); err == nil {
+			times[i] = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		prices[i] = fiat.history.At(times[i]).Price
+	}
+
+	for _, dataset := range chartData["datasets"].([]map[string]interface{}) {
+		data, ok := dataset["data"].([]int64)
+		if !ok || len(data) != len(times) {
+			continue
+		}
+		values := make([]float64, len(data))
+		for i, sats := range data {
+			values[i] = fiat.Value(sats, times[i])
+		}
+		dataset["fiat_data"] = values
+	}
+
+	metadata := chartData["metadata"].(map[string]interface{})
+	metadata["fiat"] = map[string]interface{}{"currency": fiat.Currency, "btc_prices": prices}
+}
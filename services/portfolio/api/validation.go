@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// DefaultHistoryDays is the window used when no range parameter is given
+	DefaultHistoryDays = 30
+	// DateLayout is the format accepted by the from and to parameters
+	DateLayout = "2006-01-02"
+)
+
+// Validation error codes returned in FieldError.Code
+const (
+	ErrCodeRequired   = "required"
+	ErrCodeInvalid    = "invalid"
+	ErrCodeOutOfRange = "out_of_range"
+	ErrCodeConflict   = "conflict"
+)
+
+// FieldError describes why a single request parameter was rejected
+type FieldError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// writeValidationError writes a 400 response for a rejected parameter. The
+// top-level error keeps the message for existing clients; errors carries the
+// structured detail.
+func (s *Server) writeValidationError(w http.ResponseWriter, fieldErr *FieldError) {
+	s.writeJSONStatus(w, http.StatusBadRequest, APIResponse{
+		Success: false,
+		Error:   fieldErr.Message,
+		Errors:  []FieldError{*fieldErr},
+	})
+}
+
+// TimeRange is a validated window for history queries
+type TimeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Days is the length of the window, rounded up to whole days
+	Days int `json:"days"`
+}
+
+// rangePresets are the named windows accepted by the range parameter
+var rangePresets = map[string]func(now time.Time) time.Time{
+	"7d":  func(now time.Time) time.Time { return now.AddDate(0, 0, -7) },
+	"30d": func(now time.Time) time.Time { return now.AddDate(0, 0, -30) },
+	"90d": func(now time.Time) time.Time { return now.AddDate(0, 0, -90) },
+	"1y":  func(now time.Time) time.Time { return now.AddDate(-1, 0, 0) },
+	"ytd": func(now time.Time) time.Time { return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()) },
+	"mtd": func(now time.Time) time.Time {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	},
+	"all": func(time.Time) time.Time { return genesisDate() },
+}
+
+// rangePresetNames lists the presets in the order they are documented
+var rangePresetNames = []string{"7d", "30d", "90d", "1y", "ytd", "mtd", "all"}
+
+func genesisDate() time.Time {
+	genesis, _ := time.Parse(DateLayout, BitcoinGenesisDate)
+	return genesis
+}
+
+// ParseTimeRange reads the history window from query parameters. A window is
+// selected by exactly one of:
+//
+//   - days: a number between 1 and MaxHistoryDays, or "all"
+//   - range: a preset (7d, 30d, 90d, 1y, ytd, mtd, all)
+//   - from/to: dates in YYYY-MM-DD; to is inclusive and defaults to now.
+//     Both must fall between BitcoinGenesisDate and today.
+//
+// With none of them the last DefaultHistoryDays days are used.
+func ParseTimeRange(query url.Values, now time.Time) (TimeRange, *FieldError) {
+	daysStr := query.Get("days")
+	preset := query.Get("range")
+	fromStr := query.Get("from")
+	toStr := query.Get("to")
+
+	selectors := 0
+	for _, set := range []bool{daysStr != "", preset != "", fromStr != "" || toStr != ""} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors > 1 {
+		return TimeRange{}, &FieldError{
+			Code:    ErrCodeConflict,
+			Field:   "range",
+			Message: "Use only one of days, range, or from/to",
+		}
+	}
+
+	var from, to time.Time
+	switch {
+	case daysStr == "all":
+		from, to = genesisDate(), now
+	case daysStr != "":
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > MaxHistoryDays {
+			return TimeRange{}, &FieldError{
+				Code:    ErrCodeOutOfRange,
+				Field:   "days",
+				Message: "Invalid days parameter. Must be a number between 1 and " + strconv.Itoa(MaxHistoryDays) + ", or 'all'",
+			}
+		}
+		from, to = now.AddDate(0, 0, -days), now
+	case preset != "":
+		start, ok := rangePresets[preset]
+		if !ok {
+			return TimeRange{}, invalidEnum("range", rangePresetNames)
+		}
+		from, to = start(now), now
+	case fromStr != "" || toStr != "":
+		if fromStr == "" {
+			return TimeRange{}, &FieldError{Code: ErrCodeRequired, Field: "from", Message: "from is required when to is set"}
+		}
+		var fieldErr *FieldError
+		if from, fieldErr = parseDate("from", fromStr, now.Location()); fieldErr != nil {
+			return TimeRange{}, fieldErr
+		}
+		if fieldErr := checkDateBounds("from", from, now); fieldErr != nil {
+			return TimeRange{}, fieldErr
+		}
+		to = now
+		if toStr != "" {
+			end, fieldErr := parseDate("to", toStr, now.Location())
+			if fieldErr != nil {
+				return TimeRange{}, fieldErr
+			}
+			if fieldErr := checkDateBounds("to", end, now); fieldErr != nil {
+				return TimeRange{}, fieldErr
+			}
+			// to names a whole day, so include all of it, but no later than now
+			to = end.AddDate(0, 0, 1).Add(-time.Second)
+			if to.After(now) {
+				to = now
+			}
+		}
+		if from.After(to) {
+			return TimeRange{}, &FieldError{Code: ErrCodeOutOfRange, Field: "from", Message: "from must not be after to"}
+		}
+	default:
+		from, to = now.AddDate(0, 0, -DefaultHistoryDays), now
+	}
+
+	return TimeRange{
+		From: from,
+		To:   to,
+		Days: int(math.Ceil(to.Sub(from).Hours() / 24)),
+	}, nil
+}
+
+func parseDate(field, value string, loc *time.Location) (time.Time, *FieldError) {
+	t, err := time.ParseInLocation(DateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, &FieldError{
+			Code:    ErrCodeInvalid,
+			Field:   field,
+			Message: fmt.Sprintf("Invalid %s date %q. Must be YYYY-MM-DD", field, value),
+		}
+	}
+	return t, nil
+}
+
+// parseTimestamp validates a required moment given as an RFC 3339 timestamp
+// or a YYYY-MM-DD date, which means the start of that day in now's time zone
+func parseTimestamp(field, value string, now time.Time) (time.Time, *FieldError) {
+	if value == "" {
+		return time.Time{}, &FieldError{Code: ErrCodeRequired, Field: field, Message: field + " is required"}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		var fieldErr *FieldError
+		if t, fieldErr = parseDate(field, value, now.Location()); fieldErr != nil {
+			fieldErr.Message = fmt.Sprintf("Invalid %s %q. Must be an RFC 3339 timestamp or YYYY-MM-DD", field, value)
+			return time.Time{}, fieldErr
+		}
+	}
+	if fieldErr := checkDateBounds(field, t, now); fieldErr != nil {
+		return time.Time{}, fieldErr
+	}
+	return t, nil
+}
+
+// checkDateBounds rejects dates before the genesis block or after today
+func checkDateBounds(field string, date, now time.Time) *FieldError {
+	if date.Before(genesisDate()) {
+		return &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   field,
+			Message: fmt.Sprintf("%s must not be before %s", field, BitcoinGenesisDate),
+		}
+	}
+	if date.After(now) {
+		return &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   field,
+			Message: fmt.Sprintf("%s must not be in the future", field),
+		}
+	}
+	return nil
+}
+
+type timeRangeKey struct{}
+
+// withTimeRange validates the history window before the handler runs and
+// makes it available through timeRangeFrom
+func (s *Server) withTimeRange(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tr, fieldErr := ParseTimeRange(r.URL.Query(), time.Now())
+		if fieldErr != nil {
+			s.writeValidationError(w, fieldErr)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), timeRangeKey{}, tr)))
+	}
+}
+
+// timeRangeFrom returns the window validated by withTimeRange
+func timeRangeFrom(r *http.Request) TimeRange {
+	if tr, ok := r.Context().Value(timeRangeKey{}).(TimeRange); ok {
+		return tr
+	}
+	now := time.Now()
+	return TimeRange{From: now.AddDate(0, 0, -DefaultHistoryDays), To: now, Days: DefaultHistoryDays}
+}
+
+// parseID validates a positive integer ID. label names the entity in
+// messages, e.g. "address" gives "Invalid address ID".
+func parseID(field, value, label string) (int64, *FieldError) {
+	if value == "" {
+		return 0, &FieldError{
+			Code:    ErrCodeRequired,
+			Field:   field,
+			Message: strings.ToUpper(label[:1]) + label[1:] + " ID is required",
+		}
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, &FieldError{Code: ErrCodeInvalid, Field: field, Message: "Invalid " + label + " ID"}
+	}
+	return id, nil
+}
+
+// parsePathID validates the {id} route variable
+func parsePathID(r *http.Request, label string) (int64, *FieldError) {
+	return parseID("id", mux.Vars(r)["id"], label)
+}
+
+// parseBirthday validates an optional wallet birth height and YYYY-MM-DD
+// birth date. An empty date returns nil.
+func parseBirthday(height int64, date string, now time.Time) (*time.Time, *FieldError) {
+	if height < 0 {
+		return nil, &FieldError{Code: ErrCodeOutOfRange, Field: "birth_height", Message: "birth_height must not be negative"}
+	}
+	if date == "" {
+		return nil, nil
+	}
+	birthDate, fieldErr := parseDate("birth_date", date, time.UTC)
+	if fieldErr != nil {
+		return nil, fieldErr
+	}
+	if fieldErr := checkDateBounds("birth_date", birthDate, now); fieldErr != nil {
+		return nil, fieldErr
+	}
+	return &birthDate, nil
+}
+
+// channelIDPattern matches LND's numeric channel ID and the
+// block:tx:output or BLOCKxTXxOUTPUT short channel ID forms
+var channelIDPattern = regexp.MustCompile(`^[0-9]+([:x][0-9]+[:x][0-9]+)?$`)
+
+// parseChannelID validates the {id} route variable of a channel route
+func parseChannelID(r *http.Request) (string, *FieldError) {
+	id := mux.Vars(r)["id"]
+	if !channelIDPattern.MatchString(id) {
+		return "", &FieldError{Code: ErrCodeInvalid, Field: "id", Message: "Invalid channel ID"}
+	}
+	return id, nil
+}
+
+// parsePubkey validates the {pubkey} route variable of a peer route.
+// Pubkeys are matched in lowercase.
+func parsePubkey(r *http.Request) (string, *FieldError) {
+	pubkey := strings.ToLower(mux.Vars(r)["pubkey"])
+	if !utils.ValidateNodePubkey(pubkey) {
+		return "", &FieldError{Code: ErrCodeInvalid, Field: "pubkey", Message: "Invalid node pubkey. Must be 66 hex characters starting with 02 or 03"}
+	}
+	return pubkey, nil
+}
+
+// parseIntParam validates an optional integer parameter, returning def when
+// the value is empty
+func parseIntParam(field, value string, def, min, max int) (int, *FieldError) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, &FieldError{
+			Code:    ErrCodeOutOfRange,
+			Field:   field,
+			Message: fmt.Sprintf("Invalid %s. Must be a number between %d and %d", field, min, max),
+		}
+	}
+	return n, nil
+}
+
+// validateEnum checks an optional parameter against its allowed values.
+// An empty value is accepted so callers can apply their own default.
+func validateEnum(field, value string, allowed []string) *FieldError {
+	if value == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return invalidEnum(field, allowed)
+}
+
+func invalidEnum(field string, allowed []string) *FieldError {
+	return &FieldError{
+		Code:    ErrCodeInvalid,
+		Field:   field,
+		Message: fmt.Sprintf("Invalid %s. Must be one of: %s", field, strings.Join(allowed, ", ")),
+	}
+}
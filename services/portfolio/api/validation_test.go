@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantDays int
+	}{
+		{"default", "", now.AddDate(0, 0, -30), now, 30},
+		{"days", "days=7", now.AddDate(0, 0, -7), now, 7},
+		{"days all", "days=all", genesisDate(), now, int(now.Sub(genesisDate()).Hours()/24) + 1},
+		{"preset ytd", "range=ytd", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), now, 167},
+		{"preset mtd", "range=mtd", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), now, 15},
+		{"from only", "from=2024-06-01", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), now, 15},
+		{
+			"from and to",
+			"from=2023-01-01&to=2023-12-31",
+			time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC),
+			365,
+		},
+		{
+			// A window ending today stops at now rather than midnight
+			"to today",
+			"from=2024-06-01&to=2024-06-15",
+			time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			now,
+			15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			testutils.AssertNoError(t, err)
+
+			tr, fieldErr := ParseTimeRange(query, now)
+			if fieldErr != nil {
+				t.Fatalf("unexpected error: %v", fieldErr)
+			}
+			testutils.AssertEqual(t, tr.From, tt.wantFrom)
+			testutils.AssertEqual(t, tr.To, tt.wantTo)
+			testutils.AssertEqual(t, tr.Days, tt.wantDays)
+		})
+	}
+}
+
+func TestParseTimeRangeErrors(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		query     string
+		wantCode  string
+		wantField string
+	}{
+		{"days=0", ErrCodeOutOfRange, "days"},
+		{"days=1000", ErrCodeOutOfRange, "days"},
+		{"days=week", ErrCodeOutOfRange, "days"},
+		{"range=forever", ErrCodeInvalid, "range"},
+		{"days=7&range=ytd", ErrCodeConflict, "range"},
+		{"days=7&from=2024-01-01", ErrCodeConflict, "range"},
+		{"to=2024-01-01", ErrCodeRequired, "from"},
+		{"from=01/01/2024", ErrCodeInvalid, "from"},
+		{"from=2024-01-01&to=2024-13-01", ErrCodeInvalid, "to"},
+		{"from=2024-02-01&to=2024-01-01", ErrCodeOutOfRange, "from"},
+		{"from=2008-12-31", ErrCodeOutOfRange, "from"},
+		{"from=2024-06-16", ErrCodeOutOfRange, "from"},
+		{"from=2024-01-01&to=2024-06-16", ErrCodeOutOfRange, "to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			testutils.AssertNoError(t, err)
+
+			_, fieldErr := ParseTimeRange(query, now)
+			if fieldErr == nil {
+				t.Fatal("expected error, got nil")
+			}
+			testutils.AssertEqual(t, fieldErr.Code, tt.wantCode)
+			testutils.AssertEqual(t, fieldErr.Field, tt.wantField)
+		})
+	}
+}
+
+func TestParseID(t *testing.T) {
+	id, fieldErr := parseID("id", "42", "address")
+	if fieldErr != nil {
+		t.Fatalf("unexpected error: %v", fieldErr)
+	}
+	testutils.AssertEqual(t, id, int64(42))
+
+	_, fieldErr = parseID("id", "", "address")
+	testutils.AssertEqual(t, fieldErr.Message, "Address ID is required")
+
+	_, fieldErr = parseID("account", "0", "account")
+	testutils.AssertEqual(t, fieldErr.Message, "Invalid account ID")
+	testutils.AssertEqual(t, fieldErr.Field, "account")
+}
+
+func TestValidateEnum(t *testing.T) {
+	allowed := []string{"name", "balance"}
+
+	testutils.AssertEqual(t, validateEnum("sort", "", allowed) == nil, true)
+	testutils.AssertEqual(t, validateEnum("sort", "balance", allowed) == nil, true)
+
+	fieldErr := validateEnum("sort", "size", allowed)
+	testutils.AssertEqual(t, fieldErr.Message, "Invalid sort. Must be one of: name, balance")
+}
+
+func TestParseTimestamp(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	at, fieldErr := parseTimestamp("timestamp", "2024-01-01T08:30:00+02:00", now)
+	if fieldErr != nil {
+		t.Fatalf("unexpected error: %v", fieldErr)
+	}
+	testutils.AssertEqual(t, at.UTC(), time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC))
+
+	at, fieldErr = parseTimestamp("timestamp", "2024-01-01", now)
+	if fieldErr != nil {
+		t.Fatalf("unexpected error: %v", fieldErr)
+	}
+	testutils.AssertEqual(t, at, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	_, fieldErr = parseTimestamp("timestamp", "", now)
+	testutils.AssertEqual(t, fieldErr.Code, ErrCodeRequired)
+
+	_, fieldErr = parseTimestamp("timestamp", "yesterday", now)
+	testutils.AssertEqual(t, fieldErr.Message, `Invalid timestamp "yesterday". Must be an RFC 3339 timestamp or YYYY-MM-DD`)
+
+	_, fieldErr = parseTimestamp("timestamp", "2024-06-16T00:00:00Z", now)
+	testutils.AssertEqual(t, fieldErr.Code, ErrCodeOutOfRange)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// APIVersion is the current version of the portfolio API
+	APIVersion = "v1"
+	// APIPrefix is the versioned route prefix new clients should use
+	APIPrefix = "/api/" + APIVersion
+	// LegacyAPIPrefix is the unversioned prefix kept as a compatibility alias
+	LegacyAPIPrefix = "/api"
+)
+
+// Deprecation describes a route that is scheduled to be removed or replaced.
+// It is advertised to clients through the Deprecation, Sunset and Link headers.
+type Deprecation struct {
+	// Sunset is when the route stops being served. Zero means no date is set yet.
+	Sunset time.Time
+	// Successor is the path clients should migrate to, if any
+	Successor string
+}
+
+// setHeaders writes the deprecation headers for a response
+func (d Deprecation) setHeaders(w http.ResponseWriter) {
+	w.Header().Set("Deprecation", "true")
+	if !d.Sunset.IsZero() {
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		w.Header().Set("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+}
+
+// versionHeader tags responses with the API version that served them
+func versionHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", APIVersion)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// legacyAlias marks requests to unversioned /api paths as deprecated and
+// points clients at the matching /api/v1 path
+func legacyAlias(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		successor := APIPrefix + strings.TrimPrefix(r.URL.Path, LegacyAPIPrefix)
+		if r.URL.RawQuery != "" {
+			successor += "?" + r.URL.RawQuery
+		}
+		Deprecation{Successor: successor}.setHeaders(w)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAPIPath reports whether a request path belongs to the API rather than
+// the static dashboard
+func isAPIPath(path string) bool {
+	return path == LegacyAPIPrefix || strings.HasPrefix(path, LegacyAPIPrefix+"/")
+}
+
+// apiMethods are the HTTP methods used by API routes
+var apiMethods = []string{"GET", "POST", "PUT", "DELETE"}
+
+// handleNotFound returns a JSON error envelope for unknown API paths and
+// falls through to the static file server for everything else
+func (s *Server) handleNotFound(router *mux.Router, static http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAPIPath(r.URL.Path) {
+			static.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed := allowedMethods(router, r); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		s.writeError(w, http.StatusNotFound, "Endpoint not found")
+	})
+}
+
+// allowedMethods lists the methods a request path is routed for. gorilla/mux
+// loses method mismatches inside subrouters when a later route shares the
+// prefix, so the path is probed with each method instead.
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range apiMethods {
+		if method == r.Method {
+			continue
+		}
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// mountAPI registers the API routes under the versioned prefix and under
+// the legacy unversioned prefix. The versioned prefix is mounted first so
+// /api/v1 paths are never treated as legacy paths.
+func (s *Server) mountAPI(router *mux.Router, register func(api *mux.Router)) {
+	v1 := router.PathPrefix(APIPrefix).Subrouter()
+	v1.Use(versionHeader)
+	register(v1)
+
+	legacy := router.PathPrefix(LegacyAPIPrefix).Subrouter()
+	legacy.Use(versionHeader, legacyAlias)
+	register(legacy)
+}
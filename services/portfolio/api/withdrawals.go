@@ -0,0 +1,178 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/strike"
+)
+
+// withdrawalStatuses are the values accepted by the status filter of
+// /strike/withdrawals
+var withdrawalStatuses = []string{
+	db.WithdrawalPendingApproval, db.WithdrawalApproved, db.WithdrawalExecuting,
+	db.WithdrawalExecuted, db.WithdrawalFailed, db.WithdrawalRejected,
+}
+
+// handleGetWithdrawals handles GET /api/strike/withdrawals, the log of
+// withdrawals proposed by the Strike balance collector, newest first
+func (s *Server) handleGetWithdrawals(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if fieldErr := validateEnum("status", status, withdrawalStatuses); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	var statuses []string
+	if status != "" {
+		statuses = append(statuses, status)
+	}
+	withdrawals, err := s.db.GetStrikeWithdrawals(statuses...)
+	if err != nil {
+		log.Printf("handleGetWithdrawals: failed to get withdrawals: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get Strike withdrawals")
+		return
+	}
+
+	now := time.Now()
+	infos := make([]WithdrawalInfo, 0, len(withdrawals))
+	for _, withdrawal := range withdrawals {
+		infos = append(infos, WithdrawalInfo{StrikeWithdrawal: withdrawal, Stuck: strike.Stuck(withdrawal, now)})
+	}
+	s.writeJSON(w, APIResponse{Success: true, Data: infos})
+}
+
+// WithdrawalInfo is a withdrawal as listed by /strike/withdrawals. Stuck is
+// set for one left executing, e.g. by a collector crash mid-payment, which
+// blocks new withdrawals until it is resolved.
+type WithdrawalInfo struct {
+	db.StrikeWithdrawal
+	Stuck bool `json:"stuck,omitempty"`
+}
+
+// ResolveWithdrawalRequest is the outcome of a stuck withdrawal as found on
+// Strike by looking up its quote
+type ResolveWithdrawalRequest struct {
+	Status    string `json:"status"` // executed or failed
+	PaymentID string `json:"payment_id"`
+	Error     string `json:"error"`
+}
+
+// resolvedStatuses are the outcomes a stuck withdrawal can be resolved to
+var resolvedStatuses = []string{db.WithdrawalExecuted, db.WithdrawalFailed}
+
+// handleResolveWithdrawal handles POST /api/strike/withdrawals/{id}/resolve,
+// recording whether a stuck withdrawal was paid. Withdrawals still within
+// strike.StuckAfter may yet be finished by the collector and are refused.
+func (s *Server) handleResolveWithdrawal(w http.ResponseWriter, r *http.Request) {
+	id, fieldErr := parsePathID(r, "withdrawal")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	var req ResolveWithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+	if req.Status == "" {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeRequired, Field: "status", Message: "status must be executed or failed"})
+		return
+	}
+	if fieldErr := validateEnum("status", req.Status, resolvedStatuses); fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+	var reason string
+	if req.Status == db.WithdrawalFailed {
+		reason = strings.TrimSpace(req.Error)
+		if reason == "" {
+			reason = "interrupted while executing, not paid on Strike"
+		}
+	}
+
+	withdrawal, err := s.db.GetStrikeWithdrawal(id)
+	switch {
+	case err == sql.ErrNoRows:
+		s.writeError(w, http.StatusNotFound, "Withdrawal not found")
+		return
+	case err != nil:
+		log.Printf("handleResolveWithdrawal: failed to get withdrawal %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get withdrawal")
+		return
+	}
+	if !strike.Stuck(*withdrawal, time.Now()) {
+		s.writeValidationError(w, &FieldError{Code: ErrCodeConflict, Field: "status",
+			Message: fmt.Sprintf("Withdrawal is not stuck executing (for %s)", strike.StuckAfter)})
+		return
+	}
+
+	err = s.db.ResolveStrikeWithdrawal(id, req.Status, strings.TrimSpace(req.PaymentID), reason)
+	switch {
+	case errors.Is(err, db.ErrStatusChanged):
+		s.writeValidationError(w, &FieldError{Code: ErrCodeConflict, Field: "status", Message: "Withdrawal is no longer executing"})
+		return
+	case err != nil:
+		log.Printf("handleResolveWithdrawal: failed to resolve withdrawal %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to update withdrawal")
+		return
+	}
+	log.Printf("Strike withdrawal #%d resolved as %s", id, req.Status)
+
+	withdrawal, err = s.db.GetStrikeWithdrawal(id)
+	if err != nil {
+		log.Printf("handleResolveWithdrawal: failed to get withdrawal %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get withdrawal")
+		return
+	}
+	s.writeJSON(w, APIResponse{Success: true, Data: withdrawal})
+}
+
+// handleApproveWithdrawal handles POST /api/strike/withdrawals/{id}/approve.
+// The collector sends approved withdrawals on its next run.
+func (s *Server) handleApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
+	s.decideWithdrawal(w, r, db.WithdrawalApproved)
+}
+
+// handleRejectWithdrawal handles POST /api/strike/withdrawals/{id}/reject
+func (s *Server) handleRejectWithdrawal(w http.ResponseWriter, r *http.Request) {
+	s.decideWithdrawal(w, r, db.WithdrawalRejected)
+}
+
+// decideWithdrawal moves a withdrawal awaiting approval to status
+func (s *Server) decideWithdrawal(w http.ResponseWriter, r *http.Request, status string) {
+	id, fieldErr := parsePathID(r, "withdrawal")
+	if fieldErr != nil {
+		s.writeValidationError(w, fieldErr)
+		return
+	}
+
+	err := s.db.TransitionStrikeWithdrawal(id, db.WithdrawalPendingApproval, status)
+	switch {
+	case err == sql.ErrNoRows:
+		s.writeError(w, http.StatusNotFound, "Withdrawal not found")
+		return
+	case errors.Is(err, db.ErrStatusChanged):
+		s.writeValidationError(w, &FieldError{Code: ErrCodeConflict, Field: "status", Message: "Withdrawal is not awaiting approval"})
+		return
+	case err != nil:
+		log.Printf("decideWithdrawal: failed to set withdrawal %d to %s: %v", id, status, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to update withdrawal")
+		return
+	}
+
+	withdrawal, err := s.db.GetStrikeWithdrawal(id)
+	if err != nil {
+		log.Printf("decideWithdrawal: failed to get withdrawal %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get withdrawal")
+		return
+	}
+	s.writeJSON(w, APIResponse{Success: true, Data: withdrawal})
+}
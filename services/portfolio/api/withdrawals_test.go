@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/strike"
+	"github.com/brewgator/lightning-node-tools/internal/testutils"
+)
+
+func TestWithdrawalApproval(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.db.Close()
+
+	do := func(router http.Handler, method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	withdrawal := &db.StrikeWithdrawal{Amount: 150000, DestinationType: db.WithdrawalLightning, Status: db.WithdrawalPendingApproval}
+	testutils.AssertNoError(t, server.db.InsertStrikeWithdrawal(withdrawal))
+	path := fmt.Sprintf("/api/v1/strike/withdrawals/%d", withdrawal.ID)
+
+	// Approvals are refused on the public listener
+	testutils.AssertEqual(t, do(server.router, "POST", path+"/approve").Code, http.StatusForbidden)
+
+	rr := do(server.adminRouter, "POST", path+"/approve")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var approved struct {
+		Data db.StrikeWithdrawal `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &approved))
+	testutils.AssertEqual(t, approved.Data.Status, db.WithdrawalApproved)
+
+	// Only withdrawals awaiting approval can be decided
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", path+"/reject").Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", "/api/v1/strike/withdrawals/999/approve").Code, http.StatusNotFound)
+
+	rr = do(server.router, "GET", "/api/v1/strike/withdrawals?status=approved")
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var listed struct {
+		Data []db.StrikeWithdrawal `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	testutils.AssertEqual(t, len(listed.Data), 1)
+	testutils.AssertEqual(t, do(server.router, "GET", "/api/v1/strike/withdrawals?status=sent").Code, http.StatusBadRequest)
+}
+
+func TestResolveStuckWithdrawal(t *testing.T) {
+	server := setupTestServer(t)
+	dbPath := testutils.CreateTestDBPath(t)
+	database, err := db.NewDatabase(dbPath)
+	testutils.AssertNoError(t, err)
+	server.db.Close()
+	server.db = database
+	defer server.db.Close()
+
+	do := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	withdrawal := &db.StrikeWithdrawal{Amount: 150000, DestinationType: db.WithdrawalLightning, Status: db.WithdrawalExecuting}
+	testutils.AssertNoError(t, server.db.InsertStrikeWithdrawal(withdrawal))
+	path := fmt.Sprintf("/api/v1/strike/withdrawals/%d/resolve", withdrawal.ID)
+
+	// A withdrawal the collector may still be paying is left alone
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", path, `{"status": "failed"}`).Code, http.StatusBadRequest)
+
+	// Once the collector was interrupted long enough ago it is flagged...
+	conn, err := sql.Open("sqlite3", dbPath)
+	testutils.AssertNoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec(`UPDATE strike_withdrawals SET updated_at = ? WHERE id = ?`,
+		time.Now().UTC().Add(-strike.StuckAfter), withdrawal.ID)
+	testutils.AssertNoError(t, err)
+
+	var listed struct {
+		Data []WithdrawalInfo `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(do(server.router, "GET", "/api/v1/strike/withdrawals", "").Body.Bytes(), &listed))
+	testutils.AssertEqual(t, len(listed.Data), 1)
+	testutils.AssertEqual(t, listed.Data[0].Stuck, true)
+
+	// ...and can be resolved on the admin listener with what Strike reports
+	testutils.AssertEqual(t, do(server.router, "POST", path, `{"status": "executed"}`).Code, http.StatusForbidden)
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", path, `{"status": "rejected"}`).Code, http.StatusBadRequest)
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", "/api/v1/strike/withdrawals/999/resolve", `{"status": "failed"}`).Code, http.StatusNotFound)
+
+	rr := do(server.adminRouter, "POST", path, `{"status": "executed", "payment_id": "strike-payment-1"}`)
+	testutils.AssertEqual(t, rr.Code, http.StatusOK)
+	var resolved struct {
+		Data db.StrikeWithdrawal `json:"data"`
+	}
+	testutils.AssertNoError(t, json.Unmarshal(rr.Body.Bytes(), &resolved))
+	testutils.AssertEqual(t, resolved.Data.Status, db.WithdrawalExecuted)
+	testutils.AssertEqual(t, resolved.Data.PaymentID, "strike-payment-1")
+
+	testutils.AssertEqual(t, do(server.adminRouter, "POST", path, `{"status": "failed"}`).Code, http.StatusBadRequest)
+}
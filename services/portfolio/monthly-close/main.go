@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lock"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/statement"
+)
+
+// MonthlyClose freezes each finished month into an immutable statement and
+// archives it as CSV and PDF. Only the month that just ended is closed automatically;
+// older months can be closed with --month.
+type MonthlyClose struct {
+	db         *db.Database
+	archiveDir string
+}
+
+func main() {
+	var (
+		dbPath      = flag.String("db", "data/portfolio.db", "Path to SQLite database")
+		profile     = flag.String("profile", db.DefaultProfile, db.ProfileFlagUsage)
+		archiveDir  = flag.String("archive-dir", "data/statements", "Directory for statement CSV and PDF archives")
+		interval    = flag.Duration("interval", time.Hour, "How often to check whether last month is closed")
+		month       = flag.String("month", "", "Close this month (YYYY-MM) once and exit")
+		oneshot     = flag.Bool("oneshot", false, "Run once and exit (for cron or testing)")
+		mockMode    = flag.Bool("mock", false, "Use mock database tables")
+		ignoreMode  = flag.Bool("ignore-mode-check", false, db.ModeCheckFlagUsage)
+		dryRun      = flag.Bool("dry-run", false, db.DryRunFlagUsage)
+		verboseLogs = flag.Bool("verbose-logs", false, redact.FlagUsage)
+		metricsAddr = flag.String("metrics-addr", "", metrics.AddrFlagUsage)
+	)
+	flag.Parse()
+	redact.SetVerbose(*verboseLogs)
+
+	// A dry run closes one month and exits
+	if *dryRun {
+		*oneshot = true
+	}
+
+	profilePath, err := db.ProfilePath(*dbPath, *profile)
+	if err != nil {
+		log.Fatalf("Invalid --profile: %v", err)
+	}
+	*dbPath = profilePath
+
+	// Ensure data directory exists
+	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	instance, err := lock.AcquireUnlessDryRun(*dbPath, "monthly-close", *mockMode, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer instance.Release()
+
+	openDatabase := db.NewDatabaseWithMockMode
+	if *dryRun {
+		openDatabase = db.OpenDryRun
+	}
+	database, err := openDatabase(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+	if *dryRun {
+		defer database.LogDryRun(redact.Verbose())
+	}
+
+	if !*ignoreMode {
+		if err := database.CheckMode(time.Now()); err != nil {
+			log.Fatalf("❌ %v; check --mock, or pass --ignore-mode-check to start anyway", err)
+		}
+	}
+
+	if *mockMode {
+		fmt.Println("📊 Using mock database tables (data will not affect real data)")
+	}
+
+	if *dryRun {
+		// The statement files are written too, so keep them out of the archive
+		*archiveDir, err = os.MkdirTemp("", "lnt-dry-run-statements-*")
+		if err != nil {
+			log.Fatalf("Failed to create a directory for dry run statements: %v", err)
+		}
+		defer os.RemoveAll(*archiveDir)
+	}
+
+	closer := &MonthlyClose{db: database, archiveDir: *archiveDir}
+
+	if *month != "" {
+		start, err := statement.ParseMonth(*month)
+		if err != nil {
+			log.Fatalf("Invalid --month: %v", err)
+		}
+		if err := closer.run(start); err != nil {
+			log.Fatalf("Monthly close failed: %v", err)
+		}
+		return
+	}
+
+	if *oneshot {
+		if err := closer.run(statement.PreviousMonth(time.Now())); err != nil {
+			log.Fatalf("Monthly close failed: %v", err)
+		}
+		return
+	}
+
+	if err := metrics.Default.Serve(*metricsAddr, metricsNamespace); err != nil {
+		log.Fatalf("Failed to serve metrics: %v", err)
+	}
+
+	// Set up signal handling for graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// Closing is idempotent, so a short interval only makes sure the month is
+	// closed soon after it ends or after downtime.
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Starting monthly close, checking every %v...\n", *interval)
+
+	if err := closer.run(statement.PreviousMonth(time.Now())); err != nil {
+		log.Printf("Monthly close failed: %v", err)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := closer.run(statement.PreviousMonth(time.Now())); err != nil {
+				log.Printf("Monthly close failed: %v", err)
+			}
+		case <-sigChan:
+			fmt.Println("Received shutdown signal, exiting...")
+			return
+		}
+	}
+}
+
+// collectorName is recorded in the collector_runs table
+const collectorName = "monthly-close"
+
+// metricsNamespace prefixes the collector's Prometheus metric names
+const metricsNamespace = "monthly_close"
+
+// run closes the month starting at start unless it is already closed, and
+// rewrites missing archives for a closed month
+func (c *MonthlyClose) run(start time.Time) error {
+	month := start.Format(statement.MonthLayout)
+
+	existing, err := c.db.GetStatement(month)
+	if err != nil {
+		return fmt.Errorf("failed to check statement for %s: %w", month, err)
+	}
+	if existing != nil {
+		return statement.ArchiveMissing(c.db, existing, c.archiveDir)
+	}
+
+	return c.db.RecordCollectorRun(collectorName, func(run *db.CollectorRun) error {
+		closed, err := statement.Close(c.db, start, c.archiveDir, time.Now())
+		if errors.Is(err, db.ErrStatementExists) {
+			return nil
+		}
+		if closed != nil {
+			run.ItemsInserted = 1
+			run.ResumePoint = month
+		}
+		if err != nil {
+			return fmt.Errorf("failed to close %s: %w", month, err)
+		}
+
+		fmt.Printf("✅ Closed %s: total %s, %s routing fees, archived to %s\n",
+			month, redact.Sats(closed.TotalPortfolio), redact.Sats(closed.ForwardingFees), c.archiveDir)
+		return nil
+	})
+}
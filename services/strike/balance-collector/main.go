@@ -1,18 +1,20 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/envfile"
+	"github.com/brewgator/lightning-node-tools/internal/lock"
+	"github.com/brewgator/lightning-node-tools/internal/metrics"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
 	"github.com/brewgator/lightning-node-tools/internal/strike"
 )
 
@@ -23,73 +25,53 @@ type Config struct {
 	CurrencyFilter     string // Optional: only track specific currency (e.g., "BTC")
 }
 
+// collectorName is recorded in the collector_runs table
+const collectorName = "strike-balance"
+
+// metricsNamespace prefixes the collector's Prometheus metric names
+const metricsNamespace = "strike_balance_collector"
+
 type BalanceCollector struct {
 	config   *Config
 	db       *db.Database
 	mockMode bool
-}
-
-// loadEnv loads environment variables from .env file
-func loadEnv(envPath string) error {
-	file, err := os.Open(envPath)
-	if err != nil {
-		// .env file is optional, so don't fail if it doesn't exist
-		return nil
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.Trim(strings.TrimSpace(parts[1]), "\"")
-
-		// Only set if not already set in environment
-		if os.Getenv(key) == "" {
-			os.Setenv(key, value)
-		}
-	}
-
-	return scanner.Err()
+	// withdrawer moves BTC off Strike past a threshold; nil when disabled
+	withdrawer *strike.Withdrawer
 }
 
 func main() {
-	// Try to load .env file from project root
-	// This allows reading STRIKE_API_KEY from .env
-	exePath, err := os.Executable()
-	if err == nil {
-		exeDir := filepath.Dir(exePath)
-		// If running from bin/, go up one level to project root
-		projectRoot := filepath.Dir(exeDir)
-		envPath := filepath.Join(projectRoot, ".env")
-		if err := loadEnv(envPath); err != nil {
-			log.Printf("Warning: Failed to load .env file: %v", err)
-		}
-	}
-
-	// Also try loading from current directory (useful for development)
-	if err := loadEnv(".env"); err != nil {
-		log.Printf("Warning: Failed to load .env from current directory: %v", err)
+	// STRIKE_API_KEY can be kept in the project's .env, or the working
+	// directory's while developing
+	if err := envfile.LoadProject(); err != nil {
+		log.Printf("Warning: Failed to load .env file: %v", err)
 	}
 
 	var (
-		dbPath   = flag.String("db", "data/portfolio.db", "Path to SQLite database")
-		interval = flag.Duration("interval", 15*time.Minute, "Collection interval")
-		oneshot  = flag.Bool("oneshot", false, "Run once and exit (for testing)")
-		mockMode = flag.Bool("mock", false, "Use mock data for testing without Strike API")
-		apiKey   = flag.String("api-key", "", "Strike API key (or set STRIKE_API_KEY env var or in .env file)")
-		currency = flag.String("currency", "", "Optional: only track specific currency (BTC, USD, etc.)")
+		dbPath      = flag.String("db", "data/portfolio.db", "Path to SQLite database")
+		profile     = flag.String("profile", db.DefaultProfile, db.ProfileFlagUsage)
+		interval    = flag.Duration("interval", 15*time.Minute, "Collection interval")
+		oneshot     = flag.Bool("oneshot", false, "Run once and exit (for testing)")
+		mockMode    = flag.Bool("mock", false, "Use mock data for testing without Strike API")
+		ignoreMode  = flag.Bool("ignore-mode-check", false, db.ModeCheckFlagUsage)
+		dryRun      = flag.Bool("dry-run", false, db.DryRunFlagUsage)
+		verboseLogs = flag.Bool("verbose-logs", false, redact.FlagUsage)
+		apiKey      = flag.String("api-key", "", "Strike API key (or set STRIKE_API_KEY env var or in .env file)")
+		currency    = flag.String("currency", "", "Optional: only track specific currency (BTC, USD, etc.)")
+
+		withdrawThreshold = flag.Int64("withdraw-threshold", 0, "Withdraw BTC once the available Strike balance exceeds this many sats (0 disables)")
+		withdrawKeep      = flag.Int64("withdraw-keep", 0, "Sats left on Strike after a withdrawal")
+		withdrawAddressID = flag.Int64("withdraw-address-id", 0, "ID of the tracked address withdrawals are sent to")
+		withdrawLightning = flag.Bool("withdraw-lightning", false, "Withdraw over Lightning to an invoice from the local LND instead of to an address")
+		withdrawApproval  = flag.String("withdraw-approval", strike.ApprovalManual, "manual: withdrawals wait for approval through the API; auto: they are sent right away")
+		metricsAddr       = flag.String("metrics-addr", "", metrics.AddrFlagUsage)
 	)
 	flag.Parse()
+	redact.SetVerbose(*verboseLogs)
+
+	// A dry run collects once and exits
+	if *dryRun {
+		*oneshot = true
+	}
 
 	// Priority order: CLI flag > Environment variable > .env file
 	// Get API key from environment if not provided via flag
@@ -102,17 +84,42 @@ func main() {
 		log.Fatal("❌ Strike API key required! Use --api-key flag, STRIKE_API_KEY environment variable, or add to .env file")
 	}
 
+	profilePath, err := db.ProfilePath(*dbPath, *profile)
+	if err != nil {
+		log.Fatalf("Invalid --profile: %v", err)
+	}
+	*dbPath = profilePath
+
 	// Ensure data directory exists
 	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
 		log.Fatalf("Failed to create data directory: %v", err)
 	}
 
+	instance, err := lock.AcquireUnlessDryRun(*dbPath, "strike-balance-collector", *mockMode, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer instance.Release()
+
 	// Initialize database with mock mode support
-	database, err := db.NewDatabaseWithMockMode(*dbPath, *mockMode)
+	openDatabase := db.NewDatabaseWithMockMode
+	if *dryRun {
+		openDatabase = db.OpenDryRun
+	}
+	database, err := openDatabase(*dbPath, *mockMode)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.Close()
+	if *dryRun {
+		defer database.LogDryRun(redact.Verbose())
+	}
+
+	if !*ignoreMode {
+		if err := database.CheckMode(time.Now()); err != nil {
+			log.Fatalf("❌ %v; check --mock, or pass --ignore-mode-check to start anyway", err)
+		}
+	}
 
 	if *mockMode {
 		fmt.Println("📊 Using mock database tables (data will not affect real data)")
@@ -128,6 +135,20 @@ func main() {
 		fmt.Println("⚡ Connected to Strike API")
 	}
 
+	// A dry run must not move funds, so it leaves withdrawals out
+	var withdrawer *strike.Withdrawer
+	if *withdrawThreshold > 0 && !*dryRun {
+		withdrawer, err = newWithdrawer(database, strikeClient, *mockMode, strike.WithdrawalPolicy{
+			Threshold: *withdrawThreshold,
+			Keep:      *withdrawKeep,
+			Approval:  *withdrawApproval,
+		}, *withdrawAddressID, *withdrawLightning)
+		if err != nil {
+			log.Fatalf("Invalid withdrawal settings: %v", err)
+		}
+		fmt.Printf("🏦 Withdrawing above %s (%s approval)\n", redact.Sats(*withdrawThreshold), *withdrawApproval)
+	}
+
 	config := &Config{
 		DatabasePath:       *dbPath,
 		CollectionInterval: *interval,
@@ -136,9 +157,10 @@ func main() {
 	}
 
 	collector := &BalanceCollector{
-		config:   config,
-		db:       database,
-		mockMode: *mockMode,
+		config:     config,
+		db:         database,
+		mockMode:   *mockMode,
+		withdrawer: withdrawer,
 	}
 
 	if *oneshot {
@@ -150,6 +172,10 @@ func main() {
 		return
 	}
 
+	if err := metrics.Default.Serve(*metricsAddr, metricsNamespace); err != nil {
+		log.Fatalf("Failed to serve metrics: %v", err)
+	}
+
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -178,13 +204,18 @@ func main() {
 	}
 }
 
+// collectBalances runs one recorded collection
 func (c *BalanceCollector) collectBalances() error {
+	return c.db.RecordCollectorRun(collectorName, c.collectBalanceSnapshots)
+}
+
+func (c *BalanceCollector) collectBalanceSnapshots(run *db.CollectorRun) error {
 	currentTime := time.Now()
 	fmt.Printf("[%s] Collecting Strike balances...\n",
 		currentTime.Format("2006-01-02 15:04:05"))
 
 	if c.mockMode {
-		return c.collectMockBalances()
+		return c.collectMockBalances(run)
 	}
 
 	if c.config.StrikeClient == nil {
@@ -221,21 +252,29 @@ func (c *BalanceCollector) collectBalances() error {
 
 		if err := c.db.InsertStrikeBalanceSnapshot(snapshot); err != nil {
 			log.Printf("Warning: failed to insert Strike balance for %s: %v", balance.Currency, err)
+			run.Errors++
 			continue
 		}
 
-		fmt.Printf("  💰 %s: Available=%d, Total=%d\n",
+		fmt.Printf("  💰 %s: Available=%s, Total=%s\n",
 			balance.Currency,
-			balance.Available,
-			balance.Total)
+			redact.Amount(balance.Available),
+			redact.Amount(balance.Total))
 		insertedCount++
 	}
 
+	run.ItemsInserted = int64(insertedCount)
 	fmt.Printf("✅ Inserted %d Strike balance snapshots\n", insertedCount)
+
+	for _, balance := range balances {
+		if balance.Currency == "BTC" {
+			c.withdraw(balance.Available, run)
+		}
+	}
 	return nil
 }
 
-func (c *BalanceCollector) collectMockBalances() error {
+func (c *BalanceCollector) collectMockBalances(run *db.CollectorRun) error {
 	// Create mock Strike balance data for testing
 	now := time.Now()
 
@@ -267,16 +306,32 @@ func (c *BalanceCollector) collectMockBalances() error {
 
 		if err := c.db.InsertStrikeBalanceSnapshot(balance); err != nil {
 			log.Printf("Warning: failed to insert mock Strike balance for %s: %v", balance.Currency, err)
+			run.Errors++
 			continue
 		}
 
-		fmt.Printf("  💰 %s: Available=%d, Total=%d (mock)\n",
+		fmt.Printf("  💰 %s: Available=%s, Total=%s (mock)\n",
 			balance.Currency,
-			balance.Available,
-			balance.Total)
+			redact.Amount(balance.Available),
+			redact.Amount(balance.Total))
 		insertedCount++
 	}
 
+	run.ItemsInserted = int64(insertedCount)
 	fmt.Printf("✅ Inserted %d mock Strike balance snapshots\n", insertedCount)
+
+	c.withdraw(mockBalances[0].Available, run)
 	return nil
 }
+
+// withdraw runs the withdrawal automation with the available BTC balance.
+// Its failures are counted on the run but do not fail the collection.
+func (c *BalanceCollector) withdraw(available int64, run *db.CollectorRun) {
+	if c.withdrawer == nil {
+		return
+	}
+	if err := c.withdrawer.Run(available); err != nil {
+		log.Printf("Warning: Strike withdrawal failed: %v", err)
+		run.Errors++
+	}
+}
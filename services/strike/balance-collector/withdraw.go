@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/notify"
+	"github.com/brewgator/lightning-node-tools/internal/strike"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// newWithdrawer sets up the withdrawal automation for policy, sending to the
+// tracked address addressID or, with lightning, to the local node. Mock mode
+// pays with mockPayer instead of Strike.
+func newWithdrawer(database *db.Database, client *strike.Client, mockMode bool, policy strike.WithdrawalPolicy, addressID int64, lightning bool) (*strike.Withdrawer, error) {
+	switch {
+	case lightning && addressID != 0:
+		return nil, fmt.Errorf("--withdraw-address-id and --withdraw-lightning are exclusive")
+	case lightning:
+		policy.DestinationType = db.WithdrawalLightning
+	case addressID != 0:
+		address, err := database.GetOnchainAddressByID(addressID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tracked address %d: %w", addressID, err)
+		}
+		if address == nil {
+			return nil, fmt.Errorf("tracked address %d not found", addressID)
+		}
+		if utils.ValidateXPub(address.Address) {
+			return nil, fmt.Errorf("tracked address %d is an xpub, pick one of its addresses", addressID)
+		}
+		policy.DestinationType = db.WithdrawalOnchain
+		policy.Address = address.Address
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	withdrawer := &strike.Withdrawer{Policy: policy, DB: database, Payer: client, Invoice: lnd.AddInvoice}
+	if mockMode {
+		withdrawer.Payer = &mockPayer{}
+		withdrawer.Invoice = func(amount int64, memo string) (string, error) {
+			return fmt.Sprintf("lnbcrt%dmock", amount), nil
+		}
+	}
+
+	telegram := notify.Telegram{BotToken: os.Getenv("BOT_TOKEN"), ChatID: os.Getenv("CHAT_ID")}
+	if telegram.Enabled() {
+		withdrawer.Notify = func(message string) {
+			if err := telegram.Send(message); err != nil {
+				fmt.Printf("⚠️  %v\n", err)
+			}
+		}
+	}
+	return withdrawer, nil
+}
+
+// mockPayer accepts every payment without calling Strike
+type mockPayer struct {
+	payments int
+}
+
+func (p *mockPayer) QuoteOnchainPayment(address string, amount int64) (*strike.PaymentQuote, error) {
+	return p.quote(), nil
+}
+
+func (p *mockPayer) QuoteLightningPayment(invoice string) (*strike.PaymentQuote, error) {
+	return p.quote(), nil
+}
+
+func (p *mockPayer) ExecuteQuote(quoteID string) (*strike.Payment, error) {
+	return &strike.Payment{PaymentID: "mock-payment-" + quoteID, State: strike.PaymentCompleted}, nil
+}
+
+func (p *mockPayer) quote() *strike.PaymentQuote {
+	p.payments++
+	return &strike.PaymentQuote{
+		PaymentQuoteID: fmt.Sprint(p.payments),
+		TotalFee:       strike.Money{Amount: "0.00000250", Currency: "BTC"},
+	}
+}
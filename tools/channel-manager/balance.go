@@ -56,7 +56,7 @@ func showChannelBalances() {
 
 // displayChannel displays balance information for a single channel
 func displayChannel(channel Channel) {
-	alias := getNodeAlias(channel.RemotePubkey)
+	alias := channelLabel(channel)
 	capacity, _ := strconv.ParseInt(channel.Capacity, 10, 64)
 	local, _ := strconv.ParseInt(channel.LocalBalance, 10, 64)
 	remote, _ := strconv.ParseInt(channel.RemoteBalance, 10, 64)
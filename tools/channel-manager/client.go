@@ -3,8 +3,12 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"strconv"
+	"sync"
 
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/envfile"
 	"github.com/brewgator/lightning-node-tools/internal/lnd"
 )
 
@@ -38,30 +42,62 @@ func getNodeAlias(pubkey string) string {
 	return lnd.GetNodeAlias(pubkey)
 }
 
-// getCurrentChannelPolicy gets the current policy for a channel including time lock delta
-func getCurrentChannelPolicy(channelID string) (baseFee, feeRatePpm, timeLockDelta string, err error) {
+var (
+	channelNamesOnce sync.Once
+	channelNames     map[string]string
+)
+
+// channelLabel returns the name given to channel with lnt channel-names,
+// or its peer's alias when it has none. Names are read from the database
+// of LNT_DB and LNT_PROFILE, or the project's data/portfolio.db.
+func channelLabel(channel Channel) string {
+	channelNamesOnce.Do(func() {
+		fallback, err := envfile.ProjectPath("data/portfolio.db")
+		if err != nil {
+			fallback = "data/portfolio.db"
+		}
+		path, err := db.EnvPath(fallback)
+		if err == nil {
+			channelNames, err = db.ReadChannelNames(path)
+		}
+		if err != nil {
+			log.Printf("Warning: failed to read channel names, showing peer aliases: %v", err)
+		}
+	})
+	if name := channelNames[channel.ChanID]; name != "" {
+		return name
+	}
+	return getNodeAlias(channel.RemotePubkey)
+}
+
+// getOurPolicy returns our side's routing policy for a channel
+func getOurPolicy(channelID string) (*lnd.RoutingPolicy, error) {
 	// Get our node's public key to determine which policy is ours
 	ourPubkey, err := lnd.GetNodePubkey()
 	if err != nil {
-		// Fall back to defaults if we can't get our pubkey
-		return "1000", "1", "40", nil
+		return nil, fmt.Errorf("failed to get node pubkey: %v", err)
 	}
 
 	// Get detailed channel information
 	channelInfo, err := lnd.GetChannelInfo(channelID)
 	if err != nil {
-		// Fall back to defaults if we can't get channel info
-		return "1000", "1", "40", nil
+		return nil, fmt.Errorf("failed to get channel info: %v", err)
 	}
 
 	// Determine which policy is ours based on our pubkey
-	var ourPolicy lnd.RoutingPolicy
 	if channelInfo.Node1Pub == ourPubkey {
-		ourPolicy = channelInfo.Node1Policy
+		return &channelInfo.Node1Policy, nil
 	} else if channelInfo.Node2Pub == ourPubkey {
-		ourPolicy = channelInfo.Node2Policy
-	} else {
-		// Fall back to defaults if we're not found in the channel
+		return &channelInfo.Node2Policy, nil
+	}
+	return nil, fmt.Errorf("our node is not a party to channel %s", channelID)
+}
+
+// getCurrentChannelPolicy gets the current policy for a channel including time lock delta
+func getCurrentChannelPolicy(channelID string) (baseFee, feeRatePpm, timeLockDelta string, err error) {
+	ourPolicy, err := getOurPolicy(channelID)
+	if err != nil {
+		// Fall back to defaults if we can't find our policy
 		return "1000", "1", "40", nil
 	}
 
@@ -73,8 +109,29 @@ func getCurrentChannelPolicy(channelID string) (baseFee, feeRatePpm, timeLockDel
 	return ourPolicy.FeeBaseMsat, strconv.FormatInt(feeRatePpmValue, 10), strconv.FormatUint(uint64(ourPolicy.TimeLockDelta), 10), nil
 }
 
+// getCurrentHTLCLimits gets the min_htlc and max_htlc_msat we advertise for a channel
+func getCurrentHTLCLimits(channelID string) (minHTLCMsat, maxHTLCMsat int64, err error) {
+	ourPolicy, err := getOurPolicy(channelID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	minHTLCMsat, _ = strconv.ParseInt(ourPolicy.MinHtlc, 10, 64)
+	maxHTLCMsat, err = strconv.ParseInt(ourPolicy.MaxHtlcMsat, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid max_htlc_msat %q: %v", ourPolicy.MaxHtlcMsat, err)
+	}
+	return minHTLCMsat, maxHTLCMsat, nil
+}
+
 // setChannelFees updates the fee policy for a specific channel
 func setChannelFees(channelID, baseFee, ppm string) error {
+	return setChannelPolicy(channelID, baseFee, ppm, 0)
+}
+
+// setChannelPolicy updates the fee policy for a specific channel and, when
+// maxHTLCMsat is positive, its max_htlc_msat. Empty fees keep current values.
+func setChannelPolicy(channelID, baseFee, ppm string, maxHTLCMsat int64) error {
 	// Get current channel policy to preserve unspecified values
 	feeReport, err := getFeeReport()
 	if err != nil {
@@ -112,6 +169,9 @@ func setChannelFees(channelID, baseFee, ppm string) error {
 	// Get current time lock delta or use default
 	_, _, timeLockDelta, _ := getCurrentChannelPolicy(channelID)
 	args = append(args, "--time_lock_delta", timeLockDelta)
+	if maxHTLCMsat > 0 {
+		args = append(args, "--max_htlc_msat", strconv.FormatInt(maxHTLCMsat, 10))
+	}
 	args = append(args, "--chan_point", currentFeeInfo.ChannelPoint)
 
 	_, err = lnd.RunLNCLI(args...)
@@ -125,7 +125,7 @@ func showDetailedChannelEarnings() {
 
 	totalEarnings := int64(0)
 	for _, channel := range channels {
-		alias := getNodeAlias(channel.RemotePubkey)
+		alias := channelLabel(channel)
 		if len(alias) > 29 {
 			alias = alias[:26] + "..."
 		}
@@ -255,7 +255,7 @@ func getIncomingChannelAlias(chanID string) string {
 
 	for _, channel := range channels {
 		if channel.ChanID == chanID {
-			alias := getNodeAlias(channel.RemotePubkey)
+			alias := channelLabel(channel)
 			if len(alias) > 15 {
 				alias = alias[:12] + "..."
 			}
@@ -293,7 +293,7 @@ func calculateChannelEarnings(channels []Channel, events []ForwardingEvent) ([]c
 		earnings := channelEarnings[channel.ChanID]
 		forwards := channelForwards[channel.ChanID]
 		if earnings > 0 || forwards > 0 {
-			alias := getNodeAlias(channel.RemotePubkey)
+			alias := channelLabel(channel)
 			if len(alias) > 15 {
 				alias = alias[:12] + "..."
 			}
@@ -64,7 +64,7 @@ func showChannelFees() {
 
 // displayChannelFees displays fee information for a single channel
 func displayChannelFees(channel Channel, feeMap map[string]ChannelFeeReport) {
-	alias := getNodeAlias(channel.RemotePubkey)
+	alias := channelLabel(channel)
 
 	// Truncate alias if too long
 	if len(alias) > 29 {
@@ -242,17 +242,17 @@ func handleBulkSetFees() {
 	successCount := 0
 	for _, channel := range channels {
 		if !channel.Active {
-			fmt.Printf("⏭️  Skipping inactive channel: %s\n", getNodeAlias(channel.RemotePubkey))
+			fmt.Printf("⏭️  Skipping inactive channel: %s\n", channelLabel(channel))
 			continue
 		}
 
 		err := setChannelFees(channel.ChanID, baseFee, ppm)
 		if err != nil {
-			fmt.Printf("❌ Failed to set fees for %s: %v\n", getNodeAlias(channel.RemotePubkey), err)
+			fmt.Printf("❌ Failed to set fees for %s: %v\n", channelLabel(channel), err)
 			continue
 		}
 
-		fmt.Printf("✅ %s\n", getNodeAlias(channel.RemotePubkey))
+		fmt.Printf("✅ %s\n", channelLabel(channel))
 		successCount++
 	}
 
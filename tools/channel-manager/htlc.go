@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Default share of spendable local balance advertised as max_htlc_msat
+const DefaultMaxHTLCRatio = 0.9
+
+const (
+	// minMaxHTLCMsat keeps drained channels advertising a small but usable max_htlc
+	minMaxHTLCMsat int64 = 1_000_000
+	// maxHTLCStepMsat rounds max_htlc down to whole 1k sat steps
+	maxHTLCStepMsat int64 = 1_000_000
+	// maxHTLCUpdateThreshold is the relative change needed before max_htlc is
+	// updated, so small balance moves don't flood the network with gossip
+	maxHTLCUpdateThreshold = 0.2
+)
+
+// recommendedMaxHTLC sizes max_htlc_msat to ratio of the channel's spendable
+// local balance (local balance minus our reserve). The result is rounded down
+// to maxHTLCStepMsat, kept at or above minMaxHTLCMsat and minHTLCMsat, and
+// never exceeds the channel capacity.
+func recommendedMaxHTLC(localBalance, reserve, capacity int64, ratio float64, minHTLCMsat int64) int64 {
+	spendable := localBalance - reserve
+	if spendable < 0 {
+		spendable = 0
+	}
+
+	maxHTLC := int64(float64(spendable*1000) * ratio)
+	maxHTLC -= maxHTLC % maxHTLCStepMsat
+
+	floor := minMaxHTLCMsat
+	if minHTLCMsat > floor {
+		floor = minHTLCMsat
+	}
+	if maxHTLC < floor {
+		maxHTLC = floor
+	}
+	if capacityMsat := capacity * 1000; capacityMsat > 0 && maxHTLC > capacityMsat {
+		maxHTLC = capacityMsat
+	}
+	return maxHTLC
+}
+
+// maxHTLCNeedsUpdate reports whether recommended differs enough from current
+// to be worth a channel update
+func maxHTLCNeedsUpdate(current, recommended int64) bool {
+	if current <= 0 {
+		return recommended > 0
+	}
+	change := math.Abs(float64(recommended-current)) / float64(current)
+	return change >= maxHTLCUpdateThreshold
+}
+
+// planMaxHTLC fills in current and recommended max_htlc_msat for each analysis.
+// Channels whose policy cannot be read are left without a recommendation.
+func planMaxHTLC(analyses []ChannelAnalysis, ratio float64) {
+	for i := range analyses {
+		analysis := &analyses[i]
+
+		minHTLC, currentMax, err := getCurrentHTLCLimits(analysis.Channel.ChanID)
+		if err != nil {
+			fmt.Printf("⚠️  Skipping max_htlc for channel %s: %v\n", analysis.Channel.ChanID, err)
+			continue
+		}
+
+		reserve, _ := strconv.ParseInt(analysis.Channel.LocalChanReserveSat, 10, 64)
+		analysis.CurrentMaxHTLCMsat = currentMax
+		analysis.RecommendedMaxHTLCMsat = recommendedMaxHTLC(
+			analysis.LocalBalance, reserve, analysis.Capacity, ratio, minHTLC,
+		)
+	}
+}
+
+// formatMsatAsSats formats an msat amount as sats for display
+func formatMsatAsSats(msat int64) string {
+	return formatSats(msat / 1000)
+}
@@ -34,6 +34,10 @@ func main() {
 		handleFeeOptimizer()
 	case "open-channel":
 		handleOpenChannel()
+	case "swap-quote":
+		handleSwapQuote()
+	case "mission-control", "mc":
+		handleMissionControl()
 	case "help", "-h", "--help":
 		showHelp()
 	default:
@@ -66,17 +70,33 @@ func showHelp() {
 	fmt.Println("    channel-manager suggest-fees         Analyze and suggest optimal fee adjustments")
 	fmt.Println("    channel-manager fee-optimizer        Automatically apply optimal fee adjustments")
 	fmt.Println("    channel-manager fee-optimizer --dry-run  Preview fee changes without applying")
+	fmt.Println("    channel-manager fee-optimizer --manage-htlc [--htlc-ratio <0-1>]")
+	fmt.Println("                                         Also size max_htlc to spendable local balance (default 0.9)")
 	fmt.Println("")
 	fmt.Println("  Channel Management Commands:")
 	fmt.Println("    channel-manager open-channel --peer <address> --size <sats> --fee-rate <sat/vB>")
 	fmt.Println("                                         Open a new channel to a peer")
 	fmt.Println("")
+	fmt.Println("  Swap Commands:")
+	fmt.Println("    channel-manager swap-quote --inbound <sats> | --outbound <sats> | --channel-id <ID>")
+	fmt.Println("                                         Compare Loop and Boltz swap fees and recommend the cheapest")
+	fmt.Println("")
+	fmt.Println("  Mission Control Commands:")
+	fmt.Println("    channel-manager mission-control      Summarize LND's route success/failure history")
+	fmt.Println("    channel-manager mission-control export [--output <file>]")
+	fmt.Println("                                         Export mission control pairs as JSON")
+	fmt.Println("    channel-manager mission-control reset --confirm")
+	fmt.Println("                                         Clear mission control history")
+	fmt.Println("    channel-manager mc                   Short alias for mission-control")
+	fmt.Println("")
 	fmt.Println("  Examples:")
 	fmt.Println("    channel-manager set-fees --channel-id 12345 --ppm 1 --base-fee 1000")
 	fmt.Println("    channel-manager bulk-set-fees --ppm 2")
 	fmt.Println("    channel-manager suggest-fees")
 	fmt.Println("    channel-manager fee-optimizer --dry-run")
+	fmt.Println("    channel-manager fee-optimizer --manage-htlc --htlc-ratio 0.8 --dry-run")
 	fmt.Println("    channel-manager open-channel --peer 02a1b2c3...@192.168.1.100:9735 --size 1000000 --fee-rate 10")
+	fmt.Println("    channel-manager swap-quote --inbound 2000000")
 	fmt.Println("")
 	fmt.Println("  Help:")
 	fmt.Println("    channel-manager help                 Show this help message")
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+)
+
+// handleMissionControl dispatches the mission-control subcommands
+func handleMissionControl() {
+	subcommand := "show"
+	if len(os.Args) > 2 {
+		subcommand = os.Args[2]
+	}
+
+	switch subcommand {
+	case "show":
+		showMissionControl()
+	case "export":
+		handleMissionControlExport()
+	case "reset":
+		handleMissionControlReset()
+	default:
+		fmt.Printf("Unknown mission-control subcommand: %s\n", subcommand)
+		fmt.Println("Usage: channel-manager mission-control [show|export [--output <file>]|reset --confirm]")
+	}
+}
+
+// showMissionControl summarizes mission control and lists the most recent failures
+func showMissionControl() {
+	pairs, err := lnd.QueryMissionControl()
+	if err != nil {
+		fmt.Printf("Error: failed to query mission control: %v\n", err)
+		return
+	}
+
+	var failed []lnd.MissionControlPair
+	successes := 0
+	for _, pair := range pairs {
+		history := pair.History.Parse()
+		if history.SuccessTime > 0 {
+			successes++
+		}
+		if history.FailTime > 0 {
+			failed = append(failed, pair)
+		}
+	}
+
+	fmt.Println("🧭 Mission Control")
+	fmt.Println(strings.Repeat("─", 100))
+	fmt.Printf("Pairs: %d  │  With successes: %d  │  With failures: %d\n", len(pairs), successes, len(failed))
+
+	if len(failed) == 0 {
+		return
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].History.Parse().FailTime > failed[j].History.Parse().FailTime
+	})
+	if len(failed) > 10 {
+		failed = failed[:10]
+	}
+
+	now := time.Now()
+	fmt.Println("\nMost recent failures:")
+	for _, pair := range failed {
+		history := pair.History.Parse()
+		fmt.Printf("  %-25s → %-25s │ %8s │ %s ago │ %.0f%% now\n",
+			truncateAlias(getNodeAlias(pair.NodeFrom)),
+			truncateAlias(getNodeAlias(pair.NodeTo)),
+			formatMsatAsSats(history.FailAmtMsat),
+			now.Sub(time.Unix(history.FailTime, 0)).Round(time.Minute),
+			history.SuccessProbability(history.FailAmtMsat, now)*100,
+		)
+	}
+}
+
+// handleMissionControlExport writes the mission control pairs as JSON to a
+// file or stdout
+func handleMissionControlExport() {
+	var output string
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--output", "-o":
+			if i+1 >= len(args) {
+				fmt.Printf("Error: Missing value for %s\n", args[i])
+				return
+			}
+			i++
+			output = args[i]
+		default:
+			fmt.Printf("Unknown flag: %s\n", args[i])
+			return
+		}
+	}
+
+	pairs, err := lnd.QueryMissionControl()
+	if err != nil {
+		fmt.Printf("Error: failed to query mission control: %v\n", err)
+		return
+	}
+
+	data, err := json.MarshalIndent(lnd.MissionControlResponse{Pairs: pairs}, "", "  ")
+	if err != nil {
+		fmt.Printf("Error: failed to encode mission control: %v\n", err)
+		return
+	}
+
+	if output == "" {
+		fmt.Println(string(data))
+		return
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		fmt.Printf("Error: failed to write %s: %v\n", output, err)
+		return
+	}
+	fmt.Printf("✅ Exported %d mission control pairs to %s\n", len(pairs), output)
+}
+
+// handleMissionControlReset clears mission control after explicit confirmation
+func handleMissionControlReset() {
+	confirmed := len(os.Args) > 3 && os.Args[3] == "--confirm"
+	if !confirmed {
+		fmt.Println("⚠️  This clears all of LND's learned payment history and can make pathfinding")
+		fmt.Println("   slower until it is relearned. Export it first if you may want it later:")
+		fmt.Println("     channel-manager mission-control export --output mc-backup.json")
+		fmt.Println("   Run again with --confirm to reset.")
+		return
+	}
+
+	if err := lnd.ResetMissionControl(); err != nil {
+		fmt.Printf("Error: failed to reset mission control: %v\n", err)
+		return
+	}
+	fmt.Println("✅ Mission control reset")
+}
+
+// truncateAlias shortens an alias to fit a 25 character column
+func truncateAlias(alias string) string {
+	if len(alias) > 25 {
+		return alias[:22] + "..."
+	}
+	return alias
+}
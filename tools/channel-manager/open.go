@@ -4,57 +4,24 @@ import (
 	"fmt"
 	"os"
 	"strconv"
-	"strings"
 
 	"github.com/brewgator/lightning-node-tools/internal/lnd"
 )
 
 // openChannelToPeer opens a new channel to a peer
 func openChannelToPeer(peerAddress string, channelSize int64, feeRate int64) error {
-	// Parse peer address to extract pubkey
-	peerPubkey := extractPubkeyFromAddress(peerAddress)
-
 	fmt.Printf("Opening channel to peer: %s\n", peerAddress)
 	fmt.Printf("Channel size: %d sats\n", channelSize)
 	fmt.Printf("Fee rate: %d sat/vByte\n", feeRate)
 	fmt.Println()
 
-	// Step 1: Connect to the peer
-	fmt.Printf("Step 1: Connecting to peer...\n")
-	err := lnd.ConnectPeer(peerAddress)
-	if err != nil {
-		return fmt.Errorf("failed to connect to peer: %v", err)
-	}
-	fmt.Printf("✓ Successfully connected to peer\n")
-
-	// Step 2: Verify connection
-	fmt.Printf("Step 2: Verifying connection...\n")
-	peers, err := lnd.ListPeers()
+	// Connects to the peer first unless it is connected already
+	response, err := lnd.ConnectAndOpenChannel(peerAddress, channelSize, feeRate)
 	if err != nil {
-		return fmt.Errorf("failed to list peers: %v", err)
+		return err
 	}
 
-	connected := false
-	for _, peer := range peers {
-		if peer.PubKey == peerPubkey {
-			connected = true
-			break
-		}
-	}
-
-	if !connected {
-		return fmt.Errorf("peer connection verification failed")
-	}
-	fmt.Printf("✓ Peer connection verified\n")
-
-	// Step 3: Open the channel
-	fmt.Printf("Step 3: Opening channel...\n")
-	response, err := lnd.OpenChannel(peerPubkey, channelSize, feeRate)
-	if err != nil {
-		return fmt.Errorf("failed to open channel: %v", err)
-	}
-
-	// Step 4: Output the pending transaction ID
+	// Output the pending transaction ID
 	fmt.Printf("✓ Channel opening initiated successfully!\n")
 	fmt.Println()
 	fmt.Printf("Pending Transaction ID: %s\n", response.FundingTxidStr)
@@ -66,16 +33,6 @@ func openChannelToPeer(peerAddress string, channelSize int64, feeRate int64) err
 	return nil
 }
 
-// extractPubkeyFromAddress extracts the pubkey from a peer address
-// Peer addresses can be in format: pubkey@host:port or just pubkey
-func extractPubkeyFromAddress(peerAddress string) string {
-	if strings.Contains(peerAddress, "@") {
-		parts := strings.Split(peerAddress, "@")
-		return parts[0]
-	}
-	return peerAddress
-}
-
 // handleOpenChannel handles the open-channel command
 func handleOpenChannel() {
 	if len(os.Args) < 8 {
@@ -34,6 +34,10 @@ type ChannelAnalysis struct {
 	DaysSinceForward int
 	Reasoning        string
 	Priority         string // high, medium, low
+
+	// max_htlc_msat management, only filled in with --manage-htlc
+	CurrentMaxHTLCMsat     int64
+	RecommendedMaxHTLCMsat int64
 }
 
 // ChannelCategory constants
@@ -72,13 +76,32 @@ func handleSuggestFees() {
 
 // handleFeeOptimizer automatically applies optimized fees based on analysis
 func handleFeeOptimizer() {
-	var dryRun bool
+	var dryRun, manageHTLC bool
+	htlcRatio := DefaultMaxHTLCRatio
 
-	// Check for --dry-run flag
-	for _, arg := range os.Args[2:] {
-		if arg == "--dry-run" {
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--dry-run":
 			dryRun = true
-			break
+		case "--manage-htlc":
+			manageHTLC = true
+		case "--htlc-ratio":
+			if i+1 >= len(args) {
+				fmt.Printf("Error: Missing value for %s\n", args[i])
+				return
+			}
+			i++
+			ratio, err := strconv.ParseFloat(args[i], 64)
+			if err != nil || ratio <= 0 || ratio > 1 {
+				fmt.Println("Error: --htlc-ratio must be a number greater than 0 and at most 1")
+				return
+			}
+			htlcRatio = ratio
+			manageHTLC = true
+		default:
+			fmt.Printf("Unknown flag: %s\n", args[i])
+			return
 		}
 	}
 
@@ -87,6 +110,9 @@ func handleFeeOptimizer() {
 	} else {
 		fmt.Println("🤖 Running automatic fee optimizer...")
 	}
+	if manageHTLC {
+		fmt.Printf("📏 Managing max_htlc_msat at %.0f%% of spendable local balance\n", htlcRatio*100)
+	}
 
 	analyses, err := analyzeChannelsForFeeOptimization()
 	if err != nil {
@@ -98,46 +124,56 @@ func handleFeeOptimizer() {
 		return
 	}
 
-	// Filter for high and medium priority changes
+	if manageHTLC {
+		planMaxHTLC(analyses, htlcRatio)
+	}
+
+	// Filter for high and medium priority fee changes and meaningful max_htlc changes
 	var toUpdate []ChannelAnalysis
 	for _, analysis := range analyses {
-		if analysis.Priority == "high" || analysis.Priority == "medium" {
-			// Only update if there's a meaningful change
-			if math.Abs(float64(analysis.RecommendedPPM-analysis.CurrentPPM)) >= 10 {
-				toUpdate = append(toUpdate, analysis)
-			}
+		if feeNeedsUpdate(analysis) || htlcNeedsUpdate(analysis) {
+			toUpdate = append(toUpdate, analysis)
 		}
 	}
 
 	if len(toUpdate) == 0 {
-		fmt.Println("✅ All channels already have optimal fees")
+		if manageHTLC {
+			fmt.Println("✅ All channels already have optimal fees and max_htlc")
+		} else {
+			fmt.Println("✅ All channels already have optimal fees")
+		}
 		return
 	}
 
-	fmt.Printf("📊 Found %d channels that would benefit from fee optimization:\n\n", len(toUpdate))
+	fmt.Printf("📊 Found %d channels that would benefit from policy optimization:\n\n", len(toUpdate))
 
 	successCount := 0
 	for _, analysis := range toUpdate {
-		alias := getNodeAlias(analysis.Channel.RemotePubkey)
+		alias := channelLabel(analysis.Channel)
+		changes := describePolicyChanges(analysis)
 
 		if dryRun {
-			fmt.Printf("🔧 Would update %s: %d → %d ppm (%s priority)\n",
-				alias, analysis.CurrentPPM, analysis.RecommendedPPM, analysis.Priority)
+			fmt.Printf("🔧 Would update %s: %s\n", alias, changes)
 			successCount++
+			continue
+		}
+
+		// Empty fee values keep the current fees when only max_htlc changes
+		var baseFee, ppm string
+		if feeNeedsUpdate(analysis) {
+			baseFee = strconv.FormatInt(analysis.RecommendedBase, 10)
+			ppm = strconv.FormatInt(analysis.RecommendedPPM, 10)
+		}
+		var maxHTLC int64
+		if htlcNeedsUpdate(analysis) {
+			maxHTLC = analysis.RecommendedMaxHTLCMsat
+		}
+
+		if err := setChannelPolicy(analysis.Channel.ChanID, baseFee, ppm, maxHTLC); err != nil {
+			fmt.Printf("❌ Failed to update %s: %v\n", alias, err)
 		} else {
-			err := setChannelFees(
-				analysis.Channel.ChanID,
-				strconv.FormatInt(analysis.RecommendedBase, 10),
-				strconv.FormatInt(analysis.RecommendedPPM, 10),
-			)
-
-			if err != nil {
-				fmt.Printf("❌ Failed to update %s: %v\n", alias, err)
-			} else {
-				fmt.Printf("✅ Updated %s: %d → %d ppm (%s priority)\n",
-					alias, analysis.CurrentPPM, analysis.RecommendedPPM, analysis.Priority)
-				successCount++
-			}
+			fmt.Printf("✅ Updated %s: %s\n", alias, changes)
+			successCount++
 		}
 	}
 
@@ -149,6 +185,36 @@ func handleFeeOptimizer() {
 	}
 }
 
+// feeNeedsUpdate reports whether a high or medium priority fee change is large enough to apply
+func feeNeedsUpdate(analysis ChannelAnalysis) bool {
+	if analysis.Priority != "high" && analysis.Priority != "medium" {
+		return false
+	}
+	return math.Abs(float64(analysis.RecommendedPPM-analysis.CurrentPPM)) >= 10
+}
+
+// htlcNeedsUpdate reports whether the channel has a max_htlc recommendation worth applying
+func htlcNeedsUpdate(analysis ChannelAnalysis) bool {
+	if analysis.RecommendedMaxHTLCMsat == 0 {
+		return false
+	}
+	return maxHTLCNeedsUpdate(analysis.CurrentMaxHTLCMsat, analysis.RecommendedMaxHTLCMsat)
+}
+
+// describePolicyChanges summarizes the fee and max_htlc changes for a channel
+func describePolicyChanges(analysis ChannelAnalysis) string {
+	var parts []string
+	if feeNeedsUpdate(analysis) {
+		parts = append(parts, fmt.Sprintf("%d → %d ppm (%s priority)",
+			analysis.CurrentPPM, analysis.RecommendedPPM, analysis.Priority))
+	}
+	if htlcNeedsUpdate(analysis) {
+		parts = append(parts, fmt.Sprintf("max_htlc %s → %s",
+			formatMsatAsSats(analysis.CurrentMaxHTLCMsat), formatMsatAsSats(analysis.RecommendedMaxHTLCMsat)))
+	}
+	return strings.Join(parts, ", ")
+}
+
 // analyzeChannelsForFeeOptimization performs comprehensive channel analysis for fee optimization
 func analyzeChannelsForFeeOptimization() ([]ChannelAnalysis, error) {
 	channels, err := getChannels()
@@ -367,7 +433,7 @@ func displayFeeOptimizationSuggestions(analyses []ChannelAnalysis) {
 	mediumPriorityCount := 0
 
 	for _, analysis := range analyses {
-		alias := getNodeAlias(analysis.Channel.RemotePubkey)
+		alias := channelLabel(analysis.Channel)
 		if len(alias) > 25 {
 			alias = alias[:22] + "..."
 		}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+	"github.com/brewgator/lightning-node-tools/internal/swap"
+)
+
+// handleSwapQuote handles the swap-quote command
+func handleSwapQuote() {
+	config := swap.DefaultConfig()
+	var direction, channelID string
+	var amount int64
+
+	// Parse command line arguments
+	for i := 2; i < len(os.Args); i += 2 {
+		if i+1 >= len(os.Args) {
+			fmt.Printf("Error: Missing value for argument %s\n", os.Args[i])
+			return
+		}
+
+		value := os.Args[i+1]
+		switch os.Args[i] {
+		case "--inbound", "--outbound":
+			var err error
+			amount, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || amount <= 0 {
+				fmt.Printf("Error: Invalid amount: %s\n", value)
+				return
+			}
+			// Inbound liquidity comes from swapping out, outbound from swapping in
+			direction = swap.DirectionOut
+			if os.Args[i] == "--outbound" {
+				direction = swap.DirectionIn
+			}
+		case "--channel-id":
+			channelID = value
+		case "--boltz-api":
+			config.BoltzURL = value
+		case "--loop-rest":
+			config.LoopURL = value
+		case "--loop-macaroon":
+			config.LoopMacaroon = value
+		case "--loop-tls-cert":
+			config.LoopTLSCert = value
+		default:
+			fmt.Printf("Error: Unknown argument: %s\n", os.Args[i])
+			return
+		}
+	}
+
+	if channelID != "" {
+		if direction != "" {
+			fmt.Println("Error: use either --channel-id or --inbound/--outbound, not both")
+			return
+		}
+		var err error
+		direction, amount, err = channelShift(channelID)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+	}
+	if direction == "" {
+		fmt.Println("Error: Missing required arguments for swap-quote command")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  channel-manager swap-quote --inbound <sats> | --outbound <sats> | --channel-id <ID>")
+		fmt.Println("                             [--boltz-api <url>] [--loop-rest <url>] [--loop-macaroon <file>] [--loop-tls-cert <file>]")
+		fmt.Println()
+		fmt.Println("Arguments:")
+		fmt.Println("  --inbound     Inbound liquidity to gain, quoted as a swap out")
+		fmt.Println("  --outbound    Outbound liquidity to gain, quoted as a swap in")
+		fmt.Println("  --channel-id  Quote the swap that brings this channel to half local balance")
+		fmt.Println("  --boltz-api   Boltz API (default " + swap.DefaultBoltzURL + ", empty disables)")
+		fmt.Println("  --loop-rest   loopd REST API (default " + swap.DefaultLoopURL + ", empty disables)")
+		return
+	}
+
+	providers, errs := config.Providers()
+	for _, err := range errs {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	if len(providers) == 0 {
+		fmt.Println("Error: no swap providers available")
+		return
+	}
+
+	comparison, err := swap.Compare(providers, direction, amount)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	printSwapComparison(comparison)
+}
+
+// channelShift returns the swap that brings a channel to half local balance
+func channelShift(channelID string) (string, int64, error) {
+	channels, err := lnd.GetChannels()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to list channels: %w", err)
+	}
+
+	for _, channel := range channels {
+		if channel.ChanID != channelID {
+			continue
+		}
+		capacity, _ := strconv.ParseInt(channel.Capacity, 10, 64)
+		local, _ := strconv.ParseInt(channel.LocalBalance, 10, 64)
+		direction, amount, err := swap.ShiftForChannel(local, capacity)
+		if err != nil {
+			return "", 0, fmt.Errorf("channel %s: %w", channelID, err)
+		}
+		fmt.Printf("Channel %s holds %s of %s local; quoting a swap %s of %s\n\n",
+			channelID, formatSats(local), formatSats(capacity), direction, formatSats(amount))
+		return direction, amount, nil
+	}
+	return "", 0, fmt.Errorf("channel %s not found", channelID)
+}
+
+func printSwapComparison(comparison *swap.Comparison) {
+	gain := "inbound"
+	if comparison.Direction == swap.DirectionIn {
+		gain = "outbound"
+	}
+	fmt.Printf("🔁 Swap %s quotes for %s sats (%s liquidity)\n", comparison.Direction, formatSats(comparison.Amount), gain)
+	fmt.Println(strings.Repeat("─", 80))
+	fmt.Printf("%-10s %12s %12s %12s %10s\n", "Provider", "Service fee", "Miner fee", "Total", "ppm")
+
+	for _, quote := range comparison.Quotes {
+		if quote.Error != "" {
+			fmt.Printf("%-10s ❌ %s\n", quote.Provider, quote.Error)
+			continue
+		}
+		fmt.Printf("%-10s %12s %12s %12s %10d\n", quote.Provider,
+			formatSats(quote.ServiceFee), formatSats(quote.MinerFee), formatSats(quote.TotalFee), quote.FeePPM)
+		if quote.Note != "" {
+			fmt.Printf("%-10s   (%s)\n", "", quote.Note)
+		}
+	}
+
+	fmt.Println(strings.Repeat("─", 80))
+	if comparison.Recommended == nil {
+		fmt.Println("No provider could quote this swap")
+		return
+	}
+	fmt.Printf("✅ Cheapest: %s at %s sats (%d ppm)\n", comparison.Recommended.Provider,
+		formatSats(comparison.Recommended.TotalFee), comparison.Recommended.FeePPM)
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/backfill"
+	"github.com/brewgator/lightning-node-tools/internal/bitcoin"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/mempool"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+const collectorName = "historical-backfill"
+
+// options holds the parsed command line flags
+type options struct {
+	from       time.Time
+	to         time.Time
+	scriptType string
+	depth      int
+	dryRun     bool
+	verbose    bool
+	mock       bool
+	workers    int
+	// reorged holds entries whose stored history was invalidated by a
+	// re-org, with the first day deleted; they are rebuilt from there to today
+	reorged map[int64]time.Time
+}
+
+func main() {
+	var (
+		dbPath      = flag.String("db", "data/portfolio.db", "Path to SQLite database")
+		address     = flag.String("address", "", "Backfill only this tracked address or xpub (default: all active entries)")
+		fromStr     = flag.String("from", "", "First day to write, YYYY-MM-DD (default: first transaction)")
+		toStr       = flag.String("to", "", "Last day to write, YYYY-MM-DD (default: today)")
+		scriptType  = flag.String("script-type", "", "Script type for xpubs: pkh, sh(wpkh) or wpkh (default: from the key prefix)")
+		depth       = flag.Int("depth", 500, "Number of receive and change addresses to scan per xpub")
+		dryRun      = flag.Bool("dry-run", false, "Show the per-day differences without writing to the database")
+		verbose     = flag.Bool("verbose", false, "List every changed day, not just the summary")
+		mockMode    = flag.Bool("mock", false, "Use mock database tables and generated transactions")
+		workers     = flag.Int("workers", 4, "Number of entries to scan in parallel")
+		rpcRate     = flag.Float64("rpc-rate", 20, "Maximum Bitcoin Core RPC calls per second (0 for no limit)")
+		resume      = flag.Bool("resume", false, "Skip entries finished by an interrupted run")
+		fallbackURL = flag.String("fallback-url", "", "Esplora or mempool.space API for history below a pruned node's prune height, e.g. https://mempool.space/api")
+		reorgDepth  = flag.Int64("reorg-depth", backfill.DefaultReorgDepth, "Blocks below the tip to check stored history against for re-orgs (0 to skip)")
+		reorgsOnly  = flag.Bool("reorgs-only", false, "Only check for re-orgs and rebuild the entries they affected, e.g. from cron")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: historical-backfill [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Rebuilds per-day balance history for tracked addresses and xpubs from their\n")
+		fmt.Fprintf(os.Stderr, "full transaction history and merges it into address_balances. Each day keeps\n")
+		fmt.Fprintf(os.Stderr, "one row; existing rows for a backfilled day are replaced. Entries with a\n")
+		fmt.Fprintf(os.Stderr, "birth height or date are only scanned from there on. History derived from\n")
+		fmt.Fprintf(os.Stderr, "blocks that were re-orged out is deleted and rebuilt first.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	opts := options{
+		scriptType: *scriptType,
+		depth:      *depth,
+		dryRun:     *dryRun,
+		verbose:    *verbose,
+		mock:       *mockMode,
+		workers:    *workers,
+	}
+	var err error
+	if opts.from, err = parseDay(*fromStr); err != nil {
+		log.Fatalf("Invalid --from: %v", err)
+	}
+	if opts.to, err = parseDay(*toStr); err != nil {
+		log.Fatalf("Invalid --to: %v", err)
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if opts.to.IsZero() || opts.to.After(today) {
+		opts.to = today
+	}
+	if !opts.from.IsZero() && opts.from.After(opts.to) {
+		log.Fatalf("--from must not be after --to")
+	}
+	if opts.depth < 1 {
+		log.Fatalf("--depth must be at least 1")
+	}
+	if opts.workers < 1 {
+		log.Fatalf("--workers must be at least 1")
+	}
+	if *reorgDepth < 0 {
+		log.Fatalf("--reorg-depth must not be negative")
+	}
+	if *reorgsOnly && (*reorgDepth == 0 || opts.mock) {
+		log.Fatalf("--reorgs-only needs Bitcoin Core and a --reorg-depth above 0")
+	}
+	bitcoin.SetRPCRateLimit(*rpcRate)
+
+	database, err := db.NewDatabaseWithMockMode(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	var scanner *bitcoin.TransactionScanner
+	var client *bitcoin.Client
+	if !opts.mock {
+		client, err = bitcoin.NewClient()
+		if err != nil {
+			log.Fatalf("❌ Bitcoin Core is required for backfilling: %v", err)
+		}
+		if *fallbackURL != "" {
+			scanner = bitcoin.NewTransactionScannerWithFallback(client, mempool.NewClient(*fallbackURL))
+		} else {
+			scanner = bitcoin.NewTransactionScanner(client)
+		}
+	}
+
+	if client != nil && *reorgDepth > 0 {
+		if opts.reorged, err = checkReorgs(database, client, *reorgDepth, opts.dryRun); err != nil {
+			log.Fatalf("❌ Re-org check failed: %v", err)
+		}
+	}
+
+	var entries []db.OnchainAddress
+	if !*reorgsOnly {
+		if entries, err = selectEntries(database, *address); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+	if entries, err = addReorgedEntries(database, entries, opts.reorged); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if len(entries) == 0 {
+		if *reorgsOnly {
+			fmt.Printf("✅ No re-orged history from the last %d blocks to rebuild\n", *reorgDepth)
+		} else {
+			fmt.Println("No tracked addresses to backfill")
+		}
+		return
+	}
+
+	completed := make(map[int64]bool)
+	if *resume {
+		if completed, err = loadCompleted(database); err != nil {
+			log.Fatalf("❌ Failed to read the last backfill run: %v", err)
+		}
+		if len(completed) == 0 {
+			fmt.Println("No interrupted backfill to resume, starting from the beginning")
+		}
+		// History deleted after a re-org is rebuilt even if the entry was done
+		for id := range opts.reorged {
+			delete(completed, id)
+		}
+	}
+
+	// Stop handing out entries on Ctrl-C; finished entries stay recorded
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	run := func(run *db.CollectorRun) error {
+		return backfillEntries(ctx, database, scanner, entries, completed, opts, run)
+	}
+	if opts.dryRun {
+		err = run(&db.CollectorRun{Collector: collectorName})
+	} else {
+		err = database.RecordCollectorRun(collectorName, run)
+	}
+	if err != nil {
+		log.Fatalf("❌ Backfill failed: %v", err)
+	}
+}
+
+// selectEntries returns the tracked entry named by address, or every active
+// entry when address is empty
+func selectEntries(database *db.Database, address string) ([]db.OnchainAddress, error) {
+	tracked, err := database.GetOnchainAddresses()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tracked addresses: %w", err)
+	}
+
+	var entries []db.OnchainAddress
+	for _, entry := range tracked {
+		if address == "" && entry.Active || entry.Address == address {
+			entries = append(entries, entry)
+		}
+	}
+	if address != "" && len(entries) == 0 {
+		return nil, fmt.Errorf("%s is not a tracked address or xpub", address)
+	}
+	return entries, nil
+}
+
+// checkReorgs looks for stored history derived from blocks within depth of
+// the tip that are no longer in the active chain. The balances from the
+// first such block on are deleted, except on a dry run, and the affected
+// entries are returned with the first deleted day of each.
+func checkReorgs(database *db.Database, client *bitcoin.Client, depth int64, dryRun bool) (map[int64]time.Time, error) {
+	info, err := client.GetBlockchainInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain tip: %w", err)
+	}
+	reorg, err := backfill.FindReorg(database, client, info.Blocks, depth)
+	if err != nil || reorg == nil {
+		return nil, err
+	}
+
+	fmt.Printf("⛓️  Block %d (%s) is no longer in the active chain\n", reorg.Height, reorg.Hash)
+	if dryRun {
+		fmt.Println("   History derived from it would be deleted and rebuilt")
+		return nil, nil
+	}
+
+	reorged, err := database.InvalidateAddressBalances(reorg.Height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to invalidate history from block %d: %w", reorg.Height, err)
+	}
+	fmt.Printf("   Deleted history derived from block %d on for %d entries, rebuilding it\n", reorg.Height, len(reorged))
+	return reorged, nil
+}
+
+// addReorgedEntries adds entries whose history was invalidated by a re-org
+// to the entries to backfill, whether or not they were selected
+func addReorgedEntries(database *db.Database, entries []db.OnchainAddress, reorged map[int64]time.Time) ([]db.OnchainAddress, error) {
+	selected := make(map[int64]bool, len(entries))
+	for _, entry := range entries {
+		selected[entry.ID] = true
+	}
+	for id := range reorged {
+		if selected[id] {
+			continue
+		}
+		entry, err := database.GetOnchainAddressByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load re-orged entry %d: %w", id, err)
+		}
+		if entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries, nil
+}
+
+// entryRange returns the days to write for an entry: --from and --to,
+// widened for an entry re-orged history was deleted from so every deleted
+// day is written again. A zero from means the first transaction.
+func entryRange(entry db.OnchainAddress, opts options) (time.Time, time.Time) {
+	from, to := opts.from, opts.to
+	if deleted, ok := opts.reorged[entry.ID]; ok {
+		if deleted.Before(from) {
+			from = deleted.UTC().Truncate(24 * time.Hour)
+		}
+		to = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	return from, to
+}
+
+// scanResult is the transaction history of one entry, loaded by a worker
+type scanResult struct {
+	entry   db.OnchainAddress
+	history *bitcoin.MovementHistory
+	err     error
+}
+
+// scanEntries loads entry histories on opts.workers goroutines. No new
+// entries are started once ctx is cancelled; the results channel closes
+// when the in-flight ones finish.
+func scanEntries(ctx context.Context, scanner *bitcoin.TransactionScanner, entries []db.OnchainAddress, opts options) <-chan scanResult {
+	jobs := make(chan db.OnchainAddress)
+	results := make(chan scanResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range jobs {
+				history, err := loadHistory(scanner, entry, opts)
+				results <- scanResult{entry: entry, history: history, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, entry := range entries {
+			select {
+			case jobs <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// backfillEntries rebuilds and merges the history of each entry. Histories
+// are scanned in parallel; diffs and writes happen here, one entry at a
+// time, so SQLite sees a single writer. A failing entry is reported and
+// counted but does not stop the others. Entries listed in completed are
+// skipped, and each entry that finishes is added to the run's resume point.
+func backfillEntries(ctx context.Context, database *db.Database, scanner *bitcoin.TransactionScanner, entries []db.OnchainAddress, completed map[int64]bool, opts options, run *db.CollectorRun) error {
+	var pending []db.OnchainAddress
+	for _, entry := range entries {
+		if !completed[entry.ID] {
+			pending = append(pending, entry)
+		}
+	}
+
+	mode := ""
+	if opts.dryRun {
+		mode = " (dry run, nothing will be written)"
+	}
+	if skipped := len(entries) - len(pending); skipped > 0 {
+		mode += fmt.Sprintf(", skipping %d completed by the interrupted run", skipped)
+	}
+	fmt.Printf("🔎 Backfilling %d tracked entries with %d workers%s\n", len(pending), opts.workers, mode)
+
+	var added, changed, done int
+	fail := func(entry db.OnchainAddress, format string, err error) {
+		fmt.Printf(format+"\n", err)
+		run.Errors++
+		run.ErrorMessage = fmt.Sprintf("%s: %v", entry.Address, err)
+	}
+	for result := range scanEntries(ctx, scanner, pending, opts) {
+		entry := result.entry
+		done++
+		fmt.Printf("[%d/%d] %s: ", done, len(pending), describe(entry))
+
+		if result.err != nil {
+			fail(entry, "❌ %v", result.err)
+			continue
+		}
+		movements := result.history.Movements
+
+		from, to := entryRange(entry, opts)
+		if from.IsZero() {
+			first, ok := backfill.FirstActivity(movements)
+			if !ok {
+				fmt.Println("no confirmed transactions")
+				markCompleted(database, run, completed, entry.ID)
+				continue
+			}
+			from = first
+		}
+
+		computed := backfill.DailyBalances(entry.ID, movements, from, to)
+		existing, err := database.GetAddressBalanceHistory(entry.Address, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			fail(entry, "❌ failed to load stored history: %v", err)
+			continue
+		}
+		changes := backfill.Diff(existing, computed)
+
+		entryAdded := 0
+		for _, change := range changes {
+			if change.Old == nil {
+				entryAdded++
+			}
+		}
+		fmt.Printf("%d transactions from %s, %d days, %d new, %d changed\n",
+			len(movements), strings.Join(result.history.Sources, "+"), len(computed), entryAdded, len(changes)-entryAdded)
+		if !result.history.Complete {
+			fmt.Printf("   ⚠️  node is pruned below block %d, earlier history may be missing (see --fallback-url)\n",
+				result.history.PruneHeight)
+		}
+		added += entryAdded
+		changed += len(changes) - entryAdded
+
+		if opts.dryRun || opts.verbose {
+			printChanges(changes)
+		}
+		if opts.dryRun {
+			continue
+		}
+
+		if len(changes) > 0 {
+			merged := make([]db.AddressBalance, len(changes))
+			for j, change := range changes {
+				merged[j] = change.New
+			}
+			if err := database.MergeAddressBalances(entry.ID, merged); err != nil {
+				fail(entry, "   ❌ failed to write history: %v", err)
+				continue
+			}
+			run.ItemsInserted += int64(len(merged))
+		}
+		markCompleted(database, run, completed, entry.ID)
+	}
+
+	verb := "Wrote"
+	if opts.dryRun {
+		verb = "Would write"
+	}
+	fmt.Printf("✅ %s %d new and %d changed days", verb, added, changed)
+	if run.Errors > 0 {
+		fmt.Printf(", %d entries failed", run.Errors)
+	}
+	fmt.Println()
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted after %d of %d entries, continue with --resume", done, len(pending))
+	}
+	if run.Errors == 0 {
+		run.ResumePoint = resumeComplete
+	}
+	return nil
+}
+
+// markCompleted records a finished entry in the run's resume point
+func markCompleted(database *db.Database, run *db.CollectorRun, completed map[int64]bool, id int64) {
+	completed[id] = true
+	if run.ID == 0 {
+		return
+	}
+	run.ResumePoint = formatResumePoint(completed)
+	if err := database.UpdateCollectorRunProgress(run); err != nil {
+		log.Printf("Warning: failed to save backfill progress: %v", err)
+	}
+}
+
+// resumeComplete is the resume point of a run that finished every entry
+const resumeComplete = "complete"
+
+// formatResumePoint encodes completed entry IDs as a sorted, comma-separated list
+func formatResumePoint(completed map[int64]bool) string {
+	ids := make([]int64, 0, len(completed))
+	for id := range completed {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// loadCompleted returns the entries finished by the last backfill run if it
+// was interrupted, or an empty set if it completed
+func loadCompleted(database *db.Database) (map[int64]bool, error) {
+	completed := make(map[int64]bool)
+	resumePoint, err := database.GetLastResumePoint(collectorName)
+	if err != nil || resumePoint == "" || resumePoint == resumeComplete {
+		return completed, err
+	}
+	for _, part := range strings.Split(resumePoint, ",") {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resume point %q", resumePoint)
+		}
+		completed[id] = true
+	}
+	return completed, nil
+}
+
+// loadHistory returns the confirmed transaction history of an address or
+// xpub, scanning from its birthday when one is set
+func loadHistory(scanner *bitcoin.TransactionScanner, entry db.OnchainAddress, opts options) (*bitcoin.MovementHistory, error) {
+	if opts.mock {
+		return &bitcoin.MovementHistory{Movements: mockMovements(entry), Sources: []string{"mock"}, Complete: true}, nil
+	}
+	birthday := bitcoin.BirthdayOf(entry)
+	if utils.ValidateXPub(entry.Address) {
+		return scanner.GetXPubMovements(entry.Address, opts.scriptType, opts.depth, birthday)
+	}
+	return scanner.GetAddressMovements(entry.Address, birthday)
+}
+
+// mockMovements generates a repeatable history for an entry so the tool can
+// be exercised without Bitcoin Core
+func mockMovements(entry db.OnchainAddress) []bitcoin.BalanceMovement {
+	rng := rand.New(rand.NewSource(entry.ID))
+	start := time.Now().UTC().AddDate(0, 0, -365)
+
+	var movements []bitcoin.BalanceMovement
+	var balance int64
+	for i := 0; i < 12; i++ {
+		blocktime := start.AddDate(0, 0, i*30+rng.Intn(30)).Unix()
+		amount := int64(rng.Intn(5000000) + 100000)
+		if balance > amount && rng.Intn(3) == 0 {
+			amount = -amount
+		}
+		balance += amount
+		movements = append(movements, bitcoin.BalanceMovement{
+			TxID:      fmt.Sprintf("mock-%d-%d", entry.ID, i),
+			Blocktime: blocktime,
+			Amount:    amount,
+		})
+	}
+	return movements
+}
+
+func printChanges(changes []backfill.Change) {
+	for _, change := range changes {
+		date := change.Date.Format("2006-01-02")
+		if change.Old == nil {
+			fmt.Printf("   + %s  %s\n", date, utils.FormatSats(change.New.Balance))
+			continue
+		}
+		if change.Old.Balance == change.New.Balance && change.Old.TxCount == change.New.TxCount {
+			fmt.Printf("   ~ %s  %s, now from block %d\n", date, utils.FormatSats(change.New.Balance), change.New.BlockHeight)
+			continue
+		}
+		fmt.Printf("   ~ %s  %s → %s\n", date,
+			utils.FormatSats(change.Old.Balance), utils.FormatSats(change.New.Balance))
+	}
+}
+
+// describe names an entry by label and shortened address
+func describe(entry db.OnchainAddress) string {
+	address := entry.Address
+	if len(address) > 20 {
+		address = address[:10] + "…" + address[len(address)-6:]
+	}
+	if strings.TrimSpace(entry.Label) == "" {
+		return address
+	}
+	return fmt.Sprintf("%s (%s)", entry.Label, address)
+}
+
+// parseDay parses an optional YYYY-MM-DD flag as a UTC day
+func parseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
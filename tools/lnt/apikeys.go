@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/brewgator/lightning-node-tools/internal/apikeys"
+)
+
+// handleAPIKeys dispatches the api-keys subcommands, which edit the file the
+// portfolio API reads with --api-keys
+func handleAPIKeys(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: lnt api-keys list|add|remove ...")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "list":
+		handleAPIKeysList(args[1:])
+	case "add":
+		handleAPIKeysAdd(args[1:])
+	case "remove":
+		handleAPIKeysRemove(args[1:])
+	default:
+		fmt.Printf("Unknown api-keys subcommand: %s\n", args[0])
+		os.Exit(2)
+	}
+}
+
+func handleAPIKeysList(args []string) {
+	fs := flag.NewFlagSet("api-keys list", flag.ExitOnError)
+	file := fs.String("file", "", "API key file (required)")
+	fs.Parse(args)
+	requireKeyFile(*file)
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer f.Close()
+	keys, err := apikeys.Parse(f)
+	if err != nil {
+		log.Fatalf("❌ %s: %v", *file, err)
+	}
+	if len(keys) == 0 {
+		fmt.Println("No API keys")
+		return
+	}
+
+	for _, key := range keys {
+		fmt.Printf("%-12s %-20s %s\n", key.Scope, key.Profile, key.Redacted())
+	}
+}
+
+func handleAPIKeysAdd(args []string) {
+	fs := flag.NewFlagSet("api-keys add", flag.ExitOnError)
+	file := fs.String("file", "", "API key file (required, created if missing)")
+	scope := fs.String("scope", apikeys.ScopeRead, "What the key may do: read, or write to also change data")
+	fs.Parse(args)
+	requireKeyFile(*file)
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: lnt api-keys add <profile> --file <path> [--scope read|write]")
+		os.Exit(2)
+	}
+
+	key, err := apikeys.Add(*file, fs.Arg(0), *scope)
+	if err != nil {
+		log.Fatalf("❌ Failed to add key: %v", err)
+	}
+	fmt.Printf("✅ Added a %s key for profile %s. It is shown only once:\n\n    %s\n\n", key.Scope, key.Profile, key.Key)
+	fmt.Println("Send the API a SIGHUP, or restart it for a new profile, to apply it.")
+}
+
+func handleAPIKeysRemove(args []string) {
+	fs := flag.NewFlagSet("api-keys remove", flag.ExitOnError)
+	file := fs.String("file", "", "API key file (required)")
+	fs.Parse(args)
+	requireKeyFile(*file)
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: lnt api-keys remove <key prefix> --file <path>")
+		os.Exit(2)
+	}
+
+	key, err := apikeys.Remove(*file, fs.Arg(0))
+	if err != nil {
+		log.Fatalf("❌ Failed to remove key: %v", err)
+	}
+	fmt.Printf("✅ Removed %s key %s of profile %s\n", key.Scope, key.Redacted(), key.Profile)
+	fmt.Println("Send the API a SIGHUP to apply it.")
+}
+
+func requireKeyFile(file string) {
+	if file == "" {
+		fmt.Println("❌ --file is required")
+		os.Exit(2)
+	}
+}
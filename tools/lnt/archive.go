@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/archive"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+func handleArchive(args []string) {
+	fs := flag.NewFlagSet("archive", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	months := fs.Int("months", archive.DefaultMonths, "Months of forwards and channel snapshots kept in the database")
+	vacuum := fs.Bool("vacuum", false, "Shrink the database file afterwards; this rewrites it and blocks collectors meanwhile")
+	fs.Parse(args)
+
+	if *months < 1 {
+		log.Fatalf("--months must be at least 1")
+	}
+	// The API reads the archive of each database from next to it
+	dir := archive.DirFor(*dbPath)
+
+	database, err := db.NewDatabase(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	before := time.Now().UTC().AddDate(0, -*months, 0)
+	result, err := archive.New(dir).Run(database, before)
+	if err != nil {
+		log.Fatalf("❌ Archive failed: %v", err)
+	}
+
+	fmt.Printf("📦 Archived %d forwarding events and %d channel snapshots to %s\n",
+		result.ForwardingEvents, result.ChannelSnapshots, dir)
+	if result.ForwardsBefore.IsZero() {
+		fmt.Println("⚠️  No forwards were archived: forwarding_daily has no summaries yet, run the forwarding collector first")
+	} else if result.ForwardsBefore.Before(before.Truncate(24 * time.Hour)) {
+		fmt.Printf("⚠️  Forwards from %s on were kept, forwarding_daily has not summarized them yet\n",
+			result.ForwardsBefore.Format(time.DateOnly))
+	}
+
+	if *vacuum {
+		if err := database.Vacuum(); err != nil {
+			log.Fatalf("❌ Vacuum failed: %v", err)
+		}
+		fmt.Println("🧹 Database file shrunk")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+func handleFsck(args []string) {
+	fs := flag.NewFlagSet("fsck", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	mockMode := fs.Bool("mock", globals.mock, "Check the mock database tables")
+	repair := fs.Bool("repair", false, "Offer to quarantine the rows of each issue found")
+	yes := fs.Bool("yes", false, "Repair every issue without asking (only used with --repair)")
+	fs.Parse(args)
+
+	database, err := db.NewDatabaseWithMockMode(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	issues, err := database.CheckIntegrity()
+	if err != nil {
+		log.Fatalf("❌ Integrity check failed: %v", err)
+	}
+	if len(issues) == 0 {
+		fmt.Println("✅ No integrity issues found")
+		return
+	}
+
+	for _, issue := range issues {
+		fmt.Printf("❌ %-30s %d %s (%s, e.g. id %s)\n", issue.Check, issue.Count, issue.Description,
+			issue.Table, joinIDs(issue.SampleIDs))
+	}
+	if !*repair {
+		fmt.Printf("Found %d issues; run with --repair to quarantine the affected rows\n", len(issues))
+		os.Exit(1)
+	}
+
+	fmt.Println("")
+	fmt.Println("Repairs move rows into quarantined_records, where they can still be inspected.")
+	stdin := bufio.NewReader(os.Stdin)
+	remaining := 0
+	for _, found := range issues {
+		// A row can break several rules, so an earlier repair may have fixed this one
+		issue, ok := currentIssue(database, found.Check)
+		if !ok {
+			fmt.Printf("✅ %s already repaired\n", found.Check)
+			continue
+		}
+
+		question := fmt.Sprintf("Quarantine %d rows of %s (%s)?", issue.Count, issue.Table, issue.Description)
+		if !*yes && !confirm(stdin, question) {
+			fmt.Printf("⏭️  Skipped %s\n", issue.Check)
+			remaining++
+			continue
+		}
+
+		moved, err := database.RepairIntegrityIssue(issue.Check)
+		if err != nil {
+			log.Printf("❌ Failed to repair %s: %v", issue.Check, err)
+			remaining++
+			continue
+		}
+		fmt.Printf("✅ Quarantined %d rows of %s\n", moved, issue.Table)
+	}
+
+	if remaining > 0 {
+		fmt.Printf("%d issues left unrepaired\n", remaining)
+		os.Exit(1)
+	}
+}
+
+// currentIssue rechecks the database and returns the issue found by check, if any
+func currentIssue(database *db.Database, check string) (db.IntegrityIssue, bool) {
+	issues, err := database.CheckIntegrity()
+	if err != nil {
+		log.Fatalf("❌ Integrity check failed: %v", err)
+	}
+	for _, issue := range issues {
+		if issue.Check == check {
+			return issue, true
+		}
+	}
+	return db.IntegrityIssue{}, false
+}
+
+// confirm asks a yes/no question on stdout, defaulting to no
+func confirm(r *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// joinIDs formats row IDs as a comma-separated list
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ", ")
+}
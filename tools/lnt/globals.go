@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/redact"
+	"github.com/brewgator/lightning-node-tools/internal/version"
+)
+
+// globalFlags are given before the command, e.g. lnt --profile business fsck,
+// and apply to lnt's own commands and the tools it runs. Each can also be
+// set as LNT_<FLAG> in the environment or the project's .env, e.g.
+// LNT_PROFILE=business; the command line wins.
+type globalFlags struct {
+	db          string
+	profile     string
+	mock        bool
+	verboseLogs bool
+	// set holds the flags given on the command line or in the environment;
+	// only those are passed on, so the tools' own defaults apply otherwise
+	set map[string]bool
+}
+
+var globals = globalFlags{db: "data/portfolio.db", profile: db.DefaultProfile, set: map[string]bool{}}
+
+// parseGlobals reads the global flags of args and returns the command and
+// its arguments
+func parseGlobals(args []string) (string, []string) {
+	fs := flag.NewFlagSet("lnt", flag.ExitOnError)
+	fs.Usage = showHelp
+	fs.StringVar(&globals.db, "db", envString("db", globals.db), "Path to SQLite database")
+	fs.StringVar(&globals.profile, "profile", envString("profile", globals.profile), db.ProfileFlagUsage)
+	fs.BoolVar(&globals.mock, "mock", envBool("mock"), "Use mock database tables")
+	fs.BoolVar(&globals.verboseLogs, "verbose-logs", envBool("verbose-logs"), redact.FlagUsage)
+	showVersion := fs.Bool("version", false, "Print the version and exit")
+	fs.Parse(args)
+	fs.Visit(func(f *flag.Flag) { globals.set[f.Name] = true })
+
+	if *showVersion {
+		return "version", nil
+	}
+	if fs.NArg() == 0 {
+		return "", nil
+	}
+	redact.SetVerbose(globals.verboseLogs)
+	return fs.Arg(0), fs.Args()[1:]
+}
+
+func envName(flag string) string {
+	return "LNT_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+func envString(flag, fallback string) string {
+	if value := os.Getenv(envName(flag)); value != "" {
+		globals.set[flag] = true
+		return value
+	}
+	return fallback
+}
+
+func envBool(flag string) bool {
+	value := os.Getenv(envName(flag))
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", envName(flag), err)
+	}
+	globals.set[flag] = true
+	return b
+}
+
+// dbPath is the database of the selected profile, the default of lnt's own
+// commands' --db
+func (g *globalFlags) dbPath() string {
+	path, err := db.ProfilePath(g.db, g.profile)
+	if err != nil {
+		log.Fatalf("Invalid --profile: %v", err)
+	}
+	return path
+}
+
+// args returns the global flags set that a tool accepting flags takes, to
+// pass before the user's arguments so those override them. Tools without
+// --profile are given the profile's database.
+func (g *globalFlags) args(accepts []string) []string {
+	var args []string
+	for _, name := range accepts {
+		switch {
+		case name == "db" && !slices.Contains(accepts, "profile"):
+			if g.set["db"] || g.set["profile"] {
+				args = append(args, "--db="+g.dbPath())
+			}
+			continue
+		case !g.set[name]:
+			continue
+		}
+		switch name {
+		case "db":
+			args = append(args, "--db="+g.db)
+		case "profile":
+			args = append(args, "--profile="+g.profile)
+		case "mock":
+			args = append(args, "--mock="+strconv.FormatBool(g.mock))
+		case "verbose-logs":
+			args = append(args, "--verbose-logs="+strconv.FormatBool(g.verboseLogs))
+		}
+	}
+	return args
+}
+
+// setEnv exports the database flags given on the command line as LNT_DB
+// and LNT_PROFILE, for the tools that take no flags but read the database
+func (g *globalFlags) setEnv() {
+	if g.set["db"] {
+		os.Setenv(envName("db"), g.db)
+	}
+	if g.set["profile"] {
+		os.Setenv(envName("profile"), g.profile)
+	}
+}
+
+func handleVersion() {
+	fmt.Printf("lnt %s (commit %s", version.Version, version.GitCommit())
+	if version.BuildDate != "" {
+		fmt.Printf(", built %s", version.BuildDate)
+	}
+	fmt.Println(")")
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/brewgator/lightning-node-tools/internal/bundle"
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/envfile"
+)
+
+func main() {
+	// The project's .env may set the global flags, and is passed on to the
+	// tools lnt runs
+	if err := envfile.LoadProject(); err != nil {
+		log.Printf("Warning: Failed to load .env file: %v", err)
+	}
+
+	command, args := parseGlobals(os.Args[1:])
+	if command == "" {
+		showHelp()
+		return
+	}
+
+	switch command {
+	case "api":
+		runTool(apiTool, args)
+	case "collect":
+		handleCollect(args)
+	case "channels":
+		runTool(channelsTool, args)
+	case "monitor":
+		runTool(monitorTool, args)
+	case "backfill":
+		runTool(backfillTool, args)
+	case "version":
+		handleVersion()
+	case "export-config":
+		handleExportConfig(args)
+	case "import-config":
+		handleImportConfig(args)
+	case "peers":
+		handlePeers(args)
+	case "channel-names":
+		handleChannelNames(args)
+	case "api-keys":
+		handleAPIKeys(args)
+	case "smoke":
+		handleSmoke(args)
+	case "fsck":
+		handleFsck(args)
+	case "archive":
+		handleArchive(args)
+	case "snapshot":
+		handleSnapshot(args)
+	case "verify-multisig":
+		handleVerifyMultisig(args)
+	case "multisig":
+		handleMultisig(args)
+	case "help", "-h", "--help":
+		showHelp()
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		showHelp()
+		os.Exit(2)
+	}
+}
+
+func showHelp() {
+	fmt.Println("lnt - Lightning Node Tools")
+	fmt.Println("")
+	fmt.Println("Usage:")
+	fmt.Println("  lnt [--db <path>] [--profile <name>] [--mock] [--verbose-logs] <command> [flags]")
+	fmt.Println("")
+	fmt.Println("  Global flags apply to every command that takes them and can be set as")
+	fmt.Println("  LNT_DB, LNT_PROFILE, LNT_MOCK and LNT_VERBOSE_LOGS, e.g. in the project's .env")
+	fmt.Println("")
+	fmt.Println("  Service Commands:")
+	fmt.Println("    lnt api [flags]                      Run the portfolio API (portfolio-api)")
+	fmt.Printf("    lnt collect <collector> [flags]      Run a collector: %s\n", strings.Join(collectorNames(), ", "))
+	fmt.Println("    lnt monitor                          Run the Telegram monitor (telegram-monitor)")
+	fmt.Println("")
+	fmt.Println("  Tool Commands:")
+	fmt.Println("    lnt channels <command>               Channel balances, fees and opens (channel-manager)")
+	fmt.Println("    lnt backfill [flags]                 Backfill address history (historical-backfill)")
+	fmt.Println("    lnt version                          Print the version lnt and the tools were built as")
+	fmt.Println("")
+	fmt.Println("  Configuration Commands:")
+	fmt.Println("    lnt export-config [--db <path>] [--out <file>]")
+	fmt.Println("                                         Export tracked addresses, xpubs and offline accounts as JSON (no balances)")
+	fmt.Println("    lnt import-config --file <file> [--db <path>] [--dry-run]")
+	fmt.Println("                                         Add entities from an exported bundle, skipping ones that exist")
+	fmt.Println("")
+	fmt.Println("  Peer Policy Commands:")
+	fmt.Println("    lnt peers list [--db <path>]         List blocklisted and preferred peers")
+	fmt.Println("    lnt peers set <pubkey> [--blocklisted[=false]] [--preferred[=false]] [--notes <text>]")
+	fmt.Println("                                         Create or edit a peer's policy")
+	fmt.Println("    lnt peers remove <pubkey>            Delete a peer's policy")
+	fmt.Println("    lnt peers notes [--db <path>]        List the private notes kept about peers")
+	fmt.Println("    lnt peers note <pubkey> [--contact <handle>] [--agreement <text>] [--agreed-on YYYY-MM-DD] [--notes <text>]")
+	fmt.Println("                                         Create or edit the notes about a peer, shown with its channels")
+	fmt.Println("    lnt peers remove-note <pubkey>       Delete the notes about a peer")
+	fmt.Println("")
+	fmt.Println("  Channel Name Commands:")
+	fmt.Println("    lnt channel-names list [--db <path>] List the names given to channels")
+	fmt.Println("    lnt channel-names set <channel_id> <name>")
+	fmt.Println("                                         Name a channel, shown instead of its peer's alias by the API, channels and monitor")
+	fmt.Println("    lnt channel-names remove <channel_id>")
+	fmt.Println("                                         Go back to the peer's alias")
+	fmt.Println("")
+	fmt.Println("  Cold Storage Commands:")
+	fmt.Println("    lnt verify-multisig --descriptor <descriptor|@file> --index <n> [--change] [--export <files>]")
+	fmt.Println("                                         Show what each cosigner device displays for a multisig address, optionally checking device exports")
+	fmt.Println("    lnt multisig import <file> [--name <name>] [--db <path>]")
+	fmt.Println("                                         Store a multisig wallet from a Caravan, Unchained, Sparrow, Coldcard or Ledger export")
+	fmt.Println("    lnt multisig list [--db <path>]      List the imported multisig wallets")
+	fmt.Println("")
+	fmt.Println("  Maintenance Commands:")
+	fmt.Println("    lnt fsck [--db <path>] [--repair] [--yes]")
+	fmt.Println("                                         Check for orphaned rows and impossible values, optionally quarantining them")
+	fmt.Println("    lnt archive [--db <path>] [--months 12] [--vacuum]")
+	fmt.Println("                                         Move older forwards and channel snapshots into gzipped CSV files, still served by the export endpoints")
+	fmt.Println("    lnt snapshot [--db <path>] [--out <file>] [--every <duration>]")
+	fmt.Println("                                         Write a consistent read-only copy of the database for Metabase or Grafana to query")
+	fmt.Println("")
+	fmt.Println("  API Key Commands:")
+	fmt.Println("    lnt api-keys list --file <path>      List the portfolio API's keys, their profile and scope")
+	fmt.Println("    lnt api-keys add <profile> --file <path> [--scope read|write]")
+	fmt.Println("                                         Generate a key for a profile and print it once")
+	fmt.Println("    lnt api-keys remove <key prefix> --file <path>")
+	fmt.Println("                                         Delete the key starting with the prefix")
+	fmt.Println("")
+	fmt.Println("  Deployment Commands:")
+	fmt.Println("    lnt smoke [--url <base>] [--wait <duration>]")
+	fmt.Println("                                         Check a running API answers health, portfolio and chart requests")
+	fmt.Println("")
+	fmt.Println("  Examples:")
+	fmt.Println("    lnt --profile business collect forwarding --oneshot")
+	fmt.Println("    lnt api --port 8090 --startup-wait 5m")
+	fmt.Println("    lnt channels balance")
+	fmt.Println("    lnt export-config --out lnt-config.json")
+	fmt.Println("    lnt import-config --file lnt-config.json --dry-run")
+	fmt.Println("    lnt peers set 02abc...def --blocklisted --notes \"force closed twice\"")
+	fmt.Println("    lnt peers note 03def...abc --contact @bob --agreement \"we both keep 1k ppm\" --agreed-on 2024-03-01")
+	fmt.Println("    lnt channel-names set 812345678901234567 \"LOOP main\"")
+	fmt.Println("    lnt verify-multisig --descriptor @vault.txt --index 12 --export coldcard-vault.txt,ledger-policy.json")
+	fmt.Println("    lnt multisig import caravan-vault.json --name Vault")
+	fmt.Println("    lnt fsck --repair")
+	fmt.Println("    lnt snapshot --out /var/lib/grafana/portfolio.db --every 15m")
+	fmt.Println("    lnt api-keys add business --file /etc/portfolio-api/api-keys --scope write")
+	fmt.Println("    lnt smoke --url http://127.0.0.1:18090")
+}
+
+func handleExportConfig(args []string) {
+	fs := flag.NewFlagSet("export-config", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	out := fs.String("out", "", "Output file (default stdout)")
+	mockMode := fs.Bool("mock", globals.mock, "Export from mock database tables")
+	fs.Parse(args)
+
+	database, err := db.NewDatabaseWithMockMode(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	b, err := bundle.Export(database)
+	if err != nil {
+		log.Fatalf("❌ Export failed: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := b.Write(w); err != nil {
+		log.Fatalf("❌ Failed to write bundle: %v", err)
+	}
+
+	if *out != "" {
+		fmt.Printf("✅ Exported %d addresses and %d offline accounts to %s\n",
+			len(b.Addresses), len(b.OfflineAccounts), *out)
+	}
+}
+
+func handleImportConfig(args []string) {
+	fs := flag.NewFlagSet("import-config", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	file := fs.String("file", "", "Bundle file to import (required)")
+	dryRun := fs.Bool("dry-run", false, "Validate the bundle without changing the database")
+	mockMode := fs.Bool("mock", globals.mock, "Import into mock database tables")
+	fs.Parse(args)
+
+	if *file == "" {
+		fmt.Println("❌ --file is required")
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	b, err := bundle.Read(f)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	fmt.Printf("📦 Bundle exported %s: %d addresses, %d offline accounts\n",
+		b.ExportedAt.Format("2006-01-02 15:04"), len(b.Addresses), len(b.OfflineAccounts))
+
+	if *dryRun {
+		fmt.Println("Dry run, nothing written")
+		return
+	}
+
+	database, err := db.NewDatabaseWithMockMode(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	result, err := bundle.Import(database, b)
+	if err != nil {
+		log.Fatalf("❌ Import stopped: %v", err)
+	}
+
+	fmt.Printf("✅ Addresses: %d added, %d already tracked\n", result.AddressesAdded, result.AddressesSkipped)
+	fmt.Printf("✅ Offline accounts: %d added, %d already present\n", result.OfflineAccountsAdded, result.OfflineAccountsSkipped)
+	for _, warning := range result.Warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/multisig"
+)
+
+func handleVerifyMultisig(args []string) {
+	fs := flag.NewFlagSet("verify-multisig", flag.ExitOnError)
+	descriptor := fs.String("descriptor", "", "Wallet descriptor, or @file to read it from a file (required)")
+	index := fs.Uint("index", 0, "Address index to verify")
+	change := fs.Bool("change", false, "Verify the change address at --index instead of the receive address")
+	exports := fs.String("export", "", "Comma-separated exports to check against: Coldcard setup files, Ledger wallet policies, Caravan or Unchained wallet configs, or descriptor files such as Sparrow's")
+	fs.Parse(args)
+
+	if *descriptor == "" {
+		fmt.Println("❌ --descriptor is required")
+		os.Exit(2)
+	}
+	text := *descriptor
+	if path, ok := strings.CutPrefix(text, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", path, err)
+		}
+		text = string(data)
+	}
+
+	wallet, err := multisig.Parse(text)
+	if err != nil {
+		log.Fatalf("❌ Invalid descriptor: %v", err)
+	}
+	address, err := wallet.Derive(uint32(*index), *change)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	kind := "receive"
+	if *change {
+		kind = "change"
+	}
+	order := "sortedmulti"
+	if !wallet.Sorted {
+		order = "multi"
+	}
+	fmt.Printf("🔐 %d of %d %s, %s address #%d\n", wallet.Threshold, len(wallet.Cosigners), order, kind, *index)
+	fmt.Println("")
+	fmt.Printf("Address:        %s\n", address.Address)
+	fmt.Printf("Witness script: %s\n", hex.EncodeToString(address.WitnessScript))
+	fmt.Println("")
+	fmt.Println("Cosigners (each device should show this path for its own key):")
+	for i, key := range address.Keys {
+		fmt.Printf("  %d. [%s] %s\n", i+1, key.Fingerprint, key.Path)
+		fmt.Printf("     %s\n", hex.EncodeToString(key.PubKey))
+	}
+	fmt.Println("")
+	fmt.Println("Public keys in script order:")
+	for i, pubKey := range address.ScriptKeys {
+		fmt.Printf("  %d. %s\n", i+1, hex.EncodeToString(pubKey))
+	}
+
+	if *exports == "" {
+		return
+	}
+	fmt.Println("")
+	mismatches := 0
+	for _, path := range strings.Split(*exports, ",") {
+		path = strings.TrimSpace(path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", path, err)
+		}
+		device, format, err := multisig.ParseExport(data)
+		if err != nil {
+			log.Fatalf("❌ %s: %v", path, err)
+		}
+
+		differences, err := wallet.Differences(device, uint32(*index), *change)
+		if err != nil {
+			log.Fatalf("❌ %s: %v", path, err)
+		}
+		if len(differences) == 0 {
+			fmt.Printf("✅ %s (%s) matches\n", path, format)
+			continue
+		}
+		mismatches++
+		fmt.Printf("❌ %s (%s) differs:\n", path, format)
+		for _, difference := range differences {
+			fmt.Printf("   - %s\n", difference)
+		}
+	}
+	if mismatches > 0 {
+		fmt.Printf("%d exports do not match the descriptor; do not use this address until they do\n", mismatches)
+		os.Exit(1)
+	}
+}
+
+// handleMultisig dispatches the multisig subcommands, which manage the
+// wallets the portfolio API can simulate spends from
+func handleMultisig(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: lnt multisig import|list ...")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "import":
+		handleMultisigImport(args[1:])
+	case "list":
+		handleMultisigList(args[1:])
+	default:
+		fmt.Printf("Unknown multisig subcommand: %s\n", args[0])
+		os.Exit(2)
+	}
+}
+
+func handleMultisigImport(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: lnt multisig import <file> [--name <name>]")
+		os.Exit(2)
+	}
+	path := args[0]
+
+	fs := flag.NewFlagSet("multisig import", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	name := fs.String("name", "", "Wallet name (default the file name without its extension)")
+	fs.Parse(args[1:])
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("❌ Failed to read %s: %v", path, err)
+	}
+	wallet, format, err := multisig.ParseExport(data)
+	if err != nil {
+		log.Fatalf("❌ Invalid wallet export %s: %v", path, err)
+	}
+	descriptor, err := wallet.Text()
+	if err != nil {
+		log.Fatalf("❌ Invalid wallet export %s: %v", path, err)
+	}
+
+	walletName := strings.TrimSpace(*name)
+	if walletName == "" {
+		walletName = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if utf8.RuneCountInString(walletName) > db.MaxMultisigWalletNameLength {
+		fmt.Printf("❌ --name must be at most %d characters\n", db.MaxMultisigWalletNameLength)
+		os.Exit(2)
+	}
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	stored := &db.MultisigWallet{Name: walletName, Descriptor: descriptor, Format: format}
+	if err := database.InsertMultisigWallet(stored); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			log.Fatalf("❌ A multisig wallet named %q already exists, choose another with --name", walletName)
+		}
+		log.Fatalf("❌ Failed to import wallet: %v", err)
+	}
+	fmt.Printf("✅ Imported %d of %d multisig wallet %q (#%d) from a %s\n",
+		wallet.Threshold, len(wallet.Cosigners), stored.Name, stored.ID, format)
+}
+
+func handleMultisigList(args []string) {
+	fs := flag.NewFlagSet("multisig list", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	fs.Parse(args)
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	wallets, err := database.GetMultisigWallets()
+	if err != nil {
+		log.Fatalf("❌ Failed to list multisig wallets: %v", err)
+	}
+	if len(wallets) == 0 {
+		fmt.Println("No multisig wallets")
+		return
+	}
+
+	for _, wallet := range wallets {
+		fmt.Printf("#%-4d %-24s next address %-6d %s\n", wallet.ID, wallet.Name, wallet.NextAddressIndex, wallet.Format)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+// channelIDPattern matches the channel IDs the portfolio API accepts
+var channelIDPattern = regexp.MustCompile(`^[0-9]+([:x][0-9]+[:x][0-9]+)?$`)
+
+// handleChannelNames dispatches the channel-names subcommands
+func handleChannelNames(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: lnt channel-names list|set|remove ...")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "list":
+		handleChannelNamesList(args[1:])
+	case "set":
+		handleChannelNamesSet(args[1:])
+	case "remove":
+		handleChannelNamesRemove(args[1:])
+	default:
+		fmt.Printf("Unknown channel-names subcommand: %s\n", args[0])
+		os.Exit(2)
+	}
+}
+
+func handleChannelNamesList(args []string) {
+	fs := flag.NewFlagSet("channel-names list", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	fs.Parse(args)
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	names, err := database.GetChannelNames()
+	if err != nil {
+		log.Fatalf("❌ Failed to list channel names: %v", err)
+	}
+	if len(names) == 0 {
+		fmt.Println("No channel names")
+		return
+	}
+
+	for _, name := range names {
+		fmt.Printf("%-20s %s\n", name.ChannelID, name.Name)
+	}
+}
+
+func handleChannelNamesSet(args []string) {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		fmt.Println("Usage: lnt channel-names set <channel_id> <name> [--db <path>]")
+		os.Exit(2)
+	}
+	channelID := channelIDArg(args[0])
+	name := strings.TrimSpace(args[1])
+	if name == "" || utf8.RuneCountInString(name) > db.MaxChannelNameLength {
+		fmt.Printf("❌ The name must be 1 to %d characters\n", db.MaxChannelNameLength)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("channel-names set", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	fs.Parse(args[2:])
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	if _, err := database.SetChannelName(channelID, name); err != nil {
+		log.Fatalf("❌ Failed to save channel name: %v", err)
+	}
+	fmt.Printf("✅ Named channel %s \"%s\"\n", channelID, name)
+}
+
+func handleChannelNamesRemove(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: lnt channel-names remove <channel_id> [--db <path>]")
+		os.Exit(2)
+	}
+	channelID := channelIDArg(args[0])
+
+	fs := flag.NewFlagSet("channel-names remove", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	fs.Parse(args[1:])
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	err := database.DeleteChannelName(channelID)
+	if err == sql.ErrNoRows {
+		fmt.Printf("No name for channel %s\n", channelID)
+		return
+	}
+	if err != nil {
+		log.Fatalf("❌ Failed to remove channel name: %v", err)
+	}
+	fmt.Printf("✅ Channel %s is shown by its peer's alias again\n", channelID)
+}
+
+func channelIDArg(arg string) string {
+	if !channelIDPattern.MatchString(arg) {
+		fmt.Printf("❌ Invalid channel ID: %s\n", arg)
+		os.Exit(2)
+	}
+	return arg
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+// handlePeers dispatches the peers subcommands
+func handlePeers(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: lnt peers list|set|remove|notes|note|remove-note ...")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "list":
+		handlePeersList(args[1:])
+	case "set":
+		handlePeersSet(args[1:])
+	case "remove":
+		handlePeersRemove(args[1:])
+	case "notes":
+		handlePeersNotes(args[1:])
+	case "note":
+		handlePeersNote(args[1:])
+	case "remove-note":
+		handlePeersRemoveNote(args[1:])
+	default:
+		fmt.Printf("Unknown peers subcommand: %s\n", args[0])
+		os.Exit(2)
+	}
+}
+
+func handlePeersList(args []string) {
+	fs := flag.NewFlagSet("peers list", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	fs.Parse(args)
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	policies, err := database.GetPeerPolicies()
+	if err != nil {
+		log.Fatalf("❌ Failed to list peer policies: %v", err)
+	}
+	if len(policies) == 0 {
+		fmt.Println("No peer policies")
+		return
+	}
+
+	for _, policy := range policies {
+		status := "—"
+		switch {
+		case policy.Blocklisted:
+			status = "🚫 blocklisted"
+		case policy.Preferred:
+			status = "⭐ preferred"
+		}
+		fmt.Printf("%s  %-15s %s\n", policy.Pubkey, status, policy.Notes)
+	}
+}
+
+func handlePeersSet(args []string) {
+	pubkey := pubkeyArg(args, "set")
+
+	fs := flag.NewFlagSet("peers set", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	blocklisted := fs.Bool("blocklisted", false, "Avoid this peer (e.g. reject its channel opens)")
+	preferred := fs.Bool("preferred", false, "Favour this peer (e.g. skip the channel acceptor's size rules)")
+	notes := fs.String("notes", "", "Free-form notes about the peer")
+	fs.Parse(args[1:])
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	policy := db.PeerPolicy{Pubkey: pubkey}
+	existing, err := database.GetPeerPolicy(pubkey)
+	if err != nil {
+		log.Fatalf("❌ Failed to read peer policy: %v", err)
+	}
+	if existing != nil {
+		policy = *existing
+	}
+
+	// Only flags given on the command line change the stored policy
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "blocklisted":
+			policy.Blocklisted = *blocklisted
+		case "preferred":
+			policy.Preferred = *preferred
+		case "notes":
+			policy.Notes = strings.TrimSpace(*notes)
+		}
+	})
+
+	if policy.Blocklisted && policy.Preferred {
+		fmt.Println("❌ A peer cannot be both blocklisted and preferred")
+		os.Exit(2)
+	}
+
+	if _, err := database.SetPeerPolicy(policy); err != nil {
+		log.Fatalf("❌ Failed to save peer policy: %v", err)
+	}
+	fmt.Printf("✅ Saved policy for %s\n", pubkey)
+}
+
+func handlePeersRemove(args []string) {
+	pubkey := pubkeyArg(args, "remove")
+
+	fs := flag.NewFlagSet("peers remove", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	fs.Parse(args[1:])
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	err := database.DeletePeerPolicy(pubkey)
+	if err == sql.ErrNoRows {
+		fmt.Printf("No policy for %s\n", pubkey)
+		return
+	}
+	if err != nil {
+		log.Fatalf("❌ Failed to remove peer policy: %v", err)
+	}
+	fmt.Printf("✅ Removed policy for %s\n", pubkey)
+}
+
+func handlePeersNotes(args []string) {
+	fs := flag.NewFlagSet("peers notes", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	fs.Parse(args)
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	notes, err := database.GetPeerNotes()
+	if err != nil {
+		log.Fatalf("❌ Failed to list peer notes: %v", err)
+	}
+	if len(notes) == 0 {
+		fmt.Println("No peer notes")
+		return
+	}
+
+	for _, note := range notes {
+		fmt.Println(note.Pubkey)
+		if note.Contact != "" {
+			fmt.Printf("  Contact:   %s\n", note.Contact)
+		}
+		if note.Agreement != "" {
+			agreement := note.Agreement
+			if note.AgreedOn != "" {
+				agreement += " (since " + note.AgreedOn + ")"
+			}
+			fmt.Printf("  Agreement: %s\n", agreement)
+		}
+		if note.Notes != "" {
+			fmt.Printf("  Notes:     %s\n", note.Notes)
+		}
+	}
+}
+
+func handlePeersNote(args []string) {
+	pubkey := pubkeyArg(args, "note")
+
+	fs := flag.NewFlagSet("peers note", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	contact := fs.String("contact", "", "How to reach the peer's operator, e.g. @alice on Telegram")
+	agreement := fs.String("agreement", "", "What was agreed, e.g. \"we both keep 1k ppm\"")
+	agreedOn := fs.String("agreed-on", "", "Date of the agreement (YYYY-MM-DD)")
+	notes := fs.String("notes", "", "Free-form notes about the peer")
+	fs.Parse(args[1:])
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	note := db.PeerNote{Pubkey: pubkey}
+	existing, err := database.GetPeerNote(pubkey)
+	if err != nil {
+		log.Fatalf("❌ Failed to read peer note: %v", err)
+	}
+	if existing != nil {
+		note = *existing
+	}
+
+	// Only flags given on the command line change the stored notes
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "contact":
+			note.Contact = strings.TrimSpace(*contact)
+		case "agreement":
+			note.Agreement = strings.TrimSpace(*agreement)
+		case "agreed-on":
+			note.AgreedOn = strings.TrimSpace(*agreedOn)
+		case "notes":
+			note.Notes = strings.TrimSpace(*notes)
+		}
+	})
+
+	if note.AgreedOn != "" {
+		if _, err := time.Parse("2006-01-02", note.AgreedOn); err != nil {
+			fmt.Printf("❌ Invalid --agreed-on %q, use YYYY-MM-DD\n", note.AgreedOn)
+			os.Exit(2)
+		}
+	}
+
+	if _, err := database.SetPeerNote(note); err != nil {
+		log.Fatalf("❌ Failed to save peer note: %v", err)
+	}
+	fmt.Printf("✅ Saved notes about %s\n", pubkey)
+}
+
+func handlePeersRemoveNote(args []string) {
+	pubkey := pubkeyArg(args, "remove-note")
+
+	fs := flag.NewFlagSet("peers remove-note", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	fs.Parse(args[1:])
+
+	database := openDatabase(*dbPath)
+	defer database.Close()
+
+	err := database.DeletePeerNote(pubkey)
+	if err == sql.ErrNoRows {
+		fmt.Printf("No notes about %s\n", pubkey)
+		return
+	}
+	if err != nil {
+		log.Fatalf("❌ Failed to remove peer note: %v", err)
+	}
+	fmt.Printf("✅ Removed notes about %s\n", pubkey)
+}
+
+// pubkeyArg returns the validated pubkey that must follow a peers subcommand
+func pubkeyArg(args []string, subcommand string) string {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Printf("Usage: lnt peers %s <pubkey> [flags]\n", subcommand)
+		os.Exit(2)
+	}
+	pubkey := strings.ToLower(args[0])
+	if !utils.ValidateNodePubkey(pubkey) {
+		fmt.Printf("❌ Invalid node pubkey: %s\n", args[0])
+		os.Exit(2)
+	}
+	return pubkey
+}
+
+func openDatabase(path string) *db.Database {
+	database, err := db.NewDatabase(path)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	return database
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// smokeCheck is one request a running API must answer
+type smokeCheck struct {
+	name string
+	path string
+	// page is set for static pages, which are HTML rather than the JSON envelope
+	page bool
+}
+
+// smokeChecks cover what the dashboard loads first: health, the portfolio
+// and the charts. A deployment only swaps services when all of them pass
+// against the new binary.
+var smokeChecks = []smokeCheck{
+	{name: "health", path: "/api/v1/health"},
+	{name: "current portfolio", path: "/api/v1/portfolio/current"},
+	{name: "portfolio history chart", path: "/api/v1/portfolio/history?days=30"},
+	{name: "fee chart", path: "/api/v1/lightning/fees?days=30"},
+	{name: "forwards chart", path: "/api/v1/lightning/forwards?days=30"},
+	{name: "forward stats", path: "/api/v1/lightning/forwards/stats"},
+	{name: "dashboard page", path: "/", page: true},
+}
+
+func handleSmoke(args []string) {
+	fs := flag.NewFlagSet("smoke", flag.ExitOnError)
+	url := fs.String("url", "http://127.0.0.1:8090", "Base URL of the portfolio API")
+	wait := fs.Duration("wait", 30*time.Second, "How long to wait for the API to come up")
+	fs.Parse(args)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	base := strings.TrimRight(*url, "/")
+
+	if err := waitForHealth(client, base, *wait); err != nil {
+		fmt.Printf("❌ API at %s did not come up: %v\n", base, err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, check := range smokeChecks {
+		if err := check.run(client, base); err != nil {
+			fmt.Printf("❌ %-24s %s: %v\n", check.name, check.path, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %-24s %s\n", check.name, check.path)
+	}
+
+	if failed > 0 {
+		fmt.Printf("❌ %d of %d smoke checks failed\n", failed, len(smokeChecks))
+		os.Exit(1)
+	}
+	fmt.Printf("✅ All %d smoke checks passed\n", len(smokeChecks))
+}
+
+// waitForHealth polls the health endpoint until it answers or wait passes
+func waitForHealth(client *http.Client, base string, wait time.Duration) error {
+	health := smokeChecks[0]
+	deadline := time.Now().Add(wait)
+	for {
+		err := health.run(client, base)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// run requests the check's path and verifies the response
+func (c smokeCheck) run(client *http.Client, base string) error {
+	resp, err := client.Get(base + c.path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+
+	if c.page {
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+			return fmt.Errorf("content type %q, want text/html", resp.Header.Get("Content-Type"))
+		}
+		return nil
+	}
+
+	var envelope struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   string          `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if !envelope.Success {
+		return fmt.Errorf("success=false: %s", envelope.Error)
+	}
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return fmt.Errorf("no data")
+	}
+	return nil
+}
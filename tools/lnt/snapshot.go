@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+)
+
+func handleSnapshot(args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	dbPath := fs.String("db", globals.dbPath(), "Path to SQLite database")
+	out := fs.String("out", "", "Snapshot file to write (default: next to the database, e.g. data/portfolio-snapshot.db)")
+	every := fs.Duration("every", 0, "Keep running and refresh the snapshot at this interval (0 writes it once)")
+	fs.Parse(args)
+
+	if *out == "" {
+		*out = db.SnapshotPath(*dbPath)
+	}
+	if *out == *dbPath {
+		log.Fatalf("--out must not be the database itself")
+	}
+	if _, err := os.Stat(*dbPath); err != nil {
+		log.Fatalf("❌ No database to snapshot: %v", err)
+	}
+
+	snapshot := func() error {
+		start := time.Now()
+		if err := db.Snapshot(*dbPath, *out); err != nil {
+			return err
+		}
+		fmt.Printf("📸 Wrote a read-only snapshot of %s to %s in %v\n", *dbPath, *out, time.Since(start).Round(time.Millisecond))
+		return nil
+	}
+	if *every <= 0 {
+		if err := snapshot(); err != nil {
+			log.Fatalf("❌ Snapshot failed: %v", err)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(*every)
+	defer ticker.Stop()
+	for {
+		// A failed refresh leaves the previous snapshot in place
+		if err := snapshot(); err != nil {
+			log.Printf("⚠️  Snapshot failed: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
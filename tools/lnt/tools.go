@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// tool is a binary of this project that lnt runs as a command
+type tool struct {
+	binary string
+	// accepts lists the global flags the binary takes
+	accepts []string
+}
+
+// collectorFlags are the global flags every collector takes
+var collectorFlags = []string{"db", "profile", "mock", "verbose-logs"}
+
+var (
+	apiTool      = tool{binary: "portfolio-api", accepts: []string{"db", "mock", "verbose-logs"}}
+	channelsTool = tool{binary: "channel-manager"}
+	monitorTool  = tool{binary: "telegram-monitor"}
+	backfillTool = tool{binary: "historical-backfill", accepts: []string{"db", "mock"}}
+)
+
+// collectors are run by lnt collect <name>
+var collectors = map[string]tool{
+	"forwarding":    {binary: "forwarding-collector", accepts: collectorFlags},
+	"strike":        {binary: "strike-balance-collector", accepts: collectorFlags},
+	"cold-storage":  {binary: "cold-storage-collector", accepts: collectorFlags},
+	"liquid":        {binary: "liquid-balance-collector", accepts: collectorFlags},
+	"ecash":         {binary: "ecash-balance-collector", accepts: collectorFlags},
+	"prober":        {binary: "payment-prober", accepts: collectorFlags},
+	"monthly-close": {binary: "monthly-close", accepts: collectorFlags},
+}
+
+func collectorNames() []string {
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func handleCollect(args []string) {
+	if len(args) == 0 {
+		fmt.Printf("Usage: lnt collect <%s> [flags]\n", strings.Join(collectorNames(), "|"))
+		os.Exit(2)
+	}
+	collector, ok := collectors[args[0]]
+	if !ok {
+		fmt.Printf("Unknown collector: %s (one of %s)\n", args[0], strings.Join(collectorNames(), "|"))
+		os.Exit(2)
+	}
+	runTool(collector, args[1:])
+}
+
+// runTool replaces lnt with the tool's binary, passing it the global flags
+// it takes followed by args. The binary is looked up next to lnt, as make
+// builds them all into bin/, then in PATH.
+func runTool(t tool, args []string) {
+	path, err := findBinary(t.binary)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	argv := append([]string{t.binary}, globals.args(t.accepts)...)
+	argv = append(argv, args...)
+	// The environment carries the project's .env to the tool
+	globals.setEnv()
+	if err := syscall.Exec(path, argv, os.Environ()); err != nil {
+		log.Fatalf("❌ Failed to run %s: %v", path, err)
+	}
+}
+
+func findBinary(name string) (string, error) {
+	if exe, err := os.Executable(); err == nil {
+		path := filepath.Join(filepath.Dir(exe), name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	path, err := exec.LookPath(name)
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", fmt.Errorf("%s not found next to lnt or in PATH, build it with make %s", name, name)
+	}
+	return path, err
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -79,6 +80,16 @@ func getCurrentLightningState() (*LightningState, error) {
 		}
 	}
 
+	// Deposits only count once they have enough confirmations
+	if config.OnchainMinConfirmations > 0 {
+		settled, err := getSettledOnchainBalance(config.OnchainMinConfirmations)
+		if err != nil {
+			return nil, err
+		}
+		state.OnchainPending = state.OnchainBalance - settled
+		state.OnchainBalance = settled
+	}
+
 	// Get channel balance
 	channelBalance, err := lnd.RunLNCLI("channelbalance")
 	if err != nil {
@@ -160,3 +171,30 @@ func getCurrentLightningState() (*LightningState, error) {
 
 	return state, nil
 }
+
+// getSettledOnchainBalance sums the wallet's UTXOs with at least minConf confirmations
+func getSettledOnchainBalance(minConf int64) (int64, error) {
+	output, err := lnd.RunLNCLI("listunspent", "--min_confs", strconv.FormatInt(minConf, 10))
+	if err != nil {
+		return 0, err
+	}
+
+	var unspent struct {
+		UTXOs []struct {
+			AmountSat string `json:"amount_sat"`
+		} `json:"utxos"`
+	}
+	if err := json.Unmarshal(output, &unspent); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, utxo := range unspent.UTXOs {
+		amount, err := strconv.ParseInt(utxo.AmountSat, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse UTXO amount %q: %w", utxo.AmountSat, err)
+		}
+		total += amount
+	}
+	return total, nil
+}
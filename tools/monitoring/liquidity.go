@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/brewgator/lightning-node-tools/internal/liquidity"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+)
+
+// checkChannelLiquidity alerts when a channel becomes depleted or saturated
+// and when it recovers. Channel states are kept in the saved state so each
+// transition is reported once.
+func checkChannelLiquidity(current, prev *LightningState) {
+	channels, err := lnd.GetChannels()
+	if err != nil {
+		log.Printf("Failed to get channels for liquidity check: %v", err)
+		current.ChannelStates = prev.ChannelStates
+		return
+	}
+
+	current.ChannelStates = make(map[string]string, len(channels))
+	for _, channel := range channels {
+		capacity, _ := strconv.ParseInt(channel.Capacity, 10, 64)
+		local, _ := strconv.ParseInt(channel.LocalBalance, 10, 64)
+		ratio := liquidity.LocalRatio(local, capacity)
+
+		previous, known := prev.ChannelStates[channel.ChanID]
+		state := liquidity.Classify(ratio, config.Liquidity.For(channel.ChanID), previous)
+		current.ChannelStates[channel.ChanID] = state
+
+		// Channels seen for the first time are recorded without alerting, so
+		// upgrading the monitor or opening a channel does not send a burst
+		if !known || state == previous {
+			continue
+		}
+		sendTelegram(liquidityMessage(channel, state, ratio))
+	}
+}
+
+// liquidityMessage describes a channel's new liquidity state
+func liquidityMessage(channel lnd.Channel, state string, ratio float64) string {
+	alias := channelLabel(channel)
+	capacity, _ := strconv.ParseInt(channel.Capacity, 10, 64)
+	local, _ := strconv.ParseInt(channel.LocalBalance, 10, 64)
+
+	var title, hint string
+	switch state {
+	case liquidity.StateDepleted:
+		title = "🪫 <b>Channel Depleted</b>"
+		hint = "Outbound liquidity is nearly gone. Consider raising fees or rebalancing."
+	case liquidity.StateSaturated:
+		title = "🔋 <b>Channel Saturated</b>"
+		hint = "Inbound liquidity is nearly gone. Consider lowering fees or rebalancing."
+	default:
+		title = "✅ <b>Channel Rebalanced</b>"
+		hint = "Liquidity is back within thresholds."
+	}
+
+	return fmt.Sprintf("%s\nChannel: %s (%s)\nLocal: %s of %s (%.1f%%)\n%s",
+		title, alias, channel.ChanID, formatSats(local), formatSats(capacity), ratio*100, hint)
+}
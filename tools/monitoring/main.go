@@ -21,6 +21,8 @@ func main() {
 	dataDir = filepath.Join(projectRoot, "data")
 	stateFile = filepath.Join(dataDir, "last_state.json")
 	uptimeFile = filepath.Join(dataDir, "last_uptime.txt")
+	walletLockFile = filepath.Join(dataDir, "wallet_locked")
+	updateStateFile = filepath.Join(dataDir, "update_check.json")
 
 	// Load configuration from project root
 	if err := loadConfig(filepath.Join(projectRoot, ".env")); err != nil {
@@ -32,11 +34,20 @@ func main() {
 		log.Fatal("Failed to create data directory:", err)
 	}
 
+	loadChannelNames()
+
 	// Check for server reboot
 	if err := checkServerReboot(); err != nil {
 		log.Printf("Error checking server reboot: %v", err)
 	}
 
+	checkForUpdates()
+
+	// A locked wallet answers nothing else, so alert and stop here
+	if !checkWalletLock() {
+		return
+	}
+
 	// Get current Lightning state
 	currentState, err := getCurrentLightningState()
 	if err != nil {
@@ -77,6 +88,7 @@ func main() {
 	checkBalanceChanges(currentState, prevState)
 	checkRoutingFees(currentState, prevState)
 	checkRoutingActivity(currentState, prevState)
+	checkChannelLiquidity(currentState, prevState)
 
 	// Save current state
 	if err := saveState(currentState); err != nil {
@@ -106,6 +106,10 @@ func checkBalanceChanges(current, prev *LightningState) {
 	// Only report on-chain changes (these are always real payments/receipts)
 	if onchainChange != 0 && int64(math.Abs(float64(onchainChange))) >= threshold {
 		msg := createBalanceMessage("On-chain", onchainChange, current.OnchainBalance)
+		if current.OnchainPending > 0 {
+			msg += fmt.Sprintf("\nPending: %s (under %d confirmations)",
+				formatSats(current.OnchainPending), config.OnchainMinConfirmations)
+		}
 		sendTelegram(msg)
 	}
 
@@ -253,7 +257,7 @@ func getSuperDetailedEarningsForTelegram() string {
 	var topChannels []channelSummary
 	for _, channel := range channels {
 		if earnings := channelEarnings[channel.ChanID]; earnings > 0 {
-			alias := lnd.GetNodeAlias(channel.RemotePubkey)
+			alias := channelLabel(channel)
 			if len(alias) > 15 {
 				alias = alias[:12] + "..."
 			}
@@ -332,10 +336,10 @@ func checkRoutingFees(current, prev *LightningState) {
 		return
 	}
 
-	// Create channel ID to alias mapping
+	// Create channel ID to name or alias mapping
 	channelAliases := make(map[string]string)
 	for _, channel := range channels {
-		alias := lnd.GetNodeAlias(channel.RemotePubkey)
+		alias := channelLabel(channel)
 		if len(alias) > 15 {
 			alias = alias[:12] + "..."
 		}
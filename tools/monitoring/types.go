@@ -1,9 +1,27 @@
 package main
 
+import (
+	"github.com/brewgator/lightning-node-tools/internal/liquidity"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
 // Config holds the bot configuration
 type Config struct {
 	BotToken string
 	ChatID   string
+	// Liquidity holds the depleted/saturated thresholds for channel alerts
+	Liquidity liquidity.Config
+	// OnchainMinConfirmations is how many confirmations on-chain funds need
+	// before they count towards the balance; 0 counts unconfirmed funds too
+	OnchainMinConfirmations int64
+	// WalletPasswordFile unlocks LND's wallet when it is found locked; empty
+	// only alerts
+	WalletPasswordFile string
+	// UpdateCheck announces new lightning-node-tools releases daily
+	UpdateCheck bool
+	// NumberLocale writes amounts in full with its separators, e.g.
+	// "1 234 567 sats"; nil keeps the compact "1.23M sats"
+	NumberLocale *utils.NumberLocale
 }
 
 // LightningState represents the current state of the Lightning node
@@ -14,17 +32,13 @@ type LightningState struct {
 	Invoices             int   `json:"invoices"`
 	Forwards             int   `json:"forwards"`
 	OnchainBalance       int64 `json:"onchain_balance"`
+	OnchainPending       int64 `json:"onchain_pending"` // Below OnchainMinConfirmations, not in OnchainBalance
 	LocalBalance         int64 `json:"local_balance"`
 	RemoteBalance        int64 `json:"remote_balance"`
 	TotalBalance         int64 `json:"total_balance"`
 	LastForwardTimestamp int64 `json:"last_forward_timestamp"`
-}
-
-// TelegramMessage represents a message to send via Telegram API
-type TelegramMessage struct {
-	ChatID    string `json:"chat_id"`
-	Text      string `json:"text"`
-	ParseMode string `json:"parse_mode"`
+	// ChannelStates maps channel IDs to their liquidity state, see internal/liquidity
+	ChannelStates map[string]string `json:"channel_states,omitempty"`
 }
 
 // Constants for monitoring thresholds
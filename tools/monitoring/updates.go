@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/brewgator/lightning-node-tools/internal/updater"
+	"github.com/brewgator/lightning-node-tools/internal/version"
+)
+
+// updateCheckInterval is how often GitHub is asked for a new release
+const updateCheckInterval = 24 * time.Hour
+
+// UpdateState remembers when releases were last checked and which release
+// has been announced, so each release is only announced once
+type UpdateState struct {
+	LastCheck   time.Time `json:"last_check"`
+	NotifiedTag string    `json:"notified_tag,omitempty"`
+}
+
+// checkForUpdates announces a newer lightning-node-tools release with its
+// changelog highlights. It only runs with UPDATE_CHECK=true in .env.
+func checkForUpdates() {
+	if !config.UpdateCheck {
+		return
+	}
+
+	state := loadUpdateState()
+	if time.Since(state.LastCheck) < updateCheckInterval {
+		return
+	}
+	state.LastCheck = time.Now()
+	defer saveUpdateState(state)
+
+	release, err := updater.NewClient("").LatestRelease()
+	if err != nil {
+		log.Printf("Failed to check for updates: %v", err)
+		return
+	}
+	if release.TagName == state.NotifiedTag {
+		return
+	}
+
+	newer, err := release.IsNewer(version.Version)
+	if err != nil {
+		log.Printf("Failed to compare release %s: %v", release.TagName, err)
+		return
+	}
+	if newer {
+		sendTelegram(release.Message(version.Version))
+		state.NotifiedTag = release.TagName
+	}
+}
+
+// loadUpdateState reads the update check state, empty when there is none
+func loadUpdateState() UpdateState {
+	var state UpdateState
+	data, err := os.ReadFile(updateStateFile)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("Failed to parse update state: %v", err)
+	}
+	return state
+}
+
+// saveUpdateState writes the update check state to disk
+func saveUpdateState(state UpdateState) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode update state: %v", err)
+		return
+	}
+	if err := os.WriteFile(updateStateFile, data, 0644); err != nil {
+		log.Printf("Failed to save update state: %v", err)
+	}
+}
@@ -5,19 +5,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/liquidity"
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
 	"github.com/brewgator/lightning-node-tools/internal/utils"
 )
 
 // Global variables for configuration and file paths
 var (
-	config     Config
-	dataDir    string
-	stateFile  string
-	uptimeFile string
+	config          Config
+	dataDir         string
+	stateFile       string
+	uptimeFile      string
+	walletLockFile  string
+	updateStateFile string
+	// channelNames are the names given to channels with lnt channel-names
+	channelNames map[string]string
 )
 
 // loadConfig loads configuration from the .env file
@@ -28,6 +37,9 @@ func loadConfig(envFile string) error {
 	}
 	defer file.Close()
 
+	config.Liquidity = liquidity.NewConfig()
+	var overrides string
+
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -48,6 +60,36 @@ func loadConfig(envFile string) error {
 			config.BotToken = value
 		case "CHAT_ID":
 			config.ChatID = value
+		case "CHANNEL_LOW_RATIO":
+			if err := parseRatio(key, value, &config.Liquidity.Default.Low); err != nil {
+				return err
+			}
+		case "CHANNEL_HIGH_RATIO":
+			if err := parseRatio(key, value, &config.Liquidity.Default.High); err != nil {
+				return err
+			}
+		case "CHANNEL_RATIO_HYSTERESIS":
+			if err := parseRatio(key, value, &config.Liquidity.Default.Hysteresis); err != nil {
+				return err
+			}
+		case "CHANNEL_RATIO_OVERRIDES":
+			overrides = value
+		case "UPDATE_CHECK":
+			config.UpdateCheck = value == "true"
+		case "NUMBER_LOCALE":
+			locale, err := utils.ParseNumberLocale(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			config.NumberLocale = &locale
+		case "WALLET_PASSWORD_FILE":
+			config.WalletPasswordFile = value
+		case "ONCHAIN_MIN_CONFIRMATIONS":
+			minConf, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || minConf < 0 {
+				return fmt.Errorf("%s must be a non-negative integer, got %q", key, value)
+			}
+			config.OnchainMinConfirmations = minConf
 		}
 	}
 
@@ -55,9 +97,49 @@ func loadConfig(envFile string) error {
 		return fmt.Errorf("BOT_TOKEN and CHAT_ID must be set in .env file")
 	}
 
+	if err := config.Liquidity.Default.Validate(); err != nil {
+		return fmt.Errorf("invalid channel ratio thresholds: %w", err)
+	}
+	parsed, err := liquidity.ParseOverrides(overrides, config.Liquidity.Default.Hysteresis)
+	if err != nil {
+		return fmt.Errorf("invalid CHANNEL_RATIO_OVERRIDES: %w", err)
+	}
+	config.Liquidity.Overrides = parsed
+
 	return scanner.Err()
 }
 
+// loadChannelNames reads the channel names from the database of LNT_DB and
+// LNT_PROFILE, or data/portfolio.db. Without them messages name channels by
+// their peer's alias.
+func loadChannelNames() {
+	path, err := db.EnvPath(filepath.Join(dataDir, "portfolio.db"))
+	if err == nil {
+		channelNames, err = db.ReadChannelNames(path)
+	}
+	if err != nil {
+		log.Printf("Failed to read channel names, showing peer aliases: %v", err)
+	}
+}
+
+// channelLabel returns the channel's name, or its peer's alias when it has none
+func channelLabel(channel lnd.Channel) string {
+	if name := channelNames[channel.ChanID]; name != "" {
+		return name
+	}
+	return lnd.GetNodeAlias(channel.RemotePubkey)
+}
+
+// parseRatio parses a 0-1 ratio from the .env file
+func parseRatio(key, value string, ratio *float64) error {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 || parsed > 1 {
+		return fmt.Errorf("%s must be a ratio between 0 and 1, got %q", key, value)
+	}
+	*ratio = parsed
+	return nil
+}
+
 // saveState saves the Lightning state to disk
 func saveState(state *LightningState) error {
 	data, err := json.MarshalIndent(state, "", "  ")
@@ -79,8 +161,12 @@ func loadState() (*LightningState, error) {
 	return &state, err
 }
 
-// formatSats formats satoshi amounts using shared utility
+// formatSats formats satoshi amounts using shared utility, or in full when
+// NUMBER_LOCALE is set
 func formatSats(amount int64) string {
+	if config.NumberLocale != nil {
+		return config.NumberLocale.FormatInt(amount) + " sats"
+	}
 	return utils.FormatSats(amount)
 }
 
@@ -101,7 +187,7 @@ func formatSatsChange(amount int64) string {
 	}
 
 	// For larger amounts, use the standard formatting
-	return utils.FormatSats(amount)
+	return formatSats(amount)
 }
 
 // checkServerReboot checks if the server has rebooted and sends notification
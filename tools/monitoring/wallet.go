@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/brewgator/lightning-node-tools/internal/lnd"
+)
+
+// checkWalletLock alerts when LND's wallet is locked, e.g. after a restart,
+// and unlocks it when WALLET_PASSWORD_FILE is configured. It reports whether
+// the node is ready, since nothing else can be read from a locked node.
+func checkWalletLock() bool {
+	state, err := lnd.GetWalletState()
+	if err != nil {
+		// Leave it to the other checks to report an unreachable node
+		log.Printf("Failed to get wallet state: %v", err)
+		return true
+	}
+
+	switch state {
+	case lnd.WalletStateLocked:
+		handleLockedWallet()
+		return false
+	case lnd.WalletStateNonExisting:
+		log.Println("LND has no wallet yet, skipping checks")
+		return false
+	case lnd.WalletStateWaitingToStart, lnd.WalletStateUnlocked:
+		// Still starting up, the RPC server is not ready
+		return false
+	}
+
+	// The wallet was locked on an earlier run and has since been unlocked
+	if _, err := os.Stat(walletLockFile); err == nil {
+		sendTelegram("🔓 <b>LND Wallet Unlocked</b>\nThe node is running again")
+		if err := os.Remove(walletLockFile); err != nil {
+			log.Printf("Failed to remove wallet lock marker: %v", err)
+		}
+	}
+	return true
+}
+
+// handleLockedWallet alerts once per lock and tries the configured password
+// file on every run until the wallet unlocks
+func handleLockedWallet() {
+	_, err := os.Stat(walletLockFile)
+	firstSeen := os.IsNotExist(err)
+
+	if config.WalletPasswordFile == "" {
+		if firstSeen {
+			sendTelegram("🔒 <b>LND Wallet Locked</b>\nLND is waiting for its wallet password.\nUnlock it with: lncli unlock")
+		}
+		markWalletLocked()
+		return
+	}
+
+	if err := lnd.UnlockWallet(config.WalletPasswordFile); err != nil {
+		log.Printf("Failed to unlock wallet: %v", err)
+		if firstSeen {
+			sendTelegram(fmt.Sprintf("🔒 <b>LND Wallet Locked</b>\nAuto-unlock failed: %v\nUnlock it with: lncli unlock", err))
+		}
+		markWalletLocked()
+		return
+	}
+
+	log.Println("Unlocked LND wallet")
+	sendTelegram("🔓 <b>LND Wallet Unlocked</b>\nThe wallet was locked and has been unlocked automatically")
+	if !firstSeen {
+		if err := os.Remove(walletLockFile); err != nil {
+			log.Printf("Failed to remove wallet lock marker: %v", err)
+		}
+	}
+}
+
+// markWalletLocked records that the wallet lock has been reported, so the
+// alert is not repeated every run
+func markWalletLocked() {
+	if err := os.WriteFile(walletLockFile, nil, 0644); err != nil {
+		log.Printf("Failed to save wallet lock marker: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/brewgator/lightning-node-tools/internal/db"
+	"github.com/brewgator/lightning-node-tools/internal/importer"
+	"github.com/brewgator/lightning-node-tools/internal/utils"
+)
+
+func main() {
+	var (
+		dbPath   = flag.String("db", "data/portfolio.db", "Path to SQLite database")
+		file     = flag.String("file", "", "CSV file to import (required)")
+		unit     = flag.String("unit", "sats", "Unit of the amount columns (sats or BTC)")
+		dryRun   = flag.Bool("dry-run", false, "Validate the CSV without writing to the database")
+		mockMode = flag.Bool("mock", false, "Import into mock database tables")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: portfolio-import --file history.csv [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Imports historical balance snapshots from CSV. The header must contain a\n")
+		fmt.Fprintf(os.Stderr, "timestamp (or date) column and any of: lightning_local, lightning_remote,\n")
+		fmt.Fprintf(os.Stderr, "onchain_confirmed, onchain_unconfirmed, tracked_addresses, cold_storage,\n")
+		fmt.Fprintf(os.Stderr, "total_portfolio, total_liquid. Existing snapshots with the same timestamp\n")
+		fmt.Fprintf(os.Stderr, "are replaced.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open CSV: %v", err)
+	}
+	defer f.Close()
+
+	snapshots, err := importer.ParseBalanceSnapshotsCSV(f, *unit)
+	if err != nil {
+		log.Fatalf("❌ Invalid CSV: %v", err)
+	}
+
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+	fmt.Printf("📊 Parsed %d snapshots from %s to %s\n",
+		len(snapshots), first.Timestamp.Format("2006-01-02"), last.Timestamp.Format("2006-01-02"))
+	fmt.Printf("   Latest total portfolio: %s\n", utils.FormatSats(last.TotalPortfolio))
+
+	if *dryRun {
+		fmt.Println("Dry run, nothing written")
+		return
+	}
+
+	database, err := db.NewDatabaseWithMockMode(*dbPath, *mockMode)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.InsertBalanceSnapshots(snapshots); err != nil {
+		log.Fatalf("❌ Import failed, no snapshots written: %v", err)
+	}
+
+	fmt.Printf("✅ Imported %d snapshots\n", len(snapshots))
+}